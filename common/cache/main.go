@@ -13,15 +13,19 @@ import (
 	"github.com/eko/gocache/lib/v4/store"
 	freecache_store "github.com/eko/gocache/store/freecache/v4"
 	redis_store "github.com/eko/gocache/store/redis/v4"
+	"github.com/spf13/viper"
 	"golang.org/x/sync/singleflight"
 )
 
 var (
-	kvCache       *marshaler.Marshaler
-	ctx           = context.Background()
-	sfGroup       singleflight.Group
-	CacheTimeout  = 5 * time.Second
-	CacheNotFound = errors.New("cache not found")
+	kvCache *marshaler.Marshaler
+	// responseKvCache 响应缓存专用。内存模式下 freecache 单条上限为总容量的 1/1024，
+	// 响应体远大于普通缓存项，因此单独开一块，避免与 kvCache 互相挤占。
+	responseKvCache *marshaler.Marshaler
+	ctx             = context.Background()
+	sfGroup         singleflight.Group
+	CacheTimeout    = 5 * time.Second
+	CacheNotFound   = errors.New("cache not found")
 )
 
 func InitCacheManager() {
@@ -29,12 +33,17 @@ func InitCacheManager() {
 	if config.RedisEnabled {
 		redisStore := redis_store.NewRedis(redis.RDB)
 		client = cacheM.New[any](redisStore)
-	} else {
-		freecacheStore := freecache_store.NewFreecache(freecache.NewCache(1024 * 1024))
-		client = cacheM.New[any](freecacheStore)
+		kvCache = marshaler.New(client)
+		responseKvCache = kvCache
+		return
 	}
 
+	freecacheStore := freecache_store.NewFreecache(freecache.NewCache(1024 * 1024))
+	client = cacheM.New[any](freecacheStore)
 	kvCache = marshaler.New(client)
+
+	responseStore := freecache_store.NewFreecache(freecache.NewCache(viper.GetInt("response_cache.memory_size") * 1024 * 1024))
+	responseKvCache = marshaler.New(cacheM.New[any](responseStore))
 }
 
 func GetCache[T any](key string) (T, error) {
@@ -49,6 +58,24 @@ func GetCache[T any](key string) (T, error) {
 	return val, nil
 }
 
+// GetResponseCache 读取响应缓存
+func GetResponseCache[T any](key string) (T, error) {
+	var val T
+	_, err := responseKvCache.Get(ctx, key, &val)
+	if err != nil {
+		if errors.Is(err, store.NotFound{}) {
+			return *new(T), CacheNotFound
+		}
+		return *new(T), err
+	}
+	return val, nil
+}
+
+// SetResponseCache 写入响应缓存
+func SetResponseCache(key string, value any, expiration time.Duration) error {
+	return responseKvCache.Set(ctx, key, value, store.WithExpiration(expiration))
+}
+
 func SetCache(key string, value any, expiration time.Duration) error {
 	return kvCache.Set(ctx, key, value, store.WithExpiration(expiration))
 }
@@ -35,6 +35,8 @@ func defaultConfig() {
 	viper.SetDefault("gin_mode", "release")
 	viper.SetDefault("log_dir", "./logs")
 	viper.SetDefault("sqlite_path", "done-hub.db")
+	viper.SetDefault("storage.local.path", "./files")
+	viper.SetDefault("response_cache.memory_size", 64)
 	viper.SetDefault("sqlite_busy_timeout", 3000)
 	viper.SetDefault("sync_frequency", 600)
 	viper.SetDefault("batch_update_interval", 5)
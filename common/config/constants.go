@@ -223,6 +223,51 @@ var DisableTokenEncoders = false
 var RetryTimes = 0
 var RetryTimeOut = 10
 
+// BatchPriceRatio 批处理（Message Batches 等）按条计费时在分组倍率之上叠加的折扣倍率，
+// 1 表示不打折。BatchConcurrency 为网关代执行批处理时全局同时在途的条目数上限。
+var BatchPriceRatio = 0.5
+var BatchConcurrency = 5
+
+// FileMaxSize 网关保存的单个文件上限（MB）；FileStorageQuota 每个用户可保存的文件与会话 item 总量（MB），0 表示不限；
+// FileExpireDays 上传时未指定 expires_after 的文件默认保留天数，0 表示不过期。
+var FileMaxSize = 100
+var FileStorageQuota = 1024
+var FileExpireDays = 30
+
+// ResponsesStoreEnabled 是否由网关保存 Responses API 的响应（请求未指定 store: false 时），
+// 用于在任意渠道上支持 previous_response_id；ResponsesRetentionDays 保存天数，0 表示不过期。
+var ResponsesStoreEnabled = true
+var ResponsesRetentionDays = 30
+
+// ResponseCacheEnabled 响应缓存总开关，令牌还需在设置中单独开启。ResponseCacheTTL 为默认缓存秒数，
+// 可由 ResponseCacheModelTTL（JSON，模型 -> 秒数，0 表示该模型不缓存）按模型覆盖；
+// ResponseCacheHitRatio 命中缓存时在原价上叠加的计费倍率，0 表示免费。
+var ResponseCacheEnabled = false
+var ResponseCacheTTL = 3600
+var ResponseCacheHitRatio = 0.0
+var ResponseCacheModelTTL = ""
+
+var (
+	responseCacheModelTTLMap  = map[string]int{}
+	responseCacheModelTTLLock sync.RWMutex
+)
+
+func SetResponseCacheModelTTLMap(m map[string]int) {
+	responseCacheModelTTLLock.Lock()
+	defer responseCacheModelTTLLock.Unlock()
+	responseCacheModelTTLMap = m
+}
+
+// GetResponseCacheTTL 返回模型的缓存秒数，未单独配置时使用 ResponseCacheTTL
+func GetResponseCacheTTL(modelName string) int {
+	responseCacheModelTTLLock.RLock()
+	defer responseCacheModelTTLLock.RUnlock()
+	if ttl, ok := responseCacheModelTTLMap[modelName]; ok {
+		return ttl
+	}
+	return ResponseCacheTTL
+}
+
 // ChannelFailErrorWrapEnabled 是否启用"渠道失败统一封装"。
 // 开启（默认）：FilterOpenAIErr 把所有非 400 上游错误坍缩为 503 + ChannelFailErrorMessage，
 //
@@ -292,6 +337,23 @@ func GetRetryCooldownForStatus(statusCode int) (int, bool) {
 	return v, ok
 }
 
+// 渠道熔断器：按渠道+模型统计滚动窗口（CircuitBreakerWindowSeconds）内的错误率与连续失败次数，
+// 错误率达到 CircuitBreakerErrorRate%（且请求数不少于 CircuitBreakerMinRequests）或连续失败
+// CircuitBreakerConsecutiveFailures 次时打开。打开时长从 CircuitBreakerOpenSeconds 起每次重新打开翻倍，
+// 最长 CircuitBreakerMaxOpenSeconds；到期后半开，放行 CircuitBreakerHalfOpenRequests 个试探请求，全部成功才关闭。
+// 关闭熔断器后回退到按 RetryCooldownSeconds 固定冷却。
+var CircuitBreakerEnabled = true
+var CircuitBreakerWindowSeconds = 60
+var CircuitBreakerMinRequests = 10
+var CircuitBreakerErrorRate = 50
+var CircuitBreakerConsecutiveFailures = 5
+var CircuitBreakerOpenSeconds = 30
+var CircuitBreakerMaxOpenSeconds = 1800
+var CircuitBreakerHalfOpenRequests = 1
+
+// 延迟感知路由（分组路由策略为 latency 时生效）中按权重随机探索的流量比例（%）
+var LatencyRoutingExploreRate = 10
+
 var CFWorkerImageUrl = ""
 var CFWorkerImageKey = ""
 
@@ -438,6 +500,7 @@ const (
 	RelayModeChatRealtime
 	RelayModeKling
 	RelayModeResponses
+	RelayModeVideos
 )
 
 type ContextKey string
@@ -36,4 +36,48 @@ const (
 	// 异协议兼容路径复用（响应还要经 ToResponses / convertOpenAIResponseToClaude 等结构体转换），
 	// 此时若字节透传就会把 chat 字节当作目标协议返回，造成协议错乱。故仅在同构直返分支放行。
 	GinRawPassThroughAllowedKey = "raw_passthrough_allowed"
+
+	// GinBatchPriceRatioKey 批处理条目在网关内重放时设置的折扣倍率（float64），
+	// relay_util.NewQuota 读取后叠加到输入/输出倍率上；普通请求不设置，按 1 处理。
+	GinBatchPriceRatioKey = "batch_price_ratio"
+
+	// GinResponseCacheHitKey 请求由响应缓存直接返回时置 true，
+	// relay_util.NewQuota 据此按 ResponseCacheHitRatio 计费并在日志中标记 cache_hit。
+	GinResponseCacheHitKey = "response_cache_hit"
+
+	// GinChannelModelKey 选渠道时使用的模型名（通配符/大小写匹配后、模型映射前），
+	// 与 balancer 的冷却、熔断器 key 一致；new_model 经过渠道模型映射，不能用于定位熔断器。
+	GinChannelModelKey = "channel_model"
+
+	// GinCostRoutingKey 成本优先路由选中渠道时设置的 *model.CostRoutingChoice，
+	// relay_util.NewQuota 读取后在日志中记录相对默认选择节省的成本。
+	GinCostRoutingKey = "cost_routing"
+
+	// GinChannelLeaseKey 选中设有并发上限的渠道时占用的名额（*model.channelLease），
+	// 每次尝试结束后释放；Distribute 中间件在请求结束时兜底释放。
+	GinChannelLeaseKey = "channel_lease"
+
+	// GinChannelKeyIdKey 多密钥渠道本次请求使用的密钥 ID（int），未使用密钥池时为 0，
+	// 用于密钥级冷却/自动禁用、用量统计和日志记录。
+	GinChannelKeyIdKey = "channel_key_id"
+
+	// GinSpecificChannelKeyIdKey 与 specific_channel_id 一起设置，指定使用密钥池中的某个密钥，
+	// 异步任务的后续操作需沿用提交任务时的密钥。
+	GinSpecificChannelKeyIdKey = "specific_channel_key_id"
+
+	// GinHedgeKey 启用对冲请求时设置的 *relay_util.HedgeRecord，主请求与对冲请求共享，
+	// relay_util.NewQuota 读取后在胜出方的日志中记录对冲过程。
+	GinHedgeKey = "hedge"
+
+	// GinVirtualModelKey 请求虚拟模型时设置的 *relay_util.VirtualModelRoute，记录回退链当前所在的步骤，
+	// 选渠道时按当前步骤的模型选择，relay_util.NewQuota 读取后在日志中记录实际使用的步骤。
+	GinVirtualModelKey = "virtual_model"
+
+	// GinRoutingContentKey 选渠道前识别出的请求内容特征（*model.RoutingContent），
+	// 选渠道时按当前分组的内容路由规则生成额外的渠道过滤器。
+	GinRoutingContentKey = "routing_content"
+
+	// GinRequestRoutingKey 请求体中 OpenRouter 风格的路由参数（*model.RequestRouting），分组禁用时不设置。
+	// models 作为回退链，provider 偏好在选渠道时按渠道标签或类型过滤与排序。
+	GinRequestRoutingKey = "request_routing"
 )
@@ -0,0 +1,145 @@
+package limit
+
+import (
+	"context"
+	"done-hub/common/config"
+	"done-hub/common/redis"
+	_ "embed"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	concurrencyFormat = "{%s}:concurrency"
+)
+
+var (
+	//go:embed concurrencyscript.lua
+	concurrencyLuaScript string
+	concurrencyScript    = redis.NewScript(concurrencyLuaScript)
+
+	//go:embed concurrencygetscript.lua
+	concurrencyGetLuaScript string
+	concurrencyGetScript    = redis.NewScript(concurrencyGetLuaScript)
+)
+
+// ConcurrencyLimiter 并发（在途请求数）限制。每个名额是一份带过期时间的租约，
+// 调用方结束后需 Release；进程崩溃等原因未释放的租约到期后自动回收。
+type ConcurrencyLimiter interface {
+	Acquire(keyPrefix, leaseId string, max int) bool
+	Release(keyPrefix, leaseId string)
+	GetCurrent(keyPrefix string) (int, error)
+}
+
+// NewConcurrencyLimiter 启用 Redis 时多节点共享并发计数，否则只在本节点内存中计数
+func NewConcurrencyLimiter(leaseTTL time.Duration) ConcurrencyLimiter {
+	if config.RedisEnabled {
+		return &RedisConcurrencyLimiter{leaseTTL: leaseTTL}
+	}
+	return &MemoryConcurrencyLimiter{leaseTTL: leaseTTL, leases: make(map[string]map[string]time.Time)}
+}
+
+type RedisConcurrencyLimiter struct {
+	leaseTTL time.Duration
+}
+
+func (l *RedisConcurrencyLimiter) Acquire(keyPrefix, leaseId string, max int) bool {
+	now := time.Now()
+	result, err := redis.ScriptRunCtx(context.Background(),
+		concurrencyScript,
+		[]string{
+			fmt.Sprintf(concurrencyFormat, keyPrefix),
+		},
+		max,                             // ARGV[1]: max concurrency
+		now.UnixMilli(),                 // ARGV[2]: now
+		now.Add(l.leaseTTL).UnixMilli(), // ARGV[3]: lease expire time
+		leaseId,                         // ARGV[4]: lease id
+		int(l.leaseTTL.Seconds())+60,    // ARGV[5]: key ttl
+	)
+	if err != nil {
+		return false
+	}
+
+	return result.(int64) == 1
+}
+
+func (l *RedisConcurrencyLimiter) Release(keyPrefix, leaseId string) {
+	client := redis.GetRedisClient()
+	client.ZRem(context.Background(), fmt.Sprintf(concurrencyFormat, keyPrefix), leaseId)
+}
+
+func (l *RedisConcurrencyLimiter) GetCurrent(keyPrefix string) (int, error) {
+	result, err := redis.ScriptRunCtx(context.Background(),
+		concurrencyGetScript,
+		[]string{
+			fmt.Sprintf(concurrencyFormat, keyPrefix),
+		},
+		time.Now().UnixMilli(),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	count, ok := result.(int64)
+	if !ok {
+		return 0, fmt.Errorf("无法转换计数结果")
+	}
+	return int(count), nil
+}
+
+type MemoryConcurrencyLimiter struct {
+	leaseTTL time.Duration
+	leases   map[string]map[string]time.Time
+	mutex    sync.Mutex
+}
+
+func (l *MemoryConcurrencyLimiter) Acquire(keyPrefix, leaseId string, max int) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	leases := l.activeLeases(keyPrefix, now)
+	if len(leases) >= max {
+		return false
+	}
+	if leases == nil {
+		leases = make(map[string]time.Time)
+		l.leases[keyPrefix] = leases
+	}
+	leases[leaseId] = now.Add(l.leaseTTL)
+	return true
+}
+
+func (l *MemoryConcurrencyLimiter) Release(keyPrefix, leaseId string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if leases, ok := l.leases[keyPrefix]; ok {
+		delete(leases, leaseId)
+		if len(leases) == 0 {
+			delete(l.leases, keyPrefix)
+		}
+	}
+}
+
+func (l *MemoryConcurrencyLimiter) GetCurrent(keyPrefix string) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return len(l.activeLeases(keyPrefix, time.Now())), nil
+}
+
+// activeLeases 返回未过期的租约，顺带清理过期租约
+func (l *MemoryConcurrencyLimiter) activeLeases(keyPrefix string, now time.Time) map[string]time.Time {
+	leases, ok := l.leases[keyPrefix]
+	if !ok {
+		return nil
+	}
+	for id, expireAt := range leases {
+		if now.After(expireAt) {
+			delete(leases, id)
+		}
+	}
+	return leases
+}
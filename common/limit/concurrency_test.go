@@ -0,0 +1,85 @@
+package limit
+
+import (
+	"testing"
+	"time"
+)
+
+// TestMemoryConcurrencyLimiter 测试并发名额的占用与释放：达到上限后拒绝，释放或租约过期后可再次占用
+func TestMemoryConcurrencyLimiter(t *testing.T) {
+	type step struct {
+		action  string // acquire、release、wait
+		key     string
+		lease   string
+		want    bool
+		current int
+	}
+	tests := []struct {
+		name  string
+		ttl   time.Duration
+		steps []step
+	}{
+		{
+			name: "达到上限后拒绝，释放后可再占用",
+			ttl:  time.Minute,
+			steps: []step{
+				{action: "acquire", key: "a", lease: "1", want: true, current: 1},
+				{action: "acquire", key: "a", lease: "2", want: true, current: 2},
+				{action: "acquire", key: "a", lease: "3", want: false, current: 2},
+				{action: "release", key: "a", lease: "1", current: 1},
+				{action: "acquire", key: "a", lease: "3", want: true, current: 2},
+			},
+		},
+		{
+			name: "不同 key 分别计数",
+			ttl:  time.Minute,
+			steps: []step{
+				{action: "acquire", key: "a", lease: "1", want: true, current: 1},
+				{action: "acquire", key: "a", lease: "2", want: true, current: 2},
+				{action: "acquire", key: "b", lease: "3", want: true, current: 1},
+			},
+		},
+		{
+			name: "重复释放与释放不存在的租约不影响计数",
+			ttl:  time.Minute,
+			steps: []step{
+				{action: "acquire", key: "a", lease: "1", want: true, current: 1},
+				{action: "release", key: "a", lease: "2", current: 1},
+				{action: "release", key: "b", lease: "1", current: 0},
+				{action: "release", key: "a", lease: "1", current: 0},
+				{action: "release", key: "a", lease: "1", current: 0},
+			},
+		},
+		{
+			name: "未释放的租约过期后回收",
+			ttl:  20 * time.Millisecond,
+			steps: []step{
+				{action: "acquire", key: "a", lease: "1", want: true, current: 1},
+				{action: "acquire", key: "a", lease: "2", want: true, current: 2},
+				{action: "wait", key: "a", current: 0},
+				{action: "acquire", key: "a", lease: "3", want: true, current: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := &MemoryConcurrencyLimiter{leaseTTL: tt.ttl, leases: make(map[string]map[string]time.Time)}
+			for i, s := range tt.steps {
+				switch s.action {
+				case "acquire":
+					if got := limiter.Acquire(s.key, s.lease, 2); got != s.want {
+						t.Fatalf("第 %d 步 Acquire(%s, %s) = %v, 期望 %v", i+1, s.key, s.lease, got, s.want)
+					}
+				case "release":
+					limiter.Release(s.key, s.lease)
+				case "wait":
+					time.Sleep(2 * tt.ttl)
+				}
+				if current, _ := limiter.GetCurrent(s.key); current != s.current {
+					t.Fatalf("第 %d 步后 %s 的并发数 = %d, 期望 %d", i+1, s.key, current, s.current)
+				}
+			}
+		})
+	}
+}
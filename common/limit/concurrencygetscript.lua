@@ -0,0 +1,4 @@
+-- KEYS[1] as concurrency_key
+-- ARGV[1] as now (ms)
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+return redis.call('ZCARD', KEYS[1])
\ No newline at end of file
@@ -0,0 +1,16 @@
+-- KEYS[1] as concurrency_key (sorted set: member = lease id, score = lease expire time in ms)
+-- ARGV[1] as max concurrency
+-- ARGV[2] as now (ms)
+-- ARGV[3] as lease expire time (ms)
+-- ARGV[4] as lease id
+-- ARGV[5] as key ttl (seconds)
+
+-- 先清理过期租约，避免未释放的租约永久占用名额
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[2])
+if redis.call('ZCARD', KEYS[1]) >= tonumber(ARGV[1]) then
+    return 0
+end
+
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[4])
+redis.call('EXPIRE', KEYS[1], ARGV[5])
+return 1
\ No newline at end of file
@@ -0,0 +1,103 @@
+package limit
+
+import (
+	"context"
+	"done-hub/common/config"
+	"done-hub/common/redis"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// WindowCounter 固定窗口用量累计。与 RateLimiter 不同，超出上限时照常累加，
+// 用于 TPM 这类请求结束后才知道用量、只能事后计数再据此拦截后续请求的场景。
+type WindowCounter interface {
+	Add(keyPrefix string, n int)
+	GetCurrent(keyPrefix string) (int, error)
+}
+
+// NewWindowCounter 启用 Redis 时多节点共享计数，否则只在本节点内存中计数
+func NewWindowCounter(window time.Duration) WindowCounter {
+	if config.RedisEnabled {
+		return &RedisWindowCounter{window: window}
+	}
+	return &MemoryWindowCounter{window: window, store: make(map[string]*windowData)}
+}
+
+// RedisWindowCounter 复用固定窗口计数脚本，上限取最大值即只累加不拦截
+type RedisWindowCounter struct {
+	window time.Duration
+}
+
+func (c *RedisWindowCounter) Add(keyPrefix string, n int) {
+	redis.ScriptRunCtx(context.Background(),
+		countScript,
+		[]string{
+			fmt.Sprintf(countFormat, keyPrefix),
+		},
+		math.MaxInt32,           // ARGV[1]: rate
+		int(c.window.Seconds()), // ARGV[2]: window size in seconds
+		n,
+	)
+}
+
+func (c *RedisWindowCounter) GetCurrent(keyPrefix string) (int, error) {
+	result, err := redis.ScriptRunCtx(context.Background(),
+		countGetScript,
+		[]string{
+			fmt.Sprintf(countFormat, keyPrefix),
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	count, ok := result.(int64)
+	if !ok {
+		return 0, fmt.Errorf("无法转换计数结果")
+	}
+	return int(count), nil
+}
+
+type MemoryWindowCounter struct {
+	window time.Duration
+	store  map[string]*windowData
+	mutex  sync.Mutex
+}
+
+func (c *MemoryWindowCounter) Add(keyPrefix string, n int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	data, ok := c.store[keyPrefix]
+	if !ok || now.Sub(data.windowStart) >= c.window {
+		c.removeExpired(now)
+		c.store[keyPrefix] = &windowData{count: n, windowStart: now, lastUpdated: now}
+		return
+	}
+
+	data.count += n
+	data.lastUpdated = now
+}
+
+func (c *MemoryWindowCounter) GetCurrent(keyPrefix string) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	data, ok := c.store[keyPrefix]
+	if !ok || time.Since(data.windowStart) >= c.window {
+		return 0, nil
+	}
+	return data.count, nil
+}
+
+// removeExpired 新窗口开始时顺带清理过期的 key，避免渠道删除后残留
+func (c *MemoryWindowCounter) removeExpired(now time.Time) {
+	for key, data := range c.store {
+		if now.Sub(data.windowStart) >= 2*c.window {
+			delete(c.store, key)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package limit
+
+import (
+	"testing"
+	"time"
+)
+
+// TestMemoryWindowCounter 测试固定窗口累计：窗口内持续累加不做拦截，窗口结束后重新计数
+func TestMemoryWindowCounter(t *testing.T) {
+	const window = 50 * time.Millisecond
+	counter := &MemoryWindowCounter{window: window, store: make(map[string]*windowData)}
+
+	if current, _ := counter.GetCurrent("a"); current != 0 {
+		t.Fatalf("未累计时 GetCurrent() = %d, 期望 0", current)
+	}
+
+	counter.Add("a", 100)
+	counter.Add("a", 50)
+	counter.Add("b", 7)
+	if current, _ := counter.GetCurrent("a"); current != 150 {
+		t.Errorf("a 窗口内累计 = %d, 期望 150", current)
+	}
+	if current, _ := counter.GetCurrent("b"); current != 7 {
+		t.Errorf("b 窗口内累计 = %d, 期望 7", current)
+	}
+
+	time.Sleep(window + 10*time.Millisecond)
+	if current, _ := counter.GetCurrent("a"); current != 0 {
+		t.Errorf("窗口结束后 a = %d, 期望 0", current)
+	}
+	counter.Add("a", 5)
+	if current, _ := counter.GetCurrent("a"); current != 5 {
+		t.Errorf("新窗口 a = %d, 期望 5", current)
+	}
+
+	// b 超过两个窗口未更新，新窗口开始时被清理
+	time.Sleep(2 * window)
+	counter.Add("c", 1)
+	counter.mutex.Lock()
+	_, remained := counter.store["b"]
+	counter.mutex.Unlock()
+	if remained {
+		t.Error("过期的 b 应在新窗口开始时被清理")
+	}
+}
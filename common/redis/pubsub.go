@@ -0,0 +1,34 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func RedisPublish(channel string, message string) error {
+	ctx := context.Background()
+	return RDB.Publish(ctx, channel, message).Err()
+}
+
+// RedisSubscribe 订阅频道并阻塞处理消息，断线由 go-redis 自动重连
+func RedisSubscribe(channel string, handler func(payload string)) {
+	pubsub := RDB.Subscribe(context.Background(), channel)
+	defer pubsub.Close()
+
+	for message := range pubsub.Channel(redis.WithChannelHealthCheckInterval(30 * time.Second)) {
+		handler(message.Payload)
+	}
+}
+
+// RedisScanKeys 按模式遍历 key，避免 KEYS 阻塞 Redis
+func RedisScanKeys(pattern string) ([]string, error) {
+	ctx := context.Background()
+	var keys []string
+	iter := RDB.Scan(ctx, 0, pattern, 1000).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
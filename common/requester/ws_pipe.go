@@ -0,0 +1,81 @@
+package requester
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/websocket"
+)
+
+// NewWSPipe 创建一对进程内互联的 websocket 连接。
+// 上游协议与 OpenAI realtime 不同的渠道（如 Gemini Live）在 server 端做协议转换，
+// client 端交给 WSProxy，像普通上游连接一样转发和计费。
+func NewWSPipe() (client *websocket.Conn, server *websocket.Conn, err error) {
+	clientNetConn, serverNetConn := net.Pipe()
+
+	type upgradeResult struct {
+		conn *websocket.Conn
+		err  error
+	}
+	serverCh := make(chan upgradeResult, 1)
+
+	go func() {
+		reader := bufio.NewReader(serverNetConn)
+		req, err := http.ReadRequest(reader)
+		if err != nil {
+			serverCh <- upgradeResult{err: err}
+			return
+		}
+
+		w := &pipeResponseWriter{
+			conn:   serverNetConn,
+			brw:    bufio.NewReadWriter(reader, bufio.NewWriter(serverNetConn)),
+			header: make(http.Header),
+		}
+		upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+		conn, err := upgrader.Upgrade(w, req, nil)
+		serverCh <- upgradeResult{conn: conn, err: err}
+	}()
+
+	u := &url.URL{Scheme: "ws", Host: "pipe", Path: "/"}
+	client, _, err = websocket.NewClient(clientNetConn, u, nil, 0, 0)
+	if err != nil {
+		clientNetConn.Close()
+		serverNetConn.Close()
+		<-serverCh
+		return nil, nil, err
+	}
+
+	result := <-serverCh
+	if result.err != nil {
+		client.Close()
+		serverNetConn.Close()
+		return nil, nil, result.err
+	}
+
+	return client, result.conn, nil
+}
+
+// pipeResponseWriter 仅供 websocket.Upgrader 在管道上完成握手
+type pipeResponseWriter struct {
+	conn   net.Conn
+	brw    *bufio.ReadWriter
+	header http.Header
+}
+
+func (w *pipeResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *pipeResponseWriter) Write(data []byte) (int, error) {
+	return 0, errors.New("pipe: write before hijack")
+}
+
+func (w *pipeResponseWriter) WriteHeader(statusCode int) {}
+
+func (w *pipeResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.conn, w.brw, nil
+}
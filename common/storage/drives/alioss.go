@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"io"
 )
 
 type AliOSSUpload struct {
@@ -54,3 +55,53 @@ func (a *AliOSSUpload) Upload(data []byte, fileName string) (string, error) {
 
 	return objectURL, nil
 }
+
+func (a *AliOSSUpload) bucket() (*oss.Bucket, error) {
+	client, err := oss.New(a.Endpoint, a.AccessKeyId, a.AccessKeySecret)
+	if err != nil {
+		return nil, fmt.Errorf("creating OSS client: %w", err)
+	}
+
+	bucket, err := client.Bucket(a.BucketName)
+	if err != nil {
+		return nil, fmt.Errorf("getting bucket: %w", err)
+	}
+	return bucket, nil
+}
+
+func (a *AliOSSUpload) Put(key string, data []byte) error {
+	bucket, err := a.bucket()
+	if err != nil {
+		return err
+	}
+	if err := bucket.PutObject(key, bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("uploading file: %w", err)
+	}
+	return nil
+}
+
+func (a *AliOSSUpload) Get(key string) ([]byte, error) {
+	bucket, err := a.bucket()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := bucket.GetObject(key)
+	if err != nil {
+		return nil, fmt.Errorf("getting file: %w", err)
+	}
+	defer body.Close()
+
+	return io.ReadAll(body)
+}
+
+func (a *AliOSSUpload) Delete(key string) error {
+	bucket, err := a.bucket()
+	if err != nil {
+		return err
+	}
+	if err := bucket.DeleteObject(key); err != nil {
+		return fmt.Errorf("deleting file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package drives
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStorage 本地磁盘存储，只用于保存 Files API 的文件，不提供外链
+type LocalStorage struct {
+	Root string
+}
+
+func NewLocalStorage(root string) *LocalStorage {
+	return &LocalStorage{
+		Root: root,
+	}
+}
+
+func (l *LocalStorage) Name() string {
+	return "Local"
+}
+
+func (l *LocalStorage) Put(key string, data []byte) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func (l *LocalStorage) Get(key string) ([]byte, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+func (l *LocalStorage) Delete(key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path 把 key 限制在根目录内，避免 ../ 越界
+func (l *LocalStorage) path(key string) (string, error) {
+	path := filepath.Join(l.Root, filepath.FromSlash(key))
+	if !strings.HasPrefix(path, filepath.Clean(l.Root)+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid key: %s", key)
+	}
+	return path, nil
+}
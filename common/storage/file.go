@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrNoFileDrive = errors.New("no file storage available")
+
+// PutFile 依次尝试可用的文件存储，返回实际保存的驱动名
+func (s *Storage) PutFile(key string, data []byte) (string, error) {
+	var lastErr error = ErrNoFileDrive
+	for _, drive := range s.fileDrives {
+		if err := drive.Put(key, data); err != nil {
+			lastErr = fmt.Errorf("%s: %w", drive.Name(), err)
+			continue
+		}
+		return drive.Name(), nil
+	}
+	return "", lastErr
+}
+
+func (s *Storage) getFileDrive(driveName string) (FileDrive, error) {
+	for _, drive := range s.fileDrives {
+		if drive.Name() == driveName {
+			return drive, nil
+		}
+	}
+	return nil, fmt.Errorf("file storage %s is not configured", driveName)
+}
+
+func PutFile(key string, data []byte) (string, error) {
+	return storageDrives.PutFile(key, data)
+}
+
+func GetFile(driveName, key string) ([]byte, error) {
+	drive, err := storageDrives.getFileDrive(driveName)
+	if err != nil {
+		return nil, err
+	}
+	return drive.Get(key)
+}
+
+func DeleteFile(driveName, key string) error {
+	drive, err := storageDrives.getFileDrive(driveName)
+	if err != nil {
+		return err
+	}
+	return drive.Delete(key)
+}
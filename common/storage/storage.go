@@ -8,6 +8,8 @@ import (
 
 type Storage struct {
 	drives map[string]StorageDrive
+	// fileDrives 按注册顺序排列，保存文件时优先使用对象存储，本地磁盘兜底
+	fileDrives []FileDrive
 }
 
 func InitStorage() {
@@ -15,6 +17,16 @@ func InitStorage() {
 	InitSMStorage()
 	InitALIOSSStorage()
 	InitS3Storage()
+	InitLocalStorage()
+}
+
+func InitLocalStorage() {
+	root := viper.GetString("storage.local.path")
+	if root == "" {
+		return
+	}
+
+	AddFileDrive(drives.NewLocalStorage(root))
 }
 
 func InitALIOSSStorage() {
@@ -7,9 +7,19 @@ type StorageDrive interface {
 	Name() string
 }
 
+// FileDrive 可按 key 读取、删除的存储，Files API 的文件内容保存在这里。
+// 图床类驱动只能上传拿外链，不实现该接口。
+type FileDrive interface {
+	Name() string
+	Put(key string, data []byte) error
+	Get(key string) ([]byte, error)
+	Delete(key string) error
+}
+
 func New() *Storage {
 	storageDrive := &Storage{
-		drives: make(map[string]StorageDrive, 0),
+		drives:     make(map[string]StorageDrive, 0),
+		fileDrives: make([]FileDrive, 0),
 	}
 
 	return storageDrive
@@ -32,5 +42,28 @@ func (s *Storage) addDrive(drive StorageDrive) {
 			return
 		}
 		s.drives[driveName] = drive
+
+		// 同时支持读取的对象存储也用来保存文件
+		if fileDrive, ok := drive.(FileDrive); ok {
+			s.addFileDrive(fileDrive)
+		}
+	}
+}
+
+func AddFileDrive(drives ...FileDrive) {
+	for _, d := range drives {
+		storageDrives.addFileDrive(d)
+	}
+}
+
+func (s *Storage) addFileDrive(drive FileDrive) {
+	if drive == nil {
+		return
+	}
+	for _, d := range s.fileDrives {
+		if d.Name() == drive.Name() {
+			return
+		}
 	}
+	s.fileDrives = append(s.fileDrives, drive)
 }
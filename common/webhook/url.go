@@ -0,0 +1,83 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+// blockedNetworks 除本机、内网、链路本地（含 169.254.169.254 元数据地址）外，额外禁止投递的网段
+var blockedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",     // 本网络
+	"100.64.0.0/10", // 运营商级 NAT，阿里云元数据地址 100.100.100.200 在此网段
+	"192.0.0.0/24",  // IETF 协议分配
+	"198.18.0.0/15", // 基准测试
+	"64:ff9b::/96",  // NAT64，可映射到任意 IPv4 地址
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// isBlockedIP 回调地址不能指向网关自身或内网，避免被用来探测内部服务
+func isBlockedIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateURL 校验回调地址：只允许 http(s)，主机不能是本机、内网、链路本地或云厂商元数据地址。
+// 域名在这里无法确定最终地址，投递连接时由 dialControl 按解析结果再检查一次。
+func ValidateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid webhook url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("webhook url must start with http:// or https://")
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" {
+		return errors.New("webhook url must contain a host")
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errors.New("webhook url must not point to a local address")
+	}
+	if ip := net.ParseIP(host); ip != nil && isBlockedIP(ip) {
+		return errors.New("webhook url must not point to a local, private or metadata address")
+	}
+
+	return nil
+}
+
+// dialControl 在建立连接前检查实际连接的地址，防止域名解析到内网（包括 DNS rebinding）
+func dialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || isBlockedIP(ip) {
+		return fmt.Errorf("webhook connection to %s is not allowed", host)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestValidateURL 测试回调地址校验：拒绝非 http(s)、本机、内网、链路本地与元数据地址
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{name: "公网域名", url: "https://example.com/webhook", wantErr: false},
+		{name: "公网 IP 带端口", url: "http://93.184.216.34:8080/hook", wantErr: false},
+		{name: "公网 IPv6", url: "https://[2606:2800:220:1:248:1893:25c8:1946]/hook", wantErr: false},
+		{name: "非 http 协议", url: "ftp://example.com/hook", wantErr: true},
+		{name: "file 协议", url: "file:///etc/passwd", wantErr: true},
+		{name: "缺少主机", url: "http:///hook", wantErr: true},
+		{name: "localhost", url: "http://localhost:3000/api", wantErr: true},
+		{name: "localhost 子域名", url: "http://a.localhost./api", wantErr: true},
+		{name: "IPv4 回环", url: "http://127.0.0.1/hook", wantErr: true},
+		{name: "IPv4 回环其他地址", url: "http://127.1.2.3/hook", wantErr: true},
+		{name: "IPv6 回环", url: "http://[::1]/hook", wantErr: true},
+		{name: "未指定地址", url: "http://0.0.0.0/hook", wantErr: true},
+		{name: "10 网段", url: "http://10.0.0.5/hook", wantErr: true},
+		{name: "172.16 网段", url: "http://172.16.3.4/hook", wantErr: true},
+		{name: "192.168 网段", url: "https://192.168.1.1/hook", wantErr: true},
+		{name: "IPv6 唯一本地地址", url: "http://[fd00:ec2::254]/latest/meta-data", wantErr: true},
+		{name: "链路本地元数据地址", url: "http://169.254.169.254/latest/meta-data", wantErr: true},
+		{name: "IPv6 链路本地", url: "http://[fe80::1]/hook", wantErr: true},
+		{name: "阿里云元数据地址", url: "http://100.100.100.200/latest/meta-data", wantErr: true},
+		{name: "IPv4 映射的 IPv6 回环", url: "http://[::ffff:127.0.0.1]/hook", wantErr: true},
+		{name: "NAT64 映射的内网地址", url: "http://[64:ff9b::a00:1]/hook", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateURL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateURL(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestDialControl 测试连接前按实际地址拦截，域名解析到内网时同样拒绝
+func TestDialControl(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		wantErr bool
+	}{
+		{name: "公网 IPv4", address: "93.184.216.34:443", wantErr: false},
+		{name: "公网 IPv6", address: "[2606:2800:220:1:248:1893:25c8:1946]:443", wantErr: false},
+		{name: "回环", address: "127.0.0.1:80", wantErr: true},
+		{name: "内网", address: "10.1.2.3:8080", wantErr: true},
+		{name: "元数据地址", address: "169.254.169.254:80", wantErr: true},
+		{name: "IPv6 回环", address: "[::1]:80", wantErr: true},
+		{name: "非 IP 地址", address: "example.com:80", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := dialControl("tcp", tt.address, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("dialControl(%q) error = %v, wantErr %v", tt.address, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestHTTPClientRejectsLoopback 测试投递客户端不会连接本机地址，即使地址绕过了登记时的校验
+func TestHTTPClientRejectsLoopback(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	resp, err := httpClient.Post(server.URL, "application/json", strings.NewReader("{}"))
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("连接本机地址应被拒绝")
+	}
+	if !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("错误应来自地址检查, 实际 %v", err)
+	}
+	if called {
+		t.Error("请求不应到达本机服务")
+	}
+}
@@ -0,0 +1,140 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"done-hub/common/logger"
+	"done-hub/common/utils"
+	"done-hub/model"
+	"done-hub/types"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"gorm.io/datatypes"
+)
+
+// Enqueue 任务进入终态时登记一次回调。hookURL 为请求中指定的回调地址，为空时使用令牌的默认地址，
+// 两者都没有则不回调。投递由 worker 异步完成，这里只写发件箱。
+func Enqueue(userId, tokenId int, hookURL string, data *types.WebhookTaskData) {
+	if tokenId == 0 {
+		return
+	}
+
+	setting, err := model.GetTokenWebhookSetting(tokenId)
+	if err != nil {
+		logger.SysError(fmt.Sprintf("webhook get token #%d setting error: %s", tokenId, err.Error()))
+		return
+	}
+	if hookURL == "" {
+		hookURL = setting.URL
+	}
+	if hookURL == "" {
+		return
+	}
+	if err = ValidateURL(hookURL); err != nil {
+		logger.SysError(fmt.Sprintf("webhook token #%d task %s: %s", tokenId, data.TaskID, err.Error()))
+		return
+	}
+	if setting.Secret == "" {
+		logger.SysError(fmt.Sprintf("webhook token #%d has no signing secret", tokenId))
+		return
+	}
+
+	event := &types.WebhookEvent{
+		ID:        "evt_" + utils.GetRandomString(32),
+		Type:      types.WebhookEventTaskSucceeded,
+		CreatedAt: utils.GetTimestamp(),
+		Data:      data,
+	}
+	if data.Status == model.TaskStatusFailure {
+		event.Type = types.WebhookEventTaskFailed
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		logger.SysError(fmt.Sprintf("webhook marshal event error: %s", err.Error()))
+		return
+	}
+
+	inserted, err := model.InsertWebhookDelivery(&model.WebhookDelivery{
+		EventID:   event.ID,
+		EventType: event.Type,
+		UserId:    userId,
+		TokenID:   tokenId,
+		Platform:  data.Platform,
+		TaskID:    data.TaskID,
+		URL:       hookURL,
+		Payload:   datatypes.JSON(payload),
+		Status:    model.WebhookDeliveryStatusPending,
+	})
+	if err != nil {
+		logger.SysError(fmt.Sprintf("webhook insert delivery %s error: %s", data.TaskID, err.Error()))
+		return
+	}
+
+	if inserted {
+		Activate()
+	}
+}
+
+// EnqueueTask 任务表（Suno、可灵、视频等）的终态回调，result 为各平台对外的任务结果
+func EnqueueTask(task *model.Task, result any) {
+	data := &types.WebhookTaskData{
+		Platform:   task.Platform,
+		TaskID:     task.TaskID,
+		Action:     task.Action,
+		Status:     string(task.Status),
+		FailReason: task.FailReason,
+		SubmitTime: task.SubmitTime,
+		StartTime:  task.StartTime,
+		FinishTime: task.FinishTime,
+	}
+	if result != nil {
+		data.Result, _ = json.Marshal(result)
+	}
+
+	Enqueue(task.UserId, task.TokenID, task.NotifyHook, data)
+}
+
+// EnqueueMidjourney Midjourney 任务的终态回调
+func EnqueueMidjourney(task *model.Midjourney) {
+	result := map[string]any{
+		"prompt":     task.Prompt,
+		"promptEn":   task.PromptEn,
+		"imageUrl":   task.ImageUrl,
+		"progress":   task.Progress,
+		"buttons":    rawJSON(task.Buttons),
+		"properties": rawJSON(task.Properties),
+	}
+	data := &types.WebhookTaskData{
+		Platform:   "midjourney",
+		TaskID:     task.MjId,
+		Action:     task.Action,
+		Status:     task.Status,
+		FailReason: task.FailReason,
+		SubmitTime: task.SubmitTime,
+		StartTime:  task.StartTime,
+		FinishTime: task.FinishTime,
+	}
+	data.Result, _ = json.Marshal(result)
+
+	Enqueue(task.UserId, task.TokenID, task.NotifyHook, data)
+}
+
+// rawJSON 原样嵌入已序列化的字段，空值或非法 JSON 按 null 处理
+func rawJSON(raw string) json.RawMessage {
+	if !json.Valid([]byte(raw)) {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(raw)
+}
+
+// Sign 按 Standard Webhooks 规范签名：HMAC-SHA256(secret, "{id}.{timestamp}.{body}")，base64 编码后以 v1, 为前缀
+func Sign(secret, eventID string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(eventID + "." + strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "v1," + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,176 @@
+package webhook
+
+import (
+	"bytes"
+	"done-hub/common"
+	"done-hub/common/config"
+	"done-hub/common/logger"
+	"done-hub/common/utils"
+	"done-hub/model"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// maxAttempts 超过后不再自动重试，只能由管理员手动重投
+	maxAttempts = 10
+	// 第 n 次失败后等待 baseBackoff * 2^(n-1)，最长 maxBackoff
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 6 * time.Hour
+
+	deliveryTimeout = 10 * time.Second
+	// leaseDuration 领取后的租约，需大于单次投递超时
+	leaseDuration = time.Minute
+	concurrency   = 8
+	// maxErrorLength 记录到投递日志的响应内容上限
+	maxErrorLength = 512
+)
+
+var (
+	workerSignal = make(chan struct{}, 1)
+	// httpClient 不走环境代理，直连时才能由 dialControl 检查实际连接的地址，重定向产生的新连接同样会检查
+	httpClient = &http.Client{
+		Timeout: deliveryTimeout,
+		Transport: &http.Transport{
+			DialContext: (&net.Dialer{
+				Timeout: deliveryTimeout,
+				Control: dialControl,
+			}).DialContext,
+			MaxIdleConnsPerHost: concurrency,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+)
+
+// InitWorker 启动回调投递 worker。发件箱在数据库中，只在主节点消费；
+// 从节点登记的投递由主节点按轮询间隔领取。
+func InitWorker() {
+	if !config.IsMasterNode {
+		return
+	}
+
+	common.SafeGoroutine(worker)
+	Activate()
+}
+
+// Activate 唤醒 worker 立即投递新登记的回调
+func Activate() {
+	select {
+	case workerSignal <- struct{}{}:
+	default:
+	}
+}
+
+func worker() {
+	for {
+		deliveries, err := model.GetDueWebhookDeliveries(100)
+		if err != nil {
+			logger.SysError(fmt.Sprintf("webhook get due deliveries error: %s", err.Error()))
+		}
+
+		if len(deliveries) == 0 {
+			select {
+			case <-workerSignal:
+			case <-time.After(10 * time.Second):
+			}
+			continue
+		}
+
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, concurrency)
+		for _, delivery := range deliveries {
+			claimed, err := model.ClaimWebhookDelivery(delivery, time.Now().Add(leaseDuration).Unix())
+			if err != nil || !claimed {
+				continue
+			}
+
+			sem <- struct{}{}
+			wg.Add(1)
+			common.SafeGoroutine(func() {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				deliver(delivery)
+			})
+		}
+		wg.Wait()
+	}
+}
+
+// deliver 投递一次并记录结果，2xx 视为成功，其余按指数退避重新排期
+func deliver(delivery *model.WebhookDelivery) {
+	statusCode, deliverErr := send(delivery)
+	attempts := delivery.Attempts + 1
+
+	params := map[string]any{
+		"attempts":         attempts,
+		"last_status_code": statusCode,
+		"last_error":       "",
+	}
+	switch {
+	case deliverErr == nil:
+		params["status"] = model.WebhookDeliveryStatusSuccess
+		params["delivered_at"] = utils.GetTimestamp()
+	case attempts >= maxAttempts:
+		params["status"] = model.WebhookDeliveryStatusFailed
+		params["last_error"] = deliverErr.Error()
+	default:
+		params["next_attempt_at"] = time.Now().Add(backoff(attempts)).Unix()
+		params["last_error"] = deliverErr.Error()
+	}
+
+	if err := delivery.UpdateResult(params); err != nil {
+		logger.SysError(fmt.Sprintf("webhook update delivery #%d error: %s", delivery.ID, err.Error()))
+	}
+}
+
+func send(delivery *model.WebhookDelivery) (int, error) {
+	setting, err := model.GetTokenWebhookSetting(delivery.TokenID)
+	if err != nil {
+		return 0, fmt.Errorf("get token setting failed: %w", err)
+	}
+
+	if err = ValidateURL(delivery.URL); err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Done-Hub-Webhook")
+	req.Header.Set("Webhook-Id", delivery.EventID)
+	req.Header.Set("Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("Webhook-Signature", Sign(setting.Secret, delivery.EventID, timestamp, body))
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+		return resp.StatusCode, fmt.Errorf("status code %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	return resp.StatusCode, nil
+}
+
+func backoff(attempts int) time.Duration {
+	delay := baseBackoff << (attempts - 1)
+	if delay <= 0 || delay > maxBackoff {
+		return maxBackoff
+	}
+	return delay
+}
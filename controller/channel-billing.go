@@ -1,14 +1,19 @@
 package controller
 
 import (
+	"done-hub/common"
 	"done-hub/common/config"
+	"done-hub/common/logger"
+	"done-hub/common/notify"
 	"done-hub/model"
 	"done-hub/providers"
 	providersBase "done-hub/providers/base"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -57,7 +62,7 @@ func updateChannelBalance(channel *model.Channel) (float64, error) {
 
 	req.Header.Set("Content-Type", "application/json")
 
-	provider := providers.GetProvider(channel, c)
+	provider := providers.GetProvider(model.ChannelWithKey(channel, 0), c)
 	if provider == nil {
 		return 0, errors.New("provider not found")
 	}
@@ -88,7 +93,7 @@ func UpdateChannelBalance(c *gin.Context) {
 		})
 		return
 	}
-	balance, err := updateChannelBalance(channel)
+	balance, err := refreshChannelBalance(channel)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -103,6 +108,93 @@ func UpdateChannelBalance(c *gin.Context) {
 	})
 }
 
+// refreshChannelBalance 更新渠道余额并根据余额变化处理渠道：余额耗尽时禁用，余额恢复后重新启用因余额不足被禁用的渠道，
+// 首次低于提醒阈值时发送通知
+func refreshChannelBalance(channel *model.Channel) (float64, error) {
+	// 更新余额会改写 channel 中的余额，先记下上一次的余额
+	hasPrevious := channel.BalanceUpdatedTime > 0
+	previous := channel.Balance
+
+	balance, err := updateChannelBalance(channel)
+	if err != nil {
+		return 0, err
+	}
+	checkChannelBalance(channel, balance, previous, hasPrevious)
+
+	return balance, nil
+}
+
+func checkChannelBalance(channel *model.Channel, balance, previous float64, hasPrevious bool) {
+	if balance <= 0 {
+		if channel.Status == config.ChannelStatusEnabled {
+			DisableChannel(channel.Id, channel.Name, model.ChannelBalanceDisabledReason, true)
+		}
+		return
+	}
+
+	// 只重新启用余额监控自己禁用的渠道，因其他原因自动禁用的渠道保持禁用
+	if channel.Status == config.ChannelStatusAutoDisabled && channel.StatusReason == model.ChannelBalanceDisabledReason {
+		EnableChannel(channel.Id, channel.Name, true)
+	}
+
+	threshold := channel.BalanceThreshold
+	if threshold > 0 && balance < threshold && (!hasPrevious || previous >= threshold) {
+		subject := fmt.Sprintf("通道「%s」（#%d）余额不足", channel.Name, channel.Id)
+		content := fmt.Sprintf("通道「%s」（#%d）余额为 %.4f，低于提醒阈值 %.4f", channel.Name, channel.Id, balance, threshold)
+		notify.Send(subject, content)
+	}
+}
+
+// 设置了余额检查间隔的渠道上次尝试更新余额的时间，更新失败时也记录，避免每分钟重试
+var channelBalanceChecked sync.Map
+
+// RefreshDueChannelsBalance 由定时任务每分钟执行，更新到达检查间隔的渠道余额
+func RefreshDueChannelsBalance() {
+	channels, err := model.GetBalanceMonitoredChannels()
+	if err != nil {
+		logger.SysError("failed to get balance monitored channels: " + err.Error())
+		return
+	}
+
+	now := time.Now().Unix()
+	for _, channel := range channels {
+		lastChecked := channel.BalanceUpdatedTime
+		if value, ok := channelBalanceChecked.Load(channel.Id); ok && value.(int64) > lastChecked {
+			lastChecked = value.(int64)
+		}
+		if now-lastChecked < int64(channel.BalanceCheckInterval)*60 {
+			continue
+		}
+		channelBalanceChecked.Store(channel.Id, now)
+
+		if _, err = refreshChannelBalance(channel); err != nil {
+			logger.SysError(fmt.Sprintf("failed to update channel %d balance: %s", channel.Id, err.Error()))
+		}
+		time.Sleep(config.RequestInterval)
+	}
+}
+
+// GetChannelBalanceHistory 渠道最近一段时间的余额记录、每日消耗与预计用完时间
+func GetChannelBalanceHistory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	days, _ := strconv.Atoi(c.Query("days"))
+
+	trend, err := model.GetChannelBalanceTrend(id, days)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    trend,
+	})
+}
+
 func updateAllChannelsBalance() error {
 	channels, err := model.GetAllChannels()
 	if err != nil {
@@ -116,14 +208,8 @@ func updateAllChannelsBalance() error {
 		if channel.Type != config.ChannelTypeOpenAI && channel.Type != config.ChannelTypeCustom {
 			continue
 		}
-		balance, err := updateChannelBalance(channel)
-		if err != nil {
+		if _, err = refreshChannelBalance(channel); err != nil {
 			continue
-		} else {
-			// err is nil & balance <= 0 means quota is used up
-			if balance <= 0 {
-				DisableChannel(channel.Id, channel.Name, "余额不足", true)
-			}
 		}
 		time.Sleep(config.RequestInterval)
 	}
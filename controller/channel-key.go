@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"done-hub/common"
+	"done-hub/common/config"
+	"done-hub/model"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type importChannelKeysRequest struct {
+	Keys   string `json:"keys" binding:"required"`
+	Remark string `json:"remark"`
+}
+
+type updateChannelKeyRequest struct {
+	Status int    `json:"status" binding:"required"`
+	Remark string `json:"remark"`
+}
+
+func GetChannelKeys(c *gin.Context) {
+	channelId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	keys, err := model.GetChannelKeys(channelId)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	// 列表中只展示脱敏后的密钥，完整密钥通过导出获取
+	for _, key := range keys {
+		key.Key = model.MaskChannelKey(key.Key)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    keys,
+	})
+}
+
+func ImportChannelKeys(c *gin.Context) {
+	channelId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	var request importChannelKeysRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if _, err := model.GetChannelById(channelId); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	count, err := model.ImportChannelKeys(channelId, request.Keys, request.Remark)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    count,
+	})
+}
+
+// ExportChannelKeys 以纯文本下载密钥，每行一个；enabled=true 时只导出启用的密钥
+func ExportChannelKeys(c *gin.Context) {
+	channelId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	content, err := model.ExportChannelKeys(channelId, c.Query("enabled") == "true")
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=channel_%d_keys.txt", channelId))
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(content))
+}
+
+func UpdateChannelKey(c *gin.Context) {
+	channelId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	keyId, err := strconv.Atoi(c.Param("key_id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	var request updateChannelKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	if request.Status != config.ChannelStatusEnabled && request.Status != config.ChannelStatusManuallyDisabled {
+		common.APIRespondWithError(c, http.StatusOK, fmt.Errorf("无效的状态"))
+		return
+	}
+
+	key, err := model.GetChannelKeyById(channelId, keyId)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	key.Status = request.Status
+	key.Remark = request.Remark
+	if err := key.Update(); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+func DeleteChannelKey(c *gin.Context) {
+	channelId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	keyId, err := strconv.Atoi(c.Param("key_id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if err := model.DeleteChannelKey(channelId, keyId); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+func DeleteDisabledChannelKeys(c *gin.Context) {
+	channelId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	rows, err := model.DeleteDisabledChannelKeys(channelId)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    rows,
+	})
+}
@@ -42,7 +42,7 @@ func GetModelList(c *gin.Context) {
 		}
 	}
 
-	provider := providers.GetProvider(channel, c)
+	provider := providers.GetProvider(model.ChannelWithKey(channel, 0), c)
 	if provider == nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -78,7 +78,7 @@ func testChannel(channel *model.Channel, testModel string) (openaiErr *types.Ope
 	c.Request = req
 
 	// 获取并验证provider
-	provider := providers.GetProvider(channel, c)
+	provider := providers.GetProvider(model.ChannelWithKey(channel, 0), c)
 	if provider == nil {
 		return nil, errors.New("channel not implemented")
 	}
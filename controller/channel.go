@@ -72,7 +72,18 @@ func AddChannel(c *gin.Context) {
 		})
 		return
 	}
+	if err = channel.ValidateSettings(); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
 	channel.CreatedTime = utils.GetTimestamp()
+	if channel.IsKeyPool() {
+		addKeyPoolChannel(c, channel)
+		return
+	}
 	keys := strings.Split(channel.Key, "\n")
 
 	baseUrls := []string{}
@@ -112,6 +123,36 @@ func AddChannel(c *gin.Context) {
 	})
 }
 
+// addKeyPoolChannel 启用密钥池时多行密钥全部导入同一个渠道的密钥池，第一个密钥同时作为渠道自身的密钥
+func addKeyPoolChannel(c *gin.Context, channel model.Channel) {
+	keys := channel.Key
+	for _, key := range strings.Split(keys, "\n") {
+		if key = strings.TrimSpace(key); key != "" {
+			channel.Key = key
+			break
+		}
+	}
+	if channel.BaseURL != nil {
+		baseUrl := strings.TrimSpace(strings.Split(*channel.BaseURL, "\n")[0])
+		channel.BaseURL = &baseUrl
+	}
+
+	channels := []model.Channel{channel}
+	if err := model.BatchInsertChannels(channels); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	if _, err := model.ImportChannelKeys(channels[0].Id, keys, ""); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
 func DeleteChannel(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
 	channel := model.Channel{Id: id}
@@ -184,6 +225,13 @@ func UpdateChannel(c *gin.Context) {
 			return
 		}
 	}
+	if err = channel.ValidateSettings(); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
 	if channel.Models == "" {
 		err = channel.Update(false)
 	} else {
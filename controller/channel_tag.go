@@ -80,6 +80,10 @@ func UpdateChannelsTag(c *gin.Context) {
 		common.APIRespondWithError(c, http.StatusOK, err)
 		return
 	}
+	if err = channel.ValidateSettings(); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
 
 	err = model.UpdateChannelsTag(tag, &channel)
 	if err != nil {
@@ -70,7 +70,7 @@ func CreateCheckChannel(channelId int, models string) (*CheckChannel, error) {
 	c, _ := gin.CreateTestContext(w)
 	c.Request = req
 
-	provider := providers.GetProvider(channel, c)
+	provider := providers.GetProvider(model.ChannelWithKey(channel, 0), c)
 	if provider == nil {
 		return nil, errors.New("channel not implemented")
 	}
@@ -175,8 +175,8 @@ func DisableChannel(channelId int, channelName string, reason string, sendNotify
 			return nil, nil
 		}
 
-		// 执行禁用操作
-		model.UpdateChannelStatusById(channelId, config.ChannelStatusAutoDisabled)
+		// 执行禁用操作，记录原因
+		model.UpdateChannelStatusWithReason(channelId, config.ChannelStatusAutoDisabled, reason)
 
 		// 发送通知：受全局开关控制，并通过 SETNX 在多节点间去重。
 		// reason 可能来自上游 err.Message,可能包含 URL/IP/api_key 等敏感串,
@@ -9,6 +9,7 @@ import (
 	"done-hub/common"
 	"done-hub/common/logger"
 	"done-hub/common/requester"
+	"done-hub/common/webhook"
 	"done-hub/model"
 	provider "done-hub/providers/midjourney"
 	"encoding/json"
@@ -126,9 +127,17 @@ func UpdateMidjourneyTaskBulk() {
 				continue
 			}
 
-			err := MjTaskHandler(midjourneyChannel, taskIds, taskM)
-			if err != nil {
-				logger.LogError(ctx, fmt.Sprintf("MjTaskHandler error: %v", err))
+			// 多密钥渠道按提交任务时使用的密钥分别查询
+			keyTaskIds := make(map[int][]string)
+			for _, taskId := range taskIds {
+				keyId := taskM[taskId].ChannelKeyId
+				keyTaskIds[keyId] = append(keyTaskIds[keyId], taskId)
+			}
+			for keyId, ids := range keyTaskIds {
+				err := MjTaskHandler(model.ChannelWithKey(midjourneyChannel, keyId), ids, taskM)
+				if err != nil {
+					logger.LogError(ctx, fmt.Sprintf("MjTaskHandler error: %v", err))
+				}
 			}
 		}
 		time.Sleep(time.Duration(15) * time.Second)
@@ -225,6 +234,8 @@ func MjTaskHandler(midjourneyChannel *model.Channel, taskIds []string, taskM map
 		err = task.Update()
 		if err != nil {
 			logger.LogError(ctx, "UpdateMidjourneyTask task error: "+err.Error())
+		} else if task.Progress == "100%" {
+			webhook.EnqueueMidjourney(task)
 		}
 	}
 
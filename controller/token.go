@@ -4,6 +4,7 @@ import (
 	"done-hub/common"
 	"done-hub/common/config"
 	"done-hub/common/utils"
+	"done-hub/common/webhook"
 	"done-hub/model"
 	"errors"
 	"net/http"
@@ -328,6 +329,11 @@ func UpdateToken(c *gin.Context) {
 		}
 		// 可信用户：直接使用前端传入的值（包括空值，用于清除 BillingTag）
 
+		// 未回传回调签名密钥时沿用原值，避免客户端验签失效
+		if newSetting.Webhook.Secret == "" {
+			newSetting.Webhook.Secret = oldSetting.Webhook.Secret
+		}
+
 		cleanToken.Setting.Set(newSetting)
 	}
 	err = cleanToken.Update()
@@ -474,6 +480,9 @@ func UpdateTokenByAdmin(c *gin.Context) {
 		}
 		cleanToken.Group = token.Group
 		cleanToken.BackupGroup = token.BackupGroup
+		if newSetting.Webhook.Secret == "" {
+			newSetting.Webhook.Secret = cleanToken.Setting.Data().Webhook.Secret
+		}
 		cleanToken.Setting.Set(newSetting)
 
 		// 管理员可以转移token给其他用户
@@ -546,5 +555,11 @@ func validateTokenSetting(setting *model.TokenSetting) error {
 		}
 	}
 
+	if setting.Webhook.URL != "" {
+		if err := webhook.ValidateURL(setting.Webhook.URL); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
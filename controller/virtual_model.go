@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"done-hub/common"
+	"done-hub/model"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetVirtualModels(c *gin.Context) {
+	virtualModels, err := model.GetAllVirtualModels()
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    virtualModels,
+	})
+}
+
+func GetVirtualModel(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	virtualModel, err := model.GetVirtualModelById(id)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    virtualModel,
+	})
+}
+
+func AddVirtualModel(c *gin.Context) {
+	virtualModel := model.VirtualModel{}
+	if err := c.ShouldBindJSON(&virtualModel); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if err := virtualModel.Validate(); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if err := virtualModel.Create(); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+func UpdateVirtualModel(c *gin.Context) {
+	virtualModel := model.VirtualModel{}
+	if err := c.ShouldBindJSON(&virtualModel); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if err := virtualModel.Validate(); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if err := virtualModel.Update(); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+func DeleteVirtualModel(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	virtualModel, err := model.GetVirtualModelById(id)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if err := virtualModel.Delete(); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"done-hub/common"
+	"done-hub/common/webhook"
+	"done-hub/model"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetWebhookDeliveries 回调投递日志，列表不返回请求体
+func GetWebhookDeliveries(c *gin.Context) {
+	var params model.WebhookDeliveryQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	deliveries, err := model.GetWebhookDeliveries(&params)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    deliveries,
+	})
+}
+
+func GetWebhookDelivery(c *gin.Context) {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	delivery, err := model.GetWebhookDeliveryById(id)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	if delivery == nil {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("投递记录不存在"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    delivery,
+	})
+}
+
+// RedeliverWebhook 手动重投，重新排入发件箱由 worker 投递
+func RedeliverWebhook(c *gin.Context) {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err := model.RedeliverWebhook(id); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	webhook.Activate()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"done-hub/common/config"
 	"done-hub/common/logger"
 	"done-hub/common/scheduler"
+	"done-hub/controller"
 	"done-hub/model"
 	"fmt"
 	"github.com/spf13/viper"
@@ -92,6 +93,92 @@ func InitCron() {
 		return
 	}
 
+	// 每分钟检查渠道花费预算：发送提醒、暂停超出预算的渠道，周期重置后清零花费并恢复渠道
+	err = scheduler.Manager.AddJob(
+		"channel_budget_check",
+		gocron.CronJob("* * * * *", false),
+		gocron.NewTask(func() {
+			model.CheckChannelBudgets()
+		}),
+	)
+	if err != nil {
+		logger.SysError("Cron job error: " + err.Error())
+		return
+	}
+
+	// 每分钟更新到达检查间隔的渠道余额
+	err = scheduler.Manager.AddJob(
+		"channel_balance_refresh",
+		gocron.CronJob("* * * * *", false),
+		gocron.NewTask(func() {
+			controller.RefreshDueChannelsBalance()
+		}),
+	)
+	if err != nil {
+		logger.SysError("Cron job error: " + err.Error())
+		return
+	}
+
+	// 每天凌晨 3:30 清理超过保存天数的渠道余额记录
+	err = scheduler.Manager.AddJob(
+		"channel_balance_history_delete",
+		gocron.DailyJob(1, gocron.NewAtTimes(gocron.NewAtTime(3, 30, 0))),
+		gocron.NewTask(func() {
+			deleted, err := model.DeleteOldChannelBalanceHistories()
+			if err != nil {
+				logger.SysError(fmt.Sprintf("[cron] 渠道余额记录清理失败: %v", err))
+				return
+			}
+			if deleted > 0 {
+				logger.SysLog(fmt.Sprintf("[cron] 渠道余额记录清理完成，共删除 %d 条", deleted))
+			}
+		}),
+	)
+	if err != nil {
+		logger.SysError("Cron job error: " + err.Error())
+		return
+	}
+
+	// 每小时清理过期的 Files API 文件
+	err = scheduler.Manager.AddJob(
+		"file_expire_delete",
+		gocron.DurationJob(time.Hour),
+		gocron.NewTask(func() {
+			deleted, err := model.DeleteExpiredFiles()
+			if err != nil {
+				logger.SysError(fmt.Sprintf("[cron] 过期文件清理失败，已删 %d 个: %v", deleted, err))
+				return
+			}
+			if deleted > 0 {
+				logger.SysLog(fmt.Sprintf("[cron] 过期文件清理完成，共删除 %d 个", deleted))
+			}
+		}),
+	)
+	if err != nil {
+		logger.SysError("Cron job error: " + err.Error())
+		return
+	}
+
+	// 每小时清理超过保存期限的 Responses 响应
+	err = scheduler.Manager.AddJob(
+		"response_expire_delete",
+		gocron.DurationJob(time.Hour),
+		gocron.NewTask(func() {
+			deleted, err := model.DeleteExpiredResponses()
+			if err != nil {
+				logger.SysError(fmt.Sprintf("[cron] 过期响应清理失败: %v", err))
+				return
+			}
+			if deleted > 0 {
+				logger.SysLog(fmt.Sprintf("[cron] 过期响应清理完成，共删除 %d 条", deleted))
+			}
+		}),
+	)
+	if err != nil {
+		logger.SysError("Cron job error: " + err.Error())
+		return
+	}
+
 	// 开启自动更新 并且设置了有效自动更新时间 同时自动更新模式不是system 则会从服务器拉取最新价格表
 	autoPriceUpdatesInterval := viper.GetInt("auto_price_updates_interval")
 	autoPriceUpdates := viper.GetBool("auto_price_updates")
@@ -14,6 +14,7 @@ import (
 	"done-hub/common/search"
 	"done-hub/common/storage"
 	"done-hub/common/telegram"
+	"done-hub/common/webhook"
 	"done-hub/controller"
 	"done-hub/cron"
 	"done-hub/middleware"
@@ -86,6 +87,7 @@ func main() {
 
 	initMemoryCache()
 	initSync()
+	model.InitClusterSync()
 
 	common.InitTokenEncoders()
 	requester.InitHttpClient()
@@ -95,6 +97,7 @@ func main() {
 
 	controller.InitMidjourneyTask()
 	task.InitTask()
+	webhook.InitWorker()
 	notify.InitNotifier()
 	cron.InitCron()
 	storage.InitStorage()
@@ -244,10 +247,7 @@ func SyncChannelCache(frequency int) {
 	for {
 		time.Sleep(time.Duration(frequency) * time.Second)
 		logger.SysLog("syncing channels from database")
-		model.ChannelGroup.Load()
-		model.PricingInstance.Init()
-		model.ModelOwnedBysInstance.Load()
-		model.GlobalUserGroupRatio.Load()
+		model.ReloadCaches()
 	}
 }
 
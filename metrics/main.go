@@ -14,6 +14,9 @@ var (
 	httpRequestDuration *prometheus.HistogramVec
 	providerCounter     *prometheus.CounterVec
 	panicCounter        *prometheus.CounterVec
+
+	circuitBreakerState       *prometheus.GaugeVec
+	circuitBreakerTransitions *prometheus.CounterVec
 )
 
 func init() {
@@ -52,6 +55,21 @@ func init() {
 		[]string{"type"},
 	)
 
+	// 4. 监控渠道熔断器
+	circuitBreakerState = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "channel_circuit_breaker_state",
+			Help: "Circuit breaker state of channel and model (0 closed, 1 half-open, 2 open).",
+		},
+		[]string{"channel_id", "model"},
+	)
+	circuitBreakerTransitions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "channel_circuit_breaker_transitions_total",
+			Help: "Total number of circuit breaker state transitions.",
+		},
+		[]string{"channel_id", "model", "state"},
+	)
 }
 
 // 记录 HTTP 请求
@@ -94,6 +112,19 @@ func RecordProvider(c *gin.Context, statusCode int) {
 	})
 }
 
+// 记录熔断器状态变化
+func RecordCircuitBreakerState(channelId, model string, state int, stateName string) {
+	SafelyRecordMetric(func() {
+		circuitBreakerState.WithLabelValues(channelId, model).Set(float64(state))
+		circuitBreakerTransitions.WithLabelValues(channelId, model, stateName).Inc()
+	})
+}
+
+// 熔断器回收后删除对应的状态指标
+func DeleteCircuitBreakerState(channelId, model string) {
+	circuitBreakerState.DeleteLabelValues(channelId, model)
+}
+
 // 记录 panic
 func RecordPanic(panicType string) {
 	panicCounter.WithLabelValues(panicType).Inc()
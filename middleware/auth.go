@@ -157,13 +157,7 @@ func tokenAuth(c *gin.Context, key string) {
 		return
 	}
 
-	c.Set("id", token.UserId)
-	c.Set("token_id", token.Id)
-	c.Set("token_name", token.Name)
-	c.Set("token_group", token.Group)
-	c.Set("token_backup_group", token.BackupGroup)
-	c.Set("token_unlimited_quota", token.UnlimitedQuota)
-	c.Set("token_setting", utils.GetPointer(token.Setting.Data()))
+	SetTokenContext(c, token)
 	if err := checkLimitIP(c); err != nil {
 		abortWithMessage(c, http.StatusForbidden, err.Error())
 		return
@@ -192,6 +186,18 @@ func tokenAuth(c *gin.Context, key string) {
 	c.Next()
 }
 
+// SetTokenContext 把令牌信息写入上下文。批处理等在网关内重放请求的场景没有经过
+// tokenAuth，也通过它还原令牌上下文。
+func SetTokenContext(c *gin.Context, token *model.Token) {
+	c.Set("id", token.UserId)
+	c.Set("token_id", token.Id)
+	c.Set("token_name", token.Name)
+	c.Set("token_group", token.Group)
+	c.Set("token_backup_group", token.BackupGroup)
+	c.Set("token_unlimited_quota", token.UnlimitedQuota)
+	c.Set("token_setting", utils.GetPointer(token.Setting.Data()))
+}
+
 // 检测是否IP白名单
 func checkLimitIP(c *gin.Context) (error error) {
 	// 从context中获取token设置
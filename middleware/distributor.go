@@ -99,5 +99,7 @@ func Distribute() func(c *gin.Context) {
 			return
 		}
 		c.Next()
+		// 兜底释放未上报结果的路径（如 Midjourney、实时会话）占用的渠道并发名额
+		model.ReleaseChannelLease(c)
 	}
 }
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"done-hub/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+func setupDistributeTest(t *testing.T) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: gormLogger.Default.LogMode(gormLogger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err = db.AutoMigrate(&model.User{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if err = db.Create(&model.User{Id: 1, Username: "distribute", Group: "default"}).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	savedDB := model.DB
+	savedGroups := model.GlobalUserGroupRatio.UserGroup
+	t.Cleanup(func() {
+		model.DB = savedDB
+		model.GlobalUserGroupRatio.UserGroup = savedGroups
+		sqlDB.Close()
+	})
+	model.DB = db
+	model.GlobalUserGroupRatio.UserGroup = map[string]*model.UserGroup{
+		"default": {Symbol: "default", Ratio: 1},
+	}
+}
+
+// TestDistributeReleasesChannelLease 测试不上报结果的路径（如 Midjourney、实时会话）在请求结束后由 Distribute 释放渠道并发名额
+func TestDistributeReleasesChannelLease(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupDistributeTest(t)
+
+	rateLimit := datatypes.NewJSONType(model.ChannelRateLimit{ChannelLimit: model.ChannelLimit{Concurrency: 1}})
+	channel := &model.Channel{Id: 9001, Name: "distribute", RateLimit: &rateLimit}
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{name: "Midjourney 提交", path: "/mj/submit/imagine"},
+		{name: "实时会话", path: "/v1/realtime"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := gin.New()
+			engine.Use(func(c *gin.Context) { c.Set("id", 1) }, Distribute())
+			engine.Any(tt.path, func(c *gin.Context) {
+				if !model.ChannelLimits.Acquire(channel, "gpt-4o", c) {
+					c.Status(http.StatusTooManyRequests)
+					return
+				}
+				// 处理过程中名额保持占用，且处理结束时不主动释放
+				if !model.ChannelLimits.Saturated(channel, "gpt-4o") {
+					t.Error("处理请求时渠道应处于饱和状态")
+				}
+				c.Status(http.StatusOK)
+			})
+
+			recorder := httptest.NewRecorder()
+			engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, tt.path, nil))
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("状态码 = %d, 期望 %d", recorder.Code, http.StatusOK)
+			}
+			if model.ChannelLimits.Saturated(channel, "gpt-4o") {
+				t.Error("请求结束后渠道并发名额未释放")
+			}
+		})
+	}
+}
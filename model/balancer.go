@@ -9,7 +9,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"math/rand"
 	"sort"
 	"strings"
 	"sync"
@@ -44,6 +43,22 @@ type ChannelChoice struct {
 	Channel       *Channel
 	CooldownsTime int64
 	Disable       bool
+	// schedule 编译后的可用时间，为 nil 时不限制
+	schedule *channelScheduler
+	// modelMapping 解析后的模型映射，成本优先路由按映射后的模型取价格
+	modelMapping map[string]string
+	// budgetPaused 花费达到预算被暂停，不修改渠道状态
+	budgetPaused bool
+}
+
+// OffSchedule 当前时间不在渠道的可用时间窗口内
+func (c *ChannelChoice) OffSchedule() bool {
+	return c.schedule != nil && !c.schedule.Active(time.Now())
+}
+
+// Paused 不在可用时间窗口内，或花费达到预算被暂停
+func (c *ChannelChoice) Paused() bool {
+	return c.budgetPaused || c.OffSchedule()
 }
 
 type ChannelsChooser struct {
@@ -96,12 +111,18 @@ func (cc *ChannelsChooser) SetCooldowns(channelId int, modelName string) bool {
 	return cc.SetCooldownsWithDuration(channelId, modelName, int64(config.RetryCooldownSeconds))
 }
 
-// SetCooldownsWithDuration 设置指定渠道和模型的冷却时间（支持自定义冻结时长）
+// SetCooldownsWithDuration 设置指定渠道和模型的冷却时间（支持自定义冻结时长）。
+// 开启熔断器时改为打开熔断，durationSeconds 作为首次打开时长，到期后半开试探而不是直接恢复
 func (cc *ChannelsChooser) SetCooldownsWithDuration(channelId int, modelName string, durationSeconds int64) bool {
 	if channelId == 0 || modelName == "" || durationSeconds == 0 {
 		return false
 	}
 
+	if config.CircuitBreakerEnabled {
+		CircuitBreakers.Trip(channelId, modelName, durationSeconds)
+		return true
+	}
+
 	key := fmt.Sprintf("%d:%s", channelId, modelName)
 	nowTime := time.Now().Unix()
 	newCooldownTime := nowTime + durationSeconds
@@ -118,17 +139,38 @@ func (cc *ChannelsChooser) SetCooldownsWithDuration(channelId int, modelName str
 		}
 		// 冷却期已过，尝试更新为新的冷却时间
 		// 如果CompareAndSwap失败，说明其他线程已经更新了，这也是可以接受的
-		cc.Cooldowns.CompareAndSwap(key, existingCooldownTime, newCooldownTime)
+		if !cc.Cooldowns.CompareAndSwap(key, existingCooldownTime, newCooldownTime) {
+			return true
+		}
 	}
 
+	publishCooldown(channelId, modelName, newCooldownTime, 0)
 	return true
 }
 
+// applyCooldown 应用其他节点广播的冷却，只会延长不会缩短
+func (cc *ChannelsChooser) applyCooldown(channelId int, modelName string, until int64) {
+	key := fmt.Sprintf("%d:%s", channelId, modelName)
+	for {
+		actualValue, loaded := cc.Cooldowns.LoadOrStore(key, until)
+		if !loaded || actualValue.(int64) >= until {
+			return
+		}
+		if cc.Cooldowns.CompareAndSwap(key, actualValue, until) {
+			return
+		}
+	}
+}
+
 func (cc *ChannelsChooser) IsInCooldown(channelId int, modelName string) bool {
 	if channelId == 0 || modelName == "" {
 		return false
 	}
 
+	if config.CircuitBreakerEnabled {
+		return !CircuitBreakers.Available(channelId, modelName)
+	}
+
 	key := fmt.Sprintf("%d:%s", channelId, modelName)
 	nowTime := time.Now().Unix()
 
@@ -142,6 +184,10 @@ func (cc *ChannelsChooser) IsInCooldown(channelId int, modelName string) bool {
 }
 
 func (cc *ChannelsChooser) CleanupExpiredCooldowns() {
+	CircuitBreakers.Cleanup()
+	ChannelStats.Cleanup()
+	ChannelKeys.Cleanup()
+
 	now := time.Now().Unix()
 	cc.Cooldowns.Range(func(key, value interface{}) bool {
 		if now >= value.(int64) {
@@ -151,8 +197,16 @@ func (cc *ChannelsChooser) CleanupExpiredCooldowns() {
 	})
 }
 
-// ClearChannelCooldowns 清除指定渠道的所有冻结缓存
+// ClearChannelCooldowns 清除指定渠道的所有冻结缓存，并通知其他节点
 func (cc *ChannelsChooser) ClearChannelCooldowns(channelId int) {
+	cc.clearChannelCooldowns(channelId)
+	publishClear(channelId)
+}
+
+func (cc *ChannelsChooser) clearChannelCooldowns(channelId int) {
+	CircuitBreakers.ResetChannel(channelId)
+	ChannelStats.ResetChannel(channelId)
+
 	prefix := fmt.Sprintf("%d:", channelId)
 	cc.Cooldowns.Range(func(key, value interface{}) bool {
 		if strings.HasPrefix(key.(string), prefix) {
@@ -182,6 +236,17 @@ func (cc *ChannelsChooser) Enable(channelId int) {
 	cc.Channels[channelId].Disable = false
 }
 
+// SetBudgetPaused 设置渠道的预算暂停状态，渠道不在内存中时忽略，重新加载时从用量记录读取
+func (cc *ChannelsChooser) SetBudgetPaused(channelId int, paused bool) {
+	cc.Lock()
+	defer cc.Unlock()
+	if _, ok := cc.Channels[channelId]; !ok {
+		return
+	}
+
+	cc.Channels[channelId].budgetPaused = paused
+}
+
 func (cc *ChannelsChooser) ChangeStatus(channelId int, status bool) {
 	if status {
 		cc.Enable(channelId)
@@ -253,6 +318,11 @@ func (cc *ChannelsChooser) checkStickySession(channelIds []int, filters []Channe
 		}
 	}
 
+	// 熔断器半开且试探名额已满、渠道限额已满、密钥池中的密钥都在冷却、不在可用时间内或预算暂停时本次不走粘性渠道，保留映射
+	if mappedChoice.Paused() || !ChannelKeys.Available(mappedChoice.Channel) || !cc.allow(mappedChoice.Channel, modelName, ginContext) {
+		return nil
+	}
+
 	// 渠道可用，续期 TTL 并返回
 	ttl := 1 * time.Hour
 	renewalThresholdMinutes := 0 // 默认 0（不续期），与 code-relay-demo 保持一致
@@ -570,14 +640,15 @@ func extractMessageContent(msgMap map[string]interface{}) string {
 	return ""
 }
 
-func (cc *ChannelsChooser) balancer(channelIds []int, filters []ChannelsFilterFunc, modelName string, ginContext interface{}) *Channel {
+// balancer 在同一优先级内选择渠道，strategy 为分组的路由策略
+func (cc *ChannelsChooser) balancer(channelIds []int, filters []ChannelsFilterFunc, modelName, strategy string, ginContext interface{}) *Channel {
 	// 1. 检查粘性 session（优先级最高）
 	stickyChannel := cc.checkStickySession(channelIds, filters, modelName, ginContext)
 	if stickyChannel != nil {
 		return stickyChannel
 	}
 
-	// 2. 按权重选择渠道
+	// 2. 按权重或延迟选择渠道
 	totalWeight := 0
 
 	validChannels := make([]*ChannelChoice, 0, len(channelIds))
@@ -591,6 +662,21 @@ func (cc *ChannelsChooser) balancer(channelIds []int, filters []ChannelsFilterFu
 			continue
 		}
 
+		// 不在可用时间窗口内或预算暂停的渠道跳过
+		if choice.Paused() {
+			continue
+		}
+
+		// 达到并发/RPM/TPM 上限的渠道直接跳过，避免打到上游触发 429 再冷却
+		if ChannelLimits.Saturated(choice.Channel, modelName) {
+			continue
+		}
+
+		// 密钥池中的密钥都在冷却的渠道跳过
+		if !ChannelKeys.Available(choice.Channel) {
+			continue
+		}
+
 		isSkip := false
 		for _, filter := range filters {
 			if filter(channelId, choice) {
@@ -611,29 +697,56 @@ func (cc *ChannelsChooser) balancer(channelIds []int, filters []ChannelsFilterFu
 		return nil
 	}
 
-	if len(validChannels) == 1 {
-		selectedChannel := validChannels[0].Channel
-		// 建立新的粘性 session 映射
-		cc.createStickySession(selectedChannel, ginContext)
-		return selectedChannel
+	var defaultCost float64
+	if strategy == RoutingStrategyCost {
+		defaultCost = expectedCost(validChannels, modelName)
 	}
 
-	choiceWeight := rand.Intn(totalWeight)
-	for _, choice := range validChannels {
-		weight := int(*choice.Channel.Weight)
-		choiceWeight -= weight
-		if choiceWeight < 0 {
-			selectedChannel := choice.Channel
+	// 熔断器半开时只放行有限的试探请求，选中的渠道名额已满则剔除后重新选择
+	for len(validChannels) > 0 {
+		var index int
+		switch strategy {
+		case RoutingStrategyLatency:
+			index = pickByLatency(validChannels, totalWeight, modelName)
+		case RoutingStrategyCost:
+			index = pickByCost(validChannels, modelName)
+		default:
+			index = pickByWeight(validChannels, totalWeight)
+		}
+
+		selectedChannel := validChannels[index].Channel
+		if cc.allow(selectedChannel, modelName, ginContext) {
+			if strategy == RoutingStrategyCost {
+				setCostRoutingChoice(ginContext, newCostRoutingChoice(validChannels[index], modelName, defaultCost))
+			}
 			// 建立新的粘性 session 映射
 			cc.createStickySession(selectedChannel, ginContext)
 			return selectedChannel
 		}
+
+		totalWeight -= int(*selectedChannel.Weight)
+		validChannels = append(validChannels[:index], validChannels[index+1:]...)
 	}
 
 	return nil
 }
 
-// GetMatchedModelName 获取匹配到的实际模型名称
+// allow 选中渠道后占用限额名额并向熔断器申请放行
+func (cc *ChannelsChooser) allow(channel *Channel, modelName string, ginContext interface{}) bool {
+	if !ChannelLimits.Acquire(channel, modelName, ginContext) {
+		return false
+	}
+	if config.CircuitBreakerEnabled && !CircuitBreakers.Allow(channel.Id, modelName) {
+		if c, ok := ginContext.(*gin.Context); ok {
+			ReleaseChannelLease(c)
+		}
+		return false
+	}
+	return true
+}
+
+// GetMatchedModelName 获取匹配到的实际模型名称。
+// 虚拟模型解析为回退链中第一个在该分组有渠道的步骤模型，供不支持逐步回退的入口直接使用
 func (cc *ChannelsChooser) GetMatchedModelName(group, modelName string) (string, error) {
 	cc.RLock()
 	defer cc.RUnlock()
@@ -641,9 +754,27 @@ func (cc *ChannelsChooser) GetMatchedModelName(group, modelName string) (string,
 		return "", fmt.Errorf(ErrNoAvailableChannelForModel, GlobalUserGroupRatio.GetDisplayName(group), modelName)
 	}
 
+	if matchModel := cc.matchModelName(group, modelName); matchModel != "" {
+		return matchModel, nil
+	}
+
+	if virtualModel := VirtualModels.Get(modelName); virtualModel != nil {
+		for _, step := range virtualModel.Steps.Data() {
+			if matchModel := cc.matchModelName(group, step.Model); matchModel != "" {
+				return matchModel, nil
+			}
+		}
+	}
+
+	message := fmt.Sprintf(ErrNoAvailableChannelForModel, GlobalUserGroupRatio.GetDisplayName(group), modelName)
+	return "", errors.New(message)
+}
+
+// matchModelName 按精确、大小写不敏感、通配符的顺序匹配分组中的模型，未匹配时返回空字符串，调用方需持有读锁
+func (cc *ChannelsChooser) matchModelName(group, modelName string) string {
 	// 如果直接匹配到了，返回原始模型名称
 	if _, ok := cc.Rule[group][modelName]; ok {
-		return modelName, nil
+		return modelName
 	}
 
 	var matchModel string
@@ -668,12 +799,7 @@ func (cc *ChannelsChooser) GetMatchedModelName(group, modelName string) (string,
 		matchModel = utils.GetModelsWithMatch(&cc.Match, modelName)
 	}
 
-	if matchModel == "" {
-		message := fmt.Sprintf(ErrNoAvailableChannelForModel, GlobalUserGroupRatio.GetDisplayName(group), modelName)
-		return "", errors.New(message)
-	}
-
-	return matchModel, nil
+	return matchModel
 }
 
 func (cc *ChannelsChooser) Next(group, modelName string, filters ...ChannelsFilterFunc) (*Channel, error) {
@@ -685,28 +811,7 @@ func (cc *ChannelsChooser) Next(group, modelName string, filters ...ChannelsFilt
 
 	channelsPriority, ok := cc.Rule[group][modelName]
 	if !ok {
-		var matchModel string
-
-		if config.ModelNameCaseInsensitiveEnabled {
-			// 1. 先尝试精确的大小写不敏感匹配
-			modelNameLower := strings.ToLower(modelName)
-			for existingModel := range cc.Rule[group] {
-				if strings.ToLower(existingModel) == modelNameLower {
-					matchModel = existingModel
-					break
-				}
-			}
-			// 2. 如果没找到，再尝试通配符的大小写不敏感匹配
-			if matchModel == "" {
-				matchModel = utils.GetModelsWithMatchCaseInsensitive(&cc.Match, modelName)
-			}
-		}
-
-		// 3. 如果还是没找到，使用原始匹配作为后备
-		if matchModel == "" {
-			matchModel = utils.GetModelsWithMatch(&cc.Match, modelName)
-		}
-
+		matchModel := cc.matchModelName(group, modelName)
 		channelsPriority, ok = cc.Rule[group][matchModel]
 		if !ok {
 			return nil, errors.New(ErrModelNotFound)
@@ -717,8 +822,9 @@ func (cc *ChannelsChooser) Next(group, modelName string, filters ...ChannelsFilt
 		return nil, errors.New(ErrChannelNotFound)
 	}
 
+	strategy := GlobalUserGroupRatio.GetRoutingStrategy(group)
 	for _, priority := range channelsPriority {
-		channel := cc.balancer(priority, filters, modelName, nil)
+		channel := cc.balancer(priority, filters, modelName, strategy, nil)
 		if channel != nil {
 			return channel, nil
 		}
@@ -746,8 +852,9 @@ func (cc *ChannelsChooser) NextByValidatedModel(group, validatedModelName string
 		return nil, ErrNoChannelsAvailableSentinel
 	}
 
+	strategy := routingStrategy(group, ginContext)
 	for _, priority := range channelsPriority {
-		channel := cc.balancer(priority, filters, validatedModelName, ginContext)
+		channel := cc.balancer(priority, filters, validatedModelName, strategy, ginContext)
 		if channel != nil {
 			return channel, nil
 		}
@@ -830,6 +937,19 @@ func (cc *ChannelsChooser) countValidChannels(channelIds []int, filters []Channe
 			continue
 		}
 
+		// 不在可用时间窗口内或预算暂停的渠道跳过
+		if choice.Paused() {
+			continue
+		}
+
+		if ChannelLimits.Saturated(choice.Channel, modelName) {
+			continue
+		}
+
+		if !ChannelKeys.Available(choice.Channel) {
+			continue
+		}
+
 		isSkip := false
 		for _, filter := range filters {
 			if filter(channelId, choice) {
@@ -848,9 +968,16 @@ func (cc *ChannelsChooser) countValidChannels(channelIds []int, filters []Channe
 
 var ChannelGroup = ChannelsChooser{}
 
+// Reload 重新加载渠道，并通知其他节点立即同步
+func (cc *ChannelsChooser) Reload() {
+	cc.Load()
+	PublishCacheReload()
+}
+
 func (cc *ChannelsChooser) Load() {
 	var channels []*Channel
 	DB.Where("status = ?", config.ChannelStatusEnabled).Find(&channels)
+	budgetPaused := getBudgetPausedChannelIds()
 
 	newGroup := make(map[string]map[string][][]int)
 	newChannels := make(map[int]*ChannelChoice)
@@ -873,6 +1000,9 @@ func (cc *ChannelsChooser) Load() {
 			Channel:       channel,
 			CooldownsTime: 0,
 			Disable:       false,
+			schedule:      loadChannelScheduler(channel),
+			modelMapping:  loadChannelModelMapping(channel),
+			budgetPaused:  budgetPaused[channel.Id],
 		}
 
 		// 处理groups和models
@@ -945,6 +1075,8 @@ func (cc *ChannelsChooser) Load() {
 		newMatchList = append(newMatchList, match)
 	}
 
+	ChannelKeys.load(channels)
+
 	// 更新ChannelsChooser
 	cc.Lock()
 	cc.Rule = newGroup
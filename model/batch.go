@@ -0,0 +1,200 @@
+package model
+
+import (
+	"done-hub/common/utils"
+	"errors"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// 批处理条目状态，取值与 Anthropic Message Batches 的 result.type 对齐
+const (
+	BatchItemStatusQueued     = "queued"
+	BatchItemStatusProcessing = "processing"
+	BatchItemStatusSucceeded  = "succeeded"
+	BatchItemStatusErrored    = "errored"
+	BatchItemStatusCanceled   = "canceled"
+	BatchItemStatusExpired    = "expired"
+)
+
+// BatchItem 批处理中的单条请求。批次本身记录在 Task 表（TaskID 即批次 id），
+// 条目表同时充当网关代执行时的持久化队列：queued 的条目会被 worker 领取执行，
+// 执行结果（一行 JSONL）写回 Result。
+type BatchItem struct {
+	ID        int64          `json:"id" gorm:"primary_key;AUTO_INCREMENT"`
+	BatchID   string         `json:"batch_id" gorm:"type:varchar(64);index:idx_batch_status"`
+	CustomID  string         `json:"custom_id" gorm:"type:varchar(128)"`
+	Status    string         `json:"status" gorm:"type:varchar(20);index:idx_batch_status;index"`
+	Request   datatypes.JSON `json:"request" gorm:"type:json"`
+	Result    datatypes.JSON `json:"result" gorm:"type:json"`
+	CreatedAt int64          `json:"created_at" gorm:"bigint"`
+	UpdatedAt int64          `json:"updated_at" gorm:"bigint"`
+}
+
+// batchTaskPlatforms 使用条目表执行的批次平台
+var batchTaskPlatforms = []string{TaskPlatformClaudeBatch, TaskPlatformOpenAIBatch}
+
+// GetBatchTask 不区分用户按批次 id 查询批次任务，供 worker 使用
+func GetBatchTask(batchID string) (task *Task, err error) {
+	task = &Task{}
+	err = DB.Where("task_id = ? and platform in (?)", batchID, batchTaskPlatforms).First(task).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return
+}
+
+// BatchItemCount 按批次、状态聚合的条目数
+type BatchItemCount struct {
+	BatchID string `json:"batch_id"`
+	Status  string `json:"status"`
+	Count   int    `json:"count"`
+}
+
+func InsertBatchItems(items []*BatchItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	now := utils.GetTimestamp()
+	for _, item := range items {
+		item.CreatedAt = now
+		item.UpdatedAt = now
+	}
+	return DB.CreateInBatches(items, 500).Error
+}
+
+// CreateBatch 在同一事务中写入批次任务与全部条目，避免轮询或 worker 看到只写了一半的批次
+func CreateBatch(task *Task, items []*BatchItem) error {
+	now := utils.GetTimestamp()
+	for _, item := range items {
+		item.CreatedAt = now
+		item.UpdatedAt = now
+	}
+
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(items, 500).Error; err != nil {
+			return err
+		}
+		return tx.Create(task).Error
+	})
+}
+
+// errBatchAlreadyFinished 批次已被其它节点结束，用于回滚 FinishBatchWithResults 的事务
+var errBatchAlreadyFinished = errors.New("batch already finished")
+
+// FinishBatchWithResults 在同一事务中结束批次任务并写入结果条目：ingest 通过 insert 分批写入条目，
+// 中途失败时任务与已写入的条目一并回滚，批次保持未结束，下次轮询重新回收；
+// 任务已被其它节点结束时返回 false，不写入任何条目
+func FinishBatchWithResults(taskID int64, params map[string]any, ingest func(insert func(items []*BatchItem) error) error) (bool, error) {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		// 先结束任务锁住任务行，并发回收的节点在此等待，提交后因条件不满足而放弃
+		result := tx.Model(&Task{}).Where("id = ? and progress != ?", taskID, 100).Updates(params)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errBatchAlreadyFinished
+		}
+
+		return ingest(func(items []*BatchItem) error {
+			if len(items) == 0 {
+				return nil
+			}
+			now := utils.GetTimestamp()
+			for _, item := range items {
+				item.CreatedAt = now
+				item.UpdatedAt = now
+			}
+			return tx.CreateInBatches(items, 500).Error
+		})
+	})
+
+	if errors.Is(err, errBatchAlreadyFinished) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// GetBatchItemCounts 统计多个批次各状态的条目数，返回 batchID -> status -> count
+func GetBatchItemCounts(batchIDs []string) (map[string]map[string]int, error) {
+	counts := make(map[string]map[string]int, len(batchIDs))
+	if len(batchIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []BatchItemCount
+	err := DB.Model(&BatchItem{}).
+		Select("batch_id, status, count(*) as count").
+		Where("batch_id in (?)", batchIDs).
+		Group("batch_id, status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		if counts[row.BatchID] == nil {
+			counts[row.BatchID] = make(map[string]int)
+		}
+		counts[row.BatchID][row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// GetQueuedBatchItems 按入队顺序取待执行的条目（仅取 id，由调用方逐条领取）
+func GetQueuedBatchItems(limit int) (items []*BatchItem, err error) {
+	err = DB.Select("id").Where("status = ?", BatchItemStatusQueued).Order("id").Limit(limit).Find(&items).Error
+	return
+}
+
+// ClaimBatchItem 以 queued -> processing 的条件更新领取条目，保证同一条目只被执行一次
+func ClaimBatchItem(id int64) (*BatchItem, error) {
+	result := DB.Model(&BatchItem{}).
+		Where("id = ? and status = ?", id, BatchItemStatusQueued).
+		Updates(map[string]any{"status": BatchItemStatusProcessing, "updated_at": utils.GetTimestamp()})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, result.Error
+	}
+
+	item := &BatchItem{}
+	if err := DB.First(item, id).Error; err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (item *BatchItem) Finish(status string, result []byte) error {
+	return DB.Model(item).Updates(map[string]any{
+		"status":     status,
+		"result":     datatypes.JSON(result),
+		"updated_at": utils.GetTimestamp(),
+	}).Error
+}
+
+// ResetProcessingBatchItems 进程重启后把残留的 processing 条目放回队列
+func ResetProcessingBatchItems() error {
+	return DB.Model(&BatchItem{}).
+		Where("status = ?", BatchItemStatusProcessing).
+		Updates(map[string]any{"status": BatchItemStatusQueued, "updated_at": utils.GetTimestamp()}).Error
+}
+
+// FinishQueuedBatchItems 把批次中尚未执行的条目批量置为终态（取消 / 过期），
+// 这类条目没有执行结果，读取时按状态补出结果行
+func FinishQueuedBatchItems(batchID string, status string) error {
+	return DB.Model(&BatchItem{}).
+		Where("batch_id = ? and status = ?", batchID, BatchItemStatusQueued).
+		Updates(map[string]any{"status": status, "updated_at": utils.GetTimestamp()}).Error
+}
+
+// GetBatchItemResults 按 id 游标分页读取批次条目的结果，statuses 为空时不过滤状态
+func GetBatchItemResults(batchID string, afterID int64, limit int, statuses ...string) (items []*BatchItem, err error) {
+	tx := DB.Select("id, custom_id, status, result").Where("batch_id = ? and id > ?", batchID, afterID)
+	if len(statuses) > 0 {
+		tx = tx.Where("status in (?)", statuses)
+	}
+	err = tx.Order("id").Limit(limit).Find(&items).Error
+	return
+}
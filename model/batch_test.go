@@ -0,0 +1,230 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// setupBatchTestDB 使用内存 SQLite 存放批次任务与条目
+func setupBatchTestDB(t *testing.T) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: gormLogger.Default.LogMode(gormLogger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err = db.AutoMigrate(&Task{}, &BatchItem{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	savedDB := DB
+	t.Cleanup(func() {
+		DB = savedDB
+		sqlDB.Close()
+	})
+	DB = db
+}
+
+func createTestBatch(t *testing.T, batchID string, statuses ...string) *Task {
+	t.Helper()
+	task := &Task{TaskID: batchID, Platform: TaskPlatformOpenAIBatch, Status: TaskStatusInProgress}
+	items := make([]*BatchItem, 0, len(statuses))
+	for i, status := range statuses {
+		items = append(items, &BatchItem{BatchID: batchID, CustomID: fmt.Sprintf("req-%d", i+1), Status: status})
+	}
+	if err := CreateBatch(task, items); err != nil {
+		t.Fatalf("CreateBatch() error = %v", err)
+	}
+	return task
+}
+
+func batchItemStatuses(t *testing.T, batchID string) []string {
+	t.Helper()
+	items, err := GetBatchItemResults(batchID, 0, 100)
+	if err != nil {
+		t.Fatalf("GetBatchItemResults() error = %v", err)
+	}
+	statuses := make([]string, 0, len(items))
+	for _, item := range items {
+		statuses = append(statuses, item.Status)
+	}
+	return statuses
+}
+
+// TestCreateBatch 测试批次任务与条目一起写入，条目按提交顺序排队
+func TestCreateBatch(t *testing.T) {
+	setupBatchTestDB(t)
+	task := createTestBatch(t, "batch_1", BatchItemStatusQueued, BatchItemStatusQueued, BatchItemStatusQueued)
+
+	got, err := GetBatchTask("batch_1")
+	if err != nil || got == nil || got.ID != task.ID {
+		t.Fatalf("GetBatchTask() = %v, %v, 期望任务 %d", got, err, task.ID)
+	}
+	if got, _ := GetBatchTask("batch_missing"); got != nil {
+		t.Errorf("GetBatchTask(不存在) = %v, 期望 nil", got)
+	}
+
+	counts, err := GetBatchItemCounts([]string{"batch_1"})
+	if err != nil {
+		t.Fatalf("GetBatchItemCounts() error = %v", err)
+	}
+	if counts["batch_1"][BatchItemStatusQueued] != 3 {
+		t.Errorf("queued 条目数 = %d, 期望 3", counts["batch_1"][BatchItemStatusQueued])
+	}
+
+	queued, err := GetQueuedBatchItems(2)
+	if err != nil {
+		t.Fatalf("GetQueuedBatchItems() error = %v", err)
+	}
+	if len(queued) != 2 || queued[0].ID >= queued[1].ID {
+		t.Errorf("GetQueuedBatchItems(2) = %d 条，期望按 id 顺序的 2 条", len(queued))
+	}
+}
+
+// TestCreateBatchRollback 测试条目写入失败时批次任务也不写入
+func TestCreateBatchRollback(t *testing.T) {
+	setupBatchTestDB(t)
+	createTestBatch(t, "batch_1", BatchItemStatusQueued)
+
+	items, _ := GetBatchItemResults("batch_1", 0, 1)
+	duplicate := &BatchItem{ID: items[0].ID, BatchID: "batch_2", Status: BatchItemStatusQueued}
+	if err := CreateBatch(&Task{TaskID: "batch_2", Platform: TaskPlatformOpenAIBatch}, []*BatchItem{duplicate}); err == nil {
+		t.Fatal("CreateBatch(主键冲突) 期望返回错误")
+	}
+	if got, _ := GetBatchTask("batch_2"); got != nil {
+		t.Errorf("条目写入失败后批次任务 = %v, 期望不写入", got)
+	}
+}
+
+// TestClaimBatchItem 测试条目只能被领取一次，重启后残留的 processing 条目重新排队
+func TestClaimBatchItem(t *testing.T) {
+	setupBatchTestDB(t)
+	createTestBatch(t, "batch_1", BatchItemStatusQueued)
+	queued, _ := GetQueuedBatchItems(1)
+	id := queued[0].ID
+
+	item, err := ClaimBatchItem(id)
+	if err != nil || item == nil {
+		t.Fatalf("第一次 ClaimBatchItem() = %v, %v, 期望领取成功", item, err)
+	}
+	if item.Status != BatchItemStatusProcessing || item.CustomID != "req-1" {
+		t.Errorf("领取的条目 = %s/%s, 期望 req-1/processing", item.CustomID, item.Status)
+	}
+	if again, err := ClaimBatchItem(id); err != nil || again != nil {
+		t.Errorf("第二次 ClaimBatchItem() = %v, %v, 期望已被领取", again, err)
+	}
+
+	if err := ResetProcessingBatchItems(); err != nil {
+		t.Fatalf("ResetProcessingBatchItems() error = %v", err)
+	}
+	if item, _ = ClaimBatchItem(id); item == nil {
+		t.Fatal("重新排队后 ClaimBatchItem() 期望领取成功")
+	}
+
+	if err := item.Finish(BatchItemStatusSucceeded, []byte(`{"custom_id":"req-1"}`)); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+	if again, _ := ClaimBatchItem(id); again != nil {
+		t.Error("已结束的条目不应再被领取")
+	}
+	results, _ := GetBatchItemResults("batch_1", 0, 10, BatchItemStatusSucceeded)
+	if len(results) != 1 || string(results[0].Result) != `{"custom_id":"req-1"}` {
+		t.Errorf("succeeded 条目结果 = %v, 期望写入执行结果", results)
+	}
+}
+
+// TestFinishQueuedBatchItems 测试取消批次只结束尚未执行的条目，执行中与已结束的条目不受影响
+func TestFinishQueuedBatchItems(t *testing.T) {
+	setupBatchTestDB(t)
+	createTestBatch(t, "batch_1", BatchItemStatusQueued, BatchItemStatusProcessing, BatchItemStatusSucceeded, BatchItemStatusQueued)
+	createTestBatch(t, "batch_2", BatchItemStatusQueued)
+
+	if err := FinishQueuedBatchItems("batch_1", BatchItemStatusCanceled); err != nil {
+		t.Fatalf("FinishQueuedBatchItems() error = %v", err)
+	}
+
+	want := []string{BatchItemStatusCanceled, BatchItemStatusProcessing, BatchItemStatusSucceeded, BatchItemStatusCanceled}
+	if got := batchItemStatuses(t, "batch_1"); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("batch_1 条目状态 = %v, 期望 %v", got, want)
+	}
+	if got := batchItemStatuses(t, "batch_2"); fmt.Sprint(got) != fmt.Sprint([]string{BatchItemStatusQueued}) {
+		t.Errorf("batch_2 条目状态 = %v, 期望不受影响", got)
+	}
+	if queued, _ := GetQueuedBatchItems(10); len(queued) != 1 {
+		t.Errorf("取消后待执行条目 = %d, 期望只剩 batch_2 的 1 条", len(queued))
+	}
+}
+
+// TestFinishBatchWithResults 测试结束批次与写入结果条目在同一事务中：中途失败整体回滚，已结束的批次不再写入
+func TestFinishBatchWithResults(t *testing.T) {
+	errRead := errors.New("read interrupted")
+	chunk := func(n int) []*BatchItem {
+		items := make([]*BatchItem, 0, n)
+		for i := 0; i < n; i++ {
+			items = append(items, &BatchItem{BatchID: "batch_1", Status: BatchItemStatusSucceeded, Result: []byte(`{}`)})
+		}
+		return items
+	}
+
+	tests := []struct {
+		name         string
+		finished     bool
+		chunks       []int
+		ingestErr    error
+		wantClaimed  bool
+		wantErr      error
+		wantItems    int
+		wantProgress int
+	}{
+		{name: "分批写入全部结果", chunks: []int{2, 3}, wantClaimed: true, wantItems: 5, wantProgress: 100},
+		{name: "没有结果也结束批次", wantClaimed: true, wantProgress: 100},
+		{name: "写入部分后读取中断", chunks: []int{2}, ingestErr: errRead, wantErr: errRead, wantProgress: 50},
+		{name: "已被其它节点结束", finished: true, chunks: []int{2}, wantItems: 0, wantProgress: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupBatchTestDB(t)
+			task := &Task{TaskID: "batch_1", Platform: TaskPlatformClaudeBatch, Status: TaskStatusInProgress, Progress: 50}
+			if tt.finished {
+				task.Progress = 100
+			}
+			if err := DB.Create(task).Error; err != nil {
+				t.Fatalf("create task: %v", err)
+			}
+
+			ingested := false
+			claimed, err := FinishBatchWithResults(task.ID, map[string]any{"status": TaskStatusSuccess, "progress": 100},
+				func(insert func(items []*BatchItem) error) error {
+					ingested = true
+					for _, n := range tt.chunks {
+						if err := insert(chunk(n)); err != nil {
+							return err
+						}
+					}
+					return tt.ingestErr
+				})
+			if claimed != tt.wantClaimed || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("FinishBatchWithResults() = %v, %v, 期望 %v, %v", claimed, err, tt.wantClaimed, tt.wantErr)
+			}
+			if tt.finished && ingested {
+				t.Error("已结束的批次不应读取结果")
+			}
+
+			if got := len(batchItemStatuses(t, "batch_1")); got != tt.wantItems {
+				t.Errorf("条目数 = %d, 期望 %d", got, tt.wantItems)
+			}
+			current := &Task{}
+			DB.First(current, task.ID)
+			if current.Progress != tt.wantProgress {
+				t.Errorf("Progress = %d, 期望 %d", current.Progress, tt.wantProgress)
+			}
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"slices"
 	"strings"
+	"time"
 
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
@@ -46,11 +47,34 @@ type Channel struct {
 	AllowExtraBody     bool     `json:"allow_extra_body" form:"allow_extra_body" gorm:"default:false"`
 	PassThroughBody    bool     `json:"pass_through_body" form:"pass_through_body" gorm:"default:false"`
 	CostRatio          *float64 `json:"cost_ratio" form:"cost_ratio" gorm:"type:decimal(10,4);default:0"`
+	// KeyMode 密钥池轮换方式（round_robin/random），为空时只使用渠道自身的密钥
+	KeyMode string `json:"key_mode" form:"key_mode" gorm:"type:varchar(32);default:''"`
+	// BalanceCheckInterval 定时更新余额的间隔（分钟），0 为不自动更新；余额耗尽时自动禁用，恢复后自动启用
+	BalanceCheckInterval int `json:"balance_check_interval" form:"balance_check_interval" gorm:"default:0"`
+	// BalanceThreshold 余额低于该值时发送提醒，0 为不提醒
+	BalanceThreshold float64 `json:"balance_threshold" form:"balance_threshold" gorm:"default:0"`
+	// StatusReason 自动禁用的原因，启用时清空；余额监控只重新启用因余额不足被自己禁用的渠道
+	StatusReason string `json:"status_reason" gorm:"type:varchar(255);default:''"`
 
 	DisabledStream *datatypes.JSONSlice[string] `json:"disabled_stream,omitempty" gorm:"type:json"`
+	// RateLimit 上游限额：渠道整体与按模型的并发、RPM、TPM 上限，达到上限的渠道在选择时被跳过
+	RateLimit *datatypes.JSONType[ChannelRateLimit] `json:"rate_limit,omitempty" gorm:"type:json"`
+	// Capabilities 能力标记（如 vision、long_context、no_function_calling），供分组的内容路由规则筛选渠道
+	Capabilities *datatypes.JSONSlice[string] `json:"capabilities,omitempty" gorm:"type:json"`
+	// Schedule 可用时间窗口，窗口之外选择渠道时跳过，不修改渠道状态
+	Schedule *datatypes.JSONType[ChannelSchedule] `json:"schedule,omitempty" gorm:"type:json"`
+	// Budget 每日、每周、每月花费预算，达到预算时暂停渠道（不修改渠道状态），周期重置后自动恢复
+	Budget *datatypes.JSONType[ChannelBudget] `json:"budget,omitempty" gorm:"type:json"`
 
 	Plugin    *datatypes.JSONType[PluginType] `json:"plugin" form:"plugin" gorm:"type:json"`
 	DeletedAt gorm.DeletedAt                  `json:"-" gorm:"index"`
+
+	// CircuitBreakers 当前节点上该渠道各模型的熔断器状态，仅在管理端渠道列表中填充
+	CircuitBreakers []*CircuitBreakerStatus `json:"circuit_breakers,omitempty" gorm:"-"`
+	// ScheduleStatus 当前是否在可用时间内及下一个可用窗口，仅在管理端渠道列表中填充
+	ScheduleStatus *ChannelScheduleStatus `json:"schedule_status,omitempty" gorm:"-"`
+	// BudgetUsage 当前预算周期内的花费，仅在管理端渠道列表中填充
+	BudgetUsage *ChannelBudgetUsage `json:"budget_usage,omitempty" gorm:"-"`
 }
 
 func (c *Channel) AllowStream(modelName string) bool {
@@ -61,6 +85,15 @@ func (c *Channel) AllowStream(modelName string) bool {
 	return !slices.Contains(*c.DisabledStream, modelName)
 }
 
+// ValidateSettings 校验渠道中需要解析的 JSON 设置（可用时间、花费预算）
+func (c *Channel) ValidateSettings() error {
+	if err := c.ValidateSchedule(); err != nil {
+		return err
+	}
+
+	return c.ValidateBudget()
+}
+
 type PluginType map[string]map[string]interface{}
 
 var allowedChannelOrderFields = map[string]bool{
@@ -169,7 +202,29 @@ func GetChannelsList(params *SearchChannelsParams) (*DataResult[Channel], error)
 		}
 	}
 
-	return PaginateAndOrder(db, &params.PaginationParams, &channels, allowedChannelOrderFields)
+	result, err := PaginateAndOrder(db, &params.PaginationParams, &channels, allowedChannelOrderFields)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	budgetChannelIds := make([]int, 0)
+	for _, channel := range channels {
+		if channel.Tag == "" {
+			channel.CircuitBreakers = CircuitBreakers.ChannelStatus(channel.Id)
+		}
+		channel.ScheduleStatus = channel.GetScheduleStatus(now)
+		if channel.HasBudget() {
+			budgetChannelIds = append(budgetChannelIds, channel.Id)
+		}
+	}
+	if usages, err := GetChannelBudgetUsages(budgetChannelIds); err == nil {
+		for _, channel := range channels {
+			channel.BudgetUsage = usages[channel.Id]
+		}
+	}
+
+	return result, nil
 }
 
 // tagAwareOrderExpr 为「展示值=整组聚合」的列返回标签感知的排序表达式：标签代表行
@@ -219,7 +274,7 @@ func GetChannelsByTag(tag string) ([]*Channel, error) {
 func DeleteChannelTag(channelId int) error {
 	result := DB.Model(&Channel{}).Where("id = ?", channelId).Update("tag", "")
 	if result.Error == nil && result.RowsAffected > 0 {
-		ChannelGroup.Load()
+		ChannelGroup.Reload()
 	}
 	return result.Error
 }
@@ -227,7 +282,7 @@ func DeleteChannelTag(channelId int) error {
 func BatchDeleteChannel(ids []int) (int64, error) {
 	result := DB.Where("id IN ?", ids).Delete(&Channel{})
 	if result.Error == nil && result.RowsAffected > 0 {
-		ChannelGroup.Load()
+		ChannelGroup.Reload()
 	}
 	return result.RowsAffected, result.Error
 }
@@ -238,7 +293,10 @@ func BatchInsertChannels(channels []Channel) error {
 		return err
 	}
 
-	ChannelGroup.Load()
+	for i := range channels {
+		EnsureChannelBudgetUsages(&channels[i])
+	}
+	ChannelGroup.Reload()
 	return nil
 }
 
@@ -254,7 +312,7 @@ func BatchUpdateChannelsAzureApi(params *BatchChannelsParams) (int64, error) {
 	}
 
 	if db.RowsAffected > 0 {
-		ChannelGroup.Load()
+		ChannelGroup.Reload()
 	}
 	return db.RowsAffected, nil
 }
@@ -283,7 +341,7 @@ func BatchDelModelChannels(params *BatchChannelsParams) (int64, error) {
 	}
 
 	if count > 0 {
-		ChannelGroup.Load()
+		ChannelGroup.Reload()
 	}
 
 	return count, nil
@@ -336,7 +394,7 @@ func BatchAddUserGroupToChannels(params *BatchChannelsParams) (int64, error) {
 	}
 
 	if count > 0 {
-		ChannelGroup.Load()
+		ChannelGroup.Reload()
 	}
 
 	return count, nil
@@ -408,7 +466,7 @@ func BatchAddModelToChannels(params *BatchChannelsParams) (int64, error) {
 	}
 
 	if count > 0 {
-		ChannelGroup.Load()
+		ChannelGroup.Reload()
 	}
 
 	return count, nil
@@ -472,7 +530,8 @@ func (channel *Channel) GetCustomParameter() string {
 func (channel *Channel) Insert() error {
 	err := DB.Omit("UsedQuota").Create(channel).Error
 	if err == nil {
-		ChannelGroup.Load()
+		EnsureChannelBudgetUsages(channel)
+		ChannelGroup.Reload()
 	}
 
 	return err
@@ -483,7 +542,8 @@ func (channel *Channel) Update(overwrite bool) error {
 	err := channel.UpdateRaw(overwrite)
 
 	if err == nil {
-		ChannelGroup.Load()
+		EnsureChannelBudgetUsages(channel)
+		ChannelGroup.Reload()
 		ChannelGroup.ClearChannelCooldowns(channel.Id)
 	}
 
@@ -522,13 +582,15 @@ func (channel *Channel) UpdateBalance(balance float64) {
 	}).Error
 	if err != nil {
 		logger.SysError("failed to update balance: " + err.Error())
+		return
 	}
+	recordChannelBalance(channel.Id, balance)
 }
 
 func (channel *Channel) Delete() error {
 	err := DB.Delete(channel).Error
 	if err == nil {
-		ChannelGroup.Load()
+		ChannelGroup.Reload()
 	}
 	return err
 }
@@ -547,8 +609,20 @@ func (channel *Channel) StatusToStr() string {
 }
 
 func UpdateChannelStatusById(id int, status int) {
+	UpdateChannelStatusWithReason(id, status, "")
+}
+
+// UpdateChannelStatusWithReason 修改渠道状态并记录原因，原因会脱敏并截断
+func UpdateChannelStatusWithReason(id int, status int, reason string) {
+	if status == config.ChannelStatusEnabled {
+		reason = ""
+	}
+
 	tx := DB.Begin()
-	err := tx.Model(&Channel{}).Where("id = ?", id).Update("status", status).Error
+	err := tx.Model(&Channel{}).Where("id = ?", id).Updates(map[string]any{
+		"status":        status,
+		"status_reason": maskStatusReason(reason),
+	}).Error
 	if err != nil {
 		logger.SysError("failed to update channel status: " + err.Error())
 		tx.Rollback()
@@ -559,6 +633,7 @@ func UpdateChannelStatusById(id int, status int) {
 
 	isEnabled := status == config.ChannelStatusEnabled
 	go ChannelGroup.ChangeStatus(id, isEnabled)
+	publishChannelStatus(id, isEnabled)
 
 	// 启用渠道时清除冻结缓存
 	if isEnabled {
@@ -606,7 +681,7 @@ func UpdateChannelKey(id int, key string) error {
 	}
 
 	ClearChannelTokenCache(id)
-	ChannelGroup.Load()
+	ChannelGroup.Reload()
 
 	return nil
 }
@@ -614,7 +689,7 @@ func UpdateChannelKey(id int, key string) error {
 func DeleteDisabledChannel() (int64, error) {
 	result := DB.Where("status = ? or status = ?", config.ChannelStatusAutoDisabled, config.ChannelStatusManuallyDisabled).Delete(&Channel{})
 	if result.Error == nil && result.RowsAffected > 0 {
-		ChannelGroup.Load()
+		ChannelGroup.Reload()
 	}
 	return result.RowsAffected, result.Error
 }
@@ -0,0 +1,93 @@
+package model
+
+import (
+	"done-hub/common/config"
+	"done-hub/common/logger"
+	"done-hub/common/utils"
+)
+
+// ChannelBalanceDisabledReason 余额监控因余额不足禁用渠道时记录的原因，余额恢复后只重新启用这类渠道
+const ChannelBalanceDisabledReason = "余额监控：余额不足"
+
+// ChannelBalanceHistoryRetentionDays 余额历史的保存天数，更早的记录由定时任务清理
+const ChannelBalanceHistoryRetentionDays = 90
+
+// ChannelBalanceHistory 渠道余额的历史记录，每次更新余额时写入，用于统计消耗速度与预计用完时间
+type ChannelBalanceHistory struct {
+	Id        int64   `json:"id" gorm:"primaryKey"`
+	ChannelId int     `json:"channel_id" gorm:"index:idx_channel_balance_time"`
+	Balance   float64 `json:"balance"`
+	CreatedAt int64   `json:"created_at" gorm:"bigint;index:idx_channel_balance_time"`
+}
+
+// ChannelBalanceTrend 一段时间内的余额记录与按此推算的消耗速度
+type ChannelBalanceTrend struct {
+	History  []*ChannelBalanceHistory `json:"history"`
+	BurnRate float64                  `json:"burn_rate"`  // 平均每天消耗的余额，余额上升（充值）的部分不计入
+	RunOutAt int64                    `json:"run_out_at"` // 按当前消耗速度预计用完的时间，无法预测时为 0
+}
+
+func recordChannelBalance(channelId int, balance float64) {
+	err := DB.Create(&ChannelBalanceHistory{
+		ChannelId: channelId,
+		Balance:   balance,
+		CreatedAt: utils.GetTimestamp(),
+	}).Error
+	if err != nil {
+		logger.SysError("failed to record channel balance: " + err.Error())
+	}
+}
+
+// GetChannelBalanceTrend 查询渠道最近 days 天的余额记录并计算消耗速度
+func GetChannelBalanceTrend(channelId int, days int) (*ChannelBalanceTrend, error) {
+	if days <= 0 || days > ChannelBalanceHistoryRetentionDays {
+		days = 30
+	}
+
+	now := utils.GetTimestamp()
+	trend := &ChannelBalanceTrend{}
+	err := DB.Where("channel_id = ? AND created_at >= ?", channelId, now-int64(days)*86400).
+		Order("created_at").Find(&trend.History).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(trend.History) < 2 {
+		return trend, nil
+	}
+
+	var consumed float64
+	for i := 1; i < len(trend.History); i++ {
+		if delta := trend.History[i-1].Balance - trend.History[i].Balance; delta > 0 {
+			consumed += delta
+		}
+	}
+	first, last := trend.History[0], trend.History[len(trend.History)-1]
+	elapsed := float64(last.CreatedAt-first.CreatedAt) / 86400
+	if elapsed <= 0 || consumed <= 0 {
+		return trend, nil
+	}
+
+	trend.BurnRate = consumed / elapsed
+	if last.Balance > 0 {
+		trend.RunOutAt = last.CreatedAt + int64(last.Balance/trend.BurnRate*86400)
+	}
+
+	return trend, nil
+}
+
+// DeleteOldChannelBalanceHistories 删除超过保存天数的余额记录
+func DeleteOldChannelBalanceHistories() (int64, error) {
+	before := utils.GetTimestamp() - ChannelBalanceHistoryRetentionDays*86400
+	result := DB.Where("created_at < ?", before).Delete(&ChannelBalanceHistory{})
+
+	return result.RowsAffected, result.Error
+}
+
+// GetBalanceMonitoredChannels 设置了余额检查间隔、且未被手动禁用的渠道
+func GetBalanceMonitoredChannels() ([]*Channel, error) {
+	var channels []*Channel
+	err := DB.Where("balance_check_interval > 0 AND status IN ?",
+		[]int{config.ChannelStatusEnabled, config.ChannelStatusAutoDisabled}).Find(&channels).Error
+
+	return channels, err
+}
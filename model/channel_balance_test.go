@@ -0,0 +1,49 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"done-hub/common/config"
+)
+
+// TestUpdateChannelStatusWithReason 测试自动禁用时记录原因、启用时清空，余额监控据此只重新启用自己禁用的渠道
+func TestUpdateChannelStatusWithReason(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		reason string
+		want   string
+	}{
+		{name: "余额监控禁用", status: config.ChannelStatusAutoDisabled, reason: ChannelBalanceDisabledReason, want: ChannelBalanceDisabledReason},
+		{name: "其他原因禁用", status: config.ChannelStatusAutoDisabled, reason: "status code 401", want: "status code 401"},
+		{name: "原因超长时截断", status: config.ChannelStatusAutoDisabled, reason: strings.Repeat("错", 300), want: strings.Repeat("错", 255)},
+		{name: "启用时清空原因", status: config.ChannelStatusEnabled, reason: ChannelBalanceDisabledReason, want: ""},
+		{name: "不带原因修改状态时清空原因", status: config.ChannelStatusManuallyDisabled, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupChannelTestDB(t)
+			channel := createBudgetTestChannel(t, 1, config.ChannelStatusEnabled, ChannelBudget{})
+			DB.Model(channel).Update("status_reason", "上一次的原因")
+
+			if tt.reason != "" {
+				UpdateChannelStatusWithReason(channel.Id, tt.status, tt.reason)
+			} else {
+				UpdateChannelStatusById(channel.Id, tt.status)
+			}
+
+			current, err := GetChannelById(channel.Id)
+			if err != nil {
+				t.Fatalf("GetChannelById() error = %v", err)
+			}
+			if current.Status != tt.status {
+				t.Errorf("Status = %d, 期望 %d", current.Status, tt.status)
+			}
+			if current.StatusReason != tt.want {
+				t.Errorf("StatusReason = %q, 期望 %q", current.StatusReason, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package model
+
+import (
+	"done-hub/common/config"
+	"done-hub/common/logger"
+	"done-hub/common/notify"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 花费达到预算的该百分比时发送提醒
+const defaultChannelBudgetAlertPercent = 80
+
+// ChannelBudget 渠道的花费预算（美元），按成本倍率折算的上游成本计算，未设置成本倍率时按用户扣费计算，0 表示不限制。
+// 达到提醒百分比时发送通知，达到预算时暂停渠道（选择渠道时跳过，不修改渠道状态），周期重置后自动恢复。
+type ChannelBudget struct {
+	Daily        float64 `json:"daily,omitempty"`
+	Weekly       float64 `json:"weekly,omitempty"`  // 自然周，周一开始
+	Monthly      float64 `json:"monthly,omitempty"` // 自然月
+	AlertPercent int     `json:"alert_percent,omitempty"`
+}
+
+func (b ChannelBudget) IsEmpty() bool {
+	return b.Daily <= 0 && b.Weekly <= 0 && b.Monthly <= 0
+}
+
+func (b ChannelBudget) alertPercent() int {
+	if b.AlertPercent <= 0 {
+		return defaultChannelBudgetAlertPercent
+	}
+
+	return b.AlertPercent
+}
+
+// ChannelBudgetUsage 渠道当前预算周期内的花费（额度），保存预算时创建，结算时累加，由定时任务按周期清零，Id 为渠道 id
+type ChannelBudgetUsage struct {
+	Id           int   `json:"id" gorm:"primaryKey;autoIncrement:false"`
+	DailySpent   int64 `json:"daily_spent" gorm:"bigint;default:0"`
+	WeeklySpent  int64 `json:"weekly_spent" gorm:"bigint;default:0"`
+	MonthlySpent int64 `json:"monthly_spent" gorm:"bigint;default:0"`
+	DailyStart   int64 `json:"daily_start" gorm:"bigint;default:0"`
+	WeeklyStart  int64 `json:"weekly_start" gorm:"bigint;default:0"`
+	MonthlyStart int64 `json:"monthly_start" gorm:"bigint;default:0"`
+	// Alerted 当前周期内已发送过提醒的周期，按位记录
+	Alerted int `json:"alerted" gorm:"default:0"`
+	// Paused 渠道因达到预算被暂停，加载渠道时据此跳过该渠道
+	Paused bool `json:"paused" gorm:"default:false"`
+}
+
+// newChannelBudgetUsage 周期从 now 所在的周期开始，避免定时任务把创建后累加的花费当作上个周期清零
+func newChannelBudgetUsage(channelId int, now time.Time) *ChannelBudgetUsage {
+	usage := &ChannelBudgetUsage{Id: channelId}
+	for _, period := range usage.periods(ChannelBudget{}, now) {
+		*period.start = period.begin
+	}
+
+	return usage
+}
+
+// channelBudgetPeriod 一个预算周期在用量记录中对应的字段
+type channelBudgetPeriod struct {
+	column string
+	name   string
+	bit    int
+	limit  float64
+	spent  *int64
+	start  *int64
+	begin  int64 // 当前周期的开始时间
+}
+
+func (u *ChannelBudgetUsage) periods(budget ChannelBudget, now time.Time) []channelBudgetPeriod {
+	year, month, day := now.Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+	// 周一为一周的第一天
+	weekday := (int(today.Weekday()) + 6) % 7
+
+	return []channelBudgetPeriod{
+		{"daily", "每日", 1, budget.Daily, &u.DailySpent, &u.DailyStart, today.Unix()},
+		{"weekly", "每周", 2, budget.Weekly, &u.WeeklySpent, &u.WeeklyStart, today.AddDate(0, 0, -weekday).Unix()},
+		{"monthly", "每月", 4, budget.Monthly, &u.MonthlySpent, &u.MonthlyStart, time.Date(year, month, 1, 0, 0, 0, 0, now.Location()).Unix()},
+	}
+}
+
+// HasBudget 渠道是否设置了花费预算
+func (c *Channel) HasBudget() bool {
+	return c.Budget != nil && !c.Budget.Data().IsEmpty()
+}
+
+// ValidateBudget 校验渠道的花费预算设置
+func (c *Channel) ValidateBudget() error {
+	if c.Budget == nil {
+		return nil
+	}
+	budget := c.Budget.Data()
+	if budget.Daily < 0 || budget.Weekly < 0 || budget.Monthly < 0 {
+		return errors.New("花费预算不能为负数")
+	}
+	if budget.AlertPercent < 0 || budget.AlertPercent > 100 {
+		return errors.New("预算提醒百分比只能是 0-100")
+	}
+
+	return nil
+}
+
+// EnsureChannelBudgetUsages 保存渠道时为设置了预算的渠道创建用量记录，已存在时保留，
+// 结算累加花费只更新已有的记录
+func EnsureChannelBudgetUsages(channels ...*Channel) {
+	now := time.Now()
+	for _, channel := range channels {
+		if !channel.HasBudget() {
+			continue
+		}
+		if err := createChannelBudgetUsage(newChannelBudgetUsage(channel.Id, now)); err != nil {
+			logger.SysError("failed to create channel budget usage: " + err.Error())
+		}
+	}
+}
+
+func createChannelBudgetUsage(usage *ChannelBudgetUsage) error {
+	return DB.Clauses(clause.OnConflict{DoNothing: true}).Create(usage).Error
+}
+
+// getBudgetPausedChannelIds 因花费达到预算被暂停的渠道
+func getBudgetPausedChannelIds() map[int]bool {
+	var ids []int
+	if err := DB.Model(&ChannelBudgetUsage{}).Where("paused = ?", true).Pluck("id", &ids).Error; err != nil {
+		logger.SysError("failed to get budget paused channels: " + err.Error())
+	}
+
+	paused := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		paused[id] = true
+	}
+
+	return paused
+}
+
+// UpdateChannelBudgetSpent 累加渠道各预算周期的花费，只对设置了预算的渠道调用
+func UpdateChannelBudgetSpent(channelId int, spent int) {
+	if spent <= 0 {
+		return
+	}
+
+	if config.BatchUpdateEnabled {
+		addNewRecord(BatchUpdateTypeChannelBudgetSpent, channelId, spent)
+		return
+	}
+	updateChannelBudgetSpent(channelId, spent)
+}
+
+func updateChannelBudgetSpent(channelId int, spent int) {
+	err := DB.Model(&ChannelBudgetUsage{}).Where("id = ?", channelId).Updates(map[string]any{
+		"daily_spent":   gorm.Expr("daily_spent + ?", spent),
+		"weekly_spent":  gorm.Expr("weekly_spent + ?", spent),
+		"monthly_spent": gorm.Expr("monthly_spent + ?", spent),
+	}).Error
+	if err != nil {
+		logger.SysError("failed to update channel budget spent: " + err.Error())
+	}
+}
+
+func batchUpdateChannelBudgetSpent(store map[int]int) {
+	batchAddColumn("channel_budget_usages", "daily_spent", store)
+	batchAddColumn("channel_budget_usages", "weekly_spent", store)
+	batchAddColumn("channel_budget_usages", "monthly_spent", store)
+}
+
+// GetChannelBudgetUsages 按渠道 id 查询预算用量
+func GetChannelBudgetUsages(channelIds []int) (map[int]*ChannelBudgetUsage, error) {
+	usages := make(map[int]*ChannelBudgetUsage, len(channelIds))
+	if len(channelIds) == 0 {
+		return usages, nil
+	}
+
+	var rows []*ChannelBudgetUsage
+	if err := DB.Where("id IN ?", channelIds).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		usages[row.Id] = row
+	}
+
+	return usages, nil
+}
+
+// CheckChannelBudgets 由定时任务每分钟执行：周期到期时清零花费并恢复被暂停的渠道，
+// 花费达到提醒百分比时发送通知，达到预算时暂停渠道。
+func CheckChannelBudgets() {
+	var channels []*Channel
+	err := DB.Select("id", "name", "status", "budget").Where("budget IS NOT NULL").Find(&channels).Error
+	if err != nil {
+		logger.SysError("failed to get channel budgets: " + err.Error())
+		return
+	}
+
+	var rows []*ChannelBudgetUsage
+	if err = DB.Find(&rows).Error; err != nil {
+		logger.SysError("failed to get channel budget usages: " + err.Error())
+		return
+	}
+	usages := make(map[int]*ChannelBudgetUsage, len(rows))
+	for _, row := range rows {
+		usages[row.Id] = row
+	}
+
+	now := time.Now()
+	for _, channel := range channels {
+		if !channel.HasBudget() {
+			continue
+		}
+		usage, ok := usages[channel.Id]
+		delete(usages, channel.Id)
+		if !ok {
+			// 保存预算时已创建，这里补上升级前设置的预算
+			usage = newChannelBudgetUsage(channel.Id, now)
+			if err = createChannelBudgetUsage(usage); err != nil {
+				logger.SysError("failed to create channel budget usage: " + err.Error())
+				continue
+			}
+		}
+		if updates := checkChannelBudget(channel, usage, now); len(updates) > 0 {
+			if err = DB.Model(&ChannelBudgetUsage{}).Where("id = ?", usage.Id).Updates(updates).Error; err != nil {
+				logger.SysError("failed to update channel budget usage: " + err.Error())
+			}
+		}
+	}
+
+	// 预算已被取消或渠道已删除，恢复被暂停的渠道并删除用量记录
+	for _, usage := range usages {
+		DB.Delete(usage)
+		if usage.Paused {
+			resumeBudgetPausedChannel(usage.Id, "花费预算已取消")
+		}
+	}
+}
+
+// checkChannelBudget 检查单个渠道的预算，返回需要写回的用量字段
+func checkChannelBudget(channel *Channel, usage *ChannelBudgetUsage, now time.Time) map[string]any {
+	budget := channel.Budget.Data()
+	updates := make(map[string]any)
+	var exceeded []string
+
+	for _, period := range usage.periods(budget, now) {
+		if *period.start != period.begin {
+			if err := resetChannelBudgetPeriod(usage.Id, period); err != nil {
+				logger.SysError("failed to reset channel budget period: " + err.Error())
+				continue
+			}
+			usage.Alerted &^= period.bit
+			updates["alerted"] = usage.Alerted
+		}
+		if period.limit <= 0 {
+			continue
+		}
+
+		limitQuota := period.limit * config.QuotaPerUnit
+		spent := float64(*period.spent)
+		if spent >= limitQuota {
+			exceeded = append(exceeded, period.name)
+		}
+		if usage.Alerted&period.bit == 0 && spent >= limitQuota*float64(budget.alertPercent())/100 {
+			usage.Alerted |= period.bit
+			updates["alerted"] = usage.Alerted
+			subject := fmt.Sprintf("通道「%s」（#%d）%s花费已达预算的 %d%%", channel.Name, channel.Id, period.name, budget.alertPercent())
+			content := fmt.Sprintf("通道「%s」（#%d）%s花费 $%.4f，预算 $%.4f", channel.Name, channel.Id, period.name, spent/config.QuotaPerUnit, period.limit)
+			notify.Send(subject, content)
+		}
+	}
+
+	switch {
+	case len(exceeded) > 0 && !usage.Paused:
+		if err := setChannelBudgetPaused(channel.Id, true); err != nil {
+			logger.SysError("failed to pause channel by budget: " + err.Error())
+			break
+		}
+		usage.Paused = true
+		subject := fmt.Sprintf("通道「%s」（#%d）已因花费达到预算被暂停", channel.Name, channel.Id)
+		content := fmt.Sprintf("通道「%s」（#%d）%s花费已达到预算，将在周期重置后自动恢复", channel.Name, channel.Id, strings.Join(exceeded, "、"))
+		notify.Send(subject, content)
+	case len(exceeded) == 0 && usage.Paused:
+		usage.Paused = false
+		resumeBudgetPausedChannel(channel.Id, "预算周期已重置或预算已调整")
+	}
+
+	return updates
+}
+
+// resetChannelBudgetPeriod 进入新周期时清零花费。按旧的开始时间条件更新，其他节点已经重置过时不再清零，
+// 避免丢失新周期内已经累加的花费，此时重新读取当前花费
+func resetChannelBudgetPeriod(channelId int, period channelBudgetPeriod) error {
+	result := DB.Model(&ChannelBudgetUsage{}).
+		Where("id = ? AND "+period.column+"_start = ?", channelId, *period.start).
+		Updates(map[string]any{
+			period.column + "_spent": 0,
+			period.column + "_start": period.begin,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	*period.start = period.begin
+	if result.RowsAffected > 0 {
+		*period.spent = 0
+		return nil
+	}
+
+	var spent []int64
+	if err := DB.Model(&ChannelBudgetUsage{}).Where("id = ?", channelId).Pluck(period.column+"_spent", &spent).Error; err != nil {
+		return err
+	}
+	if len(spent) > 0 {
+		*period.spent = spent[0]
+	}
+
+	return nil
+}
+
+// setChannelBudgetPaused 记录预算暂停状态并同步到各节点的渠道选择，不修改渠道状态
+func setChannelBudgetPaused(channelId int, paused bool) error {
+	err := DB.Model(&ChannelBudgetUsage{}).Where("id = ?", channelId).Update("paused", paused).Error
+	if err != nil {
+		return err
+	}
+
+	ChannelGroup.SetBudgetPaused(channelId, paused)
+	publishBudgetPause(channelId, paused)
+
+	return nil
+}
+
+// resumeBudgetPausedChannel 只解除预算自身的暂停，渠道被禁用时保持禁用
+func resumeBudgetPausedChannel(channelId int, reason string) {
+	if err := setChannelBudgetPaused(channelId, false); err != nil {
+		logger.SysError("failed to resume channel by budget: " + err.Error())
+		return
+	}
+
+	channel, err := GetChannelById(channelId)
+	if err != nil {
+		return
+	}
+	subject := fmt.Sprintf("通道「%s」（#%d）已解除预算暂停", channel.Name, channelId)
+	content := fmt.Sprintf("通道「%s」（#%d）已解除预算暂停，原因：%s", channel.Name, channelId, reason)
+	notify.Send(subject, content)
+}
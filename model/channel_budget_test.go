@@ -0,0 +1,374 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"done-hub/common/config"
+	"done-hub/common/logger"
+
+	"github.com/glebarez/sqlite"
+	"go.uber.org/zap"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+func init() {
+	if logger.Logger == nil {
+		logger.Logger = zap.NewNop()
+	}
+}
+
+// setupChannelTestDB 使用内存 SQLite，并让渠道选择只包含测试中加入的渠道
+func setupChannelTestDB(t *testing.T) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: gormLogger.Default.LogMode(gormLogger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err = db.AutoMigrate(&Channel{}, &ChannelBudgetUsage{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	savedDB, savedBatch := DB, config.BatchUpdateEnabled
+	ChannelGroup.Lock()
+	savedChannels := ChannelGroup.Channels
+	ChannelGroup.Channels = make(map[int]*ChannelChoice)
+	ChannelGroup.Unlock()
+	t.Cleanup(func() {
+		DB, config.BatchUpdateEnabled = savedDB, savedBatch
+		ChannelGroup.Lock()
+		ChannelGroup.Channels = savedChannels
+		ChannelGroup.Unlock()
+		sqlDB.Close()
+	})
+	DB, config.BatchUpdateEnabled = db, false
+}
+
+func createBudgetTestChannel(t *testing.T, id, status int, budget ChannelBudget) *Channel {
+	t.Helper()
+	weight := uint(1)
+	channel := &Channel{Id: id, Name: "budget", Key: "sk-test", Status: status, Weight: &weight}
+	if !budget.IsEmpty() {
+		value := datatypes.NewJSONType(budget)
+		channel.Budget = &value
+	}
+	if err := DB.Create(channel).Error; err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+
+	ChannelGroup.Lock()
+	ChannelGroup.Channels[id] = &ChannelChoice{Channel: channel}
+	ChannelGroup.Unlock()
+	return channel
+}
+
+func getBudgetTestUsage(t *testing.T, id int) *ChannelBudgetUsage {
+	t.Helper()
+	usage := &ChannelBudgetUsage{}
+	if err := DB.First(usage, "id = ?", id).Error; err != nil {
+		t.Fatalf("get usage: %v", err)
+	}
+	return usage
+}
+
+func budgetChoicePaused(id int) bool {
+	ChannelGroup.RLock()
+	defer ChannelGroup.RUnlock()
+	return ChannelGroup.Channels[id].Paused()
+}
+
+// TestChannelBudgetPeriods 测试预算周期的开始时间：自然日、周一开始的自然周、自然月，按 now 所在时区计算
+func TestChannelBudgetPeriods(t *testing.T) {
+	shanghai := mustLoadLocation(t, "Asia/Shanghai")
+	tests := []struct {
+		name    string
+		now     time.Time
+		daily   time.Time
+		weekly  time.Time
+		monthly time.Time
+	}{
+		{
+			name:    "周中",
+			now:     time.Date(2026, 10, 14, 15, 30, 0, 0, shanghai),
+			daily:   time.Date(2026, 10, 14, 0, 0, 0, 0, shanghai),
+			weekly:  time.Date(2026, 10, 12, 0, 0, 0, 0, shanghai),
+			monthly: time.Date(2026, 10, 1, 0, 0, 0, 0, shanghai),
+		},
+		{
+			name:    "周一零点开始新的一周",
+			now:     time.Date(2026, 10, 19, 0, 0, 0, 0, shanghai),
+			daily:   time.Date(2026, 10, 19, 0, 0, 0, 0, shanghai),
+			weekly:  time.Date(2026, 10, 19, 0, 0, 0, 0, shanghai),
+			monthly: time.Date(2026, 10, 1, 0, 0, 0, 0, shanghai),
+		},
+		{
+			name:    "周日仍属于上周",
+			now:     time.Date(2026, 10, 18, 23, 59, 0, 0, shanghai),
+			daily:   time.Date(2026, 10, 18, 0, 0, 0, 0, shanghai),
+			weekly:  time.Date(2026, 10, 12, 0, 0, 0, 0, shanghai),
+			monthly: time.Date(2026, 10, 1, 0, 0, 0, 0, shanghai),
+		},
+		{
+			name:    "自然周跨月",
+			now:     time.Date(2026, 11, 1, 8, 0, 0, 0, shanghai),
+			daily:   time.Date(2026, 11, 1, 0, 0, 0, 0, shanghai),
+			weekly:  time.Date(2026, 10, 26, 0, 0, 0, 0, shanghai),
+			monthly: time.Date(2026, 11, 1, 0, 0, 0, 0, shanghai),
+		},
+		{
+			name:    "自然周跨年",
+			now:     time.Date(2027, 1, 1, 0, 0, 0, 0, shanghai),
+			daily:   time.Date(2027, 1, 1, 0, 0, 0, 0, shanghai),
+			weekly:  time.Date(2026, 12, 28, 0, 0, 0, 0, shanghai),
+			monthly: time.Date(2027, 1, 1, 0, 0, 0, 0, shanghai),
+		},
+		{
+			name:    "按 now 的时区计算",
+			now:     time.Date(2026, 10, 18, 16, 30, 0, 0, time.UTC), // 上海时间周一 00:30
+			daily:   time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC),
+			weekly:  time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC),
+			monthly: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usage := &ChannelBudgetUsage{}
+			want := map[string]time.Time{"daily": tt.daily, "weekly": tt.weekly, "monthly": tt.monthly}
+			for _, period := range usage.periods(ChannelBudget{}, tt.now) {
+				if period.begin != want[period.column].Unix() {
+					t.Errorf("%s 周期开始 = %s, 期望 %s", period.column,
+						time.Unix(period.begin, 0).In(tt.now.Location()), want[period.column])
+				}
+			}
+		})
+	}
+}
+
+// TestCheckChannelBudget 测试周期切换时按旧的开始时间清零花费、达到预算时暂停、重置后只解除预算自身的暂停
+func TestCheckChannelBudget(t *testing.T) {
+	quota := func(dollars float64) int64 { return int64(dollars * config.QuotaPerUnit) }
+	// 2026-10-19 为周一
+	now := time.Date(2026, 10, 19, 0, 5, 0, 0, time.Local)
+	lastSunday := time.Date(2026, 10, 18, 12, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name    string
+		budget  ChannelBudget
+		status  int
+		usage   func() *ChannelBudgetUsage
+		stored  func() *ChannelBudgetUsage // 数据库中的记录，为 nil 时与 usage 相同
+		spent   [3]int64                   // 检查后的每日、每周、每月花费
+		paused  bool
+		updates bool
+	}{
+		{
+			name:   "周期内不清零",
+			budget: ChannelBudget{Daily: 10},
+			status: config.ChannelStatusEnabled,
+			usage: func() *ChannelBudgetUsage {
+				usage := newChannelBudgetUsage(1, now)
+				usage.DailySpent, usage.WeeklySpent, usage.MonthlySpent = quota(1), quota(2), quota(3)
+				return usage
+			},
+			spent: [3]int64{quota(1), quota(2), quota(3)},
+		},
+		{
+			name:   "跨天只清零每日花费",
+			budget: ChannelBudget{Daily: 10},
+			status: config.ChannelStatusEnabled,
+			usage: func() *ChannelBudgetUsage {
+				usage := newChannelBudgetUsage(1, now)
+				usage.DailyStart = time.Date(2026, 10, 18, 0, 0, 0, 0, time.Local).Unix()
+				usage.DailySpent, usage.WeeklySpent, usage.MonthlySpent = quota(1), quota(2), quota(3)
+				return usage
+			},
+			spent:   [3]int64{0, quota(2), quota(3)},
+			updates: true,
+		},
+		{
+			name:   "跨周清零每日与每周花费",
+			budget: ChannelBudget{Weekly: 10},
+			status: config.ChannelStatusEnabled,
+			usage: func() *ChannelBudgetUsage {
+				usage := newChannelBudgetUsage(1, lastSunday)
+				usage.DailySpent, usage.WeeklySpent, usage.MonthlySpent = quota(1), quota(2), quota(3)
+				return usage
+			},
+			spent:   [3]int64{0, 0, quota(3)},
+			updates: true,
+		},
+		{
+			name:   "达到预算暂停且不修改渠道状态",
+			budget: ChannelBudget{Daily: 10, Monthly: 100},
+			status: config.ChannelStatusEnabled,
+			usage: func() *ChannelBudgetUsage {
+				usage := newChannelBudgetUsage(1, now)
+				usage.DailySpent, usage.WeeklySpent, usage.MonthlySpent = quota(10), quota(10), quota(10)
+				return usage
+			},
+			spent:   [3]int64{quota(10), quota(10), quota(10)},
+			paused:  true,
+			updates: true,
+		},
+		{
+			name:   "禁用的渠道同样记录暂停",
+			budget: ChannelBudget{Daily: 10},
+			status: config.ChannelStatusManuallyDisabled,
+			usage: func() *ChannelBudgetUsage {
+				usage := newChannelBudgetUsage(1, now)
+				usage.DailySpent = quota(12)
+				return usage
+			},
+			spent:   [3]int64{quota(12), 0, 0},
+			paused:  true,
+			updates: true,
+		},
+		{
+			name:   "周期重置后解除暂停",
+			budget: ChannelBudget{Daily: 10},
+			status: config.ChannelStatusEnabled,
+			usage: func() *ChannelBudgetUsage {
+				usage := newChannelBudgetUsage(1, lastSunday)
+				usage.DailySpent, usage.Paused, usage.Alerted = quota(10), true, 1
+				return usage
+			},
+			spent:   [3]int64{0, 0, 0},
+			updates: true,
+		},
+		{
+			name:   "解除暂停不会启用被自动禁用的渠道",
+			budget: ChannelBudget{Daily: 10},
+			status: config.ChannelStatusAutoDisabled,
+			usage: func() *ChannelBudgetUsage {
+				usage := newChannelBudgetUsage(1, lastSunday)
+				usage.DailySpent, usage.Paused = quota(10), true
+				return usage
+			},
+			spent:   [3]int64{0, 0, 0},
+			updates: true,
+		},
+		{
+			name:   "其他节点已经重置时不再清零",
+			budget: ChannelBudget{Daily: 10},
+			status: config.ChannelStatusEnabled,
+			usage: func() *ChannelBudgetUsage {
+				usage := newChannelBudgetUsage(1, lastSunday)
+				usage.DailySpent, usage.WeeklySpent, usage.MonthlySpent = quota(9), quota(9), quota(9)
+				return usage
+			},
+			stored: func() *ChannelBudgetUsage {
+				usage := newChannelBudgetUsage(1, now)
+				usage.DailySpent, usage.WeeklySpent, usage.MonthlySpent = quota(11), quota(11), quota(9)
+				return usage
+			},
+			spent:   [3]int64{quota(11), quota(11), quota(9)},
+			paused:  true,
+			updates: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupChannelTestDB(t)
+			channel := createBudgetTestChannel(t, 1, tt.status, tt.budget)
+			usage := tt.usage()
+			stored := usage
+			if tt.stored != nil {
+				stored = tt.stored()
+			}
+			if err := DB.Create(stored).Error; err != nil {
+				t.Fatalf("create usage: %v", err)
+			}
+			if usage.Paused {
+				ChannelGroup.SetBudgetPaused(channel.Id, true)
+			}
+
+			updates := checkChannelBudget(channel, usage, now)
+			if (len(updates) > 0) != tt.updates {
+				t.Errorf("updates = %v, 期望有更新 %v", updates, tt.updates)
+			}
+			if len(updates) > 0 {
+				DB.Model(&ChannelBudgetUsage{}).Where("id = ?", usage.Id).Updates(updates)
+			}
+
+			saved := getBudgetTestUsage(t, channel.Id)
+			for i, got := range [][2]int64{{usage.DailySpent, saved.DailySpent}, {usage.WeeklySpent, saved.WeeklySpent}, {usage.MonthlySpent, saved.MonthlySpent}} {
+				if got[0] != tt.spent[i] || got[1] != tt.spent[i] {
+					t.Errorf("第 %d 个周期花费 = %d（记录 %d）, 期望 %d", i+1, got[0], got[1], tt.spent[i])
+				}
+			}
+			for _, period := range saved.periods(tt.budget, now) {
+				if *period.start != period.begin {
+					t.Errorf("%s 周期开始 = %d, 期望 %d", period.column, *period.start, period.begin)
+				}
+			}
+			if usage.Paused != tt.paused || saved.Paused != tt.paused {
+				t.Errorf("Paused = %v（记录 %v）, 期望 %v", usage.Paused, saved.Paused, tt.paused)
+			}
+			if got := budgetChoicePaused(channel.Id); got != tt.paused {
+				t.Errorf("渠道选择中的暂停状态 = %v, 期望 %v", got, tt.paused)
+			}
+
+			current, _ := GetChannelById(channel.Id)
+			if current.Status != tt.status {
+				t.Errorf("渠道状态 = %d, 期望保持 %d", current.Status, tt.status)
+			}
+		})
+	}
+}
+
+// TestChannelBudgetSpentBeforeCheck 测试保存预算时即创建用量记录，定时任务运行前结算的花费不会丢失
+func TestChannelBudgetSpentBeforeCheck(t *testing.T) {
+	setupChannelTestDB(t)
+	channel := createBudgetTestChannel(t, 1, config.ChannelStatusEnabled, ChannelBudget{Daily: 10})
+	createBudgetTestChannel(t, 2, config.ChannelStatusEnabled, ChannelBudget{})
+
+	EnsureChannelBudgetUsages(channel)
+	UpdateChannelBudgetSpent(channel.Id, 100)
+	// 再次保存不覆盖已有的花费
+	EnsureChannelBudgetUsages(channel)
+	UpdateChannelBudgetSpent(channel.Id, 50)
+
+	CheckChannelBudgets()
+
+	usage := getBudgetTestUsage(t, channel.Id)
+	if usage.DailySpent != 150 || usage.WeeklySpent != 150 || usage.MonthlySpent != 150 {
+		t.Errorf("花费 = %d/%d/%d, 期望均为 150", usage.DailySpent, usage.WeeklySpent, usage.MonthlySpent)
+	}
+
+	var count int64
+	DB.Model(&ChannelBudgetUsage{}).Where("id = ?", 2).Count(&count)
+	if count != 0 {
+		t.Error("未设置预算的渠道不应创建用量记录")
+	}
+}
+
+// TestCheckChannelBudgetsRemoved 测试取消预算后删除用量记录并解除暂停，不修改渠道状态
+func TestCheckChannelBudgetsRemoved(t *testing.T) {
+	setupChannelTestDB(t)
+	channel := createBudgetTestChannel(t, 1, config.ChannelStatusAutoDisabled, ChannelBudget{})
+
+	usage := newChannelBudgetUsage(channel.Id, time.Now())
+	usage.Paused = true
+	DB.Create(usage)
+	ChannelGroup.SetBudgetPaused(channel.Id, true)
+
+	CheckChannelBudgets()
+
+	var count int64
+	DB.Model(&ChannelBudgetUsage{}).Where("id = ?", channel.Id).Count(&count)
+	if count != 0 {
+		t.Error("取消预算后应删除用量记录")
+	}
+	if budgetChoicePaused(channel.Id) {
+		t.Error("取消预算后应解除暂停")
+	}
+	if current, _ := GetChannelById(channel.Id); current.Status != config.ChannelStatusAutoDisabled {
+		t.Errorf("渠道状态 = %d, 期望保持自动禁用", current.Status)
+	}
+}
@@ -0,0 +1,458 @@
+package model
+
+import (
+	"done-hub/common/config"
+	"done-hub/common/logger"
+	"done-hub/common/utils"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// ChannelKeyModeRoundRobin 密钥池轮询使用
+	ChannelKeyModeRoundRobin = "round_robin"
+	// ChannelKeyModeRandom 密钥池随机使用
+	ChannelKeyModeRandom = "random"
+)
+
+var ErrNoAvailableChannelKey = errors.New("渠道密钥池中没有可用的密钥")
+
+// ChannelKey 多密钥渠道的密钥池成员，状态取值同渠道状态
+type ChannelKey struct {
+	Id           int    `json:"id"`
+	ChannelId    int    `json:"channel_id" gorm:"index"`
+	Key          string `json:"key" gorm:"type:text"`
+	Remark       string `json:"remark" gorm:"type:varchar(255);default:''"`
+	Status       int    `json:"status" gorm:"default:1"`
+	StatusReason string `json:"status_reason" gorm:"type:varchar(255);default:''"`
+	UsedQuota    int64  `json:"used_quota" gorm:"bigint;default:0"`
+	RequestCount int    `json:"request_count" gorm:"default:0"`
+	CreatedTime  int64  `json:"created_time" gorm:"bigint"`
+
+	// CooldownUntil 当前节点上的冷却截止时间，仅在管理端列表中填充
+	CooldownUntil int64 `json:"cooldown_until,omitempty" gorm:"-"`
+}
+
+// IsKeyPool 渠道是否启用了密钥池
+func (channel *Channel) IsKeyPool() bool {
+	return channel.KeyMode == ChannelKeyModeRoundRobin || channel.KeyMode == ChannelKeyModeRandom
+}
+
+func GetChannelKeys(channelId int) ([]*ChannelKey, error) {
+	var keys []*ChannelKey
+	err := DB.Where("channel_id = ?", channelId).Order("id asc").Find(&keys).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range keys {
+		key.CooldownUntil = ChannelKeys.cooldownUntil(key.Id)
+	}
+	return keys, nil
+}
+
+func GetChannelKeyById(channelId, keyId int) (*ChannelKey, error) {
+	key := &ChannelKey{}
+	err := DB.Where("id = ? AND channel_id = ?", keyId, channelId).First(key).Error
+	return key, err
+}
+
+// ImportChannelKeys 批量导入密钥，每行一个，跳过空行和池中已有的密钥，返回导入数量
+func ImportChannelKeys(channelId int, content, remark string) (int, error) {
+	var existing []string
+	if err := DB.Model(&ChannelKey{}).Where("channel_id = ?", channelId).Pluck("key", &existing).Error; err != nil {
+		return 0, err
+	}
+	seen := make(map[string]bool, len(existing))
+	for _, key := range existing {
+		seen[key] = true
+	}
+
+	now := utils.GetTimestamp()
+	keys := make([]*ChannelKey, 0)
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || seen[line] {
+			continue
+		}
+		seen[line] = true
+		keys = append(keys, &ChannelKey{
+			ChannelId:   channelId,
+			Key:         line,
+			Remark:      remark,
+			Status:      config.ChannelStatusEnabled,
+			CreatedTime: now,
+		})
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	if err := DB.CreateInBatches(keys, 100).Error; err != nil {
+		return 0, err
+	}
+	ChannelGroup.Reload()
+	return len(keys), nil
+}
+
+// ExportChannelKeys 导出密钥，每行一个；onlyEnabled 为 true 时只导出启用的密钥
+func ExportChannelKeys(channelId int, onlyEnabled bool) (string, error) {
+	var keys []string
+	tx := DB.Model(&ChannelKey{}).Where("channel_id = ?", channelId)
+	if onlyEnabled {
+		tx = tx.Where("status = ?", config.ChannelStatusEnabled)
+	}
+	if err := tx.Order("id asc").Pluck("key", &keys).Error; err != nil {
+		return "", err
+	}
+	return strings.Join(keys, "\n"), nil
+}
+
+// Update 修改密钥的状态和备注，启用时清除禁用原因和冷却
+func (key *ChannelKey) Update() error {
+	updates := map[string]any{
+		"status": key.Status,
+		"remark": key.Remark,
+	}
+	if key.Status == config.ChannelStatusEnabled {
+		updates["status_reason"] = ""
+	}
+
+	err := DB.Model(&ChannelKey{}).Where("id = ? AND channel_id = ?", key.Id, key.ChannelId).Updates(updates).Error
+	if err != nil {
+		return err
+	}
+
+	if key.Status == config.ChannelStatusEnabled {
+		ChannelKeys.cooldowns.Delete(key.Id)
+	}
+	ChannelGroup.Reload()
+	return nil
+}
+
+func DeleteChannelKey(channelId, keyId int) error {
+	err := DB.Where("id = ? AND channel_id = ?", keyId, channelId).Delete(&ChannelKey{}).Error
+	if err != nil {
+		return err
+	}
+	ChannelGroup.Reload()
+	return nil
+}
+
+// DeleteDisabledChannelKeys 删除渠道中所有已禁用的密钥
+func DeleteDisabledChannelKeys(channelId int) (int64, error) {
+	result := DB.Where("channel_id = ? AND status <> ?", channelId, config.ChannelStatusEnabled).Delete(&ChannelKey{})
+	if result.Error == nil && result.RowsAffected > 0 {
+		ChannelGroup.Reload()
+	}
+	return result.RowsAffected, result.Error
+}
+
+// maskStatusReason 禁用原因可能来自上游的错误信息，脱敏后截断到字段长度
+func maskStatusReason(reason string) string {
+	reason = utils.MaskSensitiveInfo(reason)
+	if len([]rune(reason)) > 255 {
+		reason = string([]rune(reason)[:255])
+	}
+
+	return reason
+}
+
+// DisableChannelKey 自动禁用密钥，返回渠道池中剩余的启用密钥数
+func DisableChannelKey(channelId, keyId int, reason string) (int64, error) {
+	reason = maskStatusReason(reason)
+
+	err := DB.Model(&ChannelKey{}).
+		Where("id = ? AND channel_id = ? AND status = ?", keyId, channelId, config.ChannelStatusEnabled).
+		Updates(map[string]any{"status": config.ChannelStatusAutoDisabled, "status_reason": reason}).Error
+	if err != nil {
+		return 0, err
+	}
+	ChannelKeys.remove(channelId, keyId)
+	PublishCacheReload()
+
+	var remaining int64
+	err = DB.Model(&ChannelKey{}).Where("channel_id = ? AND status = ?", channelId, config.ChannelStatusEnabled).Count(&remaining).Error
+	return remaining, err
+}
+
+func UpdateChannelKeyUsage(keyId int, quota int) {
+	if keyId == 0 {
+		return
+	}
+
+	if config.BatchUpdateEnabled {
+		addNewRecord(BatchUpdateTypeChannelKeyUsedQuota, keyId, quota)
+		addNewRecord(BatchUpdateTypeChannelKeyRequestCount, keyId, 1)
+		return
+	}
+	updateChannelKeyUsage(keyId, quota)
+}
+
+func updateChannelKeyUsage(keyId int, quota int) {
+	err := DB.Model(&ChannelKey{}).Where("id = ?", keyId).Updates(map[string]any{
+		"used_quota":    gorm.Expr("used_quota + ?", quota),
+		"request_count": gorm.Expr("request_count + ?", 1),
+	}).Error
+	if err != nil {
+		logger.SysError("failed to update channel key usage: " + err.Error())
+	}
+}
+
+// channelKeyPool 单个渠道启用中的密钥
+type channelKeyPool struct {
+	mode   string
+	keys   []*ChannelKey
+	cursor atomic.Uint64
+}
+
+type channelKeyRegistry struct {
+	sync.RWMutex
+	pools map[int]*channelKeyPool
+	// cooldowns 密钥冷却截止时间（keyId -> unix 秒）
+	cooldowns sync.Map
+}
+
+// ChannelKeys 多密钥渠道的密钥池，随渠道缓存一起加载
+var ChannelKeys = &channelKeyRegistry{pools: make(map[int]*channelKeyPool)}
+
+// load 加载启用了密钥池的渠道的启用密钥
+func (r *channelKeyRegistry) load(channels []*Channel) {
+	pools := make(map[int]*channelKeyPool)
+	channelIds := make([]int, 0)
+	for _, channel := range channels {
+		if channel.IsKeyPool() {
+			pools[channel.Id] = &channelKeyPool{mode: channel.KeyMode}
+			channelIds = append(channelIds, channel.Id)
+		}
+	}
+
+	if len(channelIds) > 0 {
+		var keys []*ChannelKey
+		err := DB.Where("channel_id IN ? AND status = ?", channelIds, config.ChannelStatusEnabled).Order("id asc").Find(&keys).Error
+		if err != nil {
+			logger.SysError("failed to load channel keys: " + err.Error())
+		}
+		for _, key := range keys {
+			pools[key.ChannelId].keys = append(pools[key.ChannelId].keys, key)
+		}
+	}
+
+	r.Lock()
+	// 重新加载时保留轮询位置，避免每次重载都从第一个密钥开始
+	for channelId, pool := range pools {
+		if old, ok := r.pools[channelId]; ok {
+			pool.cursor.Store(old.cursor.Load())
+		}
+	}
+	r.pools = pools
+	r.Unlock()
+}
+
+func (r *channelKeyRegistry) getPool(channelId int) *channelKeyPool {
+	r.RLock()
+	defer r.RUnlock()
+	return r.pools[channelId]
+}
+
+func (r *channelKeyRegistry) remove(channelId, keyId int) {
+	r.Lock()
+	defer r.Unlock()
+	pool, ok := r.pools[channelId]
+	if !ok {
+		return
+	}
+
+	keys := make([]*ChannelKey, 0, len(pool.keys))
+	for _, key := range pool.keys {
+		if key.Id != keyId {
+			keys = append(keys, key)
+		}
+	}
+	newPool := &channelKeyPool{mode: pool.mode, keys: keys}
+	newPool.cursor.Store(pool.cursor.Load())
+	r.pools[channelId] = newPool
+}
+
+func (r *channelKeyRegistry) cooldownUntil(keyId int) int64 {
+	if value, ok := r.cooldowns.Load(keyId); ok {
+		if until := value.(int64); until > time.Now().Unix() {
+			return until
+		}
+	}
+	return 0
+}
+
+// SetCooldown 密钥冷却 durationSeconds 秒，期间轮换时跳过，并通知其他节点
+func (r *channelKeyRegistry) SetCooldown(keyId int, durationSeconds int64) {
+	until := time.Now().Unix() + durationSeconds
+	r.applyCooldown(keyId, until)
+	publishKeyCooldown(keyId, until)
+}
+
+// applyCooldown 只会延长不会缩短
+func (r *channelKeyRegistry) applyCooldown(keyId int, until int64) {
+	for {
+		actual, loaded := r.cooldowns.LoadOrStore(keyId, until)
+		if !loaded || actual.(int64) >= until {
+			return
+		}
+		if r.cooldowns.CompareAndSwap(keyId, actual, until) {
+			return
+		}
+	}
+}
+
+// Cleanup 清理过期的密钥冷却
+func (r *channelKeyRegistry) Cleanup() {
+	now := time.Now().Unix()
+	r.cooldowns.Range(func(key, value any) bool {
+		if value.(int64) <= now {
+			r.cooldowns.Delete(key)
+		}
+		return true
+	})
+}
+
+// Available 渠道是否还有可用的密钥。未启用密钥池或池为空（使用渠道自身的密钥）时返回 true
+func (r *channelKeyRegistry) Available(channel *Channel) bool {
+	if !channel.IsKeyPool() {
+		return true
+	}
+
+	pool := r.getPool(channel.Id)
+	if pool == nil || len(pool.keys) == 0 {
+		return true
+	}
+
+	now := time.Now().Unix()
+	for _, key := range pool.keys {
+		if r.cooldownUntil(key.Id) <= now {
+			return true
+		}
+	}
+	return false
+}
+
+// Next 按渠道的轮换方式选择下一个不在冷却中的密钥。
+// 未启用密钥池或池为空时返回 nil，使用渠道自身的密钥；池中密钥全部冷却时返回错误。
+func (r *channelKeyRegistry) Next(channel *Channel) (*ChannelKey, error) {
+	if !channel.IsKeyPool() {
+		return nil, nil
+	}
+
+	pool := r.getPool(channel.Id)
+	if pool == nil || len(pool.keys) == 0 {
+		return nil, nil
+	}
+
+	count := len(pool.keys)
+	var start int
+	if pool.mode == ChannelKeyModeRandom {
+		start = rand.Intn(count)
+	} else {
+		start = int((pool.cursor.Add(1) - 1) % uint64(count))
+	}
+
+	now := time.Now().Unix()
+	for i := 0; i < count; i++ {
+		key := pool.keys[(start+i)%count]
+		if r.cooldownUntil(key.Id) <= now {
+			return key, nil
+		}
+	}
+	return nil, ErrNoAvailableChannelKey
+}
+
+// find 在池中查找密钥，不在池中（已禁用等）时从数据库读取
+func (r *channelKeyRegistry) find(channelId, keyId int) *ChannelKey {
+	if pool := r.getPool(channelId); pool != nil {
+		for _, key := range pool.keys {
+			if key.Id == keyId {
+				return key
+			}
+		}
+	}
+
+	key, err := GetChannelKeyById(channelId, keyId)
+	if err != nil {
+		return nil
+	}
+	return key
+}
+
+// withKey 返回使用指定密钥的渠道副本，不修改缓存中的渠道
+func withKey(channel *Channel, key *ChannelKey) *Channel {
+	if key == nil {
+		return channel
+	}
+	copied := *channel
+	copied.Key = key.Key
+	return &copied
+}
+
+// BindChannelKey 为本次请求从密钥池中选择密钥，返回使用该密钥的渠道副本，并在上下文中记录密钥 ID。
+// 上下文中指定了密钥（如异步任务的后续操作需沿用提交时的密钥）时优先使用指定的密钥。
+func BindChannelKey(c *gin.Context, channel *Channel) (*Channel, error) {
+	c.Set(config.GinChannelKeyIdKey, 0)
+	if !channel.IsKeyPool() {
+		return channel, nil
+	}
+
+	var key *ChannelKey
+	if keyId := c.GetInt(config.GinSpecificChannelKeyIdKey); keyId > 0 {
+		key = ChannelKeys.find(channel.Id, keyId)
+	}
+	if key == nil {
+		var err error
+		if key, err = ChannelKeys.Next(channel); err != nil {
+			return nil, err
+		}
+	}
+	if key == nil {
+		return channel, nil
+	}
+
+	c.Set(config.GinChannelKeyIdKey, key.Id)
+	return withKey(channel, key), nil
+}
+
+// ChannelWithKey 返回使用指定密钥的渠道副本，用于异步任务轮询等没有请求上下文的场景。
+// keyId 为 0 或密钥已删除时使用池中的下一个密钥。
+func ChannelWithKey(channel *Channel, keyId int) *Channel {
+	if !channel.IsKeyPool() {
+		return channel
+	}
+
+	var key *ChannelKey
+	if keyId > 0 {
+		key = ChannelKeys.find(channel.Id, keyId)
+	}
+	if key == nil {
+		key, _ = ChannelKeys.Next(channel)
+	}
+	if key == nil && ChannelKeys.getPool(channel.Id) == nil {
+		// 渠道未启用时密钥池不在内存中，使用第一个启用的密钥
+		first := &ChannelKey{}
+		if err := DB.Where("channel_id = ? AND status = ?", channel.Id, config.ChannelStatusEnabled).Order("id asc").First(first).Error; err == nil {
+			key = first
+		}
+	}
+	return withKey(channel, key)
+}
+
+// MaskChannelKey 列表中展示的脱敏密钥
+func MaskChannelKey(key string) string {
+	if len(key) <= 12 {
+		return strings.Repeat("*", len(key))
+	}
+	return fmt.Sprintf("%s...%s", key[:6], key[len(key)-4:])
+}
@@ -0,0 +1,197 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"done-hub/common/config"
+)
+
+// setupChannelKeyTestDB 在渠道测试库上增加密钥表，并使用独立的密钥池
+func setupChannelKeyTestDB(t *testing.T) {
+	t.Helper()
+	setupChannelTestDB(t)
+	if err := DB.AutoMigrate(&ChannelKey{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	saved := ChannelKeys
+	t.Cleanup(func() { ChannelKeys = saved })
+	ChannelKeys = &channelKeyRegistry{pools: make(map[int]*channelKeyPool)}
+}
+
+// createKeyPoolTestChannel 创建启用密钥池的渠道及 count 个启用的密钥，并加载到密钥池
+func createKeyPoolTestChannel(t *testing.T, id int, mode string, count int) (*Channel, []*ChannelKey) {
+	t.Helper()
+	channel := createBudgetTestChannel(t, id, config.ChannelStatusEnabled, ChannelBudget{})
+	channel.KeyMode = mode
+	if err := DB.Model(channel).Update("key_mode", mode).Error; err != nil {
+		t.Fatalf("update key mode: %v", err)
+	}
+
+	keys := make([]*ChannelKey, 0, count)
+	for i := 1; i <= count; i++ {
+		keys = append(keys, &ChannelKey{ChannelId: id, Key: fmt.Sprintf("sk-pool-%d", i), Status: config.ChannelStatusEnabled})
+	}
+	if err := DB.Create(&keys).Error; err != nil {
+		t.Fatalf("create keys: %v", err)
+	}
+	ChannelKeys.load([]*Channel{channel})
+	return channel, keys
+}
+
+// TestChannelKeysNext 测试密钥轮换：轮询按顺序使用、随机覆盖所有密钥，冷却中的密钥被跳过
+func TestChannelKeysNext(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		cooldown []int // 冷却的密钥序号（从 0 开始）
+		want     []int // 轮询时依次选中的密钥序号
+		wantErr  bool
+	}{
+		{name: "轮询按顺序循环使用", mode: ChannelKeyModeRoundRobin, want: []int{0, 1, 2, 0, 1, 2}},
+		{name: "轮询跳过冷却中的密钥", mode: ChannelKeyModeRoundRobin, cooldown: []int{1}, want: []int{0, 2, 2, 0, 2, 2}},
+		{name: "轮询全部冷却时返回错误", mode: ChannelKeyModeRoundRobin, cooldown: []int{0, 1, 2}, wantErr: true},
+		{name: "随机覆盖所有密钥", mode: ChannelKeyModeRandom},
+		{name: "随机跳过冷却中的密钥", mode: ChannelKeyModeRandom, cooldown: []int{0, 2}},
+		{name: "随机全部冷却时返回错误", mode: ChannelKeyModeRandom, cooldown: []int{0, 1, 2}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupChannelKeyTestDB(t)
+			channel, keys := createKeyPoolTestChannel(t, 1, tt.mode, 3)
+			cooled := make(map[int]bool)
+			for _, index := range tt.cooldown {
+				ChannelKeys.SetCooldown(keys[index].Id, 60)
+				cooled[keys[index].Id] = true
+			}
+
+			if available := ChannelKeys.Available(channel); available == tt.wantErr {
+				t.Errorf("Available() = %v, 期望 %v", available, !tt.wantErr)
+			}
+			if tt.wantErr {
+				if _, err := ChannelKeys.Next(channel); !errors.Is(err, ErrNoAvailableChannelKey) {
+					t.Errorf("Next() error = %v, 期望 ErrNoAvailableChannelKey", err)
+				}
+				return
+			}
+
+			if tt.mode == ChannelKeyModeRoundRobin {
+				for i, index := range tt.want {
+					key, err := ChannelKeys.Next(channel)
+					if err != nil || key == nil || key.Id != keys[index].Id {
+						t.Fatalf("第 %d 次 Next() = %v, %v, 期望密钥 %d", i+1, key, err, keys[index].Id)
+					}
+				}
+				return
+			}
+
+			seen := make(map[int]bool)
+			for i := 0; i < 200; i++ {
+				key, err := ChannelKeys.Next(channel)
+				if err != nil || key == nil {
+					t.Fatalf("Next() = %v, %v", key, err)
+				}
+				if cooled[key.Id] {
+					t.Fatalf("选中了冷却中的密钥 %d", key.Id)
+				}
+				seen[key.Id] = true
+			}
+			if len(seen) != len(keys)-len(cooled) {
+				t.Errorf("随机选中的密钥 = %v, 期望覆盖全部 %d 个可用密钥", seen, len(keys)-len(cooled))
+			}
+		})
+	}
+}
+
+// TestChannelKeysReloadKeepsCursor 测试重新加载密钥池后轮询从上次的位置继续
+func TestChannelKeysReloadKeepsCursor(t *testing.T) {
+	setupChannelKeyTestDB(t)
+	channel, keys := createKeyPoolTestChannel(t, 1, ChannelKeyModeRoundRobin, 3)
+
+	if key, _ := ChannelKeys.Next(channel); key.Id != keys[0].Id {
+		t.Fatalf("第一次 Next() = %d, 期望 %d", key.Id, keys[0].Id)
+	}
+	ChannelKeys.load([]*Channel{channel})
+	if key, _ := ChannelKeys.Next(channel); key.Id != keys[1].Id {
+		t.Errorf("重新加载后 Next() = %d, 期望 %d", key.Id, keys[1].Id)
+	}
+}
+
+// TestBindChannelKey 测试请求绑定密钥：使用密钥的渠道副本不修改缓存中的渠道，指定的密钥优先
+func TestBindChannelKey(t *testing.T) {
+	setupChannelKeyTestDB(t)
+	channel, keys := createKeyPoolTestChannel(t, 1, ChannelKeyModeRoundRobin, 3)
+
+	c := newLimitContext()
+	bound, err := BindChannelKey(c, channel)
+	if err != nil {
+		t.Fatalf("BindChannelKey() error = %v", err)
+	}
+	if bound.Key != keys[0].Key || c.GetInt(config.GinChannelKeyIdKey) != keys[0].Id {
+		t.Errorf("绑定的密钥 = %s (id %d), 期望 %s (id %d)", bound.Key, c.GetInt(config.GinChannelKeyIdKey), keys[0].Key, keys[0].Id)
+	}
+	if channel.Key != "sk-test" {
+		t.Errorf("缓存中的渠道密钥被修改为 %s", channel.Key)
+	}
+
+	c = newLimitContext()
+	c.Set(config.GinSpecificChannelKeyIdKey, keys[2].Id)
+	if bound, _ = BindChannelKey(c, channel); bound.Key != keys[2].Key {
+		t.Errorf("指定密钥时绑定 = %s, 期望 %s", bound.Key, keys[2].Key)
+	}
+
+	plain := createBudgetTestChannel(t, 2, config.ChannelStatusEnabled, ChannelBudget{})
+	c = newLimitContext()
+	if bound, _ = BindChannelKey(c, plain); bound != plain || c.GetInt(config.GinChannelKeyIdKey) != 0 {
+		t.Errorf("未启用密钥池时应使用渠道自身的密钥")
+	}
+}
+
+// TestDisableChannelKey 测试自动禁用只影响出错的密钥，池中其他密钥照常使用
+func TestDisableChannelKey(t *testing.T) {
+	setupChannelKeyTestDB(t)
+	channel, keys := createKeyPoolTestChannel(t, 1, ChannelKeyModeRoundRobin, 3)
+
+	remaining, err := DisableChannelKey(channel.Id, keys[1].Id, "Incorrect API key provided: sk-pool-2")
+	if err != nil || remaining != 2 {
+		t.Fatalf("DisableChannelKey() = %d, %v, 期望剩余 2", remaining, err)
+	}
+
+	stored, _ := GetChannelKeys(channel.Id)
+	for i, key := range stored {
+		want := config.ChannelStatusEnabled
+		if i == 1 {
+			want = config.ChannelStatusAutoDisabled
+		}
+		if key.Status != want {
+			t.Errorf("密钥 %d 状态 = %d, 期望 %d", key.Id, key.Status, want)
+		}
+	}
+	if stored[1].StatusReason == "" {
+		t.Error("禁用的密钥应记录禁用原因")
+	}
+
+	for i := 0; i < 6; i++ {
+		key, err := ChannelKeys.Next(channel)
+		if err != nil || key.Id == keys[1].Id {
+			t.Fatalf("禁用后 Next() = %v, %v, 不应选中已禁用的密钥", key, err)
+		}
+	}
+
+	// 重复禁用不改变剩余数量
+	if remaining, _ = DisableChannelKey(channel.Id, keys[1].Id, "again"); remaining != 2 {
+		t.Errorf("重复禁用后剩余 = %d, 期望 2", remaining)
+	}
+	DisableChannelKey(channel.Id, keys[0].Id, "invalid")
+	if remaining, _ = DisableChannelKey(channel.Id, keys[2].Id, "invalid"); remaining != 0 {
+		t.Errorf("全部禁用后剩余 = %d, 期望 0", remaining)
+	}
+
+	reloaded, _ := GetChannelById(channel.Id)
+	if reloaded.Status != config.ChannelStatusEnabled {
+		t.Errorf("禁用密钥不应改变渠道状态，当前 = %d", reloaded.Status)
+	}
+}
@@ -0,0 +1,215 @@
+package model
+
+import (
+	"done-hub/common/config"
+	"done-hub/common/limit"
+	"done-hub/common/utils"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	channelLimitWindow = time.Minute
+	// channelLeaseTTL 并发名额的租约时长，正常情况下请求结束即释放，超时只用于回收异常遗留的名额
+	channelLeaseTTL = 30 * time.Minute
+
+	channelConcurrencyKey = "channel-concurrency:%s"
+	channelRPMKey         = "channel-rpm:%s"
+	channelTPMKey         = "channel-tpm:%s"
+)
+
+// ChannelLimit 上游限额，0 表示不限制
+type ChannelLimit struct {
+	Concurrency int `json:"concurrency,omitempty"`
+	RPM         int `json:"rpm,omitempty"`
+	TPM         int `json:"tpm,omitempty"`
+}
+
+func (l ChannelLimit) IsEmpty() bool {
+	return l.Concurrency <= 0 && l.RPM <= 0 && l.TPM <= 0
+}
+
+// ChannelRateLimit 渠道整体限额，Models 为按模型（选渠道时匹配到的模型名）单独设置的限额，两者同时生效
+type ChannelRateLimit struct {
+	ChannelLimit
+	Models map[string]ChannelLimit `json:"models,omitempty"`
+}
+
+type channelLimitLevel struct {
+	key   string
+	limit ChannelLimit
+}
+
+// channelLease 一次尝试占用的并发名额
+type channelLease struct {
+	id   string
+	keys []string
+}
+
+type channelLimitRegistry struct {
+	once        sync.Once
+	concurrency limit.ConcurrencyLimiter
+	tpm         limit.WindowCounter
+
+	rpmMutex sync.Mutex
+	rpm      map[int]limit.RateLimiter
+}
+
+// ChannelLimits 渠道并发、RPM、TPM 限额。启用 Redis 时多节点共享计数。
+var ChannelLimits = &channelLimitRegistry{}
+
+// init 限流器需在 Redis 初始化之后创建，首次使用时再初始化
+func (r *channelLimitRegistry) init() {
+	r.once.Do(func() {
+		r.concurrency = limit.NewConcurrencyLimiter(channelLeaseTTL)
+		r.tpm = limit.NewWindowCounter(channelLimitWindow)
+		r.rpm = make(map[int]limit.RateLimiter)
+	})
+}
+
+// rpmLimiter 限流器的速率是创建时确定的，按 RPM 值复用，计数按渠道 key 区分
+func (r *channelLimitRegistry) rpmLimiter(rpm int) limit.RateLimiter {
+	r.rpmMutex.Lock()
+	defer r.rpmMutex.Unlock()
+
+	limiter, ok := r.rpm[rpm]
+	if !ok {
+		if config.RedisEnabled {
+			limiter = limit.NewCountLimiter(rpm, rpm, channelLimitWindow)
+		} else {
+			limiter = limit.NewMemoryLimiter(rpm, rpm, channelLimitWindow, false)
+		}
+		r.rpm[rpm] = limiter
+	}
+	return limiter
+}
+
+func channelLimitLevels(channel *Channel, modelName string) []channelLimitLevel {
+	if channel.RateLimit == nil {
+		return nil
+	}
+
+	setting := channel.RateLimit.Data()
+	levels := make([]channelLimitLevel, 0, 2)
+	if !setting.ChannelLimit.IsEmpty() {
+		levels = append(levels, channelLimitLevel{key: fmt.Sprintf("%d", channel.Id), limit: setting.ChannelLimit})
+	}
+	if modelLimit, ok := setting.Models[modelName]; ok && !modelLimit.IsEmpty() {
+		levels = append(levels, channelLimitLevel{key: fmt.Sprintf("%d:%s", channel.Id, modelName), limit: modelLimit})
+	}
+	return levels
+}
+
+// Saturated 渠道或渠道+模型任一限额已满时返回 true，选择渠道时直接跳过，不会被尝试和冷却
+func (r *channelLimitRegistry) Saturated(channel *Channel, modelName string) bool {
+	levels := channelLimitLevels(channel, modelName)
+	if len(levels) == 0 {
+		return false
+	}
+
+	r.init()
+	for _, level := range levels {
+		if level.limit.Concurrency > 0 {
+			if current, err := r.concurrency.GetCurrent(fmt.Sprintf(channelConcurrencyKey, level.key)); err == nil && current >= level.limit.Concurrency {
+				return true
+			}
+		}
+		if level.limit.RPM > 0 {
+			if current, err := r.rpmLimiter(level.limit.RPM).GetCurrentRate(fmt.Sprintf(channelRPMKey, level.key)); err == nil && current >= level.limit.RPM {
+				return true
+			}
+		}
+		if level.limit.TPM > 0 {
+			if current, err := r.tpm.GetCurrent(fmt.Sprintf(channelTPMKey, level.key)); err == nil && current >= level.limit.TPM {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Acquire 选中渠道后占用并发名额与 RPM 计数，任一限额已满返回 false。
+// 并发名额记录在请求上下文中，需在尝试结束后调用 ReleaseChannelLease；没有上下文时只限制 RPM。
+func (r *channelLimitRegistry) Acquire(channel *Channel, modelName string, ginContext interface{}) bool {
+	levels := channelLimitLevels(channel, modelName)
+	if len(levels) == 0 {
+		return true
+	}
+
+	r.init()
+	c, _ := ginContext.(*gin.Context)
+	// 同一请求同时只占用一个渠道的名额
+	ReleaseChannelLease(c)
+
+	lease := &channelLease{id: utils.GetRandomString(16)}
+	if c != nil {
+		for _, level := range levels {
+			if level.limit.Concurrency <= 0 {
+				continue
+			}
+			key := fmt.Sprintf(channelConcurrencyKey, level.key)
+			if !r.concurrency.Acquire(key, lease.id, level.limit.Concurrency) {
+				r.release(lease)
+				return false
+			}
+			lease.keys = append(lease.keys, key)
+		}
+	}
+
+	for _, level := range levels {
+		if level.limit.RPM > 0 && !r.rpmLimiter(level.limit.RPM).Allow(fmt.Sprintf(channelRPMKey, level.key)) {
+			r.release(lease)
+			return false
+		}
+	}
+
+	if c != nil && len(lease.keys) > 0 {
+		c.Set(config.GinChannelLeaseKey, lease)
+	}
+	return true
+}
+
+// RecordTokens 请求结束后累计 TPM 用量
+func (r *channelLimitRegistry) RecordTokens(channel *Channel, modelName string, tokens int) {
+	if tokens <= 0 {
+		return
+	}
+
+	levels := channelLimitLevels(channel, modelName)
+	if len(levels) == 0 {
+		return
+	}
+
+	r.init()
+	for _, level := range levels {
+		if level.limit.TPM > 0 {
+			r.tpm.Add(fmt.Sprintf(channelTPMKey, level.key), tokens)
+		}
+	}
+}
+
+func (r *channelLimitRegistry) release(lease *channelLease) {
+	for _, key := range lease.keys {
+		r.concurrency.Release(key, lease.id)
+	}
+}
+
+// ReleaseChannelLease 释放请求当前占用的渠道并发名额，可重复调用
+func ReleaseChannelLease(c *gin.Context) {
+	if c == nil {
+		return
+	}
+
+	value, ok := c.Get(config.GinChannelLeaseKey)
+	if !ok || value == nil {
+		return
+	}
+	c.Set(config.GinChannelLeaseKey, nil)
+
+	if lease, ok := value.(*channelLease); ok {
+		ChannelLimits.release(lease)
+	}
+}
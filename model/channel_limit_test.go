@@ -0,0 +1,231 @@
+package model
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"done-hub/common/config"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+)
+
+// setupChannelLimits 每个测试使用独立的内存限流器
+func setupChannelLimits(t *testing.T) {
+	t.Helper()
+	saved := ChannelLimits
+	t.Cleanup(func() { ChannelLimits = saved })
+	ChannelLimits = &channelLimitRegistry{}
+}
+
+func newLimitChoice(id int, setting ChannelRateLimit) *ChannelChoice {
+	choice := newTestChoice(id, 1)
+	value := datatypes.NewJSONType(setting)
+	choice.Channel.RateLimit = &value
+	return choice
+}
+
+func newLimitContext() *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	return c
+}
+
+// TestChannelLimitsAcquire 测试并发与 RPM 名额的占用：渠道整体与按模型的限额同时生效，释放后并发名额归还
+func TestChannelLimitsAcquire(t *testing.T) {
+	type step struct {
+		action string // acquire、acquire_no_ctx、release、tokens
+		ctx    int
+		model  string
+		tokens int
+		want   bool
+	}
+	tests := []struct {
+		name      string
+		setting   ChannelRateLimit
+		steps     []step
+		saturated map[string]bool
+	}{
+		{
+			name:      "不限制时总是放行",
+			setting:   ChannelRateLimit{},
+			steps:     []step{{action: "acquire", ctx: 1, want: true}, {action: "acquire", ctx: 2, want: true}, {action: "acquire", ctx: 3, want: true}},
+			saturated: map[string]bool{"gpt-4o": false},
+		},
+		{
+			name:    "并发达到上限后拒绝，释放后放行",
+			setting: ChannelRateLimit{ChannelLimit: ChannelLimit{Concurrency: 2}},
+			steps: []step{
+				{action: "acquire", ctx: 1, want: true},
+				{action: "acquire", ctx: 2, want: true},
+				{action: "acquire", ctx: 3, want: false},
+				{action: "release", ctx: 1},
+				{action: "release", ctx: 1},
+				{action: "acquire", ctx: 3, want: true},
+			},
+			saturated: map[string]bool{"gpt-4o": true},
+		},
+		{
+			name:    "同一请求重新选择渠道时先释放上一次的名额",
+			setting: ChannelRateLimit{ChannelLimit: ChannelLimit{Concurrency: 1}},
+			steps: []step{
+				{action: "acquire", ctx: 1, want: true},
+				{action: "acquire", ctx: 1, want: true},
+				{action: "acquire", ctx: 2, want: false},
+			},
+			saturated: map[string]bool{"gpt-4o": true},
+		},
+		{
+			name:    "没有请求上下文时不占用并发名额",
+			setting: ChannelRateLimit{ChannelLimit: ChannelLimit{Concurrency: 1}},
+			steps: []step{
+				{action: "acquire_no_ctx", want: true},
+				{action: "acquire_no_ctx", want: true},
+				{action: "acquire", ctx: 1, want: true},
+			},
+			saturated: map[string]bool{"gpt-4o": true},
+		},
+		{
+			name:    "RPM 达到上限后拒绝，释放不归还 RPM",
+			setting: ChannelRateLimit{ChannelLimit: ChannelLimit{RPM: 2}},
+			steps: []step{
+				{action: "acquire", ctx: 1, want: true},
+				{action: "release", ctx: 1},
+				{action: "acquire_no_ctx", want: true},
+				{action: "acquire", ctx: 2, want: false},
+			},
+			saturated: map[string]bool{"gpt-4o": true},
+		},
+		{
+			name: "按模型的限额只影响该模型",
+			setting: ChannelRateLimit{Models: map[string]ChannelLimit{
+				"gpt-4o": {Concurrency: 1},
+			}},
+			steps: []step{
+				{action: "acquire", ctx: 1, model: "gpt-4o", want: true},
+				{action: "acquire", ctx: 2, model: "gpt-4o", want: false},
+				{action: "acquire", ctx: 3, model: "gpt-4o-mini", want: true},
+				{action: "acquire", ctx: 4, model: "gpt-4o-mini", want: true},
+			},
+			saturated: map[string]bool{"gpt-4o": true, "gpt-4o-mini": false},
+		},
+		{
+			name: "渠道整体限额覆盖所有模型",
+			setting: ChannelRateLimit{
+				ChannelLimit: ChannelLimit{Concurrency: 2},
+				Models:       map[string]ChannelLimit{"gpt-4o": {Concurrency: 1}},
+			},
+			steps: []step{
+				{action: "acquire", ctx: 1, model: "gpt-4o", want: true},
+				{action: "acquire", ctx: 2, model: "gpt-4o-mini", want: true},
+				{action: "acquire", ctx: 3, model: "gpt-4o-mini", want: false},
+			},
+			saturated: map[string]bool{"gpt-4o": true, "gpt-4o-mini": true},
+		},
+		{
+			name: "按模型拒绝时归还已占用的渠道整体名额",
+			setting: ChannelRateLimit{
+				ChannelLimit: ChannelLimit{Concurrency: 2},
+				Models:       map[string]ChannelLimit{"gpt-4o": {Concurrency: 1}},
+			},
+			steps: []step{
+				{action: "acquire", ctx: 1, model: "gpt-4o", want: true},
+				{action: "acquire", ctx: 2, model: "gpt-4o", want: false},
+				{action: "acquire", ctx: 3, model: "gpt-4o-mini", want: true},
+			},
+			saturated: map[string]bool{"gpt-4o": true, "gpt-4o-mini": true},
+		},
+		{
+			name:    "TPM 在请求结束后累计，达到上限后饱和",
+			setting: ChannelRateLimit{ChannelLimit: ChannelLimit{TPM: 100}},
+			steps: []step{
+				{action: "tokens", tokens: 60},
+				{action: "acquire", ctx: 1, want: true},
+				{action: "tokens", tokens: 40},
+			},
+			saturated: map[string]bool{"gpt-4o": true},
+		},
+		{
+			name:      "TPM 未达到上限",
+			setting:   ChannelRateLimit{ChannelLimit: ChannelLimit{TPM: 100}},
+			steps:     []step{{action: "tokens", tokens: 99}, {action: "tokens", tokens: -10}},
+			saturated: map[string]bool{"gpt-4o": false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupChannelLimits(t)
+			channel := newLimitChoice(1, tt.setting).Channel
+			contexts := make(map[int]*gin.Context)
+
+			for i, s := range tt.steps {
+				modelName := s.model
+				if modelName == "" {
+					modelName = "gpt-4o"
+				}
+				if s.ctx > 0 && contexts[s.ctx] == nil {
+					contexts[s.ctx] = newLimitContext()
+				}
+
+				switch s.action {
+				case "acquire":
+					if got := ChannelLimits.Acquire(channel, modelName, contexts[s.ctx]); got != s.want {
+						t.Fatalf("第 %d 步 Acquire(%s) = %v, 期望 %v", i+1, modelName, got, s.want)
+					}
+				case "acquire_no_ctx":
+					if got := ChannelLimits.Acquire(channel, modelName, nil); got != s.want {
+						t.Fatalf("第 %d 步无上下文 Acquire(%s) = %v, 期望 %v", i+1, modelName, got, s.want)
+					}
+				case "release":
+					ReleaseChannelLease(contexts[s.ctx])
+				case "tokens":
+					ChannelLimits.RecordTokens(channel, modelName, s.tokens)
+				}
+			}
+
+			for modelName, want := range tt.saturated {
+				if got := ChannelLimits.Saturated(channel, modelName); got != want {
+					t.Errorf("Saturated(%s) = %v, 期望 %v", modelName, got, want)
+				}
+			}
+		})
+	}
+}
+
+// TestChannelLimitsBalancer 测试选择渠道时跳过已达上限的渠道，并在选中时占用名额
+func TestChannelLimitsBalancer(t *testing.T) {
+	setupChannelLimits(t)
+	savedBreaker := config.CircuitBreakerEnabled
+	t.Cleanup(func() { config.CircuitBreakerEnabled = savedBreaker })
+	config.CircuitBreakerEnabled = false
+
+	limited := newLimitChoice(1, ChannelRateLimit{ChannelLimit: ChannelLimit{Concurrency: 1}})
+	unlimited := newTestChoice(2, 1)
+	cc := &ChannelsChooser{Channels: map[int]*ChannelChoice{1: limited, 2: unlimited}}
+
+	first := newLimitContext()
+	if channel := cc.balancer([]int{1}, nil, "gpt-4o", RoutingStrategyWeight, first); channel == nil || channel.Id != 1 {
+		t.Fatalf("第一次选择 = %v, 期望渠道 1", channel)
+	}
+	if lease, ok := first.Get(config.GinChannelLeaseKey); !ok || lease == nil {
+		t.Fatal("选中设有并发上限的渠道后应在上下文中记录名额")
+	}
+
+	for i := 0; i < 20; i++ {
+		if channel := cc.balancer([]int{1, 2}, nil, "gpt-4o", RoutingStrategyWeight, newLimitContext()); channel == nil || channel.Id != 2 {
+			t.Fatalf("渠道 1 名额已满时选择 = %v, 期望渠道 2", channel)
+		}
+	}
+	if channel := cc.balancer([]int{1}, nil, "gpt-4o", RoutingStrategyWeight, newLimitContext()); channel != nil {
+		t.Errorf("只有已满的渠道时选择 = %d, 期望 nil", channel.Id)
+	}
+	if got := cc.countValidChannels([]int{1, 2}, nil, "gpt-4o"); got != 1 {
+		t.Errorf("countValidChannels() = %d, 期望 1", got)
+	}
+
+	ReleaseChannelLease(first)
+	if channel := cc.balancer([]int{1}, nil, "gpt-4o", RoutingStrategyWeight, newLimitContext()); channel == nil || channel.Id != 1 {
+		t.Errorf("释放名额后选择 = %v, 期望渠道 1", channel)
+	}
+}
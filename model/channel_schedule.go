@@ -0,0 +1,346 @@
+package model
+
+import (
+	"done-hub/common/logger"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// 计算下一个可用窗口时最多向后查找的时间
+const channelScheduleLookahead = 8 * 24 * time.Hour
+
+// ChannelScheduleWindow 渠道的一个可用时间窗口，cron 与 weekdays/start/end 二选一
+type ChannelScheduleWindow struct {
+	Cron     string `json:"cron,omitempty"`     // 标准 5 段 cron 表达式，匹配到的每一分钟都可用，如 "* 0-7 * * *" 为每天 0 点到 8 点
+	Weekdays []int  `json:"weekdays,omitempty"` // 0-6，0 为周日，为空表示每天
+	Start    string `json:"start,omitempty"`    // 开始时间 HH:MM
+	End      string `json:"end,omitempty"`      // 结束时间 HH:MM（不含），早于开始时间时跨越零点，与开始时间相同时为全天
+}
+
+// ChannelSchedule 渠道的可用时间，窗口之外选择渠道时跳过该渠道，不修改渠道状态
+type ChannelSchedule struct {
+	Timezone string                  `json:"timezone,omitempty"` // IANA 时区，如 Asia/Shanghai，为空时使用服务器时区
+	Windows  []ChannelScheduleWindow `json:"windows"`
+}
+
+// ChannelScheduleStatus 渠道当前是否在可用时间内以及下一个可用窗口，仅在管理端渠道列表中填充
+type ChannelScheduleStatus struct {
+	Active    bool  `json:"active"`
+	NextStart int64 `json:"next_start,omitempty"` // 下一个可用窗口的开始时间，当前可用时为 0
+	NextEnd   int64 `json:"next_end,omitempty"`   // 当前或下一个可用窗口的结束时间，查找范围内不结束时为 0
+}
+
+type scheduleWindow struct {
+	cron     *cron.SpecSchedule
+	weekdays map[time.Weekday]bool // 为 nil 时每天
+	start    int                   // 一天中的第几分钟
+	end      int
+}
+
+// channelScheduler 编译后的渠道可用时间，加载渠道时生成，选择渠道时按当前时间判断
+type channelScheduler struct {
+	location *time.Location
+	windows  []scheduleWindow
+	// 同一分钟内复用上次的判断结果：分钟数左移一位，最低位为是否可用
+	cache atomic.Int64
+}
+
+// ValidateSchedule 校验渠道的可用时间设置
+func (c *Channel) ValidateSchedule() error {
+	if c.Schedule == nil {
+		return nil
+	}
+	_, err := newChannelScheduler(c.Schedule.Data())
+
+	return err
+}
+
+// GetScheduleStatus 渠道当前的可用时间状态，未设置可用时间时返回 nil
+func (c *Channel) GetScheduleStatus(now time.Time) *ChannelScheduleStatus {
+	if c.Schedule == nil {
+		return nil
+	}
+	scheduler, err := newChannelScheduler(c.Schedule.Data())
+	if err != nil || scheduler == nil {
+		return nil
+	}
+
+	return scheduler.Status(now)
+}
+
+// loadChannelScheduler 加载渠道时编译可用时间，设置无效时记录日志并按不限制处理
+func loadChannelScheduler(channel *Channel) *channelScheduler {
+	if channel.Schedule == nil {
+		return nil
+	}
+	scheduler, err := newChannelScheduler(channel.Schedule.Data())
+	if err != nil {
+		logger.SysError(fmt.Sprintf("渠道 %d 的可用时间设置无效：%s", channel.Id, err.Error()))
+		return nil
+	}
+
+	return scheduler
+}
+
+// newChannelScheduler 编译可用时间，没有任何窗口时返回 nil，表示不限制
+func newChannelScheduler(schedule ChannelSchedule) (*channelScheduler, error) {
+	if len(schedule.Windows) == 0 {
+		return nil, nil
+	}
+
+	location := time.Local
+	if tz := strings.TrimSpace(schedule.Timezone); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("无效的时区 %s", tz)
+		}
+		location = loc
+	}
+
+	scheduler := &channelScheduler{location: location}
+	scheduler.cache.Store(-1)
+	for i, window := range schedule.Windows {
+		compiled, err := compileScheduleWindow(window)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 个可用时间窗口%s", i+1, err.Error())
+		}
+		scheduler.windows = append(scheduler.windows, compiled)
+	}
+
+	return scheduler, nil
+}
+
+func compileScheduleWindow(window ChannelScheduleWindow) (scheduleWindow, error) {
+	if expr := strings.TrimSpace(window.Cron); expr != "" {
+		if len(window.Weekdays) > 0 || window.Start != "" || window.End != "" {
+			return scheduleWindow{}, errors.New("不能同时设置 cron 与星期/时间")
+		}
+		schedule, err := cron.ParseStandard(expr)
+		if err != nil {
+			return scheduleWindow{}, fmt.Errorf("的 cron 表达式无效：%s", err.Error())
+		}
+		spec, ok := schedule.(*cron.SpecSchedule)
+		if !ok {
+			return scheduleWindow{}, errors.New("的 cron 表达式不支持 @every")
+		}
+		return scheduleWindow{cron: spec}, nil
+	}
+
+	start, err := parseScheduleClock(window.Start)
+	if err != nil {
+		return scheduleWindow{}, fmt.Errorf("的开始时间%s", err.Error())
+	}
+	end, err := parseScheduleClock(window.End)
+	if err != nil {
+		return scheduleWindow{}, fmt.Errorf("的结束时间%s", err.Error())
+	}
+
+	compiled := scheduleWindow{start: start, end: end}
+	if len(window.Weekdays) > 0 {
+		compiled.weekdays = make(map[time.Weekday]bool, len(window.Weekdays))
+		for _, day := range window.Weekdays {
+			if day < 0 || day > 6 {
+				return scheduleWindow{}, errors.New("的星期只能是 0-6")
+			}
+			compiled.weekdays[time.Weekday(day)] = true
+		}
+	}
+
+	return compiled, nil
+}
+
+func parseScheduleClock(value string) (int, error) {
+	clock, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, errors.New("格式应为 HH:MM")
+	}
+
+	return clock.Hour()*60 + clock.Minute(), nil
+}
+
+// Active 判断时间点是否在可用时间内，同一分钟内复用上次的结果
+func (s *channelScheduler) Active(t time.Time) bool {
+	minute := t.Unix() / 60
+	if cached := s.cache.Load(); cached >= 0 && cached>>1 == minute {
+		return cached&1 == 1
+	}
+
+	active := s.activeAt(t)
+	cached := minute << 1
+	if active {
+		cached |= 1
+	}
+	s.cache.Store(cached)
+
+	return active
+}
+
+func (s *channelScheduler) activeAt(t time.Time) bool {
+	t = t.In(s.location)
+	for i := range s.windows {
+		if s.windows[i].active(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Status 计算当前状态以及当前（或下一个）可用窗口的起止时间
+func (s *channelScheduler) Status(now time.Time) *ChannelScheduleStatus {
+	now = now.In(s.location).Truncate(time.Minute)
+	limit := now.Add(channelScheduleLookahead)
+	status := &ChannelScheduleStatus{}
+
+	// 前一天开始的窗口可能覆盖当前时间，从前一天开始展开
+	for _, interval := range s.intervals(now.AddDate(0, 0, -1), limit) {
+		if !interval.end.After(now) {
+			continue
+		}
+		if !interval.start.After(now) {
+			status.Active = true
+		} else if interval.start.Before(limit) {
+			status.NextStart = interval.start.Unix()
+		} else {
+			break
+		}
+		if interval.end.Before(limit) {
+			status.NextEnd = interval.end.Unix()
+		}
+		break
+	}
+
+	return status
+}
+
+type scheduleInterval struct {
+	start, end time.Time
+}
+
+// intervals 按天展开 from 到 to 之间各窗口的可用区间，按开始时间排序，
+// 重叠或首尾相连的区间合并为一个
+func (s *channelScheduler) intervals(from, to time.Time) []scheduleInterval {
+	var intervals []scheduleInterval
+	for i := range s.windows {
+		intervals = s.windows[i].appendIntervals(intervals, from, to, s.location)
+	}
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].start.Before(intervals[j].start)
+	})
+
+	merged := intervals[:0]
+	for _, interval := range intervals {
+		if last := len(merged) - 1; last >= 0 && !interval.start.After(merged[last].end) {
+			if interval.end.After(merged[last].end) {
+				merged[last].end = interval.end
+			}
+			continue
+		}
+		merged = append(merged, interval)
+	}
+
+	return merged
+}
+
+func (w *scheduleWindow) allowDay(day time.Weekday) bool {
+	return w.weekdays == nil || w.weekdays[day]
+}
+
+// appendIntervals 追加窗口在 from 到 to 之间每一天的可用区间
+func (w *scheduleWindow) appendIntervals(intervals []scheduleInterval, from, to time.Time, location *time.Location) []scheduleInterval {
+	if w.cron != nil && w.cron.Location != time.Local {
+		location = w.cron.Location
+	}
+	from, to = from.In(location), to.In(location)
+
+	year, month, day := from.Date()
+	for offset := 0; ; offset++ {
+		midnight := time.Date(year, month, day+offset, 0, 0, 0, 0, location)
+		if midnight.After(to) {
+			return intervals
+		}
+		at := func(minute int) time.Time {
+			return time.Date(year, month, day+offset, 0, minute, 0, 0, location)
+		}
+
+		if w.cron != nil {
+			intervals = w.appendCronIntervals(intervals, midnight, at)
+			continue
+		}
+		if !w.allowDay(midnight.Weekday()) {
+			continue
+		}
+		switch {
+		case w.start == w.end:
+			intervals = append(intervals, scheduleInterval{start: midnight, end: at(24 * 60)})
+		case w.start < w.end:
+			intervals = append(intervals, scheduleInterval{start: at(w.start), end: at(w.end)})
+		default:
+			intervals = append(intervals, scheduleInterval{start: at(w.start), end: at(24*60 + w.end)})
+		}
+	}
+}
+
+// appendCronIntervals 按 cron 的小时与分钟位图展开一天内连续的可用分钟
+func (w *scheduleWindow) appendCronIntervals(intervals []scheduleInterval, midnight time.Time, at func(minute int) time.Time) []scheduleInterval {
+	spec := w.cron
+	if 1<<uint(midnight.Month())&spec.Month == 0 || !cronDayMatches(spec, midnight) {
+		return intervals
+	}
+
+	for hour := 0; hour < 24; hour++ {
+		if 1<<uint(hour)&spec.Hour == 0 {
+			continue
+		}
+		for minute := 0; minute < 60; minute++ {
+			if 1<<uint(minute)&spec.Minute == 0 {
+				continue
+			}
+			end := minute + 1
+			for end < 60 && 1<<uint(end)&spec.Minute > 0 {
+				end++
+			}
+			intervals = append(intervals, scheduleInterval{start: at(hour*60 + minute), end: at(hour*60 + end)})
+			minute = end
+		}
+	}
+
+	return intervals
+}
+
+// cronDayMatches 与 cron 的规则一致：日期与星期有一个为 * 时需同时匹配，否则匹配其一即可
+func cronDayMatches(spec *cron.SpecSchedule, t time.Time) bool {
+	const starBit = 1 << 63
+	domMatch := 1<<uint(t.Day())&spec.Dom > 0
+	dowMatch := 1<<uint(t.Weekday())&spec.Dow > 0
+	if spec.Dom&starBit > 0 || spec.Dow&starBit > 0 {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
+
+func (w *scheduleWindow) active(t time.Time) bool {
+	if w.cron != nil {
+		minute := t.Truncate(time.Minute)
+		return w.cron.Next(minute.Add(-time.Second)).Equal(minute)
+	}
+
+	current := t.Hour()*60 + t.Minute()
+	switch {
+	case w.start == w.end:
+		return w.allowDay(t.Weekday())
+	case w.start < w.end:
+		return w.allowDay(t.Weekday()) && current >= w.start && current < w.end
+	default:
+		// 跨越零点：当天开始之后，或前一天开始、今天结束之前
+		return (w.allowDay(t.Weekday()) && current >= w.start) ||
+			(w.allowDay(t.AddDate(0, 0, -1).Weekday()) && current < w.end)
+	}
+}
@@ -0,0 +1,323 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("缺少时区数据 %s: %v", name, err)
+	}
+	return location
+}
+
+func mustNewChannelScheduler(t *testing.T, schedule ChannelSchedule) *channelScheduler {
+	t.Helper()
+	scheduler, err := newChannelScheduler(schedule)
+	if err != nil {
+		t.Fatalf("newChannelScheduler() error = %v", err)
+	}
+	return scheduler
+}
+
+// TestChannelScheduleStatus 测试可用时间状态：当前是否可用、下一个窗口的开始与结束时间
+func TestChannelScheduleStatus(t *testing.T) {
+	const timezone = "Asia/Shanghai"
+	daily := func(start, end string, weekdays ...int) ChannelScheduleWindow {
+		return ChannelScheduleWindow{Weekdays: weekdays, Start: start, End: end}
+	}
+	cronWindow := func(expr string) ChannelScheduleWindow {
+		return ChannelScheduleWindow{Cron: expr}
+	}
+
+	// 2026-10-12 为周一；时间按 in 指定的时区解析，为空时按 timezone
+	tests := []struct {
+		name      string
+		timezone  string
+		windows   []ChannelScheduleWindow
+		in        string
+		now       string
+		active    bool
+		nextStart string
+		nextEnd   string
+	}{
+		{
+			name:    "窗口内",
+			windows: []ChannelScheduleWindow{daily("09:00", "18:00")},
+			now:     "2026-10-12 10:00",
+			active:  true,
+			nextEnd: "2026-10-12 18:00",
+		},
+		{
+			name:      "窗口开始前",
+			windows:   []ChannelScheduleWindow{daily("09:00", "18:00")},
+			now:       "2026-10-12 08:30",
+			nextStart: "2026-10-12 09:00",
+			nextEnd:   "2026-10-12 18:00",
+		},
+		{
+			name:      "结束时间不含在窗口内",
+			windows:   []ChannelScheduleWindow{daily("09:00", "18:00")},
+			now:       "2026-10-12 18:00",
+			nextStart: "2026-10-13 09:00",
+			nextEnd:   "2026-10-13 18:00",
+		},
+		{
+			name:    "跨零点窗口在开始当天",
+			windows: []ChannelScheduleWindow{daily("22:00", "06:00")},
+			now:     "2026-10-12 23:00",
+			active:  true,
+			nextEnd: "2026-10-13 06:00",
+		},
+		{
+			name:    "跨零点窗口在前一天开始",
+			windows: []ChannelScheduleWindow{daily("22:00", "06:00", 1)},
+			now:     "2026-10-13 02:00",
+			active:  true,
+			nextEnd: "2026-10-13 06:00",
+		},
+		{
+			name:      "跨零点窗口结束后等到下一个允许的星期",
+			windows:   []ChannelScheduleWindow{daily("22:00", "06:00", 1)},
+			now:       "2026-10-13 07:00",
+			nextStart: "2026-10-19 22:00",
+			nextEnd:   "2026-10-20 06:00",
+		},
+		{
+			name:      "周五下班后等到周一",
+			windows:   []ChannelScheduleWindow{daily("09:00", "18:00", 1, 2, 3, 4, 5)},
+			now:       "2026-10-16 19:00",
+			nextStart: "2026-10-19 09:00",
+			nextEnd:   "2026-10-19 18:00",
+		},
+		{
+			name:    "周末全天窗口跨零点合并",
+			windows: []ChannelScheduleWindow{daily("00:00", "00:00", 6, 0)},
+			now:     "2026-10-17 12:00",
+			active:  true,
+			nextEnd: "2026-10-19 00:00",
+		},
+		{
+			name:    "首尾相连的窗口合并",
+			windows: []ChannelScheduleWindow{daily("12:00", "18:00"), daily("00:00", "12:00")},
+			now:     "2026-10-12 06:00",
+			active:  true,
+			nextEnd: "2026-10-12 18:00",
+		},
+		{
+			name:    "每天全天可用没有结束时间",
+			windows: []ChannelScheduleWindow{daily("00:00", "00:00")},
+			now:     "2026-10-12 06:00",
+			active:  true,
+		},
+		{
+			name:    "cron 窗口内",
+			windows: []ChannelScheduleWindow{cronWindow("* 0-7 * * *")},
+			now:     "2026-10-12 03:00",
+			active:  true,
+			nextEnd: "2026-10-12 08:00",
+		},
+		{
+			name:      "cron 窗口结束后到次日",
+			windows:   []ChannelScheduleWindow{cronWindow("* 0-7 * * *")},
+			now:       "2026-10-12 09:00",
+			nextStart: "2026-10-13 00:00",
+			nextEnd:   "2026-10-13 08:00",
+		},
+		{
+			name:    "cron 窗口跨零点合并",
+			windows: []ChannelScheduleWindow{cronWindow("* 22-23 * * *"), cronWindow("* 0-5 * * *")},
+			now:     "2026-10-12 23:30",
+			active:  true,
+			nextEnd: "2026-10-13 06:00",
+		},
+		{
+			name:      "cron 只匹配一分钟",
+			windows:   []ChannelScheduleWindow{cronWindow("0 12 * * *")},
+			now:       "2026-10-12 11:00",
+			nextStart: "2026-10-12 12:00",
+			nextEnd:   "2026-10-12 12:01",
+		},
+		{
+			name:      "cron 分钟不连续",
+			windows:   []ChannelScheduleWindow{cronWindow("0-9,30-39 9 * * *")},
+			now:       "2026-10-12 09:15",
+			nextStart: "2026-10-12 09:30",
+			nextEnd:   "2026-10-12 09:40",
+		},
+		{
+			name:      "cron 日期与星期匹配其一",
+			windows:   []ChannelScheduleWindow{cronWindow("* 9 15 * 1")},
+			now:       "2026-10-13 10:00",
+			nextStart: "2026-10-15 09:00",
+			nextEnd:   "2026-10-15 10:00",
+		},
+		{
+			name:      "cron 星期",
+			windows:   []ChannelScheduleWindow{cronWindow("* 9 * * 6")},
+			now:       "2026-10-13 10:00",
+			nextStart: "2026-10-17 09:00",
+			nextEnd:   "2026-10-17 10:00",
+		},
+		{
+			name:      "按设置的时区计算",
+			timezone:  "America/New_York",
+			windows:   []ChannelScheduleWindow{daily("09:00", "17:00")},
+			in:        "UTC",
+			now:       "2026-10-12 12:30",
+			nextStart: "2026-10-12 13:00",
+			nextEnd:   "2026-10-12 21:00",
+		},
+		{
+			name:      "时区的星期与服务器不同",
+			timezone:  "Asia/Shanghai",
+			windows:   []ChannelScheduleWindow{daily("00:00", "08:00", 2)},
+			in:        "UTC",
+			now:       "2026-10-12 15:00",
+			nextStart: "2026-10-12 16:00",
+			nextEnd:   "2026-10-13 00:00",
+		},
+		{
+			name:     "夏令时结束当天窗口多一小时",
+			timezone: "America/New_York",
+			windows:  []ChannelScheduleWindow{daily("00:00", "04:00", 0)},
+			in:       "UTC",
+			now:      "2026-11-01 05:00",
+			active:   true,
+			nextEnd:  "2026-11-01 09:00",
+		},
+		{
+			name:    "查找范围内没有窗口",
+			windows: []ChannelScheduleWindow{cronWindow("* * 29 2 *")},
+			now:     "2026-10-12 10:00",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.timezone == "" {
+				tt.timezone = timezone
+			}
+			if tt.in == "" {
+				tt.in = tt.timezone
+			}
+			location := mustLoadLocation(t, tt.in)
+			parse := func(value string) int64 {
+				if value == "" {
+					return 0
+				}
+				parsed, err := time.ParseInLocation("2006-01-02 15:04", value, location)
+				if err != nil {
+					t.Fatalf("解析时间 %q: %v", value, err)
+				}
+				return parsed.Unix()
+			}
+
+			scheduler := mustNewChannelScheduler(t, ChannelSchedule{Timezone: tt.timezone, Windows: tt.windows})
+			now := time.Unix(parse(tt.now), 0)
+			status := scheduler.Status(now)
+
+			format := func(unix int64) string {
+				if unix == 0 {
+					return ""
+				}
+				return time.Unix(unix, 0).In(location).Format("2006-01-02 15:04")
+			}
+			if status.Active != tt.active {
+				t.Errorf("Active = %v, 期望 %v", status.Active, tt.active)
+			}
+			if status.NextStart != parse(tt.nextStart) {
+				t.Errorf("NextStart = %q, 期望 %q", format(status.NextStart), tt.nextStart)
+			}
+			if status.NextEnd != parse(tt.nextEnd) {
+				t.Errorf("NextEnd = %q, 期望 %q", format(status.NextEnd), tt.nextEnd)
+			}
+			if got := scheduler.activeAt(now); got != status.Active {
+				t.Errorf("activeAt() = %v, 与 Status().Active 不一致", got)
+			}
+		})
+	}
+}
+
+// TestChannelScheduleStatusMatchesActive 测试 Status 与逐分钟按 activeAt 判断的结果一致
+func TestChannelScheduleStatusMatchesActive(t *testing.T) {
+	location := mustLoadLocation(t, "Asia/Shanghai")
+	schedules := []ChannelSchedule{
+		{Windows: []ChannelScheduleWindow{{Weekdays: []int{1, 3, 5}, Start: "20:30", End: "02:15"}}},
+		{Windows: []ChannelScheduleWindow{{Weekdays: []int{0}, Start: "00:00", End: "00:00"}, {Start: "23:00", End: "01:00"}}},
+		{Windows: []ChannelScheduleWindow{{Cron: "*/20 8-10 * * 1-5"}, {Start: "10:50", End: "11:10"}}},
+	}
+	nows := []string{"2026-10-12 00:00", "2026-10-14 21:00", "2026-10-16 23:59", "2026-10-18 08:10"}
+
+	for i, schedule := range schedules {
+		schedule.Timezone = "Asia/Shanghai"
+		scheduler := mustNewChannelScheduler(t, schedule)
+		for _, value := range nows {
+			now, _ := time.ParseInLocation("2006-01-02 15:04", value, location)
+			want := scanChannelScheduleStatus(scheduler, now)
+			if got := scheduler.Status(now); *got != *want {
+				t.Errorf("第 %d 个设置在 %s: Status() = %+v, 逐分钟结果 %+v", i+1, value, *got, *want)
+			}
+		}
+	}
+}
+
+// scanChannelScheduleStatus 逐分钟判断的参照实现
+func scanChannelScheduleStatus(s *channelScheduler, now time.Time) *ChannelScheduleStatus {
+	now = now.Truncate(time.Minute)
+	limit := now.Add(channelScheduleLookahead)
+	status := &ChannelScheduleStatus{Active: s.activeAt(now)}
+
+	t := now
+	if !status.Active {
+		for t = t.Add(time.Minute); t.Before(limit) && !s.activeAt(t); t = t.Add(time.Minute) {
+		}
+		if !t.Before(limit) {
+			return status
+		}
+		status.NextStart = t.Unix()
+	}
+	for t = t.Add(time.Minute); t.Before(limit); t = t.Add(time.Minute) {
+		if !s.activeAt(t) {
+			status.NextEnd = t.Unix()
+			break
+		}
+	}
+
+	return status
+}
+
+// TestNewChannelScheduler 测试可用时间设置的校验
+func TestNewChannelScheduler(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule ChannelSchedule
+		wantNil  bool
+		wantErr  bool
+	}{
+		{name: "没有窗口表示不限制", schedule: ChannelSchedule{}, wantNil: true},
+		{name: "时间窗口", schedule: ChannelSchedule{Windows: []ChannelScheduleWindow{{Weekdays: []int{1, 5}, Start: "09:00", End: "18:00"}}}},
+		{name: "cron 窗口", schedule: ChannelSchedule{Windows: []ChannelScheduleWindow{{Cron: "* 0-7 * * *"}}}},
+		{name: "无效时区", schedule: ChannelSchedule{Timezone: "Mars/Base", Windows: []ChannelScheduleWindow{{Start: "09:00", End: "18:00"}}}, wantErr: true},
+		{name: "cron 与时间同时设置", schedule: ChannelSchedule{Windows: []ChannelScheduleWindow{{Cron: "* * * * *", Start: "09:00"}}}, wantErr: true},
+		{name: "无效 cron", schedule: ChannelSchedule{Windows: []ChannelScheduleWindow{{Cron: "* 25 * * *"}}}, wantErr: true},
+		{name: "不支持 @every", schedule: ChannelSchedule{Windows: []ChannelScheduleWindow{{Cron: "@every 1h"}}}, wantErr: true},
+		{name: "星期超出范围", schedule: ChannelSchedule{Windows: []ChannelScheduleWindow{{Weekdays: []int{7}, Start: "09:00", End: "18:00"}}}, wantErr: true},
+		{name: "时间格式错误", schedule: ChannelSchedule{Windows: []ChannelScheduleWindow{{Start: "9点", End: "18:00"}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduler, err := newChannelScheduler(tt.schedule)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newChannelScheduler() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (scheduler == nil) != tt.wantNil {
+				t.Errorf("newChannelScheduler() = %v, 期望为 nil %v", scheduler, tt.wantNil)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package model
+
+import (
+	"done-hub/common/config"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	RoutingStrategyWeight  = "weight"
+	RoutingStrategyLatency = "latency"
+	RoutingStrategyCost    = "cost"
+)
+
+const (
+	// channelStatsAlpha EWMA 平滑系数，越大越偏向最近的样本
+	channelStatsAlpha = 0.2
+	// channelStatsMinSamples 样本数不足时视为未知，优先分配流量以尽快收集数据
+	channelStatsMinSamples = 3
+	// channelStatsStale 超过该时长没有新样本的统计视为过期，重新参与探索
+	channelStatsStale = 30 * 60
+	// channelStatsMaxErrorRate 计算得分时错误率的上限，避免除零
+	channelStatsMaxErrorRate = 0.95
+)
+
+// channelStat 单个渠道+模型的 EWMA 统计：首字时间、总耗时（毫秒）和错误率
+type channelStat struct {
+	ttft          float64
+	latency       float64
+	errorRate     float64
+	latencyCount  int
+	outcomeCount  int
+	lastUpdatedAt int64
+}
+
+type channelStatsRegistry struct {
+	sync.RWMutex
+	stats map[string]*channelStat
+}
+
+// ChannelStats 延迟感知路由使用的渠道统计，仅保存在本节点内存中
+var ChannelStats = &channelStatsRegistry{stats: make(map[string]*channelStat)}
+
+func ewma(current, sample float64, count int) float64 {
+	if count == 0 {
+		return sample
+	}
+	return current + channelStatsAlpha*(sample-current)
+}
+
+func (r *channelStatsRegistry) get(channelId int, modelName string) *channelStat {
+	key := circuitKey(channelId, modelName)
+	r.RLock()
+	stat, ok := r.stats[key]
+	r.RUnlock()
+	if ok {
+		return stat
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	if stat, ok = r.stats[key]; !ok {
+		stat = &channelStat{}
+		r.stats[key] = stat
+	}
+	return stat
+}
+
+// RecordLatency 记录一次成功请求的耗时，ttft 为 0 表示没有测到首字时间
+func (r *channelStatsRegistry) RecordLatency(channelId int, modelName string, ttft, latency time.Duration) {
+	if latency <= 0 {
+		return
+	}
+
+	stat := r.get(channelId, modelName)
+	r.Lock()
+	defer r.Unlock()
+	if ttft <= 0 || ttft > latency {
+		ttft = latency
+	}
+	stat.ttft = ewma(stat.ttft, float64(ttft.Milliseconds()), stat.latencyCount)
+	stat.latency = ewma(stat.latency, float64(latency.Milliseconds()), stat.latencyCount)
+	stat.latencyCount++
+	stat.lastUpdatedAt = time.Now().Unix()
+}
+
+// RecordOutcome 记录一次请求的成败，用于错误率
+func (r *channelStatsRegistry) RecordOutcome(channelId int, modelName string, failed bool) {
+	sample := 0.0
+	if failed {
+		sample = 1
+	}
+
+	stat := r.get(channelId, modelName)
+	r.Lock()
+	defer r.Unlock()
+	stat.errorRate = ewma(stat.errorRate, sample, stat.outcomeCount)
+	stat.outcomeCount++
+	stat.lastUpdatedAt = time.Now().Unix()
+}
+
+// score 估算一次成功请求的期望等待时间：首字时间 / (1 - 错误率)，越小越好。
+// 统计不足或已过期时返回 false。
+func (r *channelStatsRegistry) score(channelId int, modelName string, now int64) (float64, bool) {
+	r.RLock()
+	defer r.RUnlock()
+	stat, ok := r.stats[circuitKey(channelId, modelName)]
+	if !ok || now-stat.lastUpdatedAt > channelStatsStale {
+		return 0, false
+	}
+	if stat.latencyCount < channelStatsMinSamples {
+		// 一直失败的渠道没有耗时样本，按错误率给一个很差的得分
+		if stat.outcomeCount >= channelStatsMinSamples && stat.errorRate > 0.5 {
+			return float64(time.Hour.Milliseconds()) * stat.errorRate, true
+		}
+		return 0, false
+	}
+
+	errorRate := stat.errorRate
+	if errorRate > channelStatsMaxErrorRate {
+		errorRate = channelStatsMaxErrorRate
+	}
+	return stat.ttft / (1 - errorRate), true
+}
+
+// Cleanup 清理过期的统计
+func (r *channelStatsRegistry) Cleanup() {
+	now := time.Now().Unix()
+	r.Lock()
+	defer r.Unlock()
+	for key, stat := range r.stats {
+		if now-stat.lastUpdatedAt > channelStatsStale {
+			delete(r.stats, key)
+		}
+	}
+}
+
+// ResetChannel 清除渠道的统计，渠道配置变更后重新学习
+func (r *channelStatsRegistry) ResetChannel(channelId int) {
+	prefix := fmt.Sprintf("%d:", channelId)
+	r.Lock()
+	defer r.Unlock()
+	for key := range r.stats {
+		if strings.HasPrefix(key, prefix) {
+			delete(r.stats, key)
+		}
+	}
+}
+
+// pickByLatency 延迟感知选择：按 LatencyRoutingExploreRate% 的概率按权重随机探索，
+// 否则按权重随机抽取两个候选（power of two choices），取得分更低者；没有统计的候选优先。
+func pickByLatency(validChannels []*ChannelChoice, totalWeight int, modelName string) int {
+	if len(validChannels) < 2 || rand.Intn(100) < config.LatencyRoutingExploreRate {
+		return pickByWeight(validChannels, totalWeight)
+	}
+
+	first := pickByWeight(validChannels, totalWeight)
+	second := first
+	for i := 0; i < 3 && second == first; i++ {
+		second = pickByWeight(validChannels, totalWeight)
+	}
+	if second == first {
+		second = (first + 1 + rand.Intn(len(validChannels)-1)) % len(validChannels)
+	}
+
+	now := time.Now().Unix()
+	firstScore, firstOk := ChannelStats.score(validChannels[first].Channel.Id, modelName, now)
+	secondScore, secondOk := ChannelStats.score(validChannels[second].Channel.Id, modelName, now)
+	switch {
+	case !firstOk:
+		return first
+	case !secondOk:
+		return second
+	case secondScore < firstScore:
+		return second
+	default:
+		return first
+	}
+}
+
+// pickByWeight 按权重随机选择
+func pickByWeight(validChannels []*ChannelChoice, totalWeight int) int {
+	if len(validChannels) < 2 || totalWeight <= 0 {
+		return 0
+	}
+
+	choiceWeight := rand.Intn(totalWeight)
+	for i, choice := range validChannels {
+		choiceWeight -= int(*choice.Channel.Weight)
+		if choiceWeight < 0 {
+			return i
+		}
+	}
+	return 0
+}
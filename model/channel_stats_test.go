@@ -0,0 +1,127 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"done-hub/common/config"
+)
+
+func newTestChoice(id int, weight uint) *ChannelChoice {
+	return &ChannelChoice{Channel: &Channel{Id: id, Weight: &weight}}
+}
+
+// TestPickByLatency 测试延迟感知选择：两个候选中取得分更低者，没有统计或统计过期的候选优先
+func TestPickByLatency(t *testing.T) {
+	const modelName = "gpt-4o"
+
+	type sample struct {
+		ttft, latency time.Duration
+		failed        bool
+	}
+	tests := []struct {
+		name        string
+		exploreRate int
+		weights     []uint
+		samples     map[int][]sample // 按候选下标记录的样本
+		stale       []int            // 统计已过期的候选下标
+		want        int
+	}{
+		{
+			name:    "只有一个候选",
+			weights: []uint{1},
+			want:    0,
+		},
+		{
+			name:    "首字时间更短的渠道胜出",
+			weights: []uint{1, 1},
+			samples: map[int][]sample{
+				0: {{ttft: 800 * time.Millisecond, latency: time.Second}, {ttft: 800 * time.Millisecond, latency: time.Second}, {ttft: 800 * time.Millisecond, latency: time.Second}},
+				1: {{ttft: 200 * time.Millisecond, latency: time.Second}, {ttft: 200 * time.Millisecond, latency: time.Second}, {ttft: 200 * time.Millisecond, latency: time.Second}},
+			},
+			want: 1,
+		},
+		{
+			name:    "错误率抬高得分",
+			weights: []uint{1, 1},
+			samples: map[int][]sample{
+				0: {{ttft: 300 * time.Millisecond, latency: time.Second}, {ttft: 300 * time.Millisecond, latency: time.Second}, {ttft: 300 * time.Millisecond, latency: time.Second}, {failed: true}, {failed: true}},
+				1: {{ttft: 400 * time.Millisecond, latency: time.Second}, {ttft: 400 * time.Millisecond, latency: time.Second}, {ttft: 400 * time.Millisecond, latency: time.Second}},
+			},
+			want: 1,
+		},
+		{
+			name:    "样本不足的渠道优先探索",
+			weights: []uint{1, 1},
+			samples: map[int][]sample{
+				0: {{ttft: 100 * time.Millisecond, latency: time.Second}, {ttft: 100 * time.Millisecond, latency: time.Second}, {ttft: 100 * time.Millisecond, latency: time.Second}},
+				1: {{ttft: 900 * time.Millisecond, latency: time.Second}},
+			},
+			want: 1,
+		},
+		{
+			name:    "统计过期的渠道重新参与探索",
+			weights: []uint{1, 1},
+			samples: map[int][]sample{
+				0: {{ttft: 100 * time.Millisecond, latency: time.Second}, {ttft: 100 * time.Millisecond, latency: time.Second}, {ttft: 100 * time.Millisecond, latency: time.Second}},
+				1: {{ttft: 900 * time.Millisecond, latency: time.Second}, {ttft: 900 * time.Millisecond, latency: time.Second}, {ttft: 900 * time.Millisecond, latency: time.Second}},
+			},
+			stale: []int{1},
+			want:  1,
+		},
+		{
+			name:    "一直失败的渠道没有耗时样本也会被避开",
+			weights: []uint{1, 1},
+			samples: map[int][]sample{
+				0: {{failed: true}, {failed: true}, {failed: true}},
+				1: {{ttft: 5 * time.Second, latency: 10 * time.Second}, {ttft: 5 * time.Second, latency: 10 * time.Second}, {ttft: 5 * time.Second, latency: 10 * time.Second}},
+			},
+			want: 1,
+		},
+		{
+			name:        "探索流量按权重随机",
+			exploreRate: 100,
+			weights:     []uint{0, 1},
+			samples: map[int][]sample{
+				0: {{ttft: 100 * time.Millisecond, latency: time.Second}, {ttft: 100 * time.Millisecond, latency: time.Second}, {ttft: 100 * time.Millisecond, latency: time.Second}},
+				1: {{ttft: 900 * time.Millisecond, latency: time.Second}, {ttft: 900 * time.Millisecond, latency: time.Second}, {ttft: 900 * time.Millisecond, latency: time.Second}},
+			},
+			want: 1,
+		},
+	}
+
+	savedExploreRate := config.LatencyRoutingExploreRate
+	t.Cleanup(func() { config.LatencyRoutingExploreRate = savedExploreRate })
+
+	for caseIndex, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.LatencyRoutingExploreRate = tt.exploreRate
+
+			choices := make([]*ChannelChoice, 0, len(tt.weights))
+			totalWeight := 0
+			for i, weight := range tt.weights {
+				id := 1000*(caseIndex+1) + i
+				choices = append(choices, newTestChoice(id, weight))
+				totalWeight += int(weight)
+				t.Cleanup(func() { ChannelStats.ResetChannel(id) })
+
+				for _, s := range tt.samples[i] {
+					ChannelStats.RecordOutcome(id, modelName, s.failed)
+					if !s.failed {
+						ChannelStats.RecordLatency(id, modelName, s.ttft, s.latency)
+					}
+				}
+			}
+			for _, i := range tt.stale {
+				ChannelStats.get(choices[i].Channel.Id, modelName).lastUpdatedAt = time.Now().Unix() - channelStatsStale - 1
+			}
+
+			// 两个候选时 power of two choices 总会比较两者，结果是确定的
+			for round := 0; round < 20; round++ {
+				if got := pickByLatency(choices, totalWeight, modelName); got != tt.want {
+					t.Fatalf("第 %d 次 pickByLatency() = %d, 期望 %d", round+1, got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,435 @@
+package model
+
+import (
+	"done-hub/common/config"
+	"done-hub/metrics"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitHalfOpen
+	CircuitOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitHalfOpen:
+		return "half_open"
+	case CircuitOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+const (
+	// circuitBuckets 滚动窗口切分的桶数，窗口长度由 CircuitBreakerWindowSeconds 决定
+	circuitBuckets = 10
+	// circuitTrialTimeout 半开试探请求未回报结果（进程内 panic、调用方未上报等）时，超时后释放名额
+	circuitTrialTimeout = 300
+)
+
+type circuitBucket struct {
+	start    int64
+	total    int
+	failures int
+}
+
+// circuitBreaker 单个渠道+模型的熔断器。
+// closed：正常放行，按滚动窗口错误率与连续失败次数判定是否打开；
+// open：拒绝流量直到 openUntil；
+// half_open：只放行有限的试探请求，全部成功才关闭，任一失败重新打开且打开时长翻倍。
+type circuitBreaker struct {
+	sync.Mutex
+	channelId int
+	modelName string
+
+	state               CircuitState
+	buckets             [circuitBuckets]circuitBucket
+	consecutiveFailures int
+	openedAt            int64
+	openUntil           int64
+	// openCount 未经关闭的连续打开次数，决定下一次打开时长
+	openCount      int
+	trialInflight  int
+	trialSuccesses int
+	trialStartedAt int64
+	lastActiveAt   int64
+}
+
+// CircuitBreakerStatus 熔断器快照，用于管理端渠道列表展示
+type CircuitBreakerStatus struct {
+	Model               string `json:"model"`
+	State               string `json:"state"`
+	Requests            int    `json:"requests"`
+	Failures            int    `json:"failures"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	OpenedAt            int64  `json:"opened_at,omitempty"`
+	OpenUntil           int64  `json:"open_until,omitempty"`
+	OpenCount           int    `json:"open_count,omitempty"`
+}
+
+type circuitBreakerRegistry struct {
+	breakers sync.Map // "channelId:model" -> *circuitBreaker
+}
+
+// CircuitBreakers 渠道+模型熔断器，替代固定时长的冷却。关闭 CircuitBreakerEnabled 时回退到 Cooldowns。
+var CircuitBreakers = &circuitBreakerRegistry{}
+
+func circuitKey(channelId int, modelName string) string {
+	return fmt.Sprintf("%d:%s", channelId, modelName)
+}
+
+func (r *circuitBreakerRegistry) get(channelId int, modelName string, create bool) *circuitBreaker {
+	key := circuitKey(channelId, modelName)
+	if value, ok := r.breakers.Load(key); ok {
+		return value.(*circuitBreaker)
+	}
+	if !create {
+		return nil
+	}
+
+	value, _ := r.breakers.LoadOrStore(key, &circuitBreaker{channelId: channelId, modelName: modelName})
+	return value.(*circuitBreaker)
+}
+
+// Available 只读判断渠道当前能否接收请求，用于过滤与计数，不占用半开名额
+func (r *circuitBreakerRegistry) Available(channelId int, modelName string) bool {
+	breaker := r.get(channelId, modelName, false)
+	if breaker == nil {
+		return true
+	}
+
+	breaker.Lock()
+	defer breaker.Unlock()
+	return breaker.available(time.Now().Unix())
+}
+
+// Allow 选中渠道后申请放行：半开状态下占用一个试探名额，名额用完返回 false
+func (r *circuitBreakerRegistry) Allow(channelId int, modelName string) bool {
+	breaker := r.get(channelId, modelName, false)
+	if breaker == nil {
+		return true
+	}
+
+	breaker.Lock()
+	defer breaker.Unlock()
+
+	now := time.Now().Unix()
+	if !breaker.available(now) {
+		return false
+	}
+	if breaker.state == CircuitOpen {
+		breaker.transition(CircuitHalfOpen, now)
+	}
+	if breaker.state == CircuitHalfOpen {
+		if breaker.trialInflight == 0 {
+			breaker.trialStartedAt = now
+		}
+		breaker.trialInflight++
+	}
+	return true
+}
+
+// RecordSuccess 记录一次成功调用。成功也要计入滚动窗口，否则首次失败前的成功不参与错误率，
+// 窗口内只有失败时错误率会被高估，因此熔断器在任一结果时创建，空闲后由 Cleanup 回收
+func (r *circuitBreakerRegistry) RecordSuccess(channelId int, modelName string) {
+	breaker := r.get(channelId, modelName, true)
+	breaker.Lock()
+	defer breaker.Unlock()
+
+	now := time.Now().Unix()
+	breaker.bucket(now).total++
+	breaker.consecutiveFailures = 0
+	breaker.lastActiveAt = now
+
+	if breaker.state != CircuitHalfOpen {
+		return
+	}
+	breaker.releaseTrial()
+	breaker.trialSuccesses++
+	if breaker.trialSuccesses >= halfOpenRequests() {
+		breaker.transition(CircuitClosed, now)
+		publishRecover(channelId, modelName)
+	}
+}
+
+// RecordFailure 记录一次渠道侧失败，达到阈值时打开熔断
+func (r *circuitBreakerRegistry) RecordFailure(channelId int, modelName string) {
+	breaker := r.get(channelId, modelName, true)
+	breaker.Lock()
+	defer breaker.Unlock()
+
+	now := time.Now().Unix()
+	bucket := breaker.bucket(now)
+	bucket.total++
+	bucket.failures++
+	breaker.consecutiveFailures++
+	breaker.lastActiveAt = now
+
+	switch breaker.state {
+	case CircuitHalfOpen:
+		breaker.releaseTrial()
+		breaker.open(now, int64(config.CircuitBreakerOpenSeconds))
+	case CircuitClosed:
+		if breaker.shouldTrip(now) {
+			breaker.open(now, int64(config.CircuitBreakerOpenSeconds))
+		}
+	}
+}
+
+// Release 释放半开试探名额但不计入统计，用于与渠道健康无关的结果（如参数错误）
+func (r *circuitBreakerRegistry) Release(channelId int, modelName string) {
+	breaker := r.get(channelId, modelName, false)
+	if breaker == nil {
+		return
+	}
+
+	breaker.Lock()
+	defer breaker.Unlock()
+	if breaker.state == CircuitHalfOpen {
+		breaker.releaseTrial()
+	}
+}
+
+// Trip 立即打开熔断，baseSeconds 为首次打开时长（上游 Retry-After、按状态码冷却等），
+// 仍处于打开期时只会延长不会缩短
+func (r *circuitBreakerRegistry) Trip(channelId int, modelName string, baseSeconds int64) {
+	breaker := r.get(channelId, modelName, true)
+	breaker.Lock()
+	defer breaker.Unlock()
+
+	now := time.Now().Unix()
+	breaker.lastActiveAt = now
+	if breaker.state == CircuitOpen && now < breaker.openUntil {
+		if until := now + baseSeconds; until > breaker.openUntil {
+			breaker.openUntil = until
+			publishCooldown(channelId, modelName, breaker.openUntil, breaker.openCount)
+		}
+		return
+	}
+	if breaker.state == CircuitHalfOpen {
+		breaker.releaseTrial()
+	}
+	breaker.open(now, baseSeconds)
+}
+
+// applyOpen 应用其他节点广播的熔断打开，不再向外广播
+func (r *circuitBreakerRegistry) applyOpen(channelId int, modelName string, until int64, openCount int) {
+	now := time.Now().Unix()
+	if until <= now {
+		return
+	}
+
+	breaker := r.get(channelId, modelName, true)
+	breaker.Lock()
+	defer breaker.Unlock()
+
+	if breaker.state == CircuitOpen && breaker.openUntil >= until {
+		return
+	}
+	if breaker.state != CircuitOpen {
+		breaker.openedAt = now
+	}
+	breaker.openUntil = until
+	breaker.openCount = max(breaker.openCount, openCount)
+	breaker.transition(CircuitOpen, now)
+}
+
+// applyRecover 应用其他节点广播的熔断恢复
+func (r *circuitBreakerRegistry) applyRecover(channelId int, modelName string) {
+	breaker := r.get(channelId, modelName, false)
+	if breaker == nil {
+		return
+	}
+
+	breaker.Lock()
+	defer breaker.Unlock()
+	if breaker.state != CircuitClosed {
+		breaker.transition(CircuitClosed, time.Now().Unix())
+	}
+}
+
+// ResetChannel 清除渠道的全部熔断状态（渠道被编辑或重新启用时）
+func (r *circuitBreakerRegistry) ResetChannel(channelId int) {
+	prefix := fmt.Sprintf("%d:", channelId)
+	r.breakers.Range(func(key, value any) bool {
+		if strings.HasPrefix(key.(string), prefix) {
+			r.breakers.Delete(key)
+			breaker := value.(*circuitBreaker)
+			metrics.DeleteCircuitBreakerState(strconv.Itoa(breaker.channelId), breaker.modelName)
+		}
+		return true
+	})
+}
+
+// Cleanup 回收长时间无流量的关闭状态熔断器，同时推进到期的打开状态
+func (r *circuitBreakerRegistry) Cleanup() {
+	now := time.Now().Unix()
+	idle := int64(config.CircuitBreakerWindowSeconds)
+	r.breakers.Range(func(key, value any) bool {
+		breaker := value.(*circuitBreaker)
+		breaker.Lock()
+		if breaker.state == CircuitOpen && now >= breaker.openUntil {
+			breaker.transition(CircuitHalfOpen, now)
+		}
+		remove := breaker.state == CircuitClosed && now-breaker.lastActiveAt > idle
+		breaker.Unlock()
+
+		if remove {
+			r.breakers.Delete(key)
+			metrics.DeleteCircuitBreakerState(strconv.Itoa(breaker.channelId), breaker.modelName)
+		}
+		return true
+	})
+}
+
+// ChannelStatus 返回渠道下所有熔断器的快照，按模型名排序
+func (r *circuitBreakerRegistry) ChannelStatus(channelId int) []*CircuitBreakerStatus {
+	prefix := fmt.Sprintf("%d:", channelId)
+	now := time.Now().Unix()
+	statuses := make([]*CircuitBreakerStatus, 0)
+	r.breakers.Range(func(key, value any) bool {
+		if !strings.HasPrefix(key.(string), prefix) {
+			return true
+		}
+		breaker := value.(*circuitBreaker)
+		breaker.Lock()
+		statuses = append(statuses, breaker.status(now))
+		breaker.Unlock()
+		return true
+	})
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Model < statuses[j].Model
+	})
+	return statuses
+}
+
+func (cb *circuitBreaker) available(now int64) bool {
+	switch cb.state {
+	case CircuitOpen:
+		return now >= cb.openUntil
+	case CircuitHalfOpen:
+		if cb.trialInflight > 0 && now-cb.trialStartedAt > circuitTrialTimeout {
+			cb.trialInflight = 0
+		}
+		return cb.trialInflight < halfOpenRequests()
+	default:
+		return true
+	}
+}
+
+func (cb *circuitBreaker) shouldTrip(now int64) bool {
+	if threshold := config.CircuitBreakerConsecutiveFailures; threshold > 0 && cb.consecutiveFailures >= threshold {
+		return true
+	}
+
+	if config.CircuitBreakerErrorRate <= 0 {
+		return false
+	}
+	total, failures := cb.window(now)
+	if total == 0 || total < config.CircuitBreakerMinRequests {
+		return false
+	}
+	return failures*100 >= total*config.CircuitBreakerErrorRate
+}
+
+// open 打开熔断，时长为 baseSeconds * 2^openCount，上限 CircuitBreakerMaxOpenSeconds
+func (cb *circuitBreaker) open(now, baseSeconds int64) {
+	if baseSeconds <= 0 {
+		baseSeconds = 1
+	}
+	duration := baseSeconds << min(cb.openCount, 20)
+	if maxSeconds := int64(config.CircuitBreakerMaxOpenSeconds); maxSeconds > 0 && duration > maxSeconds {
+		duration = max(maxSeconds, baseSeconds)
+	}
+
+	cb.openedAt = now
+	cb.openUntil = now + duration
+	cb.openCount++
+	cb.transition(CircuitOpen, now)
+	publishCooldown(cb.channelId, cb.modelName, cb.openUntil, cb.openCount)
+}
+
+func (cb *circuitBreaker) transition(state CircuitState, now int64) {
+	if cb.state == state && state != CircuitOpen {
+		return
+	}
+
+	cb.state = state
+	cb.trialInflight = 0
+	cb.trialSuccesses = 0
+	if state == CircuitClosed {
+		cb.openCount = 0
+		cb.consecutiveFailures = 0
+		cb.openedAt = 0
+		cb.openUntil = 0
+		cb.buckets = [circuitBuckets]circuitBucket{}
+	}
+	cb.lastActiveAt = now
+
+	metrics.RecordCircuitBreakerState(strconv.Itoa(cb.channelId), cb.modelName, int(state), state.String())
+}
+
+func (cb *circuitBreaker) releaseTrial() {
+	if cb.trialInflight > 0 {
+		cb.trialInflight--
+	}
+}
+
+func bucketSeconds() int64 {
+	return max(int64(config.CircuitBreakerWindowSeconds)/circuitBuckets, 1)
+}
+
+func (cb *circuitBreaker) bucket(now int64) *circuitBucket {
+	size := bucketSeconds()
+	start := now - now%size
+	bucket := &cb.buckets[(now/size)%circuitBuckets]
+	if bucket.start != start {
+		*bucket = circuitBucket{start: start}
+	}
+	return bucket
+}
+
+func (cb *circuitBreaker) window(now int64) (total, failures int) {
+	since := now - bucketSeconds()*circuitBuckets
+	for _, bucket := range cb.buckets {
+		if bucket.start > since {
+			total += bucket.total
+			failures += bucket.failures
+		}
+	}
+	return
+}
+
+func (cb *circuitBreaker) status(now int64) *CircuitBreakerStatus {
+	total, failures := cb.window(now)
+	state := cb.state
+	if state == CircuitOpen && now >= cb.openUntil {
+		state = CircuitHalfOpen
+	}
+
+	return &CircuitBreakerStatus{
+		Model:               cb.modelName,
+		State:               state.String(),
+		Requests:            total,
+		Failures:            failures,
+		ConsecutiveFailures: cb.consecutiveFailures,
+		OpenedAt:            cb.openedAt,
+		OpenUntil:           cb.openUntil,
+		OpenCount:           cb.openCount,
+	}
+}
+
+func halfOpenRequests() int {
+	return max(config.CircuitBreakerHalfOpenRequests, 1)
+}
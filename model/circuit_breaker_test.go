@@ -0,0 +1,226 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"done-hub/common/config"
+)
+
+func setupCircuitBreakerConfig(t *testing.T, consecutiveFailures int) {
+	t.Helper()
+	saved := []int{
+		config.CircuitBreakerWindowSeconds, config.CircuitBreakerMinRequests, config.CircuitBreakerErrorRate,
+		config.CircuitBreakerConsecutiveFailures, config.CircuitBreakerOpenSeconds, config.CircuitBreakerMaxOpenSeconds,
+		config.CircuitBreakerHalfOpenRequests,
+	}
+	t.Cleanup(func() {
+		config.CircuitBreakerWindowSeconds, config.CircuitBreakerMinRequests, config.CircuitBreakerErrorRate,
+			config.CircuitBreakerConsecutiveFailures, config.CircuitBreakerOpenSeconds, config.CircuitBreakerMaxOpenSeconds,
+			config.CircuitBreakerHalfOpenRequests = saved[0], saved[1], saved[2], saved[3], saved[4], saved[5], saved[6]
+	})
+
+	config.CircuitBreakerWindowSeconds = 60
+	config.CircuitBreakerMinRequests = 5
+	config.CircuitBreakerErrorRate = 50
+	config.CircuitBreakerConsecutiveFailures = consecutiveFailures
+	config.CircuitBreakerOpenSeconds = 10
+	config.CircuitBreakerMaxOpenSeconds = 40
+	config.CircuitBreakerHalfOpenRequests = 2
+}
+
+// TestCircuitBreakerTransitions 测试熔断器状态流转：closed→open→half_open→closed / 重新打开
+func TestCircuitBreakerTransitions(t *testing.T) {
+	const channelId, modelName = 1, "gpt-4o"
+
+	// 步骤：success / failure 上报结果；allow / deny 申请放行并校验结果；
+	// release 释放试探名额；expire 让打开期立即到期
+	tests := []struct {
+		name                string
+		consecutiveFailures int
+		steps               []string
+		state               CircuitState
+		openSeconds         int64 // 打开状态下本次打开的时长
+		openCount           int
+	}{
+		{
+			name:                "连续失败达到阈值打开",
+			consecutiveFailures: 3,
+			steps:               []string{"failure", "failure", "failure", "deny"},
+			state:               CircuitOpen,
+			openSeconds:         10,
+			openCount:           1,
+		},
+		{
+			name:                "成功清零连续失败",
+			consecutiveFailures: 3,
+			steps:               []string{"failure", "failure", "success", "failure", "allow"},
+			state:               CircuitClosed,
+		},
+		{
+			name:  "错误率未达阈值保持关闭",
+			steps: []string{"success", "success", "success", "failure", "failure"},
+			state: CircuitClosed,
+		},
+		{
+			name:        "错误率达到阈值打开",
+			steps:       []string{"success", "success", "success", "failure", "failure", "failure"},
+			state:       CircuitOpen,
+			openSeconds: 10,
+			openCount:   1,
+		},
+		{
+			name:  "首次失败前的成功计入窗口",
+			steps: []string{"success", "success", "success", "success", "success", "success", "failure", "failure", "failure", "failure", "failure"},
+			state: CircuitClosed,
+		},
+		{
+			name:  "样本数不足不打开",
+			steps: []string{"failure", "failure", "failure", "failure"},
+			state: CircuitClosed,
+		},
+		{
+			name:                "到期后半开只放行有限的试探请求",
+			consecutiveFailures: 1,
+			steps:               []string{"failure", "deny", "expire", "allow", "allow", "deny"},
+			state:               CircuitHalfOpen,
+			openCount:           1,
+		},
+		{
+			name:                "释放名额后可以再次试探",
+			consecutiveFailures: 1,
+			steps:               []string{"failure", "expire", "allow", "allow", "release", "allow", "deny"},
+			state:               CircuitHalfOpen,
+			openCount:           1,
+		},
+		{
+			name:                "半开试探全部成功后关闭",
+			consecutiveFailures: 1,
+			steps:               []string{"failure", "expire", "allow", "allow", "success", "success", "allow"},
+			state:               CircuitClosed,
+		},
+		{
+			name:                "半开试探部分成功仍保持半开",
+			consecutiveFailures: 1,
+			steps:               []string{"failure", "expire", "allow", "allow", "success"},
+			state:               CircuitHalfOpen,
+			openCount:           1,
+		},
+		{
+			name:                "半开试探失败重新打开且时长翻倍",
+			consecutiveFailures: 1,
+			steps:               []string{"failure", "expire", "allow", "failure", "deny"},
+			state:               CircuitOpen,
+			openSeconds:         20,
+			openCount:           2,
+		},
+		{
+			name:                "打开时长不超过上限",
+			consecutiveFailures: 1,
+			steps:               []string{"failure", "expire", "allow", "failure", "expire", "allow", "failure", "expire", "allow", "failure"},
+			state:               CircuitOpen,
+			openSeconds:         40,
+			openCount:           4,
+		},
+		{
+			name:                "重新关闭后打开时长从头计算",
+			consecutiveFailures: 1,
+			steps:               []string{"failure", "expire", "allow", "failure", "expire", "allow", "allow", "success", "success", "failure"},
+			state:               CircuitOpen,
+			openSeconds:         10,
+			openCount:           1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupCircuitBreakerConfig(t, tt.consecutiveFailures)
+			registry := &circuitBreakerRegistry{}
+
+			for i, step := range tt.steps {
+				switch step {
+				case "success":
+					registry.RecordSuccess(channelId, modelName)
+				case "failure":
+					registry.RecordFailure(channelId, modelName)
+				case "release":
+					registry.Release(channelId, modelName)
+				case "expire":
+					breaker := registry.get(channelId, modelName, false)
+					breaker.openUntil = time.Now().Unix() - 1
+				case "allow", "deny":
+					if got := registry.Allow(channelId, modelName); got != (step == "allow") {
+						t.Fatalf("第 %d 步 Allow() = %v, 期望 %v", i+1, got, step == "allow")
+					}
+				}
+			}
+
+			breaker := registry.get(channelId, modelName, false)
+			if breaker == nil {
+				t.Fatal("熔断器应已创建")
+			}
+			if breaker.state != tt.state {
+				t.Errorf("state = %s, 期望 %s", breaker.state, tt.state)
+			}
+			if breaker.openCount != tt.openCount {
+				t.Errorf("openCount = %d, 期望 %d", breaker.openCount, tt.openCount)
+			}
+			if tt.state == CircuitOpen {
+				if got := breaker.openUntil - breaker.openedAt; got != tt.openSeconds {
+					t.Errorf("打开时长 = %d, 期望 %d", got, tt.openSeconds)
+				}
+				if registry.Available(channelId, modelName) {
+					t.Error("打开期间 Available() 应为 false")
+				}
+			}
+		})
+	}
+}
+
+// TestCircuitBreakerRecordSuccessCreatesBreaker 测试成功结果也会创建熔断器并计入窗口
+func TestCircuitBreakerRecordSuccessCreatesBreaker(t *testing.T) {
+	setupCircuitBreakerConfig(t, 0)
+	registry := &circuitBreakerRegistry{}
+
+	registry.RecordSuccess(1, "gpt-4o")
+	registry.RecordSuccess(1, "gpt-4o")
+	registry.RecordFailure(1, "gpt-4o")
+
+	statuses := registry.ChannelStatus(1)
+	if len(statuses) != 1 {
+		t.Fatalf("熔断器数量 = %d, 期望 1", len(statuses))
+	}
+	if statuses[0].Requests != 3 || statuses[0].Failures != 1 {
+		t.Errorf("窗口统计 = %d/%d, 期望 1/3", statuses[0].Failures, statuses[0].Requests)
+	}
+}
+
+// TestCircuitBreakerTrip 测试 Trip 立即打开，打开期内只延长不缩短
+func TestCircuitBreakerTrip(t *testing.T) {
+	setupCircuitBreakerConfig(t, 0)
+	registry := &circuitBreakerRegistry{}
+
+	registry.Trip(1, "gpt-4o", 30)
+	breaker := registry.get(1, "gpt-4o", false)
+	until := breaker.openUntil
+	if breaker.state != CircuitOpen || until-breaker.openedAt != 30 {
+		t.Fatalf("Trip 后 state = %s, 时长 %d", breaker.state, until-breaker.openedAt)
+	}
+
+	registry.Trip(1, "gpt-4o", 5)
+	if breaker.openUntil != until {
+		t.Errorf("更短的 Trip 不应缩短打开期: %d -> %d", until, breaker.openUntil)
+	}
+	registry.Trip(1, "gpt-4o", 120)
+	if breaker.openUntil <= until {
+		t.Errorf("更长的 Trip 应延长打开期: %d -> %d", until, breaker.openUntil)
+	}
+	if breaker.openCount != 1 {
+		t.Errorf("打开期内延长不应增加 openCount: %d", breaker.openCount)
+	}
+
+	registry.ResetChannel(1)
+	if registry.get(1, "gpt-4o", false) != nil {
+		t.Error("ResetChannel 后熔断器应被清除")
+	}
+}
@@ -0,0 +1,258 @@
+package model
+
+import (
+	"done-hub/common"
+	"done-hub/common/config"
+	"done-hub/common/logger"
+	"done-hub/common/redis"
+	"done-hub/common/utils"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// 多节点部署时通过 Redis 同步渠道运行状态：冷却/熔断、启用禁用、预算暂停和缓存重载走 pub/sub 即时广播，
+// 冷却/熔断同时写入带 TTL 的 key，供后启动或断线重连的节点恢复。未启用 Redis 时全部为本地行为。
+const (
+	clusterPubSubChannel     = "done-hub:cluster"
+	clusterCooldownKeyPrefix = "channel_cooldown:"
+	// clusterReloadDebounce 批量操作会连续触发多次重载，合并后只加载一次
+	clusterReloadDebounce = 500 * time.Millisecond
+)
+
+const (
+	clusterEventCooldown    = "cooldown"     // 渠道+模型进入冷却或熔断打开
+	clusterEventRecover     = "recover"      // 熔断恢复
+	clusterEventClear       = "clear"        // 清除渠道的全部冷却
+	clusterEventStatus      = "status"       // 渠道启用/禁用
+	clusterEventReload      = "reload"       // 渠道、分组等缓存需要重新加载
+	clusterEventKeyCooldown = "key_cooldown" // 多密钥渠道的密钥进入冷却
+	clusterEventBudgetPause = "budget_pause" // 渠道因花费预算暂停/恢复
+)
+
+type clusterEvent struct {
+	Node      string `json:"node"`
+	Type      string `json:"type"`
+	ChannelId int    `json:"channel_id,omitempty"`
+	Model     string `json:"model,omitempty"`
+	Until     int64  `json:"until,omitempty"`
+	OpenCount int    `json:"open_count,omitempty"`
+	Enabled   bool   `json:"enabled,omitempty"`
+	KeyId     int    `json:"key_id,omitempty"`
+	Paused    bool   `json:"paused,omitempty"`
+}
+
+var (
+	clusterNodeId      = utils.GetRandomString(16)
+	clusterReloadQueue = make(chan struct{}, 1)
+)
+
+// InitClusterSync 恢复其他节点写入的冷却状态并订阅集群事件，需在 Redis 初始化之后调用
+func InitClusterSync() {
+	if !config.RedisEnabled {
+		return
+	}
+
+	restoreClusterCooldowns()
+	common.SafeGoroutine(clusterReloadWorker)
+	common.SafeGoroutine(func() {
+		redis.RedisSubscribe(clusterPubSubChannel, handleClusterEvent)
+	})
+	logger.SysLog("cluster sync enabled, node: " + clusterNodeId)
+}
+
+// ReloadCaches 重新加载渠道、价格、模型归属、用户分组和虚拟模型
+func ReloadCaches() {
+	ChannelGroup.Load()
+	PricingInstance.Init()
+	ModelOwnedBysInstance.Load()
+	GlobalUserGroupRatio.Load()
+	VirtualModels.Load()
+}
+
+// PublishCacheReload 通知其他节点立即重新加载缓存，不必等待 SYNC_FREQUENCY
+func PublishCacheReload() {
+	publishClusterEvent(&clusterEvent{Type: clusterEventReload})
+}
+
+func publishChannelStatus(channelId int, enabled bool) {
+	publishClusterEvent(&clusterEvent{Type: clusterEventStatus, ChannelId: channelId, Enabled: enabled})
+}
+
+func publishBudgetPause(channelId int, paused bool) {
+	publishClusterEvent(&clusterEvent{Type: clusterEventBudgetPause, ChannelId: channelId, Paused: paused})
+}
+
+func publishCooldown(channelId int, modelName string, until int64, openCount int) {
+	if !config.RedisEnabled {
+		return
+	}
+
+	event := &clusterEvent{Type: clusterEventCooldown, ChannelId: channelId, Model: modelName, Until: until, OpenCount: openCount}
+	common.SafeGoroutine(func() {
+		ttl := time.Until(time.Unix(until, 0))
+		if ttl <= 0 {
+			return
+		}
+		if payload, err := json.Marshal(event); err == nil {
+			if err := redis.RedisSet(clusterCooldownKey(channelId, modelName), string(payload), ttl); err != nil {
+				logger.SysError("cluster set cooldown error: " + err.Error())
+			}
+		}
+		publishClusterEvent(event)
+	})
+}
+
+func publishRecover(channelId int, modelName string) {
+	if !config.RedisEnabled {
+		return
+	}
+
+	common.SafeGoroutine(func() {
+		redis.RedisDel(clusterCooldownKey(channelId, modelName))
+		publishClusterEvent(&clusterEvent{Type: clusterEventRecover, ChannelId: channelId, Model: modelName})
+	})
+}
+
+func publishClear(channelId int) {
+	if !config.RedisEnabled {
+		return
+	}
+
+	common.SafeGoroutine(func() {
+		keys, err := redis.RedisScanKeys(fmt.Sprintf("%s%d:*", clusterCooldownKeyPrefix, channelId))
+		if err != nil {
+			logger.SysError("cluster scan cooldown keys error: " + err.Error())
+		}
+		for _, key := range keys {
+			redis.RedisDel(key)
+		}
+		publishClusterEvent(&clusterEvent{Type: clusterEventClear, ChannelId: channelId})
+	})
+}
+
+func publishKeyCooldown(keyId int, until int64) {
+	if !config.RedisEnabled {
+		return
+	}
+
+	common.SafeGoroutine(func() {
+		publishClusterEvent(&clusterEvent{Type: clusterEventKeyCooldown, KeyId: keyId, Until: until})
+	})
+}
+
+func publishClusterEvent(event *clusterEvent) {
+	if !config.RedisEnabled {
+		return
+	}
+
+	payload, err := encodeClusterEvent(event)
+	if err != nil {
+		return
+	}
+	if err := redis.RedisPublish(clusterPubSubChannel, payload); err != nil {
+		logger.SysError("cluster publish event error: " + err.Error())
+	}
+}
+
+// encodeClusterEvent 标记发送节点后编码，接收方据此忽略自己发出的事件
+func encodeClusterEvent(event *clusterEvent) (string, error) {
+	event.Node = clusterNodeId
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return "", err
+	}
+	return string(payload), nil
+}
+
+func handleClusterEvent(payload string) {
+	var event clusterEvent
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		logger.SysError("cluster invalid event: " + err.Error())
+		return
+	}
+	if event.Node == clusterNodeId {
+		return
+	}
+
+	applyClusterEvent(&event)
+}
+
+func applyClusterEvent(event *clusterEvent) {
+	switch event.Type {
+	case clusterEventCooldown:
+		if config.CircuitBreakerEnabled {
+			CircuitBreakers.applyOpen(event.ChannelId, event.Model, event.Until, event.OpenCount)
+		} else {
+			ChannelGroup.applyCooldown(event.ChannelId, event.Model, event.Until)
+		}
+	case clusterEventRecover:
+		CircuitBreakers.applyRecover(event.ChannelId, event.Model)
+		ChannelGroup.Cooldowns.Delete(circuitKey(event.ChannelId, event.Model))
+	case clusterEventClear:
+		ChannelGroup.clearChannelCooldowns(event.ChannelId)
+	case clusterEventStatus:
+		if event.Enabled {
+			// 启用前渠道不在内存中，需要重新加载
+			scheduleClusterReload()
+		} else {
+			ChannelGroup.ChangeStatus(event.ChannelId, false)
+		}
+	case clusterEventReload:
+		scheduleClusterReload()
+	case clusterEventKeyCooldown:
+		ChannelKeys.applyCooldown(event.KeyId, event.Until)
+	case clusterEventBudgetPause:
+		ChannelGroup.SetBudgetPaused(event.ChannelId, event.Paused)
+	}
+}
+
+func scheduleClusterReload() {
+	select {
+	case clusterReloadQueue <- struct{}{}:
+	default:
+	}
+}
+
+func clusterReloadWorker() {
+	for range clusterReloadQueue {
+		time.Sleep(clusterReloadDebounce)
+		select {
+		case <-clusterReloadQueue:
+		default:
+		}
+
+		logger.SysLog("reloading caches by cluster event")
+		ReloadCaches()
+	}
+}
+
+// restoreClusterCooldowns 启动时载入仍在有效期内的冷却
+func restoreClusterCooldowns() {
+	keys, err := redis.RedisScanKeys(clusterCooldownKeyPrefix + "*")
+	if err != nil {
+		logger.SysError("cluster scan cooldown keys error: " + err.Error())
+		return
+	}
+
+	for _, key := range keys {
+		payload, err := redis.RedisGet(key)
+		if err != nil {
+			continue
+		}
+		var event clusterEvent
+		if err := json.Unmarshal([]byte(payload), &event); err != nil {
+			continue
+		}
+		applyClusterEvent(&event)
+	}
+
+	if len(keys) > 0 {
+		logger.SysLog("restored " + strconv.Itoa(len(keys)) + " channel cooldowns from redis")
+	}
+}
+
+func clusterCooldownKey(channelId int, modelName string) string {
+	return clusterCooldownKeyPrefix + circuitKey(channelId, modelName)
+}
@@ -0,0 +1,166 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"done-hub/common/config"
+)
+
+func drainClusterReloadQueue() {
+	for {
+		select {
+		case <-clusterReloadQueue:
+		default:
+			return
+		}
+	}
+}
+
+// setupClusterTest 渠道 1、2 在内存中，熔断器、密钥冷却与重载队列相互隔离
+func setupClusterTest(t *testing.T, circuitBreaker bool) {
+	t.Helper()
+	setupChannelTestDB(t)
+	createBudgetTestChannel(t, 1, config.ChannelStatusEnabled, ChannelBudget{})
+	createBudgetTestChannel(t, 2, config.ChannelStatusEnabled, ChannelBudget{})
+
+	savedNode, savedBreakers, savedKeys, savedEnabled := clusterNodeId, CircuitBreakers, ChannelKeys, config.CircuitBreakerEnabled
+	t.Cleanup(func() {
+		clusterNodeId, CircuitBreakers, ChannelKeys, config.CircuitBreakerEnabled = savedNode, savedBreakers, savedKeys, savedEnabled
+		for _, key := range []string{circuitKey(1, "gpt-4o"), circuitKey(1, "gpt-4o-mini"), circuitKey(2, "gpt-4o")} {
+			ChannelGroup.Cooldowns.Delete(key)
+		}
+		drainClusterReloadQueue()
+	})
+	CircuitBreakers = &circuitBreakerRegistry{}
+	ChannelKeys = &channelKeyRegistry{pools: make(map[int]*channelKeyPool)}
+	config.CircuitBreakerEnabled = circuitBreaker
+	drainClusterReloadQueue()
+}
+
+// TestClusterEventRoundTrip 测试各类集群事件编码后由其他节点应用，发送节点忽略自己的事件
+func TestClusterEventRoundTrip(t *testing.T) {
+	until := time.Now().Unix() + 60
+
+	tests := []struct {
+		name           string
+		event          clusterEvent
+		circuitBreaker bool
+		prepare        func()
+		applied        func() bool
+	}{
+		{
+			name:    "冷却",
+			event:   clusterEvent{Type: clusterEventCooldown, ChannelId: 1, Model: "gpt-4o", Until: until},
+			applied: func() bool { return ChannelGroup.IsInCooldown(1, "gpt-4o") },
+		},
+		{
+			name:           "熔断打开",
+			event:          clusterEvent{Type: clusterEventCooldown, ChannelId: 1, Model: "gpt-4o", Until: until, OpenCount: 2},
+			circuitBreaker: true,
+			applied:        func() bool { return !CircuitBreakers.Available(1, "gpt-4o") },
+		},
+		{
+			name:  "熔断恢复",
+			event: clusterEvent{Type: clusterEventRecover, ChannelId: 1, Model: "gpt-4o"},
+			prepare: func() {
+				CircuitBreakers.applyOpen(1, "gpt-4o", until, 1)
+				ChannelGroup.applyCooldown(1, "gpt-4o", until)
+			},
+			applied: func() bool {
+				_, cooling := ChannelGroup.Cooldowns.Load(circuitKey(1, "gpt-4o"))
+				return CircuitBreakers.Available(1, "gpt-4o") && !cooling
+			},
+		},
+		{
+			name:  "清除渠道冷却",
+			event: clusterEvent{Type: clusterEventClear, ChannelId: 1},
+			prepare: func() {
+				ChannelGroup.applyCooldown(1, "gpt-4o", until)
+				ChannelGroup.applyCooldown(1, "gpt-4o-mini", until)
+				ChannelGroup.applyCooldown(2, "gpt-4o", until)
+			},
+			applied: func() bool {
+				return !ChannelGroup.IsInCooldown(1, "gpt-4o") && !ChannelGroup.IsInCooldown(1, "gpt-4o-mini") &&
+					ChannelGroup.IsInCooldown(2, "gpt-4o")
+			},
+		},
+		{
+			name:    "禁用渠道",
+			event:   clusterEvent{Type: clusterEventStatus, ChannelId: 1, Enabled: false},
+			applied: func() bool { return ChannelGroup.Channels[1].Disable },
+		},
+		{
+			name:    "启用渠道时重新加载",
+			event:   clusterEvent{Type: clusterEventStatus, ChannelId: 1, Enabled: true},
+			prepare: func() { ChannelGroup.Disable(1) },
+			applied: func() bool { return len(clusterReloadQueue) == 1 },
+		},
+		{
+			name:    "重新加载缓存",
+			event:   clusterEvent{Type: clusterEventReload},
+			applied: func() bool { return len(clusterReloadQueue) == 1 },
+		},
+		{
+			name:    "密钥冷却",
+			event:   clusterEvent{Type: clusterEventKeyCooldown, KeyId: 7, Until: until},
+			applied: func() bool { return ChannelKeys.cooldownUntil(7) == until },
+		},
+		{
+			name:    "预算暂停",
+			event:   clusterEvent{Type: clusterEventBudgetPause, ChannelId: 1, Paused: true},
+			applied: func() bool { return budgetChoicePaused(1) },
+		},
+		{
+			name:    "预算恢复",
+			event:   clusterEvent{Type: clusterEventBudgetPause, ChannelId: 1, Paused: false},
+			prepare: func() { ChannelGroup.SetBudgetPaused(1, true) },
+			applied: func() bool { return !budgetChoicePaused(1) },
+		},
+	}
+
+	for _, tt := range tests {
+		for _, fromSelf := range []bool{false, true} {
+			name := tt.name + "/其他节点"
+			if fromSelf {
+				name = tt.name + "/本节点"
+			}
+			t.Run(name, func(t *testing.T) {
+				setupClusterTest(t, tt.circuitBreaker)
+				if tt.prepare != nil {
+					tt.prepare()
+				}
+
+				clusterNodeId = "node-a"
+				event := tt.event
+				payload, err := encodeClusterEvent(&event)
+				if err != nil {
+					t.Fatalf("encodeClusterEvent() error = %v", err)
+				}
+				decoded := clusterEvent{}
+				if err = json.Unmarshal([]byte(payload), &decoded); err != nil || decoded != event || decoded.Node != "node-a" {
+					t.Fatalf("解码 %s = %+v, %v, 期望 %+v", payload, decoded, err, event)
+				}
+
+				if !fromSelf {
+					clusterNodeId = "node-b"
+				}
+				handleClusterEvent(payload)
+				if got := tt.applied(); got == fromSelf {
+					t.Errorf("事件已应用 = %v, 期望 %v", got, !fromSelf)
+				}
+			})
+		}
+	}
+}
+
+// TestHandleClusterEventInvalidPayload 测试无法解析的事件被忽略
+func TestHandleClusterEventInvalidPayload(t *testing.T) {
+	setupClusterTest(t, false)
+	handleClusterEvent("not json")
+	handleClusterEvent(`{"node":"other","type":"unknown","channel_id":1}`)
+	if len(clusterReloadQueue) != 0 || ChannelGroup.Channels[1].Disable {
+		t.Error("无法解析或未知类型的事件不应改变状态")
+	}
+}
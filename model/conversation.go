@@ -0,0 +1,138 @@
+package model
+
+import (
+	"done-hub/common/utils"
+	"errors"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Conversation 网关保存的 Conversations API 会话，按用户隔离，任意渠道都可以在会话上继续对话
+type Conversation struct {
+	ID        string         `json:"id" gorm:"type:varchar(64);primaryKey"`
+	UserId    int            `json:"user_id" gorm:"index"`
+	Metadata  datatypes.JSON `json:"metadata" gorm:"type:json"`
+	CreatedAt int64          `json:"created_at" gorm:"bigint"`
+}
+
+// ConversationItem 会话中的一条 item，按自增 Id 保持写入顺序
+type ConversationItem struct {
+	Id             int            `json:"-"`
+	ItemID         string         `json:"id" gorm:"type:varchar(64);index:idx_conversation_item"`
+	ConversationID string         `json:"conversation_id" gorm:"type:varchar(64);index:idx_conversation_item"`
+	UserId         int            `json:"user_id" gorm:"index"`
+	Item           datatypes.JSON `json:"item" gorm:"type:json"`
+	Bytes          int64          `json:"bytes" gorm:"bigint"`
+	CreatedAt      int64          `json:"created_at" gorm:"bigint"`
+}
+
+// Insert 创建会话并写入初始 item
+func (conversation *Conversation) Insert(items []*ConversationItem) error {
+	if conversation.CreatedAt == 0 {
+		conversation.CreatedAt = utils.GetTimestamp()
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(conversation).Error; err != nil {
+			return err
+		}
+		return createConversationItems(tx, conversation, items)
+	})
+}
+
+// AddItems 向会话追加 item
+func (conversation *Conversation) AddItems(items []*ConversationItem) error {
+	return createConversationItems(DB, conversation, items)
+}
+
+func createConversationItems(tx *gorm.DB, conversation *Conversation, items []*ConversationItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	now := utils.GetTimestamp()
+	for _, item := range items {
+		item.ConversationID = conversation.ID
+		item.UserId = conversation.UserId
+		item.Bytes = int64(len(item.Item))
+		item.CreatedAt = now
+	}
+	return tx.Create(items).Error
+}
+
+// UpdateMetadata 更新会话的 metadata
+func (conversation *Conversation) UpdateMetadata(metadata []byte) error {
+	conversation.Metadata = metadata
+	return DB.Model(conversation).Update("metadata", conversation.Metadata).Error
+}
+
+// GetUserConversation 查询用户的会话，不存在时返回 nil
+func GetUserConversation(userId int, id string) (*Conversation, error) {
+	conversation := &Conversation{}
+	err := DB.Where("id = ? and user_id = ?", id, userId).First(conversation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return conversation, err
+}
+
+// DeleteUserConversation 删除用户的会话及其全部 item，返回是否存在
+func DeleteUserConversation(userId int, id string) (deleted bool, err error) {
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? and user_id = ?", id, userId).Delete(&Conversation{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		deleted = true
+		return tx.Where("conversation_id = ?", id).Delete(&ConversationItem{}).Error
+	})
+	return
+}
+
+// GetConversationItem 查询会话中的单条 item，不存在时返回 nil
+func GetConversationItem(conversationID, itemID string) (*ConversationItem, error) {
+	item := &ConversationItem{}
+	err := DB.Where("conversation_id = ? and item_id = ?", conversationID, itemID).First(item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return item, err
+}
+
+// GetConversationItems 按写入顺序列出会话中的 item，after 为上一页最后一条
+func GetConversationItems(conversationID string, after *ConversationItem, limit int, asc bool) (items []*ConversationItem, err error) {
+	tx := DB.Where("conversation_id = ?", conversationID)
+
+	order := "id desc"
+	if asc {
+		order = "id asc"
+		if after != nil {
+			tx = tx.Where("id > ?", after.Id)
+		}
+	} else if after != nil {
+		tx = tx.Where("id < ?", after.Id)
+	}
+
+	if limit > 0 {
+		tx = tx.Limit(limit)
+	}
+	err = tx.Order(order).Find(&items).Error
+	return
+}
+
+// DeleteConversationItem 删除会话中的单条 item，返回是否存在
+func DeleteConversationItem(conversationID, itemID string) (bool, error) {
+	result := DB.Where("conversation_id = ? and item_id = ?", conversationID, itemID).Delete(&ConversationItem{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetUserStorageBytes 统计用户在网关保存的文件与会话 item 总大小，用于 FileStorageQuota 限额
+func GetUserStorageBytes(userId int) (int64, error) {
+	fileBytes, err := GetUserFileBytes(userId)
+	if err != nil {
+		return 0, err
+	}
+
+	var itemBytes int64
+	err = DB.Model(&ConversationItem{}).Where("user_id = ?", userId).Select("COALESCE(SUM(bytes), 0)").Scan(&itemBytes).Error
+	return fileBytes + itemBytes, err
+}
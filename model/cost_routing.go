@@ -0,0 +1,143 @@
+package model
+
+import (
+	"done-hub/common/config"
+	"encoding/json"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CostRoutingChoice 成本优先路由的选择结果，记录在请求上下文中，计费时据此计算相对默认选择节省的成本
+type CostRoutingChoice struct {
+	ChannelId int
+	// CostRatio 选中渠道的有效成本倍率
+	CostRatio float64
+	// DefaultCostRatio 按权重随机选择时的期望成本，折算成选中渠道计费模型价格下的成本倍率，
+	// 计费时与 CostRatio 套用同一价格即可得到节省的成本
+	DefaultCostRatio float64
+}
+
+// effectiveCostRatio 渠道的有效成本倍率，未配置成本倍率的渠道按售价（倍率 1）计
+func effectiveCostRatio(channel *Channel) float64 {
+	if ratio := channel.GetCostRatio(); ratio > 0 {
+		return ratio
+	}
+	return 1
+}
+
+// loadChannelModelMapping 解析渠道的模型映射，为空或格式错误时返回 nil
+func loadChannelModelMapping(channel *Channel) map[string]string {
+	modelMapping := channel.GetModelMapping()
+	if modelMapping == "" || modelMapping == "{}" {
+		return nil
+	}
+
+	modelMap := make(map[string]string)
+	if err := json.Unmarshal([]byte(modelMapping), &modelMap); err != nil {
+		return nil
+	}
+	return modelMap
+}
+
+// billingModel 渠道实际计费的模型，与 ModelMappingHandler 一致：取映射后的模型，映射值以 + 开头时按原模型计费
+func (c *ChannelChoice) billingModel(modelName string) string {
+	mapped := c.modelMapping[modelName]
+	if mapped == "" || strings.HasPrefix(mapped, "+") {
+		return modelName
+	}
+	return mapped
+}
+
+// unitPrice 模型输入与输出售价之和，用于比较映射到不同模型的渠道；取不到价格时按 1 计
+func unitPrice(modelName string) float64 {
+	if PricingInstance == nil {
+		return 1
+	}
+	price := PricingInstance.GetPrice(modelName)
+	if unit := price.GetInput() + price.GetOutput(); unit > 0 {
+		return unit
+	}
+	return 1
+}
+
+// cost 路由比较用的有效成本 = 渠道计费模型的售价 × 成本倍率。
+// 同一个请求模型在各渠道可能映射到不同的模型，售价要按各渠道映射后的模型取。
+func (c *ChannelChoice) cost(modelName string) float64 {
+	return unitPrice(c.billingModel(modelName)) * effectiveCostRatio(c.Channel)
+}
+
+// pickByCost 成本优先选择：只在有效成本最低的渠道中按权重随机，
+// 更贵的渠道只有在便宜渠道失败（被跳过）或冷却时才会被选到。只在同一优先级内比较。
+func pickByCost(validChannels []*ChannelChoice, modelName string) int {
+	minCost := 0.0
+	cheapest := make([]*ChannelChoice, 0, len(validChannels))
+	indexes := make([]int, 0, len(validChannels))
+	totalWeight := 0
+	for i, choice := range validChannels {
+		cost := choice.cost(modelName)
+		if len(cheapest) > 0 && cost > minCost {
+			continue
+		}
+		if len(cheapest) == 0 || cost < minCost {
+			minCost = cost
+			cheapest = cheapest[:0]
+			indexes = indexes[:0]
+			totalWeight = 0
+		}
+		cheapest = append(cheapest, choice)
+		indexes = append(indexes, i)
+		totalWeight += int(*choice.Channel.Weight)
+	}
+
+	return indexes[pickByWeight(cheapest, totalWeight)]
+}
+
+// expectedCost 按权重随机选择（默认策略）时的期望有效成本
+func expectedCost(validChannels []*ChannelChoice, modelName string) float64 {
+	totalWeight := 0.0
+	weightedCost := 0.0
+	for _, choice := range validChannels {
+		weight := float64(*choice.Channel.Weight)
+		totalWeight += weight
+		weightedCost += choice.cost(modelName) * weight
+	}
+	if totalWeight <= 0 {
+		return 0
+	}
+	return weightedCost / totalWeight
+}
+
+func newCostRoutingChoice(choice *ChannelChoice, modelName string, defaultCost float64) *CostRoutingChoice {
+	return &CostRoutingChoice{
+		ChannelId:        choice.Channel.Id,
+		CostRatio:        effectiveCostRatio(choice.Channel),
+		DefaultCostRatio: defaultCost / unitPrice(choice.billingModel(modelName)),
+	}
+}
+
+// routingStrategy 请求体中 provider.sort 指定的策略优先，其次是令牌设置的路由策略，都未设置时使用分组的策略
+func routingStrategy(group string, ginContext interface{}) string {
+	if c, ok := ginContext.(*gin.Context); ok {
+		if value, exists := c.Get(config.GinRequestRoutingKey); exists {
+			if routing, ok := value.(*RequestRouting); ok && routing != nil && routing.Provider != nil {
+				if strategy := routing.Provider.RoutingStrategy(); strategy != "" {
+					return strategy
+				}
+			}
+		}
+		if value, exists := c.Get("token_setting"); exists {
+			if setting, ok := value.(*TokenSetting); ok && setting != nil && setting.RoutingStrategy != "" {
+				return setting.RoutingStrategy
+			}
+		}
+	}
+
+	return GlobalUserGroupRatio.GetRoutingStrategy(group)
+}
+
+func setCostRoutingChoice(ginContext interface{}, choice *CostRoutingChoice) {
+	if c, ok := ginContext.(*gin.Context); ok {
+		c.Set(config.GinCostRoutingKey, choice)
+	}
+}
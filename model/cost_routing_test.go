@@ -0,0 +1,124 @@
+package model
+
+import (
+	"math"
+	"testing"
+)
+
+func setupTestPricing(t *testing.T) {
+	t.Helper()
+	saved := PricingInstance
+	t.Cleanup(func() { PricingInstance = saved })
+
+	PricingInstance = &Pricing{Prices: map[string]*Price{
+		"gpt-4o":      {Model: "gpt-4o", Type: TokensPriceType, Input: 2.5, Output: 10},
+		"gpt-4o-mini": {Model: "gpt-4o-mini", Type: TokensPriceType, Input: 0.15, Output: 0.6},
+	}}
+}
+
+func newCostChoice(id int, weight uint, costRatio float64, modelMapping string) *ChannelChoice {
+	choice := newTestChoice(id, weight)
+	if costRatio > 0 {
+		choice.Channel.CostRatio = &costRatio
+	}
+	if modelMapping != "" {
+		choice.Channel.ModelMapping = &modelMapping
+	}
+	choice.modelMapping = loadChannelModelMapping(choice.Channel)
+	return choice
+}
+
+// TestPickByCost 测试成本优先选择：按渠道映射后模型的售价 × 成本倍率取最便宜的渠道
+func TestPickByCost(t *testing.T) {
+	setupTestPricing(t)
+
+	type channel struct {
+		weight       uint
+		costRatio    float64
+		modelMapping string
+	}
+	tests := []struct {
+		name     string
+		channels []channel
+		want     int
+	}{
+		{
+			name:     "成本倍率低的渠道胜出",
+			channels: []channel{{weight: 1, costRatio: 1}, {weight: 1, costRatio: 0.5}},
+			want:     1,
+		},
+		{
+			name:     "未配置成本倍率按售价计",
+			channels: []channel{{weight: 1}, {weight: 1, costRatio: 0.8}},
+			want:     1,
+		},
+		{
+			name:     "倍率更高但映射到更便宜的模型",
+			channels: []channel{{weight: 1, costRatio: 0.5}, {weight: 1, costRatio: 1, modelMapping: `{"gpt-4o":"gpt-4o-mini"}`}},
+			want:     1,
+		},
+		{
+			name:     "映射值带 + 时按原模型计费",
+			channels: []channel{{weight: 1, costRatio: 0.5}, {weight: 1, costRatio: 1, modelMapping: `{"gpt-4o":"+gpt-4o-mini"}`}},
+			want:     0,
+		},
+		{
+			name:     "映射其他模型不影响",
+			channels: []channel{{weight: 1, costRatio: 0.5}, {weight: 1, costRatio: 1, modelMapping: `{"gpt-4":"gpt-4o-mini"}`}},
+			want:     0,
+		},
+		{
+			name:     "映射格式错误视为没有映射",
+			channels: []channel{{weight: 1, costRatio: 0.5}, {weight: 1, costRatio: 1, modelMapping: `{"gpt-4o":`}},
+			want:     0,
+		},
+		{
+			name:     "同价渠道按权重随机，更贵的渠道不会被选中",
+			channels: []channel{{weight: 100, costRatio: 2}, {weight: 0, costRatio: 0.5}, {weight: 1, costRatio: 0.5}},
+			want:     2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			choices := make([]*ChannelChoice, 0, len(tt.channels))
+			for i, c := range tt.channels {
+				choices = append(choices, newCostChoice(i+1, c.weight, c.costRatio, c.modelMapping))
+			}
+
+			for round := 0; round < 20; round++ {
+				if got := pickByCost(choices, "gpt-4o"); got != tt.want {
+					t.Fatalf("第 %d 次 pickByCost() = %d, 期望 %d", round+1, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// TestCostRoutingChoice 测试默认选择的期望成本折算为选中渠道计费模型价格下的倍率
+func TestCostRoutingChoice(t *testing.T) {
+	setupTestPricing(t)
+
+	choices := []*ChannelChoice{
+		newCostChoice(1, 1, 1, ""),                         // gpt-4o: 12.5 × 1
+		newCostChoice(2, 3, 2, `{"gpt-4o":"gpt-4o-mini"}`), // gpt-4o-mini: 0.75 × 2
+	}
+
+	defaultCost := expectedCost(choices, "gpt-4o")
+	if want := (12.5*1 + 1.5*3) / 4; math.Abs(defaultCost-want) > 1e-9 {
+		t.Fatalf("expectedCost() = %v, 期望 %v", defaultCost, want)
+	}
+
+	index := pickByCost(choices, "gpt-4o")
+	if index != 1 {
+		t.Fatalf("pickByCost() = %d, 期望 1", index)
+	}
+	choice := newCostRoutingChoice(choices[index], "gpt-4o", defaultCost)
+	if choice.ChannelId != 2 || choice.CostRatio != 2 {
+		t.Errorf("选中渠道 = #%d 倍率 %v, 期望 #2 倍率 2", choice.ChannelId, choice.CostRatio)
+	}
+	// 按选中渠道的计费模型（gpt-4o-mini）价格计算时，DefaultCostRatio 对应的成本等于期望成本
+	if got := choice.DefaultCostRatio * 0.75; math.Abs(got-defaultCost) > 1e-9 {
+		t.Errorf("DefaultCostRatio × 售价 = %v, 期望 %v", got, defaultCost)
+	}
+}
@@ -0,0 +1,139 @@
+package model
+
+import (
+	"done-hub/common/logger"
+	"done-hub/common/storage"
+	"done-hub/common/utils"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// FilePurposeBatch Batch API 的输入文件
+	FilePurposeBatch = "batch"
+	// FilePurposeBatchOutput 批次结束后生成的输出 / 错误文件
+	FilePurposeBatchOutput = "batch_output"
+)
+
+// File 由网关保存的文件，对外即 OpenAI Files API 的文件对象，按用户与令牌隔离。
+// 文件内容保存在 storage 的文件存储中，表里只记录所在的驱动与 key。
+type File struct {
+	ID         string `json:"id" gorm:"type:varchar(64);primaryKey"`
+	UserId     int    `json:"-" gorm:"index:idx_file_user_token"`
+	TokenId    int    `json:"-" gorm:"index:idx_file_user_token"`
+	Purpose    string `json:"purpose" gorm:"type:varchar(32)"`
+	Filename   string `json:"filename" gorm:"type:varchar(255)"`
+	Bytes      int64  `json:"bytes" gorm:"bigint"`
+	Drive      string `json:"-" gorm:"type:varchar(32)"`
+	StorageKey string `json:"-" gorm:"type:varchar(255)"`
+	CreatedAt  int64  `json:"created_at" gorm:"bigint;index"`
+	ExpiresAt  int64  `json:"expires_at" gorm:"bigint;index"`
+}
+
+// Save 先把内容写入文件存储再落库，落库失败时清理已写入的内容
+func (file *File) Save(content []byte) error {
+	if file.CreatedAt == 0 {
+		file.CreatedAt = utils.GetTimestamp()
+	}
+	file.Bytes = int64(len(content))
+	file.StorageKey = fmt.Sprintf("files/%d/%s", file.UserId, file.ID)
+
+	drive, err := storage.PutFile(file.StorageKey, content)
+	if err != nil {
+		return err
+	}
+	file.Drive = drive
+
+	if err := DB.Create(file).Error; err != nil {
+		if delErr := storage.DeleteFile(file.Drive, file.StorageKey); delErr != nil {
+			logger.SysError(fmt.Sprintf("delete file %s from %s error: %s", file.ID, file.Drive, delErr.Error()))
+		}
+		return err
+	}
+	return nil
+}
+
+// ReadContent 读取文件内容
+func (file *File) ReadContent() ([]byte, error) {
+	return storage.GetFile(file.Drive, file.StorageKey)
+}
+
+// Delete 删除文件记录与存储中的内容。记录先删，存储删除失败只记日志，不影响文件对外不可见。
+func (file *File) Delete() error {
+	if err := DB.Delete(&File{}, "id = ?", file.ID).Error; err != nil {
+		return err
+	}
+
+	if err := storage.DeleteFile(file.Drive, file.StorageKey); err != nil {
+		logger.SysError(fmt.Sprintf("delete file %s from %s error: %s", file.ID, file.Drive, err.Error()))
+	}
+	return nil
+}
+
+// GetUserFileById 查询令牌下的文件
+func GetUserFileById(userId, tokenId int, id string) (*File, error) {
+	file := &File{}
+	err := DB.Where("id = ? and user_id = ? and token_id = ?", id, userId, tokenId).First(file).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return file, err
+}
+
+// GetUserFiles 按创建时间列出令牌下的文件，after 为上一页最后一个文件
+func GetUserFiles(userId, tokenId int, purpose string, after *File, limit int, asc bool) (files []*File, err error) {
+	tx := DB.Where("user_id = ? and token_id = ?", userId, tokenId)
+	if purpose != "" {
+		tx = tx.Where("purpose = ?", purpose)
+	}
+
+	order := "created_at desc, id desc"
+	if asc {
+		order = "created_at asc, id asc"
+		if after != nil {
+			tx = tx.Where("(created_at > ? or (created_at = ? and id > ?))", after.CreatedAt, after.CreatedAt, after.ID)
+		}
+	} else if after != nil {
+		tx = tx.Where("(created_at < ? or (created_at = ? and id < ?))", after.CreatedAt, after.CreatedAt, after.ID)
+	}
+
+	err = tx.Order(order).Limit(limit).Find(&files).Error
+	return
+}
+
+// GetUserFileBytes 统计用户已保存的文件总大小
+func GetUserFileBytes(userId int) (int64, error) {
+	var total int64
+	err := DB.Model(&File{}).Where("user_id = ?", userId).Select("COALESCE(SUM(bytes), 0)").Scan(&total).Error
+	return total, err
+}
+
+// GetExpiredFiles 取已过期的文件，供定时清理
+func GetExpiredFiles(limit int) (files []*File, err error) {
+	err = DB.Where("expires_at > 0 and expires_at <= ?", utils.GetTimestamp()).
+		Order("expires_at").Limit(limit).Find(&files).Error
+	return
+}
+
+// DeleteExpiredFiles 清理过期文件，返回删除的数量
+func DeleteExpiredFiles() (int, error) {
+	deleted := 0
+	for {
+		files, err := GetExpiredFiles(500)
+		if err != nil {
+			return deleted, err
+		}
+		if len(files) == 0 {
+			return deleted, nil
+		}
+
+		for _, file := range files {
+			if err := file.Delete(); err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+	}
+}
@@ -29,6 +29,7 @@ func SetupDB() {
 	GlobalUserGroupRatio.Load()
 	config.RootUserEmail = GetRootUserEmail()
 	NewModelOwnedBys()
+	VirtualModels.Load()
 
 	if viper.GetBool("batch_update_enabled") {
 		config.BatchUpdateEnabled = true
@@ -129,6 +130,18 @@ func InitDB() (err error) {
 		if err != nil {
 			return err
 		}
+		err = db.AutoMigrate(&ChannelKey{})
+		if err != nil {
+			return err
+		}
+		err = db.AutoMigrate(&ChannelBudgetUsage{})
+		if err != nil {
+			return err
+		}
+		err = db.AutoMigrate(&ChannelBalanceHistory{})
+		if err != nil {
+			return err
+		}
 		err = db.AutoMigrate(&Token{})
 		if err != nil {
 			return err
@@ -174,6 +187,26 @@ func InitDB() (err error) {
 		if err != nil {
 			return err
 		}
+		err = db.AutoMigrate(&BatchItem{})
+		if err != nil {
+			return err
+		}
+		err = db.AutoMigrate(&File{})
+		if err != nil {
+			return err
+		}
+		err = db.AutoMigrate(&Response{})
+		if err != nil {
+			return err
+		}
+		err = db.AutoMigrate(&Conversation{}, &ConversationItem{})
+		if err != nil {
+			return err
+		}
+		err = db.AutoMigrate(&WebhookDelivery{})
+		if err != nil {
+			return err
+		}
 		err = db.AutoMigrate(&Statistics{})
 		if err != nil {
 			return err
@@ -199,6 +232,11 @@ func InitDB() (err error) {
 			return err
 		}
 
+		err = db.AutoMigrate(&VirtualModel{})
+		if err != nil {
+			return err
+		}
+
 		err = DB.AutoMigrate(&WebAuthnCredential{})
 		if err != nil {
 			return err
@@ -46,6 +46,10 @@ type Midjourney struct {
 	Properties  string `json:"properties"`
 	Mode        string `json:"mode,omitempty"`
 	TokenID     int    `json:"token_id" gorm:"default:0"`
+	NotifyHook  string `json:"notify_hook"`
+
+	// ChannelKeyId 多密钥渠道提交任务时使用的密钥，后续操作和查询需使用同一个密钥
+	ChannelKeyId int `json:"channel_key_id" gorm:"default:0"`
 }
 
 // TaskQueryParams 用于包含所有搜索条件的结构体，可以根据需求添加更多字段
@@ -494,6 +494,54 @@ func migrateTokenLimitsStructure() *gormigrate.Migration {
 		},
 	}
 }
+
+// addTokenWebhookSecret 为已有令牌补全回调签名密钥，之后的令牌在保存时生成
+func addTokenWebhookSecret() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "202610170001",
+		Migrate: func(tx *gorm.DB) error {
+			type TokenRaw struct {
+				Id      int    `gorm:"column:id"`
+				Setting string `gorm:"column:setting;type:json"`
+			}
+
+			var tokens []TokenRaw
+			return tx.Table("tokens").Select("id, setting").FindInBatches(&tokens, 500, func(batch *gorm.DB, _ int) error {
+				for _, token := range tokens {
+					settingMap := make(map[string]interface{})
+					if token.Setting != "" {
+						if err := json.Unmarshal([]byte(token.Setting), &settingMap); err != nil || settingMap == nil {
+							continue
+						}
+					}
+
+					webhookMap, _ := settingMap["webhook"].(map[string]interface{})
+					if webhookMap == nil {
+						webhookMap = make(map[string]interface{})
+					}
+					if secret, _ := webhookMap["secret"].(string); secret != "" {
+						continue
+					}
+					webhookMap["secret"] = newWebhookSecret()
+					settingMap["webhook"] = webhookMap
+
+					newSettingBytes, err := json.Marshal(settingMap)
+					if err != nil {
+						continue
+					}
+					if err = tx.Model(&Token{}).Where("id = ?", token.Id).Update("setting", datatypes.JSON(newSettingBytes)).Error; err != nil {
+						logger.SysLog("补全令牌回调签名密钥失败: " + err.Error())
+					}
+				}
+				return nil
+			}).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return nil
+		},
+	}
+}
+
 func migrationAfter(db *gorm.DB) error {
 	// 从库不执行
 	if !config.IsMasterNode {
@@ -507,6 +555,7 @@ func migrationAfter(db *gorm.DB) error {
 		addOldTokenMaxId(),
 		addExtraRatios(),
 		migrateTokenLimitsStructure(),
+		addTokenWebhookSecret(),
 	})
 	return m.Migrate()
 }
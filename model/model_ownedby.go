@@ -30,6 +30,7 @@ func CreateModelOwnedBy(modelOwnedBy *ModelOwnedBy) error {
 	}
 
 	ModelOwnedBysInstance.Load()
+	PublishCacheReload()
 
 	return nil
 }
@@ -41,6 +42,7 @@ func UpdateModelOwnedBy(modelOwnedBy *ModelOwnedBy) error {
 	}
 
 	ModelOwnedBysInstance.Load()
+	PublishCacheReload()
 
 	return nil
 }
@@ -70,6 +72,7 @@ func DeleteModelOwnedBy(id int) error {
 	}
 
 	ModelOwnedBysInstance.Load()
+	PublishCacheReload()
 
 	return nil
 }
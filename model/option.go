@@ -149,6 +149,16 @@ func InitOptionMap() {
 		return nil
 	}, "")
 
+	config.GlobalOption.RegisterBool("CircuitBreakerEnabled", &config.CircuitBreakerEnabled)
+	config.GlobalOption.RegisterInt("CircuitBreakerWindowSeconds", &config.CircuitBreakerWindowSeconds)
+	config.GlobalOption.RegisterInt("CircuitBreakerMinRequests", &config.CircuitBreakerMinRequests)
+	config.GlobalOption.RegisterInt("CircuitBreakerErrorRate", &config.CircuitBreakerErrorRate)
+	config.GlobalOption.RegisterInt("CircuitBreakerConsecutiveFailures", &config.CircuitBreakerConsecutiveFailures)
+	config.GlobalOption.RegisterInt("CircuitBreakerOpenSeconds", &config.CircuitBreakerOpenSeconds)
+	config.GlobalOption.RegisterInt("CircuitBreakerMaxOpenSeconds", &config.CircuitBreakerMaxOpenSeconds)
+	config.GlobalOption.RegisterInt("CircuitBreakerHalfOpenRequests", &config.CircuitBreakerHalfOpenRequests)
+	config.GlobalOption.RegisterInt("LatencyRoutingExploreRate", &config.LatencyRoutingExploreRate)
+
 	config.GlobalOption.RegisterBool("MjNotifyEnabled", &config.MjNotifyEnabled)
 	config.GlobalOption.RegisterBool("BuiltinChatEnabled", &config.BuiltinChatEnabled)
 	config.GlobalOption.RegisterString("ChatImageRequestProxy", &config.ChatImageRequestProxy)
@@ -179,6 +189,38 @@ func InitOptionMap() {
 	}, common.GetDefaultDisableChannelKeywords())
 
 	config.GlobalOption.RegisterInt("RetryTimeOut", &config.RetryTimeOut)
+	config.GlobalOption.RegisterFloat("BatchPriceRatio", &config.BatchPriceRatio)
+	config.GlobalOption.RegisterInt("BatchConcurrency", &config.BatchConcurrency)
+	config.GlobalOption.RegisterInt("FileMaxSize", &config.FileMaxSize)
+	config.GlobalOption.RegisterInt("FileStorageQuota", &config.FileStorageQuota)
+	config.GlobalOption.RegisterInt("FileExpireDays", &config.FileExpireDays)
+	config.GlobalOption.RegisterBool("ResponsesStoreEnabled", &config.ResponsesStoreEnabled)
+	config.GlobalOption.RegisterInt("ResponsesRetentionDays", &config.ResponsesRetentionDays)
+	config.GlobalOption.RegisterBool("ResponseCacheEnabled", &config.ResponseCacheEnabled)
+	config.GlobalOption.RegisterInt("ResponseCacheTTL", &config.ResponseCacheTTL)
+	config.GlobalOption.RegisterFloat("ResponseCacheHitRatio", &config.ResponseCacheHitRatio)
+	config.GlobalOption.RegisterCustom("ResponseCacheModelTTL", func() string {
+		return config.ResponseCacheModelTTL
+	}, func(value string) error {
+		trimmed := strings.TrimSpace(value)
+		if trimmed == "" {
+			config.ResponseCacheModelTTL = ""
+			config.SetResponseCacheModelTTLMap(map[string]int{})
+			return nil
+		}
+		parsed := map[string]int{}
+		if err := json.Unmarshal([]byte(trimmed), &parsed); err != nil {
+			return fmt.Errorf("ResponseCacheModelTTL must be a JSON object of model->seconds: %w", err)
+		}
+		for modelName, ttl := range parsed {
+			if ttl < 0 {
+				return fmt.Errorf("invalid cache ttl for model %s: %d (must be non-negative)", modelName, ttl)
+			}
+		}
+		config.ResponseCacheModelTTL = trimmed
+		config.SetResponseCacheModelTTLMap(parsed)
+		return nil
+	}, "")
 
 	config.GlobalOption.RegisterBool("EnableSafe", &config.EnableSafe)
 	config.GlobalOption.RegisterString("SafeToolName", &config.SafeToolName)
@@ -0,0 +1,107 @@
+package model
+
+import (
+	"strings"
+	"unicode"
+)
+
+// provider.sort 对应的渠道选择策略
+var providerSortStrategies = map[string]string{
+	"price":      RoutingStrategyCost,
+	"latency":    RoutingStrategyLatency,
+	"throughput": RoutingStrategyLatency,
+}
+
+// ProviderPreferences OpenRouter 风格的 provider 参数，provider 名称按渠道标签或渠道类型名称匹配
+type ProviderPreferences struct {
+	Order          []string `json:"order,omitempty"`           // 按顺序优先尝试的 provider
+	Only           []string `json:"only,omitempty"`            // 只允许这些 provider
+	Ignore         []string `json:"ignore,omitempty"`          // 跳过这些 provider
+	AllowFallbacks *bool    `json:"allow_fallbacks,omitempty"` // 为 false 时只使用 order 中的 provider，默认允许
+	Sort           string   `json:"sort,omitempty"`            // price / latency / throughput，覆盖分组的渠道选择策略
+}
+
+// RequestRouting 请求体中 OpenRouter 风格的路由参数：models 回退列表与 provider 偏好
+type RequestRouting struct {
+	Models   []string             `json:"models,omitempty"`
+	Provider *ProviderPreferences `json:"provider,omitempty"`
+}
+
+// AllowsFallbacks order 中的 provider 都没有可用渠道时，是否允许使用其他 provider
+func (p *ProviderPreferences) AllowsFallbacks() bool {
+	return p.AllowFallbacks == nil || *p.AllowFallbacks
+}
+
+// RoutingStrategy 返回 sort 对应的渠道选择策略，未设置或无法识别时返回空
+func (p *ProviderPreferences) RoutingStrategy() string {
+	return providerSortStrategies[strings.ToLower(strings.TrimSpace(p.Sort))]
+}
+
+// Filters 生成 only、ignore 与 allow_fallbacks 对应的渠道过滤器
+func (p *ProviderPreferences) Filters() []ChannelsFilterFunc {
+	var filters []ChannelsFilterFunc
+	if len(p.Only) > 0 {
+		filters = append(filters, FilterProviders(p.Only))
+	}
+	if len(p.Ignore) > 0 {
+		ignore := p.Ignore
+		filters = append(filters, func(_ int, choice *ChannelChoice) bool {
+			return MatchProvider(choice.Channel, ignore)
+		})
+	}
+	if len(p.Order) > 0 && !p.AllowsFallbacks() {
+		filters = append(filters, FilterProviders(p.Order))
+	}
+
+	return filters
+}
+
+// FilterProviders 跳过不属于任一指定 provider 的渠道
+func FilterProviders(names []string) ChannelsFilterFunc {
+	return func(_ int, choice *ChannelChoice) bool {
+		return !MatchProvider(choice.Channel, names)
+	}
+}
+
+// MatchProvider 渠道标签或渠道类型名称与任一 provider 名称相同（忽略大小写与符号）
+func MatchProvider(channel *Channel, names []string) bool {
+	tag := normalizeProviderName(channel.Tag)
+	typeName := normalizeProviderName(channelTypeProviderName(channel.Type))
+	for _, name := range names {
+		name = normalizeProviderName(name)
+		if name == "" {
+			continue
+		}
+		if name == tag || name == typeName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ChannelProviderName 渠道对外展示的 provider 名称，有标签时为标签，否则为渠道类型名称
+func ChannelProviderName(channel *Channel) string {
+	if channel.Tag != "" {
+		return channel.Tag
+	}
+
+	return channelTypeProviderName(channel.Type)
+}
+
+func channelTypeProviderName(channelType int) string {
+	if ModelOwnedBysInstance == nil {
+		return ""
+	}
+
+	return ModelOwnedBysInstance.GetName(channelType)
+}
+
+func normalizeProviderName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"done-hub/common/utils"
+	"errors"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Response 网关保存的 Responses API 响应对象，用于在任意渠道上支持 previous_response_id、
+// 以及 GET/DELETE /v1/responses/{id} 等接口，按用户隔离。
+// Input 只记录本轮请求自身的输入 item，历史轮次沿 PreviousResponseID 回溯得到。
+type Response struct {
+	ID                 string         `json:"id" gorm:"type:varchar(64);primaryKey"`
+	UserId             int            `json:"user_id" gorm:"index"`
+	TokenId            int            `json:"token_id"`
+	Model              string         `json:"model" gorm:"type:varchar(128)"`
+	PreviousResponseID string         `json:"previous_response_id" gorm:"type:varchar(64)"`
+	Input              datatypes.JSON `json:"input" gorm:"type:json"`
+	Object             datatypes.JSON `json:"object" gorm:"type:json"`
+	CreatedAt          int64          `json:"created_at" gorm:"bigint"`
+	ExpiresAt          int64          `json:"expires_at" gorm:"bigint;index"`
+}
+
+func (r *Response) Insert() error {
+	if r.CreatedAt == 0 {
+		r.CreatedAt = utils.GetTimestamp()
+	}
+	return DB.Create(r).Error
+}
+
+// GetUserResponse 查询用户保存的响应，不存在或已过期时返回 nil
+func GetUserResponse(userId int, id string) (*Response, error) {
+	response := &Response{}
+	err := DB.Where("id = ? and user_id = ?", id, userId).
+		Where("(expires_at = 0 or expires_at > ?)", utils.GetTimestamp()).
+		First(response).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return response, err
+}
+
+// DeleteUserResponse 删除用户保存的响应，返回是否存在
+func DeleteUserResponse(userId int, id string) (bool, error) {
+	result := DB.Where("id = ? and user_id = ?", id, userId).Delete(&Response{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteExpiredResponses 清理过期的响应，返回删除的数量
+func DeleteExpiredResponses() (int64, error) {
+	result := DB.Where("expires_at > 0 and expires_at <= ?", utils.GetTimestamp()).Delete(&Response{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,146 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// 渠道能力标记，内容路由规则按能力标记或渠道标签筛选渠道
+const (
+	ChannelCapabilityVision            = "vision"
+	ChannelCapabilityLongContext       = "long_context"
+	ChannelCapabilityNoFunctionCalling = "no_function_calling"
+)
+
+// HasCapability 渠道是否带有某个能力标记，渠道标签与之相同时也视为具备
+func (c *Channel) HasCapability(name string) bool {
+	if c.Tag != "" && c.Tag == name {
+		return true
+	}
+	if c.Capabilities == nil {
+		return false
+	}
+
+	return slices.Contains(*c.Capabilities, name)
+}
+
+// RoutingRule 分组的内容路由规则，请求满足全部条件时按能力标记或标签限定可选的渠道
+type RoutingRule struct {
+	Name            string   `json:"name"`
+	MinPromptTokens int      `json:"min_prompt_tokens,omitempty"` // 输入 tokens 不少于该值时命中，0 为不限
+	HasImage        bool     `json:"has_image,omitempty"`         // 请求包含图片时命中
+	HasTools        bool     `json:"has_tools,omitempty"`         // 请求包含工具（函数调用）时命中
+	Require         []string `json:"require,omitempty"`           // 渠道至少具备其中一个能力标记或标签
+	Exclude         []string `json:"exclude,omitempty"`           // 具备其中任一能力标记或标签的渠道被跳过
+}
+
+// Validate 规则至少要有一个条件和一个动作
+func (r *RoutingRule) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Require = trimRoutingRuleValues(r.Require)
+	r.Exclude = trimRoutingRuleValues(r.Exclude)
+
+	if r.MinPromptTokens < 0 {
+		return errors.New("输入 tokens 阈值不能为负数")
+	}
+	if r.MinPromptTokens == 0 && !r.HasImage && !r.HasTools {
+		return errors.New("至少需要一个匹配条件")
+	}
+	if len(r.Require) == 0 && len(r.Exclude) == 0 {
+		return errors.New("至少需要填写要求或排除的能力")
+	}
+
+	return nil
+}
+
+func trimRoutingRuleValues(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value != "" && !slices.Contains(result, value) {
+			result = append(result, value)
+		}
+	}
+
+	return result
+}
+
+// Match 判断请求内容是否满足规则的全部条件
+func (r *RoutingRule) Match(content *RoutingContent) bool {
+	if r.HasImage && !content.HasImage {
+		return false
+	}
+	if r.HasTools && !content.HasTools {
+		return false
+	}
+	if r.MinPromptTokens > 0 && content.PromptTokens() < r.MinPromptTokens {
+		return false
+	}
+
+	return true
+}
+
+// Filter 生成渠道过滤器，不满足规则要求的渠道被跳过
+func (r *RoutingRule) Filter() ChannelsFilterFunc {
+	require := r.Require
+	exclude := r.Exclude
+	return func(_ int, choice *ChannelChoice) bool {
+		for _, name := range exclude {
+			if choice.Channel.HasCapability(name) {
+				return true
+			}
+		}
+		if len(require) == 0 {
+			return false
+		}
+		for _, name := range require {
+			if choice.Channel.HasCapability(name) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// RoutingContent 选择渠道前识别出的请求内容特征。输入 tokens 只在有规则需要时才计算，
+// 且只计算一次，重试与分组降级时复用。
+type RoutingContent struct {
+	HasImage bool
+	HasTools bool
+
+	countTokens  func() int
+	tokensOnce   sync.Once
+	promptTokens int
+}
+
+func NewRoutingContent(hasImage, hasTools bool, countTokens func() int) *RoutingContent {
+	return &RoutingContent{
+		HasImage:    hasImage,
+		HasTools:    hasTools,
+		countTokens: countTokens,
+	}
+}
+
+func (r *RoutingContent) PromptTokens() int {
+	r.tokensOnce.Do(func() {
+		if r.countTokens != nil {
+			r.promptTokens = r.countTokens()
+		}
+	})
+
+	return r.promptTokens
+}
+
+// validateRoutingRules 校验分组的内容路由规则
+func validateRoutingRules(rules []RoutingRule) error {
+	for i := range rules {
+		if err := rules[i].Validate(); err != nil {
+			return fmt.Errorf("第 %d 条路由规则%s", i+1, err.Error())
+		}
+	}
+
+	return nil
+}
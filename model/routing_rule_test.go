@@ -0,0 +1,189 @@
+package model
+
+import (
+	"slices"
+	"testing"
+
+	"gorm.io/datatypes"
+)
+
+// newRoutingTestChoices 测试用渠道：1 无标记，2 vision，3 long_context，4 标签为 vision，5 vision 且 no_function_calling
+func newRoutingTestChoices() map[int]*ChannelChoice {
+	withCapabilities := func(id int, tag string, capabilities ...string) *ChannelChoice {
+		choice := newTestChoice(id, 1)
+		choice.Channel.Tag = tag
+		if len(capabilities) > 0 {
+			value := datatypes.JSONSlice[string](capabilities)
+			choice.Channel.Capabilities = &value
+		}
+		return choice
+	}
+
+	return map[int]*ChannelChoice{
+		1: withCapabilities(1, ""),
+		2: withCapabilities(2, "", ChannelCapabilityVision),
+		3: withCapabilities(3, "", ChannelCapabilityLongContext),
+		4: withCapabilities(4, ChannelCapabilityVision),
+		5: withCapabilities(5, "", ChannelCapabilityVision, ChannelCapabilityNoFunctionCalling),
+	}
+}
+
+// remainingRoutingChannels 应用过滤器后剩余的渠道 ID
+func remainingRoutingChannels(filters []ChannelsFilterFunc) []int {
+	var remaining []int
+	for id, choice := range newRoutingTestChoices() {
+		skipped := false
+		for _, filter := range filters {
+			if filter(id, choice) {
+				skipped = true
+				break
+			}
+		}
+		if !skipped {
+			remaining = append(remaining, id)
+		}
+	}
+	slices.Sort(remaining)
+	return remaining
+}
+
+// TestRoutingRuleValidate 测试规则校验：至少一个条件和一个动作，能力名去空白去重
+func TestRoutingRuleValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		rule        RoutingRule
+		wantErr     bool
+		wantRequire []string
+	}{
+		{name: "输入 tokens 阈值与要求", rule: RoutingRule{MinPromptTokens: 100000, Require: []string{"long_context"}}, wantRequire: []string{"long_context"}},
+		{name: "包含图片与排除", rule: RoutingRule{HasImage: true, Exclude: []string{"text_only"}}, wantRequire: []string{}},
+		{name: "能力名去空白去重", rule: RoutingRule{HasTools: true, Require: []string{" vision ", "vision", "", "tools"}}, wantRequire: []string{"vision", "tools"}},
+		{name: "没有条件", rule: RoutingRule{Require: []string{"vision"}}, wantErr: true},
+		{name: "没有动作", rule: RoutingRule{HasImage: true, Require: []string{" "}}, wantErr: true},
+		{name: "阈值为负数", rule: RoutingRule{MinPromptTokens: -1, Require: []string{"vision"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, 期望出错 %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !slices.Equal(tt.rule.Require, tt.wantRequire) {
+				t.Errorf("Require = %v, 期望 %v", tt.rule.Require, tt.wantRequire)
+			}
+		})
+	}
+}
+
+// TestRoutingRuleMatch 测试规则匹配：请求须满足全部条件，输入 tokens 只在需要时计算
+func TestRoutingRuleMatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		rule       RoutingRule
+		hasImage   bool
+		hasTools   bool
+		tokens     int
+		want       bool
+		wantCounts bool // 是否计算了输入 tokens
+	}{
+		{name: "达到 tokens 阈值", rule: RoutingRule{MinPromptTokens: 1000}, tokens: 1000, want: true, wantCounts: true},
+		{name: "未达到 tokens 阈值", rule: RoutingRule{MinPromptTokens: 1000}, tokens: 999, want: false, wantCounts: true},
+		{name: "包含图片", rule: RoutingRule{HasImage: true}, hasImage: true, want: true},
+		{name: "不包含图片", rule: RoutingRule{HasImage: true}, hasTools: true, want: false},
+		{name: "包含工具", rule: RoutingRule{HasTools: true}, hasTools: true, want: true},
+		{name: "不包含工具", rule: RoutingRule{HasTools: true}, hasImage: true, want: false},
+		{name: "图片与阈值同时满足", rule: RoutingRule{HasImage: true, MinPromptTokens: 500}, hasImage: true, tokens: 800, want: true, wantCounts: true},
+		{name: "不包含图片时不计算 tokens", rule: RoutingRule{HasImage: true, MinPromptTokens: 500}, tokens: 800, want: false},
+		{name: "图片与工具须同时包含", rule: RoutingRule{HasImage: true, HasTools: true}, hasImage: true, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counts := 0
+			content := NewRoutingContent(tt.hasImage, tt.hasTools, func() int {
+				counts++
+				return tt.tokens
+			})
+			if got := tt.rule.Match(content); got != tt.want {
+				t.Errorf("Match() = %v, 期望 %v", got, tt.want)
+			}
+			if (counts > 0) != tt.wantCounts {
+				t.Errorf("计算输入 tokens %d 次, 期望计算 %v", counts, tt.wantCounts)
+			}
+		})
+	}
+}
+
+// TestRoutingRuleFilter 测试规则生成的过滤器：要求其中一个能力标记或标签，排除优先于要求
+func TestRoutingRuleFilter(t *testing.T) {
+	tests := []struct {
+		name string
+		rule RoutingRule
+		want []int
+	}{
+		{name: "要求能力标记，标签相同也视为具备", rule: RoutingRule{Require: []string{ChannelCapabilityVision}}, want: []int{2, 4, 5}},
+		{name: "要求其中一个能力", rule: RoutingRule{Require: []string{ChannelCapabilityVision, ChannelCapabilityLongContext}}, want: []int{2, 3, 4, 5}},
+		{name: "排除能力", rule: RoutingRule{Exclude: []string{ChannelCapabilityNoFunctionCalling}}, want: []int{1, 2, 3, 4}},
+		{name: "要求与排除同时生效", rule: RoutingRule{Require: []string{ChannelCapabilityVision}, Exclude: []string{ChannelCapabilityNoFunctionCalling}}, want: []int{2, 4}},
+		{name: "没有渠道具备时全部跳过", rule: RoutingRule{Require: []string{"audio"}}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := remainingRoutingChannels([]ChannelsFilterFunc{tt.rule.Filter()}); !slices.Equal(got, tt.want) {
+				t.Errorf("剩余渠道 = %v, 期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestGetRoutingRuleFilters 测试分组按请求内容生成过滤器：每条命中的规则生成一个过滤器，共同生效
+func TestGetRoutingRuleFilters(t *testing.T) {
+	rules := datatypes.NewJSONType([]RoutingRule{
+		{Name: "长上下文", MinPromptTokens: 100000, Require: []string{ChannelCapabilityLongContext}},
+		{Name: "图片", HasImage: true, Require: []string{ChannelCapabilityVision}},
+		{Name: "工具", HasTools: true, Exclude: []string{ChannelCapabilityNoFunctionCalling}},
+	})
+	ratio := &UserGroupRatio{UserGroup: map[string]*UserGroup{
+		"routed": {Symbol: "routed", RoutingRules: &rules},
+		"plain":  {Symbol: "plain"},
+	}}
+
+	tests := []struct {
+		name        string
+		group       string
+		hasImage    bool
+		hasTools    bool
+		tokens      int
+		noContent   bool
+		wantFilters int
+		want        []int
+	}{
+		{name: "普通请求不命中规则", group: "routed", tokens: 100, wantFilters: 0, want: []int{1, 2, 3, 4, 5}},
+		{name: "长上下文", group: "routed", tokens: 200000, wantFilters: 1, want: []int{3}},
+		{name: "包含图片", group: "routed", hasImage: true, tokens: 100, wantFilters: 1, want: []int{2, 4, 5}},
+		{name: "包含工具", group: "routed", hasTools: true, tokens: 100, wantFilters: 1, want: []int{1, 2, 3, 4}},
+		{name: "图片与工具", group: "routed", hasImage: true, hasTools: true, tokens: 100, wantFilters: 2, want: []int{2, 4}},
+		{name: "长上下文与图片没有同时具备的渠道", group: "routed", hasImage: true, tokens: 200000, wantFilters: 2, want: nil},
+		{name: "分组没有规则", group: "plain", hasImage: true, tokens: 200000, wantFilters: 0, want: []int{1, 2, 3, 4, 5}},
+		{name: "分组不存在", group: "missing", hasImage: true, wantFilters: 0, want: []int{1, 2, 3, 4, 5}},
+		{name: "没有请求内容", group: "routed", noContent: true, wantFilters: 0, want: []int{1, 2, 3, 4, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var content *RoutingContent
+			if !tt.noContent {
+				content = NewRoutingContent(tt.hasImage, tt.hasTools, func() int { return tt.tokens })
+			}
+			filters := ratio.GetRoutingRuleFilters(tt.group, content)
+			if len(filters) != tt.wantFilters {
+				t.Errorf("过滤器数量 = %d, 期望 %d", len(filters), tt.wantFilters)
+			}
+			if got := remainingRoutingChannels(filters); !slices.Equal(got, tt.want) {
+				t.Errorf("剩余渠道 = %v, 期望 %v", got, tt.want)
+			}
+		})
+	}
+}
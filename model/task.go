@@ -2,6 +2,7 @@ package model
 
 import (
 	"errors"
+	"slices"
 
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
@@ -10,6 +11,12 @@ import (
 const (
 	TaskPlatformSuno  = "suno"
 	TaskPlatformKling = "kling"
+	// TaskPlatformClaudeBatch Anthropic Message Batches，TaskID 为对外暴露的批次 id
+	TaskPlatformClaudeBatch = "claude_batch"
+	// TaskPlatformOpenAIBatch OpenAI Batch API，由网关逐条代执行
+	TaskPlatformOpenAIBatch = "openai_batch"
+	// TaskPlatformVideo OpenAI 兼容的 /v1/videos，TaskID 为对外暴露的视频 id，上游任务 id 存放在 Properties
+	TaskPlatformVideo = "video"
 )
 
 type TaskStatus string
@@ -44,6 +51,9 @@ type Task struct {
 	Data       datatypes.JSON `json:"data" gorm:"type:json"`
 	NotifyHook string         `json:"notify_hook"`
 	TokenID    int            `json:"token_id" gorm:"default:0"`
+
+	// ChannelKeyId 多密钥渠道提交任务时使用的密钥，查询任务需使用同一个密钥
+	ChannelKeyId int `json:"channel_key_id" gorm:"default:0"`
 }
 
 func GetTaskByTaskIds(platform string, userId int, taskIds []string) (task []*Task, err error) {
@@ -71,6 +81,23 @@ func GetTaskByTaskId(platform string, userId int, taskId string) (task *Task, er
 	return
 }
 
+// GetUserTasksByCursor 按 id 倒序列出用户某平台的任务，兼容上游 list 接口的游标语义：
+// afterID 取比它更早的一页，beforeID 取比它更新的一页
+func GetUserTasksByCursor(platform string, userId int, beforeID, afterID int64, limit int) (tasks []*Task, err error) {
+	tx := DB.Where("platform = ? and user_id = ?", platform, userId)
+	if beforeID > 0 {
+		err = tx.Where("id > ?", beforeID).Order("id asc").Limit(limit).Find(&tasks).Error
+		slices.Reverse(tasks)
+		return
+	}
+
+	if afterID > 0 {
+		tx = tx.Where("id < ?", afterID)
+	}
+	err = tx.Order("id desc").Limit(limit).Find(&tasks).Error
+	return
+}
+
 func (Task *Task) Insert() error {
 	return DB.Create(Task).Error
 }
@@ -106,6 +133,15 @@ func TaskBulkUpdateByID(ids []int64, params map[string]any) error {
 		Updates(params).Error
 }
 
+// TaskUpdateIfUnfinished 仅在任务尚未完成（progress != 100）时更新，返回是否命中。
+// 多个节点同时轮询同一任务时，用它保证完成后的副作用（如结果回收、计费）只执行一次。
+func TaskUpdateIfUnfinished(id int64, params map[string]any) (bool, error) {
+	result := DB.Model(&Task{}).
+		Where("id = ? and progress != ?", id, 100).
+		Updates(params)
+	return result.RowsAffected > 0, result.Error
+}
+
 func GetAllUnFinishSyncTasks(limit int) []*Task {
 	var tasks []*Task
 	// get all tasks progress is not 100%
@@ -69,9 +69,15 @@ func (token *Token) AfterCreate(tx *gorm.DB) (err error) {
 }
 
 type TokenSetting struct {
-	Heartbeat  HeartbeatSetting `json:"heartbeat,omitempty"`
-	Limits     LimitsConfig     `json:"limits,omitempty"`
-	BillingTag *string          `json:"billing_tag,omitempty"` // 费用标签，用于按分组统计费用，仅可信内部员工和管理员可见
+	Heartbeat     HeartbeatSetting     `json:"heartbeat,omitempty"`
+	Limits        LimitsConfig         `json:"limits,omitempty"`
+	ResponseCache ResponseCacheSetting `json:"response_cache,omitempty"`
+	BillingTag    *string              `json:"billing_tag,omitempty"` // 费用标签，用于按分组统计费用，仅可信内部员工和管理员可见
+	Webhook       WebhookSetting       `json:"webhook,omitempty"`
+	// RoutingStrategy 覆盖分组的渠道路由策略（weight/latency/cost），为空时跟随分组
+	RoutingStrategy string `json:"routing_strategy,omitempty"`
+	// HedgeDelay 对冲请求阈值（毫秒），大于 0 时覆盖分组的设置
+	HedgeDelay int `json:"hedge_delay,omitempty"`
 }
 
 type HeartbeatSetting struct {
@@ -79,6 +85,18 @@ type HeartbeatSetting struct {
 	TimeoutSeconds int  `json:"timeout_seconds"`
 }
 
+// ResponseCacheSetting 令牌级的响应缓存开关，需系统同时开启 ResponseCacheEnabled 才生效
+type ResponseCacheSetting struct {
+	Enabled bool `json:"enabled"`
+}
+
+// WebhookSetting 异步任务完成回调。URL 为默认回调地址，请求里单独指定的回调地址优先；
+// Secret 用于 HMAC 签名，未设置时在保存令牌时自动生成
+type WebhookSetting struct {
+	URL    string `json:"url,omitempty"`
+	Secret string `json:"secret,omitempty"`
+}
+
 type LimitsConfig struct {
 	LimitModelSetting LimitModelSetting `json:"limit_model_setting,omitempty"`
 	LimitsIPSetting   LimitsIPSetting   `json:"limits_ip_setting,omitempty"`
@@ -313,6 +331,31 @@ func GetTokenById(id int) (*Token, error) {
 	return &token, err
 }
 
+// GetTokenWebhookSetting 读取令牌的回调配置
+func GetTokenWebhookSetting(id int) (*WebhookSetting, error) {
+	token, err := GetTokenById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	setting := token.Setting.Data()
+	return &setting.Webhook, nil
+}
+
+func newWebhookSecret() string {
+	return "whsec_" + utils.GetRandomString(32)
+}
+
+// ensureWebhookSecret 保存令牌前补全回调签名密钥
+func (token *Token) ensureWebhookSecret() {
+	setting := token.Setting.Data()
+	if setting.Webhook.Secret != "" {
+		return
+	}
+	setting.Webhook.Secret = newWebhookSecret()
+	token.Setting.Set(setting)
+}
+
 func GetTokenByName(name string, userId int) (*Token, error) {
 	if name == "" {
 		return nil, errors.New("name 为空！")
@@ -336,12 +379,14 @@ func GetTokenByKey(key string) (*Token, error) {
 }
 
 func (token *Token) Insert() error {
+	token.ensureWebhookSecret()
 	err := DB.Create(token).Error
 	return err
 }
 
 // Update Make sure your token's fields is completed, because this will update non-zero values
 func (token *Token) Update() error {
+	token.ensureWebhookSecret()
 	err := DB.Model(token).Select("name", "status", "expired_time", "remain_quota", "unlimited_quota", "group", "backup_group", "setting").Updates(token).Error
 	// 防止Redis缓存不生效，直接删除
 	if err == nil && config.RedisEnabled {
@@ -353,6 +398,7 @@ func (token *Token) Update() error {
 
 // UpdateByAdmin 管理员更新token，支持更新user_id字段
 func (token *Token) UpdateByAdmin() error {
+	token.ensureWebhookSecret()
 	err := DB.Model(token).Select("user_id", "name", "status", "expired_time", "remain_quota", "unlimited_quota", "group", "backup_group", "setting").Updates(token).Error
 	if err == nil && config.RedisEnabled {
 		redis.RedisDel(fmt.Sprintf(UserTokensKey, token.Key))
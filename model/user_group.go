@@ -6,20 +6,28 @@ import (
 	"done-hub/common/logger"
 	"fmt"
 	"sync"
+
+	"gorm.io/datatypes"
 )
 
 type UserGroup struct {
-	Id          int     `json:"id"`
-	Symbol      string  `json:"symbol" gorm:"type:varchar(50);uniqueIndex"`
-	Name        string  `json:"name" gorm:"type:varchar(50)"`
-	Description string  `json:"description" gorm:"type:varchar(500)"`            // 分组描述，展示给用户看
-	Ratio       float64 `json:"ratio" gorm:"type:decimal(10,2); default:1"`      // 倍率
-	APIRate     int     `json:"api_rate" gorm:"default:600"`                     // 每分组允许的请求数
-	Public      bool    `json:"public" form:"public" gorm:"default:false"`       // 是否为公开分组，如果是，则可以被用户在令牌中选择
-	Promotion   bool    `json:"promotion" form:"promotion" gorm:"default:false"` // 是否是自动升级用户组， 如果是则用户充值金额满足条件自动升级
-	Min         int     `json:"min" form:"min" gorm:"default:0"`                 // 晋级条件最小值
-	Max         int     `json:"max" form:"max" gorm:"default:0"`                 // 晋级条件最大值
-	Enable      *bool   `json:"enable" form:"enable" gorm:"default:true"`        // 是否启用
+	Id              int     `json:"id"`
+	Symbol          string  `json:"symbol" gorm:"type:varchar(50);uniqueIndex"`
+	Name            string  `json:"name" gorm:"type:varchar(50)"`
+	Description     string  `json:"description" gorm:"type:varchar(500)"`                      // 分组描述，展示给用户看
+	Ratio           float64 `json:"ratio" gorm:"type:decimal(10,2); default:1"`                // 倍率
+	APIRate         int     `json:"api_rate" gorm:"default:600"`                               // 每分组允许的请求数
+	Public          bool    `json:"public" form:"public" gorm:"default:false"`                 // 是否为公开分组，如果是，则可以被用户在令牌中选择
+	Promotion       bool    `json:"promotion" form:"promotion" gorm:"default:false"`           // 是否是自动升级用户组， 如果是则用户充值金额满足条件自动升级
+	Min             int     `json:"min" form:"min" gorm:"default:0"`                           // 晋级条件最小值
+	Max             int     `json:"max" form:"max" gorm:"default:0"`                           // 晋级条件最大值
+	Enable          *bool   `json:"enable" form:"enable" gorm:"default:true"`                  // 是否启用
+	RoutingStrategy string  `json:"routing_strategy" gorm:"type:varchar(32);default:'weight'"` // 同优先级内的渠道选择策略：weight 按权重，latency 按延迟与错误率择优，cost 成本优先
+	HedgeDelay      int     `json:"hedge_delay" gorm:"default:0"`                              // 对冲请求阈值（毫秒），超过该时间仍未收到首字时向另一个渠道发出相同请求，0 为不启用
+	// RoutingRules 内容路由规则，按请求的输入 tokens、是否包含图片或工具限定可选的渠道
+	RoutingRules *datatypes.JSONType[[]RoutingRule] `json:"routing_rules,omitempty" gorm:"type:json"`
+	// DisableRequestRouting 忽略请求体中 OpenRouter 风格的 models 与 provider 路由参数
+	DisableRequestRouting bool `json:"disable_request_routing" gorm:"default:false"`
 }
 
 type SearchUserGroupParams struct {
@@ -60,27 +68,62 @@ func (c *UserGroup) Create() error {
 		enable := true
 		c.Enable = &enable
 	}
+	c.normalizeRoutingStrategy()
+	if err := c.normalizeRoutingRules(); err != nil {
+		return err
+	}
 	err := DB.Create(c).Error
 	if err == nil {
 		GlobalUserGroupRatio.Load()
+		PublishCacheReload()
 	}
 	return err
 }
 
 func (c *UserGroup) Update() error {
-	err := DB.Select("name", "description", "ratio", "public", "api_rate", "promotion", "min", "max").Updates(c).Error
+	c.normalizeRoutingStrategy()
+	if err := c.normalizeRoutingRules(); err != nil {
+		return err
+	}
+	err := DB.Select("name", "description", "ratio", "public", "api_rate", "promotion", "min", "max", "routing_strategy", "hedge_delay", "routing_rules", "disable_request_routing").Updates(c).Error
 	if err == nil {
 		GlobalUserGroupRatio.Load()
+		PublishCacheReload()
 	}
 
 	return err
 }
 
+func (c *UserGroup) normalizeRoutingStrategy() {
+	if c.RoutingStrategy != RoutingStrategyLatency && c.RoutingStrategy != RoutingStrategyCost {
+		c.RoutingStrategy = RoutingStrategyWeight
+	}
+}
+
+// normalizeRoutingRules 校验内容路由规则，清空规则时存为空数组，避免 Select 更新时跳过
+func (c *UserGroup) normalizeRoutingRules() error {
+	var rules []RoutingRule
+	if c.RoutingRules != nil {
+		rules = c.RoutingRules.Data()
+	}
+	if err := validateRoutingRules(rules); err != nil {
+		return err
+	}
+	if rules == nil {
+		rules = []RoutingRule{}
+	}
+	routingRules := datatypes.NewJSONType(rules)
+	c.RoutingRules = &routingRules
+
+	return nil
+}
+
 func (c *UserGroup) Delete() error {
 	err := DB.Delete(c).Error
 
 	if err == nil {
 		GlobalUserGroupRatio.Load()
+		PublishCacheReload()
 	}
 	return err
 }
@@ -89,6 +132,7 @@ func ChangeUserGroupEnable(id int, enable bool) error {
 	err := DB.Model(&UserGroup{}).Where("id = ?", id).Update("enable", enable).Error
 	if err == nil {
 		GlobalUserGroupRatio.Load()
+		PublishCacheReload()
 	}
 	return err
 }
@@ -184,6 +228,49 @@ func (cgrm *UserGroupRatio) GetAPIRate(symbol string) int {
 	return userGroup.APIRate
 }
 
+// GetRoutingStrategy 返回分组的渠道选择策略，未知分组按权重
+func (cgrm *UserGroupRatio) GetRoutingStrategy(symbol string) string {
+	userGroup := cgrm.GetBySymbol(symbol)
+	if userGroup == nil || userGroup.RoutingStrategy == "" {
+		return RoutingStrategyWeight
+	}
+
+	return userGroup.RoutingStrategy
+}
+
+// GetHedgeDelay 返回分组的对冲请求阈值（毫秒），未知分组不启用
+func (cgrm *UserGroupRatio) GetHedgeDelay(symbol string) int {
+	userGroup := cgrm.GetBySymbol(symbol)
+	if userGroup == nil || userGroup.HedgeDelay < 0 {
+		return 0
+	}
+
+	return userGroup.HedgeDelay
+}
+
+// AllowRequestRouting 分组是否接受请求体中的 models 与 provider 路由参数，未知分组不接受
+func (cgrm *UserGroupRatio) AllowRequestRouting(symbol string) bool {
+	userGroup := cgrm.GetBySymbol(symbol)
+	return userGroup != nil && !userGroup.DisableRequestRouting
+}
+
+// GetRoutingRuleFilters 返回分组中请求内容命中的路由规则生成的渠道过滤器
+func (cgrm *UserGroupRatio) GetRoutingRuleFilters(symbol string, content *RoutingContent) []ChannelsFilterFunc {
+	userGroup := cgrm.GetBySymbol(symbol)
+	if userGroup == nil || userGroup.RoutingRules == nil || content == nil {
+		return nil
+	}
+
+	var filters []ChannelsFilterFunc
+	for _, rule := range userGroup.RoutingRules.Data() {
+		if rule.Match(content) {
+			filters = append(filters, rule.Filter())
+		}
+	}
+
+	return filters
+}
+
 // GetDisplayName 返回分组展示名（启用/禁用都只返回 name），name 为空或分组被物理删除时 fallback 到 symbol。
 // 用于错误模板等对外/通用文案，保持纯文本输出，避免污染日志关键字告警的正则匹配。
 // 需要在日志里区分禁用状态时，用 GetDisplayNameWithStatus。
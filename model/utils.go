@@ -20,6 +20,9 @@ const (
 	BatchUpdateTypeUsedQuota
 	BatchUpdateTypeChannelUsedQuota
 	BatchUpdateTypeRequestCount
+	BatchUpdateTypeChannelKeyUsedQuota
+	BatchUpdateTypeChannelKeyRequestCount
+	BatchUpdateTypeChannelBudgetSpent
 	BatchUpdateTypeCount // if you add a new type, you need to add a new map and a new lock
 )
 
@@ -142,6 +145,12 @@ func batchUpdate() {
 			batchAddColumn("users", "request_count", store)
 		case BatchUpdateTypeChannelUsedQuota:
 			batchAddColumn("channels", "used_quota", store)
+		case BatchUpdateTypeChannelKeyUsedQuota:
+			batchAddColumn("channel_keys", "used_quota", store)
+		case BatchUpdateTypeChannelKeyRequestCount:
+			batchAddColumn("channel_keys", "request_count", store)
+		case BatchUpdateTypeChannelBudgetSpent:
+			batchUpdateChannelBudgetSpent(store)
 		}
 	}
 	logger.SysLog("batch update finished")
@@ -0,0 +1,219 @@
+package model
+
+import (
+	"done-hub/common/logger"
+	"done-hub/common/utils"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"gorm.io/datatypes"
+)
+
+// 虚拟模型步骤的回退条件（错误类别）
+const (
+	VirtualModelFallbackAny           = "any"
+	VirtualModelFallbackRateLimit     = "rate_limit"
+	VirtualModelFallbackServerError   = "server_error"
+	VirtualModelFallbackTimeout       = "timeout"
+	VirtualModelFallbackAuth          = "auth"
+	VirtualModelFallbackContextLength = "context_length"
+)
+
+var virtualModelFallbackConditions = map[string]bool{
+	VirtualModelFallbackAny:           true,
+	VirtualModelFallbackRateLimit:     true,
+	VirtualModelFallbackServerError:   true,
+	VirtualModelFallbackTimeout:       true,
+	VirtualModelFallbackAuth:          true,
+	VirtualModelFallbackContextLength: true,
+}
+
+// VirtualModelStep 虚拟模型回退链中的一步
+type VirtualModelStep struct {
+	Model      string   `json:"model"`
+	FallbackOn []string `json:"fallback_on,omitempty"` // 出现哪些类别的错误时回退到下一步，为空时任何错误都回退
+	Timeout    int      `json:"timeout,omitempty"`     // 本步骤的耗时上限（秒），超过后不再在本步骤内重试而是回退到下一步，0 为不限制
+}
+
+// VirtualModel 对外暴露的虚拟模型，请求按步骤顺序尝试实际模型，计费以实际完成请求的模型为准
+type VirtualModel struct {
+	Id          int                                    `json:"id"`
+	Name        string                                 `json:"name" gorm:"type:varchar(100);uniqueIndex"`
+	Description string                                 `json:"description" gorm:"type:varchar(500)"`
+	Steps       datatypes.JSONType[[]VirtualModelStep] `json:"steps" gorm:"type:json"`
+	Enable      *bool                                  `json:"enable" gorm:"default:true"`
+	CreatedTime int64                                  `json:"created_time" gorm:"bigint"`
+}
+
+func (v *VirtualModel) TableName() string {
+	return "virtual_models"
+}
+
+// Validate 校验名称与步骤配置，名称不能与渠道中已有的模型重名
+func (v *VirtualModel) Validate() error {
+	v.Name = strings.TrimSpace(v.Name)
+	if v.Name == "" {
+		return errors.New("虚拟模型名称不能为空")
+	}
+	if strings.ContainsAny(v.Name, "#*") {
+		return errors.New("虚拟模型名称不能包含 # 或 *")
+	}
+	if _, ok := ChannelGroup.GetModelsGroups()[v.Name]; ok {
+		return fmt.Errorf("模型 %s 已由渠道提供，不能作为虚拟模型名称", v.Name)
+	}
+
+	steps := v.Steps.Data()
+	if len(steps) == 0 {
+		return errors.New("至少需要配置一个步骤")
+	}
+	for i := range steps {
+		step := &steps[i]
+		step.Model = strings.TrimSpace(step.Model)
+		if step.Model == "" {
+			return fmt.Errorf("第 %d 步未填写模型", i+1)
+		}
+		if step.Model == v.Name {
+			return fmt.Errorf("第 %d 步不能引用虚拟模型自身", i+1)
+		}
+		if other := VirtualModels.Get(step.Model); other != nil {
+			return fmt.Errorf("第 %d 步不能引用其他虚拟模型 %s", i+1, step.Model)
+		}
+		if step.Timeout < 0 {
+			return fmt.Errorf("第 %d 步的超时时间不能为负数", i+1)
+		}
+		for _, condition := range step.FallbackOn {
+			if !virtualModelFallbackConditions[condition] {
+				return fmt.Errorf("第 %d 步的回退条件 %s 无效", i+1, condition)
+			}
+		}
+	}
+	v.Steps = datatypes.NewJSONType(steps)
+
+	return nil
+}
+
+// Matches 判断某一类别的错误是否触发回退，errorClass 为空表示本步骤没有可用渠道
+func (s *VirtualModelStep) Matches(errorClass string) bool {
+	if len(s.FallbackOn) == 0 || errorClass == "" {
+		return true
+	}
+	for _, condition := range s.FallbackOn {
+		if condition == VirtualModelFallbackAny || condition == errorClass {
+			return true
+		}
+	}
+	return false
+}
+
+func GetAllVirtualModels() ([]*VirtualModel, error) {
+	var virtualModels []*VirtualModel
+	err := DB.Order("id asc").Find(&virtualModels).Error
+	return virtualModels, err
+}
+
+func GetVirtualModelById(id int) (*VirtualModel, error) {
+	var virtualModel VirtualModel
+	err := DB.Where("id = ?", id).First(&virtualModel).Error
+	return &virtualModel, err
+}
+
+func (v *VirtualModel) Create() error {
+	if v.Enable == nil {
+		enable := true
+		v.Enable = &enable
+	}
+	v.CreatedTime = utils.GetTimestamp()
+	err := DB.Create(v).Error
+	if err != nil {
+		return err
+	}
+
+	VirtualModels.Load()
+	PublishCacheReload()
+
+	return nil
+}
+
+func (v *VirtualModel) Update() error {
+	err := DB.Select("name", "description", "steps", "enable").Updates(v).Error
+	if err != nil {
+		return err
+	}
+
+	VirtualModels.Load()
+	PublishCacheReload()
+
+	return nil
+}
+
+func (v *VirtualModel) Delete() error {
+	err := DB.Delete(v).Error
+	if err != nil {
+		return err
+	}
+
+	VirtualModels.Load()
+	PublishCacheReload()
+
+	return nil
+}
+
+type VirtualModelRegistry struct {
+	sync.RWMutex
+	models map[string]*VirtualModel
+}
+
+var VirtualModels = &VirtualModelRegistry{
+	models: make(map[string]*VirtualModel),
+}
+
+// Load 从数据库加载启用的虚拟模型
+func (r *VirtualModelRegistry) Load() {
+	var virtualModels []*VirtualModel
+	if err := DB.Where("enable = ?", true).Find(&virtualModels).Error; err != nil {
+		logger.SysError("failed to load virtual models: " + err.Error())
+		return
+	}
+
+	newModels := make(map[string]*VirtualModel, len(virtualModels))
+	for _, virtualModel := range virtualModels {
+		newModels[virtualModel.Name] = virtualModel
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.models = newModels
+}
+
+// Get 返回启用的虚拟模型，不存在时返回 nil
+func (r *VirtualModelRegistry) Get(name string) *VirtualModel {
+	r.RLock()
+	defer r.RUnlock()
+	return r.models[name]
+}
+
+// GroupModels 返回分组可用的虚拟模型名称，至少有一个步骤的模型在该分组中有渠道时才可用
+func (r *VirtualModelRegistry) GroupModels(group string) []string {
+	r.RLock()
+	virtualModels := make([]*VirtualModel, 0, len(r.models))
+	for _, virtualModel := range r.models {
+		virtualModels = append(virtualModels, virtualModel)
+	}
+	r.RUnlock()
+
+	var names []string
+	for _, virtualModel := range virtualModels {
+		for _, step := range virtualModel.Steps.Data() {
+			if _, err := ChannelGroup.GetMatchedModelName(group, step.Model); err == nil {
+				names = append(names, virtualModel.Name)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,134 @@
+package model
+
+import (
+	"done-hub/common/utils"
+	"errors"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	WebhookDeliveryStatusPending = "pending"
+	WebhookDeliveryStatusSuccess = "success"
+	WebhookDeliveryStatusFailed  = "failed"
+)
+
+// WebhookDelivery 异步任务完成回调的投递记录。表本身即持久化的发件箱：
+// pending 的记录到达 next_attempt_at 后由 worker 领取投递，失败按指数退避重新排期。
+// 同一任务只登记一次（platform + task_id 唯一），多节点同时轮询到终态也不会重复回调。
+type WebhookDelivery struct {
+	ID             int64          `json:"id" gorm:"primary_key;AUTO_INCREMENT"`
+	EventID        string         `json:"event_id" gorm:"type:varchar(40);uniqueIndex"`
+	EventType      string         `json:"event_type" gorm:"type:varchar(40)"`
+	UserId         int            `json:"user_id" gorm:"index"`
+	TokenID        int            `json:"token_id" gorm:"default:0"`
+	Platform       string         `json:"platform" gorm:"type:varchar(30);uniqueIndex:idx_webhook_task"`
+	TaskID         string         `json:"task_id" gorm:"type:varchar(64);uniqueIndex:idx_webhook_task"`
+	URL            string         `json:"url" gorm:"type:text"`
+	Payload        datatypes.JSON `json:"payload" gorm:"type:json"`
+	Status         string         `json:"status" gorm:"type:varchar(20);index:idx_webhook_due"`
+	NextAttemptAt  int64          `json:"next_attempt_at" gorm:"bigint;index:idx_webhook_due"`
+	Attempts       int            `json:"attempts" gorm:"default:0"`
+	LastStatusCode int            `json:"last_status_code" gorm:"default:0"`
+	LastError      string         `json:"last_error" gorm:"type:text"`
+	DeliveredAt    int64          `json:"delivered_at" gorm:"bigint"`
+	CreatedAt      int64          `json:"created_at" gorm:"bigint;index"`
+	UpdatedAt      int64          `json:"updated_at" gorm:"bigint"`
+}
+
+type WebhookDeliveryQueryParams struct {
+	PaginationParams
+	UserID   int    `form:"user_id"`
+	Platform string `form:"platform"`
+	TaskID   string `form:"task_id"`
+	Status   string `form:"status"`
+}
+
+var allowedWebhookDeliveryOrderFields = map[string]bool{
+	"id":              true,
+	"created_at":      true,
+	"next_attempt_at": true,
+	"attempts":        true,
+}
+
+// InsertWebhookDelivery 登记一次投递，任务已登记过时返回 false
+func InsertWebhookDelivery(delivery *WebhookDelivery) (bool, error) {
+	now := utils.GetTimestamp()
+	delivery.CreatedAt = now
+	delivery.UpdatedAt = now
+	if delivery.NextAttemptAt == 0 {
+		delivery.NextAttemptAt = now
+	}
+
+	result := DB.Clauses(clause.OnConflict{DoNothing: true}).Create(delivery)
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetDueWebhookDeliveries 取出已到投递时间的记录
+func GetDueWebhookDeliveries(limit int) (deliveries []*WebhookDelivery, err error) {
+	err = DB.Where("status = ? and next_attempt_at <= ?", WebhookDeliveryStatusPending, utils.GetTimestamp()).
+		Order("next_attempt_at").Limit(limit).Find(&deliveries).Error
+	return
+}
+
+// ClaimWebhookDelivery 把 next_attempt_at 推迟到 leaseUntil 作为租约，只有一个 worker 能领取成功；
+// 进程在投递中途退出时，租约到期后记录会被重新领取
+func ClaimWebhookDelivery(delivery *WebhookDelivery, leaseUntil int64) (bool, error) {
+	result := DB.Model(&WebhookDelivery{}).
+		Where("id = ? and status = ? and next_attempt_at = ?", delivery.ID, WebhookDeliveryStatusPending, delivery.NextAttemptAt).
+		Updates(map[string]any{"next_attempt_at": leaseUntil, "updated_at": utils.GetTimestamp()})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (delivery *WebhookDelivery) UpdateResult(params map[string]any) error {
+	params["updated_at"] = utils.GetTimestamp()
+	return DB.Model(delivery).Updates(params).Error
+}
+
+func GetWebhookDeliveryById(id int64) (*WebhookDelivery, error) {
+	delivery := &WebhookDelivery{}
+	err := DB.First(delivery, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return delivery, err
+}
+
+// RedeliverWebhook 管理员手动重投：重新排入发件箱并清零重试次数
+func RedeliverWebhook(id int64) error {
+	result := DB.Model(&WebhookDelivery{}).Where("id = ?", id).Updates(map[string]any{
+		"status":          WebhookDeliveryStatusPending,
+		"next_attempt_at": utils.GetTimestamp(),
+		"attempts":        0,
+		"updated_at":      utils.GetTimestamp(),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("投递记录不存在")
+	}
+	return nil
+}
+
+func GetWebhookDeliveries(params *WebhookDeliveryQueryParams) (*DataResult[WebhookDelivery], error) {
+	tx := DB.Omit("payload")
+	var deliveries []*WebhookDelivery
+
+	if params.UserID != 0 {
+		tx = tx.Where("user_id = ?", params.UserID)
+	}
+	if params.Platform != "" {
+		tx = tx.Where("platform = ?", params.Platform)
+	}
+	if params.TaskID != "" {
+		tx = tx.Where("task_id = ?", params.TaskID)
+	}
+	if params.Status != "" {
+		tx = tx.Where("status = ?", params.Status)
+	}
+
+	return PaginateAndOrder(tx, &params.PaginationParams, &deliveries, allowedWebhookDeliveryOrderFields)
+}
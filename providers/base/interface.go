@@ -192,3 +192,11 @@ type ResponsesCompactInterface interface {
 	ProviderInterface
 	CreateResponsesCompaction(request *types.OpenAIResponsesRequest) (*types.OpenAIResponsesResponses, *types.OpenAIErrorWithStatusCode)
 }
+
+// VideoInterface /v1/videos 的渠道能力：提交异步生成任务、按上游任务 id 查询状态与下载成片
+type VideoInterface interface {
+	ProviderInterface
+	CreateVideo(request *types.VideoRequest) (*types.VideoTaskResult, *types.OpenAIErrorWithStatusCode)
+	GetVideo(taskID, action string) (*types.VideoTaskResult, *types.OpenAIErrorWithStatusCode)
+	GetVideoContent(result *types.VideoTaskResult) (*http.Response, *types.OpenAIErrorWithStatusCode)
+}
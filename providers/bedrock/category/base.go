@@ -111,6 +111,23 @@ func GetCategory(modelName, region string) (*Category, error) {
 	return nil, errors.New("category_not_found")
 }
 
+// GetFoundationModelName 返回不带跨区前缀的基础模型 id。
+// CountTokens 等只接受 foundation model id、不接受 inference profile 的接口使用。
+func GetFoundationModelName(modelName string) string {
+	for _, prefix := range regionPrefixes {
+		if strings.HasPrefix(modelName, prefix) {
+			modelName = modelName[len(prefix):]
+			break
+		}
+	}
+
+	if mappedName, exists := bedrockMap[modelName]; exists {
+		modelName = mappedName
+	}
+
+	return modelName
+}
+
 func GetModelName(modelName, region string) string {
 	// 提取用户显式书写的区域前缀
 	regionPrefix := ""
@@ -0,0 +1,78 @@
+package bedrock
+
+import (
+	"done-hub/common"
+	"done-hub/providers/bedrock/category"
+	"done-hub/providers/claude"
+	"done-hub/types"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	bedrockTypes "github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// countTokensDefaultMaxTokens InvokeModel 请求体要求 max_tokens，count_tokens 请求本身不带；
+// 该值不影响计数结果，只用于通过 Bedrock 的请求体校验。
+const countTokensDefaultMaxTokens = 4096
+
+func (p *BedrockProvider) CountClaudeTokens(request *claude.ClaudeRequest) (*claude.CountTokensResponse, *types.OpenAIErrorWithStatusCode) {
+	body, errWithCode := p.buildCountTokensBody(request)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	client, err := p.getAwsClient()
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "aws_client_error", http.StatusInternalServerError)
+	}
+
+	// CountTokens 只接受 foundation model id，跨区 inference profile（us./global. 等）会被拒绝。
+	out, err := client.CountTokens(p.invokeContext(), &bedrockruntime.CountTokensInput{
+		ModelId: aws.String(category.GetFoundationModelName(request.Model)),
+		Input: &bedrockTypes.CountTokensInputMemberInvokeModel{
+			Value: bedrockTypes.InvokeModelTokensRequest{Body: body},
+		},
+	})
+	if err != nil {
+		return nil, p.awsErrorToOpenAI(err)
+	}
+
+	response := &claude.CountTokensResponse{}
+	if out.InputTokens != nil {
+		response.InputTokens = int(*out.InputTokens)
+	}
+
+	return response, nil
+}
+
+// buildCountTokensBody 把 count_tokens 请求改写成 InvokeModel 请求体：
+// 去掉走 URL 的 model，注入 anthropic_version，并补上校验必需的 max_tokens。
+func (p *BedrockProvider) buildCountTokensBody(request *claude.ClaudeRequest) ([]byte, *types.OpenAIErrorWithStatusCode) {
+	rawBody, _ := p.ReadNativeRawBody("messages")
+	body, err := claude.BuildCountTokensBody(rawBody, request)
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "marshal_count_tokens_failed", http.StatusInternalServerError)
+	}
+
+	if body, err = sjson.DeleteBytes(body, "model"); err != nil {
+		return nil, common.ErrorWrapper(err, "marshal_count_tokens_failed", http.StatusInternalServerError)
+	}
+
+	maxTokens := countTokensDefaultMaxTokens
+	// thinking 开启时 Claude 要求 max_tokens > budget_tokens
+	if budget := int(gjson.GetBytes(body, "thinking.budget_tokens").Int()); budget >= maxTokens {
+		maxTokens = budget + 1
+	}
+	if body, err = sjson.SetBytes(body, "max_tokens", maxTokens); err != nil {
+		return nil, common.ErrorWrapper(err, "marshal_count_tokens_failed", http.StatusInternalServerError)
+	}
+
+	if body, err = sjson.SetBytes(body, "anthropic_version", category.AnthropicVersion); err != nil {
+		return nil, common.ErrorWrapper(err, "marshal_count_tokens_failed", http.StatusInternalServerError)
+	}
+
+	return body, nil
+}
@@ -0,0 +1,79 @@
+package claude
+
+import (
+	"done-hub/common"
+	"done-hub/common/config"
+	"done-hub/types"
+	"net/http"
+	"net/url"
+)
+
+func (p *ClaudeProvider) CreateMessageBatch(body []byte) (*MessageBatch, *types.OpenAIErrorWithStatusCode) {
+	return p.sendMessageBatchRequest(http.MethodPost, "", body)
+}
+
+func (p *ClaudeProvider) RetrieveMessageBatch(id string) (*MessageBatch, *types.OpenAIErrorWithStatusCode) {
+	return p.sendMessageBatchRequest(http.MethodGet, "/"+url.PathEscape(id), nil)
+}
+
+func (p *ClaudeProvider) CancelMessageBatch(id string) (*MessageBatch, *types.OpenAIErrorWithStatusCode) {
+	return p.sendMessageBatchRequest(http.MethodPost, "/"+url.PathEscape(id)+"/cancel", nil)
+}
+
+func (p *ClaudeProvider) GetMessageBatchResults(id string) (*http.Response, *types.OpenAIErrorWithStatusCode) {
+	fullRequestURL, errWithCode := p.getMessageBatchURL("/" + url.PathEscape(id) + "/results")
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	req, err := p.Requester.NewRequest(http.MethodGet, fullRequestURL, p.Requester.WithHeader(p.GetRequestHeaders()))
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "new_request_failed", http.StatusInternalServerError)
+	}
+
+	return p.Requester.SendRequestRaw(req)
+}
+
+func (p *ClaudeProvider) getMessageBatchURL(path string) (string, *types.OpenAIErrorWithStatusCode) {
+	uri, errWithCode := p.GetSupportedAPIUri(config.RelayModeChatCompletions)
+	if errWithCode != nil {
+		return "", errWithCode
+	}
+
+	fullRequestURL := p.GetFullRequestURL(uri + "/batches" + path)
+	if fullRequestURL == "" {
+		return "", common.ErrorWrapperLocal(nil, "invalid_claude_config", http.StatusInternalServerError)
+	}
+	return fullRequestURL, nil
+}
+
+func (p *ClaudeProvider) sendMessageBatchRequest(method, path string, body []byte) (*MessageBatch, *types.OpenAIErrorWithStatusCode) {
+	fullRequestURL, errWithCode := p.getMessageBatchURL(path)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	headers := p.GetRequestHeaders()
+	var (
+		req *http.Request
+		err error
+	)
+	if body != nil {
+		req, err = p.Requester.NewRequest(method, fullRequestURL, p.Requester.WithBody(body), p.Requester.WithHeader(headers))
+	} else {
+		req, err = p.Requester.NewRequest(method, fullRequestURL, p.Requester.WithHeader(headers))
+	}
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "new_request_failed", http.StatusInternalServerError)
+	}
+	if req.Body != nil {
+		defer req.Body.Close()
+	}
+
+	batch := &MessageBatch{}
+	if _, errWithCode = p.Requester.SendRequest(req, batch, false); errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	return batch, nil
+}
@@ -0,0 +1,83 @@
+package claude
+
+import (
+	"done-hub/common"
+	"done-hub/common/config"
+	"done-hub/types"
+	"encoding/json"
+	"net/http"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// countTokensFields 是 count_tokens 端点接受的顶层字段。
+// /v1/messages 独有的 max_tokens / stream / temperature 等若原样带上，会被上游以
+// "Extra inputs are not permitted" 拒绝，所以只挑这几项。
+var countTokensFields = []string{"messages", "system", "tools", "tool_choice", "thinking", "mcp_servers"}
+
+// BuildCountTokensBody 从客户端原始请求字节中挑出 count_tokens 接受的字段，并写入映射后的 model。
+// rawBody 为空时回退到结构体序列化结果，保证非 HTTP 入口（如测试）也能构造出请求体。
+// Bedrock / Vertex 在此基础上再按各自协议增删字段。
+func BuildCountTokensBody(rawBody []byte, request *ClaudeRequest) ([]byte, error) {
+	if len(rawBody) == 0 || !gjson.GetBytes(rawBody, "messages").Exists() {
+		marshaled, err := json.Marshal(request)
+		if err != nil {
+			return nil, err
+		}
+		rawBody = marshaled
+	}
+
+	out := []byte(`{}`)
+	var err error
+	if request.Model != "" {
+		if out, err = sjson.SetBytes(out, "model", request.Model); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, field := range countTokensFields {
+		value := gjson.GetBytes(rawBody, field)
+		if !value.Exists() || value.Type == gjson.Null {
+			continue
+		}
+		if out, err = sjson.SetRawBytes(out, field, []byte(value.Raw)); err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+func (p *ClaudeProvider) CountClaudeTokens(request *ClaudeRequest) (*CountTokensResponse, *types.OpenAIErrorWithStatusCode) {
+	url, errWithCode := p.GetSupportedAPIUri(config.RelayModeChatCompletions)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	fullRequestURL := p.GetFullRequestURL(url + "/count_tokens")
+	if fullRequestURL == "" {
+		return nil, common.ErrorWrapperLocal(nil, "invalid_claude_config", http.StatusInternalServerError)
+	}
+
+	rawBody, _ := p.ReadNativeRawBody("messages")
+	body, err := BuildCountTokensBody(rawBody, request)
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "marshal_count_tokens_failed", http.StatusInternalServerError)
+	}
+
+	// 不走 NewRequestWithCustomParamsBytes：渠道自定义参数是为 /v1/messages 准备的，
+	// 合并进来（如 max_tokens / temperature）会让 count_tokens 直接 400。
+	req, err := p.Requester.NewRequest(http.MethodPost, fullRequestURL, p.Requester.WithBody(body), p.Requester.WithHeader(p.GetRequestHeaders()))
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "new_request_failed", http.StatusInternalServerError)
+	}
+	defer req.Body.Close()
+
+	response := &CountTokensResponse{}
+	if _, errWithCode = p.Requester.SendRequest(req, response, false); errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	return response, nil
+}
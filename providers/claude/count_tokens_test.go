@@ -0,0 +1,71 @@
+package claude
+
+import (
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+// TestBuildCountTokensBody 测试 count_tokens 请求体只保留上游接受的字段
+func TestBuildCountTokensBody(t *testing.T) {
+	tests := []struct {
+		name      string
+		rawBody   string
+		request   *ClaudeRequest
+		expected  map[string]string
+		forbidden []string
+	}{
+		{
+			name:    "剔除 messages 独有字段并改写 model",
+			rawBody: `{"model":"alias","max_tokens":1024,"stream":true,"temperature":0.5,"system":"be brief","messages":[{"role":"user","content":"hi"}],"tools":[{"name":"t"}]}`,
+			request: &ClaudeRequest{Model: "claude-sonnet-4-6"},
+			expected: map[string]string{
+				"model":           "claude-sonnet-4-6",
+				"system":          "be brief",
+				"messages.0.role": "user",
+				"tools.0.name":    "t",
+			},
+			forbidden: []string{"max_tokens", "stream", "temperature"},
+		},
+		{
+			name:    "原始字节缺失时回退结构体序列化",
+			rawBody: ``,
+			request: &ClaudeRequest{
+				Model:     "claude-opus-4-6",
+				MaxTokens: 10,
+				Messages:  []Message{{Role: "user", Content: "hello"}},
+			},
+			expected: map[string]string{
+				"model":              "claude-opus-4-6",
+				"messages.0.content": "hello",
+			},
+			forbidden: []string{"max_tokens"},
+		},
+		{
+			name:      "null 字段不透传",
+			rawBody:   `{"model":"m","messages":[],"tools":null}`,
+			request:   &ClaudeRequest{Model: "m"},
+			expected:  map[string]string{"model": "m"},
+			forbidden: []string{"tools"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := BuildCountTokensBody([]byte(tt.rawBody), tt.request)
+			if err != nil {
+				t.Fatalf("构造请求体失败: %v", err)
+			}
+			for path, want := range tt.expected {
+				if got := gjson.GetBytes(body, path).String(); got != want {
+					t.Errorf("字段 %s 期望 %q，实际 %q（body=%s）", path, want, got, body)
+				}
+			}
+			for _, field := range tt.forbidden {
+				if gjson.GetBytes(body, field).Exists() {
+					t.Errorf("字段 %s 不应出现在请求体中（body=%s）", field, body)
+				}
+			}
+		})
+	}
+}
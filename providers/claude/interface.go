@@ -4,6 +4,7 @@ import (
 	"done-hub/common/requester"
 	"done-hub/providers/base"
 	"done-hub/types"
+	"net/http"
 )
 
 type ClaudeChatInterface interface {
@@ -11,3 +12,21 @@ type ClaudeChatInterface interface {
 	CreateClaudeChat(request *ClaudeRequest) (*ClaudeResponse, *types.OpenAIErrorWithStatusCode)
 	CreateClaudeChatStream(request *ClaudeRequest) (requester.StreamReaderInterface[string], *types.OpenAIErrorWithStatusCode)
 }
+
+// ClaudeCountTokensInterface 由能直接调用上游原生 count_tokens 端点的渠道实现
+// （Anthropic / Bedrock / Vertex Claude），其余渠道由 relay 层本地估算。
+type ClaudeCountTokensInterface interface {
+	base.ProviderInterface
+	CountClaudeTokens(request *ClaudeRequest) (*CountTokensResponse, *types.OpenAIErrorWithStatusCode)
+}
+
+// ClaudeBatchInterface 原生 Message Batches 透传，仅 Anthropic 官方渠道使用；
+// 其余渠道的批处理由网关逐条代执行。
+type ClaudeBatchInterface interface {
+	base.ProviderInterface
+	CreateMessageBatch(body []byte) (*MessageBatch, *types.OpenAIErrorWithStatusCode)
+	RetrieveMessageBatch(id string) (*MessageBatch, *types.OpenAIErrorWithStatusCode)
+	CancelMessageBatch(id string) (*MessageBatch, *types.OpenAIErrorWithStatusCode)
+	// GetMessageBatchResults 返回结果 JSONL 的原始响应，调用方负责关闭 Body
+	GetMessageBatchResults(id string) (*http.Response, *types.OpenAIErrorWithStatusCode)
+}
@@ -312,6 +312,49 @@ type ContentBlock struct {
 	Text  string `json:"text,omitempty"`
 }
 
+// CountTokensResponse 对应 /v1/messages/count_tokens 的响应体。
+type CountTokensResponse struct {
+	InputTokens int `json:"input_tokens"`
+}
+
+// MessageBatch Anthropic Message Batches 的批次对象，时间字段为 RFC 3339 字符串
+type MessageBatch struct {
+	ID                string                    `json:"id"`
+	Type              string                    `json:"type"`
+	ProcessingStatus  string                    `json:"processing_status"`
+	RequestCounts     MessageBatchRequestCounts `json:"request_counts"`
+	EndedAt           *string                   `json:"ended_at"`
+	CreatedAt         string                    `json:"created_at"`
+	ExpiresAt         string                    `json:"expires_at"`
+	ArchivedAt        *string                   `json:"archived_at"`
+	CancelInitiatedAt *string                   `json:"cancel_initiated_at"`
+	ResultsURL        *string                   `json:"results_url"`
+}
+
+type MessageBatchRequestCounts struct {
+	Processing int `json:"processing"`
+	Succeeded  int `json:"succeeded"`
+	Errored    int `json:"errored"`
+	Canceled   int `json:"canceled"`
+	Expired    int `json:"expired"`
+}
+
+type MessageBatchRequest struct {
+	Requests []MessageBatchRequestItem `json:"requests"`
+}
+
+type MessageBatchRequestItem struct {
+	CustomID string          `json:"custom_id"`
+	Params   json.RawMessage `json:"params"`
+}
+
+type MessageBatchListResponse struct {
+	Data    []*MessageBatch `json:"data"`
+	HasMore bool            `json:"has_more"`
+	FirstID *string         `json:"first_id"`
+	LastID  *string         `json:"last_id"`
+}
+
 type ModelListResponse struct {
 	Data []Model `json:"data"`
 }
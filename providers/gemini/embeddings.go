@@ -0,0 +1,75 @@
+package gemini
+
+import (
+	"done-hub/common"
+	"done-hub/types"
+	"net/http"
+	"strings"
+)
+
+type GeminiEmbedContentRequest struct {
+	Model                string            `json:"model,omitempty"`
+	Content              GeminiChatContent `json:"content"`
+	TaskType             string            `json:"taskType,omitempty"`
+	Title                string            `json:"title,omitempty"`
+	OutputDimensionality int               `json:"outputDimensionality,omitempty"`
+}
+
+type GeminiBatchEmbedRequest struct {
+	Requests []GeminiEmbedContentRequest `json:"requests"`
+}
+
+type GeminiEmbedding struct {
+	Values any `json:"values"`
+}
+
+type GeminiEmbedContentResponse struct {
+	Embedding *GeminiEmbedding `json:"embedding"`
+}
+
+type GeminiBatchEmbedResponse struct {
+	Embeddings []GeminiEmbedding `json:"embeddings"`
+}
+
+// GetText 返回 content 中全部文本，Gemini 只对文本生成向量
+func (r *GeminiEmbedContentRequest) GetText() string {
+	texts := make([]string, 0, len(r.Content.Parts))
+	for _, part := range r.Content.Parts {
+		if part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// CreateGeminiEmbeddings 调用原生 batchEmbedContents，单条 embedContent 也按批量发送。
+// 上游不返回 token 用量，沿用 relay 预先计算的输入 token。
+func (p *GeminiProvider) CreateGeminiEmbeddings(request *GeminiBatchEmbedRequest, modelName string) (*GeminiBatchEmbedResponse, *types.OpenAIErrorWithStatusCode) {
+	for i := range request.Requests {
+		request.Requests[i].Model = "models/" + modelName
+	}
+
+	fullRequestURL := p.GetFullRequestURL("batchEmbedContents", modelName)
+	headers := p.GetRequestHeaders()
+
+	req, err := p.Requester.NewRequest(http.MethodPost, fullRequestURL, p.Requester.WithBody(request), p.Requester.WithHeader(headers))
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "new_request_failed", http.StatusInternalServerError)
+	}
+	defer req.Body.Close()
+
+	response := &GeminiBatchEmbedResponse{}
+	_, errWithCode := p.Requester.SendRequest(req, response, false)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	if len(response.Embeddings) != len(request.Requests) {
+		return nil, common.StringErrorWrapper("embeddings count mismatch", "no_embeddings", http.StatusInternalServerError)
+	}
+
+	usage := p.GetUsage()
+	usage.TotalTokens = usage.PromptTokens
+
+	return response, nil
+}
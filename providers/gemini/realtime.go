@@ -0,0 +1,296 @@
+package gemini
+
+import (
+	"done-hub/common"
+	"done-hub/common/config"
+	"done-hub/common/requester"
+	"done-hub/types"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/tidwall/gjson"
+)
+
+// Gemini Live（BidiGenerateContent）websocket 消息，只保留 OpenAI realtime 能对应上的字段
+type GeminiLiveClientMessage struct {
+	Setup         *GeminiLiveSetup         `json:"setup,omitempty"`
+	ClientContent *GeminiLiveClientContent `json:"clientContent,omitempty"`
+	RealtimeInput *GeminiLiveRealtimeInput `json:"realtimeInput,omitempty"`
+	ToolResponse  *GeminiLiveToolResponse  `json:"toolResponse,omitempty"`
+}
+
+type GeminiLiveSetup struct {
+	Model                    string                         `json:"model"`
+	GenerationConfig         *GeminiLiveGenerationConfig    `json:"generationConfig,omitempty"`
+	SystemInstruction        *GeminiChatContent             `json:"systemInstruction,omitempty"`
+	Tools                    []GeminiChatTools              `json:"tools,omitempty"`
+	RealtimeInputConfig      *GeminiLiveRealtimeInputConfig `json:"realtimeInputConfig,omitempty"`
+	InputAudioTranscription  *struct{}                      `json:"inputAudioTranscription,omitempty"`
+	OutputAudioTranscription *struct{}                      `json:"outputAudioTranscription,omitempty"`
+}
+
+type GeminiLiveGenerationConfig struct {
+	ResponseModalities []string                `json:"responseModalities,omitempty"`
+	Temperature        *float64                `json:"temperature,omitempty"`
+	MaxOutputTokens    int                     `json:"maxOutputTokens,omitempty"`
+	SpeechConfig       *GeminiLiveSpeechConfig `json:"speechConfig,omitempty"`
+}
+
+type GeminiLiveSpeechConfig struct {
+	VoiceConfig GeminiLiveVoiceConfig `json:"voiceConfig"`
+}
+
+type GeminiLiveVoiceConfig struct {
+	PrebuiltVoiceConfig GeminiLivePrebuiltVoiceConfig `json:"prebuiltVoiceConfig"`
+}
+
+type GeminiLivePrebuiltVoiceConfig struct {
+	VoiceName string `json:"voiceName"`
+}
+
+type GeminiLiveRealtimeInputConfig struct {
+	AutomaticActivityDetection *GeminiLiveActivityDetection `json:"automaticActivityDetection,omitempty"`
+}
+
+type GeminiLiveActivityDetection struct {
+	Disabled bool `json:"disabled"`
+}
+
+type GeminiLiveClientContent struct {
+	Turns        []GeminiChatContent `json:"turns,omitempty"`
+	TurnComplete bool                `json:"turnComplete"`
+}
+
+type GeminiLiveRealtimeInput struct {
+	Audio          *GeminiInlineData `json:"audio,omitempty"`
+	AudioStreamEnd bool              `json:"audioStreamEnd,omitempty"`
+	ActivityStart  *struct{}         `json:"activityStart,omitempty"`
+	ActivityEnd    *struct{}         `json:"activityEnd,omitempty"`
+}
+
+type GeminiLiveToolResponse struct {
+	FunctionResponses []GeminiLiveFunctionResponse `json:"functionResponses"`
+}
+
+type GeminiLiveFunctionResponse struct {
+	ID       string `json:"id,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Response any    `json:"response"`
+}
+
+type GeminiLiveServerMessage struct {
+	SetupComplete        *struct{}                       `json:"setupComplete,omitempty"`
+	ServerContent        *GeminiLiveServerContent        `json:"serverContent,omitempty"`
+	ToolCall             *GeminiLiveToolCall             `json:"toolCall,omitempty"`
+	ToolCallCancellation *GeminiLiveToolCallCancellation `json:"toolCallCancellation,omitempty"`
+	UsageMetadata        *GeminiLiveUsageMetadata        `json:"usageMetadata,omitempty"`
+	GoAway               *GeminiLiveGoAway               `json:"goAway,omitempty"`
+}
+
+type GeminiLiveServerContent struct {
+	ModelTurn           *GeminiChatContent       `json:"modelTurn,omitempty"`
+	TurnComplete        bool                     `json:"turnComplete,omitempty"`
+	Interrupted         bool                     `json:"interrupted,omitempty"`
+	InputTranscription  *GeminiLiveTranscription `json:"inputTranscription,omitempty"`
+	OutputTranscription *GeminiLiveTranscription `json:"outputTranscription,omitempty"`
+}
+
+type GeminiLiveTranscription struct {
+	Text string `json:"text"`
+}
+
+type GeminiLiveToolCall struct {
+	FunctionCalls []GeminiFunctionCall `json:"functionCalls"`
+}
+
+type GeminiLiveToolCallCancellation struct {
+	IDs []string `json:"ids"`
+}
+
+type GeminiLiveGoAway struct {
+	TimeLeft string `json:"timeLeft"`
+}
+
+// GeminiLiveUsageMetadata Live 的用量字段名与 generateContent 不同（response 而非 candidates）
+type GeminiLiveUsageMetadata struct {
+	PromptTokenCount        int                          `json:"promptTokenCount"`
+	CachedContentTokenCount int                          `json:"cachedContentTokenCount"`
+	ResponseTokenCount      int                          `json:"responseTokenCount"`
+	ToolUsePromptTokenCount int                          `json:"toolUsePromptTokenCount"`
+	ThoughtsTokenCount      int                          `json:"thoughtsTokenCount"`
+	TotalTokenCount         int                          `json:"totalTokenCount"`
+	PromptTokensDetails     []GeminiUsageMetadataDetails `json:"promptTokensDetails,omitempty"`
+	ResponseTokensDetails   []GeminiUsageMetadataDetails `json:"responseTokensDetails,omitempty"`
+}
+
+// ToUsageEvent 转换为 OpenAI realtime response.done 中的 usage，按模态拆出音频与文本 token 供计费
+func (u *GeminiLiveUsageMetadata) ToUsageEvent() *types.UsageEvent {
+	usage := &types.UsageEvent{
+		InputTokens:  u.PromptTokenCount + u.ToolUsePromptTokenCount,
+		OutputTokens: u.ResponseTokenCount + u.ThoughtsTokenCount,
+	}
+	usage.TotalTokens = usage.InputTokens + usage.OutputTokens
+	if u.TotalTokenCount > usage.TotalTokens {
+		usage.TotalTokens = u.TotalTokenCount
+	}
+
+	usage.InputTokenDetails.CachedTokens = u.CachedContentTokenCount
+	for _, detail := range u.PromptTokensDetails {
+		switch detail.Modality {
+		case ModalityAUDIO:
+			usage.InputTokenDetails.AudioTokens += detail.TokenCount
+		case ModalityTEXT:
+			usage.InputTokenDetails.TextTokens += detail.TokenCount
+		case ModalityIMAGE, ModalityVIDEO:
+			usage.InputTokenDetails.ImageTokens += detail.TokenCount
+		}
+	}
+	for _, detail := range u.ResponseTokensDetails {
+		switch detail.Modality {
+		case ModalityAUDIO:
+			usage.OutputTokenDetails.AudioTokens += detail.TokenCount
+		case ModalityTEXT:
+			usage.OutputTokenDetails.TextTokens += detail.TokenCount
+		}
+	}
+	usage.OutputTokenDetails.ReasoningTokens = u.ThoughtsTokenCount
+
+	return usage
+}
+
+// CreateChatRealtime 把 OpenAI realtime 协议桥接到 Gemini Live。
+// 返回的连接是进程内管道的一端，另一端由 geminiRealtimeBridge 做双向协议转换，
+// 转换后的 response.done 带 OpenAI 格式的 usage，沿用 OpenAIProvider.HandleMessage 计费。
+func (p *GeminiProvider) CreateChatRealtime(modelName string) (*websocket.Conn, requester.MessageHandler, *types.OpenAIErrorWithStatusCode) {
+	// geminicli、antigravity、vertexai_express 内嵌 GeminiProvider，但没有 Live 端点
+	if p.Channel.Type != config.ChannelTypeGemini {
+		return nil, nil, common.StringErrorWrapperLocal("channel not implemented", "channel_error", http.StatusServiceUnavailable)
+	}
+
+	httpHeaders := make(http.Header)
+	for key, value := range p.GetRequestHeaders() {
+		httpHeaders.Set(key, value)
+	}
+
+	wsRequester := requester.NewWSRequester(p.Channel.GetProxy())
+	upstreamConn, err := wsRequester.NewRequest(p.getLiveURL(), httpHeaders)
+	if err != nil {
+		return nil, nil, common.ErrorWrapper(err, "ws_request_failed", http.StatusInternalServerError)
+	}
+
+	clientConn, bridgeConn, err := requester.NewWSPipe()
+	if err != nil {
+		upstreamConn.Close()
+		return nil, nil, common.ErrorWrapper(err, "ws_pipe_failed", http.StatusInternalServerError)
+	}
+
+	newGeminiRealtimeBridge(modelName, bridgeConn, upstreamConn).start()
+
+	return clientConn, p.HandleMessage, nil
+}
+
+// getLiveURL BidiGenerateContent 的 websocket 地址，http(s) 基础地址换成 ws(s)
+func (p *GeminiProvider) getLiveURL() string {
+	baseURL := strings.TrimSuffix(p.GetBaseURL(), "/")
+	if strings.HasPrefix(baseURL, "https://") {
+		baseURL = "wss://" + strings.TrimPrefix(baseURL, "https://")
+	} else if strings.HasPrefix(baseURL, "http://") {
+		baseURL = "ws://" + strings.TrimPrefix(baseURL, "http://")
+	}
+
+	version := "v1beta"
+	if p.Channel.Other != "" {
+		version = p.Channel.Other
+	}
+
+	return fmt.Sprintf("%s/ws/google.ai.generativelanguage.%s.GenerativeService.BidiGenerateContent", baseURL, version)
+}
+
+// openaiRealtimeVoices OpenAI 的音色名，Gemini 不认识，遇到时沿用 Gemini 默认音色
+var openaiRealtimeVoices = map[string]bool{
+	"alloy": true, "ash": true, "ballad": true, "coral": true, "echo": true,
+	"sage": true, "shimmer": true, "verse": true, "marin": true, "cedar": true,
+}
+
+// realtimeSessionToLiveSetup 把 OpenAI realtime session 转换为 Gemini Live 的 setup。
+// Live 一个会话只能输出一种模态：modalities 含 audio 时输出音频并开启输出转写，否则输出文本。
+func realtimeSessionToLiveSetup(modelName string, session gjson.Result) *GeminiLiveSetup {
+	setup := &GeminiLiveSetup{
+		Model:            "models/" + modelName,
+		GenerationConfig: &GeminiLiveGenerationConfig{},
+	}
+
+	if realtimeSessionOutputsAudio(session) {
+		setup.GenerationConfig.ResponseModalities = []string{ModalityAUDIO}
+		setup.OutputAudioTranscription = &struct{}{}
+		if voice := session.Get("voice").String(); voice != "" && !openaiRealtimeVoices[voice] {
+			setup.GenerationConfig.SpeechConfig = &GeminiLiveSpeechConfig{
+				VoiceConfig: GeminiLiveVoiceConfig{PrebuiltVoiceConfig: GeminiLivePrebuiltVoiceConfig{VoiceName: voice}},
+			}
+		}
+	} else {
+		setup.GenerationConfig.ResponseModalities = []string{ModalityTEXT}
+	}
+
+	if temperature := session.Get("temperature"); temperature.Exists() {
+		value := temperature.Float()
+		setup.GenerationConfig.Temperature = &value
+	}
+	// max_response_output_tokens 为 "inf" 时不限制
+	if maxTokens := session.Get("max_response_output_tokens"); maxTokens.Type == gjson.Number {
+		setup.GenerationConfig.MaxOutputTokens = int(maxTokens.Int())
+	}
+
+	if instructions := session.Get("instructions").String(); instructions != "" {
+		setup.SystemInstruction = &GeminiChatContent{Parts: []GeminiPart{{Text: instructions}}}
+	}
+
+	var functionDeclarations []types.ChatCompletionFunction
+	for _, tool := range session.Get("tools").Array() {
+		if tool.Get("type").String() != "function" {
+			continue
+		}
+		function := types.ChatCompletionFunction{
+			Name:        tool.Get("name").String(),
+			Description: tool.Get("description").String(),
+		}
+		if params, ok := tool.Get("parameters").Value().(map[string]interface{}); ok {
+			if properties, ok := params["properties"].(map[string]interface{}); !ok || len(properties) > 0 {
+				cleanSchemaRecursively(params)
+				function.Parameters = params
+			}
+		}
+		functionDeclarations = append(functionDeclarations, function)
+	}
+	if len(functionDeclarations) > 0 {
+		setup.Tools = []GeminiChatTools{{FunctionDeclarations: functionDeclarations}}
+	}
+
+	// turn_detection 为 null 表示客户端自己 commit，对应关闭 Live 的自动活动检测
+	if turnDetection := session.Get("turn_detection"); turnDetection.Exists() && turnDetection.Type == gjson.Null {
+		setup.RealtimeInputConfig = &GeminiLiveRealtimeInputConfig{
+			AutomaticActivityDetection: &GeminiLiveActivityDetection{Disabled: true},
+		}
+	}
+
+	if transcription := session.Get("input_audio_transcription"); transcription.Exists() && transcription.Type != gjson.Null {
+		setup.InputAudioTranscription = &struct{}{}
+	}
+
+	return setup
+}
+
+func realtimeSessionOutputsAudio(session gjson.Result) bool {
+	modalities := session.Get("modalities")
+	if !modalities.Exists() {
+		return true
+	}
+	for _, modality := range modalities.Array() {
+		if modality.String() == "audio" {
+			return true
+		}
+	}
+	return false
+}
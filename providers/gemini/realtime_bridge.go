@@ -0,0 +1,633 @@
+package gemini
+
+import (
+	"done-hub/common/logger"
+	"done-hub/common/utils"
+	"done-hub/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// realtimeAudioMimeType OpenAI realtime 的 pcm16 固定为 24kHz 单声道，Live 按声明的采样率重采样
+const realtimeAudioMimeType = "audio/pcm;rate=24000"
+
+// liveSetupTimeout 等待上游 setupComplete 的最长时间
+const liveSetupTimeout = 15 * time.Second
+
+// geminiRealtimeBridge 在 OpenAI realtime 事件与 Gemini Live 消息之间双向转换。
+//
+// Live 要求 setup 是第一条消息且之后不能再改，而 OpenAI 客户端先收到 session.created 再用 session.update 配置会话，
+// 所以 session.created 由网关生成，setup 推迟到第一条非 session.update 事件时按累积的 session 发送。
+type geminiRealtimeBridge struct {
+	modelName string
+	client    *websocket.Conn // 管道的 server 端，收发 OpenAI realtime 事件
+	upstream  *websocket.Conn
+
+	clientMu  sync.Mutex // client 会被两个方向的 goroutine 同时写
+	closeOnce sync.Once
+	setupOnce sync.Once
+	closed    chan struct{}
+	setupDone chan struct{}
+
+	// 以下字段只在读 client 的 goroutine 中访问
+	session        string
+	setupSent      bool
+	manualActivity bool
+	activityActive bool
+	pendingTurns   []GeminiChatContent
+
+	// callNames 记录工具调用 id 到函数名，function_call_output 需要回填 name
+	callMu    sync.Mutex
+	callNames map[string]string
+
+	// 以下字段只在读 upstream 的 goroutine 中访问
+	response        *realtimeResponse
+	pendingUsage    *GeminiLiveUsageMetadata
+	inputItemID     string
+	inputTranscript strings.Builder
+}
+
+// realtimeResponse 一次模型回复，对应 OpenAI 的 response.created 到 response.done
+type realtimeResponse struct {
+	id         string
+	itemID     string
+	hasMessage bool
+	audio      bool
+	text       strings.Builder
+	transcript strings.Builder
+	output     []any
+	usage      *GeminiLiveUsageMetadata
+}
+
+func newGeminiRealtimeBridge(modelName string, client, upstream *websocket.Conn) *geminiRealtimeBridge {
+	session := fmt.Sprintf(`{"id":"sess_%s","object":"realtime.session","model":"","modalities":["audio","text"],"instructions":"","voice":"","input_audio_format":"pcm16","output_audio_format":"pcm16","input_audio_transcription":null,"turn_detection":{"type":"server_vad"},"tools":[],"tool_choice":"auto","max_response_output_tokens":"inf"}`, utils.GetRandomString(24))
+	session, _ = sjson.Set(session, "model", modelName)
+
+	return &geminiRealtimeBridge{
+		modelName: modelName,
+		client:    client,
+		upstream:  upstream,
+		closed:    make(chan struct{}),
+		setupDone: make(chan struct{}),
+		session:   session,
+		callNames: make(map[string]string),
+	}
+}
+
+// start 管道是同步的，session.created 要等 WSProxy 读取，必须在 goroutine 中发送
+func (b *geminiRealtimeBridge) start() {
+	go func() {
+		b.emit("session.created", map[string]any{"session": json.RawMessage(b.session)})
+		go b.readUpstream()
+		b.readClient()
+	}()
+}
+
+func (b *geminiRealtimeBridge) close() {
+	b.closeOnce.Do(func() {
+		close(b.closed)
+		b.client.Close()
+		b.upstream.Close()
+	})
+}
+
+// emit 向客户端发送一条 OpenAI realtime 事件
+func (b *geminiRealtimeBridge) emit(eventType string, fields map[string]any) {
+	if fields == nil {
+		fields = map[string]any{}
+	}
+	fields["event_id"] = "event_" + utils.GetRandomString(24)
+	fields["type"] = eventType
+
+	message, err := json.Marshal(fields)
+	if err != nil {
+		return
+	}
+
+	b.clientMu.Lock()
+	defer b.clientMu.Unlock()
+	b.client.WriteMessage(websocket.TextMessage, message)
+}
+
+func (b *geminiRealtimeBridge) emitError(code, message string) {
+	b.emit("error", map[string]any{"error": map[string]any{
+		"type":    "invalid_request_error",
+		"code":    code,
+		"message": message,
+	}})
+}
+
+func (b *geminiRealtimeBridge) sendUpstream(message *GeminiLiveClientMessage) error {
+	return b.upstream.WriteJSON(message)
+}
+
+// readClient 读取客户端的 OpenAI realtime 事件并转换为 Live 消息
+func (b *geminiRealtimeBridge) readClient() {
+	defer b.close()
+
+	for {
+		messageType, message, err := b.client.ReadMessage()
+		if err != nil {
+			return
+		}
+		if messageType != websocket.TextMessage {
+			continue
+		}
+
+		if err := b.handleClientEvent(gjson.ParseBytes(message)); err != nil {
+			logger.SysError("gemini realtime bridge: " + err.Error())
+			return
+		}
+	}
+}
+
+func (b *geminiRealtimeBridge) handleClientEvent(event gjson.Result) error {
+	eventType := event.Get("type").String()
+	if eventType == "session.update" {
+		return b.updateSession(event.Get("session"))
+	}
+
+	if err := b.ensureSetup(); err != nil {
+		return err
+	}
+
+	switch eventType {
+	case "input_audio_buffer.append":
+		if b.manualActivity && !b.activityActive {
+			b.activityActive = true
+			if err := b.sendUpstream(&GeminiLiveClientMessage{RealtimeInput: &GeminiLiveRealtimeInput{ActivityStart: &struct{}{}}}); err != nil {
+				return err
+			}
+		}
+		return b.sendUpstream(&GeminiLiveClientMessage{RealtimeInput: &GeminiLiveRealtimeInput{
+			Audio: &GeminiInlineData{MimeType: realtimeAudioMimeType, Data: event.Get("audio").String()},
+		}})
+
+	case "input_audio_buffer.commit":
+		input := &GeminiLiveRealtimeInput{AudioStreamEnd: true}
+		if b.manualActivity {
+			if !b.activityActive {
+				b.emitError("input_audio_buffer_commit_empty", "Error committing input audio buffer: the buffer is empty.")
+				return nil
+			}
+			b.activityActive = false
+			input = &GeminiLiveRealtimeInput{ActivityEnd: &struct{}{}}
+		}
+		if err := b.sendUpstream(&GeminiLiveClientMessage{RealtimeInput: input}); err != nil {
+			return err
+		}
+		b.emit("input_audio_buffer.committed", map[string]any{"previous_item_id": nil, "item_id": "item_" + utils.GetRandomString(24)})
+
+	case "input_audio_buffer.clear":
+		// Live 无法撤回已发送的音频，只能应答
+		b.emit("input_audio_buffer.cleared", nil)
+
+	case "conversation.item.create":
+		return b.createItem(event.Get("item"))
+
+	case "response.create":
+		if len(b.pendingTurns) == 0 {
+			// 音频输入在 activityEnd 或自动活动检测后 Live 会自动回复，无需再触发
+			return nil
+		}
+		turns := b.pendingTurns
+		b.pendingTurns = nil
+		return b.sendUpstream(&GeminiLiveClientMessage{ClientContent: &GeminiLiveClientContent{Turns: turns, TurnComplete: true}})
+
+	case "response.cancel":
+		// Live 没有取消回复的消息，只能由新的用户输入打断
+	default:
+		b.emitError("unsupported_event", fmt.Sprintf("Event type '%s' is not supported by this model.", eventType))
+	}
+
+	return nil
+}
+
+// updateSession setup 发送前合并 session 配置；之后 Live 不允许修改，返回错误事件
+func (b *geminiRealtimeBridge) updateSession(update gjson.Result) error {
+	if b.setupSent {
+		b.emitError("session_update_unsupported", "The session cannot be updated after it has started.")
+		return nil
+	}
+
+	update.ForEach(func(key, value gjson.Result) bool {
+		if key.String() != "id" && key.String() != "object" && key.String() != "model" {
+			b.session, _ = sjson.SetRaw(b.session, key.String(), value.Raw)
+		}
+		return true
+	})
+
+	b.emit("session.updated", map[string]any{"session": json.RawMessage(b.session)})
+	return nil
+}
+
+// ensureSetup 按当前 session 发送 setup，并等待上游 setupComplete
+func (b *geminiRealtimeBridge) ensureSetup() error {
+	if b.setupSent {
+		return nil
+	}
+	b.setupSent = true
+
+	session := gjson.Parse(b.session)
+	setup := realtimeSessionToLiveSetup(b.modelName, session)
+	b.manualActivity = setup.RealtimeInputConfig != nil
+
+	if err := b.sendUpstream(&GeminiLiveClientMessage{Setup: setup}); err != nil {
+		return err
+	}
+
+	select {
+	case <-b.setupDone:
+		return nil
+	case <-b.closed:
+		return errors.New("upstream closed before setup completed")
+	case <-time.After(liveSetupTimeout):
+		b.emitError("setup_timeout", "Timed out waiting for the upstream session to start.")
+		return errors.New("setup timeout")
+	}
+}
+
+// createItem conversation.item.create：消息暂存到 response.create 时一起发送，工具结果立即发送
+func (b *geminiRealtimeBridge) createItem(item gjson.Result) error {
+	itemID := item.Get("id").String()
+	if itemID == "" {
+		itemID = "item_" + utils.GetRandomString(24)
+	}
+
+	switch item.Get("type").String() {
+	case "function_call_output":
+		callID := item.Get("call_id").String()
+		b.callMu.Lock()
+		name := b.callNames[callID]
+		delete(b.callNames, callID)
+		b.callMu.Unlock()
+
+		err := b.sendUpstream(&GeminiLiveClientMessage{ToolResponse: &GeminiLiveToolResponse{
+			FunctionResponses: []GeminiLiveFunctionResponse{{
+				ID:       callID,
+				Name:     name,
+				Response: map[string]any{"output": item.Get("output").String()},
+			}},
+		}})
+		if err != nil {
+			return err
+		}
+
+	case "function_call":
+		args := map[string]interface{}{}
+		json.Unmarshal([]byte(item.Get("arguments").String()), &args)
+		b.pendingTurns = append(b.pendingTurns, GeminiChatContent{
+			Role:  "model",
+			Parts: []GeminiPart{{FunctionCall: &GeminiFunctionCall{Id: item.Get("call_id").String(), Name: item.Get("name").String(), Args: args}}},
+		})
+
+	default:
+		role := "user"
+		if item.Get("role").String() == types.ChatMessageRoleAssistant {
+			role = "model"
+		}
+		content := GeminiChatContent{Role: role}
+		for _, part := range item.Get("content").Array() {
+			switch part.Get("type").String() {
+			case "input_text", "text", "output_text":
+				content.Parts = append(content.Parts, GeminiPart{Text: part.Get("text").String()})
+			case "input_audio", "audio":
+				if audio := part.Get("audio").String(); audio != "" {
+					content.Parts = append(content.Parts, GeminiPart{InlineData: &GeminiInlineData{MimeType: realtimeAudioMimeType, Data: audio}})
+				} else if transcript := part.Get("transcript").String(); transcript != "" {
+					content.Parts = append(content.Parts, GeminiPart{Text: transcript})
+				}
+			}
+		}
+		if len(content.Parts) > 0 {
+			b.pendingTurns = append(b.pendingTurns, content)
+		}
+	}
+
+	created, _ := sjson.Set(item.Raw, "id", itemID)
+	b.emit("conversation.item.created", map[string]any{"previous_item_id": nil, "item": json.RawMessage(created)})
+	return nil
+}
+
+// readUpstream 读取 Live 消息并转换为 OpenAI realtime 事件
+func (b *geminiRealtimeBridge) readUpstream() {
+	defer b.close()
+
+	for {
+		// Live 的 JSON 消息可能以二进制帧下发
+		_, message, err := b.upstream.ReadMessage()
+		if err != nil {
+			var closeErr *websocket.CloseError
+			if errors.As(err, &closeErr) && closeErr.Code != websocket.CloseNormalClosure {
+				b.emit("error", map[string]any{"error": map[string]any{
+					"type":    "server_error",
+					"code":    fmt.Sprintf("upstream_close_%d", closeErr.Code),
+					"message": closeErr.Text,
+				}})
+			}
+			b.flushPendingUsage()
+			return
+		}
+
+		var serverMessage GeminiLiveServerMessage
+		if err := json.Unmarshal(message, &serverMessage); err != nil {
+			logger.SysError("gemini realtime bridge: " + err.Error())
+			continue
+		}
+		b.handleUpstreamMessage(&serverMessage)
+	}
+}
+
+func (b *geminiRealtimeBridge) handleUpstreamMessage(message *GeminiLiveServerMessage) {
+	if message.SetupComplete != nil {
+		b.setupOnce.Do(func() { close(b.setupDone) })
+	}
+
+	if message.UsageMetadata != nil {
+		// Live 在一轮内可能多次下发用量，取最后一次
+		if b.response != nil {
+			b.response.usage = message.UsageMetadata
+		} else {
+			b.pendingUsage = message.UsageMetadata
+		}
+	}
+
+	if content := message.ServerContent; content != nil {
+		if content.InputTranscription != nil && content.InputTranscription.Text != "" {
+			if b.inputItemID == "" {
+				b.inputItemID = "item_" + utils.GetRandomString(24)
+			}
+			b.inputTranscript.WriteString(content.InputTranscription.Text)
+			b.emit("conversation.item.input_audio_transcription.delta", map[string]any{
+				"item_id":       b.inputItemID,
+				"content_index": 0,
+				"delta":         content.InputTranscription.Text,
+			})
+		}
+
+		if content.ModelTurn != nil {
+			for _, part := range content.ModelTurn.Parts {
+				if part.Thought {
+					continue
+				}
+				if part.InlineData != nil && strings.HasPrefix(part.InlineData.MimeType, "audio/") {
+					b.ensureMessage(true)
+					b.emitContentDelta("response.audio.delta", part.InlineData.Data)
+				} else if part.Text != "" {
+					b.ensureMessage(false)
+					b.response.text.WriteString(part.Text)
+					b.emitContentDelta("response.text.delta", part.Text)
+				}
+			}
+		}
+
+		if content.OutputTranscription != nil && content.OutputTranscription.Text != "" {
+			b.ensureMessage(true)
+			b.response.transcript.WriteString(content.OutputTranscription.Text)
+			b.emitContentDelta("response.audio_transcript.delta", content.OutputTranscription.Text)
+		}
+
+		if content.Interrupted {
+			b.finishResponse("cancelled")
+		} else if content.TurnComplete {
+			b.finishResponse("completed")
+		}
+	}
+
+	if message.ToolCall != nil && len(message.ToolCall.FunctionCalls) > 0 {
+		b.emitFunctionCalls(message.ToolCall.FunctionCalls)
+		// Live 在工具调用后等待 toolResponse，不会下发 turnComplete，按 OpenAI 语义在此结束本次回复
+		b.finishResponse("completed")
+	}
+
+	if message.GoAway != nil {
+		logger.SysLog("gemini realtime bridge: upstream go away, time left " + message.GoAway.TimeLeft)
+	}
+}
+
+func (b *geminiRealtimeBridge) ensureResponse() {
+	if b.response != nil {
+		return
+	}
+
+	b.flushInputTranscript()
+
+	b.response = &realtimeResponse{
+		id:    "resp_" + utils.GetRandomString(24),
+		usage: b.pendingUsage,
+	}
+	b.pendingUsage = nil
+	b.emit("response.created", map[string]any{"response": map[string]any{
+		"id":     b.response.id,
+		"object": "realtime.response",
+		"status": "in_progress",
+		"output": []any{},
+	}})
+}
+
+// ensureMessage 回复的第一段内容到达时补发 output_item.added 与 content_part.added
+func (b *geminiRealtimeBridge) ensureMessage(audio bool) {
+	b.ensureResponse()
+	if b.response.hasMessage {
+		return
+	}
+
+	b.response.hasMessage = true
+	b.response.audio = audio
+	b.response.itemID = "item_" + utils.GetRandomString(24)
+
+	b.emit("response.output_item.added", map[string]any{
+		"response_id":  b.response.id,
+		"output_index": len(b.response.output),
+		"item": map[string]any{
+			"id":      b.response.itemID,
+			"object":  "realtime.item",
+			"type":    "message",
+			"status":  "in_progress",
+			"role":    types.ChatMessageRoleAssistant,
+			"content": []any{},
+		},
+	})
+	b.emit("response.content_part.added", map[string]any{
+		"response_id":   b.response.id,
+		"item_id":       b.response.itemID,
+		"output_index":  len(b.response.output),
+		"content_index": 0,
+		"part":          b.contentPart(),
+	})
+}
+
+func (b *geminiRealtimeBridge) contentPart() map[string]any {
+	if b.response.audio {
+		return map[string]any{"type": "audio", "transcript": b.response.transcript.String()}
+	}
+	return map[string]any{"type": "text", "text": b.response.text.String()}
+}
+
+func (b *geminiRealtimeBridge) emitContentDelta(eventType, delta string) {
+	b.emit(eventType, map[string]any{
+		"response_id":   b.response.id,
+		"item_id":       b.response.itemID,
+		"output_index":  len(b.response.output),
+		"content_index": 0,
+		"delta":         delta,
+	})
+}
+
+// finishMessage 结束当前消息 item，依次发送各 done 事件
+func (b *geminiRealtimeBridge) finishMessage(status string) {
+	if !b.response.hasMessage {
+		return
+	}
+	b.response.hasMessage = false
+
+	fields := func(extra map[string]any) map[string]any {
+		event := map[string]any{
+			"response_id":   b.response.id,
+			"item_id":       b.response.itemID,
+			"output_index":  len(b.response.output),
+			"content_index": 0,
+		}
+		for key, value := range extra {
+			event[key] = value
+		}
+		return event
+	}
+
+	if b.response.audio {
+		b.emit("response.audio.done", fields(nil))
+		b.emit("response.audio_transcript.done", fields(map[string]any{"transcript": b.response.transcript.String()}))
+	} else {
+		b.emit("response.text.done", fields(map[string]any{"text": b.response.text.String()}))
+	}
+
+	part := b.contentPart()
+	b.emit("response.content_part.done", fields(map[string]any{"part": part}))
+
+	item := map[string]any{
+		"id":      b.response.itemID,
+		"object":  "realtime.item",
+		"type":    "message",
+		"status":  status,
+		"role":    types.ChatMessageRoleAssistant,
+		"content": []any{part},
+	}
+	b.emit("response.output_item.done", map[string]any{
+		"response_id":  b.response.id,
+		"output_index": len(b.response.output),
+		"item":         item,
+	})
+	b.response.output = append(b.response.output, item)
+}
+
+func (b *geminiRealtimeBridge) emitFunctionCalls(calls []GeminiFunctionCall) {
+	b.ensureResponse()
+	b.finishMessage("completed")
+
+	for _, call := range calls {
+		callID := call.Id
+		if callID == "" {
+			callID = "call_" + utils.GetRandomString(24)
+		}
+		b.callMu.Lock()
+		b.callNames[callID] = call.Name
+		b.callMu.Unlock()
+
+		arguments := "{}"
+		if call.Args != nil {
+			if data, err := json.Marshal(call.Args); err == nil {
+				arguments = string(data)
+			}
+		}
+
+		itemID := "item_" + utils.GetRandomString(24)
+		item := map[string]any{
+			"id":        itemID,
+			"object":    "realtime.item",
+			"type":      "function_call",
+			"status":    "in_progress",
+			"call_id":   callID,
+			"name":      call.Name,
+			"arguments": "",
+		}
+		outputIndex := len(b.response.output)
+		b.emit("response.output_item.added", map[string]any{"response_id": b.response.id, "output_index": outputIndex, "item": item})
+		b.emit("response.function_call_arguments.done", map[string]any{
+			"response_id":  b.response.id,
+			"item_id":      itemID,
+			"output_index": outputIndex,
+			"call_id":      callID,
+			"name":         call.Name,
+			"arguments":    arguments,
+		})
+
+		done := make(map[string]any, len(item))
+		for key, value := range item {
+			done[key] = value
+		}
+		done["status"] = "completed"
+		done["arguments"] = arguments
+		b.emit("response.output_item.done", map[string]any{"response_id": b.response.id, "output_index": outputIndex, "item": done})
+		b.response.output = append(b.response.output, done)
+	}
+}
+
+// finishResponse 发送 response.done，usage 为 OpenAI 格式，由 HandleMessage 交给计费
+func (b *geminiRealtimeBridge) finishResponse(status string) {
+	if b.response == nil {
+		if b.pendingUsage == nil {
+			return
+		}
+		b.ensureResponse()
+	}
+
+	b.finishMessage(status)
+
+	response := map[string]any{
+		"id":     b.response.id,
+		"object": "realtime.response",
+		"status": status,
+		"output": b.response.output,
+	}
+	if b.response.output == nil {
+		response["output"] = []any{}
+	}
+	if b.response.usage != nil {
+		response["usage"] = b.response.usage.ToUsageEvent()
+	}
+
+	b.emit("response.done", map[string]any{"response": response})
+	b.response = nil
+}
+
+// flushPendingUsage 上游断开时把尚未结算的用量补一个 response.done
+func (b *geminiRealtimeBridge) flushPendingUsage() {
+	if b.response != nil {
+		b.finishResponse("incomplete")
+		return
+	}
+	b.finishResponse("completed")
+}
+
+// flushInputTranscript 用户语音转写在模型开始回复时视为完成
+func (b *geminiRealtimeBridge) flushInputTranscript() {
+	if b.inputItemID == "" {
+		return
+	}
+
+	b.emit("conversation.item.input_audio_transcription.completed", map[string]any{
+		"item_id":       b.inputItemID,
+		"content_index": 0,
+		"transcript":    b.inputTranscript.String(),
+	})
+	b.inputItemID = ""
+	b.inputTranscript.Reset()
+}
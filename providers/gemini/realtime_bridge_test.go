@@ -0,0 +1,328 @@
+package gemini
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"done-hub/common/logger"
+	"done-hub/common/requester"
+
+	"github.com/gorilla/websocket"
+	"github.com/tidwall/gjson"
+	"go.uber.org/zap"
+)
+
+func init() {
+	// 桥接出错时会写系统日志，测试中不落盘
+	logger.Logger = zap.NewNop()
+}
+
+// fakeLive 模拟的 Gemini Live 端点：收到的消息放入 received，测试通过 conn 直接下发消息
+type fakeLive struct {
+	conn     *websocket.Conn
+	received chan gjson.Result
+}
+
+// newLiveBridge 启动模拟的 Live 端点，按 CreateChatRealtime 的方式建立管道与桥接，返回客户端一侧的连接
+func newLiveBridge(t *testing.T) (*fakeLive, *websocket.Conn) {
+	t.Helper()
+	live := &fakeLive{received: make(chan gjson.Result, 16)}
+	connCh := make(chan *websocket.Conn, 1)
+
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("升级 websocket 失败: %v", err)
+			return
+		}
+		connCh <- conn
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				close(live.received)
+				return
+			}
+			live.received <- gjson.ParseBytes(message)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	upstream, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("连接模拟上游失败: %v", err)
+	}
+	live.conn = <-connCh
+	t.Cleanup(func() { live.conn.Close() })
+
+	client, bridgeConn, err := requester.NewWSPipe()
+	if err != nil {
+		t.Fatalf("创建管道失败: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	newGeminiRealtimeBridge("gemini-live", bridgeConn, upstream).start()
+	expectEvent(t, client, "session.created")
+
+	return live, client
+}
+
+func (l *fakeLive) send(t *testing.T, message string) {
+	t.Helper()
+	if err := l.conn.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
+		t.Fatalf("模拟上游发送失败: %v", err)
+	}
+}
+
+// expect 读取桥接发给上游的下一条消息
+func (l *fakeLive) expect(t *testing.T) gjson.Result {
+	t.Helper()
+	select {
+	case message, ok := <-l.received:
+		if !ok {
+			t.Fatal("上游连接已关闭")
+		}
+		return message
+	case <-time.After(5 * time.Second):
+		t.Fatal("等待上游消息超时")
+	}
+	return gjson.Result{}
+}
+
+func sendEvent(t *testing.T, client *websocket.Conn, event string) {
+	t.Helper()
+	if err := client.WriteMessage(websocket.TextMessage, []byte(event)); err != nil {
+		t.Fatalf("客户端发送失败: %v", err)
+	}
+}
+
+func readEvent(t *testing.T, client *websocket.Conn) gjson.Result {
+	t.Helper()
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, message, err := client.ReadMessage()
+	if err != nil {
+		t.Fatalf("客户端读取失败: %v", err)
+	}
+	return gjson.ParseBytes(message)
+}
+
+// expectEvent 读取下一条事件并校验类型
+func expectEvent(t *testing.T, client *websocket.Conn, eventType string) gjson.Result {
+	t.Helper()
+	event := readEvent(t, client)
+	if got := event.Get("type").String(); got != eventType {
+		t.Fatalf("期望事件 %s, 实际 %s", eventType, event.Raw)
+	}
+	return event
+}
+
+// assertPaths 按 gjson 路径校验消息字段
+func assertPaths(t *testing.T, message gjson.Result, expected map[string]string) {
+	t.Helper()
+	for path, want := range expected {
+		if got := message.Get(path); got.String() != want && got.Raw != want {
+			t.Errorf("%s = %s, 期望 %s, 消息 %s", path, got.Raw, want, message.Raw)
+		}
+	}
+}
+
+// TestRealtimeBridgeTextTurn 测试文本会话：session.update 合并为 setup，文本消息与回复双向转换，用量写入 response.done
+func TestRealtimeBridgeTextTurn(t *testing.T) {
+	live, client := newLiveBridge(t)
+
+	sendEvent(t, client, `{"type":"session.update","session":{"modalities":["text"],"instructions":"be brief","temperature":0.6,
+		"max_response_output_tokens":256,"tools":[{"type":"function","name":"get_weather","description":"weather",
+		"parameters":{"type":"object","properties":{"city":{"type":"string"}}}}]}}`)
+	updated := expectEvent(t, client, "session.updated")
+	assertPaths(t, updated, map[string]string{
+		"session.instructions": "be brief",
+		"session.model":        "gemini-live",
+	})
+
+	// 第一条非 session.update 事件触发 setup，等待 setupComplete 后才继续处理
+	sendEvent(t, client, `{"type":"conversation.item.create","item":{"type":"message","role":"user","content":[{"type":"input_text","text":"hi"}]}}`)
+	setup := live.expect(t)
+	assertPaths(t, setup, map[string]string{
+		"setup.model": "models/gemini-live",
+		"setup.generationConfig.responseModalities":            `["TEXT"]`,
+		"setup.generationConfig.temperature":                   "0.6",
+		"setup.generationConfig.maxOutputTokens":               "256",
+		"setup.systemInstruction.parts.0.text":                 "be brief",
+		"setup.tools.0.functionDeclarations.0.name":            "get_weather",
+		"setup.tools.0.functionDeclarations.0.parameters.type": "object",
+	})
+	for _, path := range []string{"setup.outputAudioTranscription", "setup.realtimeInputConfig"} {
+		if setup.Get(path).Exists() {
+			t.Errorf("文本会话不应包含 %s: %s", path, setup.Raw)
+		}
+	}
+	live.send(t, `{"setupComplete":{}}`)
+	created := expectEvent(t, client, "conversation.item.created")
+	if created.Get("item.id").String() == "" {
+		t.Errorf("conversation.item.created 应补全 item id: %s", created.Raw)
+	}
+
+	sendEvent(t, client, `{"type":"response.create"}`)
+	assertPaths(t, live.expect(t), map[string]string{
+		"clientContent.turns.0.role":         "user",
+		"clientContent.turns.0.parts.0.text": "hi",
+		"clientContent.turnComplete":         "true",
+	})
+
+	live.send(t, `{"serverContent":{"modelTurn":{"parts":[{"text":"thinking","thought":true},{"text":"Hel"}]}}}`)
+	live.send(t, `{"serverContent":{"modelTurn":{"parts":[{"text":"lo"}]}}}`)
+	live.send(t, `{"usageMetadata":{"promptTokenCount":5,"responseTokenCount":2,"totalTokenCount":7,
+		"promptTokensDetails":[{"modality":"TEXT","tokenCount":5}]},"serverContent":{"turnComplete":true}}`)
+
+	responseID := expectEvent(t, client, "response.created").Get("response.id").String()
+	expectEvent(t, client, "response.output_item.added")
+	expectEvent(t, client, "response.content_part.added")
+	assertPaths(t, expectEvent(t, client, "response.text.delta"), map[string]string{"delta": "Hel", "response_id": responseID})
+	assertPaths(t, expectEvent(t, client, "response.text.delta"), map[string]string{"delta": "lo"})
+	assertPaths(t, expectEvent(t, client, "response.text.done"), map[string]string{"text": "Hello"})
+	expectEvent(t, client, "response.content_part.done")
+	expectEvent(t, client, "response.output_item.done")
+	assertPaths(t, expectEvent(t, client, "response.done"), map[string]string{
+		"response.id":                                    responseID,
+		"response.status":                                "completed",
+		"response.output.0.content.0.text":               "Hello",
+		"response.usage.input_tokens":                    "5",
+		"response.usage.output_tokens":                   "2",
+		"response.usage.total_tokens":                    "7",
+		"response.usage.input_token_details.text_tokens": "5",
+	})
+
+	// setup 之后 Live 不允许修改会话
+	sendEvent(t, client, `{"type":"session.update","session":{"instructions":"changed"}}`)
+	assertPaths(t, expectEvent(t, client, "error"), map[string]string{"error.code": "session_update_unsupported"})
+}
+
+// TestRealtimeBridgeAudioAndToolCall 测试手动提交的音频输入、音频回复转写与工具调用往返
+func TestRealtimeBridgeAudioAndToolCall(t *testing.T) {
+	live, client := newLiveBridge(t)
+
+	sendEvent(t, client, `{"type":"session.update","session":{"turn_detection":null,"voice":"Puck","input_audio_transcription":{"model":"whisper-1"}}}`)
+	expectEvent(t, client, "session.updated")
+
+	sendEvent(t, client, `{"type":"input_audio_buffer.append","audio":"AAAA"}`)
+	assertPaths(t, live.expect(t), map[string]string{
+		"setup.generationConfig.responseModalities":                                     `["AUDIO"]`,
+		"setup.generationConfig.speechConfig.voiceConfig.prebuiltVoiceConfig.voiceName": "Puck",
+		"setup.realtimeInputConfig.automaticActivityDetection.disabled":                 "true",
+		"setup.outputAudioTranscription":                                                "{}",
+		"setup.inputAudioTranscription":                                                 "{}",
+	})
+	live.send(t, `{"setupComplete":{}}`)
+
+	// 关闭自动活动检测时，第一段音频前补发 activityStart，commit 对应 activityEnd
+	if !live.expect(t).Get("realtimeInput.activityStart").Exists() {
+		t.Fatal("第一段音频前应发送 activityStart")
+	}
+	assertPaths(t, live.expect(t), map[string]string{
+		"realtimeInput.audio.mimeType": realtimeAudioMimeType,
+		"realtimeInput.audio.data":     "AAAA",
+	})
+	sendEvent(t, client, `{"type":"input_audio_buffer.commit"}`)
+	if !live.expect(t).Get("realtimeInput.activityEnd").Exists() {
+		t.Fatal("commit 应发送 activityEnd")
+	}
+	expectEvent(t, client, "input_audio_buffer.committed")
+
+	live.send(t, `{"serverContent":{"inputTranscription":{"text":"weather in Paris"}}}`)
+	assertPaths(t, expectEvent(t, client, "conversation.item.input_audio_transcription.delta"), map[string]string{"delta": "weather in Paris"})
+
+	live.send(t, `{"serverContent":{"modelTurn":{"parts":[{"inlineData":{"mimeType":"audio/pcm;rate=24000","data":"BBBB"}}]},
+		"outputTranscription":{"text":"Let me check"}}}`)
+	assertPaths(t, expectEvent(t, client, "conversation.item.input_audio_transcription.completed"), map[string]string{"transcript": "weather in Paris"})
+	expectEvent(t, client, "response.created")
+	expectEvent(t, client, "response.output_item.added")
+	assertPaths(t, expectEvent(t, client, "response.content_part.added"), map[string]string{"part.type": "audio"})
+	assertPaths(t, expectEvent(t, client, "response.audio.delta"), map[string]string{"delta": "BBBB"})
+	assertPaths(t, expectEvent(t, client, "response.audio_transcript.delta"), map[string]string{"delta": "Let me check"})
+
+	// 工具调用结束当前消息与本次回复
+	live.send(t, `{"toolCall":{"functionCalls":[{"id":"call_1","name":"get_weather","args":{"city":"Paris"}}]}}`)
+	expectEvent(t, client, "response.audio.done")
+	assertPaths(t, expectEvent(t, client, "response.audio_transcript.done"), map[string]string{"transcript": "Let me check"})
+	expectEvent(t, client, "response.content_part.done")
+	expectEvent(t, client, "response.output_item.done")
+	assertPaths(t, expectEvent(t, client, "response.output_item.added"), map[string]string{"item.type": "function_call", "output_index": "1"})
+	assertPaths(t, expectEvent(t, client, "response.function_call_arguments.done"), map[string]string{
+		"call_id":   "call_1",
+		"name":      "get_weather",
+		"arguments": `{"city":"Paris"}`,
+	})
+	expectEvent(t, client, "response.output_item.done")
+	assertPaths(t, expectEvent(t, client, "response.done"), map[string]string{
+		"response.status":           "completed",
+		"response.output.#":         "2",
+		"response.output.1.call_id": "call_1",
+	})
+
+	// 工具结果立即以 toolResponse 发送，并回填函数名
+	sendEvent(t, client, `{"type":"conversation.item.create","item":{"type":"function_call_output","call_id":"call_1","output":"sunny"}}`)
+	assertPaths(t, live.expect(t), map[string]string{
+		"toolResponse.functionResponses.0.id":              "call_1",
+		"toolResponse.functionResponses.0.name":            "get_weather",
+		"toolResponse.functionResponses.0.response.output": "sunny",
+	})
+	expectEvent(t, client, "conversation.item.created")
+
+	// 手动模式下没有音频时 commit 返回错误事件
+	sendEvent(t, client, `{"type":"input_audio_buffer.commit"}`)
+	assertPaths(t, expectEvent(t, client, "error"), map[string]string{"error.code": "input_audio_buffer_commit_empty"})
+}
+
+// TestRealtimeBridgeUpstreamClose 测试上游异常关闭：转发错误事件、补发未结算的用量并关闭客户端连接
+func TestRealtimeBridgeUpstreamClose(t *testing.T) {
+	live, client := newLiveBridge(t)
+
+	sendEvent(t, client, `{"type":"response.create"}`)
+	live.expect(t)
+	live.send(t, `{"setupComplete":{}}`)
+
+	sendEvent(t, client, `{"type":"unknown.event"}`)
+	assertPaths(t, expectEvent(t, client, "error"), map[string]string{"error.code": "unsupported_event"})
+
+	live.send(t, `{"usageMetadata":{"promptTokenCount":3,"responseTokenCount":4,"totalTokenCount":7}}`)
+	live.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "quota exceeded"), time.Now().Add(time.Second))
+
+	assertPaths(t, expectEvent(t, client, "error"), map[string]string{
+		"error.type":    "server_error",
+		"error.code":    "upstream_close_1011",
+		"error.message": "quota exceeded",
+	})
+	expectEvent(t, client, "response.created")
+	assertPaths(t, expectEvent(t, client, "response.done"), map[string]string{
+		"response.status":             "completed",
+		"response.usage.total_tokens": "7",
+	})
+
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, _, err := client.ReadMessage(); err == nil {
+		t.Fatal("上游关闭后客户端连接应被关闭")
+	}
+}
+
+// TestRealtimeBridgeSetupClosed 测试 setup 完成前上游断开时桥接直接关闭
+func TestRealtimeBridgeSetupClosed(t *testing.T) {
+	live, client := newLiveBridge(t)
+
+	sendEvent(t, client, `{"type":"conversation.item.create","item":{"type":"message","role":"user","content":[{"type":"input_text","text":"hi"}]}}`)
+	live.expect(t)
+	live.conn.Close()
+
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		_, message, err := client.ReadMessage()
+		if err != nil {
+			break
+		}
+		if eventType := gjson.GetBytes(message, "type").String(); eventType == "conversation.item.created" {
+			t.Fatalf("setup 未完成时不应继续处理事件: %s", message)
+		}
+	}
+}
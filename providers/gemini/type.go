@@ -953,7 +953,13 @@ type VeoVideoRequest struct {
 }
 
 type VeoVideoInstance struct {
-	Prompt string `json:"prompt"`
+	Prompt string         `json:"prompt"`
+	Image  *VeoVideoImage `json:"image,omitempty"` // 图生视频的首帧
+}
+
+type VeoVideoImage struct {
+	BytesBase64Encoded string `json:"bytesBase64Encoded"`
+	MimeType           string `json:"mimeType"`
 }
 
 type VeoVideoParameters struct {
@@ -120,9 +120,11 @@ func (p *GeminiProvider) GetVeoOperationStatus(operationName string) (*VeoLongRu
 		version = p.Channel.Other
 	}
 
-	inputVersion := p.Context.Param("version")
-	if inputVersion != "" {
-		version = inputVersion
+	// 后台轮询 /v1/videos 任务时没有请求上下文
+	if p.Context != nil {
+		if inputVersion := p.Context.Param("version"); inputVersion != "" {
+			version = inputVersion
+		}
 	}
 
 	fullRequestURL := baseURL + "/" + version + "/" + operationName
@@ -0,0 +1,104 @@
+package gemini
+
+import (
+	"done-hub/common"
+	"done-hub/common/config"
+	"done-hub/common/image"
+	"done-hub/model"
+	"done-hub/types"
+	"net/http"
+)
+
+// CreateVideo 把 /v1/videos 请求转换为 Veo predictLongRunning 任务，上游 operation name 作为任务 id
+func (p *GeminiProvider) CreateVideo(request *types.VideoRequest) (*types.VideoTaskResult, *types.OpenAIErrorWithStatusCode) {
+	// 内嵌 GeminiProvider 的渠道（Vertex Express、Gemini CLI 等）没有 Veo 端点
+	if p.Channel.Type != config.ChannelTypeGemini {
+		return nil, common.StringErrorWrapperLocal("channel not implemented", "channel_error", http.StatusServiceUnavailable)
+	}
+
+	instance := VeoVideoInstance{Prompt: request.Prompt}
+	if request.InputReference != "" {
+		mimeType, data, err := image.GetImageFromUrl(request.InputReference)
+		if err != nil {
+			return nil, common.ErrorWrapperLocal(err, "invalid_input_reference", http.StatusBadRequest)
+		}
+		instance.Image = &VeoVideoImage{BytesBase64Encoded: data, MimeType: mimeType}
+	}
+
+	veoRequest := &VeoVideoRequest{
+		Instances: []VeoVideoInstance{instance},
+		Parameters: &VeoVideoParameters{
+			AspectRatio:     request.AspectRatio(),
+			NegativePrompt:  request.NegativePrompt,
+			DurationSeconds: request.Duration(),
+		},
+	}
+
+	operation, errWithCode := p.submitVeoTask(veoRequest, request.Model)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+	if operation.Name == "" {
+		return nil, common.StringErrorWrapper("empty operation name", "empty_operation", http.StatusInternalServerError)
+	}
+
+	return &types.VideoTaskResult{
+		TaskID: operation.Name,
+		Status: model.TaskStatusSubmitted,
+	}, nil
+}
+
+func (p *GeminiProvider) GetVideo(taskID, action string) (*types.VideoTaskResult, *types.OpenAIErrorWithStatusCode) {
+	operation, errWithCode := p.GetVeoOperationStatus(taskID)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	result := &types.VideoTaskResult{
+		TaskID:   taskID,
+		Action:   action,
+		Status:   model.TaskStatusInProgress,
+		Progress: 50,
+		Data:     operation,
+	}
+	if !operation.Done {
+		return result, nil
+	}
+
+	result.Progress = 100
+	if operation.Error != nil {
+		result.Status = model.TaskStatusFailure
+		result.FailReason = operation.Error.Message
+		return result, nil
+	}
+
+	if operation.Response == nil ||
+		operation.Response.GenerateVideoResponse == nil ||
+		len(operation.Response.GenerateVideoResponse.GeneratedSamples) == 0 ||
+		operation.Response.GenerateVideoResponse.GeneratedSamples[0].Video == nil {
+		result.Status = model.TaskStatusFailure
+		result.FailReason = "no video generated"
+		return result, nil
+	}
+
+	result.Status = model.TaskStatusSuccess
+	result.VideoURL = operation.Response.GenerateVideoResponse.GeneratedSamples[0].Video.Uri
+	return result, nil
+}
+
+// GetVideoContent Veo 的下载地址需要带渠道密钥访问
+func (p *GeminiProvider) GetVideoContent(result *types.VideoTaskResult) (*http.Response, *types.OpenAIErrorWithStatusCode) {
+	if result.VideoURL == "" {
+		return nil, common.StringErrorWrapperLocal("video is not ready", "video_not_ready", http.StatusNotFound)
+	}
+
+	headers := p.GetRequestHeaders()
+	delete(headers, "Content-Type")
+
+	req, err := p.Requester.NewRequest(http.MethodGet, result.VideoURL, p.Requester.WithHeader(headers))
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "new_request_failed", http.StatusInternalServerError)
+	}
+
+	return p.Requester.SendRequestRaw(req)
+}
@@ -0,0 +1,126 @@
+package kling
+
+import (
+	"done-hub/common"
+	"done-hub/types"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// CreateVideo 把 /v1/videos 请求转换为可灵 text2video / image2video 任务。
+// 模型名沿用 kling-video_{model}_{mode}_{duration} 的计费格式，直接传入可灵模型名时使用默认模式与时长。
+func (p *KlingProvider) CreateVideo(request *types.VideoRequest) (*types.VideoTaskResult, *types.OpenAIErrorWithStatusCode) {
+	klingRequest := &KlingTask{
+		Prompt:         request.Prompt,
+		ModelName:      request.Model,
+		Mode:           request.Mode,
+		NegativePrompt: request.NegativePrompt,
+		Duration:       "5",
+	}
+	if request.Duration() > 5 {
+		klingRequest.Duration = "10"
+	}
+
+	if parts := strings.Split(request.Model, "_"); len(parts) == 4 && parts[0] == "kling-video" {
+		klingRequest.ModelName = parts[1]
+		klingRequest.Mode = parts[2]
+		klingRequest.Duration = parts[3]
+	}
+	if klingRequest.Mode == "" {
+		klingRequest.Mode = "std"
+	}
+	if aspectRatio := request.AspectRatio(); aspectRatio != "" {
+		klingRequest.AspectRatio = aspectRatio
+	}
+
+	action := "text2video"
+	if request.InputReference != "" {
+		action = "image2video"
+		// 可灵只接受 URL 或不带前缀的 base64
+		klingRequest.Image = request.InputReference
+		if strings.HasPrefix(klingRequest.Image, "data:") {
+			if _, data, ok := strings.Cut(klingRequest.Image, ","); ok {
+				klingRequest.Image = data
+			}
+		}
+	}
+
+	fullRequestURL := p.GetFullRequestURL(fmt.Sprintf(p.Generations, "videos", action), "")
+	headers := p.GetRequestHeaders()
+	// /v1/videos 可能是 multipart 请求，不能沿用客户端的 Content-Type
+	headers["Content-Type"] = "application/json"
+
+	req, err := p.Requester.NewRequest(http.MethodPost, fullRequestURL, p.Requester.WithHeader(headers), p.Requester.WithBody(klingRequest))
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "new_request_failed", http.StatusInternalServerError)
+	}
+
+	resp := &KlingResponse[KlingTaskData]{}
+	if _, errWithCode := p.Requester.SendRequest(req, resp, false); errWithCode != nil {
+		return nil, errWithCode
+	}
+	if resp.Code != 0 || resp.Data.TaskID == "" {
+		return nil, common.StringErrorWrapper(resp.Message, "kling_error", http.StatusInternalServerError)
+	}
+
+	return &types.VideoTaskResult{
+		TaskID: resp.Data.TaskID,
+		Action: action,
+		Status: switchTaskStatus(resp.Data.TaskStatus),
+		Data:   resp.Data,
+	}, nil
+}
+
+func (p *KlingProvider) GetVideo(taskID, action string) (*types.VideoTaskResult, *types.OpenAIErrorWithStatusCode) {
+	fullRequestURL := p.GetFullRequestURL(fmt.Sprintf(p.Fetch, "videos", action, taskID), "")
+	headers := p.GetRequestHeaders()
+
+	req, err := p.Requester.NewRequest(http.MethodGet, fullRequestURL, p.Requester.WithHeader(headers))
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "new_request_failed", http.StatusInternalServerError)
+	}
+
+	klResponse := &KlingResponse[*KlingTaskData]{}
+	if _, errWithCode := p.Requester.SendRequest(req, klResponse, false); errWithCode != nil {
+		return nil, errWithCode
+	}
+	if klResponse.Code != 0 || klResponse.Data == nil {
+		return nil, common.StringErrorWrapper(klResponse.Message, "kling_error", http.StatusInternalServerError)
+	}
+
+	result := &types.VideoTaskResult{
+		TaskID:     taskID,
+		Action:     action,
+		Status:     switchTaskStatus(klResponse.Data.TaskStatus),
+		FailReason: klResponse.Data.TaskStatusMsg,
+		Data:       klResponse.Data,
+	}
+
+	switch klResponse.Data.TaskStatus {
+	case "processing":
+		result.Progress = 50
+	case "succeed", "failed":
+		result.Progress = 100
+	}
+
+	if klResponse.Data.TaskResult != nil && len(klResponse.Data.TaskResult.Videos) > 0 {
+		result.VideoURL = klResponse.Data.TaskResult.Videos[0].URL
+	}
+
+	return result, nil
+}
+
+// GetVideoContent 可灵的成片地址是公开链接，直接下载
+func (p *KlingProvider) GetVideoContent(result *types.VideoTaskResult) (*http.Response, *types.OpenAIErrorWithStatusCode) {
+	if result.VideoURL == "" {
+		return nil, common.StringErrorWrapperLocal("video is not ready", "video_not_ready", http.StatusNotFound)
+	}
+
+	req, err := p.Requester.NewRequest(http.MethodGet, result.VideoURL)
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "new_request_failed", http.StatusInternalServerError)
+	}
+
+	return p.Requester.SendRequestRaw(req)
+}
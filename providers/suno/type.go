@@ -23,6 +23,7 @@ type SunoSubmitReq struct {
 	TaskID               string  `json:"task_id,omitempty"`
 	ContinueClipId       string  `json:"continue_clip_id,omitempty"`
 	MakeInstrumental     bool    `json:"make_instrumental"`
+	NotifyHook           string  `json:"notify_hook,omitempty"`
 }
 
 type FetchReq struct {
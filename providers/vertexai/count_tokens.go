@@ -0,0 +1,56 @@
+package vertexai
+
+import (
+	"done-hub/common"
+	"done-hub/providers/claude"
+	"done-hub/providers/vertexai/category"
+	"done-hub/types"
+	"net/http"
+	"strings"
+
+	"github.com/tidwall/sjson"
+)
+
+// Vertex 上 Claude 的 count_tokens 是 anthropic 发布方下的一个固定"模型"，
+// 真实模型名放在请求体的 model 字段里。
+const claudeCountTokensModel = "count-tokens"
+
+func (p *VertexAIProvider) CountClaudeTokens(request *claude.ClaudeRequest) (*claude.CountTokensResponse, *types.OpenAIErrorWithStatusCode) {
+	fullRequestURL := p.GetFullRequestURL(claudeCountTokensModel, "rawPredict")
+	if fullRequestURL == "" {
+		return nil, common.ErrorWrapperLocal(nil, "invalid_vertexai_config", http.StatusInternalServerError)
+	}
+	fullRequestURL = strings.Replace(fullRequestURL, "/publishers/google/", "/publishers/anthropic/", 1)
+
+	headers, err := p.getRequestHeadersInternal()
+	if err != nil {
+		return nil, p.handleTokenError(err)
+	}
+
+	countRequest := *request
+	countRequest.Model = category.GetClaudeModelName(request.Model)
+
+	rawBody, _ := p.ReadNativeRawBody("messages")
+	body, err := claude.BuildCountTokensBody(rawBody, &countRequest)
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "marshal_count_tokens_failed", http.StatusInternalServerError)
+	}
+	if body, err = sjson.SetBytes(body, "anthropic_version", category.AnthropicVersion); err != nil {
+		return nil, common.ErrorWrapper(err, "marshal_count_tokens_failed", http.StatusInternalServerError)
+	}
+
+	p.Requester.ErrorHandler = RequestErrorHandle(claude.RequestErrorHandle)
+
+	req, err := p.Requester.NewRequest(http.MethodPost, fullRequestURL, p.Requester.WithBody(body), p.Requester.WithHeader(headers))
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "new_request_failed", http.StatusInternalServerError)
+	}
+	defer req.Body.Close()
+
+	response := &claude.CountTokensResponse{}
+	if _, errWithCode := p.Requester.SendRequest(req, response, false); errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	return response, nil
+}
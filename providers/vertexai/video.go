@@ -0,0 +1,197 @@
+package vertexai
+
+import (
+	"bytes"
+	"done-hub/common"
+	"done-hub/common/image"
+	"done-hub/model"
+	"done-hub/providers/gemini"
+	"done-hub/types"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type VertexAIVideoOperation struct {
+	Name     string                    `json:"name"`
+	Done     bool                      `json:"done"`
+	Response *VertexAIVideoResponse    `json:"response,omitempty"`
+	Error    *gemini.VeoOperationError `json:"error,omitempty"`
+}
+
+type VertexAIVideoResponse struct {
+	RaiMediaFilteredCount   int                 `json:"raiMediaFilteredCount,omitempty"`
+	RaiMediaFilteredReasons []string            `json:"raiMediaFilteredReasons,omitempty"`
+	Videos                  []VertexAIVideoData `json:"videos,omitempty"`
+}
+
+type VertexAIVideoData struct {
+	GcsUri             string `json:"gcsUri,omitempty"`
+	BytesBase64Encoded string `json:"bytesBase64Encoded,omitempty"`
+	MimeType           string `json:"mimeType,omitempty"`
+}
+
+// CreateVideo 提交 Veo predictLongRunning 任务，返回的 operation name 中带有项目、区域与模型，轮询时据此还原请求地址
+func (p *VertexAIProvider) CreateVideo(request *types.VideoRequest) (*types.VideoTaskResult, *types.OpenAIErrorWithStatusCode) {
+	instance := gemini.VeoVideoInstance{Prompt: request.Prompt}
+	if request.InputReference != "" {
+		mimeType, data, err := image.GetImageFromUrl(request.InputReference)
+		if err != nil {
+			return nil, common.ErrorWrapperLocal(err, "invalid_input_reference", http.StatusBadRequest)
+		}
+		instance.Image = &gemini.VeoVideoImage{BytesBase64Encoded: data, MimeType: mimeType}
+	}
+
+	veoRequest := &gemini.VeoVideoRequest{
+		Instances: []gemini.VeoVideoInstance{instance},
+		Parameters: &gemini.VeoVideoParameters{
+			AspectRatio:     request.AspectRatio(),
+			NegativePrompt:  request.NegativePrompt,
+			SampleCount:     1,
+			DurationSeconds: request.Duration(),
+		},
+	}
+
+	fullRequestURL := p.GetFullRequestURL(request.Model, "predictLongRunning")
+	operation := &VertexAIVideoOperation{}
+	if errWithCode := p.sendVideoRequest(fullRequestURL, veoRequest, operation); errWithCode != nil {
+		return nil, errWithCode
+	}
+	if operation.Name == "" {
+		return nil, common.StringErrorWrapper("empty operation name", "empty_operation", http.StatusInternalServerError)
+	}
+
+	return &types.VideoTaskResult{
+		TaskID: operation.Name,
+		Status: model.TaskStatusSubmitted,
+	}, nil
+}
+
+func (p *VertexAIProvider) GetVideo(taskID, action string) (*types.VideoTaskResult, *types.OpenAIErrorWithStatusCode) {
+	operation, errWithCode := p.fetchVideoOperation(taskID)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	result := &types.VideoTaskResult{
+		TaskID:   taskID,
+		Action:   action,
+		Status:   model.TaskStatusInProgress,
+		Progress: 50,
+	}
+	if !operation.Done {
+		return result, nil
+	}
+
+	result.Progress = 100
+	switch {
+	case operation.Error != nil:
+		result.Status = model.TaskStatusFailure
+		result.FailReason = operation.Error.Message
+	case operation.Response == nil || len(operation.Response.Videos) == 0:
+		result.Status = model.TaskStatusFailure
+		result.FailReason = "no video generated"
+		if operation.Response != nil && len(operation.Response.RaiMediaFilteredReasons) > 0 {
+			result.FailReason = strings.Join(operation.Response.RaiMediaFilteredReasons, "; ")
+		}
+	default:
+		result.Status = model.TaskStatusSuccess
+		// 未指定 storageUri 时视频以 base64 内联返回，体积较大，不落库，下载时重新拉取
+		result.VideoURL = operation.Response.Videos[0].GcsUri
+	}
+
+	return result, nil
+}
+
+func (p *VertexAIProvider) GetVideoContent(result *types.VideoTaskResult) (*http.Response, *types.OpenAIErrorWithStatusCode) {
+	operation, errWithCode := p.fetchVideoOperation(result.TaskID)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+	if !operation.Done || operation.Response == nil || len(operation.Response.Videos) == 0 {
+		return nil, common.StringErrorWrapperLocal("video is not ready", "video_not_ready", http.StatusNotFound)
+	}
+
+	video := operation.Response.Videos[0]
+	mimeType := video.MimeType
+	if mimeType == "" {
+		mimeType = "video/mp4"
+	}
+
+	if video.BytesBase64Encoded != "" {
+		data, err := base64.StdEncoding.DecodeString(video.BytesBase64Encoded)
+		if err != nil {
+			return nil, common.ErrorWrapper(err, "decode_video_failed", http.StatusInternalServerError)
+		}
+		return &http.Response{
+			StatusCode:    http.StatusOK,
+			Header:        http.Header{"Content-Type": []string{mimeType}},
+			Body:          io.NopCloser(bytes.NewReader(data)),
+			ContentLength: int64(len(data)),
+		}, nil
+	}
+
+	// gs://bucket/object 通过 GCS JSON API 下载
+	bucket, object, ok := strings.Cut(strings.TrimPrefix(video.GcsUri, "gs://"), "/")
+	if !ok {
+		return nil, common.StringErrorWrapper("invalid gcs uri", "invalid_gcs_uri", http.StatusInternalServerError)
+	}
+	headers, err := p.getRequestHeadersInternal()
+	if err != nil {
+		return nil, p.handleTokenError(err)
+	}
+	delete(headers, "Content-Type")
+
+	downloadURL := fmt.Sprintf("https://storage.googleapis.com/download/storage/v1/b/%s/o/%s?alt=media", bucket, url.PathEscape(object))
+	req, err := p.Requester.NewRequest(http.MethodGet, downloadURL, p.Requester.WithHeader(headers))
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "new_request_failed", http.StatusInternalServerError)
+	}
+
+	return p.Requester.SendRequestRaw(req)
+}
+
+// fetchVideoOperation 调用 fetchPredictOperation 查询长任务状态。
+// operation name 形如 projects/{project}/locations/{region}/publishers/google/models/{model}/operations/{id}
+func (p *VertexAIProvider) fetchVideoOperation(operationName string) (*VertexAIVideoOperation, *types.OpenAIErrorWithStatusCode) {
+	parts := strings.Split(operationName, "/")
+	if len(parts) < 10 || parts[0] != "projects" || parts[2] != "locations" || parts[6] != "models" {
+		return nil, common.StringErrorWrapperLocal("invalid operation name", "invalid_operation", http.StatusInternalServerError)
+	}
+	project, region, modelName := parts[1], parts[3], parts[7]
+
+	regionPrefix := region + "-"
+	if region == "global" {
+		regionPrefix = ""
+	}
+	fullRequestURL := fmt.Sprintf(p.GetBaseURL(), regionPrefix, project, region, modelName, "fetchPredictOperation")
+
+	operation := &VertexAIVideoOperation{}
+	if errWithCode := p.sendVideoRequest(fullRequestURL, map[string]string{"operationName": operationName}, operation); errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	return operation, nil
+}
+
+func (p *VertexAIProvider) sendVideoRequest(fullRequestURL string, body any, response any) *types.OpenAIErrorWithStatusCode {
+	headers, err := p.getRequestHeadersInternal()
+	if err != nil {
+		return p.handleTokenError(err)
+	}
+	// /v1/videos 可能是 multipart 请求，不能沿用客户端的 Content-Type
+	headers["Content-Type"] = "application/json"
+
+	p.Requester.ErrorHandler = RequestErrorHandle(nil)
+
+	req, err := p.Requester.NewRequest(http.MethodPost, fullRequestURL, p.Requester.WithBody(body), p.Requester.WithHeader(headers))
+	if err != nil {
+		return common.ErrorWrapper(err, "new_request_failed", http.StatusInternalServerError)
+	}
+
+	_, errWithCode := p.Requester.SendRequest(req, response, false)
+	return errWithCode
+}
@@ -95,6 +95,10 @@ func (r *relayBase) getModelName() string {
 	billingOriginalModel := r.c.GetBool("billing_original_model")
 
 	if billingOriginalModel {
+		// 虚拟模型没有自己的价格，按实际使用的步骤模型计费
+		if route := getVirtualModelRoute(r.c); route != nil {
+			return route.Step().Model
+		}
 		return r.originalModel
 	}
 	return r.modelName
@@ -5,6 +5,7 @@ import (
 	"done-hub/common/config"
 	"done-hub/common/requester"
 	"done-hub/common/utils"
+	"done-hub/model"
 	providersBase "done-hub/providers/base"
 	"done-hub/safty"
 	"done-hub/types"
@@ -35,6 +36,10 @@ func NewRelayChat(c *gin.Context) *relayChat {
 }
 
 func (r *relayChat) setRequest() error {
+	if err := parseRequestRouting(r.c); err != nil {
+		return err
+	}
+
 	if err := common.UnmarshalBodyReusable(r.c, &r.chatRequest); err != nil {
 		return err
 	}
@@ -83,6 +88,21 @@ func (r *relayChat) forcePromptTokens() int {
 	return common.CountTokenMessages(r.chatRequest.Messages, r.modelName, config.PreCostDefault)
 }
 
+func (r *relayChat) routingContent() *model.RoutingContent {
+	hasImage := false
+	for _, message := range r.chatRequest.Messages {
+		if hasMessagePartType(message.Content, types.ContentTypeImageURL) {
+			hasImage = true
+			break
+		}
+	}
+	hasTools := len(r.chatRequest.Tools) > 0 || len(r.chatRequest.Functions) > 0
+
+	return model.NewRoutingContent(hasImage, hasTools, func() int {
+		return common.CountTokenMessages(r.chatRequest.Messages, r.getOriginalModel(), config.PreCostNotImage)
+	})
+}
+
 var need2Response = map[string]bool{
 	"o3-pro-2025-06-10":                true,
 	"o3-pro":                           true,
@@ -0,0 +1,110 @@
+package relay
+
+import (
+	"done-hub/common/config"
+	"done-hub/model"
+	"done-hub/types"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RecordChannelResult 把一次上游调用结果记入渠道+模型的熔断器与路由统计，并释放渠道并发名额。
+// 本地错误与请求参数类错误与渠道健康无关，只释放半开状态占用的试探名额。
+func RecordChannelResult(c *gin.Context, channelId, statusCode int, localError bool) {
+	model.ReleaseChannelLease(c)
+	if channelId == 0 {
+		return
+	}
+
+	modelName := c.GetString(config.GinChannelModelKey)
+	if modelName == "" {
+		return
+	}
+
+	switch {
+	case statusCode/100 == 2:
+		model.ChannelStats.RecordOutcome(channelId, modelName, false)
+		if config.CircuitBreakerEnabled {
+			model.CircuitBreakers.RecordSuccess(channelId, modelName)
+		}
+	case !localError && isCircuitFailure(statusCode) && !isChannelKeyFailure(c, statusCode):
+		model.ChannelStats.RecordOutcome(channelId, modelName, true)
+		if config.CircuitBreakerEnabled {
+			model.CircuitBreakers.RecordFailure(channelId, modelName)
+		}
+	default:
+		if config.CircuitBreakerEnabled {
+			model.CircuitBreakers.Release(channelId, modelName)
+		}
+	}
+}
+
+// recordRelayTokens 累计渠道 TPM 用量
+func recordRelayTokens(c *gin.Context, channel *model.Channel, usage *types.Usage) {
+	modelName := c.GetString(config.GinChannelModelKey)
+	if channel == nil || modelName == "" {
+		return
+	}
+
+	model.ChannelLimits.RecordTokens(channel, modelName, usage.PromptTokens+usage.CompletionTokens)
+}
+
+// recordRelayLatency 记录成功请求的首字时间与总耗时，供延迟感知路由使用
+func recordRelayLatency(c *gin.Context, channel *model.Channel, startTime, firstResponseTime time.Time) {
+	modelName := c.GetString(config.GinChannelModelKey)
+	if channel == nil || modelName == "" {
+		return
+	}
+
+	var ttft time.Duration
+	if firstResponseTime.After(startTime) {
+		ttft = firstResponseTime.Sub(startTime)
+	}
+	model.ChannelStats.RecordLatency(channel.Id, modelName, ttft, time.Since(startTime))
+}
+
+func recordRelayResult(c *gin.Context, channel *model.Channel, apiErr *types.OpenAIErrorWithStatusCode) {
+	if channel == nil {
+		model.ReleaseChannelLease(c)
+		return
+	}
+
+	if apiErr == nil {
+		RecordChannelResult(c, channel.Id, http.StatusOK, false)
+		return
+	}
+	RecordChannelResult(c, channel.Id, apiErr.StatusCode, apiErr.LocalError)
+}
+
+// releaseChannelAttempt 放弃一次尝试的结果（如被取消的对冲落败方），只释放并发名额与半开状态的试探名额
+func releaseChannelAttempt(c *gin.Context, channel *model.Channel) {
+	model.ReleaseChannelLease(c)
+	modelName := c.GetString(config.GinChannelModelKey)
+	if channel == nil || modelName == "" || !config.CircuitBreakerEnabled {
+		return
+	}
+	model.CircuitBreakers.Release(channel.Id, modelName)
+}
+
+// isChannelKeyFailure 多密钥渠道的限流与鉴权失效只与出错的密钥有关，由密钥冷却/禁用处理，不计入渠道熔断
+func isChannelKeyFailure(c *gin.Context, statusCode int) bool {
+	if c.GetInt(config.GinChannelKeyIdKey) == 0 {
+		return false
+	}
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusUnauthorized, http.StatusForbidden:
+		return true
+	}
+	return false
+}
+
+// isCircuitFailure 限流、鉴权失效与 5xx 视为渠道故障
+func isCircuitFailure(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusUnauthorized, http.StatusForbidden:
+		return true
+	}
+	return statusCode/100 == 5
+}
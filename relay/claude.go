@@ -8,6 +8,7 @@ import (
 	"done-hub/common/model_utils"
 	"done-hub/common/requester"
 	"done-hub/common/utils"
+	"done-hub/model"
 	"done-hub/providers/antigravity"
 	"done-hub/providers/claude"
 	"done-hub/providers/gemini"
@@ -273,6 +274,20 @@ func (r *relayClaudeOnly) forcePromptTokens() int {
 	return countClaudeMessageTokens(r.claudeRequest)
 }
 
+func (r *relayClaudeOnly) routingContent() *model.RoutingContent {
+	hasImage := false
+	for _, message := range r.claudeRequest.Messages {
+		if hasMessagePartType(message.Content, claude.ContentTypeImage) {
+			hasImage = true
+			break
+		}
+	}
+
+	return model.NewRoutingContent(hasImage, len(r.claudeRequest.Tools) > 0, func() int {
+		return countClaudeMessageTokens(r.claudeRequest)
+	})
+}
+
 // sendCustomChannelWithClaudeFormat 处理自定义渠道的Claude格式请求
 // 仅在 /claude/v1/messages 路由时调用，实现 Claude格式 -> OpenAI格式 -> 上游接口 -> OpenAI响应 -> Claude格式 的转换
 func (r *relayClaudeOnly) sendCustomChannelWithClaudeFormat() (err *types.OpenAIErrorWithStatusCode, done bool) {
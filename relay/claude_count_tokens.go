@@ -0,0 +1,108 @@
+package relay
+
+import (
+	"done-hub/common"
+	"done-hub/common/config"
+	"done-hub/common/logger"
+	"done-hub/providers/claude"
+	"done-hub/types"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// claudeCountTokensUpstreamTypes 走上游原生 count_tokens 的渠道类型。
+// ClaudeCode 等虽内嵌 ClaudeProvider，但鉴权方式不同，统一按转换渠道本地估算。
+var claudeCountTokensUpstreamTypes = map[int]bool{
+	config.ChannelTypeAnthropic: true,
+	config.ChannelTypeBedrock:   true,
+	config.ChannelTypeVertexAI:  true,
+}
+
+// RelayClaudeCountTokens 处理 /claude/v1/messages/count_tokens。
+// 客户端（如 Claude Code）用它估算上下文，所以不走 RelayHandler：
+// 不预扣费、不记消费日志，也不触发渠道冷却与自动禁用。
+func RelayClaudeCountTokens(c *gin.Context) {
+	defer func() {
+		c.Set(config.GinRequestBodyKey, nil)
+	}()
+
+	relay := NewRelayClaudeOnly(c)
+	if err := relay.setRequest(); err != nil {
+		relay.HandleJsonError(common.StringErrorWrapperLocal(err.Error(), "invalid_request_error", http.StatusBadRequest))
+		return
+	}
+
+	if err := relay.setProvider(relay.getOriginalModel()); err != nil {
+		if IsModelNotFound(err) {
+			relay.HandleJsonError(common.ModelNotFoundError(relay.getOriginalModel()))
+		} else {
+			relay.HandleJsonError(common.UpstreamUnavailableError(err.Error()))
+		}
+		return
+	}
+
+	inputTokens, apiErr := relay.countTokens()
+	if apiErr != nil {
+		relay.HandleJsonError(apiErr)
+		return
+	}
+
+	c.JSON(http.StatusOK, claude.CountTokensResponse{InputTokens: inputTokens})
+}
+
+// countTokens 优先调用上游 count_tokens；上游不支持或失败（400 除外）时回退本地估算，
+// 保证 count_tokens 永远可用——它只是估算，不值得为它换渠道重试。
+func (r *relayClaudeOnly) countTokens() (int, *types.OpenAIErrorWithStatusCode) {
+	channel := r.provider.GetChannel()
+	counter, ok := r.provider.(claude.ClaudeCountTokensInterface)
+	if !ok || !claudeCountTokensUpstreamTypes[channel.Type] {
+		return countClaudeInputTokens(r.claudeRequest), nil
+	}
+
+	r.claudeRequest.Model = r.modelName
+	response, apiErr := counter.CountClaudeTokens(r.claudeRequest)
+	if apiErr == nil {
+		return response.InputTokens, nil
+	}
+
+	// 400 是请求本身的问题（字段非法等），对客户端有意义，原样返回
+	if apiErr.StatusCode == http.StatusBadRequest {
+		return 0, apiErr
+	}
+
+	logger.LogWarn(r.c.Request.Context(), fmt.Sprintf("count_tokens_fallback channel_id=%d model=%s status_code=%d error=\"%s\"",
+		channel.Id, r.modelName, apiErr.StatusCode, apiErr.Message))
+
+	return countClaudeInputTokens(r.claudeRequest), nil
+}
+
+// countClaudeInputTokens 本地估算完整输入 token：在 countClaudeMessageTokens 的基础上
+// 补上 system 与 tools，二者在 Claude Code 场景下往往占输入的大头。
+func countClaudeInputTokens(request *claude.ClaudeRequest) int {
+	tokenNum := countClaudeMessageTokens(request)
+	tokenEncoder := common.GetTokenEncoder(request.Model)
+
+	switch system := request.System.(type) {
+	case string:
+		tokenNum += common.GetTokenNum(tokenEncoder, system)
+	case []any:
+		for _, item := range system {
+			if block, ok := item.(map[string]any); ok {
+				if text, ok := block["text"].(string); ok {
+					tokenNum += common.GetTokenNum(tokenEncoder, text)
+				}
+			}
+		}
+	}
+
+	if len(request.Tools) > 0 {
+		if toolsJson, err := json.Marshal(request.Tools); err == nil {
+			tokenNum += common.GetTokenNum(tokenEncoder, string(toolsJson))
+		}
+	}
+
+	return tokenNum
+}
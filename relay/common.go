@@ -57,6 +57,8 @@ func Path2Relay(c *gin.Context, path string) RelayBaseInterface {
 			relay = NewRelayVeoOnly(c)
 		} else if strings.Contains(path, ":predict") {
 			relay = newRelayImageGenerations(c)
+		} else if strings.HasSuffix(path, ":embedContent") || strings.HasSuffix(path, ":batchEmbedContents") {
+			relay = NewRelayGeminiEmbeddings(c)
 		} else {
 			relay = NewRelayGeminiOnly(c)
 		}
@@ -173,6 +175,12 @@ func GetProvider(c *gin.Context, modelName string) (provider providersBase.Provi
 		return nil, "", err
 	}
 
+	// 虚拟模型按回退链当前步骤的模型选择渠道，用户原始请求的模型名仍为虚拟模型
+	requestModelName := modelName
+	if route := getVirtualModelRoute(c); route != nil && route.Name() == modelName {
+		modelName = route.Step().Model
+	}
+
 	// 获取分组信息
 	tokenGroup := c.GetString("token_group")
 	backupGroup := c.GetString("token_backup_group")
@@ -274,6 +282,7 @@ func GetProvider(c *gin.Context, modelName string) (provider providersBase.Provi
 	c.Set("is_backupGroup", isBackupGroup)
 	c.Set("channel_id", channel.Id)
 	c.Set("channel_type", channel.Type)
+	c.Set(config.GinChannelModelKey, actualModelName)
 
 	// 重新设置分组倍率
 	groupRatio := model.GlobalUserGroupRatio.GetBySymbol(usedGroup)
@@ -281,13 +290,19 @@ func GetProvider(c *gin.Context, modelName string) (provider providersBase.Provi
 		c.Set("group_ratio", groupRatio.Ratio)
 	}
 
+	// 多密钥渠道从密钥池中选择本次使用的密钥
+	channel, fail = model.BindChannelKey(c, channel)
+	if fail != nil {
+		return
+	}
+
 	provider = providers.GetProvider(channel, c)
 	if provider == nil {
 		fail = errors.New("channel not found")
 		return
 	}
-	provider.SetOriginalModel(modelName) // 保存用户原始请求的模型名称
-	c.Set("original_model", modelName)
+	provider.SetOriginalModel(requestModelName) // 保存用户原始请求的模型名称
+	c.Set("original_model", requestModelName)
 
 	newModelName, fail = provider.ModelMappingHandler(actualModelName) // 使用匹配到的模型名称进行映射
 	if fail != nil {
@@ -351,6 +366,12 @@ func buildChannelFilters(c *gin.Context, modelName string) []model.ChannelsFilte
 		filters = append(filters, model.FilterDisabledStream(modelName))
 	}
 
+	if content, ok := utils.GetGinValue[*model.RoutingContent](c, config.GinRoutingContentKey); ok {
+		filters = append(filters, model.GlobalUserGroupRatio.GetRoutingRuleFilters(c.GetString("token_group"), content)...)
+	}
+
+	filters = append(filters, providerPreferenceFilters(c)...)
+
 	return filters
 }
 
@@ -358,6 +379,11 @@ func fetchChannelByModel(c *gin.Context, modelName string) (*model.Channel, erro
 	group := c.GetString("token_group")
 	filters := buildChannelFilters(c, modelName)
 
+	// 请求指定了 provider 顺序时先按顺序选择，都没有可用渠道时再按分组的策略选择
+	if channel := fetchChannelByProviderOrder(c, group, modelName, filters); channel != nil {
+		return channel, nil
+	}
+
 	// 传递 gin.Context 给 balancer，用于生成 session hash
 	channel, err := model.ChannelGroup.NextByValidatedModel(group, modelName, c, filters...)
 	if err != nil {
@@ -662,17 +688,24 @@ func responseCustom(c *gin.Context, response *types.AudioResponseWrapper) *types
 	return nil
 }
 
+// responseCache 回放缓存的响应，流式时按 SSE 事件逐个写出并 flush
 func responseCache(c *gin.Context, response string, isStream bool) {
-	if isStream {
-		requester.SetEventStreamHeaders(c)
-		c.Stream(func(w io.Writer) bool {
-			fmt.Fprint(w, response)
-			return false
-		})
-	} else {
+	if !isStream {
 		c.Data(http.StatusOK, "application/json", []byte(response))
+		return
 	}
 
+	requester.SetEventStreamHeaders(c)
+	c.Writer.WriteHeader(http.StatusOK)
+	for _, event := range strings.Split(response, "\n\n") {
+		if strings.TrimSpace(event) == "" {
+			continue
+		}
+		if _, err := c.Writer.WriteString(event + "\n\n"); err != nil {
+			return
+		}
+		c.Writer.Flush()
+	}
 }
 
 func shouldRetry(c *gin.Context, apiErr *types.OpenAIErrorWithStatusCode, channelType int) bool {
@@ -760,8 +793,20 @@ func shouldRetryBadRequest(c *gin.Context, channelType int, apiErr *types.OpenAI
 	}
 }
 
-func processChannelRelayError(ctx context.Context, channelId int, channelName string, err *types.OpenAIErrorWithStatusCode, channelType int) {
+func processChannelRelayError(ctx context.Context, channelId, keyId int, channelName string, err *types.OpenAIErrorWithStatusCode, channelType int) {
 	if controller.ShouldDisableChannel(channelType, err) {
+		// 多密钥渠道只禁用出错的密钥，池中密钥全部禁用后再禁用渠道
+		if keyId > 0 {
+			remaining, disableErr := model.DisableChannelKey(channelId, keyId, err.Message)
+			logger.LogError(ctx, fmt.Sprintf("channel_key_disabled channel_id=%d channel_name=\"%s\" key_id=%d status_code=%d remaining_keys=%d error=\"%s\"",
+				channelId, channelName, keyId, err.StatusCode, remaining, err.Message))
+			if disableErr != nil || remaining > 0 {
+				return
+			}
+			controller.DisableChannel(channelId, channelName, "密钥池中的密钥已全部禁用，最后一个密钥的错误："+err.Message, true)
+			return
+		}
+
 		logger.LogError(ctx, fmt.Sprintf("channel_disabled channel_id=%d channel_name=\"%s\" channel_type=%d status_code=%d error=\"%s\" auto_disabled=true",
 			channelId, channelName, channelType, err.StatusCode, err.Message))
 		controller.DisableChannel(channelId, channelName, err.Message, true)
@@ -776,7 +821,7 @@ func processChannelRelayError(ctx context.Context, channelId int, channelName st
 //
 // 把两件事绑在一起，未来加新入口只要调一次，从机制上消除"漏调一个就丢 429 信号"的脆弱约定。
 func notifyChannelRelayError(ctx context.Context, c *gin.Context, channel *model.Channel, apiErr *types.OpenAIErrorWithStatusCode) {
-	go processChannelRelayError(ctx, channel.Id, channel.Name, apiErr, channel.Type)
+	go processChannelRelayError(ctx, channel.Id, c.GetInt(config.GinChannelKeyIdKey), channel.Name, apiErr, channel.Type)
 	if apiErr != nil && apiErr.StatusCode == http.StatusTooManyRequests {
 		c.Set("upstream_seen_429", true)
 	}
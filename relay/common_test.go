@@ -0,0 +1,128 @@
+package relay
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"done-hub/common/config"
+	"done-hub/common/logger"
+	"done-hub/model"
+	"done-hub/types"
+
+	"github.com/glebarez/sqlite"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+func setupChannelErrorTestDB(t *testing.T) {
+	t.Helper()
+	if logger.Logger == nil {
+		logger.Logger = zap.NewNop()
+	}
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: gormLogger.Default.LogMode(gormLogger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err = db.AutoMigrate(&model.Channel{}, &model.ChannelKey{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	savedDB, savedDisable, savedNotify := model.DB, config.AutomaticDisableChannelEnabled, config.AutomaticDisableChannelNotifyEnabled
+	t.Cleanup(func() {
+		model.DB, config.AutomaticDisableChannelEnabled, config.AutomaticDisableChannelNotifyEnabled = savedDB, savedDisable, savedNotify
+		sqlDB.Close()
+	})
+	model.DB = db
+	config.AutomaticDisableChannelEnabled, config.AutomaticDisableChannelNotifyEnabled = true, false
+}
+
+// TestProcessChannelRelayErrorKeyPool 测试多密钥渠道出错时只禁用出错的密钥，池中密钥全部禁用后才禁用渠道
+func TestProcessChannelRelayErrorKeyPool(t *testing.T) {
+	unauthorized := &types.OpenAIErrorWithStatusCode{
+		OpenAIError: types.OpenAIError{Message: "Incorrect API key provided", Type: "invalid_request_error", Code: "invalid_api_key"},
+		StatusCode:  http.StatusUnauthorized,
+	}
+	serverError := &types.OpenAIErrorWithStatusCode{
+		OpenAIError: types.OpenAIError{Message: "upstream error", Type: "server_error"},
+		StatusCode:  http.StatusInternalServerError,
+	}
+
+	tests := []struct {
+		name          string
+		keys          int
+		failKey       int // 出错的密钥序号，-1 表示未使用密钥池
+		err           *types.OpenAIErrorWithStatusCode
+		wantKeys      []int
+		wantChannelOn bool
+	}{
+		{
+			name:          "401 只禁用出错的密钥",
+			keys:          3,
+			failKey:       1,
+			err:           unauthorized,
+			wantKeys:      []int{config.ChannelStatusEnabled, config.ChannelStatusAutoDisabled, config.ChannelStatusEnabled},
+			wantChannelOn: true,
+		},
+		{
+			name:          "最后一个密钥禁用后禁用渠道",
+			keys:          1,
+			failKey:       0,
+			err:           unauthorized,
+			wantKeys:      []int{config.ChannelStatusAutoDisabled},
+			wantChannelOn: false,
+		},
+		{
+			name:          "未使用密钥池时禁用渠道",
+			failKey:       -1,
+			err:           unauthorized,
+			wantChannelOn: false,
+		},
+		{
+			name:          "服务端错误不禁用密钥",
+			keys:          2,
+			failKey:       0,
+			err:           serverError,
+			wantKeys:      []int{config.ChannelStatusEnabled, config.ChannelStatusEnabled},
+			wantChannelOn: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupChannelErrorTestDB(t)
+			channel := &model.Channel{Id: 1, Name: "pool", Key: "sk-test", Status: config.ChannelStatusEnabled}
+			if tt.keys > 0 {
+				channel.KeyMode = model.ChannelKeyModeRoundRobin
+			}
+			model.DB.Create(channel)
+			keys := make([]*model.ChannelKey, 0, tt.keys)
+			for i := 0; i < tt.keys; i++ {
+				keys = append(keys, &model.ChannelKey{ChannelId: channel.Id, Key: "sk-pool", Status: config.ChannelStatusEnabled})
+			}
+			if len(keys) > 0 {
+				model.DB.Create(&keys)
+			}
+
+			keyId := 0
+			if tt.failKey >= 0 {
+				keyId = keys[tt.failKey].Id
+			}
+			processChannelRelayError(context.Background(), channel.Id, keyId, channel.Name, tt.err, config.ChannelTypeOpenAI)
+
+			stored, _ := model.GetChannelKeys(channel.Id)
+			for i, key := range stored {
+				if key.Status != tt.wantKeys[i] {
+					t.Errorf("密钥 %d 状态 = %d, 期望 %d", i, key.Status, tt.wantKeys[i])
+				}
+			}
+			reloaded, _ := model.GetChannelById(channel.Id)
+			if on := reloaded.Status == config.ChannelStatusEnabled; on != tt.wantChannelOn {
+				t.Errorf("渠道状态 = %d, 期望启用 %v", reloaded.Status, tt.wantChannelOn)
+			}
+		})
+	}
+}
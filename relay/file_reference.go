@@ -0,0 +1,142 @@
+package relay
+
+import (
+	"bytes"
+	"done-hub/common"
+	"done-hub/common/config"
+	"done-hub/model"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// resolveFileReferences 把请求中引用网关文件的 file_id 替换为内联的 base64 内容，
+// 使 chat 的 file 内容块与 Responses 的 input_file / input_image 可以发往任意渠道。
+// 不是网关保存的文件 id 保持原样交给上游处理。
+func resolveFileReferences(c *gin.Context) error {
+	path := c.Request.URL.Path
+	var listPath, filePartType string
+	switch {
+	case strings.HasPrefix(path, "/v1/chat/completions"):
+		listPath, filePartType = "messages", "file"
+	case strings.HasPrefix(path, "/v1/responses") && !strings.HasPrefix(path, "/v1/responses/compact"):
+		listPath, filePartType = "input", "input_file"
+	default:
+		return nil
+	}
+
+	body, err := common.ReadBodyRaw(c)
+	if err != nil || !bytes.Contains(body, []byte(`"file_id"`)) {
+		return nil
+	}
+
+	resolver := &fileResolver{c: c, files: make(map[string]*resolvedFile)}
+	newBody := body
+	gjson.GetBytes(body, listPath).ForEach(func(i, message gjson.Result) bool {
+		message.Get("content").ForEach(func(j, part gjson.Result) bool {
+			partPath := fmt.Sprintf("%s.%d.content.%d", listPath, i.Int(), j.Int())
+			newBody, err = resolver.resolvePart(newBody, partPath, part, filePartType)
+			return err == nil
+		})
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Set(config.GinRequestBodyKey, newBody)
+	return nil
+}
+
+type resolvedFile struct {
+	filename string
+	dataURL  string
+}
+
+type fileResolver struct {
+	c     *gin.Context
+	files map[string]*resolvedFile
+}
+
+func (r *fileResolver) resolvePart(body []byte, partPath string, part gjson.Result, filePartType string) ([]byte, error) {
+	partType := part.Get("type").String()
+	switch {
+	case partType == filePartType && filePartType == "file":
+		file, err := r.get(part.Get("file.file_id").String())
+		if err != nil || file == nil {
+			return body, err
+		}
+		return sjson.SetBytes(body, partPath+".file", map[string]string{
+			"filename":  file.filename,
+			"file_data": file.dataURL,
+		})
+	case partType == filePartType:
+		file, err := r.get(part.Get("file_id").String())
+		if err != nil || file == nil {
+			return body, err
+		}
+		if body, err = sjson.DeleteBytes(body, partPath+".file_id"); err != nil {
+			return body, err
+		}
+		if body, err = sjson.SetBytes(body, partPath+".filename", file.filename); err != nil {
+			return body, err
+		}
+		return sjson.SetBytes(body, partPath+".file_data", file.dataURL)
+	case partType == "input_image":
+		file, err := r.get(part.Get("file_id").String())
+		if err != nil || file == nil {
+			return body, err
+		}
+		if body, err = sjson.DeleteBytes(body, partPath+".file_id"); err != nil {
+			return body, err
+		}
+		return sjson.SetBytes(body, partPath+".image_url", file.dataURL)
+	}
+	return body, nil
+}
+
+// get 读取令牌下的文件并转成 data URL，同一请求内多次引用只读一次
+func (r *fileResolver) get(fileID string) (*resolvedFile, error) {
+	if fileID == "" {
+		return nil, nil
+	}
+	if file, ok := r.files[fileID]; ok {
+		return file, nil
+	}
+
+	file, err := model.GetUserFileById(r.c.GetInt("id"), r.c.GetInt("token_id"), fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file == nil {
+		r.files[fileID] = nil
+		return nil, nil
+	}
+
+	content, err := file.ReadContent()
+	if err != nil {
+		return nil, fmt.Errorf("read file %s failed", fileID)
+	}
+
+	mimeType := mime.TypeByExtension(filepath.Ext(file.Filename))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(content)
+	}
+	if index := strings.Index(mimeType, ";"); index != -1 {
+		mimeType = mimeType[:index]
+	}
+
+	resolved := &resolvedFile{
+		filename: file.Filename,
+		dataURL:  fmt.Sprintf("data:%s;base64,%s", mimeType, base64.StdEncoding.EncodeToString(content)),
+	}
+	r.files[fileID] = resolved
+	return resolved, nil
+}
@@ -0,0 +1,257 @@
+package files
+
+import (
+	"done-hub/common"
+	"done-hub/common/config"
+	"done-hub/common/logger"
+	"done-hub/common/utils"
+	"done-hub/model"
+	"done-hub/types"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// filePurposes 允许上传的 purpose，与 OpenAI Files API 一致；batch_output 只由批次生成
+var filePurposes = map[string]bool{
+	"assistants":           true,
+	model.FilePurposeBatch: true,
+	"fine-tune":            true,
+	"vision":               true,
+	"user_data":            true,
+	"evals":                true,
+}
+
+const (
+	minFileExpireSeconds = 3600
+	maxFileExpireSeconds = 30 * 24 * 3600
+)
+
+// UploadFile 处理 POST /v1/files，文件内容写入 storage 的文件存储，按用户与令牌隔离
+func UploadFile(c *gin.Context) {
+	maxSize := int64(config.FileMaxSize) << 20
+	if maxSize > 0 && c.Request.ContentLength > maxSize+(1<<20) {
+		common.AbortWithMessage(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("file size exceeds the limit of %d MB", config.FileMaxSize))
+		return
+	}
+
+	purpose := c.PostForm("purpose")
+	if !filePurposes[purpose] {
+		common.AbortWithMessage(c, http.StatusBadRequest, fmt.Sprintf("purpose: unsupported purpose %q", purpose))
+		return
+	}
+
+	formFile, err := c.FormFile("file")
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, "file: field required")
+		return
+	}
+	if maxSize > 0 && formFile.Size > maxSize {
+		common.AbortWithMessage(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("file size exceeds the limit of %d MB", config.FileMaxSize))
+		return
+	}
+
+	expiresAt, err := getExpiresAt(c)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userId := c.GetInt("id")
+	if config.FileStorageQuota > 0 {
+		used, err := model.GetUserStorageBytes(userId)
+		if err != nil {
+			common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if used+formFile.Size > int64(config.FileStorageQuota)<<20 {
+			common.AbortWithMessage(c, http.StatusBadRequest, fmt.Sprintf("storage quota of %d MB exceeded", config.FileStorageQuota))
+			return
+		}
+	}
+
+	reader, err := formFile.Open()
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	file := &model.File{
+		ID:        "file-" + utils.GetRandomString(24),
+		UserId:    userId,
+		TokenId:   c.GetInt("token_id"),
+		Purpose:   purpose,
+		Filename:  formFile.Filename,
+		ExpiresAt: expiresAt,
+	}
+	if err := file.Save(content); err != nil {
+		logger.LogError(c.Request.Context(), fmt.Sprintf("save file error: %s", err.Error()))
+		common.AbortWithMessage(c, http.StatusInternalServerError, "save file failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, toFileObject(file))
+}
+
+// getExpiresAt 解析 expires_after[anchor] / expires_after[seconds]，未指定时按 FileExpireDays
+func getExpiresAt(c *gin.Context) (int64, error) {
+	now := time.Now()
+	seconds := c.PostForm("expires_after[seconds]")
+	if seconds == "" {
+		if config.FileExpireDays <= 0 {
+			return 0, nil
+		}
+		return now.AddDate(0, 0, config.FileExpireDays).Unix(), nil
+	}
+
+	if anchor := c.PostForm("expires_after[anchor]"); anchor != "" && anchor != "created_at" {
+		return 0, fmt.Errorf("expires_after[anchor]: must be created_at")
+	}
+	value, err := strconv.ParseInt(seconds, 10, 64)
+	if err != nil || value < minFileExpireSeconds || value > maxFileExpireSeconds {
+		return 0, fmt.Errorf("expires_after[seconds]: must be between %d and %d", minFileExpireSeconds, maxFileExpireSeconds)
+	}
+	return now.Unix() + value, nil
+}
+
+// ListFiles 处理 GET /v1/files
+func ListFiles(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10000"))
+	if limit < 1 || limit > 10000 {
+		common.AbortWithMessage(c, http.StatusBadRequest, "limit: must be between 1 and 10000")
+		return
+	}
+
+	order := c.DefaultQuery("order", "desc")
+	if order != "asc" && order != "desc" {
+		common.AbortWithMessage(c, http.StatusBadRequest, "order: must be asc or desc")
+		return
+	}
+
+	userId := c.GetInt("id")
+	tokenId := c.GetInt("token_id")
+
+	var after *model.File
+	if afterID := c.Query("after"); afterID != "" {
+		file, err := model.GetUserFileById(userId, tokenId, afterID)
+		if err != nil || file == nil {
+			common.AbortWithMessage(c, http.StatusBadRequest, fmt.Sprintf("after: file %s not found", afterID))
+			return
+		}
+		after = file
+	}
+
+	files, err := model.GetUserFiles(userId, tokenId, c.Query("purpose"), after, limit+1, order == "asc")
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	hasMore := len(files) > limit
+	if hasMore {
+		files = files[:limit]
+	}
+
+	response := &types.FileList{
+		Object:  "list",
+		Data:    make([]*types.FileObject, 0, len(files)),
+		HasMore: hasMore,
+	}
+	for _, file := range files {
+		response.Data = append(response.Data, toFileObject(file))
+	}
+	if len(response.Data) > 0 {
+		response.FirstID = &response.Data[0].ID
+		response.LastID = &response.Data[len(response.Data)-1].ID
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RetrieveFile 处理 GET /v1/files/:id
+func RetrieveFile(c *gin.Context) {
+	file := getUserFile(c)
+	if file == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, toFileObject(file))
+}
+
+// GetFileContent 处理 GET /v1/files/:id/content
+func GetFileContent(c *gin.Context) {
+	file := getUserFile(c)
+	if file == nil {
+		return
+	}
+
+	content, err := file.ReadContent()
+	if err != nil {
+		logger.LogError(c.Request.Context(), fmt.Sprintf("read file %s error: %s", file.ID, err.Error()))
+		common.AbortWithMessage(c, http.StatusInternalServerError, "read file failed")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file.Filename))
+	c.Data(http.StatusOK, "application/octet-stream", content)
+}
+
+// DeleteFile 处理 DELETE /v1/files/:id
+func DeleteFile(c *gin.Context) {
+	file := getUserFile(c)
+	if file == nil {
+		return
+	}
+
+	if err := file.Delete(); err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, &types.FileDeleteResponse{
+		ID:      file.ID,
+		Object:  "file",
+		Deleted: true,
+	})
+}
+
+func getUserFile(c *gin.Context) *model.File {
+	fileID := c.Param("id")
+	file, err := model.GetUserFileById(c.GetInt("id"), c.GetInt("token_id"), fileID)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return nil
+	}
+	if file == nil {
+		common.AbortWithMessage(c, http.StatusNotFound, fmt.Sprintf("No such File object: %s", fileID))
+		return nil
+	}
+	return file
+}
+
+func toFileObject(file *model.File) *types.FileObject {
+	fileObject := &types.FileObject{
+		ID:        file.ID,
+		Object:    "file",
+		Bytes:     file.Bytes,
+		CreatedAt: file.CreatedAt,
+		Filename:  file.Filename,
+		Purpose:   file.Purpose,
+		Status:    "processed",
+	}
+	if file.ExpiresAt > 0 {
+		fileObject.ExpiresAt = &file.ExpiresAt
+	}
+	return fileObject
+}
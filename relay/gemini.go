@@ -5,6 +5,7 @@ import (
 	"done-hub/common/config"
 	"done-hub/common/logger"
 	"done-hub/common/requester"
+	"done-hub/model"
 	"done-hub/providers/gemini"
 	"done-hub/safty"
 	"done-hub/types"
@@ -12,6 +13,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -26,6 +28,12 @@ type relayGeminiOnly struct {
 	requestBody        []byte // 原始请求 bytes，延迟到 getChatRequest 再反序列化，避免大 payload 提前膨胀内存
 	cachedPromptTokens int    // 缓存 token 计数结果，避免 retry 时读已释放的 requestBody
 	promptTokensCached bool   // 标记 token 计数是否已缓存
+
+	// 非 Gemini 渠道转换为 OpenAI Chat 请求，在 setRequest 中提前转换：
+	// Gemini 渠道失败后 raw body 已被释放，retry 到非 Gemini 渠道时无法再读取
+	openaiRequest    *types.ChatCompletionRequest
+	openaiConvertErr *types.OpenAIErrorWithStatusCode
+	includeThoughts  bool
 }
 
 func NewRelayGeminiOnly(c *gin.Context) *relayGeminiOnly {
@@ -64,6 +72,9 @@ func (r *relayGeminiOnly) setRequest() error {
 	if action == "streamGenerateContent" {
 		isStream = true
 	}
+	if isGeminiChatAction(action) {
+		r.c.Set("allow_channel_type", allowGeminiChatChannelType)
+	}
 
 	// 只读取原始 bytes，不做 JSON 反序列化
 	// 避免 json.Unmarshal 对大 payload（如 base64 图片）的字符串分配开销
@@ -74,6 +85,11 @@ func (r *relayGeminiOnly) setRequest() error {
 	}
 	r.requestBody = rawBody
 
+	if isGeminiChatAction(action) {
+		r.openaiRequest, r.openaiConvertErr = convertGeminiToOpenAI(rawBody, isStream)
+		r.includeThoughts = gjson.GetBytes(rawBody, "generationConfig.thinkingConfig.includeThoughts").Bool()
+	}
+
 	r.geminiRequest = &gemini.GeminiChatRequest{
 		Model:  modelList[0],
 		Stream: isStream,
@@ -109,28 +125,45 @@ func (r *relayGeminiOnly) getPromptTokens() (int, error) {
 	return tokens, nil
 }
 
+func (r *relayGeminiOnly) routingContent() *model.RoutingContent {
+	hasImage := false
+	for _, content := range gjson.GetBytes(r.requestBody, "contents").Array() {
+		for _, part := range content.Get("parts").Array() {
+			mimeType := part.Get("inlineData.mimeType").String() + part.Get("inline_data.mime_type").String() +
+				part.Get("fileData.mimeType").String() + part.Get("file_data.mime_type").String()
+			if strings.HasPrefix(mimeType, "image/") {
+				hasImage = true
+				break
+			}
+		}
+		if hasImage {
+			break
+		}
+	}
+	hasTools := len(gjson.GetBytes(r.requestBody, "tools").Array()) > 0
+
+	return model.NewRoutingContent(hasImage, hasTools, func() int {
+		tokens, _ := countGeminiTokenMessagesFromBytes(r.requestBody, r.geminiRequest.Model, config.PreCostNotImage)
+		return tokens
+	})
+}
+
 func (r *relayGeminiOnly) send() (err *types.OpenAIErrorWithStatusCode, done bool) {
+	// 非 Gemini 渠道使用 Gemini->OpenAI->Gemini 的转换逻辑
+	if slices.Contains(AllowGeminiConvertChannelType, r.provider.GetChannel().Type) {
+		return r.sendWithOpenAIFormat()
+	}
+
 	chatProvider, ok := r.provider.(gemini.GeminiChatInterface)
 	if !ok {
 		return nil, false
 	}
 
-	// 内容审查：使用 gjson 直接在原始 bytes 上提取 text，避免完整 JSON 反序列化
 	// 注意：这是 r.requestBody 最后一次被使用的地方
-	if config.EnableSafe && r.requestBody != nil {
-		contents := gjson.GetBytes(r.requestBody, "contents")
-		for _, content := range contents.Array() {
-			for _, part := range content.Get("parts").Array() {
-				if text := part.Get("text").String(); text != "" {
-					CheckResult, _ := safty.CheckContent(text)
-					if !CheckResult.IsSafe {
-						err = common.StringErrorWrapperLocal(CheckResult.Reason, CheckResult.Code, http.StatusBadRequest)
-						done = true
-						return
-					}
-				}
-			}
-		}
+	if safetyErr := r.performContentSafety(); safetyErr != nil {
+		err = safetyErr
+		done = true
+		return
 	}
 
 	// 阶梯1释放：安全检查完毕后，relay 不再需要 raw bytes
@@ -192,6 +225,26 @@ func (r *relayGeminiOnly) send() (err *types.OpenAIErrorWithStatusCode, done boo
 	return
 }
 
+// performContentSafety 内容审查：使用 gjson 直接在原始 bytes 上提取 text，避免完整 JSON 反序列化
+func (r *relayGeminiOnly) performContentSafety() *types.OpenAIErrorWithStatusCode {
+	if !config.EnableSafe || r.requestBody == nil {
+		return nil
+	}
+
+	contents := gjson.GetBytes(r.requestBody, "contents")
+	for _, content := range contents.Array() {
+		for _, part := range content.Get("parts").Array() {
+			if text := part.Get("text").String(); text != "" {
+				CheckResult, _ := safty.CheckContent(text)
+				if !CheckResult.IsSafe {
+					return common.StringErrorWrapperLocal(CheckResult.Reason, CheckResult.Code, http.StatusBadRequest)
+				}
+			}
+		}
+	}
+	return nil
+}
+
 // releaseBody 释放所有 body 相关的内存引用
 // 仅在确认不会再 retry 时调用（上游请求成功后）
 func (r *relayGeminiOnly) releaseBody() {
@@ -0,0 +1,657 @@
+package relay
+
+import (
+	"done-hub/common"
+	"done-hub/common/config"
+	"done-hub/common/logger"
+	"done-hub/common/requester"
+	"done-hub/common/utils"
+	providersBase "done-hub/providers/base"
+	"done-hub/providers/gemini"
+	"done-hub/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// AllowGeminiConvertChannelType 通过 OpenAI Chat 格式转换后处理 Gemini 原生 generateContent 请求的渠道
+var AllowGeminiConvertChannelType = []int{
+	config.ChannelTypeOpenAI,
+	config.ChannelTypeAzure,
+	config.ChannelTypeCustom,
+	config.ChannelTypeOpenRouter,
+	config.ChannelTypeAnthropic,
+	config.ChannelTypeDeepseek,
+	config.ChannelTypeBedrock,
+}
+
+// allowGeminiChatChannelType generateContent / streamGenerateContent 可用的全部渠道
+var allowGeminiChatChannelType = append(slices.Clone(AllowGeminiChannelType), AllowGeminiConvertChannelType...)
+
+func isGeminiChatAction(action string) bool {
+	return action == "generateContent" || action == "streamGenerateContent"
+}
+
+// sendWithOpenAIFormat 非 Gemini 渠道：Gemini 请求 -> OpenAI Chat -> 上游 -> OpenAI 响应 -> Gemini 响应
+func (r *relayGeminiOnly) sendWithOpenAIFormat() (err *types.OpenAIErrorWithStatusCode, done bool) {
+	chatProvider, ok := r.provider.(providersBase.ChatInterface)
+	if !ok {
+		err = common.StringErrorWrapperLocal("channel not implemented", "channel_error", http.StatusServiceUnavailable)
+		done = true
+		return
+	}
+
+	if r.openaiConvertErr != nil {
+		return r.openaiConvertErr, true
+	}
+	if r.openaiRequest == nil {
+		return common.StringErrorWrapperLocal("request body not found", "one_hub_error", http.StatusInternalServerError), true
+	}
+
+	if safetyErr := r.performContentSafety(); safetyErr != nil {
+		return safetyErr, true
+	}
+	r.requestBody = nil
+
+	r.openaiRequest.Model = r.modelName
+
+	if r.openaiRequest.Stream {
+		var stream requester.StreamReaderInterface[string]
+		stream, err = chatProvider.CreateChatCompletionStream(r.openaiRequest)
+		if err != nil {
+			return
+		}
+
+		if r.heartbeat != nil {
+			r.heartbeat.Stop()
+		}
+
+		firstResponseTime := r.convertOpenAIStreamToGemini(stream)
+		r.SetFirstResponseTime(firstResponseTime)
+	} else {
+		var response *types.ChatCompletionResponse
+		response, err = chatProvider.CreateChatCompletion(r.openaiRequest)
+		if err != nil {
+			return
+		}
+
+		if r.heartbeat != nil {
+			r.heartbeat.Stop()
+		}
+
+		err = responseJsonClient(r.c, convertOpenAIResponseToGemini(response, r.includeThoughts))
+	}
+
+	if err != nil {
+		done = true
+	}
+	return
+}
+
+// convertGeminiToOpenAI 将 Gemini generateContent 请求转换为 OpenAI Chat 请求
+func convertGeminiToOpenAI(body []byte, stream bool) (*types.ChatCompletionRequest, *types.OpenAIErrorWithStatusCode) {
+	geminiRequest := &gemini.GeminiChatRequest{}
+	if err := json.Unmarshal(body, geminiRequest); err != nil {
+		return nil, common.ErrorWrapperLocal(err, "invalid_request_error", http.StatusBadRequest)
+	}
+
+	request := &types.ChatCompletionRequest{
+		Messages: make([]types.ChatCompletionMessage, 0, len(geminiRequest.Contents)+1),
+		Stream:   stream,
+	}
+	if stream {
+		request.StreamOptions = &types.StreamOptions{IncludeUsage: true}
+	}
+
+	if system := geminiSystemText(gjson.GetBytes(body, "systemInstruction")); system != "" {
+		request.Messages = append(request.Messages, types.ChatCompletionMessage{
+			Role:    types.ChatMessageRoleSystem,
+			Content: system,
+		})
+	}
+
+	// Gemini 的 functionResponse 只带函数名，按名称依次对应之前 functionCall 生成的 tool_call_id
+	pendingCalls := make(map[string][]string)
+	for _, content := range geminiRequest.Contents {
+		messages, err := convertGeminiContent(content, pendingCalls)
+		if err != nil {
+			return nil, common.ErrorWrapperLocal(err, "invalid_request_error", http.StatusBadRequest)
+		}
+		request.Messages = append(request.Messages, messages...)
+	}
+
+	generationConfig := geminiRequest.GenerationConfig
+	request.Temperature = generationConfig.Temperature
+	request.TopP = generationConfig.TopP
+	request.TopK = generationConfig.TopK
+	request.MaxTokens = generationConfig.MaxOutputTokens
+	if generationConfig.CandidateCount > 1 {
+		request.N = &generationConfig.CandidateCount
+	}
+	if len(generationConfig.StopSequences) > 0 {
+		request.Stop = generationConfig.StopSequences
+	}
+
+	if generationConfig.ResponseMimeType == "application/json" {
+		request.ResponseFormat = &types.ChatCompletionResponseFormat{Type: "json_object"}
+		schema := generationConfig.ResponseSchema
+		if jsonSchema := gjson.GetBytes(body, "generationConfig.responseJsonSchema"); jsonSchema.Exists() {
+			schema = jsonSchema.Value()
+		}
+		if schema != nil {
+			request.ResponseFormat = &types.ChatCompletionResponseFormat{
+				Type: "json_schema",
+				JsonSchema: &types.FormatJsonSchema{
+					Name:   "response",
+					Schema: convertGeminiSchema(schema),
+				},
+			}
+		}
+	}
+
+	request.Reasoning = convertGeminiThinkingConfig(generationConfig.ThinkingConfig, generationConfig.MaxOutputTokens)
+
+	for _, tool := range geminiRequest.Tools {
+		for _, function := range tool.FunctionDeclarations {
+			function.Parameters = convertGeminiSchema(function.Parameters)
+			request.Tools = append(request.Tools, &types.ChatCompletionTool{
+				Type:     types.ToolChoiceTypeFunction,
+				Function: function,
+			})
+		}
+	}
+	// functionDeclarations 之外的 parametersJsonSchema 直接就是 JSON Schema
+	gjson.GetBytes(body, "tools.#.functionDeclarations|@flatten").ForEach(func(_, declaration gjson.Result) bool {
+		if schema := declaration.Get("parametersJsonSchema"); schema.Exists() {
+			for _, tool := range request.Tools {
+				if tool.Function.Name == declaration.Get("name").String() {
+					tool.Function.Parameters = schema.Value()
+				}
+			}
+		}
+		return true
+	})
+
+	for _, tool := range request.Tools {
+		if tool.Function.Parameters == nil {
+			tool.Function.Parameters = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+	}
+	if len(request.Tools) > 0 {
+		request.ToolChoice = convertGeminiToolConfig(gjson.GetBytes(body, "toolConfig.functionCallingConfig"))
+	}
+
+	return request, nil
+}
+
+// geminiSystemText systemInstruction 可以是字符串、Content 或 Part
+func geminiSystemText(system gjson.Result) string {
+	if !system.Exists() {
+		return ""
+	}
+	if system.Type == gjson.String {
+		return system.String()
+	}
+
+	texts := make([]string, 0)
+	parts := system.Get("parts")
+	if !parts.Exists() {
+		parts = gjson.Parse("[" + system.Raw + "]")
+	}
+	parts.ForEach(func(_, part gjson.Result) bool {
+		if text := part.Get("text").String(); text != "" {
+			texts = append(texts, text)
+		}
+		return true
+	})
+	return strings.Join(texts, "\n")
+}
+
+func convertGeminiContent(content gemini.GeminiChatContent, pendingCalls map[string][]string) ([]types.ChatCompletionMessage, error) {
+	if content.Role == "model" {
+		return []types.ChatCompletionMessage{convertGeminiModelContent(content, pendingCalls)}, nil
+	}
+
+	messages := make([]types.ChatCompletionMessage, 0, 1)
+	parts := make([]types.ChatMessagePart, 0, len(content.Parts))
+	for _, part := range content.Parts {
+		switch {
+		case part.FunctionResponse != nil:
+			callID := ""
+			if ids := pendingCalls[part.FunctionResponse.Name]; len(ids) > 0 {
+				callID, pendingCalls[part.FunctionResponse.Name] = ids[0], ids[1:]
+			} else {
+				callID = "call_" + utils.GetRandomString(24)
+			}
+			output, _ := json.Marshal(part.FunctionResponse.Response)
+			messages = append(messages, types.ChatCompletionMessage{
+				Role:       types.ChatMessageRoleTool,
+				Content:    string(output),
+				ToolCallID: callID,
+			})
+		case part.Text != "":
+			parts = append(parts, types.ChatMessagePart{Type: types.ContentTypeText, Text: part.Text})
+		case part.InlineData != nil:
+			parts = append(parts, convertGeminiInlineData(part.InlineData))
+		case part.FileData != nil:
+			if part.FileData.MimeType != "" && !strings.HasPrefix(part.FileData.MimeType, "image/") {
+				return nil, fmt.Errorf("fileData with mimeType %s is not supported on this channel", part.FileData.MimeType)
+			}
+			parts = append(parts, types.ChatMessagePart{
+				Type:     types.ContentTypeImageURL,
+				ImageURL: &types.ChatMessageImageURL{URL: part.FileData.FileUri},
+			})
+		}
+	}
+
+	if len(parts) > 0 {
+		message := types.ChatCompletionMessage{Role: types.ChatMessageRoleUser, Content: parts}
+		if len(parts) == 1 && parts[0].Type == types.ContentTypeText {
+			message.Content = parts[0].Text
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+func convertGeminiModelContent(content gemini.GeminiChatContent, pendingCalls map[string][]string) types.ChatCompletionMessage {
+	message := types.ChatCompletionMessage{Role: types.ChatMessageRoleAssistant}
+
+	var text, reasoning strings.Builder
+	for _, part := range content.Parts {
+		switch {
+		case part.FunctionCall != nil:
+			callID := part.FunctionCall.Id
+			if callID == "" {
+				callID = "call_" + utils.GetRandomString(24)
+			}
+			pendingCalls[part.FunctionCall.Name] = append(pendingCalls[part.FunctionCall.Name], callID)
+
+			args, _ := json.Marshal(part.FunctionCall.Args)
+			if part.FunctionCall.Args == nil {
+				args = []byte("{}")
+			}
+			message.ToolCalls = append(message.ToolCalls, &types.ChatCompletionToolCalls{
+				Id:    callID,
+				Type:  types.ToolChoiceTypeFunction,
+				Index: len(message.ToolCalls),
+				Function: &types.ChatCompletionToolCallsFunction{
+					Name:      part.FunctionCall.Name,
+					Arguments: string(args),
+				},
+			})
+		case part.Thought:
+			reasoning.WriteString(part.Text)
+		default:
+			text.WriteString(part.Text)
+		}
+	}
+
+	message.Content = text.String()
+	message.ReasoningContent = reasoning.String()
+	return message
+}
+
+func convertGeminiInlineData(data *gemini.GeminiInlineData) types.ChatMessagePart {
+	switch {
+	case strings.HasPrefix(data.MimeType, "image/"):
+		return types.ChatMessagePart{
+			Type:     types.ContentTypeImageURL,
+			ImageURL: &types.ChatMessageImageURL{URL: fmt.Sprintf("data:%s;base64,%s", data.MimeType, data.Data)},
+		}
+	case strings.HasPrefix(data.MimeType, "audio/"):
+		format := strings.TrimPrefix(data.MimeType, "audio/")
+		if format == "mpeg" {
+			format = "mp3"
+		}
+		return types.ChatMessagePart{
+			Type:       "input_audio",
+			InputAudio: &types.InputAudio{Data: data.Data, Format: format},
+		}
+	default:
+		return types.ChatMessagePart{
+			Type: "file",
+			File: &types.ChatMessageFile{
+				Filename: "file",
+				FileData: fmt.Sprintf("data:%s;base64,%s", data.MimeType, data.Data),
+			},
+		}
+	}
+}
+
+// convertGeminiSchema Gemini 的 Schema 使用大写 type 与 nullable，转换为标准 JSON Schema
+func convertGeminiSchema(schema any) any {
+	switch value := schema.(type) {
+	case map[string]any:
+		converted := make(map[string]any, len(value))
+		for key, item := range value {
+			switch key {
+			case "type":
+				if typeName, ok := item.(string); ok {
+					item = strings.ToLower(typeName)
+				}
+			case "nullable", "propertyOrdering":
+				continue
+			default:
+				item = convertGeminiSchema(item)
+			}
+			converted[key] = item
+		}
+		if nullable, _ := value["nullable"].(bool); nullable {
+			if typeName, ok := converted["type"].(string); ok {
+				converted["type"] = []any{typeName, "null"}
+			}
+		}
+		return converted
+	case []any:
+		converted := make([]any, len(value))
+		for i, item := range value {
+			converted[i] = convertGeminiSchema(item)
+		}
+		return converted
+	default:
+		return schema
+	}
+}
+
+// convertGeminiThinkingConfig thinkingBudget 为 0 表示关闭思考；thinkingLevel 直接对应 reasoning effort，
+// 只给出 budget 时按预算大小估算 effort，budget 小于 maxOutputTokens 时一并透传
+func convertGeminiThinkingConfig(thinkingConfig *gemini.ThinkingConfig, maxOutputTokens int) *types.ChatReasoning {
+	if thinkingConfig == nil {
+		return nil
+	}
+
+	reasoning := &types.ChatReasoning{Effort: strings.ToLower(thinkingConfig.ThinkingLevel)}
+	if budget := thinkingConfig.ThinkingBudget; budget != nil {
+		switch {
+		case *budget == 0:
+			return nil
+		case *budget > 0 && (maxOutputTokens == 0 || *budget < maxOutputTokens):
+			reasoning.MaxTokens = *budget
+		}
+		if reasoning.Effort == "" {
+			switch {
+			case *budget < 0:
+				reasoning.Effort = "medium"
+			case *budget <= 4096:
+				reasoning.Effort = "low"
+			case *budget <= 16384:
+				reasoning.Effort = "medium"
+			default:
+				reasoning.Effort = "high"
+			}
+		}
+	}
+
+	if reasoning.Effort == "" && reasoning.MaxTokens == 0 {
+		return nil
+	}
+	return reasoning
+}
+
+// convertGeminiToolConfig functionCallingConfig.mode: AUTO / ANY / NONE
+func convertGeminiToolConfig(config gjson.Result) any {
+	switch strings.ToUpper(config.Get("mode").String()) {
+	case "ANY":
+		if names := config.Get("allowedFunctionNames").Array(); len(names) == 1 {
+			return map[string]any{
+				"type":     types.ToolChoiceTypeFunction,
+				"function": map[string]any{"name": names[0].String()},
+			}
+		}
+		return types.ToolChoiceTypeRequired
+	case "NONE":
+		return types.ToolChoiceTypeNone
+	default:
+		return nil
+	}
+}
+
+func convertFinishReasonToGemini(finishReason string) string {
+	switch finishReason {
+	case types.FinishReasonLength:
+		return "MAX_TOKENS"
+	case types.FinishReasonContentFilter:
+		return "SAFETY"
+	case "":
+		return ""
+	default:
+		return "STOP"
+	}
+}
+
+func convertUsageToGemini(usage *types.Usage) *gemini.GeminiUsageMetadata {
+	if usage == nil {
+		return nil
+	}
+
+	reasoningTokens := usage.CompletionTokensDetails.ReasoningTokens
+	return &gemini.GeminiUsageMetadata{
+		PromptTokenCount:        usage.PromptTokens,
+		CandidatesTokenCount:    usage.CompletionTokens - reasoningTokens,
+		ThoughtsTokenCount:      reasoningTokens,
+		TotalTokenCount:         usage.PromptTokens + usage.CompletionTokens,
+		CachedContentTokenCount: usage.PromptTokensDetails.CachedTokens,
+	}
+}
+
+func convertToolCallToGeminiPart(toolCall *types.ChatCompletionToolCalls) gemini.GeminiPart {
+	args := make(map[string]interface{})
+	if toolCall.Function.Arguments != "" {
+		if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
+			args = map[string]interface{}{}
+		}
+	}
+	return gemini.GeminiPart{
+		FunctionCall: &gemini.GeminiFunctionCall{
+			Id:   toolCall.Id,
+			Name: toolCall.Function.Name,
+			Args: args,
+		},
+	}
+}
+
+// convertOpenAIResponseToGemini 将 OpenAI Chat 响应转换为 Gemini generateContent 响应
+func convertOpenAIResponseToGemini(response *types.ChatCompletionResponse, includeThoughts bool) *gemini.GeminiChatResponse {
+	geminiResponse := &gemini.GeminiChatResponse{
+		Candidates:    make([]gemini.GeminiChatCandidate, 0, len(response.Choices)),
+		UsageMetadata: convertUsageToGemini(response.Usage),
+		ModelVersion:  response.Model,
+		ResponseId:    response.ID,
+	}
+
+	for _, choice := range response.Choices {
+		parts := make([]gemini.GeminiPart, 0)
+		if reasoning := choice.Message.GetReasoningContent(); reasoning != "" && includeThoughts {
+			parts = append(parts, gemini.GeminiPart{Text: reasoning, Thought: true})
+		}
+		if text := choice.Message.StringContent(); text != "" {
+			parts = append(parts, gemini.GeminiPart{Text: text})
+		}
+		for _, toolCall := range choice.Message.ToolCalls {
+			if toolCall.Function != nil {
+				parts = append(parts, convertToolCallToGeminiPart(toolCall))
+			}
+		}
+
+		finishReason := convertFinishReasonToGemini(choice.FinishReason)
+		geminiResponse.Candidates = append(geminiResponse.Candidates, gemini.GeminiChatCandidate{
+			Content:      gemini.GeminiChatContent{Role: "model", Parts: parts},
+			FinishReason: &finishReason,
+			Index:        int64(choice.Index),
+		})
+	}
+
+	return geminiResponse
+}
+
+// geminiStreamCandidate 流式转换中单个 candidate 的状态，工具调用的参数分片累积完整后再输出
+type geminiStreamCandidate struct {
+	toolCalls    map[int]*types.ChatCompletionToolCalls
+	finishReason string
+}
+
+// convertOpenAIStreamToGemini 将 OpenAI Chat 流转换为 Gemini 流。
+// 请求带 alt=sse 时按 SSE 输出，否则与 Gemini 一致输出流式的 JSON 数组。
+func (r *relayGeminiOnly) convertOpenAIStreamToGemini(stream requester.StreamReaderInterface[string]) (firstResponseTime time.Time) {
+	isSSE := r.c.Query("alt") == "sse"
+	if isSSE {
+		requester.SetEventStreamHeaders(r.c)
+	} else {
+		r.c.Header("Content-Type", "application/json")
+	}
+	defer stream.Close()
+
+	wroteChunk := false
+	writeChunk := func(response *gemini.GeminiChatResponse) {
+		data, err := json.Marshal(response)
+		if err != nil {
+			return
+		}
+		switch {
+		case isSSE:
+			fmt.Fprintf(r.c.Writer, "data: %s\r\n\r\n", data)
+		case !wroteChunk:
+			fmt.Fprintf(r.c.Writer, "[%s", data)
+		default:
+			fmt.Fprintf(r.c.Writer, ",\r\n%s", data)
+		}
+		wroteChunk = true
+		r.c.Writer.Flush()
+	}
+
+	var responseID, modelName string
+	candidates := make(map[int]*geminiStreamCandidate)
+	getCandidate := func(index int) *geminiStreamCandidate {
+		if candidates[index] == nil {
+			candidates[index] = &geminiStreamCandidate{toolCalls: make(map[int]*types.ChatCompletionToolCalls)}
+		}
+		return candidates[index]
+	}
+
+	dataChan, errChan := stream.Recv()
+streamLoop:
+	for {
+		select {
+		case data, ok := <-dataChan:
+			if !ok {
+				break streamLoop
+			}
+			if firstResponseTime.IsZero() {
+				firstResponseTime = time.Now()
+			}
+
+			data = strings.TrimSpace(strings.TrimPrefix(data, "data: "))
+			var chunk types.ChatCompletionStreamResponse
+			if data == "" || json.Unmarshal([]byte(data), &chunk) != nil {
+				continue
+			}
+			responseID, modelName = chunk.ID, chunk.Model
+
+			response := &gemini.GeminiChatResponse{ModelVersion: modelName, ResponseId: responseID}
+			for _, choice := range chunk.Choices {
+				candidate := getCandidate(choice.Index)
+				parts := make([]gemini.GeminiPart, 0)
+				if reasoning := choice.Delta.GetReasoningContent(); reasoning != "" && r.includeThoughts {
+					parts = append(parts, gemini.GeminiPart{Text: reasoning, Thought: true})
+				}
+				if choice.Delta.Content != "" {
+					parts = append(parts, gemini.GeminiPart{Text: choice.Delta.Content})
+				}
+				for _, toolCall := range choice.Delta.ToolCalls {
+					accumulateToolCall(candidate.toolCalls, toolCall)
+				}
+				if finishReason, ok := choice.FinishReason.(string); ok && finishReason != "" {
+					candidate.finishReason = finishReason
+				}
+
+				if len(parts) > 0 {
+					response.Candidates = append(response.Candidates, gemini.GeminiChatCandidate{
+						Content: gemini.GeminiChatContent{Role: "model", Parts: parts},
+						Index:   int64(choice.Index),
+					})
+				}
+			}
+			if len(response.Candidates) > 0 {
+				writeChunk(response)
+			}
+
+		case err := <-errChan:
+			if !errors.Is(err, io.EOF) {
+				logger.LogError(r.c.Request.Context(), "Stream err:"+err.Error())
+			}
+			break streamLoop
+		}
+	}
+
+	// 结束时输出累积的工具调用、finishReason 与 usageMetadata
+	final := &gemini.GeminiChatResponse{
+		UsageMetadata: convertUsageToGemini(r.provider.GetUsage()),
+		ModelVersion:  modelName,
+		ResponseId:    responseID,
+	}
+	indexes := make([]int, 0, len(candidates))
+	for index := range candidates {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	for _, index := range indexes {
+		candidate := candidates[index]
+		parts := make([]gemini.GeminiPart, 0, len(candidate.toolCalls))
+		toolIndexes := make([]int, 0, len(candidate.toolCalls))
+		for toolIndex := range candidate.toolCalls {
+			toolIndexes = append(toolIndexes, toolIndex)
+		}
+		sort.Ints(toolIndexes)
+		for _, toolIndex := range toolIndexes {
+			parts = append(parts, convertToolCallToGeminiPart(candidate.toolCalls[toolIndex]))
+		}
+
+		finishReason := convertFinishReasonToGemini(candidate.finishReason)
+		if finishReason == "" {
+			finishReason = "STOP"
+		}
+		final.Candidates = append(final.Candidates, gemini.GeminiChatCandidate{
+			Content:      gemini.GeminiChatContent{Role: "model", Parts: parts},
+			FinishReason: &finishReason,
+			Index:        int64(index),
+		})
+	}
+	writeChunk(final)
+
+	if !isSSE {
+		fmt.Fprint(r.c.Writer, "]")
+		r.c.Writer.Flush()
+	}
+	return
+}
+
+// accumulateToolCall 按 index 合并流式工具调用的 id、名称与参数分片
+func accumulateToolCall(toolCalls map[int]*types.ChatCompletionToolCalls, delta *types.ChatCompletionToolCalls) {
+	toolCall, ok := toolCalls[delta.Index]
+	if !ok {
+		toolCall = &types.ChatCompletionToolCalls{
+			Index:    delta.Index,
+			Type:     types.ToolChoiceTypeFunction,
+			Function: &types.ChatCompletionToolCallsFunction{},
+		}
+		toolCalls[delta.Index] = toolCall
+	}
+	if delta.Id != "" {
+		toolCall.Id = delta.Id
+	}
+	if delta.Function != nil {
+		if delta.Function.Name != "" {
+			toolCall.Function.Name = delta.Function.Name
+		}
+		toolCall.Function.Arguments += delta.Function.Arguments
+	}
+}
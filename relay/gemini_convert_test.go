@@ -0,0 +1,353 @@
+package relay
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"done-hub/common/config"
+	"done-hub/common/logger"
+	"done-hub/common/requester"
+	"done-hub/model"
+	"done-hub/providers"
+	"done-hub/providers/gemini"
+	"done-hub/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"go.uber.org/zap"
+)
+
+// TestConvertGeminiToOpenAI 测试 Gemini generateContent 请求转换为 OpenAI Chat 请求
+func TestConvertGeminiToOpenAI(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		stream    bool
+		expected  map[string]string
+		forbidden []string
+	}{
+		{
+			name: "系统提示、多轮对话与生成参数",
+			body: `{"systemInstruction":{"parts":[{"text":"be brief"},{"text":"no emoji"}]},
+				"contents":[{"role":"user","parts":[{"text":"hi"}]},{"role":"model","parts":[{"text":"hello"}]}],
+				"generationConfig":{"temperature":0.5,"maxOutputTokens":100,"stopSequences":["END"],"candidateCount":2}}`,
+			expected: map[string]string{
+				"messages.0.role":    "system",
+				"messages.0.content": "be brief\nno emoji",
+				"messages.1.role":    "user",
+				"messages.1.content": "hi",
+				"messages.2.role":    "assistant",
+				"messages.2.content": "hello",
+				"temperature":        "0.5",
+				"max_tokens":         "100",
+				"stop.0":             "END",
+				"n":                  "2",
+			},
+			forbidden: []string{"stream_options", "reasoning", "tools"},
+		},
+		{
+			name:   "字符串形式的 systemInstruction 与流式",
+			body:   `{"systemInstruction":"be brief","contents":[{"role":"user","parts":[{"text":"hi"}]}]}`,
+			stream: true,
+			expected: map[string]string{
+				"messages.0.content":           "be brief",
+				"stream":                       "true",
+				"stream_options.include_usage": "true",
+			},
+		},
+		{
+			name: "图片与音频转换为多模态内容",
+			body: `{"contents":[{"role":"user","parts":[{"text":"look"},
+				{"inlineData":{"mimeType":"image/png","data":"AAAA"}},
+				{"inlineData":{"mimeType":"audio/mpeg","data":"BBBB"}}]}]}`,
+			expected: map[string]string{
+				"messages.0.content.0.text":               "look",
+				"messages.0.content.1.image_url.url":      "data:image/png;base64,AAAA",
+				"messages.0.content.2.type":               "input_audio",
+				"messages.0.content.2.input_audio.format": "mp3",
+			},
+		},
+		{
+			name: "functionResponse 按函数名对应之前的 tool_call_id",
+			body: `{"contents":[{"role":"user","parts":[{"text":"weather?"}]},
+				{"role":"model","parts":[{"functionCall":{"id":"call_1","name":"get_weather","args":{"city":"Paris"}}}]},
+				{"role":"user","parts":[{"functionResponse":{"name":"get_weather","response":{"temp":20}}}]}]}`,
+			expected: map[string]string{
+				"messages.1.tool_calls.0.id":                 "call_1",
+				"messages.1.tool_calls.0.function.name":      "get_weather",
+				"messages.1.tool_calls.0.function.arguments": `{"city":"Paris"}`,
+				"messages.2.role":                            "tool",
+				"messages.2.tool_call_id":                    "call_1",
+				"messages.2.content":                         `{"temp":20}`,
+			},
+		},
+		{
+			name: "JSON Schema 输出转换类型与 nullable",
+			body: `{"contents":[{"role":"user","parts":[{"text":"hi"}]}],"generationConfig":{"responseMimeType":"application/json",
+				"responseSchema":{"type":"OBJECT","properties":{"name":{"type":"STRING","nullable":true}},"propertyOrdering":["name"]}}}`,
+			expected: map[string]string{
+				"response_format.type":                                    "json_schema",
+				"response_format.json_schema.schema.type":                 "object",
+				"response_format.json_schema.schema.properties.name.type": `["string","null"]`,
+			},
+			forbidden: []string{"response_format.json_schema.schema.propertyOrdering"},
+		},
+		{
+			name: "工具声明与 toolConfig",
+			body: `{"contents":[{"role":"user","parts":[{"text":"hi"}]}],
+				"tools":[{"functionDeclarations":[{"name":"lookup","description":"d"},
+					{"name":"search","parametersJsonSchema":{"type":"object","properties":{"q":{"type":"string"}}}}]}],
+				"toolConfig":{"functionCallingConfig":{"mode":"ANY","allowedFunctionNames":["search"]}}}`,
+			expected: map[string]string{
+				"tools.0.function.name":                         "lookup",
+				"tools.0.function.parameters.type":              "object",
+				"tools.1.function.parameters.properties.q.type": "string",
+				"tool_choice.type":                              "function",
+				"tool_choice.function.name":                     "search",
+			},
+		},
+		{
+			name: "thinkingBudget 小于最大输出时透传",
+			body: `{"contents":[{"role":"user","parts":[{"text":"hi"}]}],
+				"generationConfig":{"maxOutputTokens":8192,"thinkingConfig":{"thinkingBudget":2048}}}`,
+			expected: map[string]string{
+				"reasoning.effort":     "low",
+				"reasoning.max_tokens": "2048",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, errWithCode := convertGeminiToOpenAI([]byte(tt.body), tt.stream)
+			if errWithCode != nil {
+				t.Fatalf("转换失败: %v", errWithCode.Message)
+			}
+			body, _ := json.Marshal(request)
+			for path, want := range tt.expected {
+				if got := gjson.GetBytes(body, path); got.String() != want && got.Raw != want {
+					t.Errorf("%s = %s, 期望 %s", path, got.Raw, want)
+				}
+			}
+			for _, path := range tt.forbidden {
+				if gjson.GetBytes(body, path).Exists() {
+					t.Errorf("不应包含 %s: %s", path, body)
+				}
+			}
+		})
+	}
+}
+
+// TestConvertGeminiToOpenAIInvalid 测试无法转换的请求返回 400
+func TestConvertGeminiToOpenAIInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "非法 JSON", body: `{"contents":`},
+		{name: "不支持的 fileData", body: `{"contents":[{"role":"user","parts":[{"fileData":{"mimeType":"video/mp4","fileUri":"gs://a/b"}}]}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errWithCode := convertGeminiToOpenAI([]byte(tt.body), false)
+			if errWithCode == nil || errWithCode.StatusCode != 400 {
+				t.Fatalf("期望返回 400 错误, 实际 %v", errWithCode)
+			}
+		})
+	}
+}
+
+// TestConvertGeminiThinkingConfig 测试 thinkingConfig 转换为 reasoning
+func TestConvertGeminiThinkingConfig(t *testing.T) {
+	budget := func(value int) *int { return &value }
+	tests := []struct {
+		name      string
+		config    *gemini.ThinkingConfig
+		maxTokens int
+		expected  *types.ChatReasoning
+	}{
+		{name: "未设置", config: nil, expected: nil},
+		{name: "budget 为 0 关闭思考", config: &gemini.ThinkingConfig{ThinkingBudget: budget(0)}, expected: nil},
+		{name: "动态 budget", config: &gemini.ThinkingConfig{ThinkingBudget: budget(-1)}, expected: &types.ChatReasoning{Effort: "medium"}},
+		{name: "中等 budget", config: &gemini.ThinkingConfig{ThinkingBudget: budget(8192)}, expected: &types.ChatReasoning{Effort: "medium", MaxTokens: 8192}},
+		{name: "budget 超过最大输出时不透传", config: &gemini.ThinkingConfig{ThinkingBudget: budget(32768)}, maxTokens: 4096, expected: &types.ChatReasoning{Effort: "high"}},
+		{name: "thinkingLevel 优先", config: &gemini.ThinkingConfig{ThinkingLevel: "LOW", ThinkingBudget: budget(32768)}, expected: &types.ChatReasoning{Effort: "low", MaxTokens: 32768}},
+		{name: "只有 includeThoughts", config: &gemini.ThinkingConfig{IncludeThoughts: true}, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := convertGeminiThinkingConfig(tt.config, tt.maxTokens)
+			if (got == nil) != (tt.expected == nil) {
+				t.Fatalf("得到 %+v, 期望 %+v", got, tt.expected)
+			}
+			if got != nil && (got.Effort != tt.expected.Effort || got.MaxTokens != tt.expected.MaxTokens) {
+				t.Errorf("得到 %+v, 期望 %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestConvertOpenAIResponseToGemini 测试 OpenAI Chat 响应转换为 Gemini 响应
+func TestConvertOpenAIResponseToGemini(t *testing.T) {
+	response := &types.ChatCompletionResponse{
+		ID:    "chatcmpl-1",
+		Model: "gpt-4o",
+		Choices: []types.ChatCompletionChoice{
+			{
+				Index: 0,
+				Message: types.ChatCompletionMessage{
+					Role:             types.ChatMessageRoleAssistant,
+					Content:          "it is sunny",
+					ReasoningContent: "check weather",
+					ToolCalls: []*types.ChatCompletionToolCalls{
+						{Id: "call_1", Type: "function", Function: &types.ChatCompletionToolCallsFunction{Name: "get_weather", Arguments: `{"city":"Paris"}`}},
+					},
+				},
+				FinishReason: types.FinishReasonToolCalls,
+			},
+			{
+				Index:        1,
+				Message:      types.ChatCompletionMessage{Role: types.ChatMessageRoleAssistant, Content: "truncated"},
+				FinishReason: types.FinishReasonLength,
+			},
+		},
+		Usage: &types.Usage{
+			PromptTokens:            10,
+			CompletionTokens:        30,
+			PromptTokensDetails:     types.PromptTokensDetails{CachedTokens: 4},
+			CompletionTokensDetails: types.CompletionTokensDetails{ReasoningTokens: 12},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		includeThoughts bool
+		expected        map[string]string
+		forbidden       []string
+	}{
+		{
+			name:            "包含思考内容",
+			includeThoughts: true,
+			expected: map[string]string{
+				"responseId":                                          "chatcmpl-1",
+				"modelVersion":                                        "gpt-4o",
+				"candidates.0.content.role":                           "model",
+				"candidates.0.content.parts.0.text":                   "check weather",
+				"candidates.0.content.parts.0.thought":                "true",
+				"candidates.0.content.parts.1.text":                   "it is sunny",
+				"candidates.0.content.parts.2.functionCall.name":      "get_weather",
+				"candidates.0.content.parts.2.functionCall.args.city": "Paris",
+				"candidates.0.finishReason":                           "STOP",
+				"candidates.1.finishReason":                           "MAX_TOKENS",
+				"usageMetadata.promptTokenCount":                      "10",
+				"usageMetadata.candidatesTokenCount":                  "18",
+				"usageMetadata.thoughtsTokenCount":                    "12",
+				"usageMetadata.totalTokenCount":                       "40",
+				"usageMetadata.cachedContentTokenCount":               "4",
+			},
+		},
+		{
+			name: "不包含思考内容",
+			expected: map[string]string{
+				"candidates.0.content.parts.0.text":              "it is sunny",
+				"candidates.0.content.parts.1.functionCall.name": "get_weather",
+			},
+			forbidden: []string{"candidates.0.content.parts.2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(convertOpenAIResponseToGemini(response, tt.includeThoughts))
+			for path, want := range tt.expected {
+				if got := gjson.GetBytes(body, path).String(); got != want {
+					t.Errorf("%s = %s, 期望 %s", path, got, want)
+				}
+			}
+			for _, path := range tt.forbidden {
+				if gjson.GetBytes(body, path).Exists() {
+					t.Errorf("不应包含 %s: %s", path, body)
+				}
+			}
+		})
+	}
+}
+
+// TestGeminiRetryToOpenAIChannel 测试 Gemini 渠道失败后 retry 到 OpenAI 渠道：
+// Gemini provider 会释放原始请求体，转换后的 OpenAI 请求需要在 setRequest 中提前准备
+func TestGeminiRetryToOpenAIChannel(t *testing.T) {
+	if logger.Logger == nil {
+		logger.Logger = zap.NewNop()
+	}
+	if requester.HTTPClient == nil {
+		requester.InitHttpClient()
+	}
+	gin.SetMode(gin.TestMode)
+
+	geminiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":{"code":500,"message":"internal error","status":"INTERNAL"}}`))
+	}))
+	defer geminiServer.Close()
+
+	var openaiBody string
+	openaiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		openaiBody = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","created":1,"model":"gpt-4o",
+			"choices":[{"index":0,"message":{"role":"assistant","content":"hello"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`))
+	}))
+	defer openaiServer.Close()
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1beta/models/gemini-2.5-flash:generateContent",
+		strings.NewReader(`{"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "model", Value: "gemini-2.5-flash:generateContent"}}
+
+	relay := NewRelayGeminiOnly(c)
+	if err := relay.setRequest(); err != nil {
+		t.Fatalf("setRequest() error = %v", err)
+	}
+
+	geminiURL := geminiServer.URL
+	relay.provider = providers.GetProvider(&model.Channel{Id: 1, Type: config.ChannelTypeGemini, Key: "gemini-key", BaseURL: &geminiURL}, c)
+	relay.provider.SetUsage(&types.Usage{})
+	relay.modelName = "gemini-2.5-flash"
+	apiErr, done := relay.send()
+	if apiErr == nil {
+		t.Fatal("Gemini 渠道 send() 期望失败")
+	}
+	if done {
+		t.Fatalf("Gemini 渠道失败后 done = true，期望可以 retry，error = %v", apiErr.Message)
+	}
+
+	openaiURL := openaiServer.URL
+	relay.provider = providers.GetProvider(&model.Channel{Id: 2, Type: config.ChannelTypeOpenAI, Key: "sk-test", BaseURL: &openaiURL}, c)
+	relay.provider.SetUsage(&types.Usage{})
+	relay.modelName = "gpt-4o"
+	apiErr, done = relay.send()
+	if apiErr != nil {
+		t.Fatalf("OpenAI 渠道 send() error = %v, done = %v", apiErr.Message, done)
+	}
+
+	if got := gjson.Get(openaiBody, "model").String(); got != "gpt-4o" {
+		t.Errorf("上游请求 model = %q, 期望 gpt-4o", got)
+	}
+	if got := gjson.Get(openaiBody, "messages.0.content").String(); got != "hi" {
+		t.Errorf("上游请求 messages.0.content = %q, 期望 hi", got)
+	}
+	if got := gjson.Get(recorder.Body.String(), "candidates.0.content.parts.0.text").String(); got != "hello" {
+		t.Errorf("响应 candidates 文本 = %q, 期望 hello，响应 = %s", got, recorder.Body.String())
+	}
+}
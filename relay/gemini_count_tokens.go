@@ -0,0 +1,112 @@
+package relay
+
+import (
+	"done-hub/common"
+	"done-hub/common/config"
+	"done-hub/common/logger"
+	"done-hub/providers/gemini"
+	"done-hub/types"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+// RelayGemini 按 :model 后缀分发 Gemini 原生请求，countTokens 不计费，单独处理
+func RelayGemini(c *gin.Context) {
+	if strings.HasSuffix(c.Param("model"), ":countTokens") {
+		RelayGeminiCountTokens(c)
+		return
+	}
+	Relay(c)
+}
+
+// RelayGeminiCountTokens 处理 /gemini/:version/models/:model:countTokens。
+// 与 Claude count_tokens 一样不走 RelayHandler：不预扣费、不记消费日志，也不触发渠道冷却与自动禁用。
+func RelayGeminiCountTokens(c *gin.Context) {
+	defer func() {
+		c.Set(config.GinRequestBodyKey, nil)
+	}()
+
+	relay := NewRelayGeminiOnly(c)
+	if err := relay.setRequest(); err != nil {
+		relay.HandleJsonError(common.StringErrorWrapperLocal(err.Error(), "invalid_request_error", http.StatusBadRequest))
+		return
+	}
+	// 本地估算兜底，任何能处理 Gemini 对话的渠道都可以承接
+	c.Set("allow_channel_type", allowGeminiChatChannelType)
+
+	if err := relay.setProvider(relay.getOriginalModel()); err != nil {
+		if IsModelNotFound(err) {
+			relay.HandleJsonError(common.ModelNotFoundError(relay.getOriginalModel()))
+		} else {
+			relay.HandleJsonError(common.UpstreamUnavailableError(err.Error()))
+		}
+		return
+	}
+
+	response, apiErr := relay.countTokens()
+	if apiErr != nil {
+		relay.HandleJsonError(apiErr)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// countTokens 原生 Gemini 渠道优先调用上游 countTokens；转换渠道或上游失败（400 除外）时回退本地估算
+func (r *relayGeminiOnly) countTokens() (*gemini.GeminiChatResponse, *types.OpenAIErrorWithStatusCode) {
+	channel := r.provider.GetChannel()
+	chatProvider, ok := r.provider.(gemini.GeminiChatInterface)
+	if !ok || !slices.Contains(AllowGeminiChannelType, channel.Type) {
+		return &gemini.GeminiChatResponse{TotalTokens: countGeminiInputTokens(r.requestBody, r.modelName)}, nil
+	}
+
+	r.geminiRequest.Model = r.modelName
+	response, apiErr := chatProvider.CreateGeminiChat(r.geminiRequest)
+	if apiErr == nil {
+		return response, nil
+	}
+
+	// 400 是请求本身的问题，对客户端有意义，原样返回
+	if apiErr.StatusCode == http.StatusBadRequest {
+		return nil, apiErr
+	}
+
+	logger.LogWarn(r.c.Request.Context(), fmt.Sprintf("count_tokens_fallback channel_id=%d model=%s status_code=%d error=\"%s\"",
+		channel.Id, r.modelName, apiErr.StatusCode, apiErr.Message))
+
+	return &gemini.GeminiChatResponse{TotalTokens: countGeminiInputTokens(r.requestBody, r.modelName)}, nil
+}
+
+// countGeminiInputTokens 本地估算完整输入 token：contents 之外补上 systemInstruction 与 tools。
+// countTokens 请求既可以直接带 contents，也可以包在 generateContentRequest 里。
+func countGeminiInputTokens(requestBody []byte, model string) int {
+	request := gjson.ParseBytes(requestBody)
+	if generateRequest := request.Get("generateContentRequest"); generateRequest.Exists() {
+		request = generateRequest
+	}
+
+	body := []byte(request.Raw)
+	tokenNum, _ := countGeminiTokenMessagesFromBytes(body, model, config.PreCostDefault)
+	tokenEncoder := common.GetTokenEncoder(model)
+
+	systemInstruction := request.Get("systemInstruction")
+	if !systemInstruction.Exists() {
+		systemInstruction = request.Get("system_instruction")
+	}
+	for _, part := range systemInstruction.Get("parts").Array() {
+		if text := part.Get("text").String(); text != "" {
+			tokenNum += common.GetTokenNum(tokenEncoder, text)
+		}
+	}
+
+	if tools := request.Get("tools"); tools.Exists() {
+		tokenNum += common.GetTokenNum(tokenEncoder, tools.Raw)
+	}
+
+	return tokenNum
+}
@@ -0,0 +1,165 @@
+package relay
+
+import (
+	"done-hub/common"
+	"done-hub/common/config"
+	providersBase "done-hub/providers/base"
+	"done-hub/providers/gemini"
+	"done-hub/safty"
+	"done-hub/types"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// relayGeminiEmbeddings 处理 Gemini 原生 :embedContent 与 :batchEmbedContents。
+// Gemini 渠道直接调用 batchEmbedContents，其它渠道转换为 OpenAI /v1/embeddings 请求，计费与 /v1/embeddings 一致。
+type relayGeminiEmbeddings struct {
+	relayBase
+	batch   bool
+	request *gemini.GeminiBatchEmbedRequest
+}
+
+func NewRelayGeminiEmbeddings(c *gin.Context) *relayGeminiEmbeddings {
+	relay := &relayGeminiEmbeddings{}
+	relay.c = c
+	return relay
+}
+
+func (r *relayGeminiEmbeddings) setRequest() error {
+	modelList := strings.Split(r.c.Param("model"), ":")
+	if len(modelList) != 2 {
+		return errors.New("model error")
+	}
+	r.batch = modelList[1] == "batchEmbedContents"
+
+	r.request = &gemini.GeminiBatchEmbedRequest{}
+	if r.batch {
+		if err := common.UnmarshalBodyReusable(r.c, r.request); err != nil {
+			return err
+		}
+	} else {
+		request := gemini.GeminiEmbedContentRequest{}
+		if err := common.UnmarshalBodyReusable(r.c, &request); err != nil {
+			return err
+		}
+		r.request.Requests = []gemini.GeminiEmbedContentRequest{request}
+	}
+	if len(r.request.Requests) == 0 {
+		return errors.New("requests is required")
+	}
+
+	r.setOriginalModel(modelList[0])
+	r.c.Set("original_model", modelList[0])
+
+	return nil
+}
+
+func (r *relayGeminiEmbeddings) getRequest() interface{} {
+	return r.request
+}
+
+func (r *relayGeminiEmbeddings) getPromptTokens() (int, error) {
+	return common.CountTokenInput(r.getTexts(), r.modelName), nil
+}
+
+func (r *relayGeminiEmbeddings) getTexts() []string {
+	texts := make([]string, 0, len(r.request.Requests))
+	for i := range r.request.Requests {
+		texts = append(texts, r.request.Requests[i].GetText())
+	}
+	return texts
+}
+
+func (r *relayGeminiEmbeddings) send() (err *types.OpenAIErrorWithStatusCode, done bool) {
+	// 内容审查
+	if config.EnableSafe {
+		CheckResult, _ := safty.CheckContent(r.getTexts())
+		if !CheckResult.IsSafe {
+			err = common.StringErrorWrapperLocal(CheckResult.Reason, CheckResult.Code, http.StatusBadRequest)
+			done = true
+			return
+		}
+	}
+
+	var embeddings []gemini.GeminiEmbedding
+	// GeminiProvider 内嵌了 OpenAIProvider，必须先按渠道类型判断，走原生接口
+	if geminiProvider, ok := r.provider.(*gemini.GeminiProvider); ok && r.provider.GetChannel().Type == config.ChannelTypeGemini {
+		var response *gemini.GeminiBatchEmbedResponse
+		response, err = geminiProvider.CreateGeminiEmbeddings(r.request, r.modelName)
+		if err != nil {
+			return
+		}
+		embeddings = response.Embeddings
+	} else if provider, ok := r.provider.(providersBase.EmbeddingsInterface); ok {
+		embeddings, err = r.sendWithOpenAIFormat(provider)
+		if err != nil {
+			return
+		}
+	} else {
+		err = common.StringErrorWrapperLocal("channel not implemented", "channel_error", http.StatusServiceUnavailable)
+		done = true
+		return
+	}
+
+	if r.batch {
+		err = responseJsonClient(r.c, &gemini.GeminiBatchEmbedResponse{Embeddings: embeddings})
+	} else {
+		err = responseJsonClient(r.c, &gemini.GeminiEmbedContentResponse{Embedding: &embeddings[0]})
+	}
+
+	if err != nil {
+		done = true
+	}
+	return
+}
+
+// sendWithOpenAIFormat 转换为 OpenAI embeddings 请求；outputDimensionality 对应 dimensions，taskType 与 title 无对应参数
+func (r *relayGeminiEmbeddings) sendWithOpenAIFormat(provider providersBase.EmbeddingsInterface) ([]gemini.GeminiEmbedding, *types.OpenAIErrorWithStatusCode) {
+	request := &types.EmbeddingRequest{
+		Model:      r.modelName,
+		Input:      r.getTexts(),
+		Dimensions: r.request.Requests[0].OutputDimensionality,
+	}
+
+	response, err := provider.CreateEmbeddings(request)
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Data) != len(r.request.Requests) {
+		return nil, common.StringErrorWrapper("embeddings count mismatch", "no_embeddings", http.StatusInternalServerError)
+	}
+
+	embeddings := make([]gemini.GeminiEmbedding, len(response.Data))
+	for _, data := range response.Data {
+		if data.Index >= 0 && data.Index < len(embeddings) {
+			embeddings[data.Index].Values = data.Embedding
+		}
+	}
+	return embeddings, nil
+}
+
+func (r *relayGeminiEmbeddings) GetError(err *types.OpenAIErrorWithStatusCode) (int, any) {
+	newErr := FilterOpenAIErr(r.c, err)
+
+	geminiErr := gemini.OpenaiErrToGeminiErr(&newErr)
+
+	return newErr.StatusCode, geminiErr.GeminiErrorResponse
+}
+
+func (r *relayGeminiEmbeddings) HandleJsonError(err *types.OpenAIErrorWithStatusCode) {
+	statusCode, response := r.GetError(err)
+	r.c.JSON(statusCode, response)
+}
+
+func (r *relayGeminiEmbeddings) HandleStreamError(err *types.OpenAIErrorWithStatusCode) {
+	str, jsonErr := json.Marshal(err)
+	if jsonErr != nil {
+		return
+	}
+	r.c.Writer.Write([]byte("data: " + string(str) + "\n\n"))
+	r.c.Writer.Flush()
+}
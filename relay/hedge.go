@@ -0,0 +1,398 @@
+package relay
+
+import (
+	"context"
+	"done-hub/common"
+	"done-hub/common/config"
+	"done-hub/common/logger"
+	"done-hub/common/utils"
+	"done-hub/model"
+	providersBase "done-hub/providers/base"
+	"done-hub/relay/relay_util"
+	"done-hub/types"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// hedgeAttemptKey 对冲中每个尝试的上下文里保存各自的 *hedgeWriter，供 RelayHandler 判断是否落败
+const hedgeAttemptKey = "hedge_attempt"
+
+var errHedgeLost = errors.New("hedged request lost the race")
+
+const (
+	hedgeAttemptPending int32 = iota
+	hedgeAttemptWon
+	hedgeAttemptLost
+	hedgeAttemptFinished
+)
+
+// hedgeRace 主请求与对冲请求的竞争状态，先向客户端写出响应的一方胜出
+type hedgeRace struct {
+	mu       sync.Mutex
+	delay    time.Duration
+	record   *relay_util.HedgeRecord
+	winner   *hedgeWriter
+	attempts []*hedgeWriter
+	claimed  chan struct{}
+}
+
+// hedgeWriter 对冲中单个尝试的 ResponseWriter。首次写出时争夺胜出权，胜出后直接写给客户端；
+// 落败后写入返回错误，并取消该尝试的上游请求。争夺前设置的响应头暂存在本地，胜出时再写入。
+type hedgeWriter struct {
+	gin.ResponseWriter
+	race      *hedgeRace
+	name      string
+	cancel    context.CancelFunc
+	header    http.Header
+	status    int
+	statusSet bool
+	state     atomic.Int32
+}
+
+type hedgeResult struct {
+	relay  RelayBaseInterface
+	writer *hedgeWriter
+	apiErr *types.OpenAIErrorWithStatusCode
+	done   bool
+	lost   bool
+}
+
+func (race *hedgeRace) newWriter(name string, writer gin.ResponseWriter) *hedgeWriter {
+	return &hedgeWriter{
+		ResponseWriter: writer,
+		race:           race,
+		name:           name,
+		cancel:         func() {},
+		header:         make(http.Header),
+		status:         http.StatusOK,
+	}
+}
+
+// join 加入竞争，已有一方开始输出时返回 false。
+// 上游请求默认不随客户端断开而取消，这里换成可取消的 context，落败后中断上游请求；
+// 不经 Requester 发送的 provider 无法中断，落败后结果会被丢弃。
+func (race *hedgeRace) join(w *hedgeWriter, provider providersBase.ProviderInterface) bool {
+	race.mu.Lock()
+	defer race.mu.Unlock()
+
+	if race.winner != nil {
+		return false
+	}
+
+	if requester := provider.GetRequester(); requester != nil && requester.Context != nil {
+		ctx, cancel := context.WithCancel(requester.Context)
+		requester.Context = ctx
+		w.cancel = cancel
+	}
+	for key, values := range w.ResponseWriter.Header() {
+		w.header[key] = append([]string(nil), values...)
+	}
+	race.attempts = append(race.attempts, w)
+	return true
+}
+
+func (race *hedgeRace) getWinner() *hedgeWriter {
+	race.mu.Lock()
+	defer race.mu.Unlock()
+	return race.winner
+}
+
+// close 竞争结束后释放各尝试的 context
+func (race *hedgeRace) close() {
+	race.mu.Lock()
+	defer race.mu.Unlock()
+	for _, attempt := range race.attempts {
+		attempt.cancel()
+	}
+}
+
+// claim 争夺胜出权，胜出时把暂存的响应头写入客户端的响应，并取消其他未结束的尝试
+func (w *hedgeWriter) claim() bool {
+	switch w.state.Load() {
+	case hedgeAttemptWon:
+		return true
+	case hedgeAttemptLost:
+		return false
+	}
+
+	race := w.race
+	race.mu.Lock()
+	defer race.mu.Unlock()
+
+	if race.winner != nil || !w.state.CompareAndSwap(hedgeAttemptPending, hedgeAttemptWon) {
+		return w.state.Load() == hedgeAttemptWon
+	}
+	race.winner = w
+	for _, other := range race.attempts {
+		if other != w && other.state.CompareAndSwap(hedgeAttemptPending, hedgeAttemptLost) {
+			other.cancel()
+		}
+	}
+
+	header := w.ResponseWriter.Header()
+	for key, values := range w.header {
+		header[key] = values
+	}
+	if w.statusSet {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	race.record.SetWinner(w.name)
+	close(race.claimed)
+	return true
+}
+
+// finish 尝试结束，返回它是否已落败
+func (w *hedgeWriter) finish() bool {
+	w.state.CompareAndSwap(hedgeAttemptPending, hedgeAttemptFinished)
+	return w.state.Load() == hedgeAttemptLost
+}
+
+func (w *hedgeWriter) won() bool {
+	return w.state.Load() == hedgeAttemptWon
+}
+
+func (w *hedgeWriter) Header() http.Header {
+	if w.won() {
+		return w.ResponseWriter.Header()
+	}
+	return w.header
+}
+
+func (w *hedgeWriter) WriteHeader(code int) {
+	if w.won() {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.status = code
+	w.statusSet = true
+}
+
+func (w *hedgeWriter) WriteHeaderNow() {
+	if w.claim() {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *hedgeWriter) Write(data []byte) (int, error) {
+	if !w.claim() {
+		return 0, errHedgeLost
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *hedgeWriter) WriteString(s string) (int, error) {
+	if !w.claim() {
+		return 0, errHedgeLost
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *hedgeWriter) Flush() {
+	if w.claim() {
+		w.ResponseWriter.Flush()
+	}
+}
+
+func (w *hedgeWriter) Status() int {
+	if w.won() {
+		return w.ResponseWriter.Status()
+	}
+	return w.status
+}
+
+func (w *hedgeWriter) Size() int {
+	if w.won() {
+		return w.ResponseWriter.Size()
+	}
+	return -1
+}
+
+func (w *hedgeWriter) Written() bool {
+	if w.won() {
+		return w.ResponseWriter.Written()
+	}
+	return false
+}
+
+// isHedgeLoser 当前尝试是否是被取消的对冲落败方
+func isHedgeLoser(c *gin.Context) bool {
+	w, ok := utils.GetGinValue[*hedgeWriter](c, hedgeAttemptKey)
+	return ok && w != nil && w.state.Load() == hedgeAttemptLost
+}
+
+// hedgeDelay 令牌设置的对冲阈值优先，未设置时使用分组的设置
+func hedgeDelay(c *gin.Context) time.Duration {
+	delay := 0
+	if value, exists := c.Get("token_setting"); exists {
+		if setting, ok := value.(*model.TokenSetting); ok && setting != nil {
+			delay = setting.HedgeDelay
+		}
+	}
+	if delay <= 0 {
+		delay = model.GlobalUserGroupRatio.GetHedgeDelay(c.GetString("token_group"))
+	}
+
+	return time.Duration(delay) * time.Millisecond
+}
+
+// relayWithHedge 执行首次尝试。启用对冲时，主请求超过阈值仍未开始输出，就向候选列表中的另一个渠道发出相同请求，
+// 先开始输出的一方胜出，另一方被取消且不计费。返回代表最终结果的 relay，对冲请求胜出时为对冲请求的 relay。
+func relayWithHedge(relay RelayBaseInterface, heartbeat *relay_util.Heartbeat) (RelayBaseInterface, *types.OpenAIErrorWithStatusCode, bool) {
+	c := relay.getContext()
+	delay := hedgeDelay(c)
+	// 心跳会在首字之前写出响应，与按首字判断胜负冲突；指定渠道的请求没有可对冲的候选
+	if delay <= 0 || heartbeat != nil || c.GetInt("specific_channel_id") > 0 {
+		apiErr, done := RelayHandler(relay)
+		return relay, apiErr, done
+	}
+
+	record := relay_util.NewHedgeRecord(delay, relay.getProvider().GetChannel().Id)
+	c.Set(config.GinHedgeKey, record)
+	// 对冲请求使用主请求开始前的上下文副本，主请求运行期间不能再读写 c
+	hc := c.Copy()
+
+	race := &hedgeRace{delay: delay, record: record, claimed: make(chan struct{})}
+	writer := c.Writer
+	primary := race.newWriter(relay_util.HedgeWinnerPrimary, writer)
+	race.join(primary, relay.getProvider())
+	c.Writer = primary
+	c.Set(hedgeAttemptKey, primary)
+	defer func() {
+		c.Writer = writer
+		c.Set(hedgeAttemptKey, nil)
+		race.close()
+	}()
+
+	results := make(chan hedgeResult, 2)
+	runHedgeAttempt(relay, primary, results)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case result := <-results:
+		return result.relay, result.apiErr, result.done
+	case <-race.claimed:
+		result := <-results
+		return result.relay, result.apiErr, result.done
+	case <-timer.C:
+	}
+
+	if !startHedge(hc, relay, race, writer, results) {
+		result := <-results
+		return result.relay, result.apiErr, result.done
+	}
+
+	primaryResult, hedgeResult := <-results, <-results
+	if primaryResult.writer != primary {
+		primaryResult, hedgeResult = hedgeResult, primaryResult
+	}
+	return finishHedge(c, hc, race, primaryResult, hedgeResult)
+}
+
+// startHedge 排除主请求的渠道后重新选择渠道并发出对冲请求，没有可用渠道或主请求已开始输出时返回 false
+func startHedge(hc *gin.Context, relay RelayBaseInterface, race *hedgeRace, writer gin.ResponseWriter, results chan hedgeResult) bool {
+	primaryChannelId := relay.getProvider().GetChannel().Id
+	skipChannelIds, _ := utils.GetGinValue[[]int](hc, "skip_channel_ids")
+	hc.Set("skip_channel_ids", append(append([]int{}, skipChannelIds...), primaryChannelId))
+	// 副本里的并发名额属于主请求，不能被对冲请求释放
+	hc.Set(config.GinChannelLeaseKey, nil)
+
+	hedge := race.newWriter(relay_util.HedgeWinnerHedge, writer)
+	hc.Writer = hedge
+	hc.Set(hedgeAttemptKey, hedge)
+
+	hedgeRelay := Path2Relay(hc, hc.Request.URL.Path)
+	if err := hedgeRelay.setRequest(); err != nil {
+		return false
+	}
+	if err := hedgeRelay.setProvider(relay.getOriginalModel()); err != nil {
+		model.ReleaseChannelLease(hc)
+		logger.LogInfo(hc.Request.Context(), fmt.Sprintf("hedge_skip model=%s primary_channel_id=%d error=\"%s\"",
+			relay.getOriginalModel(), primaryChannelId, err.Error()))
+		return false
+	}
+
+	channel := hedgeRelay.getProvider().GetChannel()
+	if !race.join(hedge, hedgeRelay.getProvider()) {
+		releaseChannelAttempt(hc, channel)
+		return false
+	}
+
+	race.record.Fire(channel.Id)
+	logger.LogInfo(hc.Request.Context(), fmt.Sprintf("hedge_fired model=%s primary_channel_id=%d hedge_channel_id=%d delay_ms=%d",
+		relay.getOriginalModel(), primaryChannelId, channel.Id, race.delay.Milliseconds()))
+	runHedgeAttempt(hedgeRelay, hedge, results)
+	return true
+}
+
+func runHedgeAttempt(relay RelayBaseInterface, writer *hedgeWriter, results chan<- hedgeResult) {
+	go func() {
+		result := hedgeResult{relay: relay, writer: writer}
+		defer func() {
+			if r := recover(); r != nil {
+				logger.SysError(fmt.Sprintf("hedge attempt panic: %v, stack: %s", r, string(debug.Stack())))
+				result.apiErr = common.StringErrorWrapperLocal(fmt.Sprintf("%v", r), "one_hub_error", http.StatusInternalServerError)
+				result.done = true
+			}
+			result.lost = writer.finish()
+			results <- result
+		}()
+		result.apiErr, result.done = RelayHandler(relay)
+	}()
+}
+
+// finishHedge 两个尝试都结束后汇总结果。有胜出方时以胜出方为准，先于胜出方出错结束的一方照常记入渠道；
+// 都没有输出时记录对冲请求的失败，以主请求的错误进入常规重试。
+func finishHedge(c, hc *gin.Context, race *hedgeRace, primary, hedge hedgeResult) (RelayBaseInterface, *types.OpenAIErrorWithStatusCode, bool) {
+	winner := race.getWinner()
+	if winner == nil {
+		if primary.apiErr == nil {
+			winner = primary.writer
+		} else if hedge.apiErr == nil {
+			winner = hedge.writer
+		}
+	}
+
+	switch winner {
+	case primary.writer:
+		if hedge.apiErr != nil && !hedge.lost {
+			recordHedgeFailure(c, hc, hedge)
+		}
+		return primary.relay, primary.apiErr, primary.done
+	case hedge.writer:
+		logger.LogInfo(c.Request.Context(), fmt.Sprintf("hedge_won primary_channel_id=%d hedge_channel_id=%d",
+			primary.relay.getProvider().GetChannel().Id, hedge.relay.getProvider().GetChannel().Id))
+		if primary.apiErr != nil && !primary.lost {
+			channel := primary.relay.getProvider().GetChannel()
+			notifyChannelRelayError(c.Request.Context(), c, channel, primary.apiErr)
+			shouldCooldowns(c, channel, primary.apiErr)
+		}
+		// 后续的日志、监控按胜出的对冲请求记录
+		for key, value := range hc.Keys {
+			c.Set(key, value)
+		}
+		// 对冲请求已开始向客户端输出，出错时不再重试
+		return hedge.relay, hedge.apiErr, hedge.done || hedge.apiErr != nil
+	}
+
+	recordHedgeFailure(c, hc, hedge)
+	return primary.relay, primary.apiErr, primary.done
+}
+
+// recordHedgeFailure 对冲请求的失败与普通失败一样触发自动禁用与冷却，并让后续重试跳过该渠道
+func recordHedgeFailure(c, hc *gin.Context, hedge hedgeResult) {
+	channel := hedge.relay.getProvider().GetChannel()
+	notifyChannelRelayError(hc.Request.Context(), hc, channel, hedge.apiErr)
+	shouldCooldowns(hc, channel, hedge.apiErr)
+
+	skipChannelIds, _ := utils.GetGinValue[[]int](hc, "skip_channel_ids")
+	c.Set("skip_channel_ids", skipChannelIds)
+}
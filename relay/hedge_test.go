@@ -0,0 +1,266 @@
+package relay
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"done-hub/relay/relay_util"
+
+	"github.com/gin-gonic/gin"
+)
+
+type testHedgeRace struct {
+	race     *hedgeRace
+	recorder *httptest.ResponseRecorder
+	ctx      *gin.Context
+	writers  map[string]*hedgeWriter
+	canceled map[string]bool
+}
+
+// newTestHedgeRace 主请求与对冲请求共用同一个客户端响应，直接加入竞争，不经过 provider
+func newTestHedgeRace() *testHedgeRace {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+
+	tr := &testHedgeRace{
+		race: &hedgeRace{
+			delay:   100 * time.Millisecond,
+			record:  relay_util.NewHedgeRecord(100*time.Millisecond, 1),
+			claimed: make(chan struct{}),
+		},
+		recorder: recorder,
+		ctx:      ctx,
+		writers:  make(map[string]*hedgeWriter),
+		canceled: make(map[string]bool),
+	}
+	tr.race.record.Fire(2)
+
+	for _, name := range []string{relay_util.HedgeWinnerPrimary, relay_util.HedgeWinnerHedge} {
+		w := tr.race.newWriter(name, ctx.Writer)
+		w.cancel = func() { tr.canceled[name] = true }
+		tr.race.attempts = append(tr.race.attempts, w)
+		tr.writers[name] = w
+	}
+	return tr
+}
+
+func (tr *testHedgeRace) claimedClosed() bool {
+	select {
+	case <-tr.race.claimed:
+		return true
+	default:
+		return false
+	}
+}
+
+// TestHedgeWriterRace 测试对冲竞争的胜负判定：先写出响应的一方胜出并取消另一方，落败方的写入被丢弃
+func TestHedgeWriterRace(t *testing.T) {
+	const primary, hedge = relay_util.HedgeWinnerPrimary, relay_util.HedgeWinnerHedge
+
+	// 步骤：header 设置响应头 X-Attempt；status 设置状态码；write 写出响应体；flush 刷新；
+	// finish 结束尝试，wantLost 校验 finish 的返回值；write 时 wantLost 表示应返回 errHedgeLost
+	type step struct {
+		who      string
+		action   string
+		wantLost bool
+	}
+	tests := []struct {
+		name     string
+		steps    []step
+		winner   string
+		body     string
+		status   int
+		header   string
+		canceled []string
+		claimed  bool
+	}{
+		{
+			name:     "主请求先输出胜出",
+			steps:    []step{{who: primary, action: "write"}, {who: hedge, action: "write", wantLost: true}},
+			winner:   primary,
+			body:     primary,
+			status:   http.StatusOK,
+			canceled: []string{hedge},
+			claimed:  true,
+		},
+		{
+			name:     "对冲请求先输出胜出",
+			steps:    []step{{who: hedge, action: "write"}, {who: primary, action: "write", wantLost: true}, {who: hedge, action: "write"}},
+			winner:   hedge,
+			body:     hedge + hedge,
+			status:   http.StatusOK,
+			canceled: []string{primary},
+			claimed:  true,
+		},
+		{
+			name: "胜出前设置的响应头与状态码在胜出时写入，落败方的不写入",
+			steps: []step{
+				{who: primary, action: "header"}, {who: primary, action: "status"},
+				{who: hedge, action: "header"}, {who: hedge, action: "status"},
+				{who: hedge, action: "write"},
+			},
+			winner:   hedge,
+			body:     hedge,
+			status:   http.StatusAccepted,
+			header:   hedge,
+			canceled: []string{primary},
+			claimed:  true,
+		},
+		{
+			name:     "刷新也会争夺胜出权",
+			steps:    []step{{who: hedge, action: "flush"}, {who: primary, action: "write", wantLost: true}},
+			winner:   hedge,
+			status:   http.StatusOK,
+			canceled: []string{primary},
+			claimed:  true,
+		},
+		{
+			name:   "只设置响应头不算开始输出",
+			steps:  []step{{who: primary, action: "header"}, {who: primary, action: "status"}, {who: hedge, action: "header"}},
+			status: http.StatusOK,
+		},
+		{
+			name: "没有输出就结束的一方不影响另一方胜出",
+			steps: []step{
+				{who: primary, action: "finish"},
+				{who: hedge, action: "write"},
+				{who: hedge, action: "finish"},
+			},
+			winner:  hedge,
+			body:    hedge,
+			status:  http.StatusOK,
+			claimed: true,
+		},
+		{
+			name: "落败方结束时返回已落败",
+			steps: []step{
+				{who: primary, action: "write"},
+				{who: primary, action: "finish"},
+				{who: hedge, action: "finish", wantLost: true},
+			},
+			winner:   primary,
+			body:     primary,
+			status:   http.StatusOK,
+			canceled: []string{hedge},
+			claimed:  true,
+		},
+		{
+			name: "结束后的尝试不能再胜出",
+			steps: []step{
+				{who: primary, action: "finish"},
+				{who: primary, action: "write", wantLost: true},
+			},
+			status: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := newTestHedgeRace()
+
+			for i, s := range tt.steps {
+				w := tr.writers[s.who]
+				switch s.action {
+				case "header":
+					w.Header().Set("X-Attempt", s.who)
+				case "status":
+					if s.who == hedge {
+						w.WriteHeader(http.StatusAccepted)
+					} else {
+						w.WriteHeader(http.StatusCreated)
+					}
+				case "flush":
+					w.Flush()
+				case "write":
+					_, err := w.WriteString(s.who)
+					if lost := errors.Is(err, errHedgeLost); lost != s.wantLost {
+						t.Fatalf("第 %d 步 %s 写入 error = %v, 期望落败 %v", i+1, s.who, err, s.wantLost)
+					}
+				case "finish":
+					if got := w.finish(); got != s.wantLost {
+						t.Fatalf("第 %d 步 %s finish() = %v, 期望 %v", i+1, s.who, got, s.wantLost)
+					}
+				}
+			}
+
+			winner := ""
+			if w := tr.race.getWinner(); w != nil {
+				winner = w.name
+			}
+			if winner != tt.winner {
+				t.Errorf("胜出方 = %q, 期望 %q", winner, tt.winner)
+			}
+			if meta := tr.race.record.LogMeta(); meta["winner"] != tt.winner {
+				t.Errorf("记录的胜出方 = %v, 期望 %q", meta["winner"], tt.winner)
+			}
+			if tr.claimedClosed() != tt.claimed {
+				t.Errorf("claimed 已关闭 = %v, 期望 %v", tr.claimedClosed(), tt.claimed)
+			}
+
+			if got := tr.recorder.Body.String(); got != tt.body {
+				t.Errorf("响应体 = %q, 期望 %q", got, tt.body)
+			}
+			if tt.winner != "" && tr.recorder.Code != tt.status {
+				t.Errorf("状态码 = %d, 期望 %d", tr.recorder.Code, tt.status)
+			}
+			if got := tr.recorder.Header().Get("X-Attempt"); got != tt.header {
+				t.Errorf("响应头 X-Attempt = %q, 期望 %q", got, tt.header)
+			}
+
+			for _, name := range []string{primary, hedge} {
+				wantCanceled := false
+				for _, c := range tt.canceled {
+					wantCanceled = wantCanceled || c == name
+				}
+				if tr.canceled[name] != wantCanceled {
+					t.Errorf("%s 已取消 = %v, 期望 %v", name, tr.canceled[name], wantCanceled)
+				}
+
+				tr.ctx.Set(hedgeAttemptKey, tr.writers[name])
+				if got := isHedgeLoser(tr.ctx); got != wantCanceled {
+					t.Errorf("%s isHedgeLoser() = %v, 期望 %v", name, got, wantCanceled)
+				}
+			}
+		})
+	}
+}
+
+// TestHedgeWriterConcurrentClaim 测试两方同时开始输出时只有一方胜出，响应体只包含胜出方的内容
+func TestHedgeWriterConcurrentClaim(t *testing.T) {
+	for round := 0; round < 100; round++ {
+		tr := newTestHedgeRace()
+
+		var wg sync.WaitGroup
+		errs := make(map[string]error)
+		var mu sync.Mutex
+		for name, w := range tr.writers {
+			wg.Add(1)
+			go func(name string, w *hedgeWriter) {
+				defer wg.Done()
+				_, err := w.WriteString(name)
+				mu.Lock()
+				errs[name] = err
+				mu.Unlock()
+			}(name, w)
+		}
+		wg.Wait()
+
+		winner := tr.race.getWinner()
+		if winner == nil {
+			t.Fatalf("第 %d 轮没有胜出方", round+1)
+		}
+		for name, err := range errs {
+			if (name == winner.name) != (err == nil) {
+				t.Fatalf("第 %d 轮胜出方 %s, %s 写入 error = %v", round+1, winner.name, name, err)
+			}
+		}
+		if got := tr.recorder.Body.String(); got != winner.name {
+			t.Fatalf("第 %d 轮响应体 = %q, 期望 %q", round+1, got, winner.name)
+		}
+	}
+}
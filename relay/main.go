@@ -8,6 +8,7 @@ import (
 	"done-hub/metrics"
 	"done-hub/model"
 	"done-hub/relay/relay_util"
+	"done-hub/relay/responses"
 	"done-hub/types"
 	"encoding/json"
 	"fmt"
@@ -39,6 +40,18 @@ func Relay(c *gin.Context) {
 	// Apply pre-mapping before setRequest to ensure request body modifications take effect
 	applyPreMappingBeforeRequest(c)
 
+	if err := resolveFileReferences(c); err != nil {
+		openaiErr := common.StringErrorWrapperLocal(err.Error(), "one_hub_error", http.StatusBadRequest)
+		relay.HandleJsonError(openaiErr)
+		return
+	}
+
+	responsesState, openaiErr := responses.Prepare(c)
+	if openaiErr != nil {
+		relay.HandleJsonError(openaiErr)
+		return
+	}
+
 	if err := relay.setRequest(); err != nil {
 		openaiErr := common.StringErrorWrapperLocal(err.Error(), "one_hub_error", http.StatusBadRequest)
 		relay.HandleJsonError(openaiErr)
@@ -46,7 +59,24 @@ func Relay(c *gin.Context) {
 	}
 
 	c.Set("is_stream", relay.IsStream())
-	if err := relay.setProvider(relay.getOriginalModel()); err != nil {
+
+	var cacheSession *responseCacheSession
+	if responsesState.Cacheable() {
+		cacheSession = newResponseCacheSession(c, relay)
+	}
+	if cacheSession != nil {
+		if cacheSession.replay(relay) {
+			return
+		}
+		cacheSession.capture(c)
+	}
+	if responsesState != nil {
+		responsesState.Capture(c)
+	}
+
+	setRoutingContent(c, relay)
+	route := startVirtualModel(c, relay.getOriginalModel())
+	if err := setProviderWithFallback(relay, route, nil); err != nil {
 		// 配置错误 → 404 model_not_found（SDK 不重试）；运行时错误 → 503 collapse（SDK 重试）。
 		if IsModelNotFound(err) {
 			relay.HandleJsonError(common.ModelNotFoundError(relay.getOriginalModel()))
@@ -61,9 +91,10 @@ func Relay(c *gin.Context) {
 		defer heartbeat.Close()
 	}
 
-	apiErr, done := RelayHandler(relay)
+	relay, apiErr, done := relayWithHedge(relay, heartbeat)
 	if apiErr == nil {
 		metrics.RecordProvider(c, 200)
+		relaySucceeded(relay, cacheSession, responsesState)
 		return
 	}
 
@@ -79,7 +110,8 @@ func Relay(c *gin.Context) {
 	modelName := c.GetString("new_model")
 	totalChannelsAtStart := model.ChannelGroup.CountAvailableChannels(groupName, modelName)
 
-	if done || !shouldRetry(c, apiErr, channel.Type) {
+	retryable := !done && shouldRetry(c, apiErr, channel.Type)
+	if !retryable {
 		logger.LogError(c.Request.Context(), fmt.Sprintf("retry_skip model=%s channel_id=%d status_code=%d upstream_id=%s done=%t should_retry=%t total_channels=%d error=\"%s\"",
 			modelName, channel.Id, apiErr.StatusCode, c.GetString(config.GinUpstreamRequestIdKey), done, shouldRetry(c, apiErr, channel.Type), totalChannelsAtStart, utils.TruncateBase64InMessage(apiErr.OpenAIError.Message)))
 		retryTimes = 0
@@ -93,6 +125,10 @@ func Relay(c *gin.Context) {
 	if totalChannelsAtStart < retryTimes {
 		actualRetryTimes = totalChannelsAtStart
 	}
+	// 虚拟模型可以回退时，后续的每个步骤至少保留一次尝试
+	if route != nil && (retryable || shouldFallbackVirtualModel(c, route, apiErr, false)) {
+		actualRetryTimes += route.RemainingSteps()
+	}
 
 	c.Set("total_channels_at_start", totalChannelsAtStart)
 	c.Set("actual_retry_times", actualRetryTimes)
@@ -126,7 +162,14 @@ func Relay(c *gin.Context) {
 			break
 		}
 
-		if err := relay.setProvider(relay.getOriginalModel()); err != nil {
+		// 本步骤无法继续重试且满足回退条件时进入下一个步骤
+		lastErr := apiErr
+		if shouldFallbackVirtualModel(c, route, apiErr, retryable) {
+			advanceVirtualModel(c, route, apiErr)
+			lastErr = nil
+		}
+
+		if err := setProviderWithFallback(relay, route, lastErr); err != nil {
 			logger.LogError(c.Request.Context(), fmt.Sprintf("retry_provider_error model=%s channel_id=%d error=\"%s\"",
 				modelName, channel.Id, err.Error()))
 			breakReason = "provider_error"
@@ -134,6 +177,10 @@ func Relay(c *gin.Context) {
 		}
 
 		channel = relay.getProvider().GetChannel()
+		if route != nil {
+			modelName = c.GetString("new_model")
+			groupName = c.GetString("token_group")
+		}
 
 		// 更新尝试计数
 		attemptCount := c.GetInt("attempt_count")
@@ -158,6 +205,7 @@ func Relay(c *gin.Context) {
 			logger.LogInfo(c.Request.Context(), fmt.Sprintf("retry_success model=%s channel_id=%d attempt=%d/%d total_channels=%d",
 				modelName, channel.Id, attemptCount, actualRetryTimes, c.GetInt("total_channels_at_start")))
 			metrics.RecordProvider(c, 200)
+			relaySucceeded(relay, cacheSession, responsesState)
 			return
 		}
 
@@ -166,7 +214,8 @@ func Relay(c *gin.Context) {
 			modelName, channel.Id, attemptCount, actualRetryTimes, apiErr.StatusCode, c.GetString(config.GinUpstreamRequestIdKey), apiErr.OpenAIError.Type, utils.TruncateBase64InMessage(apiErr.OpenAIError.Message)))
 
 		notifyChannelRelayError(c.Request.Context(), c, channel, apiErr)
-		if done || !shouldRetry(c, apiErr, channel.Type) {
+		retryable = !done && shouldRetry(c, apiErr, channel.Type)
+		if !retryable && !shouldFallbackVirtualModel(c, route, apiErr, false) {
 			logger.LogError(c.Request.Context(), fmt.Sprintf("retry_stop_condition model=%s channel_id=%d attempt=%d/%d done=%t should_retry=%t",
 				modelName, channel.Id, attemptCount, actualRetryTimes, done, shouldRetry(c, apiErr, channel.Type)))
 			breakReason = "stop_condition"
@@ -200,6 +249,16 @@ func Relay(c *gin.Context) {
 	}
 }
 
+// relaySucceeded 请求成功后写入响应缓存、保存 Responses 响应
+func relaySucceeded(relay RelayBaseInterface, cacheSession *responseCacheSession, responsesState *responses.State) {
+	if cacheSession != nil {
+		cacheSession.store(relay)
+	}
+	if responsesState != nil {
+		responsesState.Save(relay.getContext(), relay.IsStream())
+	}
+}
+
 // promptTokenForcer 由能在忽略 PreCost 开关的前提下强制计算输入 token 的 relay 实现。
 // 当渠道关闭预扣费（getPromptTokens 返回 0）时，超限守卫用它兜底，避免被绕过。
 type promptTokenForcer interface {
@@ -254,6 +313,24 @@ func checkPromptTokenLimit(relay RelayBaseInterface, promptTokens int) *types.Op
 }
 
 func RelayHandler(relay RelayBaseInterface) (err *types.OpenAIErrorWithStatusCode, done bool) {
+	startTime := time.Now()
+	var usage *types.Usage
+	defer func() {
+		if isHedgeLoser(relay.getContext()) {
+			// 对冲落败方是被主动取消的，不计入渠道健康与用量统计
+			releaseChannelAttempt(relay.getContext(), relay.getProvider().GetChannel())
+			return
+		}
+		recordRelayResult(relay.getContext(), relay.getProvider().GetChannel(), err)
+		// 与计费一致：失败且没有输出时视为上游未处理，不计入 TPM
+		if usage != nil && (err == nil || usage.CompletionTokens > 0) {
+			recordRelayTokens(relay.getContext(), relay.getProvider().GetChannel(), usage)
+		}
+		if err == nil {
+			recordRelayLatency(relay.getContext(), relay.getProvider().GetChannel(), startTime, relay.GetFirstResponseTime())
+		}
+	}()
+
 	promptTokens, tonkeErr := relay.getPromptTokens()
 	if tonkeErr != nil {
 		err = common.ErrorWrapperLocal(tonkeErr, "token_error", http.StatusBadRequest)
@@ -268,7 +345,7 @@ func RelayHandler(relay RelayBaseInterface) (err *types.OpenAIErrorWithStatusCod
 		return
 	}
 
-	usage := &types.Usage{
+	usage = &types.Usage{
 		PromptTokens: promptTokens,
 	}
 
@@ -288,6 +365,13 @@ func RelayHandler(relay RelayBaseInterface) (err *types.OpenAIErrorWithStatusCod
 	relay.getContext().Set(config.GinPassThroughHeaders, nil)
 
 	err, done = relay.send()
+	// 对冲落败方只有胜出方计费
+	if isHedgeLoser(relay.getContext()) {
+		quota.Undo(relay.getContext())
+		err = common.StringErrorWrapperLocal(errHedgeLost.Error(), "one_hub_error", http.StatusServiceUnavailable)
+		done = true
+		return
+	}
 	// 最后处理流式中断时计算tokens
 	if usage.CompletionTokens == 0 && usage.TextBuilder.Len() > 0 {
 		usage.CompletionTokens = common.CountTokenText(usage.TextBuilder.String(), relay.getModelName())
@@ -315,7 +399,11 @@ func RelayHandler(relay RelayBaseInterface) (err *types.OpenAIErrorWithStatusCod
 }
 
 func shouldCooldowns(c *gin.Context, channel *model.Channel, apiErr *types.OpenAIErrorWithStatusCode) bool {
-	modelName := c.GetString("new_model")
+	// 冷却 key 需与选渠道时的模型名一致，渠道有模型映射时 new_model 对不上
+	modelName := c.GetString(config.GinChannelModelKey)
+	if modelName == "" {
+		modelName = c.GetString("new_model")
+	}
 	channelId := channel.Id
 	statusCode := apiErr.StatusCode
 
@@ -352,7 +440,12 @@ func shouldCooldowns(c *gin.Context, channel *model.Channel, apiErr *types.OpenA
 		}
 	}
 
-	if duration > 0 {
+	// 多密钥渠道的限流通常针对单个密钥，只冷却出错的密钥，池中其他密钥照常使用
+	if keyId := c.GetInt(config.GinChannelKeyIdKey); duration > 0 && keyId > 0 && statusCode == http.StatusTooManyRequests {
+		model.ChannelKeys.SetCooldown(keyId, duration)
+		logger.LogWarn(c.Request.Context(), fmt.Sprintf("channel_key_cooldown channel_id=%d key_id=%d status_code=%d duration=%ds reason=\"%s\"",
+			channelId, keyId, statusCode, duration, reason))
+	} else if duration > 0 {
 		model.ChannelGroup.SetCooldownsWithDuration(channelId, modelName, duration)
 		extra := ""
 		if apiErr.RateLimitResetAt > 0 && reason == "upstream_retry_after" {
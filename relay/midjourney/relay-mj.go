@@ -7,7 +7,9 @@ import (
 	"bytes"
 	"context"
 	"done-hub/common"
+	"done-hub/common/config"
 	"done-hub/common/logger"
+	"done-hub/common/webhook"
 	"done-hub/controller"
 	"done-hub/model"
 	provider "done-hub/providers/midjourney"
@@ -99,6 +101,9 @@ func RelayMidjourneyNotify(c *gin.Context) *provider.MidjourneyResponse {
 			Description: "update_midjourney_task_failed",
 		}
 	}
+	if midjourneyTask.Progress == "100%" {
+		webhook.EnqueueMidjourney(midjourneyTask)
+	}
 
 	return nil
 }
@@ -226,6 +231,8 @@ func RelaySwapFace(c *gin.Context) *provider.MidjourneyResponse {
 		ChannelId:   c.GetInt("channel_id"),
 		Quota:       quota,
 		Mode:        mjModelType,
+
+		ChannelKeyId: c.GetInt(config.GinChannelKeyIdKey),
 	}
 	err = midjourneyTask.Insert()
 	if err != nil {
@@ -254,7 +261,7 @@ func RelayMidjourneyTaskImageSeed(c *gin.Context) *provider.MidjourneyResponse {
 		return provider.MidjourneyErrorWrapper(provider.MjRequestError, "task_no_found")
 	}
 
-	mjProvider, errWithMJ := getMJProviderWithChannelId(c, originTask.ChannelId)
+	mjProvider, errWithMJ := getMJProviderWithChannelId(c, originTask.ChannelId, originTask.ChannelKeyId)
 	if errWithMJ != nil {
 		return errWithMJ
 	}
@@ -415,7 +422,7 @@ func RelayMidjourneySubmit(c *gin.Context, relayMode int) *provider.MidjourneyRe
 		} else if originTask.Status != "SUCCESS" && relayMode != provider.RelayModeMidjourneyModal {
 			return provider.MidjourneyErrorWrapper(provider.MjRequestError, "task_status_not_success")
 		} else { //原任务的Status=SUCCESS，则可以做放大UPSCALE、变换VARIATION等动作，此时必须使用原来的请求地址才能正确处理
-			mjProvider, errWithMJ = getMJProviderWithChannelId(c, originTask.ChannelId)
+			mjProvider, errWithMJ = getMJProviderWithChannelId(c, originTask.ChannelId, originTask.ChannelKeyId)
 			if errWithMJ != nil {
 				return errWithMJ
 			}
@@ -446,6 +453,13 @@ func RelayMidjourneySubmit(c *gin.Context, relayMode int) *provider.MidjourneyRe
 
 	//midjRequest.NotifyHook = "http://127.0.0.1:3000/mj/notify"
 
+	// 未开启上游回调时由网关投递 notifyHook，提交时先校验地址
+	if !config.MjNotifyEnabled && midjRequest.NotifyHook != "" {
+		if err := webhook.ValidateURL(midjRequest.NotifyHook); err != nil {
+			return provider.MidjourneyErrorWrapper(provider.MjRequestError, "invalid_notify_hook")
+		}
+	}
+
 	quotaInstance, errWithOA := getQuota(c, midjRequest.Action)
 	if errWithOA != nil {
 		return &provider.MidjourneyResponse{
@@ -523,6 +537,13 @@ func RelayMidjourneySubmit(c *gin.Context, relayMode int) *provider.MidjourneyRe
 		ChannelId:   c.GetInt("channel_id"),
 		Quota:       quota,
 		Mode:        mjModelType,
+
+		ChannelKeyId: c.GetInt(config.GinChannelKeyIdKey),
+	}
+
+	// 未开启上游回调时 notifyHook 不会透传，改由网关在任务完成后签名投递
+	if !config.MjNotifyEnabled {
+		midjourneyTask.NotifyHook = midjRequest.NotifyHook
 	}
 
 	if midjResponse.Code != 1 && midjResponse.Code != 21 && midjResponse.Code != 22 {
@@ -637,8 +658,9 @@ func getMJProviderWithRequest(c *gin.Context, relayMode int, request *provider.M
 	return getMJProvider(c, midjourneyModel)
 }
 
-func getMJProviderWithChannelId(c *gin.Context, channelId int) (*provider.MidjourneyProvider, *provider.MidjourneyResponse) {
+func getMJProviderWithChannelId(c *gin.Context, channelId, channelKeyId int) (*provider.MidjourneyProvider, *provider.MidjourneyResponse) {
 	c.Set("specific_channel_id", channelId)
+	c.Set(config.GinSpecificChannelKeyIdKey, channelKeyId)
 
 	return getMJProvider(c, "")
 }
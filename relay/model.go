@@ -85,6 +85,7 @@ func ListModelsByToken(c *gin.Context) {
 		})
 		return
 	}
+	models = append(models, model.VirtualModels.GroupModels(groupName)...)
 	sort.Strings(models)
 
 	// 根据令牌的模型限制过滤模型列表
@@ -131,6 +132,7 @@ func ListGeminiModelsByToken(c *gin.Context) {
 		})
 		return
 	}
+	models = append(models, model.VirtualModels.GroupModels(groupName)...)
 	sort.Strings(models)
 
 	// 根据令牌的模型限制过滤模型列表
@@ -170,6 +172,7 @@ func ListClaudeModelsByToken(c *gin.Context) {
 		})
 		return
 	}
+	models = append(models, model.VirtualModels.GroupModels(groupName)...)
 	sort.Strings(models)
 
 	// 根据令牌的模型限制过滤模型列表
@@ -244,7 +247,12 @@ func getModelOwnedBy(channelType int) (ownedBy *string) {
 }
 
 func getOpenAIModelWithName(modelName string) *OpenAIModels {
-	price := model.PricingInstance.GetPrice(modelName)
+	priceModel := modelName
+	// 虚拟模型没有自己的价格，归属按第一个步骤的模型展示
+	if virtualModel := model.VirtualModels.Get(modelName); virtualModel != nil && len(virtualModel.Steps.Data()) > 0 {
+		priceModel = virtualModel.Steps.Data()[0].Model
+	}
+	price := model.PricingInstance.GetPrice(priceModel)
 
 	return &OpenAIModels{
 		Id:      modelName,
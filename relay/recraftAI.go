@@ -68,6 +68,7 @@ func RelayRecraftAI(c *gin.Context) {
 
 	requestURL := strings.Replace(c.Request.URL.Path, "/recraftAI", "", 1)
 	response, apiErr := recraftProvider.CreateRelay(requestURL)
+	recordRelayResult(c, recraftProvider.GetChannel(), apiErr)
 	if apiErr == nil {
 		quota.Consume(c, usage, false)
 
@@ -135,6 +136,7 @@ func RelayRecraftAI(c *gin.Context) {
 			modelName, channel.Id, attemptCount, actualRetryTimes, remainChannels, c.GetInt("total_channels_at_start"), cooldownApplied))
 
 		response, apiErr := recraftProvider.CreateRelay(requestURL)
+		recordRelayResult(c, recraftProvider.GetChannel(), apiErr)
 		if apiErr == nil {
 			quota.Consume(c, usage, false)
 
@@ -0,0 +1,54 @@
+package relay_util
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	HedgeWinnerPrimary = "primary"
+	HedgeWinnerHedge   = "hedge"
+)
+
+// HedgeRecord 对冲请求的过程记录，主请求与对冲请求共享，写入胜出方消费日志的 metadata
+type HedgeRecord struct {
+	mu               sync.Mutex
+	delay            time.Duration
+	primaryChannelId int
+	hedgeChannelId   int
+	winner           string
+}
+
+func NewHedgeRecord(delay time.Duration, primaryChannelId int) *HedgeRecord {
+	return &HedgeRecord{delay: delay, primaryChannelId: primaryChannelId}
+}
+
+// Fire 记录已向另一个渠道发出对冲请求
+func (r *HedgeRecord) Fire(hedgeChannelId int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hedgeChannelId = hedgeChannelId
+}
+
+// SetWinner 记录先开始输出的一方
+func (r *HedgeRecord) SetWinner(winner string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.winner = winner
+}
+
+// LogMeta 未发出对冲请求时返回 nil
+func (r *HedgeRecord) LogMeta() map[string]any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.hedgeChannelId == 0 {
+		return nil
+	}
+	return map[string]any{
+		"delay_ms":           r.delay.Milliseconds(),
+		"primary_channel_id": r.primaryChannelId,
+		"hedge_channel_id":   r.hedgeChannelId,
+		"winner":             r.winner,
+	}
+}
@@ -28,6 +28,10 @@ type Quota struct {
 	inputRatio       float64
 	outputRatio      float64
 	costRatio        float64
+	budgeted         bool    // 渠道设置了花费预算，结算时累加预算周期的花费
+	batchRatio       float64 // 批处理折扣，非批处理请求为 1
+	cacheHit         bool    // 由响应缓存直接返回
+	cacheHitRatio    float64 // 命中缓存时的计费倍率
 	preConsumedQuota int
 	cacheQuota       int
 	userId           int
@@ -39,6 +43,10 @@ type Quota struct {
 	startTime         time.Time
 	firstResponseTime time.Time
 	extraBillingData  map[string]ExtraBillingData
+	costRouting       *model.CostRoutingChoice // 成本优先路由的选择结果，仅用于日志
+	channelKeyId      int                      // 多密钥渠道本次使用的密钥
+	hedge             *HedgeRecord             // 对冲请求的过程记录，仅用于日志
+	virtualModel      map[string]any           // 请求虚拟模型时实际使用的步骤，仅用于日志
 }
 
 func NewQuota(c *gin.Context, modelName string, promptTokens int) *Quota {
@@ -69,13 +77,39 @@ func NewQuota(c *gin.Context, modelName string, promptTokens int) *Quota {
 	}
 
 	quota.groupRatio = c.GetFloat64("group_ratio") // 这里的倍率已经在 common.go 中正确设置了
-	quota.inputRatio = quota.price.GetInput() * quota.groupRatio
-	quota.outputRatio = quota.price.GetOutput() * quota.groupRatio
+	quota.batchRatio = 1
+	if batchRatio, ok := c.Get(config.GinBatchPriceRatioKey); ok {
+		if ratio, ok := batchRatio.(float64); ok && ratio >= 0 {
+			quota.batchRatio = ratio
+		}
+	}
+	quota.cacheHitRatio = 1
+	if c.GetBool(config.GinResponseCacheHitKey) {
+		quota.cacheHit = true
+		quota.cacheHitRatio = math.Max(config.ResponseCacheHitRatio, 0)
+	}
+	quota.inputRatio = quota.price.GetInput() * quota.groupRatio * quota.batchRatio * quota.cacheHitRatio
+	quota.outputRatio = quota.price.GetOutput() * quota.groupRatio * quota.batchRatio * quota.cacheHitRatio
 
 	// 成本倍率：仅用于成本/利润统计，不参与用户扣费。未配置或取不到渠道时为 0（不计成本）。
 	quota.costRatio = 0
 	if channel := model.ChannelGroup.GetChannel(quota.channelId); channel != nil {
 		quota.costRatio = channel.GetCostRatio()
+		quota.budgeted = channel.HasBudget()
+	}
+	quota.channelKeyId = c.GetInt(config.GinChannelKeyIdKey)
+	if value, ok := c.Get(config.GinHedgeKey); ok {
+		quota.hedge, _ = value.(*HedgeRecord)
+	}
+	if value, ok := c.Get(config.GinVirtualModelKey); ok {
+		if route, ok := value.(*VirtualModelRoute); ok && route != nil {
+			quota.virtualModel = route.LogMeta()
+		}
+	}
+	if value, ok := c.Get(config.GinCostRoutingKey); ok {
+		if choice, ok := value.(*model.CostRoutingChoice); ok && choice.ChannelId == quota.channelId {
+			quota.costRouting = choice
+		}
 	}
 
 	return quota
@@ -180,6 +214,15 @@ func (q *Quota) completedQuotaConsumption(usage *types.Usage, tokenName string,
 	if quota > 0 {
 		model.UpdateChannelUsedQuota(q.channelId, quota)
 	}
+	model.UpdateChannelKeyUsage(q.channelKeyId, quota)
+	if q.budgeted {
+		// 预算按上游成本计算，未设置成本倍率时按用户扣费计算
+		spent := costQuota
+		if q.costRatio <= 0 {
+			spent = quota
+		}
+		model.UpdateChannelBudgetSpent(q.channelId, spent)
+	}
 
 	// 无论配额操作是否成功，都要记录日志，避免上游已计费但本地无记录
 	model.RecordConsumeLog(
@@ -304,6 +347,15 @@ func (q *Quota) GetLogMeta(usage *types.Usage) map[string]any {
 		"output_ratio":      q.price.GetOutput(),
 	}
 
+	if q.batchRatio != 1 {
+		meta["batch_ratio"] = q.batchRatio
+	}
+
+	if q.cacheHit {
+		meta["cache_hit"] = true
+		meta["cache_hit_ratio"] = q.cacheHitRatio
+	}
+
 	firstResponseTime := q.GetFirstResponseTime()
 	if firstResponseTime > 0 {
 		meta["first_response"] = firstResponseTime
@@ -323,12 +375,33 @@ func (q *Quota) GetLogMeta(usage *types.Usage) map[string]any {
 			meta["long_context_input_ratio"] = inRatio
 			meta["long_context_output_ratio"] = outRatio
 		}
+
+		// 成本优先路由：记录选中渠道与按权重默认选择的成本倍率，以及相对默认选择节省的成本配额
+		if q.costRouting != nil {
+			meta["cost_routing_ratio"] = q.costRouting.CostRatio
+			meta["cost_routing_default_ratio"] = q.costRouting.DefaultCostRatio
+			meta["cost_routing_saving"] = q.getCostQuotaWithRatio(usage, q.costRouting.DefaultCostRatio) - q.getCostQuotaWithRatio(usage, q.costRouting.CostRatio)
+		}
 	}
 
 	if q.extraBillingData != nil {
 		meta["extra_billing"] = q.extraBillingData
 	}
 
+	if q.channelKeyId > 0 {
+		meta["channel_key_id"] = q.channelKeyId
+	}
+
+	if q.hedge != nil {
+		if hedge := q.hedge.LogMeta(); hedge != nil {
+			meta["hedge"] = hedge
+		}
+	}
+
+	if q.virtualModel != nil {
+		meta["virtual_model"] = q.virtualModel
+	}
+
 	return meta
 }
 
@@ -432,10 +505,14 @@ func (q *Quota) GetCostQuotaByUsage(usage *types.Usage) (costQuota int) {
 	if q.costRatio <= 0 {
 		return 0
 	}
+	return q.getCostQuotaWithRatio(usage, q.costRatio)
+}
+
+func (q *Quota) getCostQuotaWithRatio(usage *types.Usage, costRatio float64) int {
 	promptTokens, completionTokens := q.getComputeTokensByUsage(usage)
 	inRatio, outRatio := q.price.GetLongContextMultiplier(usage.PromptTokens)
 	q.GetExtraBillingData(usage.ExtraBilling)
-	return q.calcQuota(promptTokens, completionTokens, q.price.GetInput()*q.costRatio*inRatio, q.price.GetOutput()*q.costRatio*outRatio, q.costRatio)
+	return q.calcQuota(promptTokens, completionTokens, q.price.GetInput()*costRatio*inRatio, q.price.GetOutput()*costRatio*outRatio, costRatio)
 }
 
 func (q *Quota) GetFirstResponseTime() int64 {
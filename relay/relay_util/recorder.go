@@ -0,0 +1,84 @@
+package relay_util
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// responseRecorderMaxBytes 记录内容的上限，超过后放弃记录，不影响正常写出
+const responseRecorderMaxBytes = 16 << 20
+
+// ResponseRecorder 在写给客户端的同时记录响应内容，供响应缓存、Responses 存储等在请求结束后使用。
+// 心跳协程也会通过 c.Writer 写入，因此加锁。
+type ResponseRecorder struct {
+	gin.ResponseWriter
+	mu       sync.Mutex
+	buf      bytes.Buffer
+	overflow bool
+}
+
+// RecordResponse 接管 c.Writer 开始记录，已在记录时直接返回已有的 recorder
+func RecordResponse(c *gin.Context) *ResponseRecorder {
+	if recorder, ok := c.Writer.(*ResponseRecorder); ok {
+		return recorder
+	}
+
+	recorder := &ResponseRecorder{ResponseWriter: c.Writer}
+	c.Writer = recorder
+	return recorder
+}
+
+func (w *ResponseRecorder) record(data []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.overflow {
+		return
+	}
+	if w.buf.Len()+len(data) > responseRecorderMaxBytes {
+		w.overflow = true
+		w.buf.Reset()
+		return
+	}
+	w.buf.Write(data)
+}
+
+func (w *ResponseRecorder) Write(data []byte) (int, error) {
+	w.record(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *ResponseRecorder) WriteString(s string) (int, error) {
+	w.record([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Overflow 内容是否超出上限而未被完整记录
+func (w *ResponseRecorder) Overflow() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.overflow
+}
+
+func (w *ResponseRecorder) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+// StreamEvents 将记录的 SSE 内容按事件拆分，并去掉心跳
+func (w *ResponseRecorder) StreamEvents() []string {
+	body := strings.ReplaceAll(w.String(), "\r\n", "\n")
+	heartbeat := strings.TrimSpace(HeartbeatStreamText)
+
+	events := make([]string, 0)
+	for _, event := range strings.Split(body, "\n\n") {
+		if strings.TrimSpace(event) == "" || strings.HasPrefix(event, heartbeat) {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events
+}
@@ -0,0 +1,95 @@
+package relay_util
+
+import (
+	"done-hub/model"
+	"time"
+)
+
+// VirtualModelRoute 请求虚拟模型时的回退进度，记录当前所在的步骤，写入消费日志的 metadata
+type VirtualModelRoute struct {
+	name      string
+	steps     []model.VirtualModelStep
+	index     int
+	stepStart time.Time
+
+	skipChannelIds []int // 请求开始时就要跳过的渠道，进入新步骤时恢复为该列表
+	requested      bool  // 回退链来自请求体中的 models 列表
+}
+
+func NewVirtualModelRoute(virtualModel *model.VirtualModel, skipChannelIds []int) *VirtualModelRoute {
+	return &VirtualModelRoute{
+		name:           virtualModel.Name,
+		steps:          virtualModel.Steps.Data(),
+		stepStart:      time.Now(),
+		skipChannelIds: skipChannelIds,
+	}
+}
+
+// NewModelListRoute 请求体中 models 回退列表的回退进度，第一个为请求的模型，任何错误都回退到下一个模型
+func NewModelListRoute(models []string, skipChannelIds []int) *VirtualModelRoute {
+	steps := make([]model.VirtualModelStep, 0, len(models))
+	for _, name := range models {
+		steps = append(steps, model.VirtualModelStep{Model: name})
+	}
+
+	return &VirtualModelRoute{
+		name:           models[0],
+		steps:          steps,
+		stepStart:      time.Now(),
+		skipChannelIds: skipChannelIds,
+		requested:      true,
+	}
+}
+
+func (r *VirtualModelRoute) Name() string {
+	return r.name
+}
+
+// Step 当前步骤
+func (r *VirtualModelRoute) Step() *model.VirtualModelStep {
+	return &r.steps[r.index]
+}
+
+// StepNumber 当前是第几个步骤，从 1 开始
+func (r *VirtualModelRoute) StepNumber() int {
+	return r.index + 1
+}
+
+// RemainingSteps 当前步骤之后还有几个步骤
+func (r *VirtualModelRoute) RemainingSteps() int {
+	return len(r.steps) - r.index - 1
+}
+
+// StepExpired 当前步骤设置了耗时上限且已超过
+func (r *VirtualModelRoute) StepExpired() bool {
+	step := r.Step()
+	return step.Timeout > 0 && time.Since(r.stepStart) > time.Duration(step.Timeout)*time.Second
+}
+
+// InitialSkipChannelIds 请求开始时就要跳过的渠道
+func (r *VirtualModelRoute) InitialSkipChannelIds() []int {
+	return append([]int{}, r.skipChannelIds...)
+}
+
+// Advance 进入下一个步骤，已是最后一步时返回 false
+func (r *VirtualModelRoute) Advance() bool {
+	if r.RemainingSteps() <= 0 {
+		return false
+	}
+	r.index++
+	r.stepStart = time.Now()
+	return true
+}
+
+func (r *VirtualModelRoute) LogMeta() map[string]any {
+	meta := map[string]any{
+		"name":  r.name,
+		"step":  r.StepNumber(),
+		"model": r.Step().Model,
+	}
+	if r.requested {
+		meta["requested"] = true
+	}
+
+	return meta
+}
@@ -0,0 +1,128 @@
+package relay
+
+import (
+	"done-hub/common"
+	"done-hub/common/config"
+	"done-hub/common/utils"
+	"done-hub/model"
+	"encoding/json"
+	"errors"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// requestRoutingModelsLimit models 回退链最多包含的模型数（含请求的 model）
+const requestRoutingModelsLimit = 5
+
+// parseRequestRouting 读取请求体中 OpenRouter 风格的 models 与 provider 字段，分组未禁用时记录到上下文，
+// 并从缓存的请求体中删除这两个字段，避免透传到上游；请求未填写 model 时使用 models 中的第一个。
+// 需在反序列化请求体之前调用。
+func parseRequestRouting(c *gin.Context) error {
+	body, err := common.ReadBodyRaw(c)
+	if err != nil || len(body) == 0 {
+		return nil
+	}
+
+	models := gjson.GetBytes(body, "models")
+	provider := gjson.GetBytes(body, "provider")
+	if !models.Exists() && !provider.Exists() {
+		return nil
+	}
+	if !model.GlobalUserGroupRatio.AllowRequestRouting(c.GetString("token_group")) {
+		return nil
+	}
+
+	routing := &model.RequestRouting{}
+	if models.Exists() {
+		if err := json.Unmarshal([]byte(models.Raw), &routing.Models); err != nil {
+			return errors.New("models must be an array of model names")
+		}
+	}
+	if provider.Exists() {
+		if err := json.Unmarshal([]byte(provider.Raw), &routing.Provider); err != nil {
+			return errors.New("provider preferences are invalid")
+		}
+	}
+
+	for _, key := range []string{"models", "provider"} {
+		if body, err = sjson.DeleteBytes(body, key); err != nil {
+			return err
+		}
+	}
+	if gjson.GetBytes(body, "model").String() == "" && len(routing.Models) > 0 {
+		if body, err = sjson.SetBytes(body, "model", routing.Models[0]); err != nil {
+			return err
+		}
+	}
+	c.Set(config.GinRequestBodyKey, body)
+	c.Set(config.GinRequestRoutingKey, routing)
+
+	return nil
+}
+
+func getRequestRouting(c *gin.Context) *model.RequestRouting {
+	routing, _ := utils.GetGinValue[*model.RequestRouting](c, config.GinRequestRoutingKey)
+	return routing
+}
+
+// requestRoutingModels 把请求的 model 与 models 回退列表组成回退链，去重并跳过令牌不允许使用的模型
+func requestRoutingModels(c *gin.Context, modelName string) []string {
+	routing := getRequestRouting(c)
+	if routing == nil || len(routing.Models) == 0 {
+		return nil
+	}
+
+	models := []string{modelName}
+	for _, name := range routing.Models {
+		if len(models) >= requestRoutingModelsLimit {
+			break
+		}
+		if name == "" || slices.Contains(models, name) || CheckLimitModel(c, name) != nil {
+			continue
+		}
+		models = append(models, name)
+	}
+
+	return models
+}
+
+// providerPreferenceFilters 返回请求 provider 偏好中 only、ignore 与 allow_fallbacks 对应的渠道过滤器
+func providerPreferenceFilters(c *gin.Context) []model.ChannelsFilterFunc {
+	routing := getRequestRouting(c)
+	if routing == nil || routing.Provider == nil {
+		return nil
+	}
+
+	return routing.Provider.Filters()
+}
+
+// fetchChannelByProviderOrder 按请求 provider.order 的顺序逐个尝试选择渠道，都没有可用渠道时返回 nil
+func fetchChannelByProviderOrder(c *gin.Context, group, modelName string, filters []model.ChannelsFilterFunc) *model.Channel {
+	routing := getRequestRouting(c)
+	if routing == nil || routing.Provider == nil {
+		return nil
+	}
+
+	for _, name := range routing.Provider.Order {
+		orderFilters := append(slices.Clone(filters), model.FilterProviders([]string{name}))
+		if channel, err := model.ChannelGroup.NextByValidatedModel(group, modelName, c, orderFilters...); err == nil {
+			return channel
+		}
+	}
+
+	return nil
+}
+
+// setRequestRoutingHeaders 请求带有路由参数时，在响应头中返回实际使用的模型与 provider
+func setRequestRoutingHeaders(relay RelayBaseInterface) {
+	c := relay.getContext()
+	if getRequestRouting(c) == nil || c.Writer.Written() {
+		return
+	}
+
+	c.Writer.Header().Set("X-Routing-Model", c.GetString(config.GinChannelModelKey))
+	c.Writer.Header().Set("X-Routing-Provider", model.ChannelProviderName(relay.getProvider().GetChannel()))
+}
@@ -0,0 +1,190 @@
+package relay
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"done-hub/common/config"
+	"done-hub/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+func setupRequestRoutingGroups(t *testing.T) {
+	t.Helper()
+	model.GlobalUserGroupRatio.Lock()
+	saved := model.GlobalUserGroupRatio.UserGroup
+	model.GlobalUserGroupRatio.UserGroup = map[string]*model.UserGroup{
+		"default": {Symbol: "default", Ratio: 1},
+		"locked":  {Symbol: "locked", Ratio: 1, DisableRequestRouting: true},
+	}
+	model.GlobalUserGroupRatio.Unlock()
+	t.Cleanup(func() {
+		model.GlobalUserGroupRatio.Lock()
+		model.GlobalUserGroupRatio.UserGroup = saved
+		model.GlobalUserGroupRatio.Unlock()
+	})
+}
+
+func newRequestRoutingContext(group, body string) *gin.Context {
+	c := newRoutingTestContext()
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	c.Set("token_group", group)
+	return c
+}
+
+// TestParseRequestRouting 测试读取请求体中的 models 与 provider：记录到上下文并从透传给上游的请求体中删除
+func TestParseRequestRouting(t *testing.T) {
+	setupRequestRoutingGroups(t)
+	allowFallbacks := false
+
+	tests := []struct {
+		name        string
+		group       string
+		body        string
+		wantRouting *model.RequestRouting
+		wantModel   string
+		wantErr     bool
+	}{
+		{
+			name:      "没有路由参数时不处理",
+			group:     "default",
+			body:      `{"model":"gpt-4o","messages":[]}`,
+			wantModel: "gpt-4o",
+		},
+		{
+			name:        "记录 models 与 provider 并从请求体删除",
+			group:       "default",
+			body:        `{"model":"gpt-4o","models":["claude-sonnet-4","gemini-2.5-pro"],"provider":{"order":["openai"],"ignore":["azure"],"allow_fallbacks":false},"messages":[]}`,
+			wantRouting: &model.RequestRouting{Models: []string{"claude-sonnet-4", "gemini-2.5-pro"}, Provider: &model.ProviderPreferences{Order: []string{"openai"}, Ignore: []string{"azure"}, AllowFallbacks: &allowFallbacks}},
+			wantModel:   "gpt-4o",
+		},
+		{
+			name:        "未填写 model 时使用 models 中的第一个",
+			group:       "default",
+			body:        `{"models":["claude-sonnet-4","gpt-4o"],"messages":[]}`,
+			wantRouting: &model.RequestRouting{Models: []string{"claude-sonnet-4", "gpt-4o"}},
+			wantModel:   "claude-sonnet-4",
+		},
+		{
+			name:        "只有 provider",
+			group:       "default",
+			body:        `{"model":"gpt-4o","provider":{"only":["openai"]}}`,
+			wantRouting: &model.RequestRouting{Provider: &model.ProviderPreferences{Only: []string{"openai"}}},
+			wantModel:   "gpt-4o",
+		},
+		{
+			name:      "分组禁用时保留原请求体",
+			group:     "locked",
+			body:      `{"model":"gpt-4o","models":["claude-sonnet-4"]}`,
+			wantModel: "gpt-4o",
+		},
+		{
+			name:    "models 不是数组",
+			group:   "default",
+			body:    `{"model":"gpt-4o","models":"claude-sonnet-4"}`,
+			wantErr: true,
+		},
+		{
+			name:    "provider 格式错误",
+			group:   "default",
+			body:    `{"model":"gpt-4o","provider":{"order":"openai"}}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newRequestRoutingContext(tt.group, tt.body)
+			err := parseRequestRouting(c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRequestRouting() error = %v, 期望出错 %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if routing := getRequestRouting(c); !reflect.DeepEqual(routing, tt.wantRouting) {
+				t.Errorf("路由参数 = %+v, 期望 %+v", routing, tt.wantRouting)
+			}
+
+			raw, _ := c.Get(config.GinRequestBodyKey)
+			body, _ := raw.([]byte)
+			if got := gjson.GetBytes(body, "model").String(); got != tt.wantModel {
+				t.Errorf("请求体 model = %q, 期望 %q", got, tt.wantModel)
+			}
+			// 记录了路由参数时必须从请求体删除，分组禁用时原样保留
+			hasRouting := func(body []byte) bool {
+				return gjson.GetBytes(body, "models").Exists() || gjson.GetBytes(body, "provider").Exists()
+			}
+			wantKept := tt.wantRouting == nil && hasRouting([]byte(tt.body))
+			if kept := hasRouting(body); kept != wantKept {
+				t.Errorf("请求体 = %s, 期望保留路由参数 %v", body, wantKept)
+			}
+		})
+	}
+}
+
+// TestRequestRoutingModels 测试回退链：请求的 model 在前，去重并跳过令牌不允许使用的模型，最多 5 个
+func TestRequestRoutingModels(t *testing.T) {
+	tests := []struct {
+		name          string
+		modelName     string
+		models        []string
+		allowedModels []string // 令牌限制的模型，为空时不限制
+		want          []string
+	}{
+		{name: "没有 models", modelName: "gpt-4o", want: nil},
+		{name: "请求的 model 在最前", modelName: "gpt-4o", models: []string{"claude-sonnet-4", "gemini-2.5-pro"}, want: []string{"gpt-4o", "claude-sonnet-4", "gemini-2.5-pro"}},
+		{name: "去重并跳过空名称", modelName: "gpt-4o", models: []string{"gpt-4o", "", "claude-sonnet-4", "claude-sonnet-4"}, want: []string{"gpt-4o", "claude-sonnet-4"}},
+		{name: "只有请求的 model", modelName: "gpt-4o", models: []string{"gpt-4o"}, want: []string{"gpt-4o"}},
+		{name: "最多 5 个", modelName: "m0", models: []string{"m1", "m2", "m3", "m4", "m5", "m6"}, want: []string{"m0", "m1", "m2", "m3", "m4"}},
+		{
+			name:          "跳过令牌不允许使用的模型",
+			modelName:     "gpt-4o",
+			models:        []string{"claude-sonnet-4", "gemini-2.5-pro", "gpt-4o-mini"},
+			allowedModels: []string{"gpt-4o", "gpt-4o-mini"},
+			want:          []string{"gpt-4o", "gpt-4o-mini"},
+		},
+		{
+			name:          "不允许的模型不占用数量上限",
+			modelName:     "m0",
+			models:        []string{"x1", "m1", "x2", "m2", "m3", "m4", "m5"},
+			allowedModels: []string{"m0", "m1", "m2", "m3", "m4", "m5"},
+			want:          []string{"m0", "m1", "m2", "m3", "m4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newRoutingTestContext()
+			if tt.models != nil {
+				c.Set(config.GinRequestRoutingKey, &model.RequestRouting{Models: tt.models})
+			}
+			if len(tt.allowedModels) > 0 {
+				setting := &model.TokenSetting{}
+				setting.Limits.LimitModelSetting = model.LimitModelSetting{Enabled: true, Models: tt.allowedModels}
+				c.Set("token_setting", setting)
+			}
+
+			got := requestRoutingModels(c, tt.modelName)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("requestRoutingModels() = %v, 期望 %v", got, tt.want)
+			}
+
+			// 回退链不少于两个模型时才按模型列表回退
+			route := startVirtualModel(c, tt.modelName)
+			if wantRoute := len(tt.want) > 1; (route != nil) != wantRoute {
+				t.Fatalf("startVirtualModel() = %v, 期望创建回退进度 %v", route, wantRoute)
+			}
+			if route != nil && (route.Name() != tt.modelName || route.Step().Model != tt.modelName || route.RemainingSteps() != len(tt.want)-1) {
+				t.Errorf("回退进度 name = %s, step = %s, remaining = %d", route.Name(), route.Step().Model, route.RemainingSteps())
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package relay
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"done-hub/common"
+	"done-hub/common/cache"
+	"done-hub/common/config"
+	"done-hub/common/logger"
+	"done-hub/model"
+	"done-hub/relay/relay_util"
+	"done-hub/types"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+// responseCacheMaxBytes 单条响应超过该大小不缓存
+const responseCacheMaxBytes = 4 << 20
+
+// responseCachePaths 可以缓存的入口，只缓存结果只取决于请求体的接口
+var responseCachePaths = []string{
+	"/v1/chat/completions",
+	"/v1/completions",
+	"/v1/embeddings",
+	"/v1/responses",
+}
+
+// cachedResponse 缓存的一次成功响应，Body 为写给客户端的原始内容（流式时为完整的 SSE 文本）
+type cachedResponse struct {
+	Model            string
+	Stream           bool
+	Body             string
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// responseCacheSession 单次请求的响应缓存状态
+type responseCacheSession struct {
+	key      string
+	ttl      time.Duration
+	lookup   bool
+	recorder *relay_util.ResponseRecorder
+}
+
+// newResponseCacheSession 判断本次请求是否走响应缓存，不走时返回 nil。
+// key 由分组、模型与规范化后的请求体哈希组成，同分组下完全相同的请求共享缓存。
+// 客户端可用 Cache-Control: no-cache 跳过读取（仍会刷新缓存），no-store 完全绕过缓存。
+func newResponseCacheSession(c *gin.Context, relay RelayBaseInterface) *responseCacheSession {
+	if !config.ResponseCacheEnabled || !isResponseCachePath(c.Request.URL.Path) {
+		return nil
+	}
+	if !tokenResponseCacheEnabled(c) || c.GetInt("specific_channel_id") > 0 {
+		return nil
+	}
+
+	cacheControl := strings.ToLower(c.GetHeader("Cache-Control"))
+	if strings.Contains(cacheControl, "no-store") {
+		return nil
+	}
+
+	modelName := relay.getOriginalModel()
+	ttl := config.GetResponseCacheTTL(modelName)
+	if ttl <= 0 {
+		return nil
+	}
+
+	body, err := common.ReadBodyRaw(c)
+	if err != nil || len(body) == 0 || gjson.GetBytes(body, "background").Bool() {
+		return nil
+	}
+
+	hash, err := normalizedRequestHash(c.Request.URL.Path, relay.IsStream(), body)
+	if err != nil {
+		return nil
+	}
+
+	return &responseCacheSession{
+		key:    fmt.Sprintf("response_cache:%s:%s:%s", c.GetString("token_group"), modelName, hash),
+		ttl:    time.Duration(ttl) * time.Second,
+		lookup: !strings.Contains(cacheControl, "no-cache"),
+	}
+}
+
+func isResponseCachePath(path string) bool {
+	if strings.HasPrefix(path, "/v1/responses/") {
+		return false
+	}
+	for _, prefix := range responseCachePaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func tokenResponseCacheEnabled(c *gin.Context) bool {
+	setting, exists := c.Get("token_setting")
+	if !exists {
+		return false
+	}
+	tokenSetting, ok := setting.(*model.TokenSetting)
+	return ok && tokenSetting != nil && tokenSetting.ResponseCache.Enabled
+}
+
+// normalizedRequestHash 将请求体按 key 排序重新序列化后取哈希，忽略字段顺序与空白差异
+func normalizedRequestHash(path string, isStream bool, body []byte) (string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var request any
+	if err := decoder.Decode(&request); err != nil {
+		return "", err
+	}
+	normalized, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n%t\n", path, isStream)
+	hash.Write(normalized)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// replay 命中缓存时直接返回缓存内容并按 ResponseCacheHitRatio 计费，返回是否已处理
+func (s *responseCacheSession) replay(relay RelayBaseInterface) bool {
+	if !s.lookup {
+		return false
+	}
+
+	c := relay.getContext()
+	// 命中时不会经过 GetProvider，令牌的模型限制在这里单独校验，不通过时交给正常流程报错
+	if CheckLimitModel(c, relay.getOriginalModel()) != nil {
+		return false
+	}
+
+	entry, err := cache.GetResponseCache[cachedResponse](s.key)
+	if err != nil || entry.Stream != relay.IsStream() || entry.Body == "" {
+		return false
+	}
+
+	groupRatio := model.GlobalUserGroupRatio.GetBySymbol(c.GetString("token_group"))
+	if groupRatio == nil {
+		return false
+	}
+	c.Set("group_ratio", groupRatio.Ratio)
+	c.Set("original_model", relay.getOriginalModel())
+	c.Set("new_model", entry.Model)
+	c.Set(config.GinResponseCacheHitKey, true)
+
+	c.Header("X-Cache", "HIT")
+	responseCache(c, entry.Body, entry.Stream)
+
+	usage := &types.Usage{
+		PromptTokens:     entry.PromptTokens,
+		CompletionTokens: entry.CompletionTokens,
+		TotalTokens:      entry.PromptTokens + entry.CompletionTokens,
+	}
+	quota := relay_util.NewQuota(c, entry.Model, entry.PromptTokens)
+	quota.SetFirstResponseTime(time.Now())
+	quota.Consume(c, usage, entry.Stream)
+
+	return true
+}
+
+// capture 接管 c.Writer，记录本次写给客户端的内容，供成功后写入缓存
+func (s *responseCacheSession) capture(c *gin.Context) {
+	s.recorder = relay_util.RecordResponse(c)
+	c.Header("X-Cache", "MISS")
+}
+
+// store 请求成功后写入缓存，不完整或过大的响应不缓存
+func (s *responseCacheSession) store(relay RelayBaseInterface) {
+	if s.recorder == nil || s.recorder.Overflow() || s.recorder.Status() != http.StatusOK {
+		return
+	}
+
+	usage := relay.getProvider().GetUsage()
+	if usage == nil || usage.PromptTokens+usage.CompletionTokens == 0 {
+		return
+	}
+
+	var body string
+	if relay.IsStream() {
+		body = strings.Join(s.recorder.StreamEvents(), "\n\n") + "\n\n"
+		if !strings.Contains(body, "[DONE]") && !strings.Contains(body, "response.completed") {
+			return
+		}
+	} else {
+		body = strings.TrimSpace(s.recorder.String())
+		if !gjson.Valid(body) {
+			return
+		}
+	}
+	if len(body) > responseCacheMaxBytes {
+		return
+	}
+
+	entry := cachedResponse{
+		Model:            relay.getModelName(),
+		Stream:           relay.IsStream(),
+		Body:             body,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+	}
+	if err := cache.SetResponseCache(s.key, entry, s.ttl); err != nil {
+		logger.LogWarn(relay.getContext().Request.Context(), fmt.Sprintf("response cache set error: %s", err.Error()))
+	}
+}
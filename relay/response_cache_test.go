@@ -0,0 +1,297 @@
+package relay
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"done-hub/common/cache"
+	"done-hub/common/config"
+	"done-hub/common/logger"
+	"done-hub/model"
+	"done-hub/providers"
+	"done-hub/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// testCacheRelay 只用于响应缓存测试的 relay，请求体由测试直接给出
+type testCacheRelay struct {
+	relayBase
+	stream bool
+}
+
+func (r *testCacheRelay) send() (*types.OpenAIErrorWithStatusCode, bool) { return nil, true }
+func (r *testCacheRelay) getPromptTokens() (int, error)                  { return 0, nil }
+func (r *testCacheRelay) setRequest() error                              { return nil }
+func (r *testCacheRelay) IsStream() bool                                 { return r.stream }
+
+// setupResponseCache 开启响应缓存，使用内存缓存与内存 SQLite，命中计费按分组 default、倍率 1
+func setupResponseCache(t *testing.T) {
+	t.Helper()
+	if logger.Logger == nil {
+		logger.Logger = zap.NewNop()
+	}
+	gin.SetMode(gin.TestMode)
+
+	viper.Set("response_cache.memory_size", 64)
+	cache.InitCacheManager()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: gormLogger.Default.LogMode(gormLogger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err = db.AutoMigrate(&model.User{}, &model.Token{}, &model.Log{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	db.Create(&model.User{Id: 1, Username: "cache-user", Quota: 1000000})
+
+	savedDB, savedBatch, savedPricing := model.DB, config.BatchUpdateEnabled, model.PricingInstance
+	savedEnabled, savedTTL, savedHitRatio := config.ResponseCacheEnabled, config.ResponseCacheTTL, config.ResponseCacheHitRatio
+	model.GlobalUserGroupRatio.Lock()
+	savedGroups := model.GlobalUserGroupRatio.UserGroup
+	model.GlobalUserGroupRatio.UserGroup = map[string]*model.UserGroup{"default": {Symbol: "default", Ratio: 1}}
+	model.GlobalUserGroupRatio.Unlock()
+	t.Cleanup(func() {
+		model.DB, config.BatchUpdateEnabled, model.PricingInstance = savedDB, savedBatch, savedPricing
+		config.ResponseCacheEnabled, config.ResponseCacheTTL, config.ResponseCacheHitRatio = savedEnabled, savedTTL, savedHitRatio
+		config.SetResponseCacheModelTTLMap(map[string]int{})
+		model.GlobalUserGroupRatio.Lock()
+		model.GlobalUserGroupRatio.UserGroup = savedGroups
+		model.GlobalUserGroupRatio.Unlock()
+		sqlDB.Close()
+	})
+
+	model.DB, config.BatchUpdateEnabled = db, false
+	model.PricingInstance = &model.Pricing{Prices: map[string]*model.Price{
+		"gpt-4o": {Model: "gpt-4o", Type: model.TokensPriceType, Input: 2.5, Output: 10},
+	}}
+	config.ResponseCacheEnabled, config.ResponseCacheTTL, config.ResponseCacheHitRatio = true, 60, 0.5
+}
+
+type cacheRequest struct {
+	path         string
+	body         string
+	stream       bool
+	group        string
+	tokenId      int
+	cacheControl string
+	tokenOff     bool
+	channelId    int
+}
+
+func newCacheTestContext(req cacheRequest) (*gin.Context, *httptest.ResponseRecorder, *testCacheRelay) {
+	if req.path == "" {
+		req.path = "/v1/chat/completions"
+	}
+	if req.group == "" {
+		req.group = "default"
+	}
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, req.path, strings.NewReader(req.body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	if req.cacheControl != "" {
+		c.Request.Header.Set("Cache-Control", req.cacheControl)
+	}
+	c.Set("id", 1)
+	c.Set("token_id", req.tokenId)
+	c.Set("token_group", req.group)
+	c.Set("token_setting", &model.TokenSetting{ResponseCache: model.ResponseCacheSetting{Enabled: !req.tokenOff}})
+	if req.channelId > 0 {
+		c.Set("specific_channel_id", req.channelId)
+	}
+
+	relay := &testCacheRelay{relayBase: relayBase{c: c}, stream: req.stream}
+	relay.setOriginalModel("gpt-4o")
+	relay.modelName = "gpt-4o"
+	return c, recorder, relay
+}
+
+const cacheTestBody = `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}],"temperature":0.5}`
+
+// TestResponseCacheSession 测试哪些请求走响应缓存，以及缓存 key 由哪些内容决定：
+// 同一分组下规范化后相同的请求共享缓存，与令牌无关；分组、模型、路径、流式或请求内容不同时不共享
+func TestResponseCacheSession(t *testing.T) {
+	setupResponseCache(t)
+	config.SetResponseCacheModelTTLMap(map[string]int{"gpt-4o": 120})
+	base, _, baseRelay := newCacheTestContext(cacheRequest{body: cacheTestBody, tokenId: 1})
+	baseSession := newResponseCacheSession(base, baseRelay)
+	if baseSession == nil || !baseSession.lookup || baseSession.ttl.Seconds() != 120 {
+		t.Fatalf("基准请求的缓存会话 = %+v, 期望读取缓存且使用模型单独配置的 120 秒", baseSession)
+	}
+
+	tests := []struct {
+		name       string
+		req        cacheRequest
+		globalOff  bool
+		noSession  bool
+		sameKey    bool
+		wantLookup bool
+	}{
+		{name: "字段顺序与空白不同", req: cacheRequest{body: `{ "temperature":0.5, "messages":[{"content":"hi","role":"user"}], "model":"gpt-4o" }`, tokenId: 1}, sameKey: true, wantLookup: true},
+		{name: "同分组的其他令牌共享缓存", req: cacheRequest{body: cacheTestBody, tokenId: 2}, sameKey: true, wantLookup: true},
+		{name: "no-cache 只跳过读取", req: cacheRequest{body: cacheTestBody, tokenId: 1, cacheControl: "no-cache"}, sameKey: true},
+		{name: "参数不同", req: cacheRequest{body: strings.Replace(cacheTestBody, "0.5", "0.7", 1), tokenId: 1}, wantLookup: true},
+		{name: "分组不同", req: cacheRequest{body: cacheTestBody, tokenId: 1, group: "vip"}, wantLookup: true},
+		{name: "流式与非流式不同", req: cacheRequest{body: cacheTestBody, tokenId: 1, stream: true}, wantLookup: true},
+		{name: "路径不同", req: cacheRequest{path: "/v1/completions", body: cacheTestBody, tokenId: 1}, wantLookup: true},
+		{name: "系统未开启", req: cacheRequest{body: cacheTestBody}, globalOff: true, noSession: true},
+		{name: "令牌未开启", req: cacheRequest{body: cacheTestBody, tokenOff: true}, noSession: true},
+		{name: "指定渠道", req: cacheRequest{body: cacheTestBody, channelId: 3}, noSession: true},
+		{name: "no-store 完全绕过", req: cacheRequest{body: cacheTestBody, cacheControl: "no-store"}, noSession: true},
+		{name: "后台模式", req: cacheRequest{path: "/v1/responses", body: `{"model":"gpt-4o","input":"hi","background":true}`}, noSession: true},
+		{name: "不缓存的路径", req: cacheRequest{path: "/v1/responses/resp_1/cancel", body: cacheTestBody}, noSession: true},
+		{name: "非法 JSON", req: cacheRequest{body: `{"model":`}, noSession: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.ResponseCacheEnabled = !tt.globalOff
+			defer func() { config.ResponseCacheEnabled = true }()
+
+			c, _, relay := newCacheTestContext(tt.req)
+			session := newResponseCacheSession(c, relay)
+			if tt.noSession {
+				if session != nil {
+					t.Fatalf("缓存会话 = %+v, 期望不走缓存", session)
+				}
+				return
+			}
+			if session == nil {
+				t.Fatal("缓存会话为 nil, 期望走缓存")
+			}
+			if (session.key == baseSession.key) != tt.sameKey {
+				t.Errorf("key = %s, 与基准 %s 相同 = %v, 期望 %v", session.key, baseSession.key, !tt.sameKey, tt.sameKey)
+			}
+			if session.lookup != tt.wantLookup {
+				t.Errorf("lookup = %v, 期望 %v", session.lookup, tt.wantLookup)
+			}
+		})
+	}
+
+	config.SetResponseCacheModelTTLMap(map[string]int{"gpt-4o": 0})
+	c, _, relay := newCacheTestContext(cacheRequest{body: cacheTestBody})
+	if session := newResponseCacheSession(c, relay); session != nil {
+		t.Error("模型缓存时间为 0 时期望不走缓存")
+	}
+}
+
+// TestResponseCacheStoreAndReplay 测试成功的响应写入缓存，并在相同请求时原样返回、按命中倍率计费；
+// 非 200、过大、流式未完整结束或没有用量的响应不写入缓存
+func TestResponseCacheStoreAndReplay(t *testing.T) {
+	const (
+		jsonBody   = `{"id":"chatcmpl-1","object":"chat.completion","model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"hello"}}]}`
+		streamBody = "data: {\"choices\":[{\"delta\":{\"content\":\"hel\"}}]}\n\ndata: {\"choices\":[{\"delta\":{\"content\":\"lo\"}}]}\n\ndata: [DONE]\n\n"
+	)
+
+	tests := []struct {
+		name     string
+		stream   bool
+		status   int
+		response string
+		usage    *types.Usage
+		stored   bool
+	}{
+		{name: "非流式响应", status: http.StatusOK, response: jsonBody, usage: &types.Usage{PromptTokens: 10, CompletionTokens: 5}, stored: true},
+		{name: "流式响应", stream: true, status: http.StatusOK, response: streamBody, usage: &types.Usage{PromptTokens: 10, CompletionTokens: 5}, stored: true},
+		{name: "非 200 响应", status: http.StatusBadRequest, response: `{"error":{"message":"bad"}}`, usage: &types.Usage{PromptTokens: 10}},
+		{name: "响应过大", status: http.StatusOK, response: `{"content":"` + strings.Repeat("a", responseCacheMaxBytes) + `"}`, usage: &types.Usage{PromptTokens: 10}},
+		{name: "超出记录上限", status: http.StatusOK, response: `{"content":"` + strings.Repeat("a", 17<<20) + `"}`, usage: &types.Usage{PromptTokens: 10}},
+		{name: "流式未完整结束", stream: true, status: http.StatusOK, response: "data: {\"choices\":[]}\n\n", usage: &types.Usage{PromptTokens: 10}},
+		{name: "没有用量", status: http.StatusOK, response: jsonBody, usage: &types.Usage{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupResponseCache(t)
+			req := cacheRequest{body: cacheTestBody, stream: tt.stream, tokenId: 1}
+
+			// 第一次请求未命中，记录写给客户端的响应
+			c, recorder, relay := newCacheTestContext(req)
+			session := newResponseCacheSession(c, relay)
+			if session.replay(relay) {
+				t.Fatal("空缓存不应命中")
+			}
+			session.capture(c)
+			relay.provider = providers.GetProvider(&model.Channel{Type: config.ChannelTypeOpenAI, Key: "sk-test"}, c)
+			relay.provider.SetUsage(tt.usage)
+			c.Writer.WriteHeader(tt.status)
+			c.Writer.WriteString(tt.response)
+			session.store(relay)
+			if got := recorder.Header().Get("X-Cache"); got != "MISS" {
+				t.Errorf("未命中时 X-Cache = %q, 期望 MISS", got)
+			}
+
+			// 第二次相同请求
+			c, recorder, relay = newCacheTestContext(req)
+			hit := newResponseCacheSession(c, relay).replay(relay)
+			if hit != tt.stored {
+				t.Fatalf("命中缓存 = %v, 期望 %v", hit, tt.stored)
+			}
+			if !hit {
+				return
+			}
+
+			if recorder.Code != http.StatusOK || recorder.Header().Get("X-Cache") != "HIT" {
+				t.Errorf("命中时状态码 = %d, X-Cache = %q", recorder.Code, recorder.Header().Get("X-Cache"))
+			}
+			if got := recorder.Body.String(); got != tt.response {
+				t.Errorf("命中时响应 = %q, 期望 %q", got, tt.response)
+			}
+			if tt.stream && !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/event-stream") {
+				t.Errorf("流式命中 Content-Type = %q", recorder.Header().Get("Content-Type"))
+			}
+
+			consumeLog := &model.Log{}
+			if err := model.DB.Where("type = ?", model.LogTypeConsume).First(consumeLog).Error; err != nil {
+				t.Fatalf("命中后没有消费日志: %v", err)
+			}
+			if consumeLog.PromptTokens != tt.usage.PromptTokens || consumeLog.CompletionTokens != tt.usage.CompletionTokens || consumeLog.ModelName != "gpt-4o" {
+				t.Errorf("消费日志 = %d/%d %s, 期望按缓存记录的用量计费", consumeLog.PromptTokens, consumeLog.CompletionTokens, consumeLog.ModelName)
+			}
+
+			// 命中倍率为 0 时免费
+			config.ResponseCacheHitRatio = 0
+			c, _, relay = newCacheTestContext(req)
+			if !newResponseCacheSession(c, relay).replay(relay) {
+				t.Fatal("第三次请求期望命中缓存")
+			}
+			var quotas []int
+			model.DB.Model(&model.Log{}).Where("type = ?", model.LogTypeConsume).Order("id").Pluck("quota", &quotas)
+			if len(quotas) != 2 || quotas[0] <= 0 || quotas[1] != 0 {
+				t.Errorf("命中计费 = %v, 期望倍率 0.5 时计费、倍率 0 时免费", quotas)
+			}
+		})
+	}
+}
+
+// TestResponseCacheReplayModelLimit 测试令牌限制了可用模型时，命中缓存也不能绕过限制
+func TestResponseCacheReplayModelLimit(t *testing.T) {
+	setupResponseCache(t)
+	key := "response_cache:default:gpt-4o:"
+	c, _, relay := newCacheTestContext(cacheRequest{body: cacheTestBody})
+	session := newResponseCacheSession(c, relay)
+	if !strings.HasPrefix(session.key, key) {
+		t.Fatalf("key = %s, 期望以 %s 开头", session.key, key)
+	}
+	cache.SetResponseCache(session.key, cachedResponse{Model: "gpt-4o", Body: `{"id":"1"}`, PromptTokens: 1}, time.Minute)
+
+	setting := &model.TokenSetting{ResponseCache: model.ResponseCacheSetting{Enabled: true}}
+	setting.Limits.LimitModelSetting.Enabled = true
+	setting.Limits.LimitModelSetting.Models = []string{"gpt-4o-mini"}
+	c.Set("token_setting", setting)
+	if session.replay(relay) {
+		t.Error("令牌不允许的模型不应命中缓存")
+	}
+}
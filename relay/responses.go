@@ -4,6 +4,7 @@ import (
 	"done-hub/common"
 	"done-hub/common/config"
 	"done-hub/common/requester"
+	"done-hub/model"
 	providersBase "done-hub/providers/base"
 	"done-hub/relay/relay_util"
 	"done-hub/types"
@@ -37,6 +38,10 @@ func NewRelayResponsesCompact(c *gin.Context) *relayResponses {
 }
 
 func (r *relayResponses) setRequest() error {
+	if err := parseRequestRouting(r.c); err != nil {
+		return err
+	}
+
 	if err := common.UnmarshalBodyReusable(r.c, &r.responsesRequest); err != nil {
 		return err
 	}
@@ -63,6 +68,26 @@ func (r *relayResponses) getPromptTokens() (int, error) {
 	return common.CountTokenInputMessages(r.responsesRequest.Input, r.modelName, channel.PreCost), nil
 }
 
+func (r *relayResponses) routingContent() *model.RoutingContent {
+	hasImage := false
+	if items, ok := r.responsesRequest.Input.([]any); ok {
+		for _, item := range items {
+			m, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+			if m["type"] == types.ContentTypeInputImage || hasMessagePartType(m["content"], types.ContentTypeInputImage) {
+				hasImage = true
+				break
+			}
+		}
+	}
+
+	return model.NewRoutingContent(hasImage, len(r.responsesRequest.Tools) > 0, func() int {
+		return common.CountTokenInputMessages(r.responsesRequest.Input, r.getOriginalModel(), config.PreCostNotImage)
+	})
+}
+
 func (r *relayResponses) send() (err *types.OpenAIErrorWithStatusCode, done bool) {
 	r.responsesRequest.Model = r.modelName
 
@@ -0,0 +1,380 @@
+package responses
+
+import (
+	"done-hub/common"
+	"done-hub/common/config"
+	"done-hub/common/utils"
+	"done-hub/model"
+	"done-hub/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	// maxConversationItems 单次创建会话或追加 item 的数量上限，与 OpenAI 一致
+	maxConversationItems = 20
+	maxMetadataPairs     = 16
+	maxMetadataKeyLen    = 64
+	maxMetadataValueLen  = 512
+)
+
+// CreateConversation 处理 POST /v1/conversations
+func CreateConversation(c *gin.Context) {
+	request := &types.ConversationCreateRequest{}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(request); err != nil {
+			common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	metadata, err := marshalMetadata(request.Metadata)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userId := c.GetInt("id")
+	items, err := newConversationItems(userId, request.Items)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	conversation := &model.Conversation{
+		ID:       "conv_" + utils.GetRandomString(48),
+		UserId:   userId,
+		Metadata: metadata,
+	}
+	if err := conversation.Insert(items); err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, toConversationObject(conversation))
+}
+
+// RetrieveConversation 处理 GET /v1/conversations/:id
+func RetrieveConversation(c *gin.Context) {
+	conversation := getUserConversation(c)
+	if conversation == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, toConversationObject(conversation))
+}
+
+// UpdateConversation 处理 POST /v1/conversations/:id，只能修改 metadata
+func UpdateConversation(c *gin.Context) {
+	request := &types.ConversationUpdateRequest{}
+	if err := c.ShouldBindJSON(request); err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	metadata, err := marshalMetadata(request.Metadata)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	conversation := getUserConversation(c)
+	if conversation == nil {
+		return
+	}
+
+	if err := conversation.UpdateMetadata(metadata); err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, toConversationObject(conversation))
+}
+
+// DeleteConversation 处理 DELETE /v1/conversations/:id，会话中的 item 一并删除
+func DeleteConversation(c *gin.Context) {
+	conversationID := c.Param("id")
+	deleted, err := model.DeleteUserConversation(c.GetInt("id"), conversationID)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !deleted {
+		common.AbortWithMessage(c, http.StatusNotFound, fmt.Sprintf("Conversation with id '%s' not found.", conversationID))
+		return
+	}
+
+	c.JSON(http.StatusOK, &types.ConversationDeleteResponse{
+		ID:      conversationID,
+		Object:  "conversation.deleted",
+		Deleted: true,
+	})
+}
+
+// CreateConversationItems 处理 POST /v1/conversations/:id/items
+func CreateConversationItems(c *gin.Context) {
+	request := &types.ConversationItemsRequest{}
+	if err := c.ShouldBindJSON(request); err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(request.Items) == 0 {
+		common.AbortWithMessage(c, http.StatusBadRequest, "items: field required")
+		return
+	}
+
+	conversation := getUserConversation(c)
+	if conversation == nil {
+		return
+	}
+
+	items, err := newConversationItems(conversation.UserId, request.Items)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := conversation.AddItems(items); err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, toConversationItemList(items, false))
+}
+
+// ListConversationItems 处理 GET /v1/conversations/:id/items
+func ListConversationItems(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		common.AbortWithMessage(c, http.StatusBadRequest, "limit: must be between 1 and 100")
+		return
+	}
+
+	order := c.DefaultQuery("order", "desc")
+	if order != "asc" && order != "desc" {
+		common.AbortWithMessage(c, http.StatusBadRequest, "order: must be asc or desc")
+		return
+	}
+
+	conversation := getUserConversation(c)
+	if conversation == nil {
+		return
+	}
+
+	var after *model.ConversationItem
+	if afterID := c.Query("after"); afterID != "" {
+		item, err := model.GetConversationItem(conversation.ID, afterID)
+		if err != nil || item == nil {
+			common.AbortWithMessage(c, http.StatusBadRequest, fmt.Sprintf("after: item %s not found", afterID))
+			return
+		}
+		after = item
+	}
+
+	items, err := model.GetConversationItems(conversation.ID, after, limit+1, order == "asc")
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	hasMore := len(items) > limit
+	if hasMore {
+		items = items[:limit]
+	}
+
+	c.JSON(http.StatusOK, toConversationItemList(items, hasMore))
+}
+
+// RetrieveConversationItem 处理 GET /v1/conversations/:id/items/:item_id
+func RetrieveConversationItem(c *gin.Context) {
+	conversation := getUserConversation(c)
+	if conversation == nil {
+		return
+	}
+
+	itemID := c.Param("item_id")
+	item, err := model.GetConversationItem(conversation.ID, itemID)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if item == nil {
+		common.AbortWithMessage(c, http.StatusNotFound, fmt.Sprintf("Item with id '%s' not found.", itemID))
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json", item.Item)
+}
+
+// DeleteConversationItem 处理 DELETE /v1/conversations/:id/items/:item_id，返回所属会话
+func DeleteConversationItem(c *gin.Context) {
+	conversation := getUserConversation(c)
+	if conversation == nil {
+		return
+	}
+
+	itemID := c.Param("item_id")
+	deleted, err := model.DeleteConversationItem(conversation.ID, itemID)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !deleted {
+		common.AbortWithMessage(c, http.StatusNotFound, fmt.Sprintf("Item with id '%s' not found.", itemID))
+		return
+	}
+
+	c.JSON(http.StatusOK, toConversationObject(conversation))
+}
+
+func getUserConversation(c *gin.Context) *model.Conversation {
+	conversationID := c.Param("id")
+	conversation, err := model.GetUserConversation(c.GetInt("id"), conversationID)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return nil
+	}
+	if conversation == nil {
+		common.AbortWithMessage(c, http.StatusNotFound, fmt.Sprintf("Conversation with id '%s' not found.", conversationID))
+		return nil
+	}
+	return conversation
+}
+
+func toConversationObject(conversation *model.Conversation) *types.ConversationObject {
+	metadata := json.RawMessage(conversation.Metadata)
+	if len(metadata) == 0 {
+		metadata = json.RawMessage("{}")
+	}
+	return &types.ConversationObject{
+		ID:        conversation.ID,
+		Object:    "conversation",
+		CreatedAt: conversation.CreatedAt,
+		Metadata:  metadata,
+	}
+}
+
+func toConversationItemList(items []*model.ConversationItem, hasMore bool) *types.ConversationItemList {
+	list := &types.ConversationItemList{
+		Object:  "list",
+		Data:    make([]json.RawMessage, 0, len(items)),
+		HasMore: hasMore,
+	}
+	for _, item := range items {
+		list.Data = append(list.Data, json.RawMessage(item.Item))
+	}
+	if len(items) > 0 {
+		list.FirstID = &items[0].ItemID
+		list.LastID = &items[len(items)-1].ItemID
+	}
+	return list
+}
+
+// marshalMetadata 校验 metadata：最多 16 对，key 不超过 64 字符，value 不超过 512 字符
+func marshalMetadata(metadata map[string]string) ([]byte, error) {
+	if len(metadata) > maxMetadataPairs {
+		return nil, fmt.Errorf("metadata: must have at most %d key-value pairs", maxMetadataPairs)
+	}
+	for key, value := range metadata {
+		if len(key) > maxMetadataKeyLen {
+			return nil, fmt.Errorf("metadata: key %q exceeds %d characters", key, maxMetadataKeyLen)
+		}
+		if len(value) > maxMetadataValueLen {
+			return nil, fmt.Errorf("metadata: value of %q exceeds %d characters", key, maxMetadataValueLen)
+		}
+	}
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	return json.Marshal(metadata)
+}
+
+// newConversationItems 校验并规范化客户端写入的 item，超出用户存储限额时返回错误
+func newConversationItems(userId int, rawItems []json.RawMessage) ([]*model.ConversationItem, error) {
+	if len(rawItems) > maxConversationItems {
+		return nil, fmt.Errorf("items: must have at most %d items", maxConversationItems)
+	}
+
+	items := make([]*model.ConversationItem, 0, len(rawItems))
+	var size int64
+	for i, raw := range rawItems {
+		item, err := normalizeConversationItem(gjson.ParseBytes(raw))
+		if err != nil {
+			return nil, fmt.Errorf("items[%d]: %s", i, err.Error())
+		}
+		items = append(items, item)
+		size += int64(len(item.Item))
+	}
+
+	if err := checkStorageQuota(userId, size); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// normalizeConversationItem 把输入 item 转成会话中保存的形式：
+// 省略 type 的消息补全为 message，字符串 content 展开为内容数组，没有 id 的 item 生成 id
+func normalizeConversationItem(item gjson.Result) (*model.ConversationItem, error) {
+	if !item.IsObject() {
+		return nil, errors.New("must be an object")
+	}
+
+	raw := item.Raw
+	itemType := item.Get("type").String()
+	if itemType == "" {
+		if !item.Get("role").Exists() {
+			return nil, errors.New("type: field required")
+		}
+		itemType = types.InputTypeMessage
+		raw, _ = sjson.Set(raw, "type", itemType)
+	}
+
+	if itemType == types.InputTypeMessage {
+		role := item.Get("role").String()
+		switch role {
+		case types.ChatMessageRoleUser, types.ChatMessageRoleAssistant, types.ChatMessageRoleSystem, types.ChatMessageRoleDeveloper:
+		default:
+			return nil, fmt.Errorf("role: unsupported role %q", role)
+		}
+
+		if content := item.Get("content"); content.Type == gjson.String {
+			partType := types.ContentTypeInputText
+			if role == types.ChatMessageRoleAssistant {
+				partType = types.ContentTypeOutputText
+			}
+			part, _ := sjson.Set(`{"type":"`+partType+`"}`, "text", content.String())
+			raw, _ = sjson.SetRaw(raw, "content", "["+part+"]")
+		}
+		if !item.Get("status").Exists() {
+			raw, _ = sjson.Set(raw, "status", types.ResponseStatusCompleted)
+		}
+	}
+
+	raw = withItemID(raw)
+	return &model.ConversationItem{
+		ItemID: gjson.Get(raw, "id").String(),
+		Item:   []byte(raw),
+	}, nil
+}
+
+// checkStorageQuota 文件与会话 item 共用 FileStorageQuota 限额
+func checkStorageQuota(userId int, size int64) error {
+	if config.FileStorageQuota <= 0 || size == 0 {
+		return nil
+	}
+
+	used, err := model.GetUserStorageBytes(userId)
+	if err != nil {
+		return err
+	}
+	if used+size > int64(config.FileStorageQuota)<<20 {
+		return fmt.Errorf("storage quota of %d MB exceeded", config.FileStorageQuota)
+	}
+	return nil
+}
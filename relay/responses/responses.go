@@ -0,0 +1,115 @@
+package responses
+
+import (
+	"done-hub/common"
+	"done-hub/model"
+	"done-hub/types"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+// RetrieveResponse 处理 GET /v1/responses/:id
+func RetrieveResponse(c *gin.Context) {
+	response := getUserResponse(c)
+	if response == nil {
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json", response.Object)
+}
+
+// DeleteResponse 处理 DELETE /v1/responses/:id
+func DeleteResponse(c *gin.Context) {
+	responseID := c.Param("id")
+	deleted, err := model.DeleteUserResponse(c.GetInt("id"), responseID)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !deleted {
+		common.AbortWithMessage(c, http.StatusNotFound, fmt.Sprintf("Response with id '%s' not found.", responseID))
+		return
+	}
+
+	c.JSON(http.StatusOK, &types.ResponsesDeleteResponse{
+		ID:      responseID,
+		Object:  "response",
+		Deleted: true,
+	})
+}
+
+// ListInputItems 处理 GET /v1/responses/:id/input_items
+func ListInputItems(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		common.AbortWithMessage(c, http.StatusBadRequest, "limit: must be between 1 and 100")
+		return
+	}
+
+	order := c.DefaultQuery("order", "desc")
+	if order != "asc" && order != "desc" {
+		common.AbortWithMessage(c, http.StatusBadRequest, "order: must be asc or desc")
+		return
+	}
+
+	response := getUserResponse(c)
+	if response == nil {
+		return
+	}
+
+	items := make([]json.RawMessage, 0)
+	gjson.ParseBytes(response.Input).ForEach(func(_, item gjson.Result) bool {
+		items = append(items, json.RawMessage(item.Raw))
+		return true
+	})
+	if order == "desc" {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	if after := c.Query("after"); after != "" {
+		for i, item := range items {
+			if gjson.GetBytes(item, "id").String() == after {
+				items = items[i+1:]
+				break
+			}
+		}
+	}
+
+	list := &types.ResponsesInputItemList{
+		Object:  "list",
+		Data:    items,
+		HasMore: len(items) > limit,
+	}
+	if list.HasMore {
+		list.Data = items[:limit]
+	}
+	if len(list.Data) > 0 {
+		firstID := gjson.GetBytes(list.Data[0], "id").String()
+		lastID := gjson.GetBytes(list.Data[len(list.Data)-1], "id").String()
+		list.FirstID = &firstID
+		list.LastID = &lastID
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+func getUserResponse(c *gin.Context) *model.Response {
+	responseID := c.Param("id")
+	response, err := model.GetUserResponse(c.GetInt("id"), responseID)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return nil
+	}
+	if response == nil {
+		common.AbortWithMessage(c, http.StatusNotFound, fmt.Sprintf("Response with id '%s' not found.", responseID))
+		return nil
+	}
+	return response
+}
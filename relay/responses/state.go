@@ -0,0 +1,329 @@
+package responses
+
+import (
+	"done-hub/common"
+	"done-hub/common/config"
+	"done-hub/common/logger"
+	"done-hub/common/utils"
+	"done-hub/model"
+	"done-hub/relay/relay_util"
+	"done-hub/types"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// maxPreviousResponses 展开 previous_response_id 时最多回溯的轮次
+const maxPreviousResponses = 100
+
+// State 一次 Responses 请求在网关侧的状态，响应成功后据此保存
+type State struct {
+	userId       int
+	tokenId      int
+	previousID   string
+	input        string
+	store        bool
+	conversation *model.Conversation
+	recorder     *relay_util.ResponseRecorder
+}
+
+// Prepare 处理 /v1/responses 与 /v1/responses/compact 的请求体：
+// previous_response_id 指向网关保存的响应、或指定了 conversation 时，展开为完整的历史 item 写回请求体，渠道无需自行支持；
+// previous_response_id 找不到时原样透传，交给上游处理。返回需要在响应后保存的状态，不需要保存时返回 nil。
+func Prepare(c *gin.Context) (*State, *types.OpenAIErrorWithStatusCode) {
+	path := c.Request.URL.Path
+	if path != "/v1/responses" && path != "/v1/responses/compact" {
+		return nil, nil
+	}
+
+	body, err := common.ReadBodyRaw(c)
+	if err != nil || !gjson.ValidBytes(body) {
+		return nil, nil
+	}
+
+	state := &State{
+		userId:     c.GetInt("id"),
+		tokenId:    c.GetInt("token_id"),
+		previousID: gjson.GetBytes(body, "previous_response_id").String(),
+		input:      normalizeInput(gjson.GetBytes(body, "input")),
+	}
+
+	conversationID := gjson.GetBytes(body, "conversation")
+	if conversationID.IsObject() {
+		conversationID = conversationID.Get("id")
+	}
+	background := gjson.GetBytes(body, "background").Bool()
+
+	var history []string
+	switch {
+	case conversationID.String() != "":
+		if state.previousID != "" {
+			return nil, common.StringErrorWrapperLocal("Mutually exclusive parameters: Ensure you are only providing one of: 'previous_response_id' or 'conversation'.", "invalid_request_error", http.StatusBadRequest)
+		}
+		if background {
+			return nil, common.StringErrorWrapperLocal("background mode is not supported with conversation", "invalid_request_error", http.StatusBadRequest)
+		}
+		if state.conversation, err = model.GetUserConversation(state.userId, conversationID.String()); err != nil {
+			return nil, common.ErrorWrapperLocal(err, "one_hub_error", http.StatusInternalServerError)
+		}
+		if state.conversation == nil {
+			return nil, common.StringErrorWrapperLocal(fmt.Sprintf("Conversation with id '%s' not found.", conversationID.String()), "invalid_request_error", http.StatusNotFound)
+		}
+		if err = checkStorageQuota(state.userId, int64(len(state.input))); err != nil {
+			return nil, common.ErrorWrapperLocal(err, "invalid_request_error", http.StatusBadRequest)
+		}
+		if history, err = loadConversationHistory(state.conversation.ID); err != nil {
+			return nil, common.ErrorWrapperLocal(err, "one_hub_error", http.StatusInternalServerError)
+		}
+		body, err = sjson.DeleteBytes(body, "conversation")
+	case state.previousID != "":
+		if history, err = loadHistory(state.userId, state.previousID); err != nil {
+			return nil, common.ErrorWrapperLocal(err, "one_hub_error", http.StatusInternalServerError)
+		}
+		if history != nil {
+			body, err = sjson.DeleteBytes(body, "previous_response_id")
+		}
+	}
+	if err != nil {
+		return nil, common.ErrorWrapperLocal(err, "one_hub_error", http.StatusInternalServerError)
+	}
+
+	if history != nil || state.conversation != nil {
+		if body, err = sjson.SetRawBytes(body, "input", []byte(mergeItems(history, state.input))); err != nil {
+			return nil, common.ErrorWrapperLocal(err, "one_hub_error", http.StatusInternalServerError)
+		}
+		c.Set(config.GinRequestBodyKey, body)
+	}
+
+	if path != "/v1/responses" {
+		return nil, nil
+	}
+	store := gjson.GetBytes(body, "store")
+	state.store = config.ResponsesStoreEnabled && (!store.Exists() || store.Bool()) && !background
+	if !state.store && state.conversation == nil {
+		return nil, nil
+	}
+
+	return state, nil
+}
+
+// normalizeInput 把 input 统一为 item 数组，字符串输入视为一条 user 消息
+func normalizeInput(input gjson.Result) string {
+	switch {
+	case !input.Exists():
+		return "[]"
+	case input.Type == gjson.String:
+		item, _ := sjson.Set(`{"type":"message","role":"user"}`, "content", input.String())
+		return "[" + item + "]"
+	case input.IsArray():
+		return input.Raw
+	default:
+		return "[" + input.Raw + "]"
+	}
+}
+
+// loadHistory 沿 previous_response_id 回溯，按时间顺序返回历史 item；起点不存在时返回 nil。
+// 中间某轮已过期或被删除时，只使用其后的轮次。
+func loadHistory(userId int, responseID string) ([]string, error) {
+	chain := make([]*model.Response, 0)
+	for responseID != "" && len(chain) < maxPreviousResponses {
+		response, err := model.GetUserResponse(userId, responseID)
+		if err != nil {
+			return nil, err
+		}
+		if response == nil {
+			break
+		}
+		chain = append(chain, response)
+		responseID = response.PreviousResponseID
+	}
+	if len(chain) == 0 {
+		return nil, nil
+	}
+
+	items := make([]string, 0)
+	for i := len(chain) - 1; i >= 0; i-- {
+		gjson.ParseBytes(chain[i].Input).ForEach(func(_, item gjson.Result) bool {
+			if raw, ok := historyItem(item); ok {
+				items = append(items, raw)
+			}
+			return true
+		})
+		gjson.GetBytes(chain[i].Object, "output").ForEach(func(_, item gjson.Result) bool {
+			if raw, ok := historyItem(item); ok {
+				items = append(items, raw)
+			}
+			return true
+		})
+	}
+	return items, nil
+}
+
+// loadConversationHistory 按写入顺序返回会话中的全部 item
+func loadConversationHistory(conversationID string) ([]string, error) {
+	conversationItems, err := model.GetConversationItems(conversationID, nil, 0, true)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]string, 0, len(conversationItems))
+	for _, conversationItem := range conversationItems {
+		if raw, ok := historyItem(gjson.ParseBytes(conversationItem.Item)); ok {
+			items = append(items, raw)
+		}
+	}
+	return items, nil
+}
+
+// historyItem 把保存的 item 转成可以再次作为输入发送的形式。
+// 网关生成或上游返回的 id 在其它渠道上没有意义，一律去掉；
+// reasoning 只有带 encrypted_content 时才能脱离上游存储复用，否则丢弃。
+func historyItem(item gjson.Result) (string, bool) {
+	if item.Get("type").String() == types.InputTypeReasoning {
+		return item.Raw, item.Get("encrypted_content").String() != ""
+	}
+
+	raw, err := sjson.Delete(item.Raw, "id")
+	if err != nil {
+		return "", false
+	}
+	return raw, true
+}
+
+func mergeItems(history []string, input string) string {
+	items := history
+	gjson.Parse(input).ForEach(func(_, item gjson.Result) bool {
+		items = append(items, item.Raw)
+		return true
+	})
+	return "[" + strings.Join(items, ",") + "]"
+}
+
+// Cacheable 需要保存响应或写入会话的请求不能走响应缓存：
+// 命中缓存会返回其它请求的响应 id，且不会保存本轮响应
+func (s *State) Cacheable() bool {
+	return s == nil
+}
+
+// Capture 开始记录写给客户端的响应
+func (s *State) Capture(c *gin.Context) {
+	s.recorder = relay_util.RecordResponse(c)
+}
+
+// Save 请求成功后保存响应对象与本轮输入，使用会话时把本轮输入与输出追加到会话
+func (s *State) Save(c *gin.Context, isStream bool) {
+	if s.recorder == nil || s.recorder.Overflow() || s.recorder.Status() != http.StatusOK {
+		return
+	}
+
+	var object string
+	if isStream {
+		object = finalStreamResponse(s.recorder.StreamEvents())
+	} else {
+		object = strings.TrimSpace(s.recorder.String())
+	}
+
+	result := gjson.Parse(object)
+	responseID := result.Get("id").String()
+	status := result.Get("status").String()
+	if responseID == "" || (status != types.ResponseStatusCompleted && status != types.ResponseStatusIncomplete) {
+		return
+	}
+
+	input := assignItemIDs(s.input)
+	if s.conversation != nil {
+		object, _ = sjson.Set(object, "conversation.id", s.conversation.ID)
+		s.appendConversation(c, input, result.Get("output"))
+	}
+	if !s.store {
+		return
+	}
+
+	if s.previousID != "" {
+		object, _ = sjson.Set(object, "previous_response_id", s.previousID)
+	}
+
+	response := &model.Response{
+		ID:                 responseID,
+		UserId:             s.userId,
+		TokenId:            s.tokenId,
+		Model:              result.Get("model").String(),
+		PreviousResponseID: s.previousID,
+		Input:              []byte(input),
+		Object:             []byte(object),
+	}
+	if config.ResponsesRetentionDays > 0 {
+		response.ExpiresAt = time.Now().AddDate(0, 0, config.ResponsesRetentionDays).Unix()
+	}
+
+	if err := response.Insert(); err != nil {
+		logger.LogError(c.Request.Context(), fmt.Sprintf("save response %s error: %s", responseID, err.Error()))
+	}
+}
+
+// appendConversation 把本轮输入与输出 item 追加到会话
+func (s *State) appendConversation(c *gin.Context, input string, output gjson.Result) {
+	items := make([]*model.ConversationItem, 0)
+	appendItem := func(_, item gjson.Result) bool {
+		raw := withItemID(item.Raw)
+		items = append(items, &model.ConversationItem{
+			ItemID: gjson.Get(raw, "id").String(),
+			Item:   []byte(raw),
+		})
+		return true
+	}
+	gjson.Parse(input).ForEach(appendItem)
+	output.ForEach(appendItem)
+
+	if err := s.conversation.AddItems(items); err != nil {
+		logger.LogError(c.Request.Context(), fmt.Sprintf("append conversation %s items error: %s", s.conversation.ID, err.Error()))
+	}
+}
+
+// finalStreamResponse 从流式事件中取出最终的响应对象
+func finalStreamResponse(events []string) string {
+	for i := len(events) - 1; i >= 0; i-- {
+		for _, line := range strings.Split(events[i], "\n") {
+			data, ok := strings.CutPrefix(line, "data:")
+			if !ok {
+				continue
+			}
+			event := gjson.Parse(strings.TrimSpace(data))
+			switch event.Get("type").String() {
+			case "response.completed", "response.incomplete":
+				return event.Get("response").Raw
+			}
+		}
+	}
+	return ""
+}
+
+// assignItemIDs 为没有 id 的输入 item 生成 id，供 input_items 列表分页使用
+func assignItemIDs(input string) string {
+	items := make([]string, 0)
+	gjson.Parse(input).ForEach(func(_, item gjson.Result) bool {
+		items = append(items, withItemID(item.Raw))
+		return true
+	})
+	return "[" + strings.Join(items, ",") + "]"
+}
+
+// withItemID item 没有 id 时生成一个，消息使用 msg_ 前缀，其余使用 item_ 前缀
+func withItemID(raw string) string {
+	item := gjson.Parse(raw)
+	if item.Get("id").String() != "" {
+		return raw
+	}
+
+	prefix := "item"
+	if itemType := item.Get("type").String(); itemType == types.InputTypeMessage || itemType == "" {
+		prefix = "msg"
+	}
+	raw, _ = sjson.Set(raw, "id", prefix+"_"+utils.GetRandomString(32))
+	return raw
+}
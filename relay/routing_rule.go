@@ -0,0 +1,39 @@
+package relay
+
+import (
+	"done-hub/common/config"
+	"done-hub/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// routingContentProvider 由能识别请求内容的 relay 实现，供分组的内容路由规则匹配。
+// 输入 tokens 在选渠道前按原始模型估算，不计算图片，避免选渠道前下载图片。
+type routingContentProvider interface {
+	routingContent() *model.RoutingContent
+}
+
+// setRoutingContent 在选择渠道前记录请求内容特征，fetchChannelByModel 据此按当前分组的路由规则过滤渠道
+func setRoutingContent(c *gin.Context, relay RelayBaseInterface) {
+	provider, ok := relay.(routingContentProvider)
+	if !ok {
+		return
+	}
+
+	c.Set(config.GinRoutingContentKey, provider.routingContent())
+}
+
+// hasMessagePartType 判断消息内容中是否有指定类型的片段
+func hasMessagePartType(content any, partType string) bool {
+	parts, ok := content.([]any)
+	if !ok {
+		return false
+	}
+	for _, part := range parts {
+		if m, ok := part.(map[string]any); ok && m["type"] == partType {
+			return true
+		}
+	}
+
+	return false
+}
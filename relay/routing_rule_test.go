@@ -0,0 +1,194 @@
+package relay
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"done-hub/common/config"
+	"done-hub/common/utils"
+	"done-hub/model"
+	"done-hub/providers/claude"
+	"done-hub/providers/gemini"
+
+	"gorm.io/datatypes"
+)
+
+// TestRoutingContentFilters 测试各类请求识别出的内容特征，以及按分组路由规则生成的渠道过滤器
+func TestRoutingContentFilters(t *testing.T) {
+	savedDisable := config.DisableTokenEncoders
+	t.Cleanup(func() { config.DisableTokenEncoders = savedDisable })
+	// 按字符数估算输入 tokens，避免加载编码器
+	config.DisableTokenEncoders = true
+
+	rules := datatypes.NewJSONType([]model.RoutingRule{
+		{Name: "长上下文", MinPromptTokens: 1000, Require: []string{model.ChannelCapabilityLongContext}},
+		{Name: "图片", HasImage: true, Require: []string{model.ChannelCapabilityVision}},
+		{Name: "工具", HasTools: true, Exclude: []string{model.ChannelCapabilityNoFunctionCalling}},
+	})
+	model.GlobalUserGroupRatio.Lock()
+	savedGroups := model.GlobalUserGroupRatio.UserGroup
+	model.GlobalUserGroupRatio.UserGroup = map[string]*model.UserGroup{
+		"routed": {Symbol: "routed", Ratio: 1, RoutingRules: &rules},
+		"plain":  {Symbol: "plain", Ratio: 1},
+	}
+	model.GlobalUserGroupRatio.Unlock()
+	t.Cleanup(func() {
+		model.GlobalUserGroupRatio.Lock()
+		model.GlobalUserGroupRatio.UserGroup = savedGroups
+		model.GlobalUserGroupRatio.Unlock()
+	})
+
+	longText := strings.Repeat("hello world ", 500)
+
+	tests := []struct {
+		name        string
+		relay       string // chat、claude、responses、gemini、other
+		group       string
+		body        string
+		wantImage   bool
+		wantTools   bool
+		wantFilters int
+	}{
+		{name: "Chat 纯文本", relay: "chat", group: "routed", body: `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`},
+		{
+			name:        "Chat 图片",
+			relay:       "chat",
+			group:       "routed",
+			body:        `{"model":"gpt-4o","messages":[{"role":"user","content":[{"type":"text","text":"what"},{"type":"image_url","image_url":{"url":"https://example.com/a.png"}}]}]}`,
+			wantImage:   true,
+			wantFilters: 1,
+		},
+		{
+			name:        "Chat 工具",
+			relay:       "chat",
+			group:       "routed",
+			body:        `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}],"tools":[{"type":"function","function":{"name":"f"}}]}`,
+			wantTools:   true,
+			wantFilters: 1,
+		},
+		{
+			name:        "Chat 旧版 functions",
+			relay:       "chat",
+			group:       "routed",
+			body:        `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}],"functions":[{"name":"f"}]}`,
+			wantTools:   true,
+			wantFilters: 1,
+		},
+		{
+			name:        "Chat 长上下文",
+			relay:       "chat",
+			group:       "routed",
+			body:        `{"model":"gpt-4o","messages":[{"role":"user","content":"` + longText + `"}]}`,
+			wantFilters: 1,
+		},
+		{
+			name:        "Chat 长上下文、图片与工具",
+			relay:       "chat",
+			group:       "routed",
+			body:        `{"model":"gpt-4o","messages":[{"role":"user","content":[{"type":"text","text":"` + longText + `"},{"type":"image_url","image_url":{"url":"https://example.com/a.png"}}]}],"tools":[{"type":"function","function":{"name":"f"}}]}`,
+			wantImage:   true,
+			wantTools:   true,
+			wantFilters: 3,
+		},
+		{
+			name:      "分组没有规则",
+			relay:     "chat",
+			group:     "plain",
+			body:      `{"model":"gpt-4o","messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"https://example.com/a.png"}}]}],"tools":[{"type":"function","function":{"name":"f"}}]}`,
+			wantImage: true,
+			wantTools: true,
+		},
+		{
+			name:        "Claude 图片与工具",
+			relay:       "claude",
+			group:       "routed",
+			body:        `{"model":"claude-sonnet-4","messages":[{"role":"user","content":[{"type":"image","source":{"type":"base64","media_type":"image/png","data":"AAAA"}}]}],"tools":[{"name":"f","input_schema":{"type":"object"}}]}`,
+			wantImage:   true,
+			wantTools:   true,
+			wantFilters: 2,
+		},
+		{name: "Claude 纯文本", relay: "claude", group: "routed", body: `{"model":"claude-sonnet-4","messages":[{"role":"user","content":"hi"}]}`},
+		{
+			name:        "Responses 顶层图片",
+			relay:       "responses",
+			group:       "routed",
+			body:        `{"model":"gpt-4o","input":[{"type":"input_image","image_url":"https://example.com/a.png"}]}`,
+			wantImage:   true,
+			wantFilters: 1,
+		},
+		{
+			name:        "Responses 消息内图片与工具",
+			relay:       "responses",
+			group:       "routed",
+			body:        `{"model":"gpt-4o","input":[{"role":"user","content":[{"type":"input_image","image_url":"https://example.com/a.png"}]}],"tools":[{"type":"function","name":"f"}]}`,
+			wantImage:   true,
+			wantTools:   true,
+			wantFilters: 2,
+		},
+		{name: "Responses 字符串输入", relay: "responses", group: "routed", body: `{"model":"gpt-4o","input":"hi"}`},
+		{
+			name:        "Gemini 图片",
+			relay:       "gemini",
+			group:       "routed",
+			body:        `{"contents":[{"role":"user","parts":[{"inlineData":{"mimeType":"image/png","data":"AAAA"}}]}]}`,
+			wantImage:   true,
+			wantFilters: 1,
+		},
+		{
+			name:        "Gemini 非图片附件与工具",
+			relay:       "gemini",
+			group:       "routed",
+			body:        `{"contents":[{"role":"user","parts":[{"file_data":{"mime_type":"application/pdf","file_uri":"gs://a.pdf"}}]}],"tools":[{"functionDeclarations":[{"name":"f"}]}]}`,
+			wantTools:   true,
+			wantFilters: 1,
+		},
+		{name: "不识别内容的请求", relay: "other", group: "routed", body: `{}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newRoutingTestContext()
+			c.Set("token_group", tt.group)
+			base := relayBase{c: c, originalModel: "gpt-4o"}
+
+			var relay RelayBaseInterface
+			var err error
+			switch tt.relay {
+			case "chat":
+				chat := &relayChat{relayBase: base}
+				err = json.Unmarshal([]byte(tt.body), &chat.chatRequest)
+				relay = chat
+			case "claude":
+				claudeRelay := &relayClaudeOnly{relayBase: base, claudeRequest: &claude.ClaudeRequest{}}
+				err = json.Unmarshal([]byte(tt.body), claudeRelay.claudeRequest)
+				relay = claudeRelay
+			case "responses":
+				responses := &relayResponses{relayBase: base}
+				err = json.Unmarshal([]byte(tt.body), &responses.responsesRequest)
+				relay = responses
+			case "gemini":
+				relay = &relayGeminiOnly{relayBase: base, requestBody: []byte(tt.body), geminiRequest: &gemini.GeminiChatRequest{Model: "gemini-2.5-pro"}}
+			default:
+				relay = &testRoutingRelay{relayBase: base}
+			}
+			if err != nil {
+				t.Fatalf("unmarshal request: %v", err)
+			}
+
+			setRoutingContent(c, relay)
+			content, ok := utils.GetGinValue[*model.RoutingContent](c, config.GinRoutingContentKey)
+			if tt.relay == "other" {
+				if ok {
+					t.Error("不识别内容的请求不应记录内容特征")
+				}
+			} else if !ok || content.HasImage != tt.wantImage || content.HasTools != tt.wantTools {
+				t.Fatalf("内容特征 = %+v, 期望 图片 %v、工具 %v", content, tt.wantImage, tt.wantTools)
+			}
+
+			if filters := buildChannelFilters(c, "gpt-4o"); len(filters) != tt.wantFilters {
+				t.Errorf("过滤器数量 = %d, 期望 %d", len(filters), tt.wantFilters)
+			}
+		})
+	}
+}
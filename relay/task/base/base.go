@@ -2,6 +2,7 @@ package base
 
 import (
 	"context"
+	"done-hub/common/config"
 	"done-hub/model"
 	"done-hub/providers/base"
 	"done-hub/relay"
@@ -21,6 +22,8 @@ type TaskBase struct {
 	OriginTaskID  string
 	BaseProvider  base.ProviderInterface
 	Response      any
+	// NotifyHook 请求中指定的回调地址，任务完成后由网关签名投递，不再透传给上游
+	NotifyHook string
 }
 
 type TaskInterface interface {
@@ -47,7 +50,23 @@ func (t *TaskBase) InitTask() {
 		SubmitTime: time.Now().Unix(),
 		Status:     model.TaskStatusNotStart,
 		Progress:   0,
+		NotifyHook: t.NotifyHook,
 	}
+	// 上游提交成功后才创建任务，此时已确定本次使用的密钥
+	t.Task.ChannelKeyId = t.C.GetInt(config.GinChannelKeyIdKey)
+}
+
+// GroupTaskIdsByChannelKey 按提交任务时使用的密钥分组，多密钥渠道需用同一个密钥查询任务
+func GroupTaskIdsByChannelKey(taskIds []string, taskM map[string]*model.Task) map[int][]string {
+	groups := make(map[int][]string)
+	for _, taskId := range taskIds {
+		keyId := 0
+		if task, ok := taskM[taskId]; ok {
+			keyId = task.ChannelKeyId
+		}
+		groups[keyId] = append(groups[keyId], taskId)
+	}
+	return groups
 }
 
 func (t *TaskBase) GetModelName() string {
@@ -92,6 +111,7 @@ func (t *TaskBase) HandleOriginTaskID() error {
 	}
 
 	t.C.Set("specific_channel_id", task.ChannelId)
+	t.C.Set(config.GinSpecificChannelKeyIdKey, task.ChannelKeyId)
 
 	return nil
 }
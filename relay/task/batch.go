@@ -0,0 +1,21 @@
+package task
+
+import (
+	"done-hub/relay/task/batch"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RelayClaudeBatchCreate 创建 Message Batch，入库后唤醒任务轮询推进批次状态
+func RelayClaudeBatchCreate(c *gin.Context) {
+	if batch.CreateClaudeBatch(c) {
+		ActivateUpdateTaskBulk()
+	}
+}
+
+// RelayOpenAIBatchCreate 创建 OpenAI 批次，入库后唤醒任务轮询推进批次状态
+func RelayOpenAIBatchCreate(c *gin.Context) {
+	if batch.CreateOpenAIBatch(c) {
+		ActivateUpdateTaskBulk()
+	}
+}
@@ -0,0 +1,191 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"done-hub/common"
+	"done-hub/common/config"
+	"done-hub/common/logger"
+	"done-hub/common/utils"
+	"done-hub/middleware"
+	"done-hub/model"
+	"done-hub/providers/claude"
+	"done-hub/relay/task/base"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ModePassthrough 整批透传给 Anthropic 官方渠道，由上游执行
+	ModePassthrough = "passthrough"
+	// ModeGateway 由网关逐条代执行，条目走常规 relay 流程（可转换到非 Anthropic 渠道）
+	ModeGateway = "gateway"
+
+	// batchExpireDuration 与 Anthropic 一致：24 小时内未执行完的条目置为 expired
+	batchExpireDuration = 24 * time.Hour
+	maxBatchRequests    = 100000
+
+	// OpenAI 批次的 completion_window 上限与单个输入文件的请求数上限
+	maxCompletionWindow    = 7 * 24 * time.Hour
+	maxOpenAIBatchRequests = 50000
+)
+
+// Properties 批次元信息，序列化后存放在 Task.Properties
+type Properties struct {
+	Mode              string `json:"mode"`
+	Total             int    `json:"total"`
+	ExpiresAt         int64  `json:"expires_at"`
+	CancelInitiatedAt int64  `json:"cancel_initiated_at,omitempty"`
+	AnthropicVersion  string `json:"anthropic_version,omitempty"`
+	AnthropicBeta     string `json:"anthropic_beta,omitempty"`
+
+	// 以下仅 OpenAI 批次使用
+	Endpoint         string            `json:"endpoint,omitempty"`
+	InputFileID      string            `json:"input_file_id,omitempty"`
+	CompletionWindow string            `json:"completion_window,omitempty"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+
+	// 以下仅透传批次使用：结果回收时按创建时的分组与计费模型还原计费上下文
+	UpstreamID    string `json:"upstream_id,omitempty"`
+	BillingModel  string `json:"billing_model,omitempty"`
+	Group         string `json:"group,omitempty"`
+	OriginalGroup string `json:"original_group,omitempty"`
+	IsBackupGroup bool   `json:"is_backup_group,omitempty"`
+}
+
+func getProperties(task *model.Task) *Properties {
+	props := &Properties{}
+	_ = json.Unmarshal(task.Properties, props)
+	return props
+}
+
+// ClaudeBatchTask 把 Message Batches 接入任务系统：批次记录在 Task 表，由 UpdateTaskBulk
+// 定时调用 UpdateTaskStatus 推进状态。批次按条计费，不适用 RelayTaskSubmit 的按次预扣，
+// 创建走独立接口，所以提交相关的方法只返回错误。
+type ClaudeBatchTask struct {
+	base.TaskBase
+}
+
+var errSubmitNotSupported = base.StringTaskError(http.StatusBadRequest, "invalid_request_error", "use /claude/v1/messages/batches to create a message batch", true)
+
+func (t *ClaudeBatchTask) Init() *base.TaskError {
+	return errSubmitNotSupported
+}
+
+func (t *ClaudeBatchTask) SetProvider() *base.TaskError {
+	return errSubmitNotSupported
+}
+
+func (t *ClaudeBatchTask) Relay() *base.TaskError {
+	return errSubmitNotSupported
+}
+
+func (t *ClaudeBatchTask) ShouldRetry(c *gin.Context, err *base.TaskError) bool {
+	return false
+}
+
+func (t *ClaudeBatchTask) HandleError(err *base.TaskError) {
+	StringError(t.C, err.StatusCode, err.Code, err.Message)
+}
+
+// OpenAIBatchTask 把 OpenAI Batch API 接入任务系统，与 ClaudeBatchTask 一样只由轮询推进
+type OpenAIBatchTask struct {
+	base.TaskBase
+}
+
+var errOpenAISubmitNotSupported = base.StringTaskError(http.StatusBadRequest, "invalid_request_error", "use /v1/batches to create a batch", true)
+
+func (t *OpenAIBatchTask) Init() *base.TaskError {
+	return errOpenAISubmitNotSupported
+}
+
+func (t *OpenAIBatchTask) SetProvider() *base.TaskError {
+	return errOpenAISubmitNotSupported
+}
+
+func (t *OpenAIBatchTask) Relay() *base.TaskError {
+	return errOpenAISubmitNotSupported
+}
+
+func (t *OpenAIBatchTask) ShouldRetry(c *gin.Context, err *base.TaskError) bool {
+	return false
+}
+
+func (t *OpenAIBatchTask) HandleError(err *base.TaskError) {
+	common.AbortWithMessage(t.C, err.StatusCode, err.Message)
+}
+
+// StringError 以 Anthropic 错误格式响应
+func StringError(c *gin.Context, httpCode int, errType, message string) {
+	c.JSON(httpCode, claude.ClaudeError{
+		Type: "error",
+		ErrorInfo: claude.ClaudeErrorInfo{
+			Type:    errType,
+			Message: message,
+		},
+	})
+}
+
+// newBatchContext 为批次在网关内重放请求构造上下文：按任务记录的令牌还原鉴权与分组信息，
+// 等价于请求依次经过 ClaudeAuth / ContextUserId / Distribute 中间件，并带上批处理折扣。
+func newBatchContext(task *model.Task, props *Properties, path string, body []byte) (*gin.Context, *httptest.ResponseRecorder, error) {
+	token, err := model.GetTokenById(task.TokenID)
+	if err != nil {
+		return nil, nil, errors.New("token not found")
+	}
+	if token.Status != config.TokenStatusEnabled || (token.ExpiredTime != -1 && token.ExpiredTime < utils.GetTimestamp()) {
+		return nil, nil, errors.New("token is not available")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if props.AnthropicVersion != "" {
+		req.Header.Set("anthropic-version", props.AnthropicVersion)
+	}
+	if props.AnthropicBeta != "" {
+		req.Header.Set("anthropic-beta", props.AnthropicBeta)
+	}
+
+	requestId := utils.GetTimeString() + utils.GetRandomString(8)
+	ctx := context.WithValue(context.Background(), logger.RequestIdKey, requestId)
+	ctx = context.WithValue(ctx, "id", task.UserId)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req.WithContext(ctx)
+	c.Set(logger.RequestIdKey, requestId)
+	c.Set("requestStartTime", time.Now())
+
+	middleware.SetTokenContext(c, token)
+	// 批处理没有客户端连接可保活，关闭令牌上的心跳设置，避免心跳字节混进响应
+	if setting, ok := c.Get("token_setting"); ok {
+		if tokenSetting, ok := setting.(*model.TokenSetting); ok && tokenSetting != nil {
+			tokenSetting.Heartbeat.Enabled = false
+		}
+	}
+	if err := middleware.NewGroupDistributor(c).SetupGroups(); err != nil {
+		return nil, nil, err
+	}
+	c.Set(config.GinBatchPriceRatioKey, config.BatchPriceRatio)
+
+	return c, w, nil
+}
+
+func formatTime(timestamp int64) string {
+	return time.Unix(timestamp, 0).UTC().Format(time.RFC3339)
+}
+
+func formatTimePtr(timestamp int64) *string {
+	if timestamp <= 0 {
+		return nil
+	}
+	return utils.GetPointer(formatTime(timestamp))
+}
@@ -0,0 +1,494 @@
+package batch
+
+import (
+	"done-hub/common"
+	"done-hub/common/config"
+	"done-hub/common/logger"
+	"done-hub/common/utils"
+	"done-hub/model"
+	"done-hub/providers/claude"
+	"done-hub/relay"
+	"done-hub/types"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"gorm.io/datatypes"
+)
+
+var customIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// CreateClaudeBatch 处理 POST /claude/v1/messages/batches，返回批次是否已创建。
+// 单一模型且选中 Anthropic 官方渠道时整批透传，其余情况入队由网关逐条执行。
+func CreateClaudeBatch(c *gin.Context) bool {
+	request := &claude.MessageBatchRequest{}
+	if err := common.UnmarshalBodyReusable(c, request); err != nil {
+		StringError(c, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return false
+	}
+
+	models, err := validateBatchRequest(request)
+	if err != nil {
+		StringError(c, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return false
+	}
+
+	for _, modelName := range models {
+		if err := relay.CheckLimitModel(c, modelName); err != nil {
+			StringError(c, http.StatusForbidden, "permission_error", err.Error())
+			return false
+		}
+	}
+
+	userQuota, err := model.CacheGetUserQuota(c.GetInt("id"))
+	if err != nil {
+		StringError(c, http.StatusInternalServerError, "api_error", err.Error())
+		return false
+	}
+	if userQuota <= 0 {
+		StringError(c, http.StatusPaymentRequired, "billing_error", "user quota is not enough")
+		return false
+	}
+
+	now := time.Now().Unix()
+	props := &Properties{
+		Mode:             ModeGateway,
+		Total:            len(request.Requests),
+		ExpiresAt:        now + int64(batchExpireDuration.Seconds()),
+		AnthropicVersion: c.GetHeader("anthropic-version"),
+		AnthropicBeta:    c.GetHeader("anthropic-beta"),
+	}
+	task := &model.Task{
+		TaskID:     "msgbatch_" + utils.GetRandomString(24),
+		Platform:   model.TaskPlatformClaudeBatch,
+		UserId:     c.GetInt("id"),
+		TokenID:    c.GetInt("token_id"),
+		Action:     "MESSAGES",
+		Status:     model.TaskStatusInProgress,
+		SubmitTime: now,
+		StartTime:  now,
+	}
+
+	var messageBatch *claude.MessageBatch
+	if len(models) == 1 {
+		// 与 /claude/v1/messages 一致，只在 Claude 入口能服务的渠道类型中选择
+		c.Set("allow_channel_type", relay.AllowChannelType)
+		provider, modelName, fail := relay.GetProvider(c, models[0])
+		if fail != nil {
+			if relay.IsModelNotFound(fail) {
+				StringError(c, http.StatusNotFound, "not_found_error", fmt.Sprintf("model %s not found", models[0]))
+			} else {
+				StringError(c, http.StatusServiceUnavailable, "overloaded_error", fail.Error())
+			}
+			return false
+		}
+
+		if batchProvider, ok := provider.(claude.ClaudeBatchInterface); ok && provider.GetChannel().Type == config.ChannelTypeAnthropic {
+			upstream, errWithCode := createUpstreamBatch(batchProvider, request, modelName)
+			if errWithCode != nil {
+				handleUpstreamError(c, errWithCode)
+				return false
+			}
+
+			props.Mode = ModePassthrough
+			props.UpstreamID = upstream.ID
+			props.BillingModel = modelName
+			if c.GetBool("billing_original_model") {
+				props.BillingModel = models[0]
+			}
+			props.Group = c.GetString("token_group")
+			props.OriginalGroup = c.GetString("original_token_group")
+			props.IsBackupGroup = c.GetBool("is_backupGroup")
+			task.ChannelId = provider.GetChannel().Id
+			task.ChannelKeyId = c.GetInt(config.GinChannelKeyIdKey)
+
+			messageBatch = upstreamToMessageBatch(task, props, upstream)
+		}
+	}
+
+	items := make([]*model.BatchItem, 0, len(request.Requests))
+	if props.Mode == ModeGateway {
+		for _, item := range request.Requests {
+			items = append(items, &model.BatchItem{
+				BatchID:  task.TaskID,
+				CustomID: item.CustomID,
+				Status:   model.BatchItemStatusQueued,
+				Request:  datatypes.JSON(item.Params),
+			})
+		}
+		messageBatch = gatewayMessageBatch(task, props, map[string]int{model.BatchItemStatusQueued: len(items)})
+	}
+
+	task.Properties, _ = json.Marshal(props)
+	task.Data, _ = json.Marshal(messageBatch)
+	if err := model.CreateBatch(task, items); err != nil {
+		logger.LogError(c.Request.Context(), fmt.Sprintf("create message batch error: %s", err.Error()))
+		StringError(c, http.StatusInternalServerError, "api_error", "create message batch failed")
+		return false
+	}
+
+	if props.Mode == ModeGateway {
+		ActivateWorker()
+	}
+
+	c.JSON(http.StatusOK, messageBatch)
+	return true
+}
+
+func validateBatchRequest(request *claude.MessageBatchRequest) ([]string, error) {
+	if len(request.Requests) == 0 {
+		return nil, fmt.Errorf("requests: at least one request is required")
+	}
+	if len(request.Requests) > maxBatchRequests {
+		return nil, fmt.Errorf("requests: a batch may contain at most %d requests", maxBatchRequests)
+	}
+
+	models := make([]string, 0)
+	seenModels := make(map[string]bool)
+	seenIDs := make(map[string]bool, len(request.Requests))
+	for i, item := range request.Requests {
+		if !customIDPattern.MatchString(item.CustomID) {
+			return nil, fmt.Errorf("requests.%d.custom_id: must be 1-64 characters of letters, digits, underscores or hyphens", i)
+		}
+		if seenIDs[item.CustomID] {
+			return nil, fmt.Errorf("requests.%d.custom_id: duplicate custom_id %s", i, item.CustomID)
+		}
+		seenIDs[item.CustomID] = true
+
+		params := gjson.ParseBytes(item.Params)
+		if !params.IsObject() {
+			return nil, fmt.Errorf("requests.%d.params: must be an object", i)
+		}
+		modelName := params.Get("model").String()
+		if modelName == "" {
+			return nil, fmt.Errorf("requests.%d.params.model: field required", i)
+		}
+		if !seenModels[modelName] {
+			seenModels[modelName] = true
+			models = append(models, modelName)
+		}
+	}
+
+	return models, nil
+}
+
+// createUpstreamBatch 把每条请求的 model 改写为映射后的上游模型名后整批提交
+func createUpstreamBatch(provider claude.ClaudeBatchInterface, request *claude.MessageBatchRequest, modelName string) (*claude.MessageBatch, *types.OpenAIErrorWithStatusCode) {
+	upstreamRequest := &claude.MessageBatchRequest{
+		Requests: make([]claude.MessageBatchRequestItem, 0, len(request.Requests)),
+	}
+	for _, item := range request.Requests {
+		params, err := sjson.SetBytes(item.Params, "model", modelName)
+		if err != nil {
+			return nil, common.ErrorWrapperLocal(err, "invalid_request_error", http.StatusBadRequest)
+		}
+		upstreamRequest.Requests = append(upstreamRequest.Requests, claude.MessageBatchRequestItem{
+			CustomID: item.CustomID,
+			Params:   params,
+		})
+	}
+
+	body, err := json.Marshal(upstreamRequest)
+	if err != nil {
+		return nil, common.ErrorWrapperLocal(err, "marshal_request_failed", http.StatusInternalServerError)
+	}
+
+	return provider.CreateMessageBatch(body)
+}
+
+func handleUpstreamError(c *gin.Context, errWithCode *types.OpenAIErrorWithStatusCode) {
+	newErr := relay.FilterOpenAIErr(c, errWithCode)
+	claudeErr := claude.OpenaiErrToClaudeErr(&newErr)
+	c.JSON(newErr.StatusCode, claudeErr.ClaudeError)
+}
+
+// RetrieveClaudeBatch 处理 GET /claude/v1/messages/batches/:id
+func RetrieveClaudeBatch(c *gin.Context) {
+	task := getUserBatchTask(c)
+	if task == nil {
+		return
+	}
+
+	messageBatch, err := loadMessageBatch(task)
+	if err != nil {
+		StringError(c, http.StatusInternalServerError, "api_error", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, messageBatch)
+}
+
+// ListClaudeBatches 处理 GET /claude/v1/messages/batches，按创建时间倒序分页
+func ListClaudeBatches(c *gin.Context) {
+	userId := c.GetInt("id")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 1000 {
+		StringError(c, http.StatusBadRequest, "invalid_request_error", "limit: must be between 1 and 1000")
+		return
+	}
+
+	var beforeID, afterID int64
+	for param, cursor := range map[string]*int64{"before_id": &beforeID, "after_id": &afterID} {
+		batchID := c.Query(param)
+		if batchID == "" {
+			continue
+		}
+		task, err := model.GetTaskByTaskId(model.TaskPlatformClaudeBatch, userId, batchID)
+		if err != nil || task == nil {
+			StringError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("%s: batch %s not found", param, batchID))
+			return
+		}
+		*cursor = task.ID
+	}
+
+	tasks, err := model.GetUserTasksByCursor(model.TaskPlatformClaudeBatch, userId, beforeID, afterID, limit+1)
+	if err != nil {
+		StringError(c, http.StatusInternalServerError, "api_error", err.Error())
+		return
+	}
+
+	hasMore := len(tasks) > limit
+	if hasMore {
+		// before_id 翻页时结果已反转为倒序，多取的一条在头部
+		if beforeID > 0 {
+			tasks = tasks[1:]
+		} else {
+			tasks = tasks[:limit]
+		}
+	}
+
+	response := &claude.MessageBatchListResponse{
+		Data:    make([]*claude.MessageBatch, 0, len(tasks)),
+		HasMore: hasMore,
+	}
+
+	batchIDs := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		if getProperties(task).Mode == ModeGateway && task.Progress != 100 {
+			batchIDs = append(batchIDs, task.TaskID)
+		}
+	}
+	counts, err := model.GetBatchItemCounts(batchIDs)
+	if err != nil {
+		StringError(c, http.StatusInternalServerError, "api_error", err.Error())
+		return
+	}
+
+	for _, task := range tasks {
+		props := getProperties(task)
+		if props.Mode == ModeGateway && task.Progress != 100 {
+			response.Data = append(response.Data, gatewayMessageBatch(task, props, counts[task.TaskID]))
+			continue
+		}
+
+		messageBatch := &claude.MessageBatch{}
+		if err := json.Unmarshal(task.Data, messageBatch); err != nil {
+			continue
+		}
+		response.Data = append(response.Data, messageBatch)
+	}
+
+	if len(response.Data) > 0 {
+		response.FirstID = &response.Data[0].ID
+		response.LastID = &response.Data[len(response.Data)-1].ID
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CancelClaudeBatch 处理 POST /claude/v1/messages/batches/:id/cancel。
+// 取消后尚未开始的条目置为 canceled，执行中的条目跑完后批次进入 ended。
+func CancelClaudeBatch(c *gin.Context) {
+	task := getUserBatchTask(c)
+	if task == nil {
+		return
+	}
+
+	props := getProperties(task)
+	if task.Progress == 100 || props.CancelInitiatedAt > 0 {
+		messageBatch, err := loadMessageBatch(task)
+		if err != nil {
+			StringError(c, http.StatusInternalServerError, "api_error", err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, messageBatch)
+		return
+	}
+
+	props.CancelInitiatedAt = time.Now().Unix()
+	params := map[string]any{}
+
+	if props.Mode == ModePassthrough {
+		provider, err := getPassthroughProvider(c, task)
+		if err != nil {
+			StringError(c, http.StatusServiceUnavailable, "overloaded_error", err.Error())
+			return
+		}
+		upstream, errWithCode := provider.CancelMessageBatch(props.UpstreamID)
+		if errWithCode != nil {
+			handleUpstreamError(c, errWithCode)
+			return
+		}
+		params["data"] = datatypes.JSON(mustMarshal(upstreamToMessageBatch(task, props, upstream)))
+	} else if err := model.FinishQueuedBatchItems(task.TaskID, model.BatchItemStatusCanceled); err != nil {
+		StringError(c, http.StatusInternalServerError, "api_error", err.Error())
+		return
+	}
+
+	params["properties"] = datatypes.JSON(mustMarshal(props))
+	if err := model.TaskBulkUpdateByID([]int64{task.ID}, params); err != nil {
+		StringError(c, http.StatusInternalServerError, "api_error", err.Error())
+		return
+	}
+	task.Properties = params["properties"].(datatypes.JSON)
+	if data, ok := params["data"]; ok {
+		task.Data = data.(datatypes.JSON)
+	}
+
+	messageBatch, err := loadMessageBatch(task)
+	if err != nil {
+		StringError(c, http.StatusInternalServerError, "api_error", err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, messageBatch)
+}
+
+// GetClaudeBatchResults 处理 GET /claude/v1/messages/batches/:id/results，
+// 以 JSONL 逐行输出每条请求的结果，顺序与提交顺序一致
+func GetClaudeBatchResults(c *gin.Context) {
+	task := getUserBatchTask(c)
+	if task == nil {
+		return
+	}
+
+	if task.Progress != 100 {
+		StringError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("batch %s is still in progress", task.TaskID))
+		return
+	}
+
+	c.Header("Content-Type", "application/x-jsonl")
+	c.Status(http.StatusOK)
+
+	var lastID int64
+	for {
+		items, err := model.GetBatchItemResults(task.TaskID, lastID, 500)
+		if err != nil {
+			logger.LogError(c.Request.Context(), fmt.Sprintf("read batch results error: %s", err.Error()))
+			return
+		}
+		if len(items) == 0 {
+			return
+		}
+
+		for _, item := range items {
+			c.Writer.Write(resultLine(item))
+			c.Writer.Write([]byte("\n"))
+		}
+		c.Writer.Flush()
+		lastID = items[len(items)-1].ID
+	}
+}
+
+// resultLine 返回条目的结果行；取消 / 过期的条目没有执行结果，按状态补出
+func resultLine(item *model.BatchItem) []byte {
+	if len(item.Result) > 0 {
+		return item.Result
+	}
+
+	line, _ := json.Marshal(map[string]any{
+		"custom_id": item.CustomID,
+		"result":    map[string]any{"type": item.Status},
+	})
+	return line
+}
+
+func getUserBatchTask(c *gin.Context) *model.Task {
+	batchID := c.Param("id")
+	task, err := model.GetTaskByTaskId(model.TaskPlatformClaudeBatch, c.GetInt("id"), batchID)
+	if err != nil {
+		StringError(c, http.StatusInternalServerError, "api_error", err.Error())
+		return nil
+	}
+	if task == nil {
+		StringError(c, http.StatusNotFound, "not_found_error", fmt.Sprintf("batch %s not found", batchID))
+		return nil
+	}
+	return task
+}
+
+// loadMessageBatch 网关代执行且未结束的批次按条目实时统计，其余直接取落库的批次对象
+func loadMessageBatch(task *model.Task) (*claude.MessageBatch, error) {
+	props := getProperties(task)
+	if props.Mode == ModeGateway && task.Progress != 100 {
+		counts, err := model.GetBatchItemCounts([]string{task.TaskID})
+		if err != nil {
+			return nil, err
+		}
+		return gatewayMessageBatch(task, props, counts[task.TaskID]), nil
+	}
+
+	messageBatch := &claude.MessageBatch{}
+	if err := json.Unmarshal(task.Data, messageBatch); err != nil {
+		return nil, err
+	}
+	return messageBatch, nil
+}
+
+func gatewayMessageBatch(task *model.Task, props *Properties, counts map[string]int) *claude.MessageBatch {
+	messageBatch := newMessageBatch(task, props)
+	messageBatch.RequestCounts = claude.MessageBatchRequestCounts{
+		Processing: counts[model.BatchItemStatusQueued] + counts[model.BatchItemStatusProcessing],
+		Succeeded:  counts[model.BatchItemStatusSucceeded],
+		Errored:    counts[model.BatchItemStatusErrored],
+		Canceled:   counts[model.BatchItemStatusCanceled],
+		Expired:    counts[model.BatchItemStatusExpired],
+	}
+	return messageBatch
+}
+
+// upstreamToMessageBatch 把上游批次对象改写为对外的批次：id 与 results_url 都指向网关
+func upstreamToMessageBatch(task *model.Task, props *Properties, upstream *claude.MessageBatch) *claude.MessageBatch {
+	messageBatch := newMessageBatch(task, props)
+	messageBatch.RequestCounts = upstream.RequestCounts
+	if upstream.ProcessingStatus == "canceling" && messageBatch.ProcessingStatus == "in_progress" {
+		messageBatch.ProcessingStatus = "canceling"
+	}
+	if upstream.CancelInitiatedAt != nil && messageBatch.CancelInitiatedAt == nil {
+		messageBatch.CancelInitiatedAt = upstream.CancelInitiatedAt
+	}
+	return messageBatch
+}
+
+func newMessageBatch(task *model.Task, props *Properties) *claude.MessageBatch {
+	messageBatch := &claude.MessageBatch{
+		ID:                task.TaskID,
+		Type:              "message_batch",
+		ProcessingStatus:  "in_progress",
+		CreatedAt:         formatTime(task.SubmitTime),
+		ExpiresAt:         formatTime(props.ExpiresAt),
+		CancelInitiatedAt: formatTimePtr(props.CancelInitiatedAt),
+	}
+
+	if props.CancelInitiatedAt > 0 {
+		messageBatch.ProcessingStatus = "canceling"
+	}
+	if task.FinishTime > 0 {
+		messageBatch.ProcessingStatus = "ended"
+		messageBatch.EndedAt = formatTimePtr(task.FinishTime)
+		messageBatch.ResultsURL = utils.GetPointer(fmt.Sprintf("%s/claude/v1/messages/batches/%s/results", strings.TrimSuffix(config.ServerAddress, "/"), task.TaskID))
+	}
+
+	return messageBatch
+}
+
+func mustMarshal(v any) []byte {
+	data, _ := json.Marshal(v)
+	return data
+}
@@ -0,0 +1,429 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"done-hub/common/config"
+	"done-hub/common/logger"
+	"done-hub/common/utils"
+	"done-hub/model"
+	"done-hub/providers"
+	"done-hub/providers/claude"
+	"done-hub/relay/relay_util"
+	"done-hub/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"gorm.io/datatypes"
+)
+
+// maxResultLineSize 单条结果行的上限，足以容纳长输出与工具调用
+const maxResultLineSize = 32 * 1024 * 1024
+
+func (t *ClaudeBatchTask) UpdateTaskStatus(ctx context.Context, taskChannelM map[int][]string, taskM map[string]*model.Task) error {
+	for _, task := range taskM {
+		props := getProperties(task)
+		var err error
+		if props.Mode == ModePassthrough {
+			err = updatePassthroughBatch(task, props)
+		} else {
+			err = updateGatewayBatch(task, props)
+		}
+		if err != nil {
+			logger.LogError(ctx, fmt.Sprintf("update message batch %s error: %s", task.TaskID, err.Error()))
+		}
+	}
+
+	return nil
+}
+
+// updateGatewayBatch 按条目状态推进网关代执行的批次：过期时把未执行的条目置为 expired，
+// 没有 queued / processing 的条目后批次结束
+func updateGatewayBatch(task *model.Task, props *Properties) error {
+	if time.Now().Unix() >= props.ExpiresAt {
+		if err := model.FinishQueuedBatchItems(task.TaskID, model.BatchItemStatusExpired); err != nil {
+			return err
+		}
+	}
+
+	counts, err := model.GetBatchItemCounts([]string{task.TaskID})
+	if err != nil {
+		return err
+	}
+	count := counts[task.TaskID]
+	pending := count[model.BatchItemStatusQueued] + count[model.BatchItemStatusProcessing]
+
+	if pending > 0 {
+		return model.TaskBulkUpdateByID([]int64{task.ID}, map[string]any{
+			"progress": batchProgress(props.Total, props.Total-pending),
+		})
+	}
+
+	task.FinishTime = time.Now().Unix()
+	messageBatch := gatewayMessageBatch(task, props, count)
+	_, err = model.TaskUpdateIfUnfinished(task.ID, map[string]any{
+		"status":      batchTaskStatus(&messageBatch.RequestCounts),
+		"progress":    100,
+		"finish_time": task.FinishTime,
+		"data":        datatypes.JSON(mustMarshal(messageBatch)),
+	})
+	return err
+}
+
+// updatePassthroughBatch 轮询上游批次状态；上游结束后回收结果 JSONL 落到条目表并逐条计费，
+// 回收中途失败时批次保持未结束，下次轮询重新回收
+func updatePassthroughBatch(task *model.Task, props *Properties) error {
+	c, _, err := newBatchContext(task, props, "/claude/v1/messages/batches", nil)
+	if err != nil {
+		return err
+	}
+	provider, err := getPassthroughProvider(c, task)
+	if err != nil {
+		return err
+	}
+
+	upstream, errWithCode := provider.RetrieveMessageBatch(props.UpstreamID)
+	if errWithCode != nil {
+		if errWithCode.StatusCode == http.StatusNotFound {
+			task.FinishTime = time.Now().Unix()
+			_, err = model.TaskUpdateIfUnfinished(task.ID, map[string]any{
+				"status":      model.TaskStatusFailure,
+				"fail_reason": errWithCode.Message,
+				"progress":    100,
+				"finish_time": task.FinishTime,
+			})
+			return err
+		}
+		return errors.New(errWithCode.Message)
+	}
+
+	counts := upstream.RequestCounts
+	finished := counts.Succeeded + counts.Errored + counts.Canceled + counts.Expired
+	if upstream.ProcessingStatus != "ended" {
+		return model.TaskBulkUpdateByID([]int64{task.ID}, map[string]any{
+			"progress": batchProgress(props.Total, finished),
+			"data":     datatypes.JSON(mustMarshal(upstreamToMessageBatch(task, props, upstream))),
+		})
+	}
+
+	resp, errWithCode := provider.GetMessageBatchResults(props.UpstreamID)
+	if errWithCode != nil {
+		return errors.New(errWithCode.Message)
+	}
+	defer resp.Body.Close()
+
+	task.FinishTime = time.Now().Unix()
+	claimed, err := model.FinishBatchWithResults(task.ID, map[string]any{
+		"status":      batchTaskStatus(&counts),
+		"progress":    100,
+		"finish_time": task.FinishTime,
+		"data":        datatypes.JSON(mustMarshal(upstreamToMessageBatch(task, props, upstream))),
+	}, func(insert func(items []*model.BatchItem) error) error {
+		return ingestPassthroughResults(task, resp.Body, insert)
+	})
+	if err != nil {
+		return fmt.Errorf("read message batch results: %w", err)
+	}
+	if !claimed {
+		// 其它节点已完成回收
+		return nil
+	}
+
+	// 条目与任务一并提交后再计费，只有结束批次的节点会计费
+	setPassthroughBillingContext(c, task, props)
+	return consumeBatchResults(c, task, props)
+}
+
+// ingestPassthroughResults 逐行读取上游结果 JSONL 写入条目表，读取中断时返回错误
+func ingestPassthroughResults(task *model.Task, body io.Reader, insert func(items []*model.BatchItem) error) error {
+	items := make([]*model.BatchItem, 0, 500)
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), maxResultLineSize)
+	for scanner.Scan() {
+		line := append([]byte(nil), scanner.Bytes()...)
+		if len(line) == 0 {
+			continue
+		}
+
+		items = append(items, &model.BatchItem{
+			BatchID:  task.TaskID,
+			CustomID: gjson.GetBytes(line, "custom_id").String(),
+			Status:   gjson.GetBytes(line, "result.type").String(),
+			Result:   datatypes.JSON(line),
+		})
+		if len(items) == cap(items) {
+			if err := insert(items); err != nil {
+				return err
+			}
+			items = make([]*model.BatchItem, 0, 500)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return insert(items)
+}
+
+// consumeBatchResults 按成功条目的 usage 逐条计费
+func consumeBatchResults(c *gin.Context, task *model.Task, props *Properties) error {
+	var lastID int64
+	for {
+		items, err := model.GetBatchItemResults(task.TaskID, lastID, 500, model.BatchItemStatusSucceeded)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+
+		for _, item := range items {
+			consumeResultUsage(c, props, item.Result)
+		}
+		lastID = items[len(items)-1].ID
+	}
+}
+
+func (t *OpenAIBatchTask) UpdateTaskStatus(ctx context.Context, taskChannelM map[int][]string, taskM map[string]*model.Task) error {
+	for _, task := range taskM {
+		if err := updateOpenAIBatch(task, getProperties(task)); err != nil {
+			logger.LogError(ctx, fmt.Sprintf("update batch %s error: %s", task.TaskID, err.Error()))
+		}
+	}
+
+	return nil
+}
+
+// updateOpenAIBatch 推进 OpenAI 批次：超过 completion_window 时把未执行的条目置为 expired，
+// 全部条目结束后把结果写成输出文件（成功的响应）与错误文件（失败、取消、过期的请求）
+func updateOpenAIBatch(task *model.Task, props *Properties) error {
+	now := time.Now().Unix()
+	if now >= props.ExpiresAt {
+		if err := model.FinishQueuedBatchItems(task.TaskID, model.BatchItemStatusExpired); err != nil {
+			return err
+		}
+	}
+
+	counts, err := model.GetBatchItemCounts([]string{task.TaskID})
+	if err != nil {
+		return err
+	}
+	count := counts[task.TaskID]
+	pending := count[model.BatchItemStatusQueued] + count[model.BatchItemStatusProcessing]
+
+	if pending > 0 {
+		return model.TaskBulkUpdateByID([]int64{task.ID}, map[string]any{
+			"progress": batchProgress(props.Total, props.Total-pending),
+		})
+	}
+
+	batch := gatewayBatch(task, props, count)
+	batch.FinalizingAt = utils.GetPointer(now)
+	switch {
+	case props.CancelInitiatedAt > 0:
+		batch.Status = "cancelled"
+		batch.CancelledAt = utils.GetPointer(now)
+	case count[model.BatchItemStatusExpired] > 0:
+		batch.Status = "expired"
+		batch.ExpiredAt = utils.GetPointer(now)
+	default:
+		batch.Status = "completed"
+		batch.CompletedAt = utils.GetPointer(now)
+	}
+
+	outputFile, err := buildBatchResultFile(task, batch.ID+"_output.jsonl", model.BatchItemStatusSucceeded)
+	if err != nil {
+		return err
+	}
+	errorFile, err := buildBatchResultFile(task, batch.ID+"_error.jsonl",
+		model.BatchItemStatusErrored, model.BatchItemStatusCanceled, model.BatchItemStatusExpired)
+	if err != nil {
+		return err
+	}
+	if outputFile != nil {
+		batch.OutputFileID = &outputFile.file.ID
+	}
+	if errorFile != nil {
+		batch.ErrorFileID = &errorFile.file.ID
+	}
+
+	status := model.TaskStatusSuccess
+	if batch.RequestCounts.Completed == 0 {
+		status = model.TaskStatusFailure
+	}
+
+	// 先保存结果文件再结束任务，避免任务已记录的文件 id 指向尚未保存的文件
+	saved, err := saveBatchResultFiles(outputFile, errorFile)
+	if err != nil {
+		return err
+	}
+
+	task.FinishTime = now
+	claimed, err := model.TaskUpdateIfUnfinished(task.ID, map[string]any{
+		"status":      status,
+		"progress":    100,
+		"finish_time": task.FinishTime,
+		"data":        datatypes.JSON(mustMarshal(batch)),
+	})
+	if err != nil || !claimed {
+		// 结束失败，或其它节点已完成收尾：删除本次保存的文件，由下次轮询或其它节点的结果为准
+		deleteBatchResultFiles(saved)
+		return err
+	}
+	return nil
+}
+
+// saveBatchResultFiles 保存结果文件，任一文件保存失败时删除已保存的文件
+func saveBatchResultFiles(results ...*batchResultFile) ([]*model.File, error) {
+	saved := make([]*model.File, 0, len(results))
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		if err := result.file.Save(result.content); err != nil {
+			deleteBatchResultFiles(saved)
+			return nil, err
+		}
+		saved = append(saved, result.file)
+	}
+	return saved, nil
+}
+
+func deleteBatchResultFiles(files []*model.File) {
+	for _, file := range files {
+		if err := file.Delete(); err != nil {
+			logger.SysError(fmt.Sprintf("delete batch result file %s error: %s", file.ID, err.Error()))
+		}
+	}
+}
+
+type batchResultFile struct {
+	file    *model.File
+	content []byte
+}
+
+// buildBatchResultFile 把指定状态的条目结果按提交顺序写成 JSONL 文件，没有条目时返回 nil
+func buildBatchResultFile(task *model.Task, filename string, statuses ...string) (*batchResultFile, error) {
+	var (
+		content bytes.Buffer
+		lastID  int64
+	)
+	for {
+		items, err := model.GetBatchItemResults(task.TaskID, lastID, 500, statuses...)
+		if err != nil {
+			return nil, err
+		}
+		if len(items) == 0 {
+			break
+		}
+
+		for _, item := range items {
+			content.Write(openaiResultLine(item))
+			content.WriteByte('\n')
+		}
+		lastID = items[len(items)-1].ID
+	}
+
+	if content.Len() == 0 {
+		return nil, nil
+	}
+
+	file := &model.File{
+		ID:       "file-" + utils.GetRandomString(24),
+		UserId:   task.UserId,
+		TokenId:  task.TokenID,
+		Purpose:  model.FilePurposeBatchOutput,
+		Filename: filename,
+	}
+	if config.FileExpireDays > 0 {
+		file.ExpiresAt = time.Now().AddDate(0, 0, config.FileExpireDays).Unix()
+	}
+
+	return &batchResultFile{file: file, content: content.Bytes()}, nil
+}
+
+// openaiResultLine 返回条目的结果行；取消 / 过期的条目没有执行结果，按状态补出错误
+func openaiResultLine(item *model.BatchItem) []byte {
+	if len(item.Result) > 0 {
+		return item.Result
+	}
+
+	switch item.Status {
+	case model.BatchItemStatusCanceled:
+		return openaiResultJSON(item, nil, map[string]any{
+			"code":    "batch_cancelled",
+			"message": "This request was not executed because the batch was cancelled.",
+		})
+	case model.BatchItemStatusExpired:
+		return openaiResultJSON(item, nil, map[string]any{
+			"code":    "batch_expired",
+			"message": "This request could not be executed before the completion window expired.",
+		})
+	default:
+		return openaiResultJSON(item, nil, map[string]any{
+			"code":    "server_error",
+			"message": "The request could not be executed.",
+		})
+	}
+}
+
+// setPassthroughBillingContext 还原创建批次时选中的渠道与分组，使 NewQuota 按当时的倍率计费
+func setPassthroughBillingContext(c *gin.Context, task *model.Task, props *Properties) {
+	c.Set("channel_id", task.ChannelId)
+	c.Set(config.GinChannelKeyIdKey, task.ChannelKeyId)
+	c.Set("token_group", props.Group)
+	c.Set("original_token_group", props.OriginalGroup)
+	c.Set("is_backupGroup", props.IsBackupGroup)
+	if groupRatio := model.GlobalUserGroupRatio.GetBySymbol(props.Group); groupRatio != nil {
+		c.Set("group_ratio", groupRatio.Ratio)
+	}
+}
+
+func consumeResultUsage(c *gin.Context, props *Properties, line []byte) {
+	claudeUsage := &claude.Usage{}
+	if err := json.Unmarshal([]byte(gjson.GetBytes(line, "result.message.usage").Raw), claudeUsage); err != nil {
+		return
+	}
+
+	usage := &types.Usage{}
+	if !claude.ClaudeUsageToOpenaiUsage(claudeUsage, usage) {
+		return
+	}
+
+	quota := relay_util.NewQuota(c, props.BillingModel, usage.PromptTokens)
+	quota.Consume(c, usage, false)
+}
+
+func getPassthroughProvider(c *gin.Context, task *model.Task) (claude.ClaudeBatchInterface, error) {
+	channel, err := model.GetChannelById(task.ChannelId)
+	if err != nil {
+		return nil, fmt.Errorf("channel %d not found", task.ChannelId)
+	}
+
+	provider, ok := providers.GetProvider(model.ChannelWithKey(channel, task.ChannelKeyId), c).(claude.ClaudeBatchInterface)
+	if !ok {
+		return nil, fmt.Errorf("channel %d does not support message batches", task.ChannelId)
+	}
+	return provider, nil
+}
+
+// batchProgress 结束前进度最多到 99，100 留给批次真正结束时设置
+func batchProgress(total, finished int) int {
+	if total <= 0 {
+		return 0
+	}
+	return min(finished*100/total, 99)
+}
+
+func batchTaskStatus(counts *claude.MessageBatchRequestCounts) string {
+	if counts.Succeeded == 0 && counts.Errored > 0 {
+		return model.TaskStatusFailure
+	}
+	return model.TaskStatusSuccess
+}
@@ -0,0 +1,300 @@
+package batch
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"done-hub/common/logger"
+	"done-hub/common/storage"
+	"done-hub/common/storage/drives"
+	"done-hub/model"
+	"done-hub/types"
+
+	"github.com/glebarez/sqlite"
+	"go.uber.org/zap"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+func TestMain(m *testing.M) {
+	if logger.Logger == nil {
+		logger.Logger = zap.NewNop()
+	}
+
+	// 结果文件保存到临时目录
+	root, err := os.MkdirTemp("", "batch-test")
+	if err != nil {
+		panic(err)
+	}
+	storage.AddFileDrive(drives.NewLocalStorage(root))
+
+	code := m.Run()
+	os.RemoveAll(root)
+	os.Exit(code)
+}
+
+// setupBatchTestDB 使用内存 SQLite 存放批次任务、条目与结果文件
+func setupBatchTestDB(t *testing.T) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: gormLogger.Default.LogMode(gormLogger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err = db.AutoMigrate(&model.Task{}, &model.BatchItem{}, &model.File{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	savedDB := model.DB
+	t.Cleanup(func() {
+		model.DB = savedDB
+		sqlDB.Close()
+	})
+	model.DB = db
+}
+
+func createTestBatchTask(t *testing.T, platform string, props *Properties, statuses ...string) *model.Task {
+	t.Helper()
+	task := &model.Task{
+		TaskID:     "batch_test",
+		Platform:   platform,
+		UserId:     1,
+		Status:     model.TaskStatusInProgress,
+		SubmitTime: time.Now().Unix(),
+		Properties: datatypes.JSON(mustMarshal(props)),
+	}
+	items := make([]*model.BatchItem, 0, len(statuses))
+	for i, status := range statuses {
+		item := &model.BatchItem{BatchID: task.TaskID, CustomID: fmt.Sprintf("req-%d", i+1), Status: status}
+		if status == model.BatchItemStatusSucceeded {
+			item.Result = datatypes.JSON(fmt.Sprintf(`{"custom_id":"req-%d","response":{"status_code":200}}`, i+1))
+		}
+		items = append(items, item)
+	}
+	if err := model.CreateBatch(task, items); err != nil {
+		t.Fatalf("CreateBatch() error = %v", err)
+	}
+	return task
+}
+
+func reloadTask(t *testing.T, task *model.Task) *model.Task {
+	t.Helper()
+	current := &model.Task{}
+	if err := model.DB.First(current, task.ID).Error; err != nil {
+		t.Fatalf("reload task: %v", err)
+	}
+	return current
+}
+
+func passthroughResultLines(n int) string {
+	var lines strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&lines, `{"custom_id":"req-%d","result":{"type":"succeeded","message":{"usage":{"input_tokens":1}}}}`+"\n", i)
+	}
+	return lines.String()
+}
+
+// TestIngestPassthroughResults 测试透传批次的结果回收：读取中途失败时整体回滚、批次保持未结束，重新回收时不会重复写入
+func TestIngestPassthroughResults(t *testing.T) {
+	errRead := errors.New("connection reset")
+	tests := []struct {
+		name      string
+		body      io.Reader
+		wantErr   error
+		wantItems int
+	}{
+		{
+			name:      "读取全部结果，跳过空行",
+			body:      strings.NewReader(passthroughResultLines(2) + "\n" + `{"custom_id":"req-3","result":{"type":"errored"}}`),
+			wantItems: 3,
+		},
+		{
+			name:      "跨越分批写入的结果",
+			body:      strings.NewReader(passthroughResultLines(1200)),
+			wantItems: 1200,
+		},
+		{
+			name:    "已写入一批后读取中断",
+			body:    io.MultiReader(strings.NewReader(passthroughResultLines(700)), iotest.ErrReader(errRead)),
+			wantErr: errRead,
+		},
+		{
+			name:    "结果行超出上限",
+			body:    strings.NewReader(`{"custom_id":"` + strings.Repeat("a", maxResultLineSize) + `"}`),
+			wantErr: bufio.ErrTooLong,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupBatchTestDB(t)
+			task := createTestBatchTask(t, model.TaskPlatformClaudeBatch, &Properties{Mode: ModePassthrough})
+
+			ingest := func(body io.Reader) (bool, error) {
+				return model.FinishBatchWithResults(task.ID, map[string]any{"progress": 100}, func(insert func(items []*model.BatchItem) error) error {
+					return ingestPassthroughResults(task, body, insert)
+				})
+			}
+
+			claimed, err := ingest(tt.body)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("回收 error = %v, 期望 %v", err, tt.wantErr)
+			}
+			if claimed != (tt.wantErr == nil) {
+				t.Errorf("claimed = %v, 期望 %v", claimed, tt.wantErr == nil)
+			}
+
+			counts, _ := model.GetBatchItemCounts([]string{task.TaskID})
+			total := 0
+			for _, n := range counts[task.TaskID] {
+				total += n
+			}
+			if total != tt.wantItems {
+				t.Errorf("条目数 = %d, 期望 %d", total, tt.wantItems)
+			}
+
+			if tt.wantErr == nil {
+				// 已结束的批次不会再次回收
+				if claimed, err = ingest(strings.NewReader(passthroughResultLines(3))); claimed || err != nil {
+					t.Errorf("重复回收 = %v, %v, 期望 false, nil", claimed, err)
+				}
+				return
+			}
+
+			if progress := reloadTask(t, task).Progress; progress == 100 {
+				t.Fatal("回收失败后批次不应结束")
+			}
+			if claimed, err = ingest(strings.NewReader(passthroughResultLines(3))); !claimed || err != nil {
+				t.Fatalf("重新回收 = %v, %v, 期望成功", claimed, err)
+			}
+			counts, _ = model.GetBatchItemCounts([]string{task.TaskID})
+			if got := counts[task.TaskID][model.BatchItemStatusSucceeded]; got != 3 {
+				t.Errorf("重新回收后 succeeded 条目数 = %d, 期望 3", got)
+			}
+		})
+	}
+}
+
+// TestUpdateOpenAIBatch 测试 OpenAI 批次的收尾：条目全部结束后生成输出与错误文件，任务已被其它节点结束时删除本次保存的文件
+func TestUpdateOpenAIBatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []string
+		cancel     bool
+		finished   bool
+		wantStatus string
+		wantOutput int
+		wantError  int
+		wantFiles  int64
+	}{
+		{
+			name:     "仍有待执行条目时只更新进度",
+			statuses: []string{model.BatchItemStatusSucceeded, model.BatchItemStatusQueued},
+		},
+		{
+			name:       "全部成功",
+			statuses:   []string{model.BatchItemStatusSucceeded, model.BatchItemStatusSucceeded},
+			wantStatus: "completed",
+			wantOutput: 2,
+			wantFiles:  1,
+		},
+		{
+			name:       "取消后未执行的条目写入错误文件",
+			statuses:   []string{model.BatchItemStatusSucceeded, model.BatchItemStatusQueued, model.BatchItemStatusQueued},
+			cancel:     true,
+			wantStatus: "cancelled",
+			wantOutput: 1,
+			wantError:  2,
+			wantFiles:  2,
+		},
+		{
+			name:     "其它节点已结束时删除本次保存的文件",
+			statuses: []string{model.BatchItemStatusSucceeded, model.BatchItemStatusErrored},
+			finished: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupBatchTestDB(t)
+			props := &Properties{Total: len(tt.statuses), ExpiresAt: time.Now().Add(time.Hour).Unix()}
+			task := createTestBatchTask(t, model.TaskPlatformOpenAIBatch, props, tt.statuses...)
+			if tt.cancel {
+				props.CancelInitiatedAt = time.Now().Unix()
+				if err := model.FinishQueuedBatchItems(task.TaskID, model.BatchItemStatusCanceled); err != nil {
+					t.Fatalf("FinishQueuedBatchItems() error = %v", err)
+				}
+			}
+			if tt.finished {
+				model.DB.Model(task).Update("progress", 100)
+			}
+
+			if err := updateOpenAIBatch(task, props); err != nil {
+				t.Fatalf("updateOpenAIBatch() error = %v", err)
+			}
+
+			var files int64
+			model.DB.Model(&model.File{}).Count(&files)
+			if files != tt.wantFiles {
+				t.Errorf("保存的文件数 = %d, 期望 %d", files, tt.wantFiles)
+			}
+
+			current := reloadTask(t, task)
+			if tt.wantStatus == "" {
+				if current.Progress == 100 && !tt.finished {
+					t.Error("批次不应结束")
+				}
+				return
+			}
+			if current.Progress != 100 {
+				t.Fatalf("Progress = %d, 期望 100", current.Progress)
+			}
+
+			batch := &types.Batch{}
+			if err := json.Unmarshal(current.Data, batch); err != nil {
+				t.Fatalf("unmarshal batch: %v", err)
+			}
+			if batch.Status != tt.wantStatus {
+				t.Errorf("批次状态 = %s, 期望 %s", batch.Status, tt.wantStatus)
+			}
+			assertResultFile(t, batch.OutputFileID, tt.wantOutput)
+			assertResultFile(t, batch.ErrorFileID, tt.wantError)
+		})
+	}
+}
+
+// assertResultFile 校验批次记录的文件已保存且行数正确，wantLines 为 0 时期望没有文件
+func assertResultFile(t *testing.T, fileID *string, wantLines int) {
+	t.Helper()
+	if wantLines == 0 {
+		if fileID != nil {
+			t.Errorf("文件 id = %s, 期望没有文件", *fileID)
+		}
+		return
+	}
+	if fileID == nil {
+		t.Fatalf("文件 id 为空，期望 %d 行", wantLines)
+	}
+
+	file, err := model.GetUserFileById(1, 0, *fileID)
+	if err != nil || file == nil {
+		t.Fatalf("GetUserFileById(%s) = %v, %v, 期望文件已保存", *fileID, file, err)
+	}
+	content, err := file.ReadContent()
+	if err != nil {
+		t.Fatalf("ReadContent() error = %v", err)
+	}
+	if lines := strings.Count(string(content), "\n"); lines != wantLines {
+		t.Errorf("文件 %s 行数 = %d, 期望 %d", *fileID, lines, wantLines)
+	}
+}
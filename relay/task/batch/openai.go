@@ -0,0 +1,378 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"done-hub/common"
+	"done-hub/common/logger"
+	"done-hub/common/utils"
+	"done-hub/model"
+	"done-hub/relay"
+	"done-hub/types"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"gorm.io/datatypes"
+)
+
+// openaiBatchEndpoints 批次可执行的端点，条目经 relay.Relay 按端点走对应的转换与计费
+var openaiBatchEndpoints = map[string]bool{
+	"/v1/chat/completions": true,
+	"/v1/completions":      true,
+	"/v1/embeddings":       true,
+	"/v1/responses":        true,
+}
+
+// CreateOpenAIBatch 处理 POST /v1/batches，返回批次是否已创建。
+// 输入文件的每一行都入队由网关逐条执行，因此批次可以用到整个渠道池，并按网关价格计费。
+func CreateOpenAIBatch(c *gin.Context) bool {
+	request := &types.BatchRequest{}
+	if err := common.UnmarshalBodyReusable(c, request); err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return false
+	}
+
+	if !openaiBatchEndpoints[request.Endpoint] {
+		common.AbortWithMessage(c, http.StatusBadRequest, fmt.Sprintf("endpoint: unsupported endpoint %s", request.Endpoint))
+		return false
+	}
+
+	completionWindow, err := time.ParseDuration(request.CompletionWindow)
+	if err != nil || completionWindow < time.Hour || completionWindow > maxCompletionWindow {
+		common.AbortWithMessage(c, http.StatusBadRequest, fmt.Sprintf("completion_window: must be a duration between 1h and %s", maxCompletionWindow))
+		return false
+	}
+
+	userId := c.GetInt("id")
+	file, err := model.GetUserFileById(userId, c.GetInt("token_id"), request.InputFileID)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return false
+	}
+	if file == nil || file.Purpose != model.FilePurposeBatch {
+		common.AbortWithMessage(c, http.StatusBadRequest, fmt.Sprintf("input_file_id: batch file %s not found", request.InputFileID))
+		return false
+	}
+
+	content, err := file.ReadContent()
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return false
+	}
+
+	lines, models, err := parseBatchInput(content, request.Endpoint)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return false
+	}
+
+	for _, modelName := range models {
+		if err := relay.CheckLimitModel(c, modelName); err != nil {
+			common.AbortWithMessage(c, http.StatusForbidden, err.Error())
+			return false
+		}
+	}
+
+	userQuota, err := model.CacheGetUserQuota(userId)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return false
+	}
+	if userQuota <= 0 {
+		common.AbortWithMessage(c, http.StatusPaymentRequired, "user quota is not enough")
+		return false
+	}
+
+	now := time.Now().Unix()
+	props := &Properties{
+		Mode:             ModeGateway,
+		Total:            len(lines),
+		ExpiresAt:        now + int64(completionWindow.Seconds()),
+		Endpoint:         request.Endpoint,
+		InputFileID:      request.InputFileID,
+		CompletionWindow: request.CompletionWindow,
+		Metadata:         request.Metadata,
+	}
+	task := &model.Task{
+		TaskID:     "batch_" + utils.GetRandomString(32),
+		Platform:   model.TaskPlatformOpenAIBatch,
+		UserId:     userId,
+		TokenID:    c.GetInt("token_id"),
+		Action:     request.Endpoint,
+		Status:     model.TaskStatusInProgress,
+		SubmitTime: now,
+		StartTime:  now,
+	}
+
+	items := make([]*model.BatchItem, 0, len(lines))
+	for _, line := range lines {
+		items = append(items, &model.BatchItem{
+			BatchID:  task.TaskID,
+			CustomID: line.CustomID,
+			Status:   model.BatchItemStatusQueued,
+			Request:  datatypes.JSON(line.Body),
+		})
+	}
+
+	batch := gatewayBatch(task, props, map[string]int{model.BatchItemStatusQueued: len(items)})
+	task.Properties, _ = json.Marshal(props)
+	task.Data, _ = json.Marshal(batch)
+	if err := model.CreateBatch(task, items); err != nil {
+		logger.LogError(c.Request.Context(), fmt.Sprintf("create batch error: %s", err.Error()))
+		common.AbortWithMessage(c, http.StatusInternalServerError, "create batch failed")
+		return false
+	}
+
+	ActivateWorker()
+	c.JSON(http.StatusOK, batch)
+	return true
+}
+
+// parseBatchInput 校验输入文件：每行一个请求，custom_id 唯一，url 与批次 endpoint 一致，body 须带 model
+func parseBatchInput(content []byte, endpoint string) ([]*types.BatchRequestLine, []string, error) {
+	lines := make([]*types.BatchRequestLine, 0)
+	models := make([]string, 0)
+	seenModels := make(map[string]bool)
+	seenIDs := make(map[string]bool)
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), maxResultLineSize)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		line := &types.BatchRequestLine{}
+		if err := json.Unmarshal(raw, line); err != nil {
+			return nil, nil, fmt.Errorf("line %d: invalid JSON", lineNo)
+		}
+		if line.CustomID == "" {
+			return nil, nil, fmt.Errorf("line %d: custom_id is required", lineNo)
+		}
+		if seenIDs[line.CustomID] {
+			return nil, nil, fmt.Errorf("line %d: duplicate custom_id %s", lineNo, line.CustomID)
+		}
+		seenIDs[line.CustomID] = true
+
+		if line.Method != http.MethodPost {
+			return nil, nil, fmt.Errorf("line %d: method must be POST", lineNo)
+		}
+		if line.URL != endpoint {
+			return nil, nil, fmt.Errorf("line %d: url %s does not match the batch endpoint %s", lineNo, line.URL, endpoint)
+		}
+
+		body := gjson.ParseBytes(line.Body)
+		if !body.IsObject() {
+			return nil, nil, fmt.Errorf("line %d: body must be an object", lineNo)
+		}
+		modelName := body.Get("model").String()
+		if modelName == "" {
+			return nil, nil, fmt.Errorf("line %d: body.model is required", lineNo)
+		}
+		if !seenModels[modelName] {
+			seenModels[modelName] = true
+			models = append(models, modelName)
+		}
+
+		lines = append(lines, line)
+		if len(lines) > maxOpenAIBatchRequests {
+			return nil, nil, fmt.Errorf("a batch may contain at most %d requests", maxOpenAIBatchRequests)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("line %d: %s", lineNo+1, err.Error())
+	}
+	if len(lines) == 0 {
+		return nil, nil, fmt.Errorf("input file contains no requests")
+	}
+
+	return lines, models, nil
+}
+
+// RetrieveOpenAIBatch 处理 GET /v1/batches/:id
+func RetrieveOpenAIBatch(c *gin.Context) {
+	task := getUserOpenAIBatchTask(c)
+	if task == nil {
+		return
+	}
+
+	batch, err := loadOpenAIBatch(task)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
+}
+
+// ListOpenAIBatches 处理 GET /v1/batches，按创建时间倒序，after 为上一页最后一个批次 id
+func ListOpenAIBatches(c *gin.Context) {
+	userId := c.GetInt("id")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		common.AbortWithMessage(c, http.StatusBadRequest, "limit: must be between 1 and 100")
+		return
+	}
+
+	var afterID int64
+	if after := c.Query("after"); after != "" {
+		task, err := model.GetTaskByTaskId(model.TaskPlatformOpenAIBatch, userId, after)
+		if err != nil || task == nil {
+			common.AbortWithMessage(c, http.StatusBadRequest, fmt.Sprintf("after: batch %s not found", after))
+			return
+		}
+		afterID = task.ID
+	}
+
+	tasks, err := model.GetUserTasksByCursor(model.TaskPlatformOpenAIBatch, userId, 0, afterID, limit+1)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	hasMore := len(tasks) > limit
+	if hasMore {
+		tasks = tasks[:limit]
+	}
+
+	batchIDs := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		if task.Progress != 100 {
+			batchIDs = append(batchIDs, task.TaskID)
+		}
+	}
+	counts, err := model.GetBatchItemCounts(batchIDs)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := &types.BatchList{
+		Object:  "list",
+		Data:    make([]*types.Batch, 0, len(tasks)),
+		HasMore: hasMore,
+	}
+	for _, task := range tasks {
+		if task.Progress != 100 {
+			response.Data = append(response.Data, gatewayBatch(task, getProperties(task), counts[task.TaskID]))
+			continue
+		}
+
+		batch := &types.Batch{}
+		if err := json.Unmarshal(task.Data, batch); err != nil {
+			continue
+		}
+		response.Data = append(response.Data, batch)
+	}
+
+	if len(response.Data) > 0 {
+		response.FirstID = &response.Data[0].ID
+		response.LastID = &response.Data[len(response.Data)-1].ID
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CancelOpenAIBatch 处理 POST /v1/batches/:id/cancel。批次进入 cancelling，
+// 尚未执行的条目直接取消，执行中的条目跑完后批次进入 cancelled 并生成输出文件。
+func CancelOpenAIBatch(c *gin.Context) {
+	task := getUserOpenAIBatchTask(c)
+	if task == nil {
+		return
+	}
+
+	props := getProperties(task)
+	if task.Progress == 100 {
+		common.AbortWithMessage(c, http.StatusConflict, fmt.Sprintf("batch %s has already finished", task.TaskID))
+		return
+	}
+
+	if props.CancelInitiatedAt == 0 {
+		props.CancelInitiatedAt = time.Now().Unix()
+		if err := model.FinishQueuedBatchItems(task.TaskID, model.BatchItemStatusCanceled); err != nil {
+			common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		task.Properties = datatypes.JSON(mustMarshal(props))
+		if err := model.TaskBulkUpdateByID([]int64{task.ID}, map[string]any{"properties": task.Properties}); err != nil {
+			common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	batch, err := loadOpenAIBatch(task)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, batch)
+}
+
+func getUserOpenAIBatchTask(c *gin.Context) *model.Task {
+	batchID := c.Param("id")
+	task, err := model.GetTaskByTaskId(model.TaskPlatformOpenAIBatch, c.GetInt("id"), batchID)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return nil
+	}
+	if task == nil {
+		common.AbortWithMessage(c, http.StatusNotFound, fmt.Sprintf("batch %s not found", batchID))
+		return nil
+	}
+	return task
+}
+
+// loadOpenAIBatch 未结束的批次按条目实时统计，已结束的直接取落库的批次对象
+func loadOpenAIBatch(task *model.Task) (*types.Batch, error) {
+	if task.Progress != 100 {
+		counts, err := model.GetBatchItemCounts([]string{task.TaskID})
+		if err != nil {
+			return nil, err
+		}
+		return gatewayBatch(task, getProperties(task), counts[task.TaskID]), nil
+	}
+
+	batch := &types.Batch{}
+	if err := json.Unmarshal(task.Data, batch); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// gatewayBatch 按条目状态生成批次对象；取消 / 过期的条目计入 failed，与 OpenAI 的统计口径一致
+func gatewayBatch(task *model.Task, props *Properties, counts map[string]int) *types.Batch {
+	batch := &types.Batch{
+		ID:               task.TaskID,
+		Object:           "batch",
+		Endpoint:         props.Endpoint,
+		InputFileID:      props.InputFileID,
+		CompletionWindow: props.CompletionWindow,
+		Status:           "in_progress",
+		CreatedAt:        task.SubmitTime,
+		InProgressAt:     utils.GetPointer(task.StartTime),
+		ExpiresAt:        utils.GetPointer(props.ExpiresAt),
+		RequestCounts: types.BatchRequestCounts{
+			Total:     props.Total,
+			Completed: counts[model.BatchItemStatusSucceeded],
+			Failed: counts[model.BatchItemStatusErrored] +
+				counts[model.BatchItemStatusCanceled] +
+				counts[model.BatchItemStatusExpired],
+		},
+		Metadata: props.Metadata,
+	}
+
+	if props.CancelInitiatedAt > 0 {
+		batch.Status = "cancelling"
+		batch.CancellingAt = utils.GetPointer(props.CancelInitiatedAt)
+	}
+	return batch
+}
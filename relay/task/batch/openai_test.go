@@ -0,0 +1,61 @@
+package batch
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"done-hub/model"
+	"done-hub/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+func cancelTestOpenAIBatch(batchID string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/batches/"+batchID+"/cancel", nil)
+	c.Params = gin.Params{{Key: "id", Value: batchID}}
+	c.Set("id", 1)
+	CancelOpenAIBatch(c)
+	return recorder
+}
+
+// TestCancelOpenAIBatch 测试取消批次：未执行的条目立即取消，执行中的条目保留，批次进入 cancelling 等待收尾
+func TestCancelOpenAIBatch(t *testing.T) {
+	setupBatchTestDB(t)
+	task := createTestBatchTask(t, model.TaskPlatformOpenAIBatch, &Properties{Total: 3},
+		model.BatchItemStatusSucceeded, model.BatchItemStatusProcessing, model.BatchItemStatusQueued)
+
+	for round := 1; round <= 2; round++ {
+		recorder := cancelTestOpenAIBatch(task.TaskID)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("第 %d 次取消状态码 = %d, 响应 = %s", round, recorder.Code, recorder.Body.String())
+		}
+		batch := &types.Batch{}
+		if err := json.Unmarshal(recorder.Body.Bytes(), batch); err != nil {
+			t.Fatalf("unmarshal batch: %v", err)
+		}
+		if batch.Status != "cancelling" || batch.CancellingAt == nil {
+			t.Errorf("第 %d 次取消后批次状态 = %s, 期望 cancelling", round, batch.Status)
+		}
+		if batch.RequestCounts.Completed != 1 || batch.RequestCounts.Failed != 1 {
+			t.Errorf("第 %d 次取消后 request_counts = %+v, 期望 completed 1、failed 1", round, batch.RequestCounts)
+		}
+	}
+
+	counts, _ := model.GetBatchItemCounts([]string{task.TaskID})
+	if count := counts[task.TaskID]; count[model.BatchItemStatusCanceled] != 1 || count[model.BatchItemStatusProcessing] != 1 {
+		t.Errorf("取消后条目状态 = %v, 期望 queued 条目取消、processing 条目保留", count)
+	}
+
+	model.DB.Model(task).Update("progress", 100)
+	if recorder := cancelTestOpenAIBatch(task.TaskID); recorder.Code != http.StatusConflict {
+		t.Errorf("已结束的批次取消状态码 = %d, 期望 %d", recorder.Code, http.StatusConflict)
+	}
+	if recorder := cancelTestOpenAIBatch("batch_missing"); recorder.Code != http.StatusNotFound {
+		t.Errorf("不存在的批次取消状态码 = %d, 期望 %d", recorder.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,222 @@
+package batch
+
+import (
+	"done-hub/common"
+	"done-hub/common/config"
+	"done-hub/common/logger"
+	"done-hub/model"
+	"done-hub/relay"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+var (
+	workerSignal = make(chan struct{}, 1)
+
+	running     int
+	runningLock sync.Mutex
+	runningCond = sync.NewCond(&runningLock)
+)
+
+// InitWorker 启动网关代执行批处理条目的 worker。条目表即持久化队列，只在主节点消费，
+// 启动时把上次进程退出时残留的 processing 条目放回队列重新执行。
+func InitWorker() {
+	if !config.IsMasterNode {
+		return
+	}
+
+	if err := model.ResetProcessingBatchItems(); err != nil {
+		logger.SysError(fmt.Sprintf("reset processing batch items error: %s", err.Error()))
+	}
+
+	common.SafeGoroutine(worker)
+	ActivateWorker()
+}
+
+// ActivateWorker 唤醒 worker 立即领取新入队的条目
+func ActivateWorker() {
+	select {
+	case workerSignal <- struct{}{}:
+	default:
+	}
+}
+
+func worker() {
+	for {
+		items, err := model.GetQueuedBatchItems(100)
+		if err != nil {
+			logger.SysError(fmt.Sprintf("get queued batch items error: %s", err.Error()))
+		}
+
+		if len(items) == 0 {
+			select {
+			case <-workerSignal:
+			case <-time.After(time.Minute):
+			}
+			continue
+		}
+
+		tasks := make(map[string]*model.Task)
+		for _, queued := range items {
+			acquire()
+			item, err := model.ClaimBatchItem(queued.ID)
+			if err != nil || item == nil {
+				release()
+				continue
+			}
+
+			task, ok := tasks[item.BatchID]
+			if !ok {
+				task, _ = model.GetBatchTask(item.BatchID)
+				tasks[item.BatchID] = task
+			}
+
+			common.TrackedGoroutine(func() {
+				defer release()
+				processItem(task, item)
+			})
+		}
+	}
+}
+
+// acquire / release 按 BatchConcurrency 限制同时在途的条目数，配置可在运行中调整
+func acquire() {
+	runningLock.Lock()
+	defer runningLock.Unlock()
+	for running >= max(config.BatchConcurrency, 1) {
+		runningCond.Wait()
+	}
+	running++
+}
+
+func release() {
+	runningLock.Lock()
+	defer runningLock.Unlock()
+	running--
+	runningCond.Signal()
+}
+
+// processItem 把条目当作一次普通请求走完整的 relay 流程（选渠道、协议转换、重试、计费、日志），
+// 再按批次所属的协议把响应包装成结果行：Claude 批次走 /claude/v1/messages，OpenAI 批次走批次的 endpoint
+func processItem(task *model.Task, item *model.BatchItem) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.SysError(fmt.Sprintf("process batch item %d panic: %v", item.ID, r))
+			finishItem(item, model.BatchItemStatusErrored, erroredResult(task, item, "api_error", "internal error"))
+		}
+	}()
+
+	if task == nil {
+		finishItem(item, model.BatchItemStatusErrored, nil)
+		return
+	}
+
+	props := getProperties(task)
+	if time.Now().Unix() >= props.ExpiresAt {
+		finishItem(item, model.BatchItemStatusExpired, nil)
+		return
+	}
+
+	// 批处理结果是完整响应，忽略条目里的流式参数
+	body, err := sjson.DeleteBytes(item.Request, "stream")
+	if err == nil {
+		body, err = sjson.DeleteBytes(body, "stream_options")
+	}
+	if err != nil {
+		finishItem(item, model.BatchItemStatusErrored, erroredResult(task, item, "invalid_request_error", err.Error()))
+		return
+	}
+
+	path := "/claude/v1/messages"
+	if task.Platform == model.TaskPlatformOpenAIBatch {
+		path = props.Endpoint
+	}
+
+	c, w, err := newBatchContext(task, props, path, body)
+	if err != nil {
+		finishItem(item, model.BatchItemStatusErrored, erroredResult(task, item, "permission_error", err.Error()))
+		return
+	}
+
+	relay.Relay(c)
+
+	response := w.Body.Bytes()
+	if !gjson.ValidBytes(response) {
+		finishItem(item, model.BatchItemStatusErrored, erroredResult(task, item, "api_error", fmt.Sprintf("invalid response with status %d", w.Code)))
+		return
+	}
+
+	status := model.BatchItemStatusSucceeded
+	if w.Code < http.StatusOK || w.Code >= http.StatusMultipleChoices {
+		status = model.BatchItemStatusErrored
+	}
+
+	if task.Platform == model.TaskPlatformOpenAIBatch {
+		finishItem(item, status, openaiResultJSON(item, map[string]any{
+			"status_code": w.Code,
+			"request_id":  c.GetString(logger.RequestIdKey),
+			"body":        json.RawMessage(response),
+		}, nil))
+		return
+	}
+
+	if status == model.BatchItemStatusErrored {
+		finishItem(item, status, resultJSON(item.CustomID, "errored", "error", response))
+		return
+	}
+	finishItem(item, status, resultJSON(item.CustomID, "succeeded", "message", response))
+}
+
+func finishItem(item *model.BatchItem, status string, result []byte) {
+	if err := item.Finish(status, result); err != nil {
+		logger.SysError(fmt.Sprintf("finish batch item %d error: %s", item.ID, err.Error()))
+	}
+}
+
+// resultJSON 拼出 {"custom_id":..., "result":{"type":..., <key>: <raw>}}，raw 原样嵌入不做二次解析
+func resultJSON(customID, resultType, key string, raw []byte) []byte {
+	line, _ := json.Marshal(map[string]any{
+		"custom_id": customID,
+		"result": map[string]any{
+			"type": resultType,
+			key:    json.RawMessage(raw),
+		},
+	})
+	return line
+}
+
+// openaiResultJSON 拼出 OpenAI 批次输出 / 错误文件中的一行
+func openaiResultJSON(item *model.BatchItem, response map[string]any, batchErr map[string]any) []byte {
+	line, _ := json.Marshal(map[string]any{
+		"id":        fmt.Sprintf("batch_req_%d", item.ID),
+		"custom_id": item.CustomID,
+		"response":  response,
+		"error":     batchErr,
+	})
+	return line
+}
+
+// erroredResult 条目未能发出请求时按批次协议生成错误结果行
+func erroredResult(task *model.Task, item *model.BatchItem, errType, message string) []byte {
+	if task != nil && task.Platform == model.TaskPlatformOpenAIBatch {
+		return openaiResultJSON(item, nil, map[string]any{
+			"code":    errType,
+			"message": message,
+		})
+	}
+
+	errorResponse, _ := json.Marshal(map[string]any{
+		"type": "error",
+		"error": map[string]any{
+			"type":    errType,
+			"message": message,
+		},
+	})
+	return resultJSON(item.CustomID, "errored", "error", errorResponse)
+}
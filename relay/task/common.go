@@ -4,8 +4,10 @@ import (
 	"done-hub/common/config"
 	"done-hub/model"
 	"done-hub/relay/task/base"
+	"done-hub/relay/task/batch"
 	"done-hub/relay/task/kling"
 	"done-hub/relay/task/suno"
+	"done-hub/relay/task/video"
 	"errors"
 
 	"github.com/gin-gonic/gin"
@@ -21,6 +23,10 @@ func GetTaskAdaptor(relayType int, c *gin.Context) (base.TaskInterface, error) {
 		return &kling.KlingTask{
 			TaskBase: getTaskBase(c, model.TaskPlatformKling),
 		}, nil
+	case config.RelayModeVideos:
+		return &video.VideoTask{
+			TaskBase: getTaskBase(c, model.TaskPlatformVideo),
+		}, nil
 	default:
 		return nil, errors.New("adaptor not found")
 	}
@@ -34,6 +40,17 @@ func GetTaskAdaptorByPlatform(platform string) (base.TaskInterface, error) {
 		relayType = config.RelayModeSuno
 	case model.TaskPlatformKling:
 		relayType = config.RelayModeKling
+	case model.TaskPlatformVideo:
+		relayType = config.RelayModeVideos
+	case model.TaskPlatformClaudeBatch:
+		// 批次只经后台轮询推进，不走 RelayTaskSubmit，无需对应的 relay mode
+		return &batch.ClaudeBatchTask{
+			TaskBase: getTaskBase(nil, model.TaskPlatformClaudeBatch),
+		}, nil
+	case model.TaskPlatformOpenAIBatch:
+		return &batch.OpenAIBatchTask{
+			TaskBase: getTaskBase(nil, model.TaskPlatformOpenAIBatch),
+		}, nil
 	}
 
 	return GetTaskAdaptor(relayType, nil)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"done-hub/common"
 	"done-hub/common/logger"
+	"done-hub/common/webhook"
 	"done-hub/model"
 	"done-hub/providers"
 	KlingProvider "done-hub/providers/kling"
@@ -43,6 +44,14 @@ func (t *KlingTask) Init() *base.TaskError {
 		return base.StringTaskError(http.StatusBadRequest, "invalid_request", err.Error(), true)
 	}
 
+	if callbackURL, ok := t.Request.CallbackURL.(string); ok && callbackURL != "" {
+		if err := webhook.ValidateURL(callbackURL); err != nil {
+			return base.StringTaskError(http.StatusBadRequest, "invalid_callback_url", err.Error(), true)
+		}
+		t.NotifyHook = callbackURL
+	}
+	t.Request.CallbackURL = nil
+
 	err = t.HandleOriginTaskID()
 	if err != nil {
 		return base.StringTaskError(http.StatusInternalServerError, "get_origin_task_failed", err.Error(), true)
@@ -131,15 +140,17 @@ func (t *KlingTask) ShouldRetry(c *gin.Context, err *base.TaskError) bool {
 
 func (t *KlingTask) UpdateTaskStatus(ctx context.Context, taskChannelM map[int][]string, taskM map[string]*model.Task) error {
 	for channelId, taskIds := range taskChannelM {
-		err := updateKlingTaskAll(ctx, channelId, taskIds, taskM)
-		if err != nil {
-			logger.LogError(ctx, fmt.Sprintf("渠道 #%d 更新异步任务失败: %s", channelId, err.Error()))
+		for keyId, keyTaskIds := range base.GroupTaskIdsByChannelKey(taskIds, taskM) {
+			err := updateKlingTaskAll(ctx, channelId, keyId, keyTaskIds, taskM)
+			if err != nil {
+				logger.LogError(ctx, fmt.Sprintf("渠道 #%d 更新异步任务失败: %s", channelId, err.Error()))
+			}
 		}
 	}
 	return nil
 }
 
-func updateKlingTaskAll(ctx context.Context, channelId int, taskIds []string, taskM map[string]*model.Task) error {
+func updateKlingTaskAll(ctx context.Context, channelId, keyId int, taskIds []string, taskM map[string]*model.Task) error {
 	logger.LogWarn(ctx, fmt.Sprintf("渠道 #%d 未完成的任务有: %d", channelId, len(taskIds)))
 	if len(taskIds) == 0 {
 		return nil
@@ -158,7 +169,7 @@ func updateKlingTaskAll(ctx context.Context, channelId int, taskIds []string, ta
 		return fmt.Errorf("channel not found")
 	}
 
-	providers := providers.GetProvider(channel, nil)
+	providers := providers.GetProvider(model.ChannelWithKey(channel, keyId), nil)
 	KlingProvider, ok := providers.(*KlingProvider.KlingProvider)
 	if !ok {
 		err := model.TaskBulkUpdate(taskIds, map[string]any{
@@ -223,6 +234,8 @@ func updateKlingTaskAll(ctx context.Context, channelId int, taskIds []string, ta
 		err := task.Update()
 		if err != nil {
 			logger.SysError("UpdateTask task error: " + err.Error())
+		} else if task.Progress == 100 {
+			webhook.EnqueueTask(task, TaskModel2Dto(task))
 		}
 	}
 
@@ -7,6 +7,7 @@ import (
 	"done-hub/common/utils"
 	"done-hub/metrics"
 	"done-hub/model"
+	"done-hub/relay"
 	"done-hub/relay/relay_util"
 	"done-hub/relay/task/base"
 	"done-hub/types"
@@ -34,6 +35,16 @@ func buildTaskChannelFilters(c *gin.Context) []model.ChannelsFilterFunc {
 	return filters
 }
 
+// recordTaskResult 任务提交结果记入渠道熔断器
+func recordTaskResult(c *gin.Context, taskAdaptor base.TaskInterface, taskErr *base.TaskError) {
+	channelId := taskAdaptor.GetProvider().GetChannel().Id
+	if taskErr == nil {
+		relay.RecordChannelResult(c, channelId, http.StatusOK, false)
+		return
+	}
+	relay.RecordChannelResult(c, channelId, taskErr.StatusCode, taskErr.LocalError)
+}
+
 func RelayTaskSubmit(c *gin.Context) {
 	var taskErr *base.TaskError
 	taskAdaptor, err := GetTaskAdaptor(GetRelayMode(c), c)
@@ -62,6 +73,7 @@ func RelayTaskSubmit(c *gin.Context) {
 	}
 
 	taskErr = taskAdaptor.Relay()
+	recordTaskResult(c, taskAdaptor, taskErr)
 	if taskErr == nil {
 		CompletedTask(quotaInstance, taskAdaptor, relay_util.NewConsumeSnapshot(c))
 		// 返回结果
@@ -104,7 +116,7 @@ func RelayTaskSubmit(c *gin.Context) {
 	logger.LogError(c.Request.Context(), fmt.Sprintf("retry_start model=%s channel_id=%d total_channels=%d config_max_retries=%d actual_max_retries=%d status_code=%d error=\"%s\"",
 		modelName, channel.Id, totalChannelsAtStart, retryTimes, actualRetryTimes, taskErr.StatusCode, taskErr.Message))
 	for i := actualRetryTimes; i > 0; i-- {
-		model.ChannelGroup.SetCooldowns(channel.Id, taskAdaptor.GetModelName())
+		model.ChannelGroup.SetCooldowns(channel.Id, c.GetString(config.GinChannelModelKey))
 		taskErr = taskAdaptor.SetProvider()
 		if taskErr != nil {
 			continue
@@ -141,6 +153,7 @@ func RelayTaskSubmit(c *gin.Context) {
 		c.Set(config.GinUpstreamRequestIdKey, "")
 
 		taskErr = taskAdaptor.Relay()
+		recordTaskResult(c, taskAdaptor, taskErr)
 		if taskErr == nil {
 			// 重试成功
 			logger.LogInfo(c.Request.Context(), fmt.Sprintf("retry_success model=%s channel_id=%d attempt=%d/%d total_channels=%d",
@@ -150,6 +163,8 @@ func RelayTaskSubmit(c *gin.Context) {
 			common.TrackedGoroutine(func() {
 				CompletedTask(quotaInstance, taskAdaptor, snap)
 			})
+			taskAdaptor.GinResponse()
+			metrics.RecordProvider(c, 200)
 			return
 		}
 
@@ -199,6 +214,8 @@ func GetRelayMode(c *gin.Context) int {
 		relayMode = config.RelayModeSuno
 	} else if strings.HasPrefix(path, "/kling") {
 		relayMode = config.RelayModeKling
+	} else if strings.HasPrefix(path, "/v1/videos") {
+		relayMode = config.RelayModeVideos
 	}
 
 	return relayMode
@@ -4,6 +4,7 @@ import (
 	"context"
 	"done-hub/common"
 	"done-hub/common/logger"
+	"done-hub/common/webhook"
 	"done-hub/metrics"
 	"done-hub/model"
 	"done-hub/providers"
@@ -43,6 +44,14 @@ func (t *SunoTask) Init() *base.TaskError {
 		return base.StringTaskError(http.StatusBadRequest, "invalid_request", err.Error(), true)
 	}
 
+	if t.Request.NotifyHook != "" {
+		if err := webhook.ValidateURL(t.Request.NotifyHook); err != nil {
+			return base.StringTaskError(http.StatusBadRequest, "invalid_notify_hook", err.Error(), true)
+		}
+	}
+	t.NotifyHook = t.Request.NotifyHook
+	t.Request.NotifyHook = ""
+
 	err = t.HandleOriginTaskID()
 	if err != nil {
 		return base.StringTaskError(http.StatusInternalServerError, "get_origin_task_failed", err.Error(), true)
@@ -156,15 +165,17 @@ func (t *SunoTask) ShouldRetry(c *gin.Context, err *base.TaskError) bool {
 
 func (t *SunoTask) UpdateTaskStatus(ctx context.Context, taskChannelM map[int][]string, taskM map[string]*model.Task) error {
 	for channelId, taskIds := range taskChannelM {
-		err := updateSunoTaskAll(ctx, channelId, taskIds, taskM)
-		if err != nil {
-			logger.LogError(ctx, fmt.Sprintf("渠道 #%d 更新异步任务失败: %s", channelId, err.Error()))
+		for keyId, keyTaskIds := range base.GroupTaskIdsByChannelKey(taskIds, taskM) {
+			err := updateSunoTaskAll(ctx, channelId, keyId, keyTaskIds, taskM)
+			if err != nil {
+				logger.LogError(ctx, fmt.Sprintf("渠道 #%d 更新异步任务失败: %s", channelId, err.Error()))
+			}
 		}
 	}
 	return nil
 }
 
-func updateSunoTaskAll(ctx context.Context, channelId int, taskIds []string, taskM map[string]*model.Task) error {
+func updateSunoTaskAll(ctx context.Context, channelId, keyId int, taskIds []string, taskM map[string]*model.Task) error {
 	logger.LogWarn(ctx, fmt.Sprintf("渠道 #%d 未完成的任务有: %d", channelId, len(taskIds)))
 	if len(taskIds) == 0 {
 		return nil
@@ -183,7 +194,7 @@ func updateSunoTaskAll(ctx context.Context, channelId int, taskIds []string, tas
 		return fmt.Errorf("channel not found")
 	}
 
-	providers := providers.GetProvider(channel, nil)
+	providers := providers.GetProvider(model.ChannelWithKey(channel, keyId), nil)
 	sunoProvider, ok := providers.(*sunoProvider.SunoProvider)
 	if !ok {
 		err := model.TaskBulkUpdate(taskIds, map[string]any{
@@ -240,6 +251,8 @@ func updateSunoTaskAll(ctx context.Context, channelId int, taskIds []string, tas
 		err := task.Update()
 		if err != nil {
 			logger.SysError("UpdateTask task error: " + err.Error())
+		} else if task.Progress == 100 {
+			webhook.EnqueueTask(task, TaskModel2Dto(task))
 		}
 	}
 	return nil
@@ -5,6 +5,7 @@ import (
 	"done-hub/common"
 	"done-hub/common/logger"
 	"done-hub/model"
+	"done-hub/relay/task/batch"
 	"fmt"
 	"sync"
 	"sync/atomic"
@@ -22,6 +23,7 @@ func InitTask() {
 		Task()
 	})
 
+	batch.InitWorker()
 	ActivateUpdateTaskBulk()
 }
 
@@ -0,0 +1,68 @@
+package video
+
+import (
+	"done-hub/model"
+	"done-hub/types"
+	"encoding/json"
+)
+
+// Properties 视频任务元信息，序列化后存放在 Task.Properties
+type Properties struct {
+	UpstreamID string `json:"upstream_id"`
+	Model      string `json:"model"`
+	Seconds    string `json:"seconds,omitempty"`
+	Size       string `json:"size,omitempty"`
+	VideoURL   string `json:"video_url,omitempty"`
+}
+
+func getProperties(task *model.Task) *Properties {
+	props := &Properties{}
+	_ = json.Unmarshal(task.Properties, props)
+	return props
+}
+
+// videoStatus 把任务状态归一到 OpenAI 的 queued / in_progress / completed / failed
+func videoStatus(status model.TaskStatus) string {
+	switch status {
+	case model.TaskStatusInProgress:
+		return "in_progress"
+	case model.TaskStatusSuccess:
+		return "completed"
+	case model.TaskStatusFailure:
+		return "failed"
+	default:
+		return "queued"
+	}
+}
+
+func toVideoObject(task *model.Task) *types.VideoObject {
+	props := getProperties(task)
+	video := &types.VideoObject{
+		ID:        task.TaskID,
+		Object:    "video",
+		Model:     props.Model,
+		Status:    videoStatus(task.Status),
+		Progress:  task.Progress,
+		CreatedAt: task.SubmitTime,
+		Size:      props.Size,
+		Seconds:   props.Seconds,
+	}
+
+	switch task.Status {
+	case model.TaskStatusSuccess:
+		video.CompletedAt = &task.FinishTime
+	case model.TaskStatusFailure:
+		video.CompletedAt = &task.FinishTime
+		video.Error = &types.VideoError{
+			Code:    "video_generation_failed",
+			Message: task.FailReason,
+		}
+	}
+
+	return video
+}
+
+func mustMarshal(v any) []byte {
+	data, _ := json.Marshal(v)
+	return data
+}
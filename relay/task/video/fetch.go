@@ -0,0 +1,139 @@
+package video
+
+import (
+	"done-hub/common"
+	"done-hub/common/logger"
+	"done-hub/model"
+	"done-hub/providers"
+	providersBase "done-hub/providers/base"
+	"done-hub/types"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RetrieveVideo 处理 GET /v1/videos/:id
+func RetrieveVideo(c *gin.Context) {
+	task := getUserVideoTask(c)
+	if task == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, toVideoObject(task))
+}
+
+// ListVideos 处理 GET /v1/videos
+func ListVideos(c *gin.Context) {
+	userId := c.GetInt("id")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		common.AbortWithMessage(c, http.StatusBadRequest, "limit: must be between 1 and 100")
+		return
+	}
+
+	var afterID int64
+	if after := c.Query("after"); after != "" {
+		task, err := model.GetTaskByTaskId(model.TaskPlatformVideo, userId, after)
+		if err != nil || task == nil {
+			common.AbortWithMessage(c, http.StatusBadRequest, fmt.Sprintf("after: video %s not found", after))
+			return
+		}
+		afterID = task.ID
+	}
+
+	tasks, err := model.GetUserTasksByCursor(model.TaskPlatformVideo, userId, 0, afterID, limit+1)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	hasMore := len(tasks) > limit
+	if hasMore {
+		tasks = tasks[:limit]
+	}
+
+	response := &types.VideoList{
+		Object:  "list",
+		Data:    make([]*types.VideoObject, 0, len(tasks)),
+		HasMore: hasMore,
+	}
+	for _, task := range tasks {
+		response.Data = append(response.Data, toVideoObject(task))
+	}
+
+	if len(response.Data) > 0 {
+		response.FirstID = &response.Data[0].ID
+		response.LastID = &response.Data[len(response.Data)-1].ID
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetVideoContent 处理 GET /v1/videos/:id/content，经原渠道下载成片
+func GetVideoContent(c *gin.Context) {
+	if variant := c.DefaultQuery("variant", "video"); variant != "video" {
+		common.AbortWithMessage(c, http.StatusBadRequest, fmt.Sprintf("variant: %s is not supported", variant))
+		return
+	}
+
+	task := getUserVideoTask(c)
+	if task == nil {
+		return
+	}
+
+	if task.Status != model.TaskStatusSuccess {
+		common.AbortWithMessage(c, http.StatusNotFound, fmt.Sprintf("video %s is not completed", task.TaskID))
+		return
+	}
+
+	channel := model.ChannelGroup.GetChannel(task.ChannelId)
+	if channel == nil {
+		common.AbortWithMessage(c, http.StatusServiceUnavailable, "channel not found")
+		return
+	}
+
+	videoProvider, ok := providers.GetProvider(model.ChannelWithKey(channel, task.ChannelKeyId), nil).(providersBase.VideoInterface)
+	if !ok {
+		common.AbortWithMessage(c, http.StatusServiceUnavailable, "channel not implemented")
+		return
+	}
+
+	props := getProperties(task)
+	resp, errWithCode := videoProvider.GetVideoContent(&types.VideoTaskResult{
+		TaskID:   props.UpstreamID,
+		Action:   task.Action,
+		Status:   string(task.Status),
+		VideoURL: props.VideoURL,
+	})
+	if errWithCode != nil {
+		logger.LogError(c.Request.Context(), fmt.Sprintf("download video %s error: %s", task.TaskID, errWithCode.Message))
+		common.AbortWithMessage(c, errWithCode.StatusCode, "download video failed")
+		return
+	}
+	defer resp.Body.Close()
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "video/mp4"
+	}
+
+	c.DataFromReader(http.StatusOK, resp.ContentLength, contentType, resp.Body, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%q", task.TaskID+".mp4"),
+	})
+}
+
+func getUserVideoTask(c *gin.Context) *model.Task {
+	videoId := c.Param("id")
+	task, err := model.GetTaskByTaskId(model.TaskPlatformVideo, c.GetInt("id"), videoId)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return nil
+	}
+	if task == nil {
+		common.AbortWithMessage(c, http.StatusNotFound, fmt.Sprintf("No such Video object: %s", videoId))
+		return nil
+	}
+	return task
+}
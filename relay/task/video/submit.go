@@ -0,0 +1,303 @@
+package video
+
+import (
+	"context"
+	"done-hub/common"
+	"done-hub/common/logger"
+	"done-hub/common/utils"
+	"done-hub/common/webhook"
+	"done-hub/metrics"
+	"done-hub/model"
+	"done-hub/providers"
+	providersBase "done-hub/providers/base"
+	"done-hub/relay/task/base"
+	"done-hub/types"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+)
+
+// VideoTask OpenAI 兼容的 /v1/videos：按模型分发到实现了 VideoInterface 的渠道（可灵、Veo 等），
+// 按次预扣与计费沿用任务系统，状态由 UpdateTaskBulk 轮询推进
+type VideoTask struct {
+	base.TaskBase
+	Request  *types.VideoRequest
+	Provider providersBase.VideoInterface
+}
+
+func (t *VideoTask) HandleError(err *base.TaskError) {
+	common.AbortWithMessage(t.C, err.StatusCode, err.Message)
+}
+
+func (t *VideoTask) Init() *base.TaskError {
+	t.Request = &types.VideoRequest{}
+	if err := common.UnmarshalBodyReusable(t.C, t.Request); err != nil {
+		return base.StringTaskError(http.StatusBadRequest, "invalid_request_error", err.Error(), true)
+	}
+
+	if t.Request.InputReference == "" {
+		inputReference, err := readInputReference(t.C)
+		if err != nil {
+			return base.StringTaskError(http.StatusBadRequest, "invalid_request_error", err.Error(), true)
+		}
+		t.Request.InputReference = inputReference
+	}
+
+	t.OriginalModel = billingModelName(t.Request)
+
+	return nil
+}
+
+// readInputReference multipart 请求中 input_reference 既可以是文件，也可以是图片 URL
+func readInputReference(c *gin.Context) (string, error) {
+	if !strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		return "", nil
+	}
+
+	fileHeader, err := c.FormFile("input_reference")
+	if err != nil {
+		return c.PostForm("input_reference"), nil
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+
+	mimeType := fileHeader.Header.Get("Content-Type")
+	if mimeType == "" || mimeType == "application/octet-stream" {
+		mimeType = http.DetectContentType(data)
+	}
+
+	return fmt.Sprintf("data:%s;base64,%s", mimeType, base64.StdEncoding.EncodeToString(data)), nil
+}
+
+// billingModelName 可灵按 kling-video_{model}_{mode}_{duration} 计费，与 /kling 接口保持一致
+func billingModelName(request *types.VideoRequest) string {
+	if !strings.HasPrefix(request.Model, "kling-") || strings.HasPrefix(request.Model, "kling-video_") {
+		return request.Model
+	}
+
+	mode := request.Mode
+	if mode == "" {
+		mode = "std"
+	}
+	duration := "5"
+	if request.Duration() > 5 {
+		duration = "10"
+	}
+
+	return fmt.Sprintf("kling-video_%s_%s_%s", request.Model, mode, duration)
+}
+
+func (t *VideoTask) SetProvider() *base.TaskError {
+	provider, err := t.GetProviderByModel()
+	if err != nil {
+		return base.StringTaskError(http.StatusServiceUnavailable, "provider_not_found", err.Error(), true)
+	}
+
+	videoProvider, ok := provider.(providersBase.VideoInterface)
+	if !ok {
+		return base.StringTaskError(http.StatusServiceUnavailable, "provider_not_found", "channel not implemented", true)
+	}
+
+	t.Provider = videoProvider
+	t.BaseProvider = provider
+
+	return nil
+}
+
+func (t *VideoTask) Relay() *base.TaskError {
+	request := *t.Request
+	request.Model = t.ModelName
+
+	result, errWithCode := t.Provider.CreateVideo(&request)
+	if errWithCode != nil {
+		return base.OpenAIErrToTaskErr(errWithCode)
+	}
+
+	t.InitTask()
+	t.Task.TaskID = "video_" + utils.GetRandomString(32)
+	t.Task.ChannelId = t.BaseProvider.GetChannel().Id
+	t.Task.Action = result.Action
+	t.Task.Status = model.TaskStatusSubmitted
+	t.Task.Properties = datatypes.JSON(mustMarshal(&Properties{
+		UpstreamID: result.TaskID,
+		Model:      t.Request.Model,
+		Seconds:    t.Request.Seconds,
+		Size:       t.Request.Size,
+	}))
+	if result.Data != nil {
+		t.Task.Data = datatypes.JSON(mustMarshal(result.Data))
+	}
+
+	t.Response = toVideoObject(t.Task)
+
+	return nil
+}
+
+func (t *VideoTask) ShouldRetry(c *gin.Context, err *base.TaskError) bool {
+	if err == nil {
+		return false
+	}
+
+	metrics.RecordProvider(c, err.StatusCode)
+
+	if err.LocalError {
+		return false
+	}
+
+	if _, ok := t.C.Get("specific_channel_id"); ok {
+		return false
+	}
+
+	// 超时不重试，上游可能已经创建了任务
+	if err.StatusCode == http.StatusGatewayTimeout || err.StatusCode == 524 {
+		return false
+	}
+
+	return true
+}
+
+func (t *VideoTask) UpdateTaskStatus(ctx context.Context, taskChannelM map[int][]string, taskM map[string]*model.Task) error {
+	for channelId, taskIds := range taskChannelM {
+		for keyId, keyTaskIds := range base.GroupTaskIdsByChannelKey(taskIds, taskM) {
+			err := updateVideoTaskAll(ctx, channelId, keyId, keyTaskIds, taskM)
+			if err != nil {
+				logger.LogError(ctx, fmt.Sprintf("渠道 #%d 更新视频任务失败: %s", channelId, err.Error()))
+			}
+		}
+	}
+	return nil
+}
+
+func updateVideoTaskAll(ctx context.Context, channelId, keyId int, taskIds []string, taskM map[string]*model.Task) error {
+	channel := model.ChannelGroup.GetChannel(channelId)
+	if channel == nil {
+		failVideoTasks(ctx, taskIds, taskM, fmt.Sprintf("获取渠道信息失败，请联系管理员，渠道ID：%d", channelId))
+		return fmt.Errorf("channel not found")
+	}
+
+	videoProvider, ok := providers.GetProvider(model.ChannelWithKey(channel, keyId), nil).(providersBase.VideoInterface)
+	if !ok {
+		failVideoTasks(ctx, taskIds, taskM, "获取供应商失败，请联系管理员")
+		return fmt.Errorf("provider not found")
+	}
+
+	for _, taskId := range taskIds {
+		task := taskM[taskId]
+		props := getProperties(task)
+
+		result, errWithCode := videoProvider.GetVideo(props.UpstreamID, task.Action)
+		if errWithCode != nil {
+			logger.LogError(ctx, fmt.Sprintf("Get video task %s error: %s", task.TaskID, errWithCode.Message))
+			continue
+		}
+
+		updateVideoTask(ctx, task, props, result)
+	}
+
+	return nil
+}
+
+func updateVideoTask(ctx context.Context, task *model.Task, props *Properties, result *types.VideoTaskResult) {
+	status := model.TaskStatus(result.Status)
+	params := map[string]any{
+		"status":   status,
+		"progress": result.Progress,
+	}
+	if result.Data != nil {
+		params["data"] = datatypes.JSON(mustMarshal(result.Data))
+	}
+	if task.StartTime == 0 && status == model.TaskStatusInProgress {
+		params["start_time"] = time.Now().Unix()
+	}
+
+	if status != model.TaskStatusSuccess && status != model.TaskStatusFailure {
+		// 进行中的进度不能到 100，否则任务会被当作已完成而停止轮询
+		if result.Progress >= 100 {
+			params["progress"] = 99
+		}
+		if err := model.TaskBulkUpdateByID([]int64{task.ID}, params); err != nil {
+			logger.LogError(ctx, fmt.Sprintf("update video task %s error: %s", task.TaskID, err.Error()))
+		}
+		return
+	}
+
+	props.VideoURL = result.VideoURL
+	task.Status = status
+	task.Progress = 100
+	task.FinishTime = time.Now().Unix()
+	task.FailReason = result.FailReason
+	task.Properties = datatypes.JSON(mustMarshal(props))
+	params["progress"] = task.Progress
+	params["finish_time"] = task.FinishTime
+	params["fail_reason"] = task.FailReason
+	params["properties"] = task.Properties
+
+	// 多节点同时轮询时只有一个节点能完成状态迁移，避免重复补偿
+	updated, err := model.TaskUpdateIfUnfinished(task.ID, params)
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("update video task %s error: %s", task.TaskID, err.Error()))
+		return
+	}
+
+	if !updated {
+		return
+	}
+
+	if status == model.TaskStatusFailure {
+		logger.LogError(ctx, task.TaskID+" 构建失败，"+result.FailReason)
+		refundVideoTask(ctx, task)
+	}
+	webhook.EnqueueTask(task, toVideoObject(task))
+}
+
+func failVideoTasks(ctx context.Context, taskIds []string, taskM map[string]*model.Task, reason string) {
+	for _, taskId := range taskIds {
+		task := taskM[taskId]
+		task.Status = model.TaskStatusFailure
+		task.Progress = 100
+		task.FailReason = reason
+		task.FinishTime = time.Now().Unix()
+		updated, err := model.TaskUpdateIfUnfinished(task.ID, map[string]any{
+			"fail_reason": task.FailReason,
+			"status":      task.Status,
+			"progress":    task.Progress,
+			"finish_time": task.FinishTime,
+		})
+		if err != nil {
+			logger.SysError(fmt.Sprintf("UpdateTask error: %v", err))
+			continue
+		}
+		if updated {
+			refundVideoTask(ctx, task)
+			webhook.EnqueueTask(task, toVideoObject(task))
+		}
+	}
+}
+
+func refundVideoTask(ctx context.Context, task *model.Task) {
+	if task.Quota <= 0 {
+		return
+	}
+
+	if err := model.IncreaseUserQuota(task.UserId, task.Quota); err != nil {
+		logger.LogError(ctx, "fail to increase user quota: "+err.Error())
+		return
+	}
+	logContent := fmt.Sprintf("异步任务执行失败 %s，补偿 %s", task.TaskID, common.LogQuota(task.Quota))
+	model.RecordLog(task.UserId, model.LogTypeSystem, logContent)
+}
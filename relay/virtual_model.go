@@ -0,0 +1,114 @@
+package relay
+
+import (
+	"done-hub/common/config"
+	"done-hub/common/logger"
+	"done-hub/common/utils"
+	"done-hub/model"
+	"done-hub/relay/relay_util"
+	"done-hub/types"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// startVirtualModel 请求的是虚拟模型或请求体带有 models 回退列表时记录回退进度，之后选渠道按当前步骤的模型进行
+func startVirtualModel(c *gin.Context, modelName string) *relay_util.VirtualModelRoute {
+	skipChannelIds, _ := utils.GetGinValue[[]int](c, "skip_channel_ids")
+
+	var route *relay_util.VirtualModelRoute
+	if virtualModel := model.VirtualModels.Get(modelName); virtualModel != nil {
+		route = relay_util.NewVirtualModelRoute(virtualModel, skipChannelIds)
+	} else if models := requestRoutingModels(c, modelName); len(models) > 1 {
+		route = relay_util.NewModelListRoute(models, skipChannelIds)
+	} else {
+		return nil
+	}
+	c.Set(config.GinVirtualModelKey, route)
+
+	return route
+}
+
+func getVirtualModelRoute(c *gin.Context) *relay_util.VirtualModelRoute {
+	route, _ := utils.GetGinValue[*relay_util.VirtualModelRoute](c, config.GinVirtualModelKey)
+	return route
+}
+
+// virtualModelErrorClass 把上游错误归入回退条件的错误类别，无法归类时返回 any，只有配置了 any 的步骤才会回退
+func virtualModelErrorClass(apiErr *types.OpenAIErrorWithStatusCode) string {
+	if apiErr == nil {
+		return ""
+	}
+
+	message := strings.ToLower(apiErr.OpenAIError.Message)
+	code := strings.ToLower(fmt.Sprintf("%v", apiErr.OpenAIError.Code))
+
+	switch {
+	case apiErr.StatusCode == http.StatusTooManyRequests:
+		return model.VirtualModelFallbackRateLimit
+	case apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden:
+		return model.VirtualModelFallbackAuth
+	case apiErr.StatusCode == http.StatusRequestTimeout || apiErr.StatusCode == http.StatusGatewayTimeout || apiErr.StatusCode == 524 ||
+		strings.Contains(message, "timeout") || strings.Contains(message, "deadline exceeded"):
+		return model.VirtualModelFallbackTimeout
+	case strings.Contains(code, "context_length") || code == "prompt_tokens_exceed_limit" ||
+		strings.Contains(message, "context length") || strings.Contains(message, "context window") ||
+		strings.Contains(message, "maximum context") || strings.Contains(message, "prompt is too long"):
+		return model.VirtualModelFallbackContextLength
+	case apiErr.StatusCode >= http.StatusInternalServerError:
+		return model.VirtualModelFallbackServerError
+	}
+
+	return model.VirtualModelFallbackAny
+}
+
+// shouldFallbackVirtualModel 判断是否回退到下一个步骤。已向客户端输出时不能再换模型；
+// 本步骤超过耗时上限时直接回退，否则先在本步骤内换渠道重试，无法重试时再按回退条件判断。
+// apiErr 为 nil 表示本步骤没有可用渠道。
+func shouldFallbackVirtualModel(c *gin.Context, route *relay_util.VirtualModelRoute, apiErr *types.OpenAIErrorWithStatusCode, retryable bool) bool {
+	if route == nil || route.RemainingSteps() == 0 || c.Writer.Written() {
+		return false
+	}
+	if route.StepExpired() {
+		return true
+	}
+	if retryable {
+		return false
+	}
+
+	return route.Step().Matches(virtualModelErrorClass(apiErr))
+}
+
+// advanceVirtualModel 进入下一个步骤，并恢复跳过的渠道列表，让上一步失败过的渠道可以为新的模型服务
+func advanceVirtualModel(c *gin.Context, route *relay_util.VirtualModelRoute, apiErr *types.OpenAIErrorWithStatusCode) {
+	from := route.Step().Model
+	errorClass := virtualModelErrorClass(apiErr)
+	if route.StepExpired() {
+		errorClass = model.VirtualModelFallbackTimeout
+	}
+	route.Advance()
+	c.Set("skip_channel_ids", route.InitialSkipChannelIds())
+
+	logger.LogWarn(c.Request.Context(), fmt.Sprintf("virtual_model_fallback name=%s from=%s to=%s step=%d error_class=%s",
+		route.Name(), from, route.Step().Model, route.StepNumber(), errorClass))
+}
+
+// setProviderWithFallback 选择渠道，请求虚拟模型且当前步骤没有可用渠道时按回退条件进入下一个步骤。
+// apiErr 为本步骤最近一次失败的错误，刚进入的步骤没有可用渠道时总是继续回退。
+func setProviderWithFallback(relay RelayBaseInterface, route *relay_util.VirtualModelRoute, apiErr *types.OpenAIErrorWithStatusCode) error {
+	c := relay.getContext()
+	for {
+		err := relay.setProvider(relay.getOriginalModel())
+		if err == nil {
+			setRequestRoutingHeaders(relay)
+			return nil
+		}
+		if !shouldFallbackVirtualModel(c, route, apiErr, false) {
+			return err
+		}
+		advanceVirtualModel(c, route, apiErr)
+		apiErr = nil
+	}
+}
@@ -0,0 +1,271 @@
+package relay
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"done-hub/common/config"
+	"done-hub/common/logger"
+	"done-hub/model"
+	"done-hub/relay/relay_util"
+	"done-hub/types"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/datatypes"
+)
+
+// testRoutingRelay 只用于回退测试的 relay，available 中的模型才有可用渠道
+type testRoutingRelay struct {
+	relayBase
+	available map[string]bool
+	tried     []string
+}
+
+func (r *testRoutingRelay) send() (*types.OpenAIErrorWithStatusCode, bool) { return nil, true }
+func (r *testRoutingRelay) getPromptTokens() (int, error)                  { return 0, nil }
+func (r *testRoutingRelay) setRequest() error                              { return nil }
+
+func (r *testRoutingRelay) setProvider(modelName string) error {
+	if route := getVirtualModelRoute(r.c); route != nil && route.Name() == modelName {
+		modelName = route.Step().Model
+	}
+	r.tried = append(r.tried, modelName)
+	if !r.available[modelName] {
+		return errors.New("no available channel")
+	}
+	return nil
+}
+
+func newRoutingTestContext() *gin.Context {
+	if logger.Logger == nil {
+		logger.Logger = zap.NewNop()
+	}
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	return c
+}
+
+func newTestVirtualModelRoute(steps ...model.VirtualModelStep) *relay_util.VirtualModelRoute {
+	return relay_util.NewVirtualModelRoute(&model.VirtualModel{
+		Name:  "smart",
+		Steps: datatypes.NewJSONType(steps),
+	}, []int{9})
+}
+
+func newTestAPIError(statusCode int, message string, code any) *types.OpenAIErrorWithStatusCode {
+	return &types.OpenAIErrorWithStatusCode{
+		OpenAIError: types.OpenAIError{Message: message, Code: code},
+		StatusCode:  statusCode,
+	}
+}
+
+// TestVirtualModelErrorClass 测试上游错误归入回退条件的错误类别
+func TestVirtualModelErrorClass(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *types.OpenAIErrorWithStatusCode
+		want string
+	}{
+		{name: "没有错误", err: nil, want: ""},
+		{name: "429 限流", err: newTestAPIError(http.StatusTooManyRequests, "rate limited", nil), want: model.VirtualModelFallbackRateLimit},
+		{name: "401 鉴权", err: newTestAPIError(http.StatusUnauthorized, "invalid key", nil), want: model.VirtualModelFallbackAuth},
+		{name: "403 鉴权", err: newTestAPIError(http.StatusForbidden, "forbidden", nil), want: model.VirtualModelFallbackAuth},
+		{name: "504 超时", err: newTestAPIError(http.StatusGatewayTimeout, "gateway", nil), want: model.VirtualModelFallbackTimeout},
+		{name: "524 超时", err: newTestAPIError(524, "origin", nil), want: model.VirtualModelFallbackTimeout},
+		{name: "消息含 timeout 优先于 5xx", err: newTestAPIError(http.StatusBadGateway, "upstream Timeout", nil), want: model.VirtualModelFallbackTimeout},
+		{name: "消息含 deadline exceeded", err: newTestAPIError(http.StatusInternalServerError, "context deadline exceeded", nil), want: model.VirtualModelFallbackTimeout},
+		{name: "code 为 context_length_exceeded", err: newTestAPIError(http.StatusBadRequest, "too long", "context_length_exceeded"), want: model.VirtualModelFallbackContextLength},
+		{name: "code 为 prompt_tokens_exceed_limit", err: newTestAPIError(http.StatusBadRequest, "too long", "prompt_tokens_exceed_limit"), want: model.VirtualModelFallbackContextLength},
+		{name: "消息为 prompt is too long", err: newTestAPIError(http.StatusBadRequest, "Prompt is too long: 210000 tokens", nil), want: model.VirtualModelFallbackContextLength},
+		{name: "消息含 maximum context", err: newTestAPIError(http.StatusBadRequest, "This model's maximum context length is 128000 tokens", nil), want: model.VirtualModelFallbackContextLength},
+		{name: "500 服务端错误", err: newTestAPIError(http.StatusInternalServerError, "internal", nil), want: model.VirtualModelFallbackServerError},
+		{name: "503 服务端错误", err: newTestAPIError(http.StatusServiceUnavailable, "overloaded", nil), want: model.VirtualModelFallbackServerError},
+		{name: "无法归类的 400", err: newTestAPIError(http.StatusBadRequest, "invalid parameter", "invalid_request"), want: model.VirtualModelFallbackAny},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := virtualModelErrorClass(tt.err); got != tt.want {
+				t.Errorf("virtualModelErrorClass() = %q, 期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestShouldFallbackVirtualModel 测试回退判断：可重试时先在本步骤内重试，无法重试时按回退条件判断
+func TestShouldFallbackVirtualModel(t *testing.T) {
+	rateLimited := newTestAPIError(http.StatusTooManyRequests, "rate limited", nil)
+	serverError := newTestAPIError(http.StatusInternalServerError, "internal", nil)
+	badRequest := newTestAPIError(http.StatusBadRequest, "invalid parameter", nil)
+
+	tests := []struct {
+		name      string
+		route     *relay_util.VirtualModelRoute
+		err       *types.OpenAIErrorWithStatusCode
+		retryable bool
+		written   bool
+		want      bool
+	}{
+		{name: "未请求虚拟模型", route: nil, err: serverError, want: false},
+		{name: "已是最后一步", route: newTestVirtualModelRoute(model.VirtualModelStep{Model: "a"}), err: serverError, want: false},
+		{name: "已向客户端输出", route: newTestVirtualModelRoute(model.VirtualModelStep{Model: "a"}, model.VirtualModelStep{Model: "b"}), err: serverError, written: true, want: false},
+		{name: "可重试时先在本步骤内重试", route: newTestVirtualModelRoute(model.VirtualModelStep{Model: "a"}, model.VirtualModelStep{Model: "b"}), err: serverError, retryable: true, want: false},
+		{name: "未配置回退条件时任何错误都回退", route: newTestVirtualModelRoute(model.VirtualModelStep{Model: "a"}, model.VirtualModelStep{Model: "b"}), err: badRequest, want: true},
+		{
+			name:  "错误类别命中回退条件",
+			route: newTestVirtualModelRoute(model.VirtualModelStep{Model: "a", FallbackOn: []string{model.VirtualModelFallbackRateLimit}}, model.VirtualModelStep{Model: "b"}),
+			err:   rateLimited,
+			want:  true,
+		},
+		{
+			name:  "错误类别未命中回退条件",
+			route: newTestVirtualModelRoute(model.VirtualModelStep{Model: "a", FallbackOn: []string{model.VirtualModelFallbackRateLimit}}, model.VirtualModelStep{Model: "b"}),
+			err:   serverError,
+			want:  false,
+		},
+		{
+			name:  "无法归类的错误只在配置了 any 时回退",
+			route: newTestVirtualModelRoute(model.VirtualModelStep{Model: "a", FallbackOn: []string{model.VirtualModelFallbackAuth, model.VirtualModelFallbackAny}}, model.VirtualModelStep{Model: "b"}),
+			err:   badRequest,
+			want:  true,
+		},
+		{
+			name:  "本步骤没有可用渠道时总是回退",
+			route: newTestVirtualModelRoute(model.VirtualModelStep{Model: "a", FallbackOn: []string{model.VirtualModelFallbackAuth}}, model.VirtualModelStep{Model: "b"}),
+			err:   nil,
+			want:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newRoutingTestContext()
+			if tt.written {
+				c.Writer.WriteHeaderNow()
+			}
+			if got := shouldFallbackVirtualModel(c, tt.route, tt.err, tt.retryable); got != tt.want {
+				t.Errorf("shouldFallbackVirtualModel() = %v, 期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestShouldFallbackVirtualModelStepExpired 测试本步骤超过耗时上限后不再在本步骤内重试，直接回退
+func TestShouldFallbackVirtualModelStepExpired(t *testing.T) {
+	route := newTestVirtualModelRoute(
+		model.VirtualModelStep{Model: "a", FallbackOn: []string{model.VirtualModelFallbackAuth}, Timeout: 1},
+		model.VirtualModelStep{Model: "b", Timeout: 1},
+	)
+	serverError := newTestAPIError(http.StatusInternalServerError, "internal", nil)
+	c := newRoutingTestContext()
+
+	if shouldFallbackVirtualModel(c, route, serverError, true) {
+		t.Fatal("未超时且可重试时不应回退")
+	}
+	time.Sleep(1100 * time.Millisecond)
+	if !route.StepExpired() || !shouldFallbackVirtualModel(c, route, serverError, true) {
+		t.Fatal("超过耗时上限后应回退，即使错误可重试且不在回退条件中")
+	}
+
+	advanceVirtualModel(c, route, serverError)
+	if route.Step().Model != "b" || route.StepExpired() {
+		t.Errorf("回退后步骤 = %s, 已超时 = %v, 期望进入 b 且重新计时", route.Step().Model, route.StepExpired())
+	}
+	if skip := c.GetIntSlice("skip_channel_ids"); !slices.Equal(skip, []int{9}) {
+		t.Errorf("回退后跳过的渠道 = %v, 期望恢复为请求开始时的 [9]", skip)
+	}
+}
+
+// TestSetProviderWithFallback 测试选择渠道时当前步骤没有可用渠道则回退到下一个步骤
+func TestSetProviderWithFallback(t *testing.T) {
+	rateLimited := newTestAPIError(http.StatusTooManyRequests, "rate limited", nil)
+
+	tests := []struct {
+		name      string
+		steps     []model.VirtualModelStep
+		available []string
+		err       *types.OpenAIErrorWithStatusCode
+		wantTried []string
+		wantStep  int
+		wantErr   bool
+	}{
+		{
+			name:      "第一步有可用渠道",
+			steps:     []model.VirtualModelStep{{Model: "a"}, {Model: "b"}},
+			available: []string{"a", "b"},
+			wantTried: []string{"a"},
+			wantStep:  1,
+		},
+		{
+			name:      "没有可用渠道时回退到下一步",
+			steps:     []model.VirtualModelStep{{Model: "a"}, {Model: "b"}, {Model: "c"}},
+			available: []string{"c"},
+			wantTried: []string{"a", "b", "c"},
+			wantStep:  3,
+		},
+		{
+			name:      "所有步骤都没有可用渠道时返回错误",
+			steps:     []model.VirtualModelStep{{Model: "a"}, {Model: "b"}},
+			wantTried: []string{"a", "b"},
+			wantStep:  2,
+			wantErr:   true,
+		},
+		{
+			name:      "上次失败的错误不满足回退条件时不回退",
+			steps:     []model.VirtualModelStep{{Model: "a", FallbackOn: []string{model.VirtualModelFallbackAuth}}, {Model: "b"}},
+			available: []string{"b"},
+			err:       rateLimited,
+			wantTried: []string{"a"},
+			wantStep:  1,
+			wantErr:   true,
+		},
+		{
+			name: "刚进入的步骤没有可用渠道时总是继续回退",
+			steps: []model.VirtualModelStep{
+				{Model: "a", FallbackOn: []string{model.VirtualModelFallbackRateLimit}},
+				{Model: "b", FallbackOn: []string{model.VirtualModelFallbackAuth}},
+				{Model: "c"},
+			},
+			available: []string{"c"},
+			err:       rateLimited,
+			wantTried: []string{"a", "b", "c"},
+			wantStep:  3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newRoutingTestContext()
+			route := newTestVirtualModelRoute(tt.steps...)
+			c.Set(config.GinVirtualModelKey, route)
+			relay := &testRoutingRelay{relayBase: relayBase{c: c, originalModel: "smart"}, available: make(map[string]bool)}
+			for _, name := range tt.available {
+				relay.available[name] = true
+			}
+
+			err := setProviderWithFallback(relay, route, tt.err)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("setProviderWithFallback() error = %v, 期望出错 %v", err, tt.wantErr)
+			}
+			if !slices.Equal(relay.tried, tt.wantTried) {
+				t.Errorf("依次尝试的模型 = %v, 期望 %v", relay.tried, tt.wantTried)
+			}
+			if route.StepNumber() != tt.wantStep {
+				t.Errorf("当前步骤 = %d, 期望 %d", route.StepNumber(), tt.wantStep)
+			}
+		})
+	}
+
+	t.Run("未请求虚拟模型时不回退", func(t *testing.T) {
+		relay := &testRoutingRelay{relayBase: relayBase{c: newRoutingTestContext(), originalModel: "a"}}
+		if err := setProviderWithFallback(relay, nil, nil); err == nil || !slices.Equal(relay.tried, []string{"a"}) {
+			t.Errorf("setProviderWithFallback() = %v, 尝试 %v, 期望只尝试 a 并返回错误", err, relay.tried)
+		}
+	})
+}
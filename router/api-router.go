@@ -166,6 +166,16 @@ func SetApiRouter(router *gin.Engine) {
 			modelInfoRoute.DELETE("/:id", controller.DeleteModelInfo)
 		}
 
+		virtualModelRoute := apiRouter.Group("/virtual_model")
+		virtualModelRoute.Use(middleware.AdminAuth())
+		{
+			virtualModelRoute.GET("/", controller.GetVirtualModels)
+			virtualModelRoute.GET("/:id", controller.GetVirtualModel)
+			virtualModelRoute.POST("/", controller.AddVirtualModel)
+			virtualModelRoute.PUT("/", controller.UpdateVirtualModel)
+			virtualModelRoute.DELETE("/:id", controller.DeleteVirtualModel)
+		}
+
 		userGroup := apiRouter.Group("/user_group")
 		userGroup.Use(middleware.AdminAuth())
 		{
@@ -188,12 +198,19 @@ func SetApiRouter(router *gin.Engine) {
 			channelRoute.GET("/test/:id", controller.TestChannel)
 			channelRoute.GET("/update_balance", controller.UpdateAllChannelsBalance)
 			channelRoute.GET("/update_balance/:id", controller.UpdateChannelBalance)
+			channelRoute.GET("/:id/balance_history", controller.GetChannelBalanceHistory)
 			channelRoute.POST("/", controller.AddChannel)
 			channelRoute.PUT("/", controller.UpdateChannel)
 			channelRoute.PUT("/batch/azure_api", controller.BatchUpdateChannelsAzureApi)
 			channelRoute.PUT("/batch/del_model", controller.BatchDelModelChannels)
 			channelRoute.PUT("/batch/add_model", controller.BatchAddModelToChannels)
 			channelRoute.PUT("/batch/add_user_group", controller.BatchAddUserGroupToChannels)
+			channelRoute.GET("/:id/keys", controller.GetChannelKeys)
+			channelRoute.GET("/:id/keys/export", controller.ExportChannelKeys)
+			channelRoute.POST("/:id/keys", controller.ImportChannelKeys)
+			channelRoute.PUT("/:id/keys/:key_id", controller.UpdateChannelKey)
+			channelRoute.DELETE("/:id/keys/disabled", controller.DeleteDisabledChannelKeys)
+			channelRoute.DELETE("/:id/keys/:key_id", controller.DeleteChannelKey)
 		}
 
 		// GeminiCli OAuth routes (no auth required for callback)
@@ -333,6 +350,14 @@ func SetApiRouter(router *gin.Engine) {
 		taskRoute := apiRouter.Group("/task")
 		taskRoute.GET("/self", middleware.UserAuth(), controller.GetUserAllTask)
 		taskRoute.GET("/", middleware.AdminAuth(), controller.GetAllTask)
+
+		webhookRoute := apiRouter.Group("/webhook")
+		webhookRoute.Use(middleware.AdminAuth())
+		{
+			webhookRoute.GET("/delivery", controller.GetWebhookDeliveries)
+			webhookRoute.GET("/delivery/:id", controller.GetWebhookDelivery)
+			webhookRoute.POST("/delivery/:id/redeliver", controller.RedeliverWebhook)
+		}
 	}
 
 	sseRouter := router.Group("/api/sse")
@@ -3,10 +3,14 @@ package router
 import (
 	"done-hub/middleware"
 	"done-hub/relay"
+	"done-hub/relay/files"
 	"done-hub/relay/midjourney"
+	"done-hub/relay/responses"
 	"done-hub/relay/task"
+	"done-hub/relay/task/batch"
 	"done-hub/relay/task/kling"
 	"done-hub/relay/task/suno"
+	"done-hub/relay/task/video"
 
 	"github.com/gin-gonic/gin"
 )
@@ -37,6 +41,17 @@ func setOpenAIRouter(router *gin.Engine) {
 		relayV1Router.POST("/chat/completions", relay.Relay)
 		relayV1Router.POST("/responses", relay.Relay)
 		relayV1Router.POST("/responses/compact", relay.Relay)
+		relayV1Router.GET("/responses/:id", responses.RetrieveResponse)
+		relayV1Router.DELETE("/responses/:id", responses.DeleteResponse)
+		relayV1Router.GET("/responses/:id/input_items", responses.ListInputItems)
+		relayV1Router.POST("/conversations", responses.CreateConversation)
+		relayV1Router.GET("/conversations/:id", responses.RetrieveConversation)
+		relayV1Router.POST("/conversations/:id", responses.UpdateConversation)
+		relayV1Router.DELETE("/conversations/:id", responses.DeleteConversation)
+		relayV1Router.POST("/conversations/:id/items", responses.CreateConversationItems)
+		relayV1Router.GET("/conversations/:id/items", responses.ListConversationItems)
+		relayV1Router.GET("/conversations/:id/items/:item_id", responses.RetrieveConversationItem)
+		relayV1Router.DELETE("/conversations/:id/items/:item_id", responses.DeleteConversationItem)
 		// relayV1Router.POST("/edits", controller.Relay)
 		relayV1Router.POST("/images/generations", relay.Relay)
 		relayV1Router.POST("/images/edits", relay.Relay)
@@ -49,17 +64,27 @@ func setOpenAIRouter(router *gin.Engine) {
 		relayV1Router.POST("/moderations", relay.Relay)
 		relayV1Router.POST("/rerank", relay.RelayRerank)
 		relayV1Router.GET("/realtime", relay.ChatRealtime)
+		relayV1Router.POST("/batches", task.RelayOpenAIBatchCreate)
+		relayV1Router.GET("/batches", batch.ListOpenAIBatches)
+		relayV1Router.GET("/batches/:id", batch.RetrieveOpenAIBatch)
+		relayV1Router.POST("/batches/:id/cancel", batch.CancelOpenAIBatch)
+		relayV1Router.POST("/videos", task.RelayTaskSubmit)
+		relayV1Router.GET("/videos", video.ListVideos)
+		relayV1Router.GET("/videos/:id", video.RetrieveVideo)
+		relayV1Router.GET("/videos/:id/content", video.GetVideoContent)
+		relayV1Router.POST("/files", files.UploadFile)
+		relayV1Router.GET("/files", files.ListFiles)
+		relayV1Router.GET("/files/:id", files.RetrieveFile)
+		relayV1Router.GET("/files/:id/content", files.GetFileContent)
+		relayV1Router.DELETE("/files/:id", files.DeleteFile)
 
 		relayV1Router.Use(middleware.SpecifiedChannel())
 		{
-			relayV1Router.Any("/files", relay.RelayOnly)
-			relayV1Router.Any("/files/*any", relay.RelayOnly)
 			relayV1Router.Any("/fine_tuning/*any", relay.RelayOnly)
 			relayV1Router.Any("/assistants", relay.RelayOnly)
 			relayV1Router.Any("/assistants/*any", relay.RelayOnly)
 			relayV1Router.Any("/threads", relay.RelayOnly)
 			relayV1Router.Any("/threads/*any", relay.RelayOnly)
-			relayV1Router.Any("/batches/*any", relay.RelayOnly)
 			relayV1Router.Any("/vector_stores/*any", relay.RelayOnly)
 			relayV1Router.DELETE("/models/:model", relay.RelayOnly)
 		}
@@ -114,6 +139,12 @@ func setClaudeRouter(router *gin.Engine) {
 	relayV1Router.Use(middleware.APIEnabled("claude"), middleware.RelayCluadePanicRecover(), middleware.ClaudeAuth(), middleware.ContextUserId(), middleware.Distribute(), middleware.DynamicRedisRateLimiter())
 	{
 		relayV1Router.POST("/messages", relay.Relay)
+		relayV1Router.POST("/messages/count_tokens", relay.RelayClaudeCountTokens)
+		relayV1Router.POST("/messages/batches", task.RelayClaudeBatchCreate)
+		relayV1Router.GET("/messages/batches", batch.ListClaudeBatches)
+		relayV1Router.GET("/messages/batches/:id", batch.RetrieveClaudeBatch)
+		relayV1Router.POST("/messages/batches/:id/cancel", batch.CancelClaudeBatch)
+		relayV1Router.GET("/messages/batches/:id/results", batch.GetClaudeBatchResults)
 		relayV1Router.GET("/models", relay.ListClaudeModelsByToken)
 	}
 }
@@ -122,7 +153,7 @@ func setGeminiRouter(router *gin.Engine) {
 	relayGeminiRouter := router.Group("/gemini")
 	relayGeminiRouter.Use(middleware.APIEnabled("gemini"), middleware.RelayGeminiPanicRecover(), middleware.GeminiAuth(), middleware.ContextUserId(), middleware.Distribute(), middleware.DynamicRedisRateLimiter())
 	{
-		relayGeminiRouter.POST("/:version/models/:model", relay.Relay)
+		relayGeminiRouter.POST("/:version/models/:model", relay.RelayGemini)
 		relayGeminiRouter.GET("/:version/models", relay.ListGeminiModelsByToken)
 	}
 }
@@ -0,0 +1,43 @@
+package types
+
+import "encoding/json"
+
+// ConversationObject Conversations API 的会话对象
+type ConversationObject struct {
+	ID        string          `json:"id"`
+	Object    string          `json:"object"`
+	CreatedAt int64           `json:"created_at"`
+	Metadata  json.RawMessage `json:"metadata"`
+}
+
+// ConversationCreateRequest POST /v1/conversations 的请求
+type ConversationCreateRequest struct {
+	Items    []json.RawMessage `json:"items,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// ConversationUpdateRequest POST /v1/conversations/{id} 的请求
+type ConversationUpdateRequest struct {
+	Metadata map[string]string `json:"metadata"`
+}
+
+// ConversationItemsRequest POST /v1/conversations/{id}/items 的请求
+type ConversationItemsRequest struct {
+	Items []json.RawMessage `json:"items"`
+}
+
+// ConversationDeleteResponse DELETE /v1/conversations/{id} 的返回
+type ConversationDeleteResponse struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Deleted bool   `json:"deleted"`
+}
+
+// ConversationItemList 会话 item 列表
+type ConversationItemList struct {
+	Object  string            `json:"object"`
+	Data    []json.RawMessage `json:"data"`
+	FirstID *string           `json:"first_id"`
+	LastID  *string           `json:"last_id"`
+	HasMore bool              `json:"has_more"`
+}
@@ -966,3 +966,19 @@ func (r *OpenAIResponsesResponses) ToChat() *ChatCompletionResponse {
 
 	return resp
 }
+
+// ResponsesDeleteResponse DELETE /v1/responses/{id} 的返回
+type ResponsesDeleteResponse struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Deleted bool   `json:"deleted"`
+}
+
+// ResponsesInputItemList GET /v1/responses/{id}/input_items 的返回
+type ResponsesInputItemList struct {
+	Object  string            `json:"object"`
+	Data    []json.RawMessage `json:"data"`
+	FirstID *string           `json:"first_id"`
+	LastID  *string           `json:"last_id"`
+	HasMore bool              `json:"has_more"`
+}
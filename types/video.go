@@ -0,0 +1,86 @@
+package types
+
+import (
+	"strconv"
+	"strings"
+)
+
+// VideoRequest POST /v1/videos 请求体，兼容 JSON 与 multipart/form-data
+type VideoRequest struct {
+	Model   string `json:"model" form:"model" binding:"required"`
+	Prompt  string `json:"prompt" form:"prompt" binding:"required"`
+	Seconds string `json:"seconds,omitempty" form:"seconds"`
+	Size    string `json:"size,omitempty" form:"size"`
+	// InputReference 参考图，URL 或 data URL；multipart 上传的文件会转换为 data URL
+	InputReference string `json:"input_reference,omitempty" form:"-"`
+
+	// 以下为扩展参数，OpenAI 无对应字段
+	Mode           string `json:"mode,omitempty" form:"mode"`
+	NegativePrompt string `json:"negative_prompt,omitempty" form:"negative_prompt"`
+}
+
+// AspectRatio 由 size（宽x高）推算画面比例，未指定时返回空
+func (r *VideoRequest) AspectRatio() string {
+	width, height, ok := strings.Cut(r.Size, "x")
+	if !ok {
+		return ""
+	}
+	w, errW := strconv.Atoi(width)
+	h, errH := strconv.Atoi(height)
+	if errW != nil || errH != nil || w <= 0 || h <= 0 {
+		return ""
+	}
+
+	switch {
+	case w > h:
+		return "16:9"
+	case w < h:
+		return "9:16"
+	default:
+		return "1:1"
+	}
+}
+
+// Duration 返回视频时长（秒），未指定或无法解析时返回 0
+func (r *VideoRequest) Duration() int {
+	seconds, _ := strconv.Atoi(r.Seconds)
+	return seconds
+}
+
+type VideoObject struct {
+	ID          string      `json:"id"`
+	Object      string      `json:"object"`
+	Model       string      `json:"model"`
+	Status      string      `json:"status"`
+	Progress    int         `json:"progress"`
+	CreatedAt   int64       `json:"created_at"`
+	CompletedAt *int64      `json:"completed_at"`
+	ExpiresAt   *int64      `json:"expires_at"`
+	Size        string      `json:"size,omitempty"`
+	Seconds     string      `json:"seconds,omitempty"`
+	Error       *VideoError `json:"error"`
+}
+
+type VideoError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type VideoList struct {
+	Object  string         `json:"object"`
+	Data    []*VideoObject `json:"data"`
+	FirstID *string        `json:"first_id"`
+	LastID  *string        `json:"last_id"`
+	HasMore bool           `json:"has_more"`
+}
+
+// VideoTaskResult 渠道侧的视频任务状态，Status 取值同 model.TaskStatus
+type VideoTaskResult struct {
+	TaskID     string `json:"task_id"`
+	Action     string `json:"action,omitempty"`
+	Status     string `json:"status"`
+	Progress   int    `json:"progress"`
+	FailReason string `json:"fail_reason,omitempty"`
+	VideoURL   string `json:"video_url,omitempty"`
+	Data       any    `json:"data,omitempty"`
+}
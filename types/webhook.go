@@ -0,0 +1,28 @@
+package types
+
+import "encoding/json"
+
+const (
+	WebhookEventTaskSucceeded = "task.succeeded"
+	WebhookEventTaskFailed    = "task.failed"
+)
+
+// WebhookEvent 异步任务完成回调的请求体，各平台统一格式
+type WebhookEvent struct {
+	ID        string           `json:"id"`
+	Type      string           `json:"type"`
+	CreatedAt int64            `json:"created_at"`
+	Data      *WebhookTaskData `json:"data"`
+}
+
+type WebhookTaskData struct {
+	Platform   string          `json:"platform"`
+	TaskID     string          `json:"task_id"`
+	Action     string          `json:"action,omitempty"`
+	Status     string          `json:"status"`
+	FailReason string          `json:"fail_reason,omitempty"`
+	SubmitTime int64           `json:"submit_time"`
+	StartTime  int64           `json:"start_time"`
+	FinishTime int64           `json:"finish_time"`
+	Result     json.RawMessage `json:"result,omitempty"`
+}
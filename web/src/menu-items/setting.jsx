@@ -15,7 +15,8 @@ const icons = {
   IconUsers: () => <Icon width={20} icon="solar:users-group-rounded-bold-duotone"/>,
   IconModel: () => <Icon width={20} icon="mingcute:ai-fill"/>,
   IconTicket: () => <Icon width={20} icon="solar:ticket-bold-duotone"/>,
-  IconInfo: () => <Icon width={20} icon="solar:info-circle-bold-duotone"/>
+  IconInfo: () => <Icon width={20} icon="solar:info-circle-bold-duotone"/>,
+  IconRoute: () => <Icon width={20} icon="solar:routing-2-bold-duotone"/>
 }
 
 const Setting = {
@@ -75,6 +76,15 @@ const Setting = {
           breadcrumbs: false,
           isAdmin: true
         },
+        {
+          id: 'virtual_model',
+          title: '虚拟模型',
+          type: 'item',
+          url: '/panel/virtual_model',
+          icon: icons.IconRoute,
+          breadcrumbs: false,
+          isAdmin: true
+        },
         {
           id: 'model_info',
           title: '模型详情',
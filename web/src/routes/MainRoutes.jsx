@@ -24,6 +24,7 @@ const Task = Loadable(lazy(() => import('views/Task')));
 const UserGroup = Loadable(lazy(() => import('views/UserGroup')));
 const ModelOwnedby = Loadable(lazy(() => import('views/ModelOwnedby')));
 const ModelInfo = Loadable(lazy(() => import('views/ModelInfo')));
+const VirtualModel = Loadable(lazy(() => import('views/VirtualModel')));
 const Invoice = Loadable(lazy(() => import('views/Invoice')));
 const InvoiceDetail = Loadable(lazy(() => import('views/Invoice/detail')));
 const MultiUserStats = Loadable(lazy(() => import('views/MultiUserStats')));
@@ -138,6 +139,10 @@ const MainRoutes = {
       path: 'model_info',
       element: <ModelInfo />
     },
+    {
+      path: 'virtual_model',
+      element: <VirtualModel />
+    },
     {
       path: 'system_info',
       element: <SystemInfo />
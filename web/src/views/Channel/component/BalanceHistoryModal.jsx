@@ -0,0 +1,101 @@
+import PropTypes from 'prop-types';
+import { useCallback, useEffect, useState } from 'react';
+import { useTranslation } from 'react-i18next';
+
+import { Box, Button, Dialog, DialogActions, DialogContent, DialogTitle, MenuItem, Stack, TextField, Typography } from '@mui/material';
+import { useTheme } from '@mui/material/styles';
+import ReactApexChart from 'react-apexcharts';
+
+import { API } from 'utils/api';
+import { showError, timestamp2string } from 'utils/common';
+
+const DAY_OPTIONS = [7, 30, 90];
+
+// 渠道余额记录：余额走势、平均每日消耗与按此推算的预计用完时间
+export default function BalanceHistoryModal({ open, channel, onClose }) {
+  const { t } = useTranslation();
+  const theme = useTheme();
+  const [days, setDays] = useState(30);
+  const [trend, setTrend] = useState(null);
+
+  const loadHistory = useCallback(async () => {
+    if (!channel?.id) return;
+    try {
+      const res = await API.get(`/api/channel/${channel.id}/balance_history`, { params: { days } });
+      const { success, message, data } = res.data;
+      if (success) {
+        setTrend(data);
+      } else {
+        showError(message);
+      }
+    } catch (error) {
+      showError(error.message);
+    }
+  }, [channel?.id, days]);
+
+  useEffect(() => {
+    if (open) {
+      loadHistory();
+    }
+  }, [open, loadHistory]);
+
+  const history = trend?.history || [];
+  const series = [
+    {
+      name: t('channel_balance.balance'),
+      data: history.map((item) => [item.created_at * 1000, item.balance])
+    }
+  ];
+  const options = {
+    chart: { type: 'area', toolbar: { show: false }, zoom: { enabled: false } },
+    theme: { mode: theme.palette.mode },
+    dataLabels: { enabled: false },
+    stroke: { curve: 'stepline', width: 2 },
+    xaxis: { type: 'datetime', labels: { datetimeUTC: false } },
+    yaxis: { labels: { formatter: (value) => value.toFixed(2) } },
+    tooltip: { x: { format: 'yyyy-MM-dd HH:mm' } }
+  };
+
+  return (
+    <Dialog open={open} onClose={onClose} fullWidth maxWidth="md">
+      <DialogTitle>{t('channel_balance.title', { name: channel?.name || '' })}</DialogTitle>
+      <DialogContent dividers>
+        <Stack spacing={2}>
+          <Stack direction={{ xs: 'column', sm: 'row' }} spacing={3} alignItems={{ sm: 'center' }}>
+            <TextField select size="small" label={t('channel_balance.range')} value={days} onChange={(e) => setDays(e.target.value)}>
+              {DAY_OPTIONS.map((option) => (
+                <MenuItem key={option} value={option}>
+                  {t('channel_balance.days', { days: option })}
+                </MenuItem>
+              ))}
+            </TextField>
+            <Typography variant="body2">
+              {t('channel_balance.burnRate')}: {trend?.burn_rate ? trend.burn_rate.toFixed(4) : '-'}
+            </Typography>
+            <Typography variant="body2">
+              {t('channel_balance.runOut')}: {trend?.run_out_at ? timestamp2string(trend.run_out_at) : '-'}
+            </Typography>
+          </Stack>
+          {history.length > 0 ? (
+            <ReactApexChart options={options} series={series} type="area" height={320} />
+          ) : (
+            <Box sx={{ py: 6, textAlign: 'center' }}>
+              <Typography variant="body2" color="text.secondary">
+                {t('channel_balance.empty')}
+              </Typography>
+            </Box>
+          )}
+        </Stack>
+      </DialogContent>
+      <DialogActions>
+        <Button onClick={onClose}>{t('common.close')}</Button>
+      </DialogActions>
+    </Dialog>
+  );
+}
+
+BalanceHistoryModal.propTypes = {
+  open: PropTypes.bool,
+  channel: PropTypes.object,
+  onClose: PropTypes.func
+};
@@ -0,0 +1,54 @@
+import PropTypes from 'prop-types';
+import Label from 'ui-component/Label';
+import Tooltip from '@mui/material/Tooltip';
+import { calculateQuota } from 'utils/common';
+import { useTranslation } from 'react-i18next';
+
+const PERIODS = ['daily', 'weekly', 'monthly'];
+
+// 渠道花费预算的使用情况，只在渠道设置了预算时显示，显示各周期中最高的使用比例
+const BudgetLabel = ({ budget, usage }) => {
+  const { t } = useTranslation();
+  const periods = PERIODS.filter((period) => budget?.[period] > 0);
+  if (periods.length === 0) {
+    return null;
+  }
+
+  const quotaPerUnit = parseFloat(localStorage.getItem('quota_per_unit')) || 1;
+  const percents = periods.map((period) => ((usage?.[`${period}_spent`] || 0) / quotaPerUnit / budget[period]) * 100);
+  const maxPercent = Math.max(...percents);
+  const alertPercent = budget.alert_percent || 80;
+
+  let color = 'success';
+  if (usage?.paused) {
+    color = 'error';
+  } else if (maxPercent >= alertPercent) {
+    color = 'warning';
+  }
+
+  const title = (
+    <>
+      {periods.map((period, index) => (
+        <div key={period}>
+          {t(`channel_row.budget.${period}`)}: ${calculateQuota(usage?.[`${period}_spent`] || 0, 2)} / ${budget[period]} (
+          {percents[index].toFixed(0)}%)
+        </div>
+      ))}
+    </>
+  );
+
+  return (
+    <Tooltip title={title} placement="top">
+      <Label color={color} variant="soft">
+        {usage?.paused ? t('channel_row.budget.paused') : `${t('channel_row.budget.label')} ${maxPercent.toFixed(0)}%`}
+      </Label>
+    </Tooltip>
+  );
+};
+
+BudgetLabel.propTypes = {
+  budget: PropTypes.object,
+  usage: PropTypes.object
+};
+
+export default BudgetLabel;
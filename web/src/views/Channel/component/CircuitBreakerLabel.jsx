@@ -0,0 +1,43 @@
+import PropTypes from 'prop-types';
+import Label from 'ui-component/Label';
+import Tooltip from '@mui/material/Tooltip';
+import { timestamp2string } from 'utils/common';
+import { useTranslation } from 'react-i18next';
+
+// 渠道各模型的熔断器状态，只在存在打开或半开的熔断器时显示
+const CircuitBreakerLabel = ({ breakers }) => {
+  const { t } = useTranslation();
+  const tripped = (breakers || []).filter((breaker) => breaker.state !== 'closed');
+  if (tripped.length === 0) {
+    return null;
+  }
+
+  const hasOpen = tripped.some((breaker) => breaker.state === 'open');
+  const title = (
+    <>
+      {tripped.map((breaker) => (
+        <div key={breaker.model}>
+          {breaker.model}: {t(`channel_row.circuitBreaker.${breaker.state}`)}
+          {breaker.state === 'open' && breaker.open_until
+            ? ` (${t('channel_row.circuitBreaker.until')} ${timestamp2string(breaker.open_until)})`
+            : ''}
+          {` · ${t('channel_row.circuitBreaker.failures')} ${breaker.failures}/${breaker.requests}`}
+        </div>
+      ))}
+    </>
+  );
+
+  return (
+    <Tooltip title={title} placement="top">
+      <Label color={hasOpen ? 'error' : 'warning'} variant="soft">
+        {t(hasOpen ? 'channel_row.circuitBreaker.open' : 'channel_row.circuitBreaker.half_open')} {tripped.length}
+      </Label>
+    </Tooltip>
+  );
+};
+
+CircuitBreakerLabel.propTypes = {
+  breakers: PropTypes.array
+};
+
+export default CircuitBreakerLabel;
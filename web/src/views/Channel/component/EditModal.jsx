@@ -42,7 +42,7 @@ import CheckBoxOutlineBlankIcon from '@mui/icons-material/CheckBoxOutlineBlank';
 import CheckBoxIcon from '@mui/icons-material/CheckBox';
 import { useTranslation } from 'react-i18next';
 import useCustomizeT from 'hooks/useCustomizeT';
-import { PreCostType } from '../type/other';
+import { KeyModeType, PreCostType } from '../type/other';
 import MapInput from './MapInput';
 import ListInput from './ListInput';
 import { formatGroupLabel } from './batchHelpers';
@@ -59,6 +59,7 @@ const icon = <CheckBoxOutlineBlankIcon fontSize="small" />;
 const checkedIcon = <CheckBoxIcon fontSize="small" />;
 
 const filter = createFilterOptions();
+const CHANNEL_CAPABILITIES = ['vision', 'long_context', 'no_function_calling'];
 const getValidationSchema = (t) =>
   Yup.object().shape({
     is_edit: Yup.boolean(),
@@ -79,7 +80,10 @@ const getValidationSchema = (t) =>
     model_mapping: Yup.array(),
     model_headers: Yup.array(),
     header_override: Yup.array(),
-    custom_parameter: Yup.string().nullable()
+    custom_parameter: Yup.string().nullable(),
+    rate_limit: Yup.string().nullable(),
+    schedule: Yup.string().nullable(),
+    budget: Yup.string().nullable()
   });
 
 const EditModal = ({ open, channelId, onCancel, onOk, groupOptions, groupMap, isTag, modelOptions, prices, tags }) => {
@@ -847,6 +851,39 @@ const EditModal = ({ open, channelId, onCancel, onOk, groupOptions, groupMap, is
       }
     }
 
+    if (values.rate_limit) {
+      try {
+        values.rate_limit = JSON.parse(values.rate_limit);
+      } catch (error) {
+        showError('Error parsing rate_limit: ' + error.message);
+        return;
+      }
+    } else {
+      values.rate_limit = null;
+    }
+
+    if (values.schedule) {
+      try {
+        values.schedule = JSON.parse(values.schedule);
+      } catch (error) {
+        showError('Error parsing schedule: ' + error.message);
+        return;
+      }
+    } else {
+      values.schedule = null;
+    }
+
+    if (values.budget) {
+      try {
+        values.budget = JSON.parse(values.budget);
+      } catch (error) {
+        showError('Error parsing budget: ' + error.message);
+        return;
+      }
+    } else {
+      values.budget = null;
+    }
+
     if (values.disabled_stream) {
       values.disabled_stream = removeDuplicates(values.disabled_stream);
     }
@@ -870,6 +907,16 @@ const EditModal = ({ open, channelId, onCancel, onOk, groupOptions, groupMap, is
       values.cost_ratio = 0;
     }
 
+    // 余额检查间隔（分钟）与余额提醒阈值同样需要转回数字，非法值按 0 处理（不启用）
+    values.balance_check_interval = parseInt(values.balance_check_interval);
+    if (isNaN(values.balance_check_interval) || values.balance_check_interval <= 0) {
+      values.balance_check_interval = 0;
+    }
+    values.balance_threshold = parseFloat(values.balance_threshold);
+    if (isNaN(values.balance_threshold) || values.balance_threshold <= 0) {
+      values.balance_threshold = 0;
+    }
+
     let baseApiUrl = '/api/channel/';
 
     if (isTag) {
@@ -1003,6 +1050,12 @@ const EditModal = ({ open, channelId, onCancel, onOk, groupOptions, groupMap, is
 
         data.base_url = data.base_url ?? '';
         data.cost_ratio = data.cost_ratio ?? 0;
+        data.balance_check_interval = data.balance_check_interval ?? 0;
+        data.balance_threshold = data.balance_threshold ?? 0;
+        data.rate_limit = data.rate_limit ? JSON.stringify(data.rate_limit, null, 2) : '';
+        data.capabilities = data.capabilities ?? [];
+        data.schedule = data.schedule ? JSON.stringify(data.schedule, null, 2) : '';
+        data.budget = data.budget ? JSON.stringify(data.budget, null, 2) : '';
         data.is_edit = true;
         if (data.plugin === null) {
           data.plugin = {};
@@ -1544,6 +1597,30 @@ const EditModal = ({ open, channelId, onCancel, onOk, groupOptions, groupMap, is
                     </FormControl>
                   )}
 
+                  {!isTag && (
+                    <FormControl fullWidth sx={{ ...theme.typography.otherInput }}>
+                      <InputLabel shrink htmlFor="channel-key_mode-label">
+                        {customizeT(inputLabel.key_mode)}
+                      </InputLabel>
+                      <Select
+                        id="channel-key_mode-label"
+                        displayEmpty
+                        input={<OutlinedInput notched label={customizeT(inputLabel.key_mode)} />}
+                        value={values.key_mode || ''}
+                        name="key_mode"
+                        onBlur={handleBlur}
+                        onChange={handleChange}
+                      >
+                        {KeyModeType.map((option) => (
+                          <MenuItem key={option.value} value={option.value}>
+                            {customizeT(option.label)}
+                          </MenuItem>
+                        ))}
+                      </Select>
+                      <FormHelperText id="helper-tex-channel-key_mode-label">{customizeT(inputPrompt.key_mode)}</FormHelperText>
+                    </FormControl>
+                  )}
+
                   {/* GeminiCli/Antigravity OAuth 授权按钮 */}
                   {(values.type === 57 || values.type === 60) && !batchAdd && (
                     <Box sx={{ mt: 2, mb: 2 }}>
@@ -1981,6 +2058,25 @@ const EditModal = ({ open, channelId, onCancel, onOk, groupOptions, groupMap, is
                       />
                     </FormControl>
                   )}
+                  {inputPrompt.capabilities && (
+                    <FormControl fullWidth sx={{ ...theme.typography.otherInput }}>
+                      <Autocomplete
+                        multiple
+                        freeSolo
+                        id="channel-capabilities-label"
+                        options={CHANNEL_CAPABILITIES}
+                        value={values.capabilities || []}
+                        getOptionLabel={(option) =>
+                          CHANNEL_CAPABILITIES.includes(option) ? `${option} (${t(`channel_edit.capabilityOptions.${option}`)})` : option
+                        }
+                        onChange={(e, value) => {
+                          setFieldValue('capabilities', removeDuplicates(value));
+                        }}
+                        renderInput={(params) => <TextField {...params} label={customizeT(inputLabel.capabilities)} />}
+                      />
+                      <FormHelperText id="helper-tex-channel-capabilities-label">{customizeT(inputPrompt.capabilities)}</FormHelperText>
+                    </FormControl>
+                  )}
                 </CollapsibleSection>
 
                 <CollapsibleSection title={t('channel_edit.sectionBilling')}>
@@ -2108,6 +2204,225 @@ const EditModal = ({ open, channelId, onCancel, onOk, groupOptions, groupMap, is
                       )}
                     </FormControl>
                   )}
+                  {inputPrompt.balance_check_interval && (
+                    <FormControl
+                      fullWidth
+                      error={Boolean(touched.balance_check_interval && errors.balance_check_interval)}
+                      sx={{ ...theme.typography.otherInput }}
+                    >
+                      <InputLabel htmlFor="channel-balance_check_interval-label">
+                        {customizeT(inputLabel.balance_check_interval)}
+                      </InputLabel>
+                      <OutlinedInput
+                        id="channel-balance_check_interval-label"
+                        label={customizeT(inputLabel.balance_check_interval)}
+                        type="number"
+                        value={values.balance_check_interval}
+                        name="balance_check_interval"
+                        onBlur={handleBlur}
+                        onChange={handleChange}
+                        inputProps={{ step: 1, min: 0 }}
+                        aria-describedby="helper-text-channel-balance_check_interval-label"
+                      />
+                      {touched.balance_check_interval && errors.balance_check_interval ? (
+                        <FormHelperText error id="helper-tex-channel-balance_check_interval-label">
+                          {errors.balance_check_interval}
+                        </FormHelperText>
+                      ) : (
+                        <FormHelperText id="helper-tex-channel-balance_check_interval-label">
+                          {customizeT(inputPrompt.balance_check_interval)}
+                        </FormHelperText>
+                      )}
+                    </FormControl>
+                  )}
+                  {inputPrompt.balance_threshold && (
+                    <FormControl
+                      fullWidth
+                      error={Boolean(touched.balance_threshold && errors.balance_threshold)}
+                      sx={{ ...theme.typography.otherInput }}
+                    >
+                      <InputLabel htmlFor="channel-balance_threshold-label">{customizeT(inputLabel.balance_threshold)}</InputLabel>
+                      <OutlinedInput
+                        id="channel-balance_threshold-label"
+                        label={customizeT(inputLabel.balance_threshold)}
+                        type="number"
+                        value={values.balance_threshold}
+                        name="balance_threshold"
+                        onBlur={handleBlur}
+                        onChange={handleChange}
+                        inputProps={{ step: 0.1, min: 0 }}
+                        aria-describedby="helper-text-channel-balance_threshold-label"
+                      />
+                      {touched.balance_threshold && errors.balance_threshold ? (
+                        <FormHelperText error id="helper-tex-channel-balance_threshold-label">
+                          {errors.balance_threshold}
+                        </FormHelperText>
+                      ) : (
+                        <FormHelperText id="helper-tex-channel-balance_threshold-label">
+                          {customizeT(inputPrompt.balance_threshold)}
+                        </FormHelperText>
+                      )}
+                    </FormControl>
+                  )}
+                  {inputPrompt.rate_limit && (
+                    <FormControl fullWidth error={Boolean(touched.rate_limit && errors.rate_limit)} sx={{ ...theme.typography.otherInput }}>
+                      <InputLabel shrink htmlFor="channel-rate_limit-label">
+                        {customizeT(inputLabel.rate_limit)}
+                      </InputLabel>
+                      <Box
+                        sx={{
+                          border: '1px solid',
+                          borderColor: touched.rate_limit && errors.rate_limit ? 'error.main' : 'divider',
+                          borderRadius: 1,
+                          overflow: 'hidden',
+                          marginTop: 2,
+                          resize: 'vertical',
+                          height: '150px',
+                          minHeight: '100px',
+                          '&:hover': {
+                            borderColor: 'primary.main'
+                          },
+                          '&:focus-within': {
+                            borderColor: 'primary.main',
+                            borderWidth: 2
+                          }
+                        }}
+                      >
+                        <Editor
+                          height="100%"
+                          language="json"
+                          theme={theme.palette.mode === 'dark' ? 'vs-dark' : 'light'}
+                          value={values.rate_limit}
+                          options={{
+                            minimap: { enabled: false },
+                            scrollBeyondLastLine: false,
+                            automaticLayout: true,
+                            fontSize: 14,
+                            lineNumbers: 'on',
+                            folding: true,
+                            formatOnPaste: true,
+                            formatOnType: true
+                          }}
+                          onChange={(value) => {
+                            setFieldValue('rate_limit', value);
+                          }}
+                        />
+                      </Box>
+                      {touched.rate_limit && errors.rate_limit ? (
+                        <FormHelperText error id="helper-tex-channel-rate_limit-label">
+                          {errors.rate_limit}
+                        </FormHelperText>
+                      ) : (
+                        <FormHelperText id="helper-tex-channel-rate_limit-label">{customizeT(inputPrompt.rate_limit)}</FormHelperText>
+                      )}
+                    </FormControl>
+                  )}
+                  {inputPrompt.schedule && (
+                    <FormControl fullWidth error={Boolean(touched.schedule && errors.schedule)} sx={{ ...theme.typography.otherInput }}>
+                      <InputLabel shrink htmlFor="channel-schedule-label">
+                        {customizeT(inputLabel.schedule)}
+                      </InputLabel>
+                      <Box
+                        sx={{
+                          border: '1px solid',
+                          borderColor: touched.schedule && errors.schedule ? 'error.main' : 'divider',
+                          borderRadius: 1,
+                          overflow: 'hidden',
+                          marginTop: 2,
+                          resize: 'vertical',
+                          height: '150px',
+                          minHeight: '100px',
+                          '&:hover': {
+                            borderColor: 'primary.main'
+                          },
+                          '&:focus-within': {
+                            borderColor: 'primary.main',
+                            borderWidth: 2
+                          }
+                        }}
+                      >
+                        <Editor
+                          height="100%"
+                          language="json"
+                          theme={theme.palette.mode === 'dark' ? 'vs-dark' : 'light'}
+                          value={values.schedule}
+                          options={{
+                            minimap: { enabled: false },
+                            scrollBeyondLastLine: false,
+                            automaticLayout: true,
+                            fontSize: 14,
+                            lineNumbers: 'on',
+                            folding: true,
+                            formatOnPaste: true,
+                            formatOnType: true
+                          }}
+                          onChange={(value) => {
+                            setFieldValue('schedule', value);
+                          }}
+                        />
+                      </Box>
+                      {touched.schedule && errors.schedule ? (
+                        <FormHelperText error id="helper-tex-channel-schedule-label">
+                          {errors.schedule}
+                        </FormHelperText>
+                      ) : (
+                        <FormHelperText id="helper-tex-channel-schedule-label">{customizeT(inputPrompt.schedule)}</FormHelperText>
+                      )}
+                    </FormControl>
+                  )}
+                  {inputPrompt.budget && (
+                    <FormControl fullWidth error={Boolean(touched.budget && errors.budget)} sx={{ ...theme.typography.otherInput }}>
+                      <InputLabel shrink htmlFor="channel-budget-label">
+                        {customizeT(inputLabel.budget)}
+                      </InputLabel>
+                      <Box
+                        sx={{
+                          border: '1px solid',
+                          borderColor: touched.budget && errors.budget ? 'error.main' : 'divider',
+                          borderRadius: 1,
+                          overflow: 'hidden',
+                          marginTop: 2,
+                          resize: 'vertical',
+                          height: '150px',
+                          minHeight: '100px',
+                          '&:hover': {
+                            borderColor: 'primary.main'
+                          },
+                          '&:focus-within': {
+                            borderColor: 'primary.main',
+                            borderWidth: 2
+                          }
+                        }}
+                      >
+                        <Editor
+                          height="100%"
+                          language="json"
+                          theme={theme.palette.mode === 'dark' ? 'vs-dark' : 'light'}
+                          value={values.budget}
+                          options={{
+                            minimap: { enabled: false },
+                            scrollBeyondLastLine: false,
+                            automaticLayout: true,
+                            fontSize: 14,
+                            lineNumbers: 'on',
+                            folding: true,
+                            formatOnPaste: true,
+                            formatOnType: true
+                          }}
+                          onChange={(value) => {
+                            setFieldValue('budget', value);
+                          }}
+                        />
+                      </Box>
+                      {touched.budget && errors.budget ? (
+                        <FormHelperText error id="helper-tex-channel-budget-label">
+                          {errors.budget}
+                        </FormHelperText>
+                      ) : (
+                        <FormHelperText id="helper-tex-channel-budget-label">{customizeT(inputPrompt.budget)}</FormHelperText>
+                      )}
+                    </FormControl>
+                  )}
                 </CollapsibleSection>
 
                 {pluginList[values.type] &&
@@ -0,0 +1,260 @@
+import PropTypes from 'prop-types';
+import { useCallback, useEffect, useState } from 'react';
+import { useTranslation } from 'react-i18next';
+
+import {
+  Box,
+  Button,
+  Dialog,
+  DialogActions,
+  DialogContent,
+  DialogTitle,
+  IconButton,
+  Stack,
+  Switch,
+  Table,
+  TableBody,
+  TableCell,
+  TableContainer,
+  TableHead,
+  TableRow,
+  TextField,
+  Tooltip,
+  Typography
+} from '@mui/material';
+import { Icon } from '@iconify/react';
+
+import Label from 'ui-component/Label';
+import { API } from 'utils/api';
+import { renderQuota, showError, showSuccess, timestamp2string } from 'utils/common';
+
+function keyStatusLabel(t, key) {
+  if (key.status === 1) {
+    if (key.cooldown_until && key.cooldown_until * 1000 > Date.now()) {
+      return (
+        <Tooltip title={t('channel_key.cooldownUntil', { time: timestamp2string(key.cooldown_until) })}>
+          <span>
+            <Label color="warning">{t('channel_key.cooldown')}</Label>
+          </span>
+        </Tooltip>
+      );
+    }
+    return <Label color="success">{t('channel_index.enabled')}</Label>;
+  }
+
+  const label = <Label color="error">{key.status === 3 ? t('channel_row.auto') : t('channel_row.manual')}</Label>;
+  if (!key.status_reason) {
+    return label;
+  }
+  return (
+    <Tooltip title={key.status_reason}>
+      <span>{label}</span>
+    </Tooltip>
+  );
+}
+
+export default function KeyPoolModal({ open, channel, onClose }) {
+  const { t } = useTranslation();
+  const [keys, setKeys] = useState([]);
+  const [importText, setImportText] = useState('');
+  const [remark, setRemark] = useState('');
+  const [importing, setImporting] = useState(false);
+
+  const loadKeys = useCallback(async () => {
+    if (!channel?.id) return;
+    try {
+      const res = await API.get(`/api/channel/${channel.id}/keys`);
+      const { success, message, data } = res.data;
+      if (success) {
+        setKeys(data || []);
+      } else {
+        showError(message);
+      }
+    } catch (error) {
+      showError(error.message);
+    }
+  }, [channel?.id]);
+
+  useEffect(() => {
+    if (open) {
+      setImportText('');
+      setRemark('');
+      loadKeys();
+    }
+  }, [open, loadKeys]);
+
+  const handleImport = async () => {
+    if (!importText.trim()) return;
+    setImporting(true);
+    try {
+      const res = await API.post(`/api/channel/${channel.id}/keys`, { keys: importText, remark });
+      const { success, message, data } = res.data;
+      if (success) {
+        showSuccess(t('channel_key.importSuccess', { count: data }));
+        setImportText('');
+        loadKeys();
+      } else {
+        showError(message);
+      }
+    } catch (error) {
+      showError(error.message);
+    }
+    setImporting(false);
+  };
+
+  const handleExport = async (enabledOnly) => {
+    try {
+      const res = await API.get(`/api/channel/${channel.id}/keys/export`, {
+        params: { enabled: enabledOnly },
+        responseType: 'blob'
+      });
+      const url = window.URL.createObjectURL(new Blob([res.data]));
+      const link = document.createElement('a');
+      link.href = url;
+      link.setAttribute('download', `channel_${channel.id}_keys.txt`);
+      document.body.appendChild(link);
+      link.click();
+      link.remove();
+      window.URL.revokeObjectURL(url);
+    } catch (error) {
+      showError(error.message);
+    }
+  };
+
+  const handleToggle = async (key) => {
+    try {
+      const res = await API.put(`/api/channel/${channel.id}/keys/${key.id}`, {
+        status: key.status === 1 ? 2 : 1,
+        remark: key.remark
+      });
+      const { success, message } = res.data;
+      if (success) {
+        loadKeys();
+      } else {
+        showError(message);
+      }
+    } catch (error) {
+      showError(error.message);
+    }
+  };
+
+  const handleDelete = async (key) => {
+    try {
+      const res = await API.delete(`/api/channel/${channel.id}/keys/${key.id}`);
+      const { success, message } = res.data;
+      if (success) {
+        loadKeys();
+      } else {
+        showError(message);
+      }
+    } catch (error) {
+      showError(error.message);
+    }
+  };
+
+  const handleDeleteDisabled = async () => {
+    try {
+      const res = await API.delete(`/api/channel/${channel.id}/keys/disabled`);
+      const { success, message, data } = res.data;
+      if (success) {
+        showSuccess(t('channel_key.deleteDisabledSuccess', { count: data }));
+        loadKeys();
+      } else {
+        showError(message);
+      }
+    } catch (error) {
+      showError(error.message);
+    }
+  };
+
+  return (
+    <Dialog open={open} onClose={onClose} fullWidth maxWidth="lg">
+      <DialogTitle>{t('channel_key.title', { name: channel?.name || '' })}</DialogTitle>
+      <DialogContent dividers>
+        <Stack spacing={2}>
+          <Typography variant="body2" color="text.secondary">
+            {t('channel_key.tip')}
+          </Typography>
+          <TextField
+            multiline
+            minRows={3}
+            maxRows={10}
+            fullWidth
+            label={t('channel_key.importKeys')}
+            placeholder={t('channel_key.importPlaceholder')}
+            value={importText}
+            onChange={(e) => setImportText(e.target.value)}
+          />
+          <Stack direction={{ xs: 'column', sm: 'row' }} spacing={1} alignItems={{ sm: 'center' }}>
+            <TextField size="small" label={t('channel_key.remark')} value={remark} onChange={(e) => setRemark(e.target.value)} />
+            <Button variant="contained" onClick={handleImport} disabled={importing || !importText.trim()}>
+              {t('channel_key.import')}
+            </Button>
+            <Box sx={{ flexGrow: 1 }} />
+            <Button variant="outlined" onClick={() => handleExport(false)}>
+              {t('channel_key.exportAll')}
+            </Button>
+            <Button variant="outlined" onClick={() => handleExport(true)}>
+              {t('channel_key.exportEnabled')}
+            </Button>
+            <Button variant="outlined" color="error" onClick={handleDeleteDisabled}>
+              {t('channel_key.deleteDisabled')}
+            </Button>
+          </Stack>
+
+          <TableContainer>
+            <Table size="small">
+              <TableHead>
+                <TableRow>
+                  <TableCell>ID</TableCell>
+                  <TableCell>{t('channel_key.key')}</TableCell>
+                  <TableCell>{t('channel_key.remark')}</TableCell>
+                  <TableCell>{t('channel_index.status')}</TableCell>
+                  <TableCell>{t('channel_key.usedQuota')}</TableCell>
+                  <TableCell>{t('channel_key.requestCount')}</TableCell>
+                  <TableCell>{t('channel_key.createdTime')}</TableCell>
+                  <TableCell align="right">{t('channel_index.actions')}</TableCell>
+                </TableRow>
+              </TableHead>
+              <TableBody>
+                {keys.length === 0 && (
+                  <TableRow>
+                    <TableCell colSpan={8} align="center">
+                      {t('channel_key.empty')}
+                    </TableCell>
+                  </TableRow>
+                )}
+                {keys.map((key) => (
+                  <TableRow key={key.id}>
+                    <TableCell>{key.id}</TableCell>
+                    <TableCell sx={{ fontFamily: 'monospace' }}>{key.key}</TableCell>
+                    <TableCell>{key.remark}</TableCell>
+                    <TableCell>{keyStatusLabel(t, key)}</TableCell>
+                    <TableCell>{renderQuota(key.used_quota)}</TableCell>
+                    <TableCell>{key.request_count}</TableCell>
+                    <TableCell>{timestamp2string(key.created_time)}</TableCell>
+                    <TableCell align="right">
+                      <Switch size="small" checked={key.status === 1} onChange={() => handleToggle(key)} />
+                      <IconButton size="small" color="error" onClick={() => handleDelete(key)}>
+                        <Icon icon="solar:trash-bin-trash-bold-duotone" width={18} />
+                      </IconButton>
+                    </TableCell>
+                  </TableRow>
+                ))}
+              </TableBody>
+            </Table>
+          </TableContainer>
+        </Stack>
+      </DialogContent>
+      <DialogActions>
+        <Button onClick={onClose}>{t('common.close')}</Button>
+      </DialogActions>
+    </Dialog>
+  );
+}
+
+KeyPoolModal.propTypes = {
+  open: PropTypes.bool,
+  channel: PropTypes.object,
+  onClose: PropTypes.func
+};
@@ -0,0 +1,44 @@
+import PropTypes from 'prop-types';
+import Label from 'ui-component/Label';
+import Tooltip from '@mui/material/Tooltip';
+import { timestamp2string } from 'utils/common';
+import { useTranslation } from 'react-i18next';
+
+// 只保留 MM-DD HH:mm，标签里放得下
+const shortTime = (timestamp) => timestamp2string(timestamp).slice(5, 16);
+
+// 渠道可用时间状态，只在渠道设置了可用时间时显示；不在可用时间内时显示下一个可用窗口
+const ScheduleLabel = ({ status }) => {
+  const { t } = useTranslation();
+  if (!status) {
+    return null;
+  }
+
+  const end = status.next_end ? timestamp2string(status.next_end) : '';
+  let title;
+  if (status.active) {
+    title = end ? `${t('channel_row.schedule.until')} ${end}` : t('channel_row.schedule.noEnd');
+  } else if (status.next_start) {
+    title = `${t('channel_row.schedule.nextWindow')} ${timestamp2string(status.next_start)} ~ ${end}`;
+  } else {
+    title = t('channel_row.schedule.noUpcoming');
+  }
+
+  return (
+    <Tooltip title={title} placement="top">
+      <Label color={status.active ? 'success' : 'warning'} variant="soft">
+        {status.active
+          ? t('channel_row.schedule.active')
+          : status.next_start
+            ? `${t('channel_row.schedule.next')} ${shortTime(status.next_start)}`
+            : t('channel_row.schedule.inactive')}
+      </Label>
+    </Tooltip>
+  );
+};
+
+ScheduleLabel.propTypes = {
+  status: PropTypes.object
+};
+
+export default ScheduleLabel;
@@ -45,6 +45,9 @@ import {
 import Label from 'ui-component/Label';
 // import TableSwitch from 'ui-component/Switch';
 import ResponseTimeLabel from './ResponseTimeLabel';
+import CircuitBreakerLabel from './CircuitBreakerLabel';
+import ScheduleLabel from './ScheduleLabel';
+import BudgetLabel from './BudgetLabel';
 import GroupLabel from './GroupLabel';
 
 import { alpha, styled } from '@mui/material/styles';
@@ -52,6 +55,8 @@ import { Icon } from '@iconify/react';
 import KeyboardArrowDownIcon from '@mui/icons-material/KeyboardArrowDown';
 import KeyboardArrowUpIcon from '@mui/icons-material/KeyboardArrowUp';
 import { ChannelCheck } from './ChannelCheck';
+import KeyPoolModal from './KeyPoolModal';
+import BalanceHistoryModal from './BalanceHistoryModal';
 import { getPageSize, PAGE_SIZE_OPTIONS, savePageSize } from 'constants';
 import { stickyCellSx } from 'ui-component/stickyCellSx';
 import KeywordTableHead from 'ui-component/TableHead';
@@ -128,6 +133,8 @@ export default function ChannelTableRow({
   const [openTest, setOpenTest] = useState(false);
   // const [openDelete, setOpenDelete] = useState(false);
   const [openCheck, setOpenCheck] = useState(false);
+  const [openKeyPool, setOpenKeyPool] = useState(false);
+  const [openBalanceHistory, setOpenBalanceHistory] = useState(false);
   const [statusSwitch, setStatusSwitch] = useState(item.status);
   const [deleting, setDeleting] = useState(false);
 
@@ -690,15 +697,20 @@ export default function ChannelTableRow({
           {!item.tag && (
             <Stack direction="column" alignItems="center" spacing={0.5}>
               <Switch checked={statusSwitch === 1} onChange={handleStatus} size="small" />
-              <Typography
-                variant="caption"
-                sx={{
-                  fontWeight: statusSwitch === 1 ? 600 : 400,
-                  color: statusSwitch === 1 ? 'success.main' : 'text.secondary'
-                }}
-              >
-                {statusInfo(t, statusSwitch)}
-              </Typography>
+              <Tooltip title={statusSwitch === 3 ? item.status_reason || '' : ''} placement="top">
+                <Typography
+                  variant="caption"
+                  sx={{
+                    fontWeight: statusSwitch === 1 ? 600 : 400,
+                    color: statusSwitch === 1 ? 'success.main' : 'text.secondary'
+                  }}
+                >
+                  {statusInfo(t, statusSwitch)}
+                </Typography>
+              </Tooltip>
+              {statusSwitch === 1 && <CircuitBreakerLabel breakers={item.circuit_breakers} />}
+              {statusSwitch === 1 && <ScheduleLabel status={item.schedule_status} />}
+              <BudgetLabel budget={item.budget} usage={item.budget_usage} />
             </Stack>
           )}
           {item.tag && (
@@ -891,6 +903,28 @@ export default function ChannelTableRow({
           {t('channel_row.check')}
         </MenuItem>
 
+        {(currentTestingChannel || item).key_mode && (
+          <MenuItem
+            onClick={() => {
+              setOpenKeyPool(true);
+              popover.onClose();
+            }}
+          >
+            <Icon icon="solar:key-minimalistic-square-bold-duotone" style={{ marginRight: '16px' }} />
+            {t('channel_key.menu')}
+          </MenuItem>
+        )}
+
+        <MenuItem
+          onClick={() => {
+            setOpenBalanceHistory(true);
+            popover.onClose();
+          }}
+        >
+          <Icon icon="solar:chart-2-bold-duotone" style={{ marginRight: '16px' }} />
+          {t('channel_balance.menu')}
+        </MenuItem>
+
         {CHANNEL_OPTIONS[currentTestingChannel ? currentTestingChannel?.type : item.type]?.url && (
           <MenuItem
             onClick={() => {
@@ -1487,6 +1521,8 @@ export default function ChannelTableRow({
         </DialogActions>
       </Dialog>
       <ChannelCheck item={currentTestingChannel || item} open={openCheck} onClose={() => setOpenCheck(false)} />
+      <KeyPoolModal channel={currentTestingChannel || item} open={openKeyPool} onClose={() => setOpenKeyPool(false)} />
+      <BalanceHistoryModal channel={currentTestingChannel || item} open={openBalanceHistory} onClose={() => setOpenBalanceHistory(false)} />
 
       <ConfirmDialog
         open={tagDeleteConfirm.value}
@@ -21,7 +21,14 @@ const defaultConfig = {
     compatible_response: false,
     allow_extra_body: false,
     pass_through_body: false,
-    cost_ratio: 0
+    cost_ratio: 0,
+    balance_check_interval: 0,
+    balance_threshold: 0,
+    rate_limit: '',
+    key_mode: '',
+    capabilities: [],
+    schedule: '',
+    budget: ''
   },
   inputLabel: {
     name: '渠道名称',
@@ -45,7 +52,14 @@ const defaultConfig = {
     compatible_response: '兼容Response API',
     allow_extra_body: '允许额外字段透传',
     pass_through_body: '请求体完整透传',
-    cost_ratio: '成本倍率'
+    cost_ratio: '成本倍率',
+    balance_check_interval: '余额检查间隔',
+    balance_threshold: '余额提醒阈值',
+    rate_limit: '上游限额',
+    key_mode: '密钥池轮换',
+    capabilities: '能力标记',
+    schedule: '可用时间',
+    budget: '花费预算'
   },
   prompt: {
     type: '请选择渠道类型',
@@ -75,7 +89,20 @@ const defaultConfig = {
     allow_extra_body: '开启后，将会透传用户请求中的额外字段（如OpenAI SDK的extra_body参数），适用于需要传递自定义参数到上游API的场景',
     pass_through_body:
       '开启后，将客户端请求体原样转发至上游，仅改写映射后的模型名，保留未知字段与原始字节；适用于同协议透明代理场景。注意：仅对 OpenAI 协议渠道生效（Claude、Gemini 等自建请求体的渠道不读取该项）；开启后将跳过额外字段合并（仅渠道额外参数仍以字节方式生效）。',
-    cost_ratio: '上游成本倍率，相对模型基础价的折扣，例如 0.5 表示成本为基础价的 5 折。仅用于成本与利润统计，不影响用户扣费。未配置或为 0 时不计成本。'
+    cost_ratio: '上游成本倍率，相对模型基础价的折扣，例如 0.5 表示成本为基础价的 5 折。仅用于成本与利润统计，不影响用户扣费。未配置或为 0 时不计成本。',
+    balance_check_interval:
+      '定时更新渠道余额的间隔，单位为分钟，0 表示不自动更新，仅对支持查询余额的渠道生效。余额耗尽时自动禁用渠道，余额恢复后自动启用，每次更新的余额都会记录，可在渠道操作菜单的“余额记录”中查看消耗速度与预计用完时间。',
+    balance_threshold: '余额低于该值时发送提醒通知，单位与渠道余额相同，0 表示不提醒。',
+    key_mode:
+      '启用后渠道从密钥池中轮换使用多个密钥，每个密钥单独统计用量；鉴权或额度错误只自动禁用出错的密钥，限流只冷却该密钥。新建渠道时批量填写的多行密钥会全部导入该渠道的密钥池，创建后可在渠道操作菜单的“密钥池”中导入、导出和管理密钥。',
+    capabilities:
+      '标记该渠道具备或缺少的能力，供用户分组的内容路由规则筛选渠道，例如 vision（支持图片）、long_context（长上下文）、no_function_calling（不支持函数调用），也可以填写自定义标记。路由规则同时会匹配渠道标签。',
+    rate_limit:
+      '限制该渠道的并发请求数（concurrency）、每分钟请求数（rpm）与每分钟 tokens（tpm），可在 models 中按模型单独设置，两者同时生效，未设置或为 0 表示不限制。达到上限的渠道会被直接跳过，不会触发冷却。例如：{"concurrency":10,"rpm":500,"tpm":200000,"models":{"gpt-4o":{"rpm":100}}}',
+    schedule:
+      '设置该渠道的可用时间窗口，窗口之外选择渠道时会跳过该渠道，但不会修改渠道状态。每个窗口填写 cron（标准 5 段表达式，匹配到的每一分钟都可用），或填写 weekdays（0-6，0 为周日，为空表示每天）与 start、end（HH:MM，结束时间早于开始时间表示跨越零点）；timezone 为 IANA 时区，为空时使用服务器时区。例如：{"timezone":"Asia/Shanghai","windows":[{"weekdays":[1,2,3,4,5],"start":"09:00","end":"18:00"},{"cron":"* 0-7 * * *"}]}',
+    budget:
+      '设置该渠道每日（daily）、每周（weekly，周一开始）、每月（monthly）的花费预算，单位为美元，按成本倍率折算的上游成本计算，未设置成本倍率时按用户扣费计算，未设置或为 0 表示不限制。花费达到预算的 alert_percent（默认 80）% 时发送提醒，达到预算时暂停渠道（选择渠道时跳过，不修改渠道状态），周期重置后自动恢复。例如：{"daily":5,"monthly":100,"alert_percent":80}'
   },
   modelGroup: 'OpenAI'
 }
@@ -1,3 +1,9 @@
+export const KeyModeType = [
+  { value: '', label: '单密钥' },
+  { value: 'round_robin', label: '轮询' },
+  { value: 'random', label: '随机' }
+];
+
 export const PreCostType = [
   { value: 1, label: '正常计费' },
   { value: 2, label: '不计算图片' },
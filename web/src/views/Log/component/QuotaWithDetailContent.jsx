@@ -43,6 +43,7 @@ export default function QuotaWithDetailContent({ item, userGroup, userIsAdmin, t
   // 成本/利润仅管理员可见，且仅在记录了上游成本时展示
   const showCost = userIsAdmin && item.cost_quota > 0;
   const costQuota = item.cost_quota || 0;
+  const costRoutingSaving = item.metadata?.cost_routing_saving || 0;
 
   const priceType = item.metadata?.price_type || 'tokens';
   const extraBilling = item?.metadata?.extra_billing || {};
@@ -59,16 +60,20 @@ export default function QuotaWithDetailContent({ item, userGroup, userIsAdmin, t
   const groupRatio = item.metadata?.group_ratio || 1;
   const longContextInputRatio = item.metadata?.long_context_input_ratio;
   const longContextOutputRatio = item.metadata?.long_context_output_ratio;
+  // 批处理请求按折扣计费，实际单价同样需要乘上批处理倍率
+  const batchRatio = item.metadata?.batch_ratio ?? 1;
+  // 命中响应缓存时按缓存倍率计费
+  const cacheHitRatio = item.metadata?.cache_hit ? (item.metadata?.cache_hit_ratio ?? 1) : 1;
   // 命中长上下文分档时，输入/输出单价需按分档倍率放大，才能与实际扣费一致。
   const inputPrice =
     item.metadata?.input_price ||
     (item.metadata?.input_ratio
-      ? `$${calculatePrice(item.metadata.input_ratio * (longContextInputRatio || 1), groupRatio, false)} `
+      ? `$${calculatePrice(item.metadata.input_ratio * (longContextInputRatio || 1) * batchRatio * cacheHitRatio, groupRatio, false)} `
       : '$0');
   const outputPrice =
     item.metadata?.output_price ||
     (item.metadata?.output_ratio
-      ? `$${calculatePrice(item.metadata.output_ratio * (longContextOutputRatio || 1), groupRatio, false)}`
+      ? `$${calculatePrice(item.metadata.output_ratio * (longContextOutputRatio || 1) * batchRatio * cacheHitRatio, groupRatio, false)}`
       : '$0');
 
   const inputPriceUnit = inputPrice + ' /M';
@@ -186,6 +191,16 @@ export default function QuotaWithDetailContent({ item, userGroup, userIsAdmin, t
               {t('logPage.quotaDetail.longContextRatio')}: {longContextInputRatio || 1}× / {longContextOutputRatio || 1}×
             </Typography>
           )}
+          {batchRatio !== 1 && (
+            <Typography sx={{ fontSize: 13, color: (theme) => theme.palette.text.secondary, textAlign: 'left' }}>
+              {t('logPage.quotaDetail.batchRatio')}: {batchRatio}×
+            </Typography>
+          )}
+          {Boolean(item.metadata?.cache_hit) && (
+            <Typography sx={{ fontSize: 13, color: (theme) => theme.palette.text.secondary, textAlign: 'left' }}>
+              {t('logPage.quotaDetail.cacheHitRatio')}: {cacheHitRatio}×
+            </Typography>
+          )}
         </Box>
         {/* Actual Price */}
         <Box
@@ -276,6 +291,20 @@ export default function QuotaWithDetailContent({ item, userGroup, userIsAdmin, t
               </Typography>
             </>
           )}
+          {userIsAdmin && costRoutingSaving > 0 && (
+            <Typography
+              sx={{
+                fontSize: 13,
+                color: (theme) => theme.palette.success.main,
+                fontWeight: 500,
+                mr: 2,
+                mb: { xs: 0.5, sm: 0 },
+                textAlign: 'left'
+              }}
+            >
+              {t('logPage.quotaDetail.costRoutingSaving')}: {renderQuota(costRoutingSaving, 6)}
+            </Typography>
+          )}
           {/* 注释掉节省百分比的显示 */}
           {/* {savePercent && (
             <Box
@@ -105,6 +105,20 @@ export default function LogTableRow({ item, userIsAdmin, userGroup, columnVisibi
         {userIsAdmin && columnVisibility.channel_id && (
           <TableCell sx={{ p: '10px 8px', textAlign: 'center', whiteSpace: 'nowrap' }}>
             {(item.channel_id || '') + ' ' + (item.channel?.name ? '(' + item.channel.name + ')' : '')}
+            {item.metadata?.channel_key_id > 0 && (
+              <Typography variant="caption" display="block" color="text.secondary">
+                {t('logPage.channelKey', { id: item.metadata.channel_key_id })}
+              </Typography>
+            )}
+            {item.metadata?.hedge && (
+              <Typography variant="caption" display="block" color="text.secondary">
+                {t('logPage.hedge', {
+                  primary: item.metadata.hedge.primary_channel_id,
+                  hedge: item.metadata.hedge.hedge_channel_id,
+                  delay: item.metadata.hedge.delay_ms
+                })}
+              </Typography>
+            )}
           </TableCell>
         )}
         {userIsAdmin && columnVisibility.user_id && (
@@ -152,8 +166,17 @@ export default function LogTableRow({ item, userIsAdmin, userGroup, columnVisibi
         {columnVisibility.type &&
           <TableCell sx={{ p: '10px 8px', textAlign: 'center' }}>{renderType(item.type, LogType, t)}</TableCell>}
         {columnVisibility.model_name &&
-          <TableCell
-            sx={{ p: '10px 8px', textAlign: 'center' }}>{viewModelName(item.model_name, item.is_stream)}</TableCell>}
+          <TableCell sx={{ p: '10px 8px', textAlign: 'center' }}>
+            {viewModelName(item.model_name, item.is_stream)}
+            {item.metadata?.virtual_model && (
+              <Typography variant="caption" display="block" color="text.secondary">
+                {t(item.metadata.virtual_model.requested ? 'logPage.requestModels' : 'logPage.virtualModel', {
+                  name: item.metadata.virtual_model.name,
+                  step: item.metadata.virtual_model.step
+                })}
+              </Typography>
+            )}
+          </TableCell>}
 
         {columnVisibility.duration && (
           <TableCell sx={{ p: '10px 8px', textAlign: 'center' }}>
@@ -93,7 +93,22 @@ const OperationSetting = () => {
     safeTools: [],
     ClaudeBudgetTokensPercentage: 0,
     ClaudeDefaultMaxTokens: '',
-    GeminiOpenThink: ''
+    GeminiOpenThink: '',
+    ResponseCacheEnabled: 'false',
+    ResponseCacheTTL: 3600,
+    ResponseCacheHitRatio: 0,
+    ResponseCacheModelTTL: '',
+    ResponsesStoreEnabled: 'true',
+    ResponsesRetentionDays: 30,
+    CircuitBreakerEnabled: 'true',
+    CircuitBreakerWindowSeconds: 60,
+    CircuitBreakerMinRequests: 10,
+    CircuitBreakerErrorRate: 50,
+    CircuitBreakerConsecutiveFailures: 5,
+    CircuitBreakerOpenSeconds: 30,
+    CircuitBreakerMaxOpenSeconds: 1800,
+    CircuitBreakerHalfOpenRequests: 1,
+    LatencyRoutingExploreRate: 10
   });
   const [originInputs, setOriginInputs] = useState({});
   // cooldownRules: rows backing the RetryCooldownPerStatus JSON config, e.g.
@@ -483,6 +498,55 @@ const OperationSetting = () => {
             await updateOption('GeminiOpenThink', inputs.GeminiOpenThink);
           }
           break;
+
+        case 'responseCache':
+          if (originInputs.ResponseCacheTTL !== inputs.ResponseCacheTTL) {
+            await updateOption('ResponseCacheTTL', inputs.ResponseCacheTTL);
+          }
+          if (originInputs.ResponseCacheHitRatio !== inputs.ResponseCacheHitRatio) {
+            await updateOption('ResponseCacheHitRatio', inputs.ResponseCacheHitRatio);
+          }
+          if (originInputs.ResponseCacheModelTTL !== inputs.ResponseCacheModelTTL) {
+            if (inputs.ResponseCacheModelTTL.trim() && !verifyJSON(inputs.ResponseCacheModelTTL)) {
+              showError('ResponseCacheModelTTL 不是合法的 JSON 字符串');
+              return;
+            }
+            await updateOption('ResponseCacheModelTTL', inputs.ResponseCacheModelTTL);
+          }
+          break;
+
+        case 'responsesStore':
+          if (originInputs.ResponsesRetentionDays !== inputs.ResponsesRetentionDays) {
+            await updateOption('ResponsesRetentionDays', inputs.ResponsesRetentionDays);
+          }
+          break;
+
+        case 'circuitBreaker':
+          if (originInputs.CircuitBreakerWindowSeconds !== inputs.CircuitBreakerWindowSeconds) {
+            await updateOption('CircuitBreakerWindowSeconds', inputs.CircuitBreakerWindowSeconds);
+          }
+          if (originInputs.CircuitBreakerMinRequests !== inputs.CircuitBreakerMinRequests) {
+            await updateOption('CircuitBreakerMinRequests', inputs.CircuitBreakerMinRequests);
+          }
+          if (originInputs.CircuitBreakerErrorRate !== inputs.CircuitBreakerErrorRate) {
+            await updateOption('CircuitBreakerErrorRate', inputs.CircuitBreakerErrorRate);
+          }
+          if (originInputs.CircuitBreakerConsecutiveFailures !== inputs.CircuitBreakerConsecutiveFailures) {
+            await updateOption('CircuitBreakerConsecutiveFailures', inputs.CircuitBreakerConsecutiveFailures);
+          }
+          if (originInputs.CircuitBreakerOpenSeconds !== inputs.CircuitBreakerOpenSeconds) {
+            await updateOption('CircuitBreakerOpenSeconds', inputs.CircuitBreakerOpenSeconds);
+          }
+          if (originInputs.CircuitBreakerMaxOpenSeconds !== inputs.CircuitBreakerMaxOpenSeconds) {
+            await updateOption('CircuitBreakerMaxOpenSeconds', inputs.CircuitBreakerMaxOpenSeconds);
+          }
+          if (originInputs.CircuitBreakerHalfOpenRequests !== inputs.CircuitBreakerHalfOpenRequests) {
+            await updateOption('CircuitBreakerHalfOpenRequests', inputs.CircuitBreakerHalfOpenRequests);
+          }
+          if (originInputs.LatencyRoutingExploreRate !== inputs.LatencyRoutingExploreRate) {
+            await updateOption('LatencyRoutingExploreRate', inputs.LatencyRoutingExploreRate);
+          }
+          break;
       }
 
       await getOptions();
@@ -1500,6 +1564,272 @@ const OperationSetting = () => {
         </Stack>
       </SubCard>
 
+      <SubCard title={t('setting_index.operationSettings.responseCacheSettings.title')}>
+        <Stack spacing={2}>
+          <Stack justifyContent="flex-start" alignItems="flex-start" spacing={2}>
+            <FormControlLabel
+              sx={{ marginLeft: '0px' }}
+              label={t('setting_index.operationSettings.responseCacheSettings.enabled')}
+              control={
+                <Checkbox
+                  checked={dataLoaded ? inputs.ResponseCacheEnabled === 'true' : false}
+                  onChange={handleInputChange}
+                  name="ResponseCacheEnabled"
+                  disabled={!dataLoaded || loading}
+                />
+              }
+            />
+            <Grid container spacing={2}>
+              <Grid item xs={12} md={6}>
+                <FormControl fullWidth>
+                  <InputLabel htmlFor="ResponseCacheTTL">{t('setting_index.operationSettings.responseCacheSettings.ttl.label')}</InputLabel>
+                  <OutlinedInput
+                    id="ResponseCacheTTL"
+                    name="ResponseCacheTTL"
+                    type="number"
+                    value={inputs.ResponseCacheTTL}
+                    onChange={handleInputChange}
+                    label={t('setting_index.operationSettings.responseCacheSettings.ttl.label')}
+                    placeholder={t('setting_index.operationSettings.responseCacheSettings.ttl.placeholder')}
+                    disabled={loading}
+                  />
+                </FormControl>
+              </Grid>
+              <Grid item xs={12} md={6}>
+                <FormControl fullWidth>
+                  <InputLabel htmlFor="ResponseCacheHitRatio">
+                    {t('setting_index.operationSettings.responseCacheSettings.hitRatio.label')}
+                  </InputLabel>
+                  <OutlinedInput
+                    id="ResponseCacheHitRatio"
+                    name="ResponseCacheHitRatio"
+                    type="number"
+                    value={inputs.ResponseCacheHitRatio}
+                    onChange={handleInputChange}
+                    label={t('setting_index.operationSettings.responseCacheSettings.hitRatio.label')}
+                    placeholder={t('setting_index.operationSettings.responseCacheSettings.hitRatio.placeholder')}
+                    disabled={loading}
+                  />
+                </FormControl>
+              </Grid>
+            </Grid>
+
+            <FormControl fullWidth>
+              <TextField
+                multiline
+                maxRows={15}
+                id="ResponseCacheModelTTL"
+                label={t('setting_index.operationSettings.responseCacheSettings.modelTTL.label')}
+                value={inputs.ResponseCacheModelTTL}
+                name="ResponseCacheModelTTL"
+                onChange={handleTextFieldChange}
+                minRows={5}
+                placeholder={t('setting_index.operationSettings.responseCacheSettings.modelTTL.placeholder')}
+                disabled={loading}
+              />
+            </FormControl>
+
+            <Button
+              variant="contained"
+              onClick={() => {
+                submitConfig('responseCache').then();
+              }}
+            >
+              {t('setting_index.operationSettings.responseCacheSettings.save')}
+            </Button>
+          </Stack>
+        </Stack>
+      </SubCard>
+
+      <SubCard title={t('setting_index.operationSettings.responsesStoreSettings.title')}>
+        <Stack justifyContent="flex-start" alignItems="flex-start" spacing={2}>
+          <FormControlLabel
+            sx={{ marginLeft: '0px' }}
+            label={t('setting_index.operationSettings.responsesStoreSettings.enabled')}
+            control={
+              <Checkbox
+                checked={dataLoaded ? inputs.ResponsesStoreEnabled === 'true' : false}
+                onChange={handleInputChange}
+                name="ResponsesStoreEnabled"
+                disabled={!dataLoaded || loading}
+              />
+            }
+          />
+          <FormControl>
+            <InputLabel htmlFor="ResponsesRetentionDays">
+              {t('setting_index.operationSettings.responsesStoreSettings.retentionDays.label')}
+            </InputLabel>
+            <OutlinedInput
+              id="ResponsesRetentionDays"
+              name="ResponsesRetentionDays"
+              type="number"
+              value={inputs.ResponsesRetentionDays}
+              onChange={handleInputChange}
+              label={t('setting_index.operationSettings.responsesStoreSettings.retentionDays.label')}
+              placeholder={t('setting_index.operationSettings.responsesStoreSettings.retentionDays.placeholder')}
+              disabled={loading}
+            />
+          </FormControl>
+          <Button
+            variant="contained"
+            onClick={() => {
+              submitConfig('responsesStore').then();
+            }}
+          >
+            {t('setting_index.operationSettings.responsesStoreSettings.save')}
+          </Button>
+        </Stack>
+      </SubCard>
+
+      <SubCard title={t('setting_index.operationSettings.circuitBreakerSettings.title')}>
+        <Stack spacing={2}>
+          <Stack justifyContent="flex-start" alignItems="flex-start" spacing={2}>
+            <FormControlLabel
+              sx={{ marginLeft: '0px' }}
+              label={t('setting_index.operationSettings.circuitBreakerSettings.enabled')}
+              control={
+                <Checkbox
+                  checked={dataLoaded ? inputs.CircuitBreakerEnabled === 'true' : false}
+                  onChange={handleInputChange}
+                  name="CircuitBreakerEnabled"
+                  disabled={!dataLoaded || loading}
+                />
+              }
+            />
+            <Grid container spacing={2}>
+              <Grid item xs={12} md={6}>
+                <FormControl fullWidth>
+                  <InputLabel htmlFor="CircuitBreakerWindowSeconds">{t('setting_index.operationSettings.circuitBreakerSettings.windowSeconds.label')}</InputLabel>
+                  <OutlinedInput
+                    id="CircuitBreakerWindowSeconds"
+                    name="CircuitBreakerWindowSeconds"
+                    type="number"
+                    value={inputs.CircuitBreakerWindowSeconds}
+                    onChange={handleInputChange}
+                    label={t('setting_index.operationSettings.circuitBreakerSettings.windowSeconds.label')}
+                    placeholder={t('setting_index.operationSettings.circuitBreakerSettings.windowSeconds.placeholder')}
+                    disabled={loading}
+                  />
+                </FormControl>
+              </Grid>
+              <Grid item xs={12} md={6}>
+                <FormControl fullWidth>
+                  <InputLabel htmlFor="CircuitBreakerMinRequests">{t('setting_index.operationSettings.circuitBreakerSettings.minRequests.label')}</InputLabel>
+                  <OutlinedInput
+                    id="CircuitBreakerMinRequests"
+                    name="CircuitBreakerMinRequests"
+                    type="number"
+                    value={inputs.CircuitBreakerMinRequests}
+                    onChange={handleInputChange}
+                    label={t('setting_index.operationSettings.circuitBreakerSettings.minRequests.label')}
+                    placeholder={t('setting_index.operationSettings.circuitBreakerSettings.minRequests.placeholder')}
+                    disabled={loading}
+                  />
+                </FormControl>
+              </Grid>
+              <Grid item xs={12} md={6}>
+                <FormControl fullWidth>
+                  <InputLabel htmlFor="CircuitBreakerErrorRate">{t('setting_index.operationSettings.circuitBreakerSettings.errorRate.label')}</InputLabel>
+                  <OutlinedInput
+                    id="CircuitBreakerErrorRate"
+                    name="CircuitBreakerErrorRate"
+                    type="number"
+                    value={inputs.CircuitBreakerErrorRate}
+                    onChange={handleInputChange}
+                    label={t('setting_index.operationSettings.circuitBreakerSettings.errorRate.label')}
+                    placeholder={t('setting_index.operationSettings.circuitBreakerSettings.errorRate.placeholder')}
+                    disabled={loading}
+                  />
+                </FormControl>
+              </Grid>
+              <Grid item xs={12} md={6}>
+                <FormControl fullWidth>
+                  <InputLabel htmlFor="CircuitBreakerConsecutiveFailures">{t('setting_index.operationSettings.circuitBreakerSettings.consecutiveFailures.label')}</InputLabel>
+                  <OutlinedInput
+                    id="CircuitBreakerConsecutiveFailures"
+                    name="CircuitBreakerConsecutiveFailures"
+                    type="number"
+                    value={inputs.CircuitBreakerConsecutiveFailures}
+                    onChange={handleInputChange}
+                    label={t('setting_index.operationSettings.circuitBreakerSettings.consecutiveFailures.label')}
+                    placeholder={t('setting_index.operationSettings.circuitBreakerSettings.consecutiveFailures.placeholder')}
+                    disabled={loading}
+                  />
+                </FormControl>
+              </Grid>
+              <Grid item xs={12} md={6}>
+                <FormControl fullWidth>
+                  <InputLabel htmlFor="CircuitBreakerOpenSeconds">{t('setting_index.operationSettings.circuitBreakerSettings.openSeconds.label')}</InputLabel>
+                  <OutlinedInput
+                    id="CircuitBreakerOpenSeconds"
+                    name="CircuitBreakerOpenSeconds"
+                    type="number"
+                    value={inputs.CircuitBreakerOpenSeconds}
+                    onChange={handleInputChange}
+                    label={t('setting_index.operationSettings.circuitBreakerSettings.openSeconds.label')}
+                    placeholder={t('setting_index.operationSettings.circuitBreakerSettings.openSeconds.placeholder')}
+                    disabled={loading}
+                  />
+                </FormControl>
+              </Grid>
+              <Grid item xs={12} md={6}>
+                <FormControl fullWidth>
+                  <InputLabel htmlFor="CircuitBreakerMaxOpenSeconds">{t('setting_index.operationSettings.circuitBreakerSettings.maxOpenSeconds.label')}</InputLabel>
+                  <OutlinedInput
+                    id="CircuitBreakerMaxOpenSeconds"
+                    name="CircuitBreakerMaxOpenSeconds"
+                    type="number"
+                    value={inputs.CircuitBreakerMaxOpenSeconds}
+                    onChange={handleInputChange}
+                    label={t('setting_index.operationSettings.circuitBreakerSettings.maxOpenSeconds.label')}
+                    placeholder={t('setting_index.operationSettings.circuitBreakerSettings.maxOpenSeconds.placeholder')}
+                    disabled={loading}
+                  />
+                </FormControl>
+              </Grid>
+              <Grid item xs={12} md={6}>
+                <FormControl fullWidth>
+                  <InputLabel htmlFor="CircuitBreakerHalfOpenRequests">{t('setting_index.operationSettings.circuitBreakerSettings.halfOpenRequests.label')}</InputLabel>
+                  <OutlinedInput
+                    id="CircuitBreakerHalfOpenRequests"
+                    name="CircuitBreakerHalfOpenRequests"
+                    type="number"
+                    value={inputs.CircuitBreakerHalfOpenRequests}
+                    onChange={handleInputChange}
+                    label={t('setting_index.operationSettings.circuitBreakerSettings.halfOpenRequests.label')}
+                    placeholder={t('setting_index.operationSettings.circuitBreakerSettings.halfOpenRequests.placeholder')}
+                    disabled={loading}
+                  />
+                </FormControl>
+              </Grid>
+              <Grid item xs={12} md={6}>
+                <FormControl fullWidth>
+                  <InputLabel htmlFor="LatencyRoutingExploreRate">{t('setting_index.operationSettings.circuitBreakerSettings.latencyExploreRate.label')}</InputLabel>
+                  <OutlinedInput
+                    id="LatencyRoutingExploreRate"
+                    name="LatencyRoutingExploreRate"
+                    type="number"
+                    value={inputs.LatencyRoutingExploreRate}
+                    onChange={handleInputChange}
+                    label={t('setting_index.operationSettings.circuitBreakerSettings.latencyExploreRate.label')}
+                    placeholder={t('setting_index.operationSettings.circuitBreakerSettings.latencyExploreRate.placeholder')}
+                    disabled={loading}
+                  />
+                </FormControl>
+              </Grid>
+            </Grid>
+            <Button
+              variant="contained"
+              onClick={() => {
+                submitConfig('circuitBreaker').then();
+              }}
+            >
+              {t('setting_index.operationSettings.circuitBreakerSettings.save')}
+            </Button>
+          </Stack>
+        </Stack>
+      </SubCard>
+
       <SubCard title={t('setting_index.operationSettings.safetySettings.title')}>
         <Stack spacing={2}>
           <Stack justifyContent="flex-start" alignItems="flex-start" spacing={2}>
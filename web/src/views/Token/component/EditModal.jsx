@@ -20,6 +20,7 @@ import {
   Switch,
   FormControlLabel,
   FormHelperText,
+  InputAdornment,
   Select,
   MenuItem,
   Typography,
@@ -84,6 +85,13 @@ const originInputs = {
       enabled: false,
       timeout_seconds: 30
     },
+    response_cache: {
+      enabled: false
+    },
+    webhook: {
+      url: '',
+      secret: ''
+    },
     limits: {
       limit_model_setting: {
         enabled: false,
@@ -285,6 +293,8 @@ const EditModal = ({ open, tokenId, onCancel, onOk, userGroupOptions, adminMode
         }
         tokenData.is_edit = true;
         if (!tokenData.setting) tokenData.setting = originInputs.setting;
+        if (!tokenData.setting.response_cache) tokenData.setting.response_cache = originInputs.setting.response_cache;
+        if (!tokenData.setting.webhook) tokenData.setting.webhook = originInputs.setting.webhook;
         if (!tokenData.setting.limits) tokenData.setting.limits = originInputs.setting.limits;
         if (!tokenData.setting.limits.limit_model_setting)
           tokenData.setting.limits.limit_model_setting = originInputs.setting.limits.limit_model_setting;
@@ -535,6 +545,88 @@ const EditModal = ({ open, tokenId, onCancel, onOk, userGroupOptions, adminMode
                   </FormControl>
                 )}
 
+                <Divider sx={{ margin: '16px 0px' }} />
+                <Typography variant="h4">{t('token_index.responseCache')}</Typography>
+                <Typography variant="caption">{t('token_index.responseCacheTip')}</Typography>
+
+                <FormControl fullWidth>
+                  <FormControlLabel
+                    control={
+                      <Switch
+                        checked={values?.setting?.response_cache?.enabled === true}
+                        onClick={() => {
+                          setFieldValue('setting.response_cache.enabled', !values.setting?.response_cache?.enabled);
+                        }}
+                      />
+                    }
+                    label={t('token_index.responseCache')}
+                  />
+                </FormControl>
+
+                <Divider sx={{ margin: '16px 0px' }} />
+                <Typography variant="h4">{t('token_index.webhook')}</Typography>
+                <Typography variant="caption">{t('token_index.webhookTip')}</Typography>
+                <FormControl fullWidth sx={{ mt: 2 }}>
+                  <InputLabel>{t('token_index.webhookUrl')}</InputLabel>
+                  <OutlinedInput
+                    label={t('token_index.webhookUrl')}
+                    value={values?.setting?.webhook?.url || ''}
+                    placeholder="https://example.com/webhook"
+                    onChange={(e) => {
+                      setFieldValue('setting.webhook.url', e.target.value);
+                    }}
+                  />
+                </FormControl>
+                <FormControl fullWidth sx={{ mt: 2 }}>
+                  <InputLabel>{t('token_index.webhookSecret')}</InputLabel>
+                  <OutlinedInput
+                    label={t('token_index.webhookSecret')}
+                    value={values?.setting?.webhook?.secret || ''}
+                    onChange={(e) => {
+                      setFieldValue('setting.webhook.secret', e.target.value);
+                    }}
+                  />
+                  <FormHelperText>{t('token_index.webhookSecretHelperText')}</FormHelperText>
+                </FormControl>
+
+                <Divider sx={{ margin: '16px 0px' }} />
+                <Typography variant="h4">{t('token_index.routingStrategy')}</Typography>
+                <Typography variant="caption">{t('token_index.routingStrategyTip')}</Typography>
+                <FormControl fullWidth sx={{ mt: 2 }}>
+                  <InputLabel>{t('token_index.routingStrategy')}</InputLabel>
+                  <Select
+                    label={t('token_index.routingStrategy')}
+                    value={values?.setting?.routing_strategy || ''}
+                    onChange={(e) => {
+                      setFieldValue('setting.routing_strategy', e.target.value);
+                    }}
+                    displayEmpty
+                  >
+                    <MenuItem value="">{t('token_index.routingStrategyFollowGroup')}</MenuItem>
+                    <MenuItem value="weight">{t('userGroup.routingStrategyWeight')}</MenuItem>
+                    <MenuItem value="latency">{t('userGroup.routingStrategyLatency')}</MenuItem>
+                    <MenuItem value="cost">{t('userGroup.routingStrategyCost')}</MenuItem>
+                  </Select>
+                </FormControl>
+
+                <Divider sx={{ margin: '16px 0px' }} />
+                <Typography variant="h4">{t('token_index.hedgeDelay')}</Typography>
+                <Typography variant="caption">{t('token_index.hedgeDelayTip')}</Typography>
+                <FormControl fullWidth sx={{ mt: 2 }}>
+                  <InputLabel>{t('token_index.hedgeDelay')}</InputLabel>
+                  <OutlinedInput
+                    label={t('token_index.hedgeDelay')}
+                    type="number"
+                    value={values?.setting?.hedge_delay || ''}
+                    onChange={(e) => {
+                      const value = parseInt(e.target.value, 10);
+                      setFieldValue('setting.hedge_delay', value > 0 ? value : 0);
+                    }}
+                    endAdornment={<InputAdornment position="end">ms</InputAdornment>}
+                  />
+                  <FormHelperText>{t('token_index.hedgeDelayHelperText')}</FormHelperText>
+                </FormControl>
+
                 <Divider sx={{ margin: '16px 0px' }} />
                 <Typography variant="h4">{t('token_index.selectGroup')}</Typography>
                 <Typography variant="caption">{t('token_index.selectGroupInfo')}</Typography>
@@ -4,6 +4,9 @@ import { Formik } from 'formik';
 import { useTheme } from '@mui/material/styles';
 import { useState, useEffect } from 'react';
 import {
+  Autocomplete,
+  Box,
+  Checkbox,
   Dialog,
   DialogTitle,
   DialogContent,
@@ -15,13 +18,25 @@ import {
   OutlinedInput,
   Switch,
   FormControlLabel,
-  FormHelperText
+  FormHelperText,
+  InputAdornment,
+  Select,
+  MenuItem,
+  IconButton,
+  Stack,
+  TextField,
+  Typography
 } from '@mui/material';
+import { Icon } from '@iconify/react';
 
 import { showSuccess, showError, trims } from 'utils/common';
 import { API } from 'utils/api';
 import { useTranslation } from 'react-i18next';
 
+const CAPABILITIES = ['vision', 'long_context', 'no_function_calling'];
+
+const newRoutingRule = () => ({ name: '', min_prompt_tokens: 0, has_image: false, has_tools: false, require: [], exclude: [] });
+
 const validationSchema = Yup.object().shape({
   is_edit: Yup.boolean(),
   symbol: Yup.string().required('symbol is required'),
@@ -30,7 +45,8 @@ const validationSchema = Yup.object().shape({
   ratio: Yup.number().required('ratio is required'),
   promotion: Yup.boolean(),
   min: Yup.number(),
-  max: Yup.number()
+  max: Yup.number(),
+  hedge_delay: Yup.number().min(0, 'userGroup.hedgeDelayMin')
 });
 
 const originInputs = {
@@ -44,7 +60,11 @@ const originInputs = {
   promotion: false,
   min: 0,
   max: 0,
-  enable: true
+  enable: true,
+  routing_strategy: 'weight',
+  hedge_delay: 0,
+  routing_rules: [],
+  disable_request_routing: false
 };
 
 const EditModal = ({ open, userGroupId, onCancel, onOk }) => {
@@ -52,11 +72,17 @@ const EditModal = ({ open, userGroupId, onCancel, onOk }) => {
   const [inputs, setInputs] = useState(originInputs);
   const { t } = useTranslation();
 
+  const capabilityLabel = (option) => (CAPABILITIES.includes(option) ? t(`channel_edit.capabilityOptions.${option}`) : option);
+
   const submit = async (values, { setErrors, setStatus, setSubmitting }) => {
     setSubmitting(true);
 
     let res;
     values = trims(values);
+    values.routing_rules = (values.routing_rules || []).map((rule) => ({
+      ...rule,
+      min_prompt_tokens: parseInt(rule.min_prompt_tokens) || 0
+    }));
     try {
       if (values.is_edit) {
         res = await API.put(`/api/user_group/`, { ...values, id: parseInt(userGroupId) });
@@ -88,6 +114,7 @@ const EditModal = ({ open, userGroupId, onCancel, onOk }) => {
       const { success, message, data } = res.data;
       if (success) {
         data.is_edit = true;
+        data.routing_rules = data.routing_rules || [];
         setInputs(data);
       } else {
         showError(message);
@@ -226,6 +253,143 @@ const EditModal = ({ open, userGroupId, onCancel, onOk }) => {
                 )}
               </FormControl>
 
+              <FormControl fullWidth sx={{ ...theme.typography.otherInput }}>
+                <InputLabel htmlFor="channel-routing-strategy-label">{t('userGroup.routingStrategy')}</InputLabel>
+                <Select
+                  id="channel-routing-strategy-label"
+                  label={t('userGroup.routingStrategy')}
+                  value={values.routing_strategy || 'weight'}
+                  name="routing_strategy"
+                  onBlur={handleBlur}
+                  onChange={handleChange}
+                >
+                  <MenuItem value="weight">{t('userGroup.routingStrategyWeight')}</MenuItem>
+                  <MenuItem value="latency">{t('userGroup.routingStrategyLatency')}</MenuItem>
+                  <MenuItem value="cost">{t('userGroup.routingStrategyCost')}</MenuItem>
+                </Select>
+                <FormHelperText id="helper-tex-channel-routing-strategy-label"> {t('userGroup.routingStrategyTip')} </FormHelperText>
+              </FormControl>
+
+              <FormControl fullWidth error={Boolean(touched.hedge_delay && errors.hedge_delay)} sx={{ ...theme.typography.otherInput }}>
+                <InputLabel htmlFor="channel-hedge-delay-label">{t('userGroup.hedgeDelay')}</InputLabel>
+                <OutlinedInput
+                  id="channel-hedge-delay-label"
+                  label={t('userGroup.hedgeDelay')}
+                  type="number"
+                  value={values.hedge_delay}
+                  name="hedge_delay"
+                  onBlur={handleBlur}
+                  onChange={handleChange}
+                  endAdornment={<InputAdornment position="end">ms</InputAdornment>}
+                  aria-describedby="helper-text-channel-hedge-delay-label"
+                />
+
+                {touched.hedge_delay && errors.hedge_delay ? (
+                  <FormHelperText error id="helper-tex-channel-hedge-delay-label">
+                    {t(errors.hedge_delay)}
+                  </FormHelperText>
+                ) : (
+                  <FormHelperText id="helper-tex-channel-hedge-delay-label"> {t('userGroup.hedgeDelayTip')} </FormHelperText>
+                )}
+              </FormControl>
+
+              <Box sx={{ mt: 2 }}>
+                <Typography variant="subtitle1">{t('userGroup.routingRules')}</Typography>
+                <Typography variant="caption" color="text.secondary">
+                  {t('userGroup.routingRulesTip')}
+                </Typography>
+              </Box>
+
+              <Stack spacing={2} sx={{ mt: 2 }}>
+                {values.routing_rules.map((rule, index) => {
+                  const updateRule = (field, value) => setFieldValue(`routing_rules.${index}.${field}`, value);
+                  return (
+                    <Box key={index} sx={{ border: 1, borderColor: 'divider', borderRadius: 1, p: 2 }}>
+                      <Stack direction={{ xs: 'column', md: 'row' }} spacing={1} alignItems={{ md: 'center' }}>
+                        <TextField
+                          sx={{ flex: 2 }}
+                          size="small"
+                          label={t('userGroup.ruleName')}
+                          value={rule.name}
+                          onChange={(e) => updateRule('name', e.target.value)}
+                        />
+                        <TextField
+                          sx={{ flex: 2 }}
+                          size="small"
+                          type="number"
+                          label={t('userGroup.ruleMinPromptTokens')}
+                          value={rule.min_prompt_tokens}
+                          onChange={(e) => updateRule('min_prompt_tokens', e.target.value)}
+                        />
+                        <FormControlLabel
+                          control={
+                            <Checkbox checked={Boolean(rule.has_image)} onChange={(e) => updateRule('has_image', e.target.checked)} />
+                          }
+                          label={t('userGroup.ruleHasImage')}
+                        />
+                        <FormControlLabel
+                          control={
+                            <Checkbox checked={Boolean(rule.has_tools)} onChange={(e) => updateRule('has_tools', e.target.checked)} />
+                          }
+                          label={t('userGroup.ruleHasTools')}
+                        />
+                        <IconButton
+                          size="small"
+                          color="error"
+                          onClick={() => setFieldValue('routing_rules', values.routing_rules.filter((_, i) => i !== index))}
+                        >
+                          <Icon icon="solar:trash-bin-trash-bold-duotone" width={18} />
+                        </IconButton>
+                      </Stack>
+                      <Stack direction={{ xs: 'column', md: 'row' }} spacing={1} sx={{ mt: 2 }}>
+                        {['require', 'exclude'].map((field) => (
+                          <Autocomplete
+                            key={field}
+                            multiple
+                            freeSolo
+                            size="small"
+                            sx={{ flex: 1 }}
+                            options={CAPABILITIES}
+                            value={rule[field] || []}
+                            getOptionLabel={capabilityLabel}
+                            onChange={(e, value) => updateRule(field, value)}
+                            renderInput={(params) => (
+                              <TextField
+                                {...params}
+                                label={t(field === 'require' ? 'userGroup.ruleRequire' : 'userGroup.ruleExclude')}
+                                placeholder={t('userGroup.ruleCapabilityPlaceholder')}
+                              />
+                            )}
+                          />
+                        ))}
+                      </Stack>
+                    </Box>
+                  );
+                })}
+              </Stack>
+              <Button
+                sx={{ mt: 1 }}
+                startIcon={<Icon icon="solar:add-circle-line-duotone" />}
+                onClick={() => setFieldValue('routing_rules', [...values.routing_rules, newRoutingRule()])}
+              >
+                {t('userGroup.addRoutingRule')}
+              </Button>
+
+              <FormControl fullWidth>
+                <FormControlLabel
+                  control={
+                    <Switch
+                      checked={Boolean(values.disable_request_routing)}
+                      onClick={() => {
+                        setFieldValue('disable_request_routing', !values.disable_request_routing);
+                      }}
+                    />
+                  }
+                  label={t('userGroup.disableRequestRouting')}
+                />
+                <FormHelperText id="helper-tex-channel-disable-request-routing-label"> {t('userGroup.disableRequestRoutingTip')} </FormHelperText>
+              </FormControl>
+
               <FormControl fullWidth>
                 <FormControlLabel
                   control={
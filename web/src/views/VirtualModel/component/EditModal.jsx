@@ -0,0 +1,310 @@
+import PropTypes from 'prop-types';
+import * as Yup from 'yup';
+import { Formik } from 'formik';
+import { useTheme } from '@mui/material/styles';
+import { useState, useEffect } from 'react';
+import {
+  Autocomplete,
+  Box,
+  Button,
+  Dialog,
+  DialogActions,
+  DialogContent,
+  DialogTitle,
+  Divider,
+  FormControl,
+  FormControlLabel,
+  FormHelperText,
+  IconButton,
+  InputAdornment,
+  InputLabel,
+  OutlinedInput,
+  Stack,
+  Switch,
+  TextField,
+  Typography
+} from '@mui/material';
+import { Icon } from '@iconify/react';
+
+import { showSuccess, showError, trims } from 'utils/common';
+import { API } from 'utils/api';
+import { useTranslation } from 'react-i18next';
+
+const FALLBACK_CONDITIONS = ['any', 'rate_limit', 'server_error', 'timeout', 'auth', 'context_length'];
+
+const validationSchema = Yup.object().shape({
+  name: Yup.string().required('virtualModel.nameRequired'),
+  steps: Yup.array()
+    .of(
+      Yup.object().shape({
+        model: Yup.string().required('virtualModel.modelRequired'),
+        timeout: Yup.number().min(0, 'virtualModel.timeoutMin')
+      })
+    )
+    .min(1, 'virtualModel.stepsRequired')
+});
+
+const newStep = () => ({ model: '', fallback_on: [], timeout: 0 });
+
+const originInputs = {
+  is_edit: false,
+  name: '',
+  description: '',
+  enable: true,
+  steps: [newStep(), newStep()]
+};
+
+const EditModal = ({ open, editId, onCancel, onOk }) => {
+  const theme = useTheme();
+  const { t } = useTranslation();
+  const [inputs, setInputs] = useState(originInputs);
+  const [modelOptions, setModelOptions] = useState([]);
+
+  const submit = async (values, { setErrors, setStatus, setSubmitting }) => {
+    setSubmitting(true);
+
+    values = trims(values);
+    const payload = {
+      ...values,
+      // 最后一步没有可回退的步骤，不保存回退条件
+      steps: values.steps.map((step, index) =>
+        index === values.steps.length - 1
+          ? { model: step.model }
+          : { model: step.model, fallback_on: step.fallback_on, timeout: parseInt(step.timeout) || 0 }
+      )
+    };
+
+    let res;
+    try {
+      if (values.is_edit) {
+        res = await API.put(`/api/virtual_model/`, { ...payload, id: parseInt(editId) });
+      } else {
+        res = await API.post(`/api/virtual_model/`, payload);
+      }
+      const { success, message } = res.data;
+      if (success) {
+        showSuccess(t('userPage.saveSuccess'));
+        setSubmitting(false);
+        setStatus({ success: true });
+        onOk(true);
+      } else {
+        showError(message);
+        setErrors({ submit: message });
+      }
+    } catch (error) {
+      return;
+    }
+  };
+
+  const loadVirtualModel = async () => {
+    try {
+      let res = await API.get(`/api/virtual_model/${editId}`);
+      const { success, message, data } = res.data;
+      if (success) {
+        data.is_edit = true;
+        data.steps = (data.steps || []).map((step) => ({ ...newStep(), ...step, fallback_on: step.fallback_on || [] }));
+        setInputs(data);
+      } else {
+        showError(message);
+      }
+    } catch (error) {
+      return;
+    }
+  };
+
+  const fetchModelList = async () => {
+    try {
+      const res = await API.get('/api/prices/model_list');
+      const { success, data } = res.data;
+      if (success) {
+        setModelOptions(data);
+      }
+    } catch (error) {
+      console.error('Failed to fetch model list:', error);
+    }
+  };
+
+  useEffect(() => {
+    fetchModelList();
+  }, []);
+
+  useEffect(() => {
+    if (editId) {
+      loadVirtualModel().then();
+    } else {
+      setInputs(originInputs);
+    }
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [editId]);
+
+  return (
+    <Dialog open={open} onClose={onCancel} fullWidth maxWidth={'md'}>
+      <DialogTitle sx={{ margin: '0px', fontWeight: 700, lineHeight: '1.55556', padding: '24px', fontSize: '1.125rem' }}>
+        {editId ? t('common.edit') : t('common.create')}
+      </DialogTitle>
+      <Divider />
+      <DialogContent>
+        <Formik initialValues={inputs} enableReinitialize validationSchema={validationSchema} onSubmit={submit}>
+          {({ errors, handleBlur, handleChange, handleSubmit, touched, values, isSubmitting, setFieldValue }) => {
+            const updateStep = (index, key, value) => {
+              const steps = [...values.steps];
+              steps[index] = { ...steps[index], [key]: value };
+              setFieldValue('steps', steps);
+            };
+            const moveStep = (index, offset) => {
+              const steps = [...values.steps];
+              [steps[index], steps[index + offset]] = [steps[index + offset], steps[index]];
+              setFieldValue('steps', steps);
+            };
+            const removeStep = (index) => {
+              setFieldValue(
+                'steps',
+                values.steps.filter((_, i) => i !== index)
+              );
+            };
+
+            return (
+              <form noValidate onSubmit={handleSubmit}>
+                <FormControl fullWidth error={Boolean(touched.name && errors.name)} sx={{ ...theme.typography.otherInput }}>
+                  <InputLabel htmlFor="virtual-model-name-label">{t('virtualModel.name')}</InputLabel>
+                  <OutlinedInput
+                    id="virtual-model-name-label"
+                    label={t('virtualModel.name')}
+                    type="text"
+                    value={values.name}
+                    name="name"
+                    onBlur={handleBlur}
+                    onChange={handleChange}
+                    inputProps={{ autoComplete: 'off' }}
+                  />
+                  {touched.name && errors.name ? (
+                    <FormHelperText error>{t(errors.name)}</FormHelperText>
+                  ) : (
+                    <FormHelperText>{t('virtualModel.nameTip')}</FormHelperText>
+                  )}
+                </FormControl>
+
+                <FormControl fullWidth sx={{ ...theme.typography.otherInput }}>
+                  <InputLabel htmlFor="virtual-model-description-label">{t('virtualModel.description')}</InputLabel>
+                  <OutlinedInput
+                    id="virtual-model-description-label"
+                    label={t('virtualModel.description')}
+                    type="text"
+                    value={values.description}
+                    name="description"
+                    onBlur={handleBlur}
+                    onChange={handleChange}
+                    multiline
+                    minRows={2}
+                  />
+                </FormControl>
+
+                <Box sx={{ mt: 2 }}>
+                  <Typography variant="subtitle1">{t('virtualModel.steps')}</Typography>
+                  <Typography variant="caption" color="text.secondary">
+                    {t('virtualModel.stepsTip')}
+                  </Typography>
+                </Box>
+
+                <Stack spacing={2} sx={{ mt: 2 }}>
+                  {values.steps.map((step, index) => {
+                    const isLast = index === values.steps.length - 1;
+                    const stepErrors = Array.isArray(errors.steps) ? errors.steps[index] : undefined;
+                    return (
+                      <Stack key={index} direction={{ xs: 'column', md: 'row' }} spacing={1} alignItems={{ md: 'flex-start' }}>
+                        <Typography variant="body2" sx={{ minWidth: 56, pt: { md: 2 } }}>
+                          {t('virtualModel.step', { index: index + 1 })}
+                        </Typography>
+                        <Autocomplete
+                          freeSolo
+                          sx={{ flex: 2 }}
+                          options={modelOptions}
+                          value={step.model}
+                          onChange={(e, value) => updateStep(index, 'model', value || '')}
+                          onInputChange={(e, value) => updateStep(index, 'model', value || '')}
+                          renderInput={(params) => (
+                            <TextField
+                              {...params}
+                              label={t('virtualModel.model')}
+                              error={Boolean(touched.steps && stepErrors?.model)}
+                              helperText={touched.steps && stepErrors?.model ? t(stepErrors.model) : ''}
+                            />
+                          )}
+                        />
+                        <Autocomplete
+                          multiple
+                          sx={{ flex: 2 }}
+                          disabled={isLast}
+                          options={FALLBACK_CONDITIONS}
+                          value={step.fallback_on}
+                          getOptionLabel={(option) => t(`virtualModel.conditions.${option}`)}
+                          onChange={(e, value) => updateStep(index, 'fallback_on', value)}
+                          renderInput={(params) => (
+                            <TextField {...params} label={t('virtualModel.fallbackOn')} placeholder={isLast ? '' : t('virtualModel.conditions.any')} />
+                          )}
+                        />
+                        <TextField
+                          sx={{ flex: 1 }}
+                          type="number"
+                          disabled={isLast}
+                          label={t('virtualModel.timeout')}
+                          value={step.timeout}
+                          onChange={(e) => updateStep(index, 'timeout', e.target.value)}
+                          error={Boolean(touched.steps && stepErrors?.timeout)}
+                          helperText={touched.steps && stepErrors?.timeout ? t(stepErrors.timeout) : ''}
+                          InputProps={{ endAdornment: <InputAdornment position="end">s</InputAdornment> }}
+                        />
+                        <Stack direction="row" sx={{ pt: { md: 1 } }}>
+                          <IconButton size="small" disabled={index === 0} onClick={() => moveStep(index, -1)}>
+                            <Icon icon="solar:alt-arrow-up-linear" width={18} />
+                          </IconButton>
+                          <IconButton size="small" disabled={isLast} onClick={() => moveStep(index, 1)}>
+                            <Icon icon="solar:alt-arrow-down-linear" width={18} />
+                          </IconButton>
+                          <IconButton size="small" color="error" disabled={values.steps.length <= 1} onClick={() => removeStep(index)}>
+                            <Icon icon="solar:trash-bin-trash-bold-duotone" width={18} />
+                          </IconButton>
+                        </Stack>
+                      </Stack>
+                    );
+                  })}
+                </Stack>
+                {typeof errors.steps === 'string' && <FormHelperText error>{t(errors.steps)}</FormHelperText>}
+                <Button
+                  sx={{ mt: 1 }}
+                  startIcon={<Icon icon="solar:add-circle-line-duotone" />}
+                  onClick={() => setFieldValue('steps', [...values.steps, newStep()])}
+                >
+                  {t('virtualModel.addStep')}
+                </Button>
+
+                <Box sx={{ mt: 2 }}>
+                  <FormControlLabel
+                    control={<Switch checked={values.enable !== false} onChange={(e) => setFieldValue('enable', e.target.checked)} />}
+                    label={t('virtualModel.enable')}
+                  />
+                </Box>
+
+                <DialogActions>
+                  <Button onClick={onCancel}>{t('userPage.cancel')}</Button>
+                  <Button disableElevation disabled={isSubmitting} type="submit" variant="contained" color="primary">
+                    {t('userPage.submit')}
+                  </Button>
+                </DialogActions>
+              </form>
+            );
+          }}
+        </Formik>
+      </DialogContent>
+    </Dialog>
+  );
+};
+
+export default EditModal;
+
+EditModal.propTypes = {
+  open: PropTypes.bool,
+  editId: PropTypes.number,
+  onCancel: PropTypes.func,
+  onOk: PropTypes.func
+};
@@ -0,0 +1,147 @@
+import PropTypes from 'prop-types';
+import { useState } from 'react';
+
+import {
+  Popover,
+  TableRow,
+  MenuItem,
+  TableCell,
+  IconButton,
+  Dialog,
+  DialogActions,
+  DialogContent,
+  DialogContentText,
+  DialogTitle,
+  Button,
+  Stack,
+  Tooltip
+} from '@mui/material';
+
+import { useTranslation } from 'react-i18next';
+import { Icon } from '@iconify/react';
+import Label from 'ui-component/Label';
+import TableSwitch from 'ui-component/Switch';
+import { stickyCellSx } from 'ui-component/stickyCellSx';
+
+function stepTooltip(t, step) {
+  const conditions = step.fallback_on?.length
+    ? step.fallback_on.map((condition) => t(`virtualModel.conditions.${condition}`)).join(', ')
+    : t('virtualModel.conditions.any');
+  const timeout = step.timeout ? t('virtualModel.timeoutLabel', { seconds: step.timeout }) : '';
+  return [t('virtualModel.fallbackOn') + ': ' + conditions, timeout].filter(Boolean).join(' / ');
+}
+
+export default function VirtualModelTableRow({ item, manageVirtualModel, handleOpenModal }) {
+  const { t } = useTranslation();
+  const [open, setOpen] = useState(null);
+  const [openDelete, setOpenDelete] = useState(false);
+  const steps = item.steps || [];
+
+  const handleDeleteOpen = () => {
+    handleCloseMenu();
+    setOpenDelete(true);
+  };
+
+  const handleDeleteClose = () => {
+    setOpenDelete(false);
+  };
+
+  const handleOpenMenu = (event) => {
+    setOpen(event.currentTarget);
+  };
+
+  const handleCloseMenu = () => {
+    setOpen(null);
+  };
+
+  const handleDelete = async () => {
+    handleCloseMenu();
+    await manageVirtualModel(item, 'delete');
+  };
+
+  return (
+    <>
+      <TableRow tabIndex={item.id}>
+        <TableCell>{item.id}</TableCell>
+        <TableCell>
+          <Label color="primary" variant="outlined" copyText={item.name}>
+            {item.name}
+          </Label>
+        </TableCell>
+        <Tooltip title={item.description || ''} placement="top" disableHoverListener={!item.description}>
+          <TableCell sx={{ maxWidth: 220, whiteSpace: 'nowrap', overflow: 'hidden', textOverflow: 'ellipsis' }}>
+            {item.description || '-'}
+          </TableCell>
+        </Tooltip>
+        <TableCell>
+          <Stack direction="row" spacing={0.5} alignItems="center" flexWrap="wrap" useFlexGap>
+            {steps.map((step, index) => (
+              <Stack direction="row" spacing={0.5} alignItems="center" key={index}>
+                {index > 0 && <Icon icon="solar:arrow-right-linear" width={14} />}
+                <Tooltip title={index < steps.length - 1 ? stepTooltip(t, step) : ''} placement="top">
+                  <span>
+                    <Label color="default" variant="soft">
+                      {step.model}
+                    </Label>
+                  </span>
+                </Tooltip>
+              </Stack>
+            ))}
+          </Stack>
+        </TableCell>
+        <TableCell>
+          <TableSwitch id={`switch-${item.id}`} checked={item.enable} onChange={() => manageVirtualModel(item, 'status')} />
+        </TableCell>
+        <TableCell sx={stickyCellSx}>
+          <IconButton onClick={handleOpenMenu} sx={{ color: 'rgb(99, 115, 129)' }}>
+            <Icon icon="solar:menu-dots-circle-bold-duotone" />
+          </IconButton>
+        </TableCell>
+      </TableRow>
+
+      <Popover
+        open={!!open}
+        anchorEl={open}
+        onClose={handleCloseMenu}
+        anchorOrigin={{ vertical: 'top', horizontal: 'left' }}
+        transformOrigin={{ vertical: 'top', horizontal: 'right' }}
+        PaperProps={{
+          sx: { minWidth: 140 }
+        }}
+      >
+        <MenuItem
+          onClick={() => {
+            handleCloseMenu();
+            handleOpenModal(item.id);
+          }}
+        >
+          <Icon icon="solar:pen-bold-duotone" style={{ marginRight: '16px' }} />
+          {t('common.edit')}
+        </MenuItem>
+        <MenuItem onClick={handleDeleteOpen} sx={{ color: 'error.main' }}>
+          <Icon icon="solar:trash-bin-trash-bold-duotone" style={{ marginRight: '16px' }} />
+          {t('common.delete')}
+        </MenuItem>
+      </Popover>
+
+      <Dialog open={openDelete} onClose={handleDeleteClose}>
+        <DialogTitle>{t('common.delete')}</DialogTitle>
+        <DialogContent>
+          <DialogContentText>{t('common.deleteConfirm', { title: item.name })}</DialogContentText>
+        </DialogContent>
+        <DialogActions>
+          <Button onClick={handleDeleteClose}>{t('common.close')}</Button>
+          <Button onClick={handleDelete} sx={{ color: 'error.main' }} autoFocus>
+            {t('common.delete')}
+          </Button>
+        </DialogActions>
+      </Dialog>
+    </>
+  );
+}
+
+VirtualModelTableRow.propTypes = {
+  item: PropTypes.object,
+  manageVirtualModel: PropTypes.func,
+  handleOpenModal: PropTypes.func
+};
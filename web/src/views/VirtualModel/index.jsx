@@ -0,0 +1,169 @@
+import { useState, useEffect } from 'react';
+import { showError, showSuccess } from 'utils/common';
+
+import Table from '@mui/material/Table';
+import TableBody from '@mui/material/TableBody';
+import TableContainer from '@mui/material/TableContainer';
+import PerfectScrollbar from 'react-perfect-scrollbar';
+import ButtonGroup from '@mui/material/ButtonGroup';
+import Toolbar from '@mui/material/Toolbar';
+
+import { Alert, Button, Card, Stack, Container, Typography } from '@mui/material';
+import VirtualModelTableRow from './component/TableRow';
+import KeywordTableHead from 'ui-component/TableHead';
+import { API } from 'utils/api';
+import EditeModal from './component/EditModal';
+import { Icon } from '@iconify/react';
+
+import { useTranslation } from 'react-i18next';
+import useStickyShadow from 'hooks/useStickyShadow';
+// ----------------------------------------------------------------------
+export default function VirtualModel() {
+  const { t } = useTranslation();
+  const stickyShadowRef = useStickyShadow();
+  const [virtualModels, setVirtualModels] = useState([]);
+  const [refreshFlag, setRefreshFlag] = useState(false);
+
+  const [openModal, setOpenModal] = useState(false);
+  const [editId, setEditId] = useState(0);
+
+  const fetchData = async () => {
+    try {
+      const res = await API.get(`/api/virtual_model/`);
+      const { success, message, data } = res.data;
+      if (success) {
+        setVirtualModels(data || []);
+      } else {
+        showError(message);
+      }
+    } catch (error) {
+      console.error(error);
+    }
+  };
+
+  // 处理刷新
+  const handleRefresh = async () => {
+    setRefreshFlag(!refreshFlag);
+  };
+
+  useEffect(() => {
+    fetchData();
+  }, [refreshFlag]);
+
+  const manageVirtualModel = async (item, action) => {
+    const url = '/api/virtual_model/';
+    let res;
+    try {
+      switch (action) {
+        case 'delete':
+          res = await API.delete(url + item.id);
+          break;
+        case 'status':
+          res = await API.put(url, { ...item, enable: !item.enable });
+          break;
+        default:
+          return false;
+      }
+
+      const { success, message } = res.data;
+      if (success) {
+        showSuccess(t('userPage.operationSuccess'));
+        await handleRefresh();
+      } else {
+        showError(message);
+      }
+
+      return res.data;
+    } catch (error) {
+      return;
+    }
+  };
+
+  const handleOpenModal = (id) => {
+    setEditId(id);
+    setOpenModal(true);
+  };
+
+  const handleCloseModal = () => {
+    setOpenModal(false);
+    setEditId(0);
+  };
+
+  const handleOkModal = (status) => {
+    if (status === true) {
+      handleCloseModal();
+      handleRefresh();
+    }
+  };
+
+  return (
+    <>
+      <Stack direction="row" alignItems="center" justifyContent="space-between" mb={5}>
+        <Stack direction="column" spacing={1}>
+          <Typography variant="h2">{t('virtualModel.title')}</Typography>
+          <Typography variant="subtitle1" color="text.secondary">
+            Virtual Model
+          </Typography>
+        </Stack>
+
+        <Button
+          variant="contained"
+          color="primary"
+          startIcon={<Icon icon="solar:add-circle-line-duotone" />}
+          onClick={() => handleOpenModal(0)}
+        >
+          {t('virtualModel.create')}
+        </Button>
+      </Stack>
+      <Alert severity="info" sx={{ mb: 2 }}>
+        {t('virtualModel.tip')}
+      </Alert>
+      <Card>
+        <Toolbar
+          sx={{
+            textAlign: 'right',
+            height: 50,
+            display: 'flex',
+            justifyContent: 'space-between',
+            p: (theme) => theme.spacing(0, 1, 0, 3)
+          }}
+        >
+          <Container maxWidth="xl">
+            <ButtonGroup variant="outlined" aria-label="outlined small primary button group">
+              <Button onClick={handleRefresh} startIcon={<Icon icon="solar:refresh-circle-bold-duotone" width={18} />}>
+                {t('userPage.refresh')}
+              </Button>
+            </ButtonGroup>
+          </Container>
+        </Toolbar>
+        <PerfectScrollbar component="div" containerRef={stickyShadowRef}>
+          <TableContainer sx={{ overflow: 'unset' }}>
+            <Table sx={{ minWidth: 800 }}>
+              <KeywordTableHead
+                headLabel={[
+                  { id: 'id', label: 'ID', disableSort: true },
+                  { id: 'name', label: t('virtualModel.name'), disableSort: true },
+                  { id: 'description', label: t('virtualModel.description'), disableSort: true },
+                  { id: 'steps', label: t('virtualModel.steps'), disableSort: true },
+                  { id: 'enable', label: t('virtualModel.enable'), disableSort: true },
+                  { id: 'action', label: t('virtualModel.action'), disableSort: true, sticky: true }
+                ]}
+              />
+              <TableBody>
+                {virtualModels.map((row) => (
+                  <VirtualModelTableRow
+                    item={row}
+                    manageVirtualModel={manageVirtualModel}
+                    key={row.id}
+                    handleOpenModal={handleOpenModal}
+                  />
+                ))}
+              </TableBody>
+            </Table>
+          </TableContainer>
+        </PerfectScrollbar>
+      </Card>
+      <EditeModal open={openModal} onCancel={handleCloseModal} onOk={handleOkModal} editId={editId} />
+    </>
+  );
+}